@@ -0,0 +1,98 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/spf13/cobra"
+)
+
+func newApprovalsCommand(opt *Options) *cobra.Command {
+	approvalsCmd := &cobra.Command{
+		Use:   "approvals",
+		Short: "Manage the commands you chose not to be asked about again",
+	}
+
+	approvalsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the stored approvals",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			approvals, err := loadApprovals(opt)
+			if err != nil {
+				return err
+			}
+			if len(approvals.List) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No approvals.")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "#\tTOOL\tPATTERN\tCONTEXT\tCREATED")
+			for i, approval := range approvals.List {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", i+1, approval.Tool, approval.Pattern, approval.Context, approval.Created.Format("2006-01-02 15:04"))
+			}
+			return w.Flush()
+		},
+	})
+
+	approvalsCmd.AddCommand(&cobra.Command{
+		Use:   "clear [number...]",
+		Short: "Remove stored approvals",
+		Long:  "Removes the approvals with the given numbers, as shown by kubectl-ai approvals list, or all of them if no numbers are given.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			approvals, err := loadApprovals(opt)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				approvals.List = nil
+				return approvals.Save()
+			}
+			remove := make([]bool, len(approvals.List))
+			for _, arg := range args {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 || n > len(approvals.List) {
+					return fmt.Errorf("no approval numbered %q, see kubectl-ai approvals list", arg)
+				}
+				remove[n-1] = true
+			}
+			i := 0
+			approvals.List = slices.DeleteFunc(approvals.List, func(tools.Approval) bool {
+				i++
+				return remove[i-1]
+			})
+			return approvals.Save()
+		},
+	})
+
+	return approvalsCmd
+}
+
+// loadApprovals loads the approvals from the file given by --approvals-file.
+func loadApprovals(opt *Options) (*tools.Approvals, error) {
+	if opt.ApprovalsPath == "" {
+		return nil, fmt.Errorf("--approvals-file is not set")
+	}
+	path, err := expandPathPlaceholders(opt.ApprovalsPath)
+	if err != nil {
+		return nil, err
+	}
+	return tools.LoadApprovals(path)
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+)
+
+// attachment is content given alongside the query: a file from --file, or the input piped to kubectl-ai with a query.
+type attachment struct {
+	// source describes the content, e.g. `the file "deploy.yaml"`.
+	source  string
+	content string
+	// size is the size of the content before it was shortened.
+	size int
+}
+
+// readAttachments reads the files given with --file.
+func readAttachments(paths []string, maxBytes int) ([]attachment, error) {
+	var attachments []attachment
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading attached file: %w", err)
+		}
+		attachment, err := newAttachment(fmt.Sprintf("the file %q", path), b, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// newAttachment checks that the content is text, and shortens it to about maxBytes (if set) by leaving out its middle.
+func newAttachment(source string, b []byte, maxBytes int) (attachment, error) {
+	if !utf8.Valid(b) {
+		return attachment{}, fmt.Errorf("%s is not text", source)
+	}
+	content := string(b)
+	if maxBytes > 0 && len(content) > maxBytes {
+		fmt.Fprintf(os.Stderr, "warning: %s has %d bytes, more than --max-attachment-bytes; only its start and end are sent\n", source, len(content))
+		content = tools.ElideMiddle(content, maxBytes)
+	}
+	return attachment{source: source, content: content, size: len(b)}, nil
+}
@@ -0,0 +1,331 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+)
+
+// batchOptions configure a batch run; they are set by the run command.
+type batchOptions struct {
+	// queriesFile is the YAML file listing the tasks to run.
+	queriesFile string
+	// parallel is the number of tasks to run at the same time.
+	parallel int
+	// reportPath is the file to write the report to; empty means stdout.
+	reportPath string
+	// transcriptsDir is the directory to write the transcript of each task to; empty means not writing them.
+	transcriptsDir string
+
+	// tasks are the tasks loaded from queriesFile.
+	tasks []batchTask
+}
+
+func newRunCommand(opt *Options) *cobra.Command {
+	runCmd := &cobra.Command{
+		Use:   "run --queries-file <file>",
+		Short: "Run a batch of queries from a file",
+		Long: `Runs the queries listed in a YAML file, each in its own conversation, and writes a JSON report of the results.
+
+The file is a list of tasks, each with a query and an optional name:
+
+  - name: failing-pods
+    query: list the pods that are not running, in all namespaces
+  - query: which deployments have fewer ready replicas than desired?
+
+No one is asked to confirm tool calls: calls that need confirmation are not run, unless the confirmation policy
+or --skip-permissions approves them. The command exits with an error if any task failed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if opt.Batch.queriesFile == "" {
+				return fmt.Errorf("--queries-file is required")
+			}
+			if opt.Batch.parallel < 1 {
+				return fmt.Errorf("--parallel must be at least 1")
+			}
+			tasks, err := loadBatchTasks(opt.Batch.queriesFile)
+			if err != nil {
+				return err
+			}
+			opt.Batch.tasks = tasks
+			return RunRootCommand(cmd.Context(), *opt, nil)
+		},
+	}
+
+	runCmd.Flags().StringVar(&opt.Batch.queriesFile, "queries-file", "", "YAML file with the list of tasks to run")
+	runCmd.Flags().IntVar(&opt.Batch.parallel, "parallel", 1, "number of tasks to run at the same time")
+	runCmd.Flags().StringVar(&opt.Batch.reportPath, "report", "", "file to write the JSON report to (default stdout)")
+	runCmd.Flags().StringVar(&opt.Batch.transcriptsDir, "transcripts-dir", "", "directory to write the transcript of each task to, as Markdown")
+
+	return runCmd
+}
+
+// batchTask is a query in the queries file.
+type batchTask struct {
+	Name  string `json:"name,omitempty"`
+	Query string `json:"query"`
+}
+
+// loadBatchTasks reads the tasks in the queries file, naming the tasks that have no name after their position.
+func loadBatchTasks(path string) ([]batchTask, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading queries file: %w", err)
+	}
+	var tasks []batchTask
+	if err := yaml.UnmarshalStrict(b, &tasks); err != nil {
+		return nil, fmt.Errorf("parsing queries file %q: %w", path, err)
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("queries file %q has no tasks", path)
+	}
+	names := make(map[string]bool)
+	for i := range tasks {
+		task := &tasks[i]
+		task.Query = strings.TrimSpace(task.Query)
+		if task.Query == "" {
+			return nil, fmt.Errorf("task %d in %q has no query", i+1, path)
+		}
+		if task.Name == "" {
+			task.Name = fmt.Sprintf("task-%d", i+1)
+		}
+		if names[task.Name] {
+			return nil, fmt.Errorf("task name %q is used more than once in %q", task.Name, path)
+		}
+		names[task.Name] = true
+	}
+	return tasks, nil
+}
+
+// Statuses of the tasks in a batch report, and of the query in the output of --output json.
+const (
+	taskSucceeded = "succeeded"
+	taskFailed    = "failed"
+)
+
+// batchReport is the machine-readable report of a batch run.
+type batchReport struct {
+	QueriesFile string         `json:"queries_file"`
+	Started     time.Time      `json:"started"`
+	Duration    float64        `json:"duration_seconds"`
+	Succeeded   int            `json:"succeeded"`
+	Failed      int            `json:"failed"`
+	Tasks       []*batchResult `json:"tasks"`
+}
+
+// batchResult is the result of a task in a batch run.
+type batchResult struct {
+	Name   string `json:"name"`
+	Query  string `json:"query"`
+	Status string `json:"status"`
+	// Error is why the task failed.
+	Error string `json:"error,omitempty"`
+	// Answer is the final answer; if the model did not give a structured answer, its summary is the last text it wrote.
+	Answer       *agent.FinalAnswer `json:"answer,omitempty"`
+	Started      time.Time          `json:"started"`
+	Duration     float64            `json:"duration_seconds"`
+	LLMRequests  int                `json:"llm_requests"`
+	ToolCalls    int                `json:"tool_calls"`
+	InputTokens  int                `json:"input_tokens"`
+	OutputTokens int                `json:"output_tokens"`
+	// CachedInputTokens are the input tokens read from the provider's prompt cache, if it reports them.
+	CachedInputTokens int `json:"cached_input_tokens,omitempty"`
+	// LLMSeconds is the time spent waiting for the LLM's responses.
+	LLMSeconds float64 `json:"llm_seconds"`
+	// Transcript is the file the conversation was written to, if any.
+	Transcript string `json:"transcript,omitempty"`
+}
+
+// runBatch runs the tasks, each in a new conversation created by newConversation,
+// and writes the report. Progress is written to stderr, as stdout may be the report.
+func runBatch(ctx context.Context, batch batchOptions, timeFormat ui.TimeFormat, newConversation func() *agent.Agent) error {
+	tasks := batch.tasks
+	if batch.transcriptsDir != "" {
+		if err := os.MkdirAll(batch.transcriptsDir, 0o755); err != nil {
+			return fmt.Errorf("creating transcripts directory: %w", err)
+		}
+	}
+
+	report := &batchReport{
+		QueriesFile: batch.queriesFile,
+		Started:     time.Now(),
+		Tasks:       make([]*batchResult, len(tasks)),
+	}
+
+	var mu sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, batch.parallel)
+	for i, task := range tasks {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result := runBatchTask(ctx, task, i, batch.transcriptsDir, timeFormat, newConversation)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Tasks[i] = result
+			done++
+			line := fmt.Sprintf("[%d/%d] %s: %s in %s", done, len(tasks), task.Name, result.Status, timeFormat.Duration(time.Duration(result.Duration*float64(time.Second))))
+			if result.Error != "" {
+				line += ": " + result.Error
+			}
+			fmt.Fprintln(os.Stderr, line)
+		}()
+	}
+	wg.Wait()
+
+	report.Duration = time.Since(report.Started).Seconds()
+	for _, result := range report.Tasks {
+		if result.Status == taskSucceeded {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+
+	if err := writeBatchReport(batch.reportPath, report); err != nil {
+		return err
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d tasks failed", report.Failed, len(tasks))
+	}
+	return nil
+}
+
+// runBatchTask answers the query of a task in a new conversation.
+func runBatchTask(ctx context.Context, task batchTask, index int, transcriptsDir string, timeFormat ui.TimeFormat, newConversation func() *agent.Agent) *batchResult {
+	result := &batchResult{
+		Name:    task.Name,
+		Query:   task.Query,
+		Status:  taskSucceeded,
+		Started: time.Now(),
+	}
+
+	doc := ui.NewDocument()
+	doc.SetTimeFormat(timeFormat)
+	// The query is recorded as the user's input, so that it is in the transcript.
+	input := ui.NewInputTextBlock()
+	doc.AddBlock(input)
+	input.Observable().Set(task.Query, nil)
+
+	conversation := newConversation()
+	err := conversation.Init(ctx, doc)
+	if err == nil {
+		_, err = conversation.RunOneRound(ctx, task.Query)
+		conversation.Close()
+	}
+	result.Duration = time.Since(result.Started).Seconds()
+	if err != nil {
+		result.Status = taskFailed
+		result.Error = err.Error()
+	}
+
+	stats := conversation.Stats()
+	result.LLMRequests = stats.LLMRequests
+	result.LLMSeconds = stats.LLMTime.Seconds()
+	result.ToolCalls = stats.ToolCalls
+	result.InputTokens = stats.InputTokens
+	result.OutputTokens = stats.OutputTokens
+	result.CachedInputTokens = stats.CachedInputTokens
+
+	result.Answer = conversation.LastAnswer()
+	if result.Answer == nil && err == nil {
+		result.Answer = &agent.FinalAnswer{Summary: lastAgentText(doc)}
+	}
+
+	if transcriptsDir != "" {
+		p := filepath.Join(transcriptsDir, fmt.Sprintf("%03d-%s.md", index+1, unsafeFileNameChars.ReplaceAllString(task.Name, "_")))
+		if err := exportBatchTranscript(doc, p); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: could not write the transcript of task %q: %v\n", task.Name, err)
+		} else {
+			result.Transcript = p
+		}
+	}
+	return result
+}
+
+// unsafeFileNameChars matches the characters of task names that are replaced in the names of transcript files.
+var unsafeFileNameChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// lastAgentText returns the text of the last non-empty agent text block in doc.
+func lastAgentText(doc *ui.Document) string {
+	blocks := doc.Blocks()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if block, ok := blocks[i].(*ui.AgentTextBlock); ok && strings.TrimSpace(block.Text()) != "" {
+			return strings.TrimSpace(block.Text())
+		}
+	}
+	return ""
+}
+
+func exportBatchTranscript(doc *ui.Document, p string) error {
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if err := ui.ExportDocument(doc, f, ui.ExportFormatMarkdown, ui.ExportDetailFull); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeBatchReport writes the report as JSON to p, or to stdout if p is empty.
+func writeBatchReport(p string, report *batchReport) error {
+	// Write empty lists rather than null, so scripts can iterate over them.
+	for _, result := range report.Tasks {
+		if answer := result.Answer; answer != nil {
+			for _, list := range []*[]string{&answer.CommandsRun, &answer.ResourcesTouched, &answer.FollowUps} {
+				if *list == nil {
+					*list = []string{}
+				}
+			}
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if p != "" {
+		f, err := os.Create(p)
+		if err != nil {
+			return fmt.Errorf("creating report file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,396 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// errExitSession is returned by the /exit command to end the session.
+var errExitSession = errors.New("exit session")
+
+// replCommand is a command starting with "/", handled locally rather than sent to the LLM.
+type replCommand struct {
+	// name is the name of the command, without the leading "/".
+	name string
+	// aliases are other names for the command.
+	aliases []string
+	// usage describes the arguments, e.g. "[path]".
+	usage string
+	// description is shown by /help.
+	description string
+	// run runs the command; args is the rest of the input after the command name.
+	run func(s *session, ctx context.Context, args string) error
+}
+
+// replCommands are the commands available in the session, in the order /help lists them.
+// They are populated in init, as /help refers to the list itself.
+var replCommands []*replCommand
+
+// legacyKeywords are bare keywords that run a command, kept for compatibility.
+var legacyKeywords = map[string]string{
+	"model":   "model",
+	"models":  "models",
+	"tools":   "tools",
+	"version": "version",
+	"reset":   "reset",
+	"clear":   "clear",
+	"exit":    "exit",
+	"quit":    "exit",
+}
+
+func init() {
+	replCommands = []*replCommand{
+		{name: "help", description: "List the available commands.", run: (*session).helpCommand},
+		{name: "model", usage: "[name]", description: "Show the current model, or continue the conversation with another model.", run: (*session).modelCommand},
+		{name: "models", description: "List the available models.", run: (*session).modelsCommand},
+		{name: "style", usage: "[name [query]]", description: "Show the answer style, or set it to concise-ops, detailed-report, executive-summary, runbook-steps or default; with a query, answer only that query in the style.", run: (*session).styleCommand},
+		{name: "tools", usage: "[name]", description: "List the available tools; with a name, show the schemas of the tool's parameters and result.", run: (*session).toolsCommand},
+		{name: "version", description: "Show the kubectl-ai version.", run: (*session).versionCommand},
+		{name: "reset", description: "Clear the conversational context.", run: (*session).resetCommand},
+		{name: "clear", description: "Clear the terminal screen.", run: (*session).clearCommand},
+		{name: "save", aliases: []string{"export"}, usage: "[path]", description: "Save the conversation as Markdown, or as HTML or JSON if the path ends in .html or .json.", run: (*session).saveCommand},
+		{name: "expand", usage: "[number]", description: "Show the full output of a folded tool call; without a number, the latest one.", run: (*session).expandCommand},
+		{name: "search", usage: "<regex>", description: "Search the conversation, including tool output.", run: (*session).searchCommand},
+		{name: "undo", aliases: []string{"undo-turn"}, description: "Remove your last query, and the answer and tool calls that followed, from the conversation, and edit the query again. Changes made to the cluster are not reverted.", run: (*session).undoCommand},
+		{name: "compact", usage: "[instructions]", description: "Condense the conversation into a short summary to free up the context window; instructions say what the summary should focus on.", run: (*session).compactCommand},
+		{name: "handoff", usage: "[note]", description: "Write an escalation document for the next responder (summary, evidence and pending plan), and post it to --handoff-webhook if set; the note is passed on to them.", run: (*session).handoffCommand},
+		{name: "branch", usage: "[name]", description: "Fork the conversation into a new branch, or switch to an existing one; without a name, list the branches.", run: (*session).branchCommand},
+		{name: "mcp", usage: "status", description: "Show the status of the MCP servers.", run: (*session).mcpCommand},
+		{name: "feedback", usage: "good|bad [comment]", description: "Rate the last answer (also up/down, 👍/👎); the rating is recorded in the trace file.", run: (*session).feedbackCommand},
+		{name: "stats", description: "Show statistics for the session: rounds, LLM requests, tool calls and tokens.", run: (*session).statsCommand},
+		{name: "exit", aliases: []string{"quit"}, description: "End the session (Ctrl+C also works).", run: (*session).exitCommand},
+	}
+}
+
+// parseCommand splits input of the form "/name args" into the command and its arguments.
+// It also recognizes the legacy bare keywords such as "models". ok is false if the input is not a command.
+func parseCommand(input string) (name string, args string, ok bool) {
+	if name, ok := legacyKeywords[input]; ok {
+		return name, "", true
+	}
+	rest, ok := strings.CutPrefix(input, "/")
+	if !ok || rest == "" {
+		return "", "", false
+	}
+	name, args, _ = strings.Cut(rest, " ")
+	return name, strings.TrimSpace(args), true
+}
+
+func lookupCommand(name string) *replCommand {
+	for _, command := range replCommands {
+		if command.name == name {
+			return command
+		}
+		for _, alias := range command.aliases {
+			if alias == name {
+				return command
+			}
+		}
+	}
+	return nil
+}
+
+// runCommand runs the command in input, if it is one; handled is false otherwise.
+func (s *session) runCommand(ctx context.Context, input string) (handled bool, err error) {
+	name, args, ok := parseCommand(input)
+	if !ok {
+		return false, nil
+	}
+	command := lookupCommand(name)
+	if command == nil {
+		return true, fmt.Errorf("unknown command /%s, type /help to list the commands", name)
+	}
+	return true, command.run(s, ctx, args)
+}
+
+func (s *session) addText(text string) {
+	s.doc.AddBlock(ui.NewAgentTextBlock().WithText(text))
+}
+
+func (s *session) helpCommand(ctx context.Context, args string) error {
+	var text strings.Builder
+	text.WriteString("Available commands:\n\n")
+	for _, command := range replCommands {
+		names := "/" + command.name
+		for _, alias := range command.aliases {
+			names += ", /" + alias
+		}
+		if command.usage != "" {
+			names += " " + command.usage
+		}
+		fmt.Fprintf(&text, "* `%s`: %s\n", names, command.description)
+	}
+	s.addText(text.String())
+	return nil
+}
+
+func (s *session) modelCommand(ctx context.Context, args string) error {
+	if args == "" {
+		s.addText(fmt.Sprintf("Current model is `%s`\n", s.model))
+		return nil
+	}
+	tokenPrice, err := resolveTokenPrice(s.tokenPrice, args, s.conversation.MaxCost)
+	if err != nil {
+		return err
+	}
+	if err := s.conversation.SwitchModel(ctx, args); err != nil {
+		return err
+	}
+	s.conversation.TokenPrice = tokenPrice
+	s.model = args
+	if s.contextWindow == 0 {
+		// Zero means unknown, which disables the context usage warnings.
+		s.conversation.ContextWindow, _ = gollm.DefaultContextWindow(args)
+	}
+	s.addText(fmt.Sprintf("Switched to model `%s`; the conversation so far is sent to it with your next query.\n", args))
+	return nil
+}
+
+func (s *session) modelsCommand(ctx context.Context, args string) error {
+	models, err := s.listModels(ctx)
+	if err != nil {
+		return fmt.Errorf("listing models: %w", err)
+	}
+	s.addText("\n  Available models:\n" + strings.Join(models, "\n"))
+	return nil
+}
+
+func (s *session) styleCommand(ctx context.Context, args string) error {
+	if args == "" {
+		style := string(s.conversation.AnswerStyle)
+		if style == "" {
+			style = "default"
+		}
+		s.addText(fmt.Sprintf("Current answer style is `%s`\n", style))
+		return nil
+	}
+	name, query, _ := strings.Cut(args, " ")
+	style, err := agent.ParseAnswerStyle(name)
+	if err != nil {
+		return err
+	}
+	query = strings.TrimSpace(query)
+	if query == "" {
+		s.conversation.AnswerStyle = style
+		s.addText(fmt.Sprintf("Switched to answer style `%s`; saved conversations follow it.\n", name))
+		return nil
+	}
+	previous := s.conversation.AnswerStyle
+	s.conversation.AnswerStyle = style
+	defer func() { s.conversation.AnswerStyle = previous }()
+	return s.answerQuery(ctx, query)
+}
+
+func (s *session) toolsCommand(ctx context.Context, args string) error {
+	if s.conversation == nil {
+		return fmt.Errorf("listing tools: conversation is not initialized")
+	}
+	if args == "" {
+		s.addText("\n  Available tools:\n" + strings.Join(s.conversation.Tools.Names(), "\n"))
+		return nil
+	}
+
+	tool := s.conversation.Tools.Lookup(args)
+	if tool == nil {
+		return fmt.Errorf("tool %q not found, use /tools to list the available tools", args)
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s**\n\n%s\n", tool.Name(), tool.Description())
+	if parameters := tool.FunctionDefinition().Parameters; parameters != nil {
+		b, err := json.MarshalIndent(parameters, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting parameters of tool %q: %w", args, err)
+		}
+		fmt.Fprintf(&sb, "\nParameters:\n```json\n%s\n```\n", b)
+	}
+	if schema := tools.ResultSchema(tool); schema != nil {
+		b, err := json.MarshalIndent(schema, "", "  ")
+		if err != nil {
+			return fmt.Errorf("formatting result schema of tool %q: %w", args, err)
+		}
+		fmt.Fprintf(&sb, "\nResult:\n```json\n%s\n```\n", b)
+	}
+	s.addText(sb.String())
+	return nil
+}
+
+func (s *session) versionCommand(ctx context.Context, args string) error {
+	s.addText(fmt.Sprintf("Version: `%s`\n", version))
+	return nil
+}
+
+func (s *session) resetCommand(ctx context.Context, args string) error {
+	return s.conversation.Init(ctx, s.doc)
+}
+
+func (s *session) clearCommand(ctx context.Context, args string) error {
+	s.ui.ClearScreen()
+	return nil
+}
+
+func (s *session) saveCommand(ctx context.Context, args string) error {
+	p := args
+	if p == "" {
+		p = s.exportPath
+	}
+	if p == "" {
+		p = filepath.Join(os.TempDir(), fmt.Sprintf("kubectl-ai-session-%s.md", time.Now().Format("20060102-150405")))
+	}
+	if err := s.exportDocument(p); err != nil {
+		return err
+	}
+	s.addText(fmt.Sprintf("Exported conversation to `%s`", p))
+	return nil
+}
+
+func (s *session) expandCommand(ctx context.Context, args string) error {
+	return s.expandBlock(args)
+}
+
+func (s *session) searchCommand(ctx context.Context, args string) error {
+	return s.searchDocument(args)
+}
+
+func (s *session) undoCommand(ctx context.Context, args string) error {
+	undone, err := s.conversation.Undo(ctx)
+	if err != nil {
+		return err
+	}
+	s.addText(fmt.Sprintf("Removed the last round from the conversation: `%s`\n\nNote that changes already made to the cluster are not reverted. Edit the query to ask it again, or clear it.", undone))
+	s.nextInput = undone
+	return nil
+}
+
+func (s *session) compactCommand(ctx context.Context, args string) error {
+	before := s.conversation.ContextUsageSummary()
+	summary, err := s.conversation.Compact(ctx, args)
+	if err != nil {
+		return err
+	}
+	s.addText(fmt.Sprintf("Compacted the conversation (%s before). The conversation continues from this summary:\n\n%s", before, summary))
+	return nil
+}
+
+func (s *session) branchCommand(ctx context.Context, args string) error {
+	if args == "" {
+		var text strings.Builder
+		text.WriteString("Branches:\n")
+		for _, branch := range s.conversation.Branches() {
+			marker := " "
+			if branch == s.conversation.CurrentBranch() {
+				marker = "*"
+			}
+			fmt.Fprintf(&text, "%s `%s`\n", marker, branch)
+		}
+		s.addText(text.String())
+		return nil
+	}
+	created, err := s.conversation.Branch(ctx, args)
+	if err != nil {
+		return err
+	}
+	if created {
+		s.addText(fmt.Sprintf("Forked the conversation into branch `%s`", args))
+	} else {
+		s.addText(fmt.Sprintf("Switched to branch `%s`", args))
+	}
+	return nil
+}
+
+func (s *session) mcpCommand(ctx context.Context, args string) error {
+	if args != "" && args != "status" {
+		return fmt.Errorf("usage: /mcp status")
+	}
+	if s.mcpManager == nil {
+		s.addText("MCP client mode is not enabled; start kubectl-ai with `--mcp-client` to connect to MCP servers.")
+		return nil
+	}
+	blocks, err := GetMCPServerStatusWithClientMode(true, s.mcpManager)
+	if err != nil {
+		return fmt.Errorf("getting MCP server status: %w", err)
+	}
+	for _, block := range blocks {
+		s.doc.AddBlock(block)
+	}
+	return nil
+}
+
+func (s *session) statsCommand(ctx context.Context, args string) error {
+	stats := s.conversation.Stats()
+	var text strings.Builder
+	text.WriteString("Session statistics:\n\n")
+	timeFormat := s.doc.TimeFormat()
+	fmt.Fprintf(&text, "* Started: %s\n", timeFormat.Time(stats.Started))
+	fmt.Fprintf(&text, "* Duration: %s\n", timeFormat.Duration(time.Since(stats.Started)))
+	fmt.Fprintf(&text, "* Session ID: `%s`\n", s.conversation.SessionID)
+	fmt.Fprintf(&text, "* Model: `%s`\n", s.model)
+	fmt.Fprintf(&text, "* Rounds: %d\n", stats.Rounds)
+	fmt.Fprintf(&text, "* LLM requests: %d\n", stats.LLMRequests)
+	if stats.LLMRequests > 0 {
+		fmt.Fprintf(&text, "* LLM time: %s (%s per request)\n", timeFormat.Duration(stats.LLMTime), timeFormat.Duration(stats.LLMTime/time.Duration(stats.LLMRequests)))
+	}
+	fmt.Fprintf(&text, "* Tool calls: %d\n", stats.ToolCalls)
+	if stats.InputTokens != 0 || stats.OutputTokens != 0 {
+		fmt.Fprintf(&text, "* Tokens: %d input, %d output\n", stats.InputTokens, stats.OutputTokens)
+		if stats.CachedInputTokens != 0 && stats.InputTokens != 0 {
+			fmt.Fprintf(&text, "* Prompt cache: %d input tokens cached (%.0f%%)\n", stats.CachedInputTokens, 100*float64(stats.CachedInputTokens)/float64(stats.InputTokens))
+		}
+	}
+	spent, cost := s.conversation.Spent()
+	if s.conversation.MaxTokens > 0 {
+		fmt.Fprintf(&text, "* Token budget: %d of %d tokens used\n", spent.TotalTokens, s.conversation.MaxTokens)
+	}
+	if s.conversation.MaxCost > 0 {
+		fmt.Fprintf(&text, "* Cost budget: $%.4f of $%.2f spent\n", cost, s.conversation.MaxCost)
+	} else if s.conversation.TokenPrice.Known() {
+		fmt.Fprintf(&text, "* Estimated cost: $%.4f\n", cost)
+	}
+	if summary := s.conversation.ToolQuotaSummary(); summary != "" {
+		fmt.Fprintf(&text, "* %s\n", summary)
+	}
+	fmt.Fprintf(&text, "* %s\n", s.conversation.ContextUsageSummary())
+	s.addText(text.String())
+	return nil
+}
+
+func (s *session) feedbackCommand(ctx context.Context, args string) error {
+	ratingArg, comment, _ := strings.Cut(args, " ")
+	if ratingArg == "" {
+		return fmt.Errorf("usage: /feedback good|bad [comment]")
+	}
+	rating, err := agent.ParseFeedbackRating(ratingArg)
+	if err != nil {
+		return err
+	}
+	_, err = s.conversation.RecordFeedback(ctx, rating, strings.TrimSpace(comment))
+	return err
+}
+
+func (s *session) exitCommand(ctx context.Context, args string) error {
+	return errExitSession
+}
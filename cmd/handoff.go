@@ -0,0 +1,74 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (s *session) handoffCommand(ctx context.Context, args string) error {
+	handoff, err := s.conversation.Handoff(ctx, args)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(os.TempDir(), fmt.Sprintf("kubectl-ai-handoff-%s.md", time.Now().Format("20060102-150405")))
+	if err := os.WriteFile(p, []byte(handoff), 0o600); err != nil {
+		return fmt.Errorf("writing handoff: %w", err)
+	}
+	s.addText(handoff)
+	s.addText(fmt.Sprintf("Wrote the handoff to `%s`", p))
+
+	if s.handoffWebhook != "" {
+		if err := postHandoff(ctx, s.handoffWebhook, handoff); err != nil {
+			return err
+		}
+		s.addText("Posted the handoff to the handoff webhook.")
+	}
+	return nil
+}
+
+// postHandoff posts the handoff document to an incoming webhook, such as the ones of Slack, Microsoft Teams,
+// Google Chat or Mattermost, or of an incident management tool, which all accept a JSON object with a "text" field.
+func postHandoff(ctx context.Context, webhook string, handoff string) error {
+	body, err := json.Marshal(map[string]string{"text": handoff})
+	if err != nil {
+		return fmt.Errorf("marshalling handoff: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating handoff request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting handoff: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("posting handoff: webhook returned %s: %s", resp.Status, bytes.TrimSpace(b))
+	}
+	return nil
+}
@@ -15,9 +15,10 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"context"
+	"crypto/fips140"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -25,10 +26,14 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"os/user"
 	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
@@ -57,6 +62,10 @@ func BuildRootCommand(opt *Options) (*cobra.Command, error) {
 		Short: "A CLI tool to interact with Kubernetes using natural language",
 		Long:  "kubectl-ai is a command-line tool that allows you to interact with your Kubernetes cluster using natural language queries. It leverages large language models to understand your intent and translate it into kubectl",
 		Args:  cobra.MaximumNArgs(1), // Only one positional arg is allowed.
+		// The workspace is applied before any command runs, so that it also applies to subcommands.
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return applyWorkspace(opt, cmd.Flags())
+		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return RunRootCommand(cmd.Context(), *opt, args)
 		},
@@ -67,11 +76,37 @@ func BuildRootCommand(opt *Options) (*cobra.Command, error) {
 		Short: "Print the version number of kubectl-ai",
 		Run: func(cmd *cobra.Command, args []string) {
 			fmt.Printf("version: %s\ncommit: %s\ndate: %s\n", version, commit, date)
+			if fips140.Enabled() {
+				fmt.Printf("fips140: enabled\n")
+			}
 			os.Exit(0)
 		},
 	})
 
-	if err := opt.bindCLIFlags(rootCmd.Flags()); err != nil {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "replay <journal-file>",
+		Short: "Resume a session from a recorded journal (trace) file",
+		Long:  "Reconstructs the chat history recorded in a journal (trace) file, and resumes the agent from that state.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opt.ReplayPath = args[0]
+			return RunRootCommand(cmd.Context(), *opt, nil)
+		},
+	})
+
+	rootCmd.AddCommand(newRunCommand(opt))
+	rootCmd.AddCommand(newScenarioCommand(opt))
+	rootCmd.AddCommand(newSessionsCommand(opt))
+	rootCmd.AddCommand(newApprovalsCommand(opt))
+	rootCmd.AddCommand(newMemoriesCommand(opt))
+	rootCmd.AddCommand(newWorkDirsCommand())
+	rootCmd.AddCommand(newPackCommand(opt))
+	rootCmd.AddCommand(newToolsCommand(opt))
+	rootCmd.AddCommand(newServeCommand(opt))
+	rootCmd.AddCommand(newWorkspacesCommand(opt))
+
+	// Flags are persistent so that they also apply to the replay command.
+	if err := opt.bindCLIFlags(rootCmd.PersistentFlags()); err != nil {
 		return nil, err
 	}
 	return rootCmd, nil
@@ -83,36 +118,249 @@ type Options struct {
 	// SkipPermissions is a flag to skip asking for confirmation before executing kubectl commands
 	// that modifies resources in the cluster.
 	SkipPermissions bool `json:"skipPermissions,omitempty"`
+	// ConfirmationPolicy decides, per tool, kubectl verb, resource kind and namespace, which tool calls are approved
+	// without asking, always confirmed, or refused. It can only be set in the configuration file.
+	ConfirmationPolicy tools.ConfirmationPolicy `json:"confirmationPolicy,omitempty"`
+	// ArtifactVerification configures the verification of the signatures of custom tools, recipes and health rules.
+	ArtifactVerification tools.ArtifactPolicy `json:"artifactVerification,omitempty"`
+	// Executor runs the commands of the kubectl and bash tools, see tools.ParseExecutor.
+	Executor string `json:"executor,omitempty"`
+	// SSHTarget is short for Executor ssh:<SSHTarget>.
+	SSHTarget string `json:"sshTarget,omitempty"`
+	// SSHTargets are the hosts that commands can be run on over SSH, by name.
+	SSHTargets map[string]tools.SSHTarget `json:"sshTargets,omitempty"`
+	// PromptProfile is the name of the variant of the system prompt to use, from PromptProfiles.
+	PromptProfile string `json:"promptProfile,omitempty"`
+	// PromptProfiles are named variants of the system prompt, e.g. "conservative" or "verbose", to compare prompts.
+	PromptProfiles map[string]PromptProfile `json:"promptProfiles,omitempty"`
+	// Offline only allows local LLM providers and MCP servers, and keeps tools from reaching the network outside the cluster.
+	Offline bool `json:"offline,omitempty"`
+	// RestrictEgress only lets the commands run by tools reach local hosts, the clusters and EgressAllowedHosts over HTTP(S).
+	RestrictEgress bool `json:"restrictEgress,omitempty"`
+	// EgressAllowedHosts are the hosts outside the cluster that commands may reach with RestrictEgress, e.g. *.example.com.
+	EgressAllowedHosts []string `json:"egressAllowedHosts,omitempty"`
+	// RestrictBashWrites only lets bash commands write to the working directory and BashWritePaths.
+	RestrictBashWrites bool `json:"restrictBashWrites,omitempty"`
+	// BashWritePaths are the files and directories, besides the working directory, that bash commands may write to.
+	BashWritePaths []string `json:"bashWritePaths,omitempty"`
+	// Simulate runs tool calls that modify resources as dry runs, without changing anything.
+	Simulate bool `json:"simulate,omitempty"`
 	// EnableToolUseShim is a flag to enable tool use shim.
 	// TODO(droot): figure out a better way to discover if the model supports tool use
 	// and set this automatically.
 	EnableToolUseShim bool `json:"enableToolUseShim,omitempty"`
+	// ShimCorrectionAttempts is how many times in a row to ask the LLM to correct a malformed response in tool use shim mode.
+	ShimCorrectionAttempts int `json:"shimCorrectionAttempts,omitempty"`
 	// Quiet flag indicates if the agent should run in non-interactive mode.
 	// It requires a query to be provided as a positional argument.
-	Quiet         bool `json:"quiet,omitempty"`
-	MCPServer     bool `json:"mcpServer,omitempty"`
-	MCPClient     bool `json:"mcpClient,omitempty"`
-	MaxIterations int  `json:"maxIterations,omitempty"`
+	Quiet bool `json:"quiet,omitempty"`
+	// Files are attached to the first query, so that it can ask about them.
+	Files []string `json:"files,omitempty"`
+	// MaxAttachmentBytes is the size above which the middle of attached files and piped input is left out; zero means no limit.
+	MaxAttachmentBytes int `json:"maxAttachmentBytes,omitempty"`
+
+	MCPServer bool `json:"mcpServer,omitempty"`
+	MCPClient bool `json:"mcpClient,omitempty"`
+	// MCPServers are the names of the MCP servers to connect to in MCP client mode; empty connects to all of them.
+	MCPServers    []string `json:"mcpServers,omitempty"`
+	MaxIterations int      `json:"maxIterations,omitempty"`
+	// IterationExtension is how many more iterations to offer when the limit is reached while the agent makes progress.
+	IterationExtension int `json:"iterationExtension,omitempty"`
+	// AutoExtendIterations is how many iterations beyond the limit non-interactive runs may take while making progress.
+	AutoExtendIterations int `json:"autoExtendIterations,omitempty"`
+	// MaxRepeatedToolCalls is how many times the same tool call can be made while answering a query; zero means no limit.
+	MaxRepeatedToolCalls int `json:"maxRepeatedToolCalls,omitempty"`
+	// OnLoop is what to do when the model is stuck in a loop: "correct" or "ask".
+	OnLoop string `json:"onLoop,omitempty"`
+	// MaxToolErrors is how many tool calls in a row can fail before the agent stops; zero means no limit.
+	MaxToolErrors int `json:"maxToolErrors,omitempty"`
+	// SummarizeToolOutput is the size in bytes above which command output is summarized by the LLM; zero means never.
+	SummarizeToolOutput int `json:"summarizeToolOutput,omitempty"`
+	// MaxToolCalls, MaxMutatingCalls and MaxBashCalls are quotas of tool calls, tool calls that modify resources
+	// and bash commands in the session; the agent pauses when one is reached, until the user extends it.
+	// Zero means no limit.
+	MaxToolCalls     int `json:"maxToolCalls,omitempty"`
+	MaxMutatingCalls int `json:"maxMutatingCalls,omitempty"`
+	MaxBashCalls     int `json:"maxBashCalls,omitempty"`
+
+	// RoundTimeout bounds the time spent answering a single query; zero means no limit.
+	RoundTimeout time.Duration `json:"roundTimeout,omitempty"`
+	// ToolTimeout bounds the time spent in a single tool invocation; zero means no limit.
+	ToolTimeout time.Duration `json:"toolTimeout,omitempty"`
 
 	// KubeConfigPath is the path to the kubeconfig file.
 	// If not provided, the default kubeconfig path will be used.
 	KubeConfigPath string `json:"kubeConfigPath,omitempty"`
 
+	// Workspace is the name of the workspace to use, which sets the kube context, namespace, model, prompt profile,
+	// confirmation policy and MCP servers saved in it.
+	Workspace string `json:"workspace,omitempty"`
+	// WorkspacesDir is the directory where workspaces are saved.
+	WorkspacesDir string `json:"workspacesDir,omitempty"`
+	// workspace is the workspace loaded for Workspace.
+	workspace *Workspace
+
 	PromptTemplateFilePath string   `json:"promptTemplateFilePath,omitempty"`
 	ExtraPromptPaths       []string `json:"extraPromptPaths,omitempty"`
 	TracePath              string   `json:"tracePath,omitempty"`
-	RemoveWorkDir          bool     `json:"removeWorkDir,omitempty"`
-	ToolConfigPaths        []string `json:"toolConfigPaths,omitempty"`
+	// RemoveWorkDir is the former way of setting WorkDirRetention to "remove".
+	RemoveWorkDir bool `json:"removeWorkDir,omitempty"`
+	// WorkDirRetention decides whether the working directory of a session is kept when it ends: keep, keep-on-error or remove.
+	WorkDirRetention string `json:"workDirRetention,omitempty"`
+	// WorkDirTTL is how long working directories are kept; older ones are removed at startup. 0 keeps them.
+	WorkDirTTL      time.Duration `json:"workDirTTL,omitempty"`
+	ToolConfigPaths []string      `json:"toolConfigPaths,omitempty"`
+	// HealthRulesPaths are files or directories with rules for interpreting the health of custom resources.
+	HealthRulesPaths []string `json:"healthRulesPaths,omitempty"`
+	// RecipesPaths are files or directories with recipes: multi-step procedures exposed to the model as tools.
+	RecipesPaths []string `json:"recipesPaths,omitempty"`
+	// DeployMarkersPath is a file of deploy markers, written by the CD pipeline, that incident_timeline includes.
+	DeployMarkersPath string `json:"deployMarkersPath,omitempty"`
+	// IaCStatePaths are rendered IaC state files (terraform show -json, pulumi stack export) to check for drift.
+	IaCStatePaths []string `json:"iacStatePaths,omitempty"`
+	// PacksDir is the directory where packs of prompts and recipes are installed by kubectl-ai pack install.
+	PacksDir string `json:"packsDir,omitempty"`
 
 	// UserInterface is the type of user interface to use.
 	UserInterface UserInterface `json:"userInterface,omitempty"`
+	// InputKeymap is the line-editing keymap of the terminal input prompt: "emacs" or "vi".
+	InputKeymap string `json:"inputKeymap,omitempty"`
+	// Notifications is how to notify the user when the agent needs their attention: "off", "desktop" or "terminal".
+	Notifications string `json:"notifications,omitempty"`
+	// NotifyAfter is how long the agent must have been working before we notify the user that it is done,
+	// or waiting for approval.
+	NotifyAfter time.Duration `json:"notifyAfter,omitempty"`
+	// TimeFormat is how timestamps are shown in the UI, journal replay and exports: "absolute" or "relative".
+	TimeFormat string `json:"timeFormat,omitempty"`
+	// TimeZone is the time zone timestamps are shown in: "local", "UTC" or an IANA name such as "Europe/Paris".
+	TimeZone string `json:"timeZone,omitempty"`
 	// UIListenAddress is the address to listen for the HTML UI.
 	UIListenAddress string `json:"uiListenAddress,omitempty"`
+	// ShareAddress is the address to share the session on, so other users can attach to it as viewers or approvers;
+	// empty disables sharing.
+	ShareAddress string `json:"shareAddress,omitempty"`
+	// ShareRequireApproval makes the tool calls that modify resources wait for an approver of the shared session.
+	ShareRequireApproval bool `json:"shareRequireApproval,omitempty"`
+	// ShareApprovalTimeout bounds the wait for an approver with ShareRequireApproval; the call is rejected when it ends.
+	ShareApprovalTimeout time.Duration `json:"shareApprovalTimeout,omitempty"`
+	// HandoffWebhook is the URL of an incoming webhook of a chat or incident tool that /handoff posts the handoff to.
+	HandoffWebhook string `json:"handoffWebhook,omitempty"`
+	// ExportPath is the path to export the conversation to when the session ends.
+	// The format is chosen from the extension: .html for HTML, Markdown otherwise.
+	ExportPath string `json:"exportPath,omitempty"`
+	// SessionsDir is the directory where the transcripts of interactive sessions are stored; empty disables storing them.
+	SessionsDir string `json:"sessionsDir,omitempty"`
+	// ApprovalsPath is the file where "don't ask me again" approvals are stored; empty keeps them for the session only.
+	ApprovalsPath string `json:"approvalsPath,omitempty"`
+	// Memory enables the long-term memory: facts the model learns are stored in MemoryPath, and the ones
+	// relevant to a query are given to it in later sessions.
+	Memory bool `json:"memory,omitempty"`
+	// MemoryPath is the file where the memories are stored.
+	MemoryPath string `json:"memoryPath,omitempty"`
+	// EmbeddingModel is the model used to find the memories relevant to a query; empty uses the provider's default.
+	EmbeddingModel string `json:"embeddingModel,omitempty"`
+	// InventoryTTL is how long the names of the resources of a namespace are cached to resolve references
+	// to resources in queries; 0 disables the inventory.
+	InventoryTTL time.Duration `json:"inventoryTTL,omitempty"`
 
 	// SkipVerifySSL is a flag to skip verifying the SSL certificate of the LLM provider.
 	SkipVerifySSL bool `json:"skipVerifySSL,omitempty"`
+
+	// LLMRetryMaxAttempts is the maximum number of attempts for each LLM request.
+	LLMRetryMaxAttempts int `json:"llmRetryMaxAttempts,omitempty"`
+	// LLMRetryInitialBackoff is the delay before the first retry of a failed LLM request.
+	LLMRetryInitialBackoff time.Duration `json:"llmRetryInitialBackoff,omitempty"`
+	// LLMRetryMaxBackoff caps the delay between retries of a failed LLM request.
+	LLMRetryMaxBackoff time.Duration `json:"llmRetryMaxBackoff,omitempty"`
+	// LLMRetryJitter adds a random delay to the retry backoff.
+	LLMRetryJitter bool `json:"llmRetryJitter,omitempty"`
+	// FallbackModelID is the model to switch to once retries against ModelID are exhausted.
+	FallbackModelID string `json:"fallbackModel,omitempty"`
+
+	// MutationCandidates is the number of candidate commands to sample before modifying resources.
+	MutationCandidates int `json:"mutationCandidates,omitempty"`
+	// CandidateSelection is how to choose between candidate commands: "verifier" or "user".
+	CandidateSelection string `json:"candidateSelection,omitempty"`
+
+	// ContextWindow is the size of the model's context window in tokens; zero means use the known size for the model.
+	ContextWindow int `json:"contextWindow,omitempty"`
+	// ContextWarningThresholds are the percentages of the context window at which to warn the user.
+	ContextWarningThresholds []int `json:"contextWarningThresholds,omitempty"`
+	// ShowContextUsage shows how much of the context window is used after each answer.
+	ShowContextUsage bool `json:"showContextUsage,omitempty"`
+
+	// MaxTokens stops the agent before it uses more than this many tokens in the session; zero means no limit.
+	MaxTokens int `json:"maxTokens,omitempty"`
+	// MaxCost stops the agent before the estimated cost of the session exceeds this many US dollars; zero means no limit.
+	MaxCost float64 `json:"maxCost,omitempty"`
+	// InputTokenPrice and OutputTokenPrice are the prices of the model's tokens, in US dollars per million tokens,
+	// used to estimate the cost; zero means use the list price of the model, if it is known.
+	InputTokenPrice  float64 `json:"inputTokenPrice,omitempty"`
+	OutputTokenPrice float64 `json:"outputTokenPrice,omitempty"`
+
+	// ClusterMetadata includes facts about the target cluster (version, API groups, nodes) in the system prompt.
+	ClusterMetadata bool `json:"clusterMetadata,omitempty"`
+	// StablePrompt keeps the system prompt and tool definitions the same across sessions, for prompt cache reuse.
+	StablePrompt bool `json:"stablePrompt,omitempty"`
+	// SlimToolSchemas shortens the descriptions of the tool definitions, for providers that limit their size.
+	SlimToolSchemas bool `json:"slimToolSchemas,omitempty"`
+
+	// VerifyMutations checks that changes took effect after each command that modifies resources.
+	VerifyMutations bool `json:"verifyMutations,omitempty"`
+	// Assertions lets the model check conditions as steps of a plan; a failed assertion stops the plan and the model replans.
+	Assertions bool `json:"assertions,omitempty"`
+	// SuggestAlternatives asks the model for a safer or read-only alternative when you decline a command.
+	SuggestAlternatives bool `json:"suggestAlternatives,omitempty"`
+	// HighlightAnomalies flags restart counts, OOMKilled, ImagePullBackOff, NotReady and pressure conditions in command output.
+	HighlightAnomalies bool `json:"highlightAnomalies,omitempty"`
+	// MonitorWindow is how long to watch for warning events and restarts after a change; 0 disables monitoring.
+	MonitorWindow time.Duration `json:"monitorWindow,omitempty"`
+
+	// Language is the language the model replies in, e.g. "French" or "ja"; empty lets it reply in the language of the query.
+	Language string `json:"language,omitempty"`
+	// AnswerStyle is the preset for the form of the answers: concise-ops, detailed-report, executive-summary,
+	// runbook-steps, or empty for the default. Prompt profiles can set it too.
+	AnswerStyle string `json:"answerStyle,omitempty"`
+	// StructuredAnswer asks the model for a structured final answer: a summary, the commands run,
+	// the resources touched and suggested follow-ups.
+	StructuredAnswer bool `json:"structuredAnswer,omitempty"`
+	// SuggestFollowUps suggests 2 or 3 numbered follow-up queries after each answer in interactive sessions;
+	// entering a number asks one.
+	SuggestFollowUps bool `json:"suggestFollowUps,omitempty"`
+	// AttributionAnnotations annotates the objects the agent creates or modifies with the session ID, the user,
+	// the time and a hash of the query.
+	AttributionAnnotations bool `json:"attributionAnnotations,omitempty"`
+	// AutoCleanup deletes the resources created by a task that is aborted before it completes, without asking.
+	AutoCleanup bool `json:"autoCleanup,omitempty"`
+	// FanOutParallelism is how many sessions the fan_out function runs at a time; zero disables fan_out.
+	FanOutParallelism int `json:"fanOutParallelism,omitempty"`
+	// OutputFormat is the format of the final answer in quiet mode: "text" or "json".
+	// The json format implies StructuredAnswer.
+	OutputFormat string `json:"outputFormat,omitempty"`
+	// StreamOutput writes the answer to stdout as it is generated, when stdout is not a terminal in quiet mode,
+	// with the other output on stderr.
+	StreamOutput bool `json:"streamOutput,omitempty"`
+
+	// AskFeedback asks the user to rate each answer in interactive sessions; ratings are recorded in the trace.
+	AskFeedback bool `json:"askFeedback,omitempty"`
+
+	// ReplayPath is the journal file to resume the session from; set by the replay command.
+	ReplayPath string `json:"-"`
+	// ResumeLast resumes the session from the most recent checkpoint, e.g. after a crash.
+	ResumeLast bool `json:"-"`
+	// Batch configures a batch run; set by the run command.
+	Batch batchOptions `json:"-"`
+	// Serve configures the server; set by the serve command.
+	Serve serveOptions `json:"-"`
+	// Scenario configures a scenario run; set by the scenario command.
+	Scenario scenarioOptions `json:"-"`
 }
 
+const (
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
+)
+
 type UserInterface string
 
 const (
@@ -144,6 +392,16 @@ var defaultToolConfigPaths = []string{
 	filepath.Join("{HOME}", ".config", "kubectl-ai", "tools.yaml"),
 }
 
+var defaultHealthRulesPaths = []string{
+	filepath.Join("{CONFIG}", "kubectl-ai", "health-rules.yaml"),
+	filepath.Join("{HOME}", ".config", "kubectl-ai", "health-rules.yaml"),
+}
+
+var defaultRecipesPaths = []string{
+	filepath.Join("{CONFIG}", "kubectl-ai", "recipes.yaml"),
+	filepath.Join("{HOME}", ".config", "kubectl-ai", "recipes.yaml"),
+}
+
 var defaultConfigPaths = []string{
 	filepath.Join("{CONFIG}", "kubectl-ai", "config.yaml"),
 	filepath.Join("{HOME}", ".config", "kubectl-ai", "config.yaml"),
@@ -159,22 +417,85 @@ func (o *Options) InitDefaults() {
 	// We now default to our strongest model (gemini-2.5-pro-exp-03-25) which supports tool use natively.
 	// so we don't need shim.
 	o.EnableToolUseShim = false
+	o.ShimCorrectionAttempts = 3
 	o.Quiet = false
+	o.Files = nil
+	o.MaxAttachmentBytes = 100000
 	o.MCPServer = false
 	o.MaxIterations = 20
+	o.IterationExtension = 10
+	o.AutoExtendIterations = 0
+	o.MaxRepeatedToolCalls = 3
+	o.MaxToolErrors = 3
+	o.SummarizeToolOutput = 50000
+	o.OnLoop = string(agent.LoopActionCorrect)
+	o.RoundTimeout = 0
+	o.ToolTimeout = 5 * time.Minute
+	o.ShareApprovalTimeout = 5 * time.Minute
 	o.KubeConfigPath = ""
 	o.PromptTemplateFilePath = ""
 	o.ExtraPromptPaths = []string{}
 	o.TracePath = filepath.Join(os.TempDir(), "kubectl-ai-trace.txt")
 	o.RemoveWorkDir = false
+	o.WorkDirRetention = string(agent.WorkDirKeep)
+	o.WorkDirTTL = 0
 	o.ToolConfigPaths = defaultToolConfigPaths
+	o.ContextWindow = 0
+	o.ContextWarningThresholds = []int{80, 95}
+	o.ShowContextUsage = true
+	o.ClusterMetadata = true
+	o.StablePrompt = false
+	o.SlimToolSchemas = false
+	o.HealthRulesPaths = defaultHealthRulesPaths
+	o.RecipesPaths = defaultRecipesPaths
+	o.PacksDir = filepath.Join("{CONFIG}", "kubectl-ai", "packs")
 	// Default to terminal UI
 	o.UserInterface = UserInterfaceTerminal
+	o.InputKeymap = string(ui.KeymapEmacs)
+	o.Notifications = string(ui.NotificationsOff)
+	o.NotifyAfter = 30 * time.Second
+	o.TimeFormat = string(ui.TimeStyleAbsolute)
+	o.TimeZone = "local"
 	// Default UI listen address for HTML UI
 	o.UIListenAddress = "localhost:8888"
+	o.ExportPath = ""
+	o.SessionsDir = ""
+	o.ApprovalsPath = filepath.Join("{CONFIG}", "kubectl-ai", "approvals.json")
+	o.WorkspacesDir = filepath.Join("{CONFIG}", "kubectl-ai", "workspaces")
+	o.Memory = false
+	o.MemoryPath = filepath.Join("{CONFIG}", "kubectl-ai", "memories.json")
+	o.EmbeddingModel = ""
+	o.InventoryTTL = 2 * time.Minute
 
 	// Default to not skipping SSL verification
 	o.SkipVerifySSL = false
+
+	o.LLMRetryMaxAttempts = 3
+	o.LLMRetryInitialBackoff = 10 * time.Second
+	o.LLMRetryMaxBackoff = 60 * time.Second
+	o.LLMRetryJitter = true
+	o.FallbackModelID = ""
+
+	// Best-of-N sampling is disabled by default, as it costs extra LLM requests.
+	o.MutationCandidates = 1
+	o.CandidateSelection = string(agent.CandidateSelectionVerifier)
+
+	o.VerifyMutations = true
+	o.Assertions = true
+	o.SuggestAlternatives = true
+	o.MonitorWindow = 0
+	o.HighlightAnomalies = true
+	o.RestrictBashWrites = true
+	o.Language = ""
+	o.AnswerStyle = ""
+	o.StructuredAnswer = false
+	o.SuggestFollowUps = false
+	o.FanOutParallelism = 4
+	o.AttributionAnnotations = true
+	o.AutoCleanup = false
+	o.OutputFormat = OutputFormatText
+	o.StreamOutput = false
+	o.AskFeedback = false
 }
 
 func (o *Options) LoadConfiguration(b []byte) error {
@@ -187,25 +508,11 @@ func (o *Options) LoadConfiguration(b []byte) error {
 func (o *Options) LoadConfigurationFile() error {
 	configPaths := defaultConfigPaths
 	for _, configPath := range configPaths {
-		pathWithPlaceholdersExpanded := configPath
-
-		if strings.Contains(pathWithPlaceholdersExpanded, "{CONFIG}") {
-			configDir, err := os.UserConfigDir()
-			if err != nil {
-				return fmt.Errorf("getting user config directory (for config file path %q): %w", configPath, err)
-			}
-			pathWithPlaceholdersExpanded = strings.ReplaceAll(pathWithPlaceholdersExpanded, "{CONFIG}", configDir)
-		}
-
-		if strings.Contains(pathWithPlaceholdersExpanded, "{HOME}") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				return fmt.Errorf("getting user home directory (for config file path %q): %w", configPath, err)
-			}
-			pathWithPlaceholdersExpanded = strings.ReplaceAll(pathWithPlaceholdersExpanded, "{HOME}", homeDir)
+		expanded, err := expandPathPlaceholders(configPath)
+		if err != nil {
+			return fmt.Errorf("%w (for config file path %q)", err, configPath)
 		}
-
-		configPath = filepath.Clean(pathWithPlaceholdersExpanded)
+		configPath = expanded
 		configBytes, err := os.ReadFile(configPath)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -223,6 +530,9 @@ func (o *Options) LoadConfigurationFile() error {
 	return nil
 }
 
+// processStarted is when kubectl-ai started, to measure how long it takes to be ready for input.
+var processStarted = time.Now()
+
 func main() {
 	ctx := context.Background()
 
@@ -230,10 +540,15 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 
 	go func() {
-		sig := <-sigCh
-		klog.Flush()
-		fmt.Fprintf(os.Stderr, "Received signal, shutting down... %s\n", sig)
-		os.Exit(0)
+		for sig := range sigCh {
+			// The first Ctrl+C stops a task that created resources, so that they can be cleaned up.
+			if sig == syscall.SIGINT && interruptRound() {
+				continue
+			}
+			klog.Flush()
+			fmt.Fprintf(os.Stderr, "Received signal, shutting down... %s\n", sig)
+			os.Exit(0)
+		}
 	}()
 
 	if err := run(ctx); err != nil {
@@ -285,35 +600,270 @@ func run(ctx context.Context) error {
 
 func (opt *Options) bindCLIFlags(f *pflag.FlagSet) error {
 	f.IntVar(&opt.MaxIterations, "max-iterations", opt.MaxIterations, "maximum number of iterations agent will try before giving up")
+	f.IntVar(&opt.IterationExtension, "iteration-extension", opt.IterationExtension, "when --max-iterations is reached while the agent is still making progress, ask whether to continue for this many more iterations (0 to always stop)")
+	f.IntVar(&opt.AutoExtendIterations, "auto-extend-iterations", opt.AutoExtendIterations, "in non-interactive runs (--output json, batch, serve), how many iterations beyond --max-iterations the agent may take on its own while it is making progress, in steps of --iteration-extension")
+	f.IntVar(&opt.MaxRepeatedToolCalls, "max-repeated-tool-calls", opt.MaxRepeatedToolCalls, "how many times the agent can make the same tool call with the same arguments while answering a query, before being told to try something else (0 for no limit)")
+	f.IntVar(&opt.SummarizeToolOutput, "summarize-tool-output", opt.SummarizeToolOutput, "size in bytes above which the output of a command is summarized by the LLM before it is added to the conversation, keeping the lines that report errors (0 to never summarize)")
+	f.IntVar(&opt.MaxToolErrors, "max-tool-errors", opt.MaxToolErrors, "how many tool calls in a row can fail (e.g. with invalid arguments) before the agent stops; failures are reported to the model so it can correct the call (0 for no limit)")
+	f.StringVar(&opt.OnLoop, "on-loop", opt.OnLoop, "what to do when the model repeats a call more than --max-repeated-tool-calls times, or alternates between two changes: correct (tell it to try something else, and stop if it keeps looping) or ask")
+	f.IntVar(&opt.MaxToolCalls, "max-tool-calls", opt.MaxToolCalls, "quota of tool calls in the session; when it is reached, the agent pauses until you allow more (0 means no limit)")
+	f.IntVar(&opt.MaxMutatingCalls, "max-mutating-calls", opt.MaxMutatingCalls, "quota of tool calls that modify resources in the session; when it is reached, the agent pauses until you allow more (0 means no limit)")
+	f.IntVar(&opt.MaxBashCalls, "max-bash-calls", opt.MaxBashCalls, "quota of bash commands in the session; when it is reached, the agent pauses until you allow more (0 means no limit)")
+	f.DurationVar(&opt.RoundTimeout, "round-timeout", opt.RoundTimeout, "maximum time to spend answering a single query (0 means no limit)")
+	f.DurationVar(&opt.ToolTimeout, "tool-timeout", opt.ToolTimeout, "maximum time a single tool invocation may run (0 means no limit)")
 	f.StringVar(&opt.KubeConfigPath, "kubeconfig", opt.KubeConfigPath, "path to kubeconfig file")
+	f.StringVar(&opt.Workspace, "workspace", opt.Workspace, "name of a workspace saved with kubectl-ai workspaces save, whose kube context, namespace, model, prompt profile, confirmation policy and MCP servers are used unless set by other flags")
+	f.StringVar(&opt.WorkspacesDir, "workspaces-dir", opt.WorkspacesDir, "directory where workspaces are saved")
 	f.StringVar(&opt.PromptTemplateFilePath, "prompt-template-file-path", opt.PromptTemplateFilePath, "path to custom prompt template file")
 	f.StringArrayVar(&opt.ExtraPromptPaths, "extra-prompt-paths", opt.ExtraPromptPaths, "extra prompt template paths")
+	f.StringVar(&opt.PromptProfile, "prompt-profile", opt.PromptProfile, "variant of the system prompt to use: the name of a profile in promptProfiles in the config file")
 	f.StringVar(&opt.TracePath, "trace-path", opt.TracePath, "path to the trace file")
-	f.BoolVar(&opt.RemoveWorkDir, "remove-workdir", opt.RemoveWorkDir, "remove the temporary working directory after execution")
+	f.BoolVar(&opt.RemoveWorkDir, "remove-workdir", opt.RemoveWorkDir, "remove the temporary working directory after execution (deprecated: use --workdir-retention=remove)")
+	f.StringVar(&opt.WorkDirRetention, "workdir-retention", opt.WorkDirRetention, "whether the temporary working directory is kept when the session ends: keep, keep-on-error (only if a query failed) or remove; see 'kubectl-ai workdirs'")
+	f.DurationVar(&opt.WorkDirTTL, "workdir-ttl", opt.WorkDirTTL, "remove the working directories of sessions not modified for this long at startup (0 means never)")
+	f.BoolVar(&opt.ResumeLast, "resume-last", opt.ResumeLast, "resume the most recent session from the checkpoint written to its working directory after every iteration, e.g. after a crash")
 
 	f.StringVar(&opt.ProviderID, "llm-provider", opt.ProviderID, "language model provider")
 	f.StringVar(&opt.ModelID, "model", opt.ModelID, "language model e.g. gemini-2.0-flash-thinking-exp-01-21, gemini-2.0-flash")
+	f.StringVar(&opt.Executor, "executor", opt.Executor, "where to run the commands of the kubectl and bash tools: local, ssh:<target>, pod:[<namespace>/]<pod>[/<container>], docker:<container> or docker-run:<image>")
+	f.StringVar(&opt.SSHTarget, "ssh-target", opt.SSHTarget, "run kubectl and bash commands over SSH on this host, e.g. a bastion: the name of a target in sshTargets in the config file, or [user@]host[:port]; short for --executor ssh:<target>")
+	f.BoolVar(&opt.RestrictEgress, "restrict-egress", opt.RestrictEgress, "only let the commands run by tools reach local hosts, the API servers in the kubeconfig and --egress-allow hosts over HTTP and HTTPS, through a filtering proxy set with HTTP_PROXY and HTTPS_PROXY")
+	f.StringArrayVar(&opt.EgressAllowedHosts, "egress-allow", opt.EgressAllowedHosts, "host that commands may reach with --restrict-egress, e.g. charts.example.com or *.example.com")
+	f.BoolVar(&opt.RestrictBashWrites, "restrict-bash-writes", opt.RestrictBashWrites, "don't run bash commands that write files outside the agent's working directory and --bash-write-paths (e.g. with > or rm), so they cannot change files in your home directory")
+	f.StringArrayVar(&opt.BashWritePaths, "bash-write-paths", opt.BashWritePaths, "file or directory, besides the working directory, that bash commands may write to with --restrict-bash-writes")
+	f.BoolVar(&opt.Offline, "offline", opt.Offline, "air-gapped mode: only allow local LLM providers (ollama, llamacpp, or an openai-compatible endpoint such as vLLM) and MCP servers, and don't run commands that reach the network outside the cluster")
+	f.BoolVar(&opt.Simulate, "simulate", opt.Simulate, "rehearse: run kubectl commands that modify resources as server-side dry runs, and don't run other tool calls that modify resources")
 	f.BoolVar(&opt.SkipPermissions, "skip-permissions", opt.SkipPermissions, "(dangerous) skip asking for confirmation before executing kubectl commands that modify resources")
 	f.BoolVar(&opt.MCPServer, "mcp-server", opt.MCPServer, "run in MCP server mode")
 	f.StringArrayVar(&opt.ToolConfigPaths, "custom-tools-config", opt.ToolConfigPaths, "path to custom tools config file or directory")
+	f.StringArrayVar(&opt.HealthRulesPaths, "health-rules-config", opt.HealthRulesPaths, "path to custom resource health rules file or directory")
+	f.StringArrayVar(&opt.RecipesPaths, "recipes-config", opt.RecipesPaths, "path to recipes file or directory")
+	f.StringVar(&opt.DeployMarkersPath, "deploy-markers", opt.DeployMarkersPath, "path to a YAML or JSON list of deploy markers (time, description and optional namespace), e.g. appended to by your CD pipeline, that the incident_timeline tool merges with events, rollouts and node changes")
+	f.StringArrayVar(&opt.IaCStatePaths, "iac-state", opt.IaCStatePaths, "path to rendered IaC state (the output of terraform show -json or pulumi stack export); enables the check_iac_drift tool, which compares the resources it declares with the cluster")
+	f.StringVar(&opt.PacksDir, "packs-dir", opt.PacksDir, "directory where packs of prompts and recipes are installed by kubectl-ai pack install; their prompts and recipes are loaded at startup")
 	f.BoolVar(&opt.MCPClient, "mcp-client", opt.MCPClient, "enable MCP client mode to connect to external MCP servers")
+	f.StringSliceVar(&opt.MCPServers, "mcp-servers", opt.MCPServers, "comma-separated names of the MCP servers to connect to in MCP client mode; empty connects to all the configured servers")
 	f.BoolVar(&opt.EnableToolUseShim, "enable-tool-use-shim", opt.EnableToolUseShim, "enable tool use shim")
+	f.IntVar(&opt.ShimCorrectionAttempts, "shim-correction-attempts", opt.ShimCorrectionAttempts, "with the tool use shim, how many times in a row to ask the model to correct a response that is not valid JSON")
 	f.BoolVar(&opt.Quiet, "quiet", opt.Quiet, "run in non-interactive mode, requires a query to be provided as a positional argument")
+	f.StringArrayVar(&opt.Files, "file", opt.Files, "attach a file (e.g. a manifest) to the first query, so you can ask about it; can be repeated")
+	f.IntVar(&opt.MaxAttachmentBytes, "max-attachment-bytes", opt.MaxAttachmentBytes, "size in bytes above which the middle of an attached file or piped input is left out (0 for no limit)")
 
 	f.Var(&opt.UserInterface, "user-interface", "user interface mode to use. Supported values: terminal, html.")
+	f.StringVar(&opt.InputKeymap, "input-keymap", opt.InputKeymap, "line-editing keybindings for the terminal input prompt. Supported values: emacs, vi.")
+	f.StringVar(&opt.Notifications, "notifications", opt.Notifications, "how to notify you when a long round finishes or an approval is waiting. Supported values: off, desktop, terminal.")
+	f.DurationVar(&opt.NotifyAfter, "notify-after", opt.NotifyAfter, "only notify if the agent has been working for at least this long")
+	f.StringVar(&opt.TimeFormat, "time-format", opt.TimeFormat, "how timestamps are shown in the UI, journal replay and exports. Supported values: absolute, relative.")
+	f.StringVar(&opt.TimeZone, "timezone", opt.TimeZone, "time zone for timestamps: local, UTC or an IANA time zone name such as Europe/Paris")
 	f.StringVar(&opt.UIListenAddress, "ui-listen-address", opt.UIListenAddress, "address to listen for the HTML UI.")
+	f.StringVar(&opt.ShareAddress, "share-address", opt.ShareAddress, "share the session on this address (e.g. localhost:8889), so others can attach to it from a browser as viewers or approvers with the links printed at startup; empty disables sharing")
+	f.BoolVar(&opt.ShareRequireApproval, "share-require-approval", opt.ShareRequireApproval, "with --share-address, commands that modify resources also wait until an approver of the shared session approves them")
+	f.DurationVar(&opt.ShareApprovalTimeout, "share-approval-timeout", opt.ShareApprovalTimeout, "with --share-require-approval, how long a command waits for an approver before it is rejected")
+	f.StringVar(&opt.HandoffWebhook, "handoff-webhook", opt.HandoffWebhook, "URL of an incoming webhook (Slack, Microsoft Teams, Google Chat, or an incident tool accepting {\"text\": ...}) that /handoff also posts the escalation document to")
+	f.StringVar(&opt.ExportPath, "export-path", opt.ExportPath, "export the conversation to this path when the session ends (.html for HTML, Markdown otherwise)")
+	f.StringVar(&opt.SessionsDir, "sessions-dir", opt.SessionsDir, "directory to store the transcripts of interactive sessions in, for kubectl-ai sessions grep; empty (the default) disables storing them, as they include tool output that may contain secrets")
+	f.StringVar(&opt.ApprovalsPath, "approvals-file", opt.ApprovalsPath, "file to store the commands you chose not to be asked about again, for later sessions and kubectl-ai approvals; empty keeps them for the session only")
+	f.BoolVar(&opt.Memory, "memory", opt.Memory, "let the model remember durable facts (cluster quirks, naming conventions, past incidents) in --memory-file, and recall the relevant ones in later sessions")
+	f.StringVar(&opt.MemoryPath, "memory-file", opt.MemoryPath, "file to store the memories in, for later sessions and kubectl-ai memories")
+	f.DurationVar(&opt.InventoryTTL, "inventory-ttl", opt.InventoryTTL, "how long the names of the resources of a namespace are cached to resolve references such as \"the payments deployment\" in queries to exact names; 0 disables it")
+	f.StringVar(&opt.EmbeddingModel, "embedding-model", opt.EmbeddingModel, "model used to find the memories relevant to a query (gemini and openai providers); empty uses the provider's default. With other providers, memories are matched by the words they share.")
 	f.BoolVar(&opt.SkipVerifySSL, "skip-verify-ssl", opt.SkipVerifySSL, "skip verifying the SSL certificate of the LLM provider")
 
+	f.IntVar(&opt.LLMRetryMaxAttempts, "llm-retry-max-attempts", opt.LLMRetryMaxAttempts, "maximum number of attempts for each LLM request")
+	f.DurationVar(&opt.LLMRetryInitialBackoff, "llm-retry-initial-backoff", opt.LLMRetryInitialBackoff, "delay before retrying a failed LLM request")
+	f.DurationVar(&opt.LLMRetryMaxBackoff, "llm-retry-max-backoff", opt.LLMRetryMaxBackoff, "maximum delay between retries of a failed LLM request")
+	f.BoolVar(&opt.LLMRetryJitter, "llm-retry-jitter", opt.LLMRetryJitter, "add random jitter to the delay between LLM request retries")
+	f.StringVar(&opt.FallbackModelID, "fallback-model", opt.FallbackModelID, "model to fail over to once retries against --model are exhausted")
+
+	f.IntVar(&opt.ContextWindow, "context-window", opt.ContextWindow, "size of the model's context window in tokens (0 means use the known size for the model)")
+	f.IntSliceVar(&opt.ContextWarningThresholds, "context-warning-thresholds", opt.ContextWarningThresholds, "percentages of the context window at which to warn that the context is running out")
+	f.BoolVar(&opt.ShowContextUsage, "show-context-usage", opt.ShowContextUsage, "show how much of the context window is used after each answer")
+	f.IntVar(&opt.MaxTokens, "max-tokens", opt.MaxTokens, "stop before the session uses more than this many tokens (0 means no limit)")
+	f.Float64Var(&opt.MaxCost, "max-cost", opt.MaxCost, "stop before the estimated cost of the session exceeds this many US dollars (0 means no limit)")
+	f.Float64Var(&opt.InputTokenPrice, "input-token-price", opt.InputTokenPrice, "price of input tokens in US dollars per million tokens, to estimate the cost (0 means use the list price of the model)")
+	f.Float64Var(&opt.OutputTokenPrice, "output-token-price", opt.OutputTokenPrice, "price of output tokens in US dollars per million tokens, to estimate the cost (0 means use the list price of the model)")
+
+	f.BoolVar(&opt.ClusterMetadata, "cluster-metadata", opt.ClusterMetadata, "include facts about the target cluster (server version, API groups, node count, context) in the system prompt")
+	f.BoolVar(&opt.SlimToolSchemas, "slim-tool-schemas", opt.SlimToolSchemas, "cut the descriptions of the tools and their parameters to their first sentence, and collapse lists of values, to keep sessions with many tools (e.g. from MCP servers) within the providers' limits on the size of tool schemas; see kubectl-ai tools size")
+	f.BoolVar(&opt.StablePrompt, "stable-prompt", opt.StablePrompt, "canonicalize the system prompt and tool definitions, and send the cluster facts with the first query, so that providers can reuse their prompt cache across sessions")
+
+	f.IntVar(&opt.MutationCandidates, "mutation-candidates", opt.MutationCandidates, "number of candidate commands to sample from the LLM before running a command that modifies resources")
+	f.StringVar(&opt.CandidateSelection, "candidate-selection", opt.CandidateSelection, "how to choose between candidate commands. Supported values: verifier, user.")
+
+	f.BoolVar(&opt.VerifyMutations, "verify-mutations", opt.VerifyMutations, "after a command that modifies resources, run read-only commands (e.g. kubectl get, kubectl rollout status) to verify that the change took effect")
+	f.BoolVar(&opt.HighlightAnomalies, "highlight-anomalies", opt.HighlightAnomalies, "flag anomalous values in command output (high restart counts, OOMKilled, ImagePullBackOff, NotReady, pressure conditions), highlighting them for you and pointing the model at them")
+	f.DurationVar(&opt.MonitorWindow, "monitor-window", opt.MonitorWindow, "after a command that modifies resources, watch the namespaces it changed for this long for warning events, restarts and pods that cannot start, and propose a rollback if the change looks like it caused a regression (0 disables monitoring)")
+	f.BoolVar(&opt.SuggestAlternatives, "suggest-alternatives", opt.SuggestAlternatives, "when you decline a command, ask the model to propose a safer or read-only alternative instead of only telling it you declined")
+	f.BoolVar(&opt.Assertions, "assertions", opt.Assertions, "let the model add assertions to its plans, read-only checks of the cluster; when one fails, the rest of the plan is not run and the model makes a new plan")
+	f.BoolVar(&opt.AskFeedback, "ask-feedback", opt.AskFeedback, "ask for a 👍/👎 rating after each answer in interactive sessions, recorded in the trace file (also available as /feedback)")
+	f.StringVar(&opt.AnswerStyle, "answer-style", opt.AnswerStyle, "style of the answers: concise-ops, detailed-report, executive-summary or runbook-steps; exports follow it, e.g. leaving out command output for executive summaries. Empty is the default style")
+	f.StringVar(&opt.Language, "language", opt.Language, "language the model replies in, as a name or code (e.g. French, ja, pt-BR); kubectl commands and resource names stay in English. Empty lets it reply in the language of your query")
+	f.BoolVar(&opt.StructuredAnswer, "structured-answer", opt.StructuredAnswer, "ask the model for a structured final answer (summary, commands run, resources touched, follow-ups)")
+	f.BoolVar(&opt.SuggestFollowUps, "suggest-follow-ups", opt.SuggestFollowUps, "after each answer in interactive sessions, suggest 2 or 3 numbered follow-up queries; enter a number to ask one")
+	f.BoolVar(&opt.AttributionAnnotations, "attribution-annotations", opt.AttributionAnnotations, "annotate the objects the agent creates or modifies with the session ID, user, time and a hash of the query (kubectl-ai/*), so that list_session_resources can find them")
+	f.BoolVar(&opt.AutoCleanup, "auto-cleanup", opt.AutoCleanup, "when a task is aborted before it completes (e.g. with Ctrl+C, or when it times out), delete the resources it created without asking; otherwise you are asked in interactive sessions")
+	f.IntVar(&opt.FanOutParallelism, "fan-out-parallelism", opt.FanOutParallelism, "let the model run a sub-task in each of a list of namespaces or contexts, in parallel sessions, this many at a time. 0 disables fan-out.")
+	f.StringVar(&opt.OutputFormat, "output", opt.OutputFormat, "format of the final answer in quiet mode. Supported values: text, json. json runs headless, and prints the structured answer with a transcript of the conversation to stdout.")
+	f.BoolVar(&opt.StreamOutput, "stream-output", opt.StreamOutput, "in quiet mode with stdout piped, write the answer to stdout as plain text as it is generated, and tool calls and other messages to stderr")
+
 	return nil
 }
 
 func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 	var err error // Declare err once for the whole function
 
+	switch agent.CandidateSelection(opt.CandidateSelection) {
+	case agent.CandidateSelectionVerifier, agent.CandidateSelectionUser:
+	default:
+		return fmt.Errorf("invalid candidate selection %q, supported values: %s, %s", opt.CandidateSelection, agent.CandidateSelectionVerifier, agent.CandidateSelectionUser)
+	}
+
+	switch agent.LoopAction(opt.OnLoop) {
+	case agent.LoopActionCorrect, agent.LoopActionAsk:
+	default:
+		return fmt.Errorf("invalid loop action %q, supported values: %s, %s", opt.OnLoop, agent.LoopActionCorrect, agent.LoopActionAsk)
+	}
+
+	switch ui.Keymap(opt.InputKeymap) {
+	case ui.KeymapEmacs, ui.KeymapVi:
+	default:
+		return fmt.Errorf("invalid input keymap %q, supported values: %s, %s", opt.InputKeymap, ui.KeymapEmacs, ui.KeymapVi)
+	}
+
+	if opt.RemoveWorkDir {
+		opt.WorkDirRetention = string(agent.WorkDirRemove)
+	}
+	switch agent.WorkDirRetention(opt.WorkDirRetention) {
+	case agent.WorkDirKeep, agent.WorkDirKeepOnError, agent.WorkDirRemove:
+	default:
+		return fmt.Errorf("invalid working directory retention %q, supported values: %s, %s, %s", opt.WorkDirRetention, agent.WorkDirKeep, agent.WorkDirKeepOnError, agent.WorkDirRemove)
+	}
+	if opt.WorkDirTTL > 0 {
+		removed, err := agent.CleanWorkDirs(opt.WorkDirTTL)
+		if err != nil {
+			klog.Warningf("error removing expired working directories: %v", err)
+		}
+		if len(removed) > 0 {
+			klog.Infof("removed %d working directories older than %v", len(removed), opt.WorkDirTTL)
+		}
+	}
+
+	if opt.ShareRequireApproval && opt.ShareAddress == "" {
+		return fmt.Errorf("--share-require-approval requires --share-address")
+	}
+	if opt.ShareRequireApproval && opt.ShareApprovalTimeout <= 0 {
+		return fmt.Errorf("--share-approval-timeout must be positive")
+	}
+
+	switch ui.NotificationMode(opt.Notifications) {
+	case ui.NotificationsOff, ui.NotificationsDesktop, ui.NotificationsTerminal:
+	default:
+		return fmt.Errorf("invalid notifications mode %q, supported values: %s, %s, %s", opt.Notifications, ui.NotificationsOff, ui.NotificationsDesktop, ui.NotificationsTerminal)
+	}
+
+	timeFormat, err := ui.NewTimeFormat(ui.TimeStyle(opt.TimeFormat), opt.TimeZone)
+	if err != nil {
+		return err
+	}
+
+	if err := opt.ConfirmationPolicy.Validate(); err != nil {
+		return err
+	}
+
+	if err := opt.ArtifactVerification.Validate(); err != nil {
+		return fmt.Errorf("artifact verification: %w", err)
+	}
+	tools.SetArtifactPolicy(opt.ArtifactVerification)
+
+	if err := applyPromptProfile(&opt); err != nil {
+		return err
+	}
+	answerStyle, err := agent.ParseAnswerStyle(opt.AnswerStyle)
+	if err != nil {
+		return err
+	}
+	opt.AnswerStyle = string(answerStyle)
+
+	if opt.SSHTarget != "" {
+		if opt.Executor != "" && opt.Executor != "local" {
+			return fmt.Errorf("--ssh-target cannot be used with --executor %s", opt.Executor)
+		}
+		opt.Executor = "ssh:" + opt.SSHTarget
+	}
+	if opt.Offline {
+		if err := checkOffline(&opt); err != nil {
+			return err
+		}
+		ctx = tools.WithOffline(ctx)
+	}
+	if opt.RestrictBashWrites {
+		policy := &tools.FilesystemPolicy{}
+		for _, path := range opt.BashWritePaths {
+			expandedPath, err := expandPathPlaceholders(path)
+			if err != nil {
+				return fmt.Errorf("expanding bash write path %q: %w", path, err)
+			}
+			policy.AllowedPaths = append(policy.AllowedPaths, expandedPath)
+		}
+		ctx = tools.WithFilesystemPolicy(ctx, policy)
+	}
+
+	executor, err := tools.ParseExecutor(opt.Executor, opt.SSHTargets)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Running kubectl and bash commands with executor %q", opt.Executor)
+	ctx = tools.WithExecutor(ctx, executor)
+
+	switch opt.OutputFormat {
+	case OutputFormatText:
+	case OutputFormatJSON:
+		if !opt.Quiet {
+			return fmt.Errorf("--output %s requires --quiet", opt.OutputFormat)
+		}
+		if opt.StreamOutput {
+			return fmt.Errorf("--stream-output cannot be used with --output %s", opt.OutputFormat)
+		}
+		opt.StructuredAnswer = true
+	default:
+		return fmt.Errorf("invalid output format %q, supported values: %s, %s", opt.OutputFormat, OutputFormatText, OutputFormatJSON)
+	}
+
+	configuredPrice := gollm.TokenPrice{Input: opt.InputTokenPrice, Output: opt.OutputTokenPrice}
+	tokenPrice, err := resolveTokenPrice(configuredPrice, opt.ModelID, opt.MaxCost)
+	if err != nil {
+		return err
+	}
+
 	// resolve kubeconfig path with priority: flag/env > KUBECONFIG > default path
 	if err = resolveKubeConfigPath(&opt); err != nil {
 		return fmt.Errorf("failed to resolve kubeconfig path: %w", err)
 	}
+	if ws := opt.workspace; ws != nil && (ws.Context != "" || ws.Namespace != "") && !tools.UsesKubeconfig(executor) {
+		return fmt.Errorf("workspace %s: its kube context and namespace cannot be used with --executor %s, which uses a kubeconfig of its own; use local or docker-run:<image>", opt.Workspace, opt.Executor)
+	}
+	removeWorkspaceKubeconfig, err := applyWorkspaceKubeconfig(ctx, &opt)
+	if err != nil {
+		return fmt.Errorf("workspace %s: %w", opt.Workspace, err)
+	}
+	defer removeWorkspaceKubeconfig()
+
+	if opt.RestrictEgress {
+		if !tools.EnforcesEgress(executor) {
+			return fmt.Errorf("--restrict-egress cannot be used with --executor %s: its commands would not go through the egress proxy; use local or docker-run:<image>", opt.Executor)
+		}
+		proxy, err := startEgressProxy(&opt)
+		if err != nil {
+			return err
+		}
+		defer proxy.Close()
+		ctx = tools.WithEgressProxy(ctx, proxy)
+	}
+
+	// Custom tools are also served in MCP server mode, as listed by kubectl-ai tools export.
+	if err := handleCustomTools(opt.ToolConfigPaths); err != nil {
+		return fmt.Errorf("failed to process custom tools: %w", err)
+	}
 
 	if opt.MCPServer {
 		if err = startMCPServer(ctx, opt); err != nil {
@@ -322,39 +872,123 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 		return nil // MCP server mode blocks, so we return here
 	}
 
-	if err := handleCustomTools(opt.ToolConfigPaths); err != nil {
-		return fmt.Errorf("failed to process custom tools: %w", err)
+	if err := addPacks(&opt); err != nil {
+		return fmt.Errorf("failed to load installed packs: %w", err)
 	}
 
-	// Initialize MCP client if requested
-	var mcpManager *mcp.Manager
-	if opt.MCPClient {
+	if err := handleHealthRules(opt.HealthRulesPaths); err != nil {
+		return fmt.Errorf("failed to process health rules: %w", err)
+	}
+
+	if err := handleRecipes(opt.RecipesPaths); err != nil {
+		return fmt.Errorf("failed to process recipes: %w", err)
+	}
+
+	if err := handleIaCState(opt.IaCStatePaths); err != nil {
+		return fmt.Errorf("failed to process IaC state: %w", err)
+	}
+
+	if opt.DeployMarkersPath != "" {
+		deployMarkersPath, err := expandPathPlaceholders(opt.DeployMarkersPath)
+		if err != nil {
+			return fmt.Errorf("expanding deploy markers path %q: %w", opt.DeployMarkersPath, err)
+		}
+		tools.SetDeployMarkersPath(deployMarkersPath)
+	}
+
+	var approvals *tools.Approvals
+	if opt.ApprovalsPath != "" {
+		approvalsPath, err := expandPathPlaceholders(opt.ApprovalsPath)
+		if err != nil {
+			return err
+		}
+		approvals, err = tools.LoadApprovals(approvalsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	var memories *tools.Memories
+	if opt.Memory {
 		var err error
-		mcpManager, err = InitializeMCPClient()
+		memories, err = loadMemories(&opt)
 		if err != nil {
-			klog.Errorf("Failed to initialize MCP client: %v", err)
-			os.Exit(1) // Fail fast instead of continuing with degraded functionality
-		} else {
-			klog.V(1).Info("MCP client initialization completed successfully")
+			return err
 		}
 	}
 
+	// Initialize MCP client if requested; it connects to the servers while the LLM client is created.
+	var mcpManager *mcp.Manager
+	var mcpErr error
+	mcpDone := make(chan struct{})
+	if opt.MCPClient {
+		go func() {
+			defer close(mcpDone)
+			started := time.Now()
+			mcpManager, mcpErr = InitializeMCPClient(opt.MCPServers)
+			klog.V(1).Infof("MCP client initialization took %v", time.Since(started))
+		}()
+	} else {
+		close(mcpDone)
+	}
+
 	// After reading stdin, it is consumed
 	var hasInputData bool
-	hasInputData, err = hasStdInData()
-	if err != nil {
-		return fmt.Errorf("failed to check if stdin has data: %w", err)
+	if opt.Batch.queriesFile == "" && opt.Serve.listenAddress == "" {
+		// Batch runs and the server take their queries from elsewhere, and leave stdin alone.
+		hasInputData, err = hasStdInData()
+		if err != nil {
+			return fmt.Errorf("failed to check if stdin has data: %w", err)
+		}
 	}
 
 	// Handles positional args or stdin
-	var queryFromCmd string
-	queryFromCmd, err = resolveQueryInput(hasInputData, args)
+	var queryFromCmd, pipedInput string
+	queryFromCmd, pipedInput, err = resolveQueryInput(hasInputData, args)
 	if err != nil {
 		return fmt.Errorf("failed to resolve query input %w", err)
 	}
+	attachments, err := readAttachments(opt.Files, opt.MaxAttachmentBytes)
+	if err != nil {
+		return err
+	}
+	if pipedInput != "" {
+		piped, err := newAttachment("the input piped to kubectl-ai", []byte(pipedInput), opt.MaxAttachmentBytes)
+		if err != nil {
+			return err
+		}
+		attachments = append(attachments, piped)
+	}
 
 	klog.Info("Application started", "pid", os.Getpid())
 
+	// Read the journal before creating the recorder, which might truncate the same file.
+	var replayHistory []*journal.HistoryEntry
+	if opt.ResumeLast {
+		if opt.ReplayPath != "" {
+			return fmt.Errorf("--resume-last cannot be used with replay")
+		}
+		checkpointPath, err := agent.LastCheckpoint()
+		if err != nil {
+			return fmt.Errorf("--resume-last: %w", err)
+		}
+		klog.Infof("Resuming from checkpoint %q", checkpointPath)
+		replayHistory, err = agent.LoadCheckpoint(checkpointPath)
+		if err != nil {
+			return err
+		}
+	}
+	if opt.ReplayPath != "" {
+		events, err := journal.ParseEventsFromFile(opt.ReplayPath)
+		if err != nil {
+			return fmt.Errorf("reading journal: %w", err)
+		}
+		replayHistory, err = journal.ReconstructHistory(events)
+		if err != nil {
+			return fmt.Errorf("reconstructing history from journal %q: %w", opt.ReplayPath, err)
+		}
+	}
+
 	var llmClient gollm.Client
 	if opt.SkipVerifySSL {
 		llmClient, err = gollm.NewClient(ctx, opt.ProviderID, gollm.WithSkipVerifySSL())
@@ -366,6 +1000,14 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 	}
 	defer llmClient.Close()
 
+	<-mcpDone
+	if mcpErr != nil {
+		klog.Errorf("Failed to initialize MCP client: %v", mcpErr)
+		os.Exit(1) // Fail fast instead of continuing with degraded functionality
+	} else if opt.MCPClient {
+		klog.V(1).Info("MCP client initialization completed successfully")
+	}
+
 	var recorder journal.Recorder
 	if opt.TracePath != "" {
 		var fileRecorder journal.Recorder
@@ -381,22 +1023,66 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 		defer recorder.Close()
 	}
 
+	if opt.Batch.queriesFile != "" {
+		// Tasks are independent, so each gets a new conversation; no one can confirm tool calls.
+		opt.StructuredAnswer = true
+		opt.ShowContextUsage = false
+		return runBatch(ctx, opt.Batch, timeFormat, func() *agent.Agent {
+			conversation := newConversation(&opt, llmClient, recorder, approvals, memories, tokenPrice)
+			conversation.NonInteractive = true
+			return conversation
+		})
+	}
+
+	if opt.Serve.listenAddress != "" {
+		// Each request gets a new conversation; no one can confirm tool calls.
+		opt.StructuredAnswer = true
+		opt.ShowContextUsage = false
+		return runServer(ctx, opt.Serve, timeFormat, func() *agent.Agent {
+			conversation := newConversation(&opt, llmClient, recorder, approvals, memories, tokenPrice)
+			conversation.NonInteractive = true
+			return conversation
+		})
+	}
+
+	// With JSON output, stdout is reserved for the JSON transcript; anything else written to stdout goes to stderr.
+	answerOutput := os.Stdout
+	if opt.OutputFormat == OutputFormatJSON {
+		os.Stdout = os.Stderr
+		defer func() { os.Stdout = answerOutput }()
+	}
+
 	doc := ui.NewDocument()
+	doc.SetTimeFormat(timeFormat)
+
+	if opt.OutputFormat != OutputFormatJSON {
+		// The notifier must be subscribed before the UI, which blocks while reading input.
+		notifications := doc.AddSubscription(ui.NewNotifier(ui.NotificationMode(opt.Notifications), opt.NotifyAfter))
+		defer notifications.Close()
+	}
 
 	var userInterface ui.UI
-	switch opt.UserInterface {
-	case UserInterfaceTerminal:
+	switch {
+	case opt.OutputFormat == OutputFormatJSON:
+		// Headless: the conversation is written as a JSON transcript instead of being rendered.
+		userInterface = &ui.HeadlessUI{}
+
+	case opt.UserInterface == UserInterfaceTerminal:
 		// since stdin is already consumed, we use TTY for taking input from user
 		useTTYForInput := hasInputData
 
-		var u ui.UI
-		u, err = ui.NewTerminalUI(doc, recorder, useTTYForInput)
+		var u *ui.TerminalUI
+		u, err = ui.NewTerminalUI(doc, recorder, useTTYForInput, ui.Keymap(opt.InputKeymap))
 		if err != nil {
 			return err
 		}
+		defer u.Close()
+		if opt.StreamOutput && opt.Quiet && !isTerminal(os.Stdout) {
+			u.StreamAnswers()
+		}
 		userInterface = u
 
-	case UserInterfaceHTML:
+	case opt.UserInterface == UserInterfaceHTML:
 		var u ui.UI
 		u, err = html.NewHTMLUserInterface(doc, opt.UIListenAddress, recorder)
 		if err != nil {
@@ -416,19 +1102,16 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 		return fmt.Errorf("user-interface mode %q is not known", opt.UserInterface)
 	}
 
-	conversation := &agent.Conversation{
-		Model:              opt.ModelID,
-		Kubeconfig:         opt.KubeConfigPath,
-		LLM:                llmClient,
-		MaxIterations:      opt.MaxIterations,
-		PromptTemplateFile: opt.PromptTemplateFilePath,
-		ExtraPromptPaths:   opt.ExtraPromptPaths,
-		Tools:              tools.Default(),
-		Recorder:           recorder,
-		RemoveWorkDir:      opt.RemoveWorkDir,
-		SkipPermissions:    opt.SkipPermissions,
-		EnableToolUseShim:  opt.EnableToolUseShim,
-		MCPClientEnabled:   opt.MCPClient,
+	conversation := newConversation(&opt, llmClient, recorder, approvals, memories, tokenPrice)
+	// Without a UI, no one can confirm tool calls.
+	conversation.NonInteractive = opt.OutputFormat == OutputFormatJSON
+
+	if opt.ShareAddress != "" {
+		shared, err := shareSession(ctx, &opt, doc, conversation)
+		if err != nil {
+			return err
+		}
+		defer shared.Close()
 	}
 
 	err = conversation.Init(ctx, doc)
@@ -437,13 +1120,49 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 	}
 	defer conversation.Close()
 
+	for _, attachment := range attachments {
+		conversation.Attach(attachment.source, attachment.content)
+	}
+
+	if opt.ReplayPath != "" {
+		if err := conversation.Resume(ctx, replayHistory); err != nil {
+			return fmt.Errorf("resuming from journal %q: %w", opt.ReplayPath, err)
+		}
+	} else if opt.ResumeLast {
+		if err := conversation.Resume(ctx, replayHistory); err != nil {
+			return fmt.Errorf("resuming from the last checkpoint: %w", err)
+		}
+	}
+
 	chatSession := session{
-		model:        opt.ModelID,
-		doc:          doc,
-		ui:           userInterface,
-		conversation: conversation,
-		LLM:          llmClient,
-		mcpManager:   mcpManager,
+		model:          opt.ModelID,
+		doc:            doc,
+		ui:             userInterface,
+		conversation:   conversation,
+		LLM:            llmClient,
+		mcpManager:     mcpManager,
+		exportPath:     opt.ExportPath,
+		handoffWebhook: opt.HandoffWebhook,
+		contextWindow:  opt.ContextWindow,
+		tokenPrice:     configuredPrice,
+		askFeedback:    opt.AskFeedback,
+	}
+
+	if opt.ExportPath != "" {
+		defer func() {
+			if err := chatSession.exportDocument(opt.ExportPath); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: could not export conversation: %v\n", err)
+			}
+		}()
+	}
+
+	if opt.SessionsDir != "" && !opt.Quiet {
+		started := time.Now()
+		defer func() {
+			if err := chatSession.saveTranscript(opt.SessionsDir, started); err != nil {
+				klog.Warningf("Failed to store the session transcript: %v", err)
+			}
+		}()
 	}
 
 	// Prepare MCP server status blocks only when MCP client is enabled
@@ -460,40 +1179,191 @@ func RunRootCommand(ctx context.Context, opt Options, args []string) error {
 		}
 	}
 
+	var startupBlocks []ui.Block
+	if opt.Offline {
+		startupBlocks = append(startupBlocks, ui.NewAgentTextBlock().WithText("Offline mode is on: only local LLM providers and MCP servers are used, and commands that reach the network outside the cluster are not run."))
+	}
+	if opt.Simulate {
+		startupBlocks = append(startupBlocks, ui.NewAgentTextBlock().WithText("Simulate mode is on: commands that modify resources are dry-run, and nothing in the cluster will be changed."))
+	}
+	for _, attachment := range attachments {
+		startupBlocks = append(startupBlocks, ui.NewAgentTextBlock().WithText(fmt.Sprintf("Attached %s (%d bytes) to the first query.", attachment.source, attachment.size)))
+	}
+	startupBlocks = append(startupBlocks, mcpBlocks...)
+
+	if opt.Scenario.scenario != nil {
+		for _, block := range startupBlocks {
+			doc.AddBlock(block)
+		}
+		_, err := conversation.RunScenario(ctx, opt.Scenario.scenario)
+		if opt.OutputFormat == OutputFormatJSON {
+			if writeErr := writeTranscriptJSON(answerOutput, opt.Scenario.scenario.Name, doc, nil, err); writeErr != nil {
+				return writeErr
+			}
+		}
+		return err
+	}
+
 	if opt.Quiet {
 		if queryFromCmd == "" {
 			return fmt.Errorf("quiet mode requires a query to be provided as a positional argument")
 		}
-		return chatSession.answerQuery(ctx, queryFromCmd)
+		if opt.OutputFormat == OutputFormatJSON {
+			// The query is recorded as the user's input, so that it is in the transcript.
+			input := ui.NewInputTextBlock()
+			doc.AddBlock(input)
+			input.Observable().Set(queryFromCmd, nil)
+		}
+		klog.Infof("Ready to answer the query %v after starting", time.Since(processStarted))
+		err := chatSession.answerQuery(ctx, queryFromCmd)
+		if errors.Is(err, errExitSession) {
+			err = nil
+		}
+		if opt.OutputFormat == OutputFormatJSON {
+			// The transcript is written even if the query failed, so programs can tell why.
+			if writeErr := writeTranscriptJSON(answerOutput, queryFromCmd, doc, conversation.LastAnswer(), err); writeErr != nil {
+				return writeErr
+			}
+		}
+		// Exit with an error also when the agent stopped (e.g. for the budget), so scripts can tell that the task was not completed.
+		return err
 	}
 
-	return chatSession.repl(ctx, queryFromCmd, mcpBlocks)
+	klog.Infof("Ready for input %v after starting", time.Since(processStarted))
+	return chatSession.repl(ctx, queryFromCmd, startupBlocks)
 }
 
-func handleCustomTools(toolConfigPaths []string) error {
-	// resolve tool config paths, and then load and register custom tools from config files and dirs
-	for _, path := range toolConfigPaths {
-		pathWithPlaceholdersExpanded := path
+// PromptProfile is a named variant of the system prompt.
+type PromptProfile struct {
+	// PromptTemplateFilePath replaces the prompt template, if set.
+	PromptTemplateFilePath string `json:"promptTemplateFilePath,omitempty"`
+	// ExtraPromptPaths are added to the extra prompt templates.
+	ExtraPromptPaths []string `json:"extraPromptPaths,omitempty"`
+	// AnswerStyle is the answer style of the profile, unless --answer-style is set.
+	AnswerStyle string `json:"answerStyle,omitempty"`
+}
 
-		if strings.Contains(pathWithPlaceholdersExpanded, "{CONFIG}") {
-			configDir, err := os.UserConfigDir()
-			if err != nil {
-				klog.Warningf("Failed to get user config directory for tools path %q: %v", path, err)
-				continue
-			}
-			pathWithPlaceholdersExpanded = strings.ReplaceAll(pathWithPlaceholdersExpanded, "{CONFIG}", configDir)
+// applyPromptProfile configures the system prompt of the profile selected by --prompt-profile.
+// The profile "default" is the prompt configured without a profile, unless promptProfiles defines it.
+func applyPromptProfile(opt *Options) error {
+	if opt.PromptProfile == "" {
+		return nil
+	}
+	profile, ok := opt.PromptProfiles[opt.PromptProfile]
+	if !ok {
+		if opt.PromptProfile == defaultPromptProfile {
+			return nil
 		}
-
-		if strings.Contains(pathWithPlaceholdersExpanded, "{HOME}") {
-			homeDir, err := os.UserHomeDir()
-			if err != nil {
-				klog.Warningf("Failed to get user home directory for tools path %q: %v", path, err)
-				continue
-			}
-			pathWithPlaceholdersExpanded = strings.ReplaceAll(pathWithPlaceholdersExpanded, "{HOME}", homeDir)
+		names := []string{defaultPromptProfile}
+		for name := range opt.PromptProfiles {
+			names = append(names, name)
 		}
+		slices.Sort(names)
+		return fmt.Errorf("unknown prompt profile %q, available profiles: %s", opt.PromptProfile, strings.Join(names, ", "))
+	}
+	if profile.PromptTemplateFilePath != "" {
+		opt.PromptTemplateFilePath = profile.PromptTemplateFilePath
+	}
+	opt.ExtraPromptPaths = append(opt.ExtraPromptPaths, profile.ExtraPromptPaths...)
+	if opt.AnswerStyle == "" {
+		opt.AnswerStyle = profile.AnswerStyle
+	}
+	klog.Infof("Using prompt profile %q", opt.PromptProfile)
+	return nil
+}
+
+// defaultPromptProfile is the name of the prompt configured without a profile.
+const defaultPromptProfile = "default"
+
+// newConversation creates a conversation with the LLM configured by opt.
+func newConversation(opt *Options, llmClient gollm.Client, recorder journal.Recorder, approvals *tools.Approvals, memories *tools.Memories, tokenPrice gollm.TokenPrice) *agent.Agent {
+	contextWindow := opt.ContextWindow
+	if contextWindow == 0 {
+		contextWindow, _ = gollm.DefaultContextWindow(opt.ModelID)
+	}
+
+	var inventory *tools.Inventory
+	if opt.InventoryTTL > 0 {
+		inventory = tools.NewInventory(opt.InventoryTTL)
+	}
+
+	return &agent.Agent{
+		Model:                opt.ModelID,
+		Kubeconfig:           opt.KubeConfigPath,
+		LLM:                  llmClient,
+		MaxIterations:        opt.MaxIterations,
+		IterationExtension:   opt.IterationExtension,
+		AutoExtendIterations: opt.AutoExtendIterations,
+		MaxRepeatedToolCalls: opt.MaxRepeatedToolCalls,
+		MaxToolErrors:        opt.MaxToolErrors,
+		SummarizeOutputBytes: opt.SummarizeToolOutput,
+		LoopAction:           agent.LoopAction(opt.OnLoop),
+		ToolQuotas: agent.ToolQuotas{
+			MaxToolCalls:     opt.MaxToolCalls,
+			MaxMutatingCalls: opt.MaxMutatingCalls,
+			MaxBashCalls:     opt.MaxBashCalls,
+		},
+		RoundTimeout:             opt.RoundTimeout,
+		ToolTimeout:              opt.ToolTimeout,
+		PromptTemplateFile:       opt.PromptTemplateFilePath,
+		ExtraPromptPaths:         opt.ExtraPromptPaths,
+		PromptProfile:            opt.PromptProfile,
+		Tools:                    tools.Default(),
+		Recorder:                 recorder,
+		WorkDirRetention:         agent.WorkDirRetention(opt.WorkDirRetention),
+		SkipPermissions:          opt.SkipPermissions,
+		ConfirmationPolicy:       &opt.ConfirmationPolicy,
+		Approvals:                approvals,
+		Simulate:                 opt.Simulate,
+		EnableToolUseShim:        opt.EnableToolUseShim,
+		ShimCorrectionAttempts:   opt.ShimCorrectionAttempts,
+		MCPClientEnabled:         opt.MCPClient,
+		FallbackModel:            opt.FallbackModelID,
+		MutationCandidates:       opt.MutationCandidates,
+		CandidateSelection:       agent.CandidateSelection(opt.CandidateSelection),
+		ContextWindow:            contextWindow,
+		ContextWarningThresholds: opt.ContextWarningThresholds,
+		ShowContextUsage:         opt.ShowContextUsage && !opt.Quiet,
+		MaxTokens:                opt.MaxTokens,
+		MaxCost:                  opt.MaxCost,
+		TokenPrice:               tokenPrice,
+		ClusterMetadata:          opt.ClusterMetadata,
+		StablePrompt:             opt.StablePrompt,
+		SlimToolSchemas:          opt.SlimToolSchemas,
+		StructuredAnswer:         opt.StructuredAnswer,
+		SuggestFollowUps:         opt.SuggestFollowUps && !opt.Quiet,
+		Language:                 opt.Language,
+		AnswerStyle:              agent.AnswerStyle(opt.AnswerStyle),
+		FanOutParallelism:        opt.FanOutParallelism,
+		AttributionAnnotations:   opt.AttributionAnnotations,
+		AutoCleanup:              opt.AutoCleanup,
+		User:                     currentUser(),
+		Memories:                 memories,
+		EmbeddingModel:           opt.EmbeddingModel,
+		Inventory:                inventory,
+		VerifyMutations:          opt.VerifyMutations,
+		Assertions:               opt.Assertions,
+		SuggestAlternatives:      opt.SuggestAlternatives,
+		MonitorWindow:            opt.MonitorWindow,
+		HighlightAnomalies:       opt.HighlightAnomalies,
+		RetryConfig: gollm.RetryConfig{
+			MaxAttempts:    opt.LLMRetryMaxAttempts,
+			InitialBackoff: opt.LLMRetryInitialBackoff,
+			MaxBackoff:     opt.LLMRetryMaxBackoff,
+			BackoffFactor:  2,
+			Jitter:         opt.LLMRetryJitter,
+		},
+	}
+}
 
-		cleanedPath := filepath.Clean(pathWithPlaceholdersExpanded)
+func handleCustomTools(toolConfigPaths []string) error {
+	// resolve tool config paths, and then load and register custom tools from config files and dirs
+	for _, path := range toolConfigPaths {
+		cleanedPath, err := expandPathPlaceholders(path)
+		if err != nil {
+			klog.Warningf("Failed to expand tools path %q: %v", path, err)
+			continue
+		}
 
 		klog.Infof("Attempting to load custom tools from processed path: %q (original value from config: %q)", cleanedPath, path)
 
@@ -509,15 +1379,164 @@ func handleCustomTools(toolConfigPaths []string) error {
 	return nil
 }
 
+func handleHealthRules(healthRulesPaths []string) error {
+	for _, path := range healthRulesPaths {
+		expandedPath, err := expandPathPlaceholders(path)
+		if err != nil {
+			klog.Warningf("Failed to expand health rules path %q: %v", path, err)
+			continue
+		}
+
+		klog.Infof("Attempting to load health rules from processed path: %q (original value from config: %q)", expandedPath, path)
+
+		if err := tools.LoadHealthRules(expandedPath); err != nil {
+			if errors.Is(err, os.ErrNotExist) && !slices.Contains(defaultHealthRulesPaths, path) {
+				return fmt.Errorf("health rules path not found (original value: %q, processed path: %q)", path, expandedPath)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func handleRecipes(recipesPaths []string) error {
+	for _, path := range recipesPaths {
+		expandedPath, err := expandPathPlaceholders(path)
+		if err != nil {
+			klog.Warningf("Failed to expand recipes path %q: %v", path, err)
+			continue
+		}
+
+		klog.Infof("Attempting to load recipes from processed path: %q (original value from config: %q)", expandedPath, path)
+
+		if err := tools.LoadRecipes(expandedPath); err != nil {
+			if errors.Is(err, os.ErrNotExist) && !slices.Contains(defaultRecipesPaths, path) {
+				return fmt.Errorf("recipes path not found (original value: %q, processed path: %q)", path, expandedPath)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func handleIaCState(iacStatePaths []string) error {
+	for _, path := range iacStatePaths {
+		expandedPath, err := expandPathPlaceholders(path)
+		if err != nil {
+			return fmt.Errorf("expanding IaC state path %q: %w", path, err)
+		}
+
+		klog.Infof("Loading IaC state from processed path: %q (original value from config: %q)", expandedPath, path)
+
+		if err := tools.LoadIaCState(expandedPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// currentUser returns the name of the user running kubectl-ai, recorded in the attribution annotations.
+func currentUser() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// expandPathPlaceholders replaces the {CONFIG} and {HOME} placeholders in a path.
+func expandPathPlaceholders(path string) (string, error) {
+	expanded := path
+	if strings.Contains(expanded, "{CONFIG}") {
+		configDir, err := os.UserConfigDir()
+		if err != nil {
+			return "", fmt.Errorf("getting user config directory: %w", err)
+		}
+		expanded = strings.ReplaceAll(expanded, "{CONFIG}", configDir)
+	}
+	if strings.Contains(expanded, "{HOME}") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("getting user home directory: %w", err)
+		}
+		expanded = strings.ReplaceAll(expanded, "{HOME}", homeDir)
+	}
+	return filepath.Clean(expanded), nil
+}
+
+// jsonOutput is the output of --output json: the final answer, with the transcript of the conversation.
+type jsonOutput struct {
+	agent.FinalAnswer
+	Query  string `json:"query"`
+	Status string `json:"status"`
+	// Error is why the query was not answered, or why the agent stopped before completing the task.
+	Error    string                 `json:"error,omitempty"`
+	Messages []ui.TranscriptMessage `json:"messages"`
+}
+
+// writeTranscriptJSON writes the answer to the query, and the transcript of the conversation, as JSON for scripting.
+// If the model did not give a structured answer, the summary is the last text it wrote.
+func writeTranscriptJSON(w io.Writer, query string, doc *ui.Document, answer *agent.FinalAnswer, queryErr error) error {
+	out := jsonOutput{
+		Query:    query,
+		Status:   taskSucceeded,
+		Messages: ui.Transcript(doc),
+	}
+	if answer != nil {
+		out.FinalAnswer = *answer
+	} else {
+		out.Summary = lastAgentText(doc)
+	}
+	if queryErr != nil {
+		out.Status = taskFailed
+		out.Error = queryErr.Error()
+	}
+	// Write empty lists rather than null, so scripts can iterate over them.
+	for _, list := range []*[]string{&out.CommandsRun, &out.ResourcesTouched, &out.FollowUps} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
 // session represents the user chat session (interactive/non-interactive both)
 type session struct {
 	model           string
 	ui              ui.UI
 	doc             *ui.Document
-	conversation    *agent.Conversation
+	conversation    *agent.Agent
 	availableModels []string
 	LLM             gollm.Client
 	mcpManager      *mcp.Manager
+	// exportPath is the default path used by /export
+	exportPath string
+	// handoffWebhook is where /handoff posts the handoff, if set.
+	handoffWebhook string
+	// contextWindow is the configured size of the context window; zero means the known size for the model.
+	contextWindow int
+	// tokenPrice is the configured price of tokens; zero means the list price of the model.
+	tokenPrice gollm.TokenPrice
+	// askFeedback asks the user to rate each answer.
+	askFeedback bool
+	// nextInput is the text the next input starts with, e.g. the query removed by /undo, to rephrase it.
+	nextInput string
+}
+
+// resolveTokenPrice returns the price of the model's tokens: the configured price if set, or the list price of the model.
+// A cost budget cannot be enforced without a price, so it is an error if maxCost is set and the price is unknown.
+func resolveTokenPrice(configured gollm.TokenPrice, model string, maxCost float64) (gollm.TokenPrice, error) {
+	if configured.Known() {
+		return configured, nil
+	}
+	price, ok := gollm.DefaultTokenPrice(model)
+	if !ok && maxCost > 0 {
+		return price, fmt.Errorf("--max-cost requires the price of model %q, which is not known; set --input-token-price and --output-token-price", model)
+	}
+	return price, nil
 }
 
 // repl is a read-eval-print loop for the chat session.
@@ -533,6 +1552,8 @@ func (s *session) repl(ctx context.Context, initialQuery string, initialBlocks [
 		if query == "" {
 			input := ui.NewInputTextBlock()
 			input.SetEditable(true)
+			input.SetInitialText(s.nextInput)
+			s.nextInput = ""
 			s.doc.AddBlock(input)
 
 			userInput, err := input.Observable().Wait()
@@ -545,26 +1566,27 @@ func (s *session) repl(ctx context.Context, initialQuery string, initialBlocks [
 				return fmt.Errorf("reading input: %w", err)
 			}
 			query = strings.TrimSpace(userInput)
+			if followUp, ok := s.conversation.FollowUp(query); ok {
+				s.doc.AddBlock(ui.NewAgentTextBlock().WithText("  Asking: " + followUp))
+				query = followUp
+			}
 		}
 
-		switch {
-		case query == "":
+		if query == "" {
 			continue
-		case query == "reset":
-			err := s.conversation.Init(ctx, s.doc)
-			if err != nil {
-				return err
+		}
+		if err := s.answerQuery(ctx, query); err != nil {
+			if errors.Is(err, errExitSession) || errors.Is(err, errInterrupted) {
+				return nil
 			}
-		case query == "clear":
-			s.ui.ClearScreen()
-		case query == "exit" || query == "quit":
-			// s.ui.RenderOutput(ctx, "Alright...bye.\n")
-			return nil
-		default:
-			if err := s.answerQuery(ctx, query); err != nil {
-				errorBlock := &ui.ErrorBlock{}
-				errorBlock.SetText(fmt.Sprintf("Error: %v\n", err))
-				s.doc.AddBlock(errorBlock)
+			errorBlock := &ui.ErrorBlock{}
+			errorBlock.SetText(fmt.Sprintf("Error: %v\n", err))
+			s.doc.AddBlock(errorBlock)
+		} else if _, _, isCommand := parseCommand(query); s.askFeedback && !isCommand {
+			if err := s.conversation.AskFeedback(ctx); err != nil {
+				// Feedback is optional: don't end the session, but stop asking.
+				s.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("Error: %v; not asking for feedback again\n", err)))
+				s.askFeedback = false
 			}
 		}
 		// Reset query to empty string so that we prompt for input again
@@ -572,6 +1594,46 @@ func (s *session) repl(ctx context.Context, initialQuery string, initialBlocks [
 	}
 }
 
+// expandBlock shows the folded output of the function call block with the given index,
+// or of the most recent folded block if no index is given.
+func (s *session) expandBlock(arg string) error {
+	blocks := s.doc.Blocks()
+	if arg == "" {
+		for i := len(blocks) - 1; i >= 0; i-- {
+			if block, ok := blocks[i].(*ui.FunctionCallRequestBlock); ok && block.Folded() {
+				block.SetExpanded(true)
+				return nil
+			}
+		}
+		return fmt.Errorf("there is no folded output to expand")
+	}
+
+	index, err := strconv.Atoi(arg)
+	if err != nil || index < 0 || index >= len(blocks) {
+		return fmt.Errorf("invalid block number %q", arg)
+	}
+	block, ok := blocks[index].(*ui.FunctionCallRequestBlock)
+	if !ok || block.Result() == nil {
+		return fmt.Errorf("block %d is not a tool output", index)
+	}
+	block.SetExpanded(true)
+	return nil
+}
+
+// exportDocument writes the conversation to p, as HTML or Markdown depending on the file extension.
+// The file is only readable by the user, as the conversation includes the output of tools, which may contain secrets.
+func (s *session) exportDocument(p string) error {
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("creating export file: %w", err)
+	}
+	if err := ui.ExportDocument(s.doc, f, ui.ExportFormatFromPath(p), s.conversation.AnswerStyle.ExportDetail()); err != nil {
+		f.Close()
+		return fmt.Errorf("exporting conversation to %q: %w", p, err)
+	}
+	return f.Close()
+}
+
 func (s *session) listModels(ctx context.Context) ([]string, error) {
 	if s.availableModels == nil {
 		modelNames, err := s.LLM.ListModels(ctx)
@@ -583,41 +1645,44 @@ func (s *session) listModels(ctx context.Context) ([]string, error) {
 	return s.availableModels, nil
 }
 
+// answerQuery runs the command in query if it is one (see replCommands), and otherwise sends it to the LLM.
 func (s *session) answerQuery(ctx context.Context, query string) error {
-	switch {
-	case query == "model":
-		infoBlock := &ui.AgentTextBlock{}
-		infoBlock.AppendText(fmt.Sprintf("Current model is `%s`\n", s.model))
-		s.doc.AddBlock(infoBlock)
-
-	case query == "version":
-		infoBlock := &ui.AgentTextBlock{}
-		infoBlock.AppendText(fmt.Sprintf("Version: `%s`\n", version))
-		s.doc.AddBlock(infoBlock)
-
-	case query == "models":
-		models, err := s.listModels(ctx)
-		if err != nil {
-			return fmt.Errorf("listing models: %w", err)
-		}
-		infoBlock := &ui.AgentTextBlock{}
-		infoBlock.AppendText("\n  Available models:\n")
-		infoBlock.AppendText(strings.Join(models, "\n"))
-		s.doc.AddBlock(infoBlock)
+	if handled, err := s.runCommand(ctx, query); handled {
+		return err
+	}
 
-	case query == "tools":
-		if s.conversation == nil {
-			return fmt.Errorf("listing tols: conversation is not initialized")
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var interrupted atomic.Bool
+	interrupt := func() bool {
+		if len(s.conversation.CreatedInRound()) == 0 || !interrupted.CompareAndSwap(false, true) {
+			return false
 		}
-		infoBlock := &ui.AgentTextBlock{}
-		infoBlock.AppendText("\n  Available tools:\n")
-		infoBlock.AppendText(strings.Join(s.conversation.Tools.Names(), "\n"))
-		s.doc.AddBlock(infoBlock)
+		fmt.Fprintf(os.Stderr, "Interrupted, stopping the task (press Ctrl+C again to exit right away)...\n")
+		cancel()
+		return true
+	}
+	roundInterrupt.Store(&interrupt)
+	defer roundInterrupt.Store(nil)
 
-	default:
-		return s.conversation.RunOneRound(ctx, query)
+	_, err := s.conversation.RunOneRound(ctx, query)
+	if interrupted.Load() {
+		return errInterrupted
 	}
-	return nil
+	return err
+}
+
+// roundInterrupt is called on Ctrl+C while a query is being answered. It stops the round and returns true
+// if the round created resources, so that they can be cleaned up before the session ends; otherwise we exit right away.
+var roundInterrupt atomic.Pointer[func() bool]
+
+// errInterrupted is returned when the user stopped the round with Ctrl+C; the session ends.
+var errInterrupted = errors.New("interrupted")
+
+// interruptRound stops the round being run, if it created resources; see roundInterrupt.
+func interruptRound() bool {
+	interrupt := roundInterrupt.Load()
+	return interrupt != nil && (*interrupt)()
 }
 
 // Redirect standard log output to our custom klog writer
@@ -642,6 +1707,15 @@ func (writer klogWriter) Write(data []byte) (n int, err error) {
 	return len(data), nil
 }
 
+// isTerminal returns true if f is a terminal.
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
+
 func hasStdInData() (bool, error) {
 	hasData := false
 
@@ -658,50 +1732,60 @@ func hasStdInData() (bool, error) {
 // It supports:
 // - 1 positional arg only -> kubectl-ai "get pods"
 // - stdin only -> echo "get pods" | kubectl-ai
-// - 1 positional arg + stdin (combined) -> kubectl-ai get <<< "pods" or kubectl-ai "get" <<< "pods"
+// - 1 positional arg + stdin -> cat deploy.yaml | kubectl-ai "review this manifest"; stdin is returned as
+// piped input, to be attached to the query
 // As default no positional arg nor stdin
-func resolveQueryInput(hasStdInData bool, args []string) (string, error) {
+func resolveQueryInput(hasStdInData bool, args []string) (query string, piped string, err error) {
 	switch {
 	case len(args) == 1 && !hasStdInData:
 		// Use argument directly
-		return args[0], nil
+		return args[0], "", nil
 
 	case len(args) == 1 && hasStdInData:
-		// Combine arg + stdin
-		var b strings.Builder
-		b.WriteString(args[0])
-		b.WriteString("\n")
-
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			b.WriteString(scanner.Text())
-			b.WriteString("\n")
-		}
-		if err := scanner.Err(); err != nil {
-			return "", fmt.Errorf("reading stdin: %w", err)
+		// The arg is the query, about the content of stdin
+		b, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", "", fmt.Errorf("reading stdin: %w", err)
 		}
-		query := strings.TrimSpace(b.String())
-		if query == "" {
-			return "", fmt.Errorf("no query provided from stdin")
+		if strings.TrimSpace(string(b)) == "" {
+			return args[0], "", nil
 		}
-		return query, nil
+		return args[0], string(b), nil
 
 	case len(args) == 0 && hasStdInData:
 		// Read stdin only
 		b, err := io.ReadAll(os.Stdin)
 		if err != nil {
-			return "", fmt.Errorf("reading stdin: %w", err)
+			return "", "", fmt.Errorf("reading stdin: %w", err)
 		}
 		query := strings.TrimSpace(string(b))
 		if query == "" {
-			return "", fmt.Errorf("no query provided from stdin")
+			return "", "", fmt.Errorf("no query provided from stdin")
 		}
-		return query, nil
+		return query, "", nil
 
 	default:
 		// Case: No input at all — return empty string, no error
-		return "", nil
+		return "", "", nil
+	}
+}
+
+// startEgressProxy starts the proxy that enforces --restrict-egress. The API servers of the clusters are always allowed,
+// and reached directly.
+func startEgressProxy(opt *Options) (*tools.EgressProxy, error) {
+	servers, err := tools.KubeconfigServerHosts(opt.KubeConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("finding the API servers to allow with --restrict-egress: %w", err)
+	}
+	proxy, err := tools.StartEgressProxy(&tools.EgressPolicy{
+		AllowedHosts: opt.EgressAllowedHosts,
+		DirectHosts:  servers,
+	})
+	if err != nil {
+		return nil, err
 	}
+	klog.Infof("Restricting egress of tool commands to local hosts, the API servers %v and %v", servers, opt.EgressAllowedHosts)
+	return proxy, nil
 }
 
 func resolveKubeConfigPath(opt *Options) error {
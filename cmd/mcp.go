@@ -60,6 +60,26 @@ func (s *kubectlMCPServer) Serve(ctx context.Context) error {
 	return server.ServeStdio(s.server)
 }
 
+// progressNotifier forwards the progress of a tool call to the client as MCP progress notifications,
+// for the request with the given progress token.
+func (s *kubectlMCPServer) progressNotifier(ctx context.Context, token mcp.ProgressToken) tools.ProgressFunc {
+	return func(progress tools.Progress) {
+		params := map[string]any{
+			"progressToken": token,
+			"progress":      progress.Current,
+		}
+		if progress.Total > 0 {
+			params["total"] = progress.Total
+		}
+		if progress.Message != "" {
+			params["message"] = progress.Message
+		}
+		if err := s.server.SendNotificationToClient(ctx, "notifications/progress", params); err != nil {
+			klog.V(2).Infof("Failed to send progress notification: %v", err)
+		}
+	}
+}
+
 func (s *kubectlMCPServer) handleToolCall(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 
 	log := klog.FromContext(ctx)
@@ -72,42 +92,32 @@ func (s *kubectlMCPServer) handleToolCall(ctx context.Context, request mcp.CallT
 		return mcp.NewToolResultError("Invalid arguments format: expected a map"), nil
 	}
 
-	// Safely extract command parameter with type checking
-	commandVal, ok := argMap["command"]
-	if !ok {
-		return mcp.NewToolResultError("Missing required parameter: command"), nil
-	}
-	command, ok := commandVal.(string)
-	if !ok {
-		return mcp.NewToolResultError("Parameter 'command' must be a string"), nil
-	}
-
-	// Safely extract modifies_resource parameter (optional)
-	var modifiesResource string
-	if modVal, ok := argMap["modifies_resource"]; ok {
-		if modStr, ok := modVal.(string); ok {
-			modifiesResource = modStr
+	// Validate the command parameter if present; tools with structured parameters don't use it.
+	var command string
+	if commandVal, ok := argMap["command"]; ok {
+		command, ok = commandVal.(string)
+		if !ok {
+			return mcp.NewToolResultError("Parameter 'command' must be a string"), nil
 		}
 	}
 
-	log.Info("Received tool call", "tool", name, "command", command, "modifies_resource", modifiesResource)
+	log.Info("Received tool call", "tool", name, "command", command, "arguments", argMap)
 
 	ctx = context.WithValue(ctx, tools.KubeconfigKey, s.kubectlConfig)
 	ctx = context.WithValue(ctx, tools.WorkDirKey, s.workDir)
+	if request.Params.Meta != nil && request.Params.Meta.ProgressToken != nil {
+		ctx = tools.WithProgress(ctx, s.progressNotifier(ctx, request.Params.Meta.ProgressToken))
+	}
 
 	tool := tools.Lookup(name)
 	if tool == nil {
 		// Use utility method for error creation in v0.31.0
 		return mcp.NewToolResultError(fmt.Sprintf("Tool %s not found", name)), nil
 	}
-	// Prepare arguments map with command and optional modifies_resource
-	args := map[string]any{
-		"command": command,
-	}
-
-	// Add modifies_resource if available
-	if modifiesResource != "" {
-		args["modifies_resource"] = modifiesResource
+	// Pass all arguments through to the tool
+	args := make(map[string]any, len(argMap))
+	for k, v := range argMap {
+		args[k] = v
 	}
 
 	output, err := tool.Run(ctx, args)
@@ -27,12 +27,12 @@ import (
 
 // InitializeMCPClient initializes MCP client functionality when --mcp-client flag is used.
 // It connects to servers and registers discovered tools with the kubectl-ai tool system.
-func InitializeMCPClient() (*mcp.Manager, error) {
-	// Initialize the MCP manager
-	manager, err := mcp.InitializeManager()
+func InitializeMCPClient(servers []string) (*mcp.Manager, error) {
+	config, err := loadMCPConfig(servers)
 	if err != nil {
 		return nil, err
 	}
+	manager := mcp.NewManager(config)
 
 	// Connect to servers and register tools
 	ctx := context.Background()
@@ -182,3 +182,17 @@ func LoadMCPConfig() {
 		klog.Warningf("Failed to load or log MCP config: %v", err)
 	}
 }
+
+// loadMCPConfig loads the MCP configuration, keeping only the given servers (from --mcp-servers), if any.
+func loadMCPConfig(servers []string) (*mcp.Config, error) {
+	config, err := mcp.LoadConfig("")
+	if err != nil {
+		return nil, err
+	}
+	if len(servers) > 0 {
+		if err := config.SelectServers(servers); err != nil {
+			return nil, err
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/spf13/cobra"
+)
+
+func newMemoriesCommand(opt *Options) *cobra.Command {
+	memoriesCmd := &cobra.Command{
+		Use:   "memories",
+		Short: "Manage the facts remembered across sessions with --memory",
+	}
+
+	memoriesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the stored memories",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			memories, err := loadMemories(opt)
+			if err != nil {
+				return err
+			}
+			if len(memories.List) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No memories.")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "#\tCREATED\tFACT")
+			for i, memory := range memories.List {
+				fmt.Fprintf(w, "%d\t%s\t%s\n", i+1, memory.Created.Format("2006-01-02 15:04"), memory.Text)
+			}
+			return w.Flush()
+		},
+	})
+
+	memoriesCmd.AddCommand(&cobra.Command{
+		Use:   "forget [number...]",
+		Short: "Remove stored memories",
+		Long:  "Removes the memories with the given numbers, as shown by kubectl-ai memories list, or all of them if no numbers are given.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			memories, err := loadMemories(opt)
+			if err != nil {
+				return err
+			}
+			if len(args) == 0 {
+				memories.List = nil
+				return memories.Save()
+			}
+			remove := make([]bool, len(memories.List))
+			for _, arg := range args {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 || n > len(memories.List) {
+					return fmt.Errorf("no memory numbered %q, see kubectl-ai memories list", arg)
+				}
+				remove[n-1] = true
+			}
+			i := 0
+			memories.List = slices.DeleteFunc(memories.List, func(tools.Memory) bool {
+				i++
+				return remove[i-1]
+			})
+			return memories.Save()
+		},
+	})
+
+	return memoriesCmd
+}
+
+// loadMemories loads the memories from the file given by --memory-file.
+func loadMemories(opt *Options) (*tools.Memories, error) {
+	if opt.MemoryPath == "" {
+		return nil, fmt.Errorf("--memory-file is not set")
+	}
+	path, err := expandPathPlaceholders(opt.MemoryPath)
+	if err != nil {
+		return nil, err
+	}
+	return tools.LoadMemories(path)
+}
@@ -0,0 +1,94 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+)
+
+// checkOffline checks that nothing in the configuration sends data outside this machine or the private network:
+// the LLM provider must be local, and so must MCP servers and the address of the HTML UI.
+func checkOffline(opt *Options) error {
+	endpoint, err := providerEndpoint(opt.ProviderID)
+	if err != nil {
+		return fmt.Errorf("--offline: %w", err)
+	}
+	if host := hostOf(endpoint); !tools.IsLocalHost(host) {
+		return fmt.Errorf("--offline: the %s provider uses %s, which is not on this machine or a private network", opt.ProviderID, endpoint)
+	}
+
+	if opt.MCPClient {
+		config, err := loadMCPConfig(opt.MCPServers)
+		if err != nil {
+			return fmt.Errorf("--offline: loading the MCP configuration: %w", err)
+		}
+		for _, server := range config.Servers {
+			if server.URL != "" && !tools.IsLocalHost(hostOf(server.URL)) {
+				return fmt.Errorf("--offline: MCP server %q is at %s, which is not on this machine or a private network", server.Name, server.URL)
+			}
+		}
+	}
+
+	if opt.UserInterface == UserInterfaceHTML {
+		host, _, err := net.SplitHostPort(opt.UIListenAddress)
+		if err != nil || host == "" || !tools.IsLocalHost(host) {
+			return fmt.Errorf("--offline: the HTML UI must listen on a local address, not %q", opt.UIListenAddress)
+		}
+	}
+	return nil
+}
+
+// providerEndpoint returns the endpoint of a local LLM provider, as configured in the environment.
+func providerEndpoint(providerID string) (string, error) {
+	switch providerID {
+	case "ollama":
+		if host := os.Getenv("OLLAMA_HOST"); host != "" {
+			return host, nil
+		}
+		return "http://127.0.0.1:11434", nil
+	case "llamacpp":
+		if host := os.Getenv("LLAMACPP_HOST"); host != "" {
+			return host, nil
+		}
+		return "http://127.0.0.1:8080", nil
+	case "openai", "openai-compatible":
+		// e.g. vLLM, which serves the OpenAI API.
+		for _, name := range []string{"OPENAI_ENDPOINT", "OPENAI_API_BASE"} {
+			if endpoint := os.Getenv(name); endpoint != "" {
+				return endpoint, nil
+			}
+		}
+		return "", fmt.Errorf("the %s provider must be given a local endpoint with OPENAI_ENDPOINT, e.g. of vLLM", providerID)
+	}
+	return "", fmt.Errorf("the %s provider is not local; use ollama, llamacpp, or openai-compatible with a local endpoint (e.g. vLLM)", providerID)
+}
+
+// hostOf returns the host name of an endpoint given as a URL or as host[:port].
+func hostOf(endpoint string) string {
+	if !strings.Contains(endpoint, "://") {
+		endpoint = "http://" + endpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
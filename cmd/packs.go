@@ -0,0 +1,138 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/spf13/cobra"
+)
+
+func newPackCommand(opt *Options) *cobra.Command {
+	packCmd := &cobra.Command{
+		Use:   "pack",
+		Short: "Manage the installed packs of prompts and recipes",
+	}
+
+	packCmd.AddCommand(&cobra.Command{
+		Use:   "install oci://<registry>/<repository>:<tag>",
+		Short: "Install a pack of prompts and recipes from an OCI registry",
+		Long: `Installs a pack of prompts and recipes from an OCI registry, pulled with oras.
+
+The version must be pinned with a tag (other than latest) or a digest; the digest of the installed version is
+recorded, and does not change until the pack is installed again. If artifactVerification has trusted signers,
+the pack must be signed (cosign sign) by one of them.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ref, err := tools.ParsePackRef(args[0])
+			if err != nil {
+				return err
+			}
+			if err := opt.ArtifactVerification.Validate(); err != nil {
+				return fmt.Errorf("artifact verification: %w", err)
+			}
+			tools.SetArtifactPolicy(opt.ArtifactVerification)
+
+			packs, err := loadPacks(opt)
+			if err != nil {
+				return err
+			}
+			previous := packs.Lookup(ref.Name())
+			pack, err := packs.Install(cmd.Context(), ref)
+			if err != nil {
+				return err
+			}
+			verified := "not verified, as there are no trusted signers"
+			if pack.Verified {
+				verified = "signature verified"
+			}
+			if previous != nil && previous.Digest != pack.Digest {
+				fmt.Fprintf(cmd.OutOrStdout(), "Updated pack %s from %s to %s (%s) in %s\n", pack.Name, previous.Ref, pack.Ref, verified, packs.Dir(pack))
+			} else {
+				fmt.Fprintf(cmd.OutOrStdout(), "Installed pack %s %s (%s) in %s\n", pack.Name, pack.Ref, verified, packs.Dir(pack))
+			}
+			return nil
+		},
+	})
+
+	packCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the installed packs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			packs, err := loadPacks(opt)
+			if err != nil {
+				return err
+			}
+			if len(packs.List) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No packs installed.")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tREF\tDIGEST\tVERIFIED\tINSTALLED")
+			for _, pack := range packs.List {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\n", pack.Name, pack.Ref, pack.Digest, pack.Verified, pack.Installed.Format("2006-01-02 15:04"))
+			}
+			return w.Flush()
+		},
+	})
+
+	packCmd.AddCommand(&cobra.Command{
+		Use:   "remove <name>",
+		Short: "Uninstall a pack",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			packs, err := loadPacks(opt)
+			if err != nil {
+				return err
+			}
+			return packs.Remove(args[0])
+		},
+	})
+
+	return packCmd
+}
+
+// loadPacks loads the packs installed in --packs-dir.
+func loadPacks(opt *Options) (*tools.Packs, error) {
+	if opt.PacksDir == "" {
+		return nil, fmt.Errorf("--packs-dir is not set")
+	}
+	dir, err := expandPathPlaceholders(opt.PacksDir)
+	if err != nil {
+		return nil, err
+	}
+	return tools.LoadPacks(dir)
+}
+
+// addPacks adds the prompts and recipes of the installed packs to those configured in opt.
+func addPacks(opt *Options) error {
+	if opt.PacksDir == "" {
+		return nil
+	}
+	packs, err := loadPacks(opt)
+	if err != nil {
+		return err
+	}
+	promptPaths, err := packs.PromptPaths()
+	if err != nil {
+		return err
+	}
+	opt.ExtraPromptPaths = append(opt.ExtraPromptPaths, promptPaths...)
+	opt.RecipesPaths = append(opt.RecipesPaths, packs.RecipesPaths()...)
+	return nil
+}
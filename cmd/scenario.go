@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/spf13/cobra"
+)
+
+// scenarioOptions configure a scenario run; they are set by the scenario command.
+type scenarioOptions struct {
+	// vars are the values of the scenario's variables, as name=value.
+	vars []string
+
+	// scenario is the scenario loaded from the file given to the command.
+	scenario *agent.Scenario
+}
+
+func newScenarioCommand(opt *Options) *cobra.Command {
+	scenarioCmd := &cobra.Command{
+		Use:   "scenario <file>",
+		Short: "Work through a scripted scenario, step by step",
+		Long: `Works through the steps of a scenario file in a single session, as a guided runbook. Each step is a query
+for the agent, followed by conditions that must hold once it is done:
+
+  name: replace-node
+  vars:
+    node: ""              # no default: must be given with --set node=...
+  steps:
+    - name: Drain the node
+      prompt: Cordon and drain node {{node}}, ignoring DaemonSets.
+      confirm: true         # ask before starting the step
+      autoApprove: true     # run its commands without asking
+      timeout: 10m
+      expect:
+        - description: no pods other than DaemonSet pods are left on the node
+          command: kubectl get pods -A --field-selector spec.nodeName={{node}} -o jsonpath={.items[*].metadata.ownerReferences[*].kind}
+          notContains: ReplicaSet
+          timeout: 2m       # wait up to 2 minutes for it to hold
+    - name: Uncordon the node
+      prompt: Uncordon node {{node}}.
+
+When a step fails or its conditions do not hold, you are asked whether to retry it, skip it or stop.
+The command exits with an error if any step failed.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			vars := make(map[string]string)
+			for _, v := range opt.Scenario.vars {
+				name, value, ok := strings.Cut(v, "=")
+				if !ok {
+					return fmt.Errorf("invalid --set %q, expected name=value", v)
+				}
+				vars[name] = value
+			}
+			scenario, err := agent.LoadScenario(args[0], vars)
+			if err != nil {
+				return err
+			}
+			opt.Scenario.scenario = scenario
+			return RunRootCommand(cmd.Context(), *opt, nil)
+		},
+	}
+
+	scenarioCmd.Flags().StringArrayVar(&opt.Scenario.vars, "set", nil, "value of a variable of the scenario, as name=value (can be repeated)")
+
+	return scenarioCmd
+}
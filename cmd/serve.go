@@ -0,0 +1,466 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"k8s.io/klog/v2"
+)
+
+// serveModelName is the model name the server reports to OpenAI clients.
+const serveModelName = "kubectl-ai"
+
+// serveAPIKeyEnv is the environment variable holding the API key that clients must send, if set.
+const serveAPIKeyEnv = "KUBECTL_AI_API_KEY"
+
+// serveOptions configure the server; they are set by the serve command.
+type serveOptions struct {
+	// listenAddress is the address to listen on.
+	listenAddress string
+	// maxSessions is the number of sessions of the sessions API that can be open at a time; zero means no limit.
+	maxSessions int
+	// sessionIdleTimeout is how long a session of the sessions API is kept without queries; zero means forever.
+	sessionIdleTimeout time.Duration
+	// allowedOrigins are the origins of web pages that may send requests.
+	allowedOrigins []string
+}
+
+func newServeCommand(opt *Options) *cobra.Command {
+	var listenAddress string
+	var maxSessions int
+	var sessionIdleTimeout time.Duration
+	var allowedOrigins []string
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the agent over HTTP, with an OpenAI-compatible API and a sessions API",
+		Long: `Serves the agent over an OpenAI-compatible chat completions API (POST /v1/chat/completions and GET /v1/models),
+so that chat UIs built for OpenAI can be pointed at kubectl-ai. Tools run on the server, with its kubeconfig.
+Each chat completion request is answered in a new conversation, given the previous messages of the chat as history.
+
+The sessions API keeps the state of each conversation on the server, for frontends that hold a conversation
+with the agent: create a session with POST /v1/sessions, send queries with POST /v1/sessions/{id}/messages
+(with "stream": true, the progress of the agent is sent as server-sent events), read the transcript with
+GET /v1/sessions/{id}, and end it with DELETE /v1/sessions/{id}.
+
+No one is asked to confirm tool calls: calls that need confirmation are not run, unless the confirmation policy or
+--skip-permissions approves them. If ` + serveAPIKeyEnv + ` is set, clients must send it as a bearer token;
+it must be set to listen on an address other than a loopback address. Requests from web pages are refused,
+unless their origin is given with --allowed-origins.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if listenAddress == "" {
+				return fmt.Errorf("--listen is required")
+			}
+			// The flag is not bound to opt directly, as its default would start the server on every run.
+			opt.Serve.listenAddress = listenAddress
+			opt.Serve.maxSessions = maxSessions
+			opt.Serve.sessionIdleTimeout = sessionIdleTimeout
+			opt.Serve.allowedOrigins = allowedOrigins
+			return RunRootCommand(cmd.Context(), *opt, nil)
+		},
+	}
+
+	serveCmd.Flags().StringVar(&listenAddress, "listen", "localhost:8080", "address to listen on")
+	serveCmd.Flags().IntVar(&maxSessions, "max-sessions", 100, "number of sessions of the sessions API that can be open at a time (0 means no limit)")
+	serveCmd.Flags().DurationVar(&sessionIdleTimeout, "session-idle-timeout", time.Hour, "close sessions of the sessions API that received no query for this long (0 means never)")
+	serveCmd.Flags().StringSliceVar(&allowedOrigins, "allowed-origins", nil, "origins of web pages that may send requests, e.g. https://chat.example.com (requests from other pages are refused)")
+
+	return serveCmd
+}
+
+// chatServer answers OpenAI chat completion requests with the agent.
+type chatServer struct {
+	// newConversation creates the conversation that answers a request.
+	newConversation func() *agent.Agent
+	timeFormat      ui.TimeFormat
+	// apiKey is the key clients must send, if not empty.
+	apiKey string
+	// allowedOrigins are the origins of web pages that may send requests.
+	allowedOrigins []string
+	// ctx is the context of the server, in which sessions are started.
+	ctx context.Context
+	// sessions are the sessions of the sessions API.
+	sessions *sessionStore
+}
+
+// runServer serves the OpenAI-compatible API until ctx is done.
+func runServer(ctx context.Context, serve serveOptions, timeFormat ui.TimeFormat, newConversation func() *agent.Agent) error {
+	s := &chatServer{
+		newConversation: newConversation,
+		timeFormat:      timeFormat,
+		apiKey:          os.Getenv(serveAPIKeyEnv),
+		allowedOrigins:  serve.allowedOrigins,
+		ctx:             ctx,
+		sessions:        &sessionStore{maxSessions: serve.maxSessions, idleTimeout: serve.sessionIdleTimeout},
+	}
+
+	listener, err := net.Listen("tcp", serve.listenAddress)
+	if err != nil {
+		return fmt.Errorf("listening on %q: %w", serve.listenAddress, err)
+	}
+	// Anyone who can reach the server can run tools with its kubeconfig, so only local clients may go without a key.
+	if addr, ok := listener.Addr().(*net.TCPAddr); s.apiKey == "" && (!ok || !addr.IP.IsLoopback()) {
+		listener.Close()
+		return fmt.Errorf("%s must be set to listen on %q, which is not a loopback address", serveAPIKeyEnv, serve.listenAddress)
+	}
+
+	go s.sessions.expire(ctx)
+	defer func() {
+		for _, session := range s.sessions.list() {
+			session.conversation.Close()
+		}
+	}()
+	server := &http.Server{
+		Handler: s.handler(),
+		// Requests are answered in contexts derived from ctx, which configures the tools (e.g. the executor).
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Serving the OpenAI-compatible API on http://%s/v1, and the sessions API on http://%s/v1/sessions\n", listener.Addr(), listener.Addr())
+	if s.apiKey == "" {
+		fmt.Fprintf(os.Stderr, "warning: %s is not set, so clients are not authenticated\n", serveAPIKeyEnv)
+	}
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// handler returns the handler of the OpenAI-compatible API and the sessions API.
+func (s *chatServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/chat/completions", s.authorize(s.handleChatCompletions))
+	mux.HandleFunc("GET /v1/models", s.authorize(s.handleModels))
+	s.registerSessionHandlers(mux)
+	return s.checkOrigin(mux)
+}
+
+// checkOrigin refuses requests from web pages whose origin is not allowed, so that a page open in a browser on the
+// machine cannot use the server; browsers send the origin of the page with its requests, other clients send none.
+// Requests from allowed origins get the CORS headers that let browsers send them.
+func (s *chatServer) checkOrigin(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if !slices.Contains(s.allowedOrigins, origin) {
+			writeOpenAIError(w, http.StatusForbidden, fmt.Sprintf("requests from %s are not allowed", origin))
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		if r.Method == http.MethodOptions {
+			// A preflight request, which asks whether the browser may send the request.
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// authorize checks the bearer token of requests, if an API key is set, and that POST requests are JSON:
+// browsers only let pages send JSON to other origins if the server allows it, unlike forms.
+func (s *chatServer) authorize(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.apiKey != "" {
+			token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.apiKey)) != 1 {
+				writeOpenAIError(w, http.StatusUnauthorized, "invalid API key")
+				return
+			}
+		}
+		if r.Method == http.MethodPost {
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/json" {
+				writeOpenAIError(w, http.StatusUnsupportedMediaType, "the Content-Type must be application/json")
+				return
+			}
+		}
+		handler(w, r)
+	}
+}
+
+// chatCompletionRequest is the part of an OpenAI chat completion request that the server uses.
+type chatCompletionRequest struct {
+	Messages []chatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+type chatMessage struct {
+	Role string `json:"role"`
+	// Content is a string, or a list of parts of which the text parts are used.
+	Content json.RawMessage `json:"content"`
+}
+
+// text returns the text of the message.
+func (m chatMessage) text() string {
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range parts {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+func (s *chatServer) handleChatCompletions(w http.ResponseWriter, r *http.Request) {
+	var request chatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if len(request.Messages) == 0 || request.Messages[len(request.Messages)-1].Role != "user" {
+		writeOpenAIError(w, http.StatusBadRequest, "the last message must be from the user")
+		return
+	}
+	query := strings.TrimSpace(request.Messages[len(request.Messages)-1].text())
+	if query == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "the last message has no text")
+		return
+	}
+
+	id := "chatcmpl-" + uuid.NewString()
+	created := time.Now().Unix()
+	history := chatHistory(request.Messages[:len(request.Messages)-1])
+	if request.Stream {
+		stream := newChatCompletionStream(w, id, created)
+		answer, err := s.answer(r.Context(), history, query, stream)
+		if err != nil {
+			klog.Errorf("Answering chat completion request: %v", err)
+		}
+		stream.finish(answer, err)
+		return
+	}
+
+	answer, err := s.answer(r.Context(), history, query, nil)
+	if err != nil {
+		klog.Errorf("Answering chat completion request: %v", err)
+		if answer == "" {
+			writeOpenAIError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		// The agent stopped before completing the task; tell the user why, along with what it did.
+		answer += fmt.Sprintf("\n\n_The agent stopped: %v_", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":      id,
+		"object":  "chat.completion",
+		"created": created,
+		"model":   serveModelName,
+		"choices": []map[string]any{{
+			"index":         0,
+			"message":       map[string]any{"role": "assistant", "content": answer},
+			"finish_reason": "stop",
+		}},
+	})
+}
+
+// answer answers the query in a new conversation that resumes from history, and returns the answer as markdown.
+// The answer may not be empty even if there is an error, if the agent stopped before completing the task.
+// If subscriber is not nil, it is told about the progress of the agent.
+func (s *chatServer) answer(ctx context.Context, history []*journal.HistoryEntry, query string, subscriber ui.Subscriber) (string, error) {
+	doc := ui.NewDocument()
+	doc.SetTimeFormat(s.timeFormat)
+	if subscriber != nil {
+		subscription := doc.AddSubscription(subscriber)
+		defer subscription.Close()
+	}
+	conversation := s.newConversation()
+	if err := conversation.Init(ctx, doc); err != nil {
+		return "", fmt.Errorf("starting conversation: %w", err)
+	}
+	defer conversation.Close()
+	if len(history) > 0 {
+		if err := conversation.Resume(ctx, history); err != nil {
+			return "", fmt.Errorf("resuming the chat: %w", err)
+		}
+	}
+	_, err := conversation.RunOneRound(ctx, query)
+	if answer := conversation.LastAnswer(); answer != nil {
+		return answer.Markdown(), err
+	}
+	return lastAgentText(doc), err
+}
+
+// chatHistory converts the previous messages of a chat to history that a conversation can resume from.
+// System messages are not kept, as the agent has its own system prompt.
+func chatHistory(messages []chatMessage) []*journal.HistoryEntry {
+	var history []*journal.HistoryEntry
+	for _, message := range messages {
+		text := message.text()
+		if text == "" {
+			continue
+		}
+		switch message.Role {
+		case "user":
+			history = append(history, &journal.HistoryEntry{Role: journal.RoleUser, Messages: []string{text}})
+		case "assistant":
+			history = append(history, &journal.HistoryEntry{Role: journal.RoleModel, Messages: []string{text}})
+		}
+	}
+	return history
+}
+
+// chatCompletionStream sends the text of the agent as chat completion chunks (server-sent events), as it is written.
+// The text of the agent's messages is separated by blank lines; the rest of the answer, such as the commands run
+// listed in a structured answer, is sent at the end.
+type chatCompletionStream struct {
+	mutex   sync.Mutex
+	w       http.ResponseWriter
+	id      string
+	created int64
+
+	// sentText is the text sent for each text block, and last the block whose text was sent last.
+	sentText map[ui.Block]string
+	last     ui.Block
+}
+
+func newChatCompletionStream(w http.ResponseWriter, id string, created int64) *chatCompletionStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	s := &chatCompletionStream{w: w, id: id, created: created, sentText: make(map[ui.Block]string)}
+	s.chunk(map[string]any{"role": "assistant", "content": ""}, nil)
+	return s
+}
+
+func (s *chatCompletionStream) DocumentChanged(doc *ui.Document, block ui.Block) {
+	textBlock, ok := block.(*ui.AgentTextBlock)
+	if !ok {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	text, sent := textBlock.Text(), s.sentText[block]
+	delta, ok := strings.CutPrefix(text, sent)
+	if !ok || delta == "" {
+		// Text that was replaced cannot be taken back from the client.
+		return
+	}
+	s.sentText[block] = text
+	if sent == "" && s.last != nil && s.last != block {
+		delta = "\n\n" + delta
+	}
+	s.last = block
+	s.chunk(map[string]any{"content": delta}, nil)
+}
+
+// finish sends the answer if it was not sent already, and the reason the agent stopped if err is not nil,
+// and ends the stream.
+func (s *chatCompletionStream) finish(answer string, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// The answer usually starts with the last message, which was sent already.
+	content := answer
+	if s.last != nil && answer != "" {
+		if rest, ok := strings.CutPrefix(answer, strings.TrimSpace(s.sentText[s.last])); ok {
+			content = rest
+		} else {
+			content = "\n\n" + answer
+		}
+	}
+	if err != nil {
+		content += fmt.Sprintf("\n\n_The agent stopped: %v_", err)
+		if s.last == nil && answer == "" {
+			content = strings.TrimPrefix(content, "\n\n")
+		}
+	}
+	if strings.TrimSpace(content) != "" {
+		s.chunk(map[string]any{"content": content}, nil)
+	}
+	s.chunk(map[string]any{}, "stop")
+	fmt.Fprint(s.w, "data: [DONE]\n\n")
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// chunk writes a chat completion chunk; the mutex must be held, except while the stream is created.
+func (s *chatCompletionStream) chunk(delta map[string]any, finishReason any) {
+	b, _ := json.Marshal(map[string]any{
+		"id":      s.id,
+		"object":  "chat.completion.chunk",
+		"created": s.created,
+		"model":   serveModelName,
+		"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finishReason}},
+	})
+	fmt.Fprintf(s.w, "data: %s\n\n", b)
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (s *chatServer) handleModels(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"object": "list",
+		"data": []map[string]any{{
+			"id":       serveModelName,
+			"object":   "model",
+			"owned_by": "kubectl-ai",
+		}},
+	})
+}
+
+// writeOpenAIError writes an error in the format of the OpenAI API.
+func writeOpenAIError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error": map[string]any{"message": message, "type": "invalid_request_error"},
+	})
+}
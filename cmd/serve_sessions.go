@@ -0,0 +1,410 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// serveSession is a conversation of the sessions API, which keeps its state between queries.
+type serveSession struct {
+	id           string
+	created      time.Time
+	conversation *agent.Agent
+	doc          *ui.Document
+
+	// running is held while a query is answered; a session answers one query at a time.
+	running sync.Mutex
+
+	// mutex guards lastUsed.
+	mutex    sync.Mutex
+	lastUsed time.Time
+}
+
+func (s *serveSession) touch() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.lastUsed = time.Now()
+}
+
+func (s *serveSession) idleSince() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastUsed
+}
+
+// sessionInfo describes a session in responses of the sessions API.
+type sessionInfo struct {
+	ID       string    `json:"id"`
+	Created  time.Time `json:"created"`
+	LastUsed time.Time `json:"last_used"`
+	// Messages is the transcript of the session, in responses about a single session.
+	Messages []ui.TranscriptMessage `json:"messages,omitempty"`
+}
+
+func (s *serveSession) info(withMessages bool) sessionInfo {
+	info := sessionInfo{ID: s.id, Created: s.created, LastUsed: s.idleSince()}
+	if withMessages {
+		info.Messages = ui.Transcript(s.doc)
+	}
+	return info
+}
+
+// sessionStore holds the sessions of the sessions API.
+type sessionStore struct {
+	mutex    sync.Mutex
+	sessions map[string]*serveSession
+
+	// maxSessions is the number of sessions that can be open at a time; zero means no limit.
+	maxSessions int
+	// idleTimeout is how long a session is kept without queries; zero means forever.
+	idleTimeout time.Duration
+}
+
+func (s *sessionStore) get(id string) *serveSession {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.sessions[id]
+}
+
+// add adds a session, unless there are already maxSessions sessions.
+func (s *sessionStore) add(session *serveSession) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.maxSessions > 0 && len(s.sessions) >= s.maxSessions {
+		return false
+	}
+	if s.sessions == nil {
+		s.sessions = make(map[string]*serveSession)
+	}
+	s.sessions[session.id] = session
+	return true
+}
+
+func (s *sessionStore) remove(id string) *serveSession {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	session := s.sessions[id]
+	delete(s.sessions, id)
+	return session
+}
+
+func (s *sessionStore) list() []*serveSession {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var sessions []*serveSession
+	for _, session := range s.sessions {
+		sessions = append(sessions, session)
+	}
+	slices.SortFunc(sessions, func(a, b *serveSession) int { return a.created.Compare(b.created) })
+	return sessions
+}
+
+// expire closes the sessions that have been idle for longer than the idle timeout, until ctx is done.
+func (s *sessionStore) expire(ctx context.Context) {
+	if s.idleTimeout <= 0 {
+		return
+	}
+	ticker := time.NewTicker(min(s.idleTimeout, time.Minute))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, session := range s.list() {
+			if time.Since(session.idleSince()) < s.idleTimeout || !session.running.TryLock() {
+				continue
+			}
+			klog.Infof("Closing session %s, idle since %v", session.id, session.idleSince())
+			s.remove(session.id)
+			session.conversation.Close()
+			session.running.Unlock()
+		}
+	}
+}
+
+// registerSessionHandlers adds the handlers of the sessions API to mux.
+func (s *chatServer) registerSessionHandlers(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/sessions", s.authorize(s.handleCreateSession))
+	mux.HandleFunc("GET /v1/sessions", s.authorize(s.handleListSessions))
+	mux.HandleFunc("GET /v1/sessions/{id}", s.authorize(s.handleGetSession))
+	mux.HandleFunc("DELETE /v1/sessions/{id}", s.authorize(s.handleDeleteSession))
+	mux.HandleFunc("POST /v1/sessions/{id}/messages", s.authorize(s.handleSessionMessage))
+}
+
+func (s *chatServer) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	doc := ui.NewDocument()
+	doc.SetTimeFormat(s.timeFormat)
+	conversation := s.newConversation()
+	// The session outlives the request, so it is not started in the context of the request.
+	if err := conversation.Init(s.ctx, doc); err != nil {
+		writeOpenAIError(w, http.StatusInternalServerError, fmt.Sprintf("starting conversation: %v", err))
+		return
+	}
+	now := time.Now()
+	session := &serveSession{
+		id:           conversation.SessionID,
+		created:      now,
+		lastUsed:     now,
+		conversation: conversation,
+		doc:          doc,
+	}
+	if !s.sessions.add(session) {
+		conversation.Close()
+		writeOpenAIError(w, http.StatusTooManyRequests, fmt.Sprintf("there are already %d sessions; delete one first", s.sessions.maxSessions))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(session.info(false))
+}
+
+func (s *chatServer) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	sessions := []sessionInfo{}
+	for _, session := range s.sessions.list() {
+		sessions = append(sessions, session.info(false))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"sessions": sessions})
+}
+
+func (s *chatServer) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	session := s.sessions.get(r.PathValue("id"))
+	if session == nil {
+		writeOpenAIError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(session.info(true))
+}
+
+func (s *chatServer) handleDeleteSession(w http.ResponseWriter, r *http.Request) {
+	session := s.sessions.get(r.PathValue("id"))
+	if session == nil {
+		writeOpenAIError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	if !session.running.TryLock() {
+		writeOpenAIError(w, http.StatusConflict, "the session is answering a query")
+		return
+	}
+	defer session.running.Unlock()
+	s.sessions.remove(session.id)
+	session.conversation.Close()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionMessageRequest is a query sent to a session.
+type sessionMessageRequest struct {
+	Query string `json:"query"`
+	// Stream sends the progress of the agent as server-sent events; so does an Accept: text/event-stream header.
+	Stream bool `json:"stream"`
+}
+
+// sessionMessageResponse is the answer to a query sent to a session, and the last event of a stream.
+type sessionMessageResponse struct {
+	SessionID string `json:"session_id"`
+	Status    string `json:"status"`
+	// Answer is the final answer, in markdown.
+	Answer string `json:"answer"`
+	// Error is why the agent stopped before completing the task.
+	Error            string             `json:"error,omitempty"`
+	ToolCalls        []sessionToolCall  `json:"tool_calls"`
+	StructuredAnswer *agent.FinalAnswer `json:"structured_answer,omitempty"`
+	Usage            gollm.Usage        `json:"usage"`
+	Cost             float64            `json:"cost,omitempty"`
+}
+
+// sessionToolCall is a tool call that ran while answering a query.
+type sessionToolCall struct {
+	Name        string         `json:"name"`
+	Arguments   map[string]any `json:"arguments,omitempty"`
+	Description string         `json:"description"`
+	Output      any            `json:"output,omitempty"`
+	Error       string         `json:"error,omitempty"`
+}
+
+func (s *chatServer) handleSessionMessage(w http.ResponseWriter, r *http.Request) {
+	session := s.sessions.get(r.PathValue("id"))
+	if session == nil {
+		writeOpenAIError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	var request sessionMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeOpenAIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	query := strings.TrimSpace(request.Query)
+	if query == "" {
+		writeOpenAIError(w, http.StatusBadRequest, "the query is empty")
+		return
+	}
+	if !session.running.TryLock() {
+		writeOpenAIError(w, http.StatusConflict, "the session is already answering a query")
+		return
+	}
+	defer session.running.Unlock()
+	session.touch()
+	defer session.touch()
+
+	// The query is recorded as the user's input, so that it is in the transcript.
+	input := ui.NewInputTextBlock()
+	session.doc.AddBlock(input)
+	input.Observable().Set(query, nil)
+
+	var events *sessionEventStream
+	if request.Stream || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		events = newSessionEventStream(w)
+		subscription := session.doc.AddSubscription(events)
+		defer subscription.Close()
+	}
+
+	result, err := session.conversation.RunOneRound(r.Context(), query)
+	response := sessionMessageResponse{
+		SessionID:        session.id,
+		Status:           taskSucceeded,
+		ToolCalls:        []sessionToolCall{},
+		StructuredAnswer: result.Answer,
+		Usage:            result.Usage,
+		Cost:             result.Cost,
+	}
+	if result.Answer != nil {
+		response.Answer = result.Answer.Markdown()
+	} else if len(result.Messages) > 0 {
+		response.Answer = strings.TrimSpace(result.Messages[len(result.Messages)-1])
+	}
+	if err != nil {
+		klog.Errorf("Answering query in session %s: %v", session.id, err)
+		response.Status = taskFailed
+		response.Error = err.Error()
+	}
+	for _, call := range result.ToolCalls {
+		response.ToolCalls = append(response.ToolCalls, sessionToolCall{
+			Name:        call.Name,
+			Arguments:   call.Arguments,
+			Description: call.Description,
+			Output:      call.Output,
+			Error:       call.Error,
+		})
+	}
+
+	if events != nil {
+		events.event("result", response)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// sessionEventStream sends the changes to the document of a session as server-sent events, while a query is answered:
+//   - "text": text streamed by the agent, {"block": n, "delta": "..."}; "replace" is set if the delta replaces the text
+//     sent earlier for the block
+//   - "tool_call": a tool call about to run, {"block": n, "description": "kubectl get pods"}
+//   - "tool_result": the result of the tool call of the block, {"block": n, "description": ..., "result": ...}
+//   - "error": {"block": n, "text": "..."}
+//   - "result": the sessionMessageResponse, when the query is answered
+type sessionEventStream struct {
+	mutex sync.Mutex
+	w     http.ResponseWriter
+
+	// ids are the numbers of the blocks in the events.
+	ids map[ui.Block]int
+	// sentText is the text sent for each text block, and sentResult the tool call blocks whose result was sent.
+	sentText   map[ui.Block]string
+	sentResult map[ui.Block]bool
+}
+
+func newSessionEventStream(w http.ResponseWriter) *sessionEventStream {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	return &sessionEventStream{
+		w:          w,
+		ids:        make(map[ui.Block]int),
+		sentText:   make(map[ui.Block]string),
+		sentResult: make(map[ui.Block]bool),
+	}
+}
+
+func (s *sessionEventStream) DocumentChanged(doc *ui.Document, block ui.Block) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	id, ok := s.ids[block]
+	if !ok {
+		id = len(s.ids)
+		s.ids[block] = id
+	}
+	switch block := block.(type) {
+	case *ui.AgentTextBlock:
+		text, sent := block.Text(), s.sentText[block]
+		if text == sent {
+			return
+		}
+		s.sentText[block] = text
+		if delta, ok := strings.CutPrefix(text, sent); ok {
+			s.send("text", map[string]any{"block": id, "delta": delta})
+		} else {
+			s.send("text", map[string]any{"block": id, "delta": text, "replace": true})
+		}
+	case *ui.FunctionCallRequestBlock:
+		switch {
+		case !ok:
+			s.send("tool_call", map[string]any{"block": id, "description": block.Description()})
+		case block.Result() != nil && !s.sentResult[block]:
+			s.sentResult[block] = true
+			s.send("tool_result", map[string]any{"block": id, "description": block.Description(), "result": block.Result()})
+		}
+	case *ui.ErrorBlock:
+		if !ok {
+			s.send("error", map[string]any{"block": id, "text": block.Text()})
+		}
+	}
+}
+
+// event writes an event.
+func (s *sessionEventStream) event(event string, data any) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.send(event, data)
+}
+
+// send writes an event; the mutex must be held.
+func (s *sessionEventStream) send(event string, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		klog.Warningf("Encoding %s event: %v", event, err)
+		return
+	}
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, b)
+	if flusher, ok := s.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
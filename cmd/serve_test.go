@@ -0,0 +1,262 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// fakeLLM answers every query with answer. If started is not nil, requests are reported to it,
+// and wait for release before they are answered.
+type fakeLLM struct {
+	answer  string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (l *fakeLLM) Close() error                                    { return nil }
+func (l *fakeLLM) StartChat(systemPrompt, model string) gollm.Chat { return &fakeChat{llm: l} }
+func (l *fakeLLM) SetResponseSchema(schema *gollm.Schema) error    { return nil }
+func (l *fakeLLM) ListModels(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+func (l *fakeLLM) GenerateCompletion(ctx context.Context, req *gollm.CompletionRequest) (gollm.CompletionResponse, error) {
+	return nil, nil
+}
+
+type fakeChat struct {
+	llm *fakeLLM
+}
+
+func (c *fakeChat) Send(ctx context.Context, contents ...any) (gollm.ChatResponse, error) {
+	return &fakeResponse{text: c.llm.answer}, nil
+}
+func (c *fakeChat) SendStreaming(ctx context.Context, contents ...any) (gollm.ChatResponseIterator, error) {
+	if c.llm.started != nil {
+		c.llm.started <- struct{}{}
+		<-c.llm.release
+	}
+	return func(yield func(gollm.ChatResponse, error) bool) {
+		yield(&fakeResponse{text: c.llm.answer}, nil)
+	}, nil
+}
+func (c *fakeChat) SetFunctionDefinitions(functionDefinitions []*gollm.FunctionDefinition) error {
+	return nil
+}
+func (c *fakeChat) IsRetryableError(err error) bool { return false }
+
+type fakeResponse struct {
+	text string
+}
+
+func (r *fakeResponse) UsageMetadata() any                            { return nil }
+func (r *fakeResponse) Candidates() []gollm.Candidate                 { return []gollm.Candidate{r} }
+func (r *fakeResponse) String() string                                { return r.text }
+func (r *fakeResponse) Parts() []gollm.Part                           { return []gollm.Part{r} }
+func (r *fakeResponse) AsText() (string, bool)                        { return r.text, true }
+func (r *fakeResponse) AsFunctionCalls() ([]gollm.FunctionCall, bool) { return nil, false }
+
+// newTestServer starts a server whose conversations use llm.
+func newTestServer(t *testing.T, llm *fakeLLM, apiKey string, allowedOrigins ...string) *httptest.Server {
+	s := &chatServer{
+		newConversation: func() *agent.Agent {
+			return &agent.Agent{LLM: llm, Model: "fake", MaxIterations: 5, Recorder: &journal.LogRecorder{}, NonInteractive: true}
+		},
+		timeFormat:     ui.TimeFormat{},
+		apiKey:         apiKey,
+		allowedOrigins: allowedOrigins,
+		ctx:            context.Background(),
+		sessions:       &sessionStore{},
+	}
+	server := httptest.NewServer(s.handler())
+	t.Cleanup(func() {
+		server.Close()
+		for _, session := range s.sessions.list() {
+			session.conversation.Close()
+		}
+	})
+	return server
+}
+
+// post sends a POST request with a JSON body and the headers, and returns the response with its body.
+func post(t *testing.T, url string, body string, headers map[string]string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		if value == "" {
+			req.Header.Del(name)
+		} else {
+			req.Header.Set(name, value)
+		}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(b)
+}
+
+func TestServeAuthorization(t *testing.T) {
+	server := newTestServer(t, &fakeLLM{answer: "All pods are running."}, "secret", "https://chat.example.com")
+	request := `{"messages": [{"role": "user", "content": "are my pods running?"}]}`
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{name: "no key", status: http.StatusUnauthorized},
+		{name: "wrong key", headers: map[string]string{"Authorization": "Bearer wrong"}, status: http.StatusUnauthorized},
+		{name: "key", headers: map[string]string{"Authorization": "Bearer secret"}, status: http.StatusOK},
+		{name: "form", headers: map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/x-www-form-urlencoded"}, status: http.StatusUnsupportedMediaType},
+		{name: "no content type", headers: map[string]string{"Authorization": "Bearer secret", "Content-Type": ""}, status: http.StatusUnsupportedMediaType},
+		{name: "content type with charset", headers: map[string]string{"Authorization": "Bearer secret", "Content-Type": "application/json; charset=utf-8"}, status: http.StatusOK},
+		{name: "origin not allowed", headers: map[string]string{"Authorization": "Bearer secret", "Origin": "https://evil.example.com"}, status: http.StatusForbidden},
+		{name: "origin allowed", headers: map[string]string{"Authorization": "Bearer secret", "Origin": "https://chat.example.com"}, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := post(t, server.URL+"/v1/chat/completions", request, tt.headers)
+			if resp.StatusCode != tt.status {
+				t.Fatalf("status = %d, expected %d: %s", resp.StatusCode, tt.status, body)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(body, "All pods are running.") {
+				t.Errorf("response %s does not have the answer", body)
+			}
+		})
+	}
+
+	// Sessions are refused the same way.
+	if resp, body := post(t, server.URL+"/v1/sessions", "", map[string]string{"Authorization": "Bearer secret", "Content-Type": "text/plain"}); resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("creating a session as text: status = %d, expected %d: %s", resp.StatusCode, http.StatusUnsupportedMediaType, body)
+	}
+	if resp, body := post(t, server.URL+"/v1/sessions", "", map[string]string{"Authorization": "Bearer secret", "Origin": "https://evil.example.com"}); resp.StatusCode != http.StatusForbidden {
+		t.Errorf("creating a session from another origin: status = %d, expected %d: %s", resp.StatusCode, http.StatusForbidden, body)
+	}
+}
+
+func TestServeListenWithoutAPIKey(t *testing.T) {
+	t.Setenv(serveAPIKeyEnv, "")
+	err := runServer(context.Background(), serveOptions{listenAddress: "0.0.0.0:0"}, ui.TimeFormat{}, nil)
+	if err == nil || !strings.Contains(err.Error(), serveAPIKeyEnv) {
+		t.Errorf("runServer() error = %v, expected it to require %s", err, serveAPIKeyEnv)
+	}
+}
+
+func TestServeChatCompletionsStream(t *testing.T) {
+	server := newTestServer(t, &fakeLLM{answer: "All pods are running."}, "")
+	resp, body := post(t, server.URL+"/v1/chat/completions", `{"messages": [{"role": "user", "content": "are my pods running?"}], "stream": true}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content-Type = %q, expected text/event-stream", contentType)
+	}
+
+	var content strings.Builder
+	var done bool
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("invalid chunk %q: %v", data, err)
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+	}
+	if !done {
+		t.Errorf("the stream did not end with [DONE]:\n%s", body)
+	}
+	if !strings.Contains(content.String(), "All pods are running.") {
+		t.Errorf("streamed content %q does not have the answer", content.String())
+	}
+}
+
+func TestServeSessionConcurrentQueries(t *testing.T) {
+	llm := &fakeLLM{answer: "All pods are running.", started: make(chan struct{}, 10), release: make(chan struct{})}
+	server := newTestServer(t, llm, "")
+
+	resp, body := post(t, server.URL+"/v1/sessions", "", nil)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("creating a session: status = %d: %s", resp.StatusCode, body)
+	}
+	var session sessionInfo
+	if err := json.Unmarshal([]byte(body), &session); err != nil {
+		t.Fatal(err)
+	}
+	messagesURL := server.URL + "/v1/sessions/" + session.ID + "/messages"
+
+	type result struct {
+		status int
+		body   string
+	}
+	first := make(chan result)
+	go func() {
+		resp, body := post(t, messagesURL, `{"query": "are my pods running?"}`, nil)
+		first <- result{resp.StatusCode, body}
+	}()
+	// Wait until the first query is sent to the LLM.
+	<-llm.started
+
+	resp, body = post(t, messagesURL, `{"query": "and my services?"}`, nil)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("second query: status = %d, expected %d: %s", resp.StatusCode, http.StatusConflict, body)
+	}
+
+	close(llm.release)
+	got := <-first
+	if got.status != http.StatusOK {
+		t.Fatalf("first query: status = %d: %s", got.status, got.body)
+	}
+	var response sessionMessageResponse
+	if err := json.Unmarshal([]byte(got.body), &response); err != nil {
+		t.Fatal(err)
+	}
+	if strings.TrimSpace(response.Answer) != "All pods are running." {
+		t.Errorf("answer = %q, expected %q", response.Answer, "All pods are running.")
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"github.com/spf13/cobra"
+)
+
+// maxSearchResults is the maximum number of matches that /search shows.
+const maxSearchResults = 50
+
+func newSessionsCommand(opt *Options) *cobra.Command {
+	sessionsCmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Work with the transcripts of previous sessions",
+	}
+
+	sessionsCmd.AddCommand(&cobra.Command{
+		Use:   "grep <regex> [path...]",
+		Short: "Search the stored session transcripts",
+		Long:  "Searches the session transcripts stored in --sessions-dir, or the given files and directories, for lines matching a regular expression.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			re, err := regexp.Compile(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid regular expression %q: %w", args[0], err)
+			}
+			paths := args[1:]
+			if len(paths) == 0 {
+				if opt.SessionsDir == "" {
+					return fmt.Errorf("no paths given and --sessions-dir is not set")
+				}
+				sessionsDir, err := expandPathPlaceholders(opt.SessionsDir)
+				if err != nil {
+					return err
+				}
+				paths = []string{sessionsDir}
+			}
+			found, err := grepTranscripts(cmd.OutOrStdout(), re, paths)
+			if err != nil {
+				return err
+			}
+			if !found {
+				// Like grep, exit with a non-zero status when nothing matched.
+				os.Exit(1)
+			}
+			return nil
+		},
+	})
+
+	return sessionsCmd
+}
+
+// grepTranscripts writes the lines of the transcripts in paths that match re, in grep's file:line:text format.
+// Directories are searched recursively. It returns whether any line matched.
+func grepTranscripts(w io.Writer, re *regexp.Regexp, paths []string) (bool, error) {
+	found := false
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			scanner := bufio.NewScanner(f)
+			scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+			for line := 1; scanner.Scan(); line++ {
+				if re.MatchString(scanner.Text()) {
+					found = true
+					fmt.Fprintf(w, "%s:%d:%s\n", p, line, scanner.Text())
+				}
+			}
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("reading %q: %w", p, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return found, fmt.Errorf("searching %q: %w", root, err)
+		}
+	}
+	return found, nil
+}
+
+// saveTranscript stores the transcript of the session in the sessions directory, as Markdown.
+func (s *session) saveTranscript(sessionsDir string, started time.Time) error {
+	dir, err := expandPathPlaceholders(sessionsDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("creating sessions directory: %w", err)
+	}
+	return s.exportDocument(filepath.Join(dir, started.Format("20060102-150405")+".md"))
+}
+
+// searchDocument adds a block listing the lines of the conversation that match the regular expression in arg.
+func (s *session) searchDocument(arg string) error {
+	if arg == "" {
+		return fmt.Errorf("usage: /search <regex>")
+	}
+	re, err := regexp.Compile(arg)
+	if err != nil {
+		return fmt.Errorf("invalid regular expression %q: %w", arg, err)
+	}
+
+	matches := ui.SearchDocument(s.doc, re)
+	if len(matches) == 0 {
+		s.doc.AddBlock(ui.NewAgentTextBlock().WithText(fmt.Sprintf("No matches for `%s`", arg)))
+		return nil
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Found %d matches for `%s`:\n\n", len(matches), arg)
+	for i, match := range matches {
+		if i == maxSearchResults {
+			fmt.Fprintf(&text, "\n... and %d more\n", len(matches)-maxSearchResults)
+			break
+		}
+		fmt.Fprintf(&text, "* [%d] %s\n", match.BlockIndex, strings.TrimSpace(match.Highlight("**", "**")))
+	}
+	text.WriteString("\nThe numbers in brackets are block numbers; use `/expand <number>` to show folded tool output.")
+	s.doc.AddBlock(ui.NewAgentTextBlock().WithText(text.String()))
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui/html"
+	"k8s.io/klog/v2"
+)
+
+// shareSession serves the session on opt.ShareAddress, so other users can attach to it as viewers or approvers.
+// With opt.ShareRequireApproval, the tool calls that modify resources also wait for an approver,
+// for at most opt.ShareApprovalTimeout.
+func shareSession(ctx context.Context, opt *Options, doc *ui.Document, conversation *agent.Agent) (*html.SharedSession, error) {
+	shared, err := html.NewSharedSession(doc, opt.ShareAddress)
+	if err != nil {
+		return nil, fmt.Errorf("sharing the session: %w", err)
+	}
+	go func() {
+		if err := shared.RunServer(ctx); err != nil && ctx.Err() == nil {
+			klog.Errorf("error serving the shared session: %v", err)
+		}
+	}()
+
+	fmt.Fprintf(os.Stderr, "Sharing this session.\n  Viewers:   %s\n  Approvers: %s\n", shared.URL(html.ShareRoleViewer), shared.URL(html.ShareRoleApprover))
+
+	if opt.ShareRequireApproval {
+		conversation.AddHooks(agent.Hooks{
+			BeforeToolCall: func(ctx context.Context, call agent.ToolCallInfo) error {
+				if call.ModifiesResource == "no" || call.Simulated {
+					return nil
+				}
+				doc.AddBlock(ui.NewAgentTextBlock().WithText(fmt.Sprintf("  Waiting for an approver to approve %s\n", call.Description)))
+				// The round waits for the hook, so the wait is bounded: nobody might be attached to approve.
+				waitCtx, cancel := context.WithTimeout(ctx, opt.ShareApprovalTimeout)
+				defer cancel()
+				approved, err := shared.RequestApproval(waitCtx, call.Description)
+				if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+					return fmt.Errorf("rejected: no approver approved it within %s", opt.ShareApprovalTimeout)
+				}
+				if err != nil {
+					return fmt.Errorf("rejected: %w", err)
+				}
+				if !approved {
+					return errors.New("rejected by the approver")
+				}
+				doc.AddBlock(ui.NewAgentTextBlock().WithText("  Approved.\n"))
+				return nil
+			},
+		})
+	}
+	return shared, nil
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/spf13/cobra"
+)
+
+func newToolsCommand(opt *Options) *cobra.Command {
+	toolsCmd := &cobra.Command{
+		Use:   "tools",
+		Short: "Work with the tools available to the agent",
+	}
+
+	var format, outputPath string
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the tool definitions as a manifest for other agent frameworks",
+		Long: `Writes the definitions of the tools (built-in, custom and, with --mcp-client, those discovered on MCP servers)
+as a manifest that other agent frameworks can import:
+
+  openai     the tools array of the OpenAI chat completions API
+  langchain  the MCP servers to connect to, in the format of langchain-mcp-adapters connections, and the tools
+             they serve; the built-in and custom tools are served by kubectl-ai --mcp-server`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := handleCustomTools(opt.ToolConfigPaths); err != nil {
+				return fmt.Errorf("failed to process custom tools: %w", err)
+			}
+			servers := map[string]tools.ManifestServer{
+				tools.ManifestServerName: {Command: "kubectl-ai", Args: []string{"--mcp-server"}},
+			}
+			if opt.MCPClient {
+				if _, err := InitializeMCPClient(opt.MCPServers); err != nil {
+					return fmt.Errorf("discovering MCP tools: %w", err)
+				}
+				config, err := loadMCPConfig(opt.MCPServers)
+				if err != nil {
+					return err
+				}
+				// Credentials and environment variables are left out, as the manifest may be shared.
+				for _, server := range config.Servers {
+					servers[server.Name] = tools.ManifestServer{Command: server.Command, Args: server.Args, URL: server.URL}
+				}
+			}
+
+			allTools := tools.Default()
+			manifest, err := tools.ToolManifest(allTools.AllTools(), format, servers)
+			if err != nil {
+				return err
+			}
+
+			var w io.Writer = cmd.OutOrStdout()
+			if outputPath != "" {
+				f, err := os.Create(outputPath)
+				if err != nil {
+					return fmt.Errorf("creating manifest file: %w", err)
+				}
+				defer f.Close()
+				w = f
+			}
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(manifest)
+		},
+	}
+	exportCmd.Flags().StringVar(&format, "format", tools.ManifestFormatOpenAI, "format of the manifest. Supported values: openai, langchain")
+	exportCmd.Flags().StringVarP(&outputPath, "output", "o", "", "file to write the manifest to (default stdout)")
+	toolsCmd.AddCommand(exportCmd)
+
+	toolsCmd.AddCommand(&cobra.Command{
+		Use:   "size",
+		Short: "Show the size of the tool definitions sent to the LLM, as they are and with --slim-tool-schemas",
+		Long: `Shows the size of the definition of each tool (built-in, custom and, with --mcp-client, those discovered on
+MCP servers) as sent to the LLM with every request, and the size with --slim-tool-schemas, largest first.
+Providers limit the size of tool schemas, and large schemas use up the context window; the system-prompt
+event of the trace file also records the size of the definitions of each session (toolsBytes).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := handleCustomTools(opt.ToolConfigPaths); err != nil {
+				return fmt.Errorf("failed to process custom tools: %w", err)
+			}
+			if opt.MCPClient {
+				if _, err := InitializeMCPClient(opt.MCPServers); err != nil {
+					return fmt.Errorf("discovering MCP tools: %w", err)
+				}
+			}
+
+			type toolSize struct {
+				name, source string
+				bytes, slim  int
+			}
+			var sizes []toolSize
+			var definitions, slimDefinitions []*gollm.FunctionDefinition
+			allTools := tools.Default()
+			for _, tool := range allTools.AllTools() {
+				source := "built-in"
+				switch tool := tool.(type) {
+				case *tools.CustomTool:
+					source = "custom"
+				case *tools.MCPTool:
+					source = "mcp:" + tool.ServerName()
+				}
+				definition := tool.FunctionDefinition()
+				slim := tools.SlimFunctionDefinition(definition)
+				definitions = append(definitions, definition)
+				slimDefinitions = append(slimDefinitions, slim)
+				sizes = append(sizes, toolSize{name: tool.Name(), source: source, bytes: tools.FunctionDefinitionsSize(definition), slim: tools.FunctionDefinitionsSize(slim)})
+			}
+			slices.SortStableFunc(sizes, func(a, b toolSize) int { return b.bytes - a.bytes })
+
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "TOOL\tSOURCE\tBYTES\tSLIM BYTES")
+			for _, size := range sizes {
+				fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", size.name, size.source, size.bytes, size.slim)
+			}
+			total, slimTotal := tools.FunctionDefinitionsSize(definitions...), tools.FunctionDefinitionsSize(slimDefinitions...)
+			fmt.Fprintf(w, "TOTAL\t%d tools\t%d\t%d\n", len(sizes), total, slimTotal)
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			// Tokenizers differ, but JSON averages about 4 bytes per token.
+			fmt.Fprintf(cmd.OutOrStdout(), "\nAbout %d tokens, or %d with --slim-tool-schemas.\n", total/4, slimTotal/4)
+			return nil
+		},
+	})
+
+	return toolsCmd
+}
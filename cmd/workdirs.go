@@ -0,0 +1,109 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/agent"
+	"github.com/spf13/cobra"
+)
+
+func newWorkDirsCommand() *cobra.Command {
+	workDirsCmd := &cobra.Command{
+		Use:   "workdirs",
+		Short: "Inspect and clean up the working directories kept by past sessions",
+		Long:  "Each session writes generated manifests, tool output and checkpoints to a temporary working directory, which is kept after the session ends according to --workdir-retention.",
+	}
+
+	workDirsCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the working directories, most recently modified first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dirs, err := agent.ListWorkDirs()
+			if err != nil {
+				return err
+			}
+			if len(dirs) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No working directories.")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "PATH\tSESSION\tMODEL\tMODIFIED\tSTATUS\tFILES\tSIZE")
+			for _, dir := range dirs {
+				session, model := "-", "-"
+				if dir.Info != nil {
+					session, model = dir.Info.SessionID, dir.Info.Model
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", dir.Path, session, model, dir.Modified.Format("2006-01-02 15:04"), workDirStatus(dir), dir.Files, formatSize(dir.Size))
+			}
+			return w.Flush()
+		},
+	})
+
+	var olderThan time.Duration
+	cleanCmd := &cobra.Command{
+		Use:   "clean",
+		Short: "Remove working directories",
+		Long:  "Removes the working directories not modified for --older-than, or all of them. The directories of sessions that are still running are never removed.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			removed, err := agent.CleanWorkDirs(olderThan)
+			for _, dir := range removed {
+				fmt.Fprintf(cmd.OutOrStdout(), "Removed %s\n", dir.Path)
+			}
+			if len(removed) == 0 && err == nil {
+				fmt.Fprintln(cmd.OutOrStdout(), "Nothing to remove.")
+			}
+			return err
+		},
+	}
+	cleanCmd.Flags().DurationVar(&olderThan, "older-than", 0, "only remove the working directories not modified for this long, e.g. 72h")
+	workDirsCmd.AddCommand(cleanCmd)
+
+	return workDirsCmd
+}
+
+// workDirStatus describes the state of the session that left a working directory.
+func workDirStatus(dir *agent.WorkDir) string {
+	switch {
+	case dir.Info == nil:
+		return "unknown"
+	case dir.Running():
+		return "running"
+	case dir.Info.Ended.IsZero():
+		return "crashed"
+	case dir.Info.Failed:
+		return "failed"
+	}
+	return "ended"
+}
+
+// formatSize formats a number of bytes for humans, e.g. 1.5 MiB.
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,337 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// Workspace binds the settings of an environment, selected together with --workspace.
+type Workspace struct {
+	// Context is the kube context to use; empty uses the current context.
+	Context string `json:"context,omitempty"`
+	// Namespace is the default namespace of kubectl commands; empty uses the namespace of the context.
+	Namespace   string `json:"namespace,omitempty"`
+	LLMProvider string `json:"llmProvider,omitempty"`
+	Model       string `json:"model,omitempty"`
+	// PromptProfile is the name of a profile in promptProfiles in the config file.
+	PromptProfile string `json:"promptProfile,omitempty"`
+	// ConfirmationPolicy rules are evaluated before those of the config file.
+	ConfirmationPolicy *tools.ConfirmationPolicy `json:"confirmationPolicy,omitempty"`
+	// MCPServers are the names of the MCP servers to connect to; setting them enables MCP client mode.
+	MCPServers []string `json:"mcpServers,omitempty"`
+}
+
+// workspaceNameRegexp matches the names of workspaces, which are also file names.
+var workspaceNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+func newWorkspacesCommand(opt *Options) *cobra.Command {
+	workspacesCmd := &cobra.Command{
+		Use:   "workspaces",
+		Short: "Manage the workspaces selected with --workspace",
+	}
+
+	workspacesCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List the saved workspaces",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir, err := workspacesDir(opt)
+			if err != nil {
+				return err
+			}
+			paths, err := filepath.Glob(filepath.Join(dir, "*.yaml"))
+			if err != nil {
+				return err
+			}
+			if len(paths) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "No workspaces.")
+				return nil
+			}
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCONTEXT\tNAMESPACE\tMODEL\tPROMPT PROFILE\tPOLICY RULES\tMCP SERVERS")
+			for _, path := range paths {
+				name := strings.TrimSuffix(filepath.Base(path), ".yaml")
+				ws, err := loadWorkspace(opt, name)
+				if err != nil {
+					return err
+				}
+				model := ws.Model
+				if ws.LLMProvider != "" {
+					model = ws.LLMProvider + "/" + model
+				}
+				rules := 0
+				if ws.ConfirmationPolicy != nil {
+					rules = len(ws.ConfirmationPolicy.Rules)
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", name, orDash(ws.Context), orDash(ws.Namespace), orDash(model), orDash(ws.PromptProfile), rules, orDash(strings.Join(ws.MCPServers, ",")))
+			}
+			return w.Flush()
+		},
+	})
+
+	var kubeContext, namespace string
+	saveCmd := &cobra.Command{
+		Use:   "save <name>",
+		Short: "Create or update a workspace",
+		Long: `Creates a workspace, or updates the settings of an existing one, from --context, --namespace, --llm-provider,
+--model, --prompt-profile and --mcp-servers; settings that are not given are left as they are. The confirmation
+policy of a workspace is edited in its file, in --workspaces-dir.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+			ws, err := loadWorkspace(opt, name)
+			if errors.Is(err, os.ErrNotExist) {
+				ws = &Workspace{}
+			} else if err != nil {
+				return err
+			}
+			flags := cmd.Flags()
+			if flags.Changed("context") {
+				ws.Context = kubeContext
+			}
+			if flags.Changed("namespace") {
+				ws.Namespace = namespace
+			}
+			if flags.Changed("llm-provider") {
+				ws.LLMProvider = opt.ProviderID
+			}
+			if flags.Changed("model") {
+				ws.Model = opt.ModelID
+			}
+			if flags.Changed("prompt-profile") {
+				ws.PromptProfile = opt.PromptProfile
+			}
+			if flags.Changed("mcp-servers") {
+				ws.MCPServers = opt.MCPServers
+			}
+			path, err := saveWorkspace(opt, name, ws)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "Saved workspace %s in %s, use it with --workspace %s\n", name, path, name)
+			return nil
+		},
+	}
+	saveCmd.Flags().StringVar(&kubeContext, "context", "", "kube context of the workspace; empty uses the current context")
+	saveCmd.Flags().StringVar(&namespace, "namespace", "", "default namespace of the workspace; empty uses the namespace of the context")
+	workspacesCmd.AddCommand(saveCmd)
+
+	workspacesCmd.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a workspace",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path, err := workspacePath(opt, args[0])
+			if err != nil {
+				return err
+			}
+			if err := os.Remove(path); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return fmt.Errorf("no workspace named %q, see kubectl-ai workspaces list", args[0])
+				}
+				return err
+			}
+			return nil
+		},
+	})
+
+	return workspacesCmd
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// workspacesDir returns the directory given by --workspaces-dir.
+func workspacesDir(opt *Options) (string, error) {
+	if opt.WorkspacesDir == "" {
+		return "", fmt.Errorf("--workspaces-dir is not set")
+	}
+	return expandPathPlaceholders(opt.WorkspacesDir)
+}
+
+// workspacePath returns the file of the workspace with the given name.
+func workspacePath(opt *Options, name string) (string, error) {
+	if !workspaceNameRegexp.MatchString(name) {
+		return "", fmt.Errorf("invalid workspace name %q, only letters, digits, '.', '_' and '-' are allowed", name)
+	}
+	dir, err := workspacesDir(opt)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name+".yaml"), nil
+}
+
+// loadWorkspace loads the workspace with the given name; the error wraps os.ErrNotExist if there is none.
+func loadWorkspace(opt *Options, name string) (*Workspace, error) {
+	path, err := workspacePath(opt, name)
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("no workspace named %q, see kubectl-ai workspaces list: %w", name, err)
+		}
+		return nil, err
+	}
+	var ws Workspace
+	if err := yaml.UnmarshalStrict(b, &ws); err != nil {
+		return nil, fmt.Errorf("parsing workspace %s: %w", path, err)
+	}
+	if ws.ConfirmationPolicy != nil {
+		if err := ws.ConfirmationPolicy.Validate(); err != nil {
+			return nil, fmt.Errorf("workspace %s: %w", path, err)
+		}
+	}
+	return &ws, nil
+}
+
+// saveWorkspace saves the workspace with the given name, and returns the path of its file.
+func saveWorkspace(opt *Options, name string, ws *Workspace) (string, error) {
+	path, err := workspacePath(opt, name)
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.Marshal(ws)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, b, 0o600)
+}
+
+// applyWorkspace applies the workspace selected by --workspace to opt; settings given by flags take precedence.
+// The kube context and namespace are applied by applyWorkspaceKubeconfig, once the kubeconfig is resolved.
+func applyWorkspace(opt *Options, flags *pflag.FlagSet) error {
+	if opt.Workspace == "" {
+		return nil
+	}
+	ws, err := loadWorkspace(opt, opt.Workspace)
+	if err != nil {
+		return err
+	}
+	if ws.LLMProvider != "" && !flags.Changed("llm-provider") {
+		opt.ProviderID = ws.LLMProvider
+	}
+	if ws.Model != "" && !flags.Changed("model") {
+		opt.ModelID = ws.Model
+	}
+	if ws.PromptProfile != "" && !flags.Changed("prompt-profile") {
+		opt.PromptProfile = ws.PromptProfile
+	}
+	if len(ws.MCPServers) > 0 && !flags.Changed("mcp-servers") {
+		opt.MCPServers = ws.MCPServers
+		if !flags.Changed("mcp-client") {
+			opt.MCPClient = true
+		}
+	}
+	if ws.ConfirmationPolicy != nil {
+		opt.ConfirmationPolicy.Rules = append(slices.Clone(ws.ConfirmationPolicy.Rules), opt.ConfirmationPolicy.Rules...)
+	}
+	opt.workspace = ws
+	klog.Infof("Using workspace %q", opt.Workspace)
+	return nil
+}
+
+// applyWorkspaceKubeconfig selects the kube context and namespace of the workspace, with a single kubeconfig file
+// that merges the resolved ones, as kubectl does, and makes the context of the workspace current, with its namespace.
+// Having a single file lets executors that mount the kubeconfig, such as docker-run, use it. The file is self-contained,
+// so it holds the credentials of the original files; it is private, and removed by the returned function.
+func applyWorkspaceKubeconfig(ctx context.Context, opt *Options) (func(), error) {
+	ws := opt.workspace
+	if ws == nil || (ws.Context == "" && ws.Namespace == "") {
+		return func() {}, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "kubectl", "config", "view", "--flatten", "--raw")
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+opt.KubeConfigPath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	b, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("merging kubeconfig %s: %w: %s", opt.KubeConfigPath, err, strings.TrimSpace(stderr.String()))
+	}
+	var kubeconfig map[string]any
+	if err := yaml.Unmarshal(b, &kubeconfig); err != nil {
+		return nil, fmt.Errorf("parsing kubeconfig %s: %w", opt.KubeConfigPath, err)
+	}
+
+	contextName := ws.Context
+	if contextName == "" {
+		contextName, _ = kubeconfig["current-context"].(string)
+	}
+	if contextName == "" {
+		return nil, fmt.Errorf("no kube context is set in the workspace or current in %s", opt.KubeConfigPath)
+	}
+	var kubeContext map[string]any
+	contexts, _ := kubeconfig["contexts"].([]any)
+	for _, c := range contexts {
+		if named, ok := c.(map[string]any); ok && named["name"] == contextName {
+			kubeContext, _ = named["context"].(map[string]any)
+		}
+	}
+	if kubeContext == nil {
+		return nil, fmt.Errorf("kube context %q not found in %s", contextName, opt.KubeConfigPath)
+	}
+	kubeconfig["current-context"] = contextName
+	if ws.Namespace != "" {
+		kubeContext["namespace"] = ws.Namespace
+	}
+
+	if b, err = yaml.Marshal(kubeconfig); err != nil {
+		return nil, err
+	}
+	// CreateTemp creates the file readable only by us.
+	f, err := os.CreateTemp("", "kubectl-ai-workspace-*.kubeconfig")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() { os.Remove(f.Name()) }
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		cleanup()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		cleanup()
+		return nil, err
+	}
+	opt.KubeConfigPath = f.Name()
+	klog.Infof("Using kube context %q, namespace %q of workspace %q", contextName, kubeContext["namespace"], opt.Workspace)
+	return cleanup, nil
+}
@@ -231,16 +231,20 @@ type AzureOpenAIChat struct {
 }
 
 func (c *AzureOpenAIChat) Send(ctx context.Context, contents ...any) (ChatResponse, error) {
-	for _, content := range contents {
+	for _, content := range coalesceContents(contents) {
 		switch v := content.(type) {
 		case string:
 			message := azopenai.ChatRequestUserMessage{
 				Content: azopenai.NewChatRequestUserMessageContent(v),
 			}
 			c.history = append(c.history, &message)
-		case FunctionCallResult:
+		case []FunctionCallResult:
+			text, err := functionCallResultsText(v)
+			if err != nil {
+				return nil, err
+			}
 			message := azopenai.ChatRequestUserMessage{
-				Content: azopenai.NewChatRequestUserMessageContent(fmt.Sprintf("Function call result: %s", v.Result)),
+				Content: azopenai.NewChatRequestUserMessageContent(text),
 			}
 			c.history = append(c.history, &message)
 		default:
@@ -328,7 +328,9 @@ func (rc *retryChat[C]) Send(ctx context.Context, contents ...any) (ChatResponse
 	return Retry[ChatResponse](ctx, rc.config, rc.underlying.IsRetryableError, operation)
 }
 
-// Embed implements the Client interface for the retryClient decorator.
+// SendStreaming implements the Chat interface for the retryChat decorator.
+// Streams are not retried: most providers only make the request when the stream is read,
+// and retrying a stream that already produced chunks would repeat them.
 func (rc *retryChat[C]) SendStreaming(ctx context.Context, contents ...any) (ChatResponseIterator, error) {
 	return rc.underlying.SendStreaming(ctx, contents...)
 }
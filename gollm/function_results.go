@@ -0,0 +1,69 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gollm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Providers with native tool messages (Gemini, OpenAI) send the results of the function calls of one turn
+// together: Gemini as the parts of a single content, OpenAI as consecutive tool messages, one per call as its
+// API requires. Providers without them get the results as user text; coalesceContents lets them send the
+// results of one turn as a single message, rather than a message per result.
+
+// coalesceContents returns the contents with consecutive function call results grouped into a []FunctionCallResult.
+// Other contents are returned as they are.
+func coalesceContents(contents []any) []any {
+	var coalesced []any
+	for _, content := range contents {
+		result, ok := content.(FunctionCallResult)
+		if !ok {
+			coalesced = append(coalesced, content)
+			continue
+		}
+		if n := len(coalesced); n > 0 {
+			if results, ok := coalesced[n-1].([]FunctionCallResult); ok {
+				coalesced[n-1] = append(results, result)
+				continue
+			}
+		}
+		coalesced = append(coalesced, []FunctionCallResult{result})
+	}
+	return coalesced
+}
+
+// functionCallResultsText formats function call results as the text of a single message, with each result as JSON.
+func functionCallResultsText(results []FunctionCallResult) (string, error) {
+	var sb strings.Builder
+	if len(results) == 1 {
+		sb.WriteString("Function call result: ")
+	} else {
+		sb.WriteString("Function call results:\n")
+	}
+	for _, result := range results {
+		b, err := json.Marshal(result.Result)
+		if err != nil {
+			return "", fmt.Errorf("marshalling function call result %q: %w", result.Name, err)
+		}
+		if len(results) == 1 {
+			sb.Write(b)
+			break
+		}
+		fmt.Fprintf(&sb, "- %s: %s\n", result.Name, b)
+	}
+	return sb.String(), nil
+}
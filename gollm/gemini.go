@@ -206,6 +206,34 @@ func (c *GoogleAIClient) Close() error {
 	return nil
 }
 
+// defaultGeminiEmbeddingModel is the embedding model used when none is given.
+const defaultGeminiEmbeddingModel = "text-embedding-004"
+
+var _ Embedder = &GoogleAIClient{}
+
+// Embed returns the embeddings of the texts.
+func (c *GoogleAIClient) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if model == "" {
+		model = defaultGeminiEmbeddingModel
+	}
+	var contents []*genai.Content
+	for _, text := range texts {
+		contents = append(contents, genai.NewContentFromText(text, genai.RoleUser))
+	}
+	response, err := c.client.Models.EmbedContent(ctx, model, contents, nil)
+	if err != nil {
+		return nil, fmt.Errorf("embedding with %q: %w", model, err)
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(response.Embeddings), len(texts))
+	}
+	embeddings := make([][]float32, len(texts))
+	for i, embedding := range response.Embeddings {
+		embeddings[i] = embedding.Values
+	}
+	return embeddings, nil
+}
+
 // SetResponseSchema constrains LLM responses to match the provided schema.
 // Calling with nil will clear the current schema.
 func (c *GoogleAIClient) SetResponseSchema(responseSchema *Schema) error {
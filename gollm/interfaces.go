@@ -40,6 +40,12 @@ type Client interface {
 	ListModels(ctx context.Context) ([]string, error)
 }
 
+// Embedder is implemented by the clients whose provider can compute text embeddings.
+type Embedder interface {
+	// Embed returns the embeddings of the texts, in order. An empty model selects the provider's default embedding model.
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
 // Chat is an active conversation with a language model.
 // Messages are sent and received, and add to a conversation history.
 type Chat interface {
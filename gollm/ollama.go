@@ -136,7 +136,7 @@ func (r *OllamaCompletionResponse) UsageMetadata() any {
 
 func (c *OllamaChat) Send(ctx context.Context, contents ...any) (ChatResponse, error) {
 	log := klog.FromContext(ctx)
-	for _, content := range contents {
+	for _, content := range coalesceContents(contents) {
 		switch v := content.(type) {
 		case string:
 			message := api.Message{
@@ -144,10 +144,14 @@ func (c *OllamaChat) Send(ctx context.Context, contents ...any) (ChatResponse, e
 				Content: v,
 			}
 			c.history = append(c.history, message)
-		case FunctionCallResult:
+		case []FunctionCallResult:
+			text, err := functionCallResultsText(v)
+			if err != nil {
+				return nil, err
+			}
 			message := api.Message{
 				Role:    "user",
-				Content: fmt.Sprintf("Function call result: %s", v.Result),
+				Content: text,
 			}
 			c.history = append(c.history, message)
 		default:
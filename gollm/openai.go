@@ -134,6 +134,40 @@ func (c *OpenAIClient) Close() error {
 	return nil
 }
 
+// defaultOpenAIEmbeddingModel is the embedding model used when none is given.
+const defaultOpenAIEmbeddingModel = openai.EmbeddingModelTextEmbedding3Small
+
+var _ Embedder = &OpenAIClient{}
+
+// Embed returns the embeddings of the texts.
+func (c *OpenAIClient) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if model == "" {
+		model = defaultOpenAIEmbeddingModel
+	}
+	response, err := c.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: model,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding with %q: %w", model, err)
+	}
+	if len(response.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(response.Data), len(texts))
+	}
+	embeddings := make([][]float32, len(texts))
+	for _, data := range response.Data {
+		if data.Index < 0 || int(data.Index) >= len(texts) {
+			return nil, fmt.Errorf("embedding has unexpected index %d", data.Index)
+		}
+		embedding := make([]float32, len(data.Embedding))
+		for i, value := range data.Embedding {
+			embedding[i] = float32(value)
+		}
+		embeddings[data.Index] = embedding
+	}
+	return embeddings, nil
+}
+
 // StartChat starts a new chat session.
 func (c *OpenAIClient) StartChat(systemPrompt, model string) Chat {
 	// Get the model to use for this chat
@@ -23,6 +23,10 @@ type RecordCompletionResponse struct {
 }
 
 type RecordChatResponse struct {
-	// TODO: Structured data?
+	// Text is the text content of the response.
+	Text string `json:"text,omitempty"`
+	// FunctionCalls are the function calls requested in the response.
+	FunctionCalls []FunctionCall `json:"functionCalls,omitempty"`
+	// Raw is the provider-specific response.
 	Raw any `json:"raw"`
 }
@@ -31,8 +31,17 @@ func BuildSchemaFor(t reflect.Type) *Schema {
 		out.Type = TypeString
 	case reflect.Bool:
 		out.Type = TypeBoolean
-	case reflect.Int:
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
 		out.Type = TypeInteger
+	case reflect.Float32, reflect.Float64:
+		out.Type = TypeNumber
+	case reflect.Pointer:
+		return BuildSchemaFor(t.Elem())
+	case reflect.Interface:
+		// Any value is allowed; we leave the type empty.
+	case reflect.Map:
+		out.Type = TypeObject
 	case reflect.Struct:
 		out.Type = TypeObject
 		out.Properties = make(map[string]*Schema)
@@ -41,19 +50,18 @@ func BuildSchemaFor(t reflect.Type) *Schema {
 		for i := 0; i < numFields; i++ {
 			field := t.Field(i)
 			jsonTag := field.Tag.Get("json")
-			if jsonTag == "" {
+			if jsonTag == "" || jsonTag == "-" {
 				continue
 			}
-			if strings.HasSuffix(jsonTag, ",omitempty") {
-				jsonTag = strings.TrimSuffix(jsonTag, ",omitempty")
-			} else {
-				required = append(required, jsonTag)
+			name, options, _ := strings.Cut(jsonTag, ",")
+			if !strings.Contains(","+options+",", ",omitempty,") {
+				required = append(required, name)
 			}
 
 			fieldType := field.Type
 
 			fieldSchema := BuildSchemaFor(fieldType)
-			out.Properties[jsonTag] = fieldSchema
+			out.Properties[name] = fieldSchema
 		}
 
 		if len(required) != 0 {
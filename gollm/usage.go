@@ -0,0 +1,184 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gollm
+
+import (
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/ai/azopenai"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+// Usage is a provider-independent summary of the tokens used by a request.
+type Usage struct {
+	// InputTokens is the number of tokens in the prompt, including the chat history.
+	InputTokens int `json:"inputTokens,omitempty"`
+	// OutputTokens is the number of tokens generated in the response.
+	OutputTokens int `json:"outputTokens,omitempty"`
+	// TotalTokens is the total number of tokens processed for the request.
+	TotalTokens int `json:"totalTokens,omitempty"`
+	// CachedInputTokens is the number of input tokens read from the provider's prompt cache, if reported.
+	CachedInputTokens int `json:"cachedInputTokens,omitempty"`
+}
+
+// UsageFromMetadata converts the provider-specific value returned by UsageMetadata into a Usage.
+// It returns false if the metadata is missing or of an unknown type.
+func UsageFromMetadata(metadata any) (Usage, bool) {
+	var usage Usage
+	switch m := metadata.(type) {
+	case *genai.GenerateContentResponseUsageMetadata:
+		if m == nil {
+			return usage, false
+		}
+		usage = Usage{
+			InputTokens:  int(m.PromptTokenCount),
+			OutputTokens: int(m.CandidatesTokenCount),
+			TotalTokens:  int(m.TotalTokenCount),
+
+			CachedInputTokens: int(m.CachedContentTokenCount),
+		}
+	case openai.CompletionUsage:
+		usage = Usage{
+			InputTokens:  int(m.PromptTokens),
+			OutputTokens: int(m.CompletionTokens),
+			TotalTokens:  int(m.TotalTokens),
+
+			CachedInputTokens: int(m.PromptTokensDetails.CachedTokens),
+		}
+	case *azopenai.CompletionsUsage:
+		if m == nil {
+			return usage, false
+		}
+		if m.PromptTokens != nil {
+			usage.InputTokens = int(*m.PromptTokens)
+		}
+		if m.CompletionTokens != nil {
+			usage.OutputTokens = int(*m.CompletionTokens)
+		}
+		if m.TotalTokens != nil {
+			usage.TotalTokens = int(*m.TotalTokens)
+		}
+		if m.PromptTokensDetails != nil && m.PromptTokensDetails.CachedTokens != nil {
+			usage.CachedInputTokens = int(*m.PromptTokensDetails.CachedTokens)
+		}
+	case Usage:
+		usage = m
+	case *Usage:
+		if m == nil {
+			return usage, false
+		}
+		usage = *m
+	default:
+		return usage, false
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.InputTokens + usage.OutputTokens
+	}
+	return usage, usage.TotalTokens > 0
+}
+
+// knownContextWindows are the context window sizes (in tokens) of common model families, by model name prefix.
+// More specific prefixes must come first.
+var knownContextWindows = []struct {
+	prefix string
+	tokens int
+}{
+	{"gemini-2.5", 1_048_576},
+	{"gemini-2.0", 1_048_576},
+	{"gemini-1.5-pro", 2_097_152},
+	{"gemini-1.5", 1_048_576},
+	{"gpt-4.1", 1_047_576},
+	{"gpt-4o", 128_000},
+	{"gpt-4-turbo", 128_000},
+	{"o1", 200_000},
+	{"o3", 200_000},
+	{"o4", 200_000},
+	{"grok-3", 131_072},
+	{"claude", 200_000},
+}
+
+// DefaultContextWindow returns the context window size of the given model, if it is known.
+func DefaultContextWindow(model string) (int, bool) {
+	model = strings.ToLower(model)
+	// Strip any path-like prefix, e.g. models/gemini-2.5-pro or openai/gpt-4o
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, known := range knownContextWindows {
+		if strings.HasPrefix(model, known.prefix) {
+			return known.tokens, true
+		}
+	}
+	return 0, false
+}
+
+// TokenPrice is the price of a model's tokens, in US dollars per million tokens.
+type TokenPrice struct {
+	Input  float64 `json:"input,omitempty"`
+	Output float64 `json:"output,omitempty"`
+}
+
+// Known returns true if the price is set.
+func (p TokenPrice) Known() bool {
+	return p.Input > 0 || p.Output > 0
+}
+
+// Cost returns the cost of the usage in US dollars.
+func (p TokenPrice) Cost(usage Usage) float64 {
+	return (float64(usage.InputTokens)*p.Input + float64(usage.OutputTokens)*p.Output) / 1_000_000
+}
+
+// knownTokenPrices are the list prices of common models, by model name prefix; they are only an estimate,
+// as providers change their prices and offer discounts (e.g. for cached input).
+// More specific prefixes must come first.
+var knownTokenPrices = []struct {
+	prefix string
+	price  TokenPrice
+}{
+	{"gemini-2.5-pro", TokenPrice{Input: 1.25, Output: 10}},
+	{"gemini-2.5-flash-lite", TokenPrice{Input: 0.10, Output: 0.40}},
+	{"gemini-2.5-flash", TokenPrice{Input: 0.30, Output: 2.50}},
+	{"gemini-2.0-flash-lite", TokenPrice{Input: 0.075, Output: 0.30}},
+	{"gemini-2.0-flash", TokenPrice{Input: 0.10, Output: 0.40}},
+	{"gpt-4.1-nano", TokenPrice{Input: 0.10, Output: 0.40}},
+	{"gpt-4.1-mini", TokenPrice{Input: 0.40, Output: 1.60}},
+	{"gpt-4.1", TokenPrice{Input: 2, Output: 8}},
+	{"gpt-4o-mini", TokenPrice{Input: 0.15, Output: 0.60}},
+	{"gpt-4o", TokenPrice{Input: 2.50, Output: 10}},
+	{"o3-mini", TokenPrice{Input: 1.10, Output: 4.40}},
+	{"o4-mini", TokenPrice{Input: 1.10, Output: 4.40}},
+	{"o3", TokenPrice{Input: 2, Output: 8}},
+	{"grok-3-mini", TokenPrice{Input: 0.30, Output: 0.50}},
+	{"grok-3", TokenPrice{Input: 3, Output: 15}},
+	{"claude-opus-4", TokenPrice{Input: 15, Output: 75}},
+	{"claude-sonnet-4", TokenPrice{Input: 3, Output: 15}},
+	{"claude-3-5-haiku", TokenPrice{Input: 0.80, Output: 4}},
+}
+
+// DefaultTokenPrice returns the list price of the given model, if it is known.
+func DefaultTokenPrice(model string) (TokenPrice, bool) {
+	model = strings.ToLower(model)
+	// Strip any path-like prefix, e.g. models/gemini-2.5-pro or openai/gpt-4o
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	for _, known := range knownTokenPrices {
+		if strings.HasPrefix(model, known.prefix) {
+			return known.price, true
+		}
+	}
+	return TokenPrice{}, false
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// flagAnomalies finds the anomalous values in the output of a command, such as restarting or OOMKilled containers
+// and nodes under pressure, and highlights them for the user. It returns them for the LLM, one per line,
+// or nil if there are none.
+func (a *Agent) flagAnomalies(output any) []string {
+	if !a.HighlightAnomalies {
+		return nil
+	}
+	execResult, ok := output.(*tools.ExecResult)
+	if !ok || execResult == nil {
+		return nil
+	}
+	anomalies := tools.DetectAnomalies(execResult.Stdout)
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	var notes []string
+	var text strings.Builder
+	text.WriteString("  Anomalies in the output:\n\n")
+	for _, anomaly := range anomalies {
+		notes = append(notes, anomaly.String())
+		fmt.Fprintf(&text, "  * %s: `%s`\n", strings.Join(anomaly.Reasons, ", "), anomaly.Line)
+	}
+	block := ui.NewAgentTextBlock().WithText(text.String())
+	block.SetColor(ui.ColorYellow)
+	a.doc.AddBlock(block)
+	return notes
+}
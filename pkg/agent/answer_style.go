@@ -0,0 +1,91 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// AnswerStyle is a preset for the form of the answers, for the audience they are written for.
+type AnswerStyle string
+
+const (
+	// AnswerStyleDefault lets the LLM choose the form of its answers.
+	AnswerStyleDefault AnswerStyle = ""
+	// AnswerStyleConciseOps is for an engineer in the middle of an operation: the conclusion and what to do next.
+	AnswerStyleConciseOps AnswerStyle = "concise-ops"
+	// AnswerStyleDetailedReport is a thorough report of the investigation, with its evidence.
+	AnswerStyleDetailedReport AnswerStyle = "detailed-report"
+	// AnswerStyleExecutiveSummary is a short summary in plain language, for people who are not Kubernetes experts.
+	AnswerStyleExecutiveSummary AnswerStyle = "executive-summary"
+	// AnswerStyleRunbookSteps is a sequence of steps that someone else can follow.
+	AnswerStyleRunbookSteps AnswerStyle = "runbook-steps"
+)
+
+// AnswerStyles are the available answer styles, other than the default.
+var AnswerStyles = []AnswerStyle{AnswerStyleConciseOps, AnswerStyleDetailedReport, AnswerStyleExecutiveSummary, AnswerStyleRunbookSteps}
+
+// answerStyleInstructions tell the LLM how to write its answers in each style.
+var answerStyleInstructions = map[AnswerStyle]string{
+	AnswerStyleConciseOps: `Answer for an engineer in the middle of an operation: lead with the conclusion in one or two sentences,
+then the key evidence and the exact commands to run next, as short bullet points. Leave out background and explanations they did not ask for.`,
+	AnswerStyleDetailedReport: `Answer with a thorough report, with headings: the context, the investigation step by step with its evidence
+(quote the relevant output), the root cause or the hypotheses and how confident you are in them, the changes made, and recommendations.`,
+	AnswerStyleExecutiveSummary: `Answer for a manager who is not a Kubernetes expert, in a few sentences of plain language: what happened,
+the impact on users or the service, the current status, and what happens next. Avoid jargon, commands and resource names unless they are needed.`,
+	AnswerStyleRunbookSteps: `Answer as a runbook that someone else can follow: start with the prerequisites, then give numbered steps,
+each with the exact command to run, what to expect in its output and what to do if it differs, and end with how to verify the result.`,
+}
+
+// ParseAnswerStyle returns the answer style named s; "" and "default" are the default style.
+func ParseAnswerStyle(s string) (AnswerStyle, error) {
+	if s == "" || s == "default" {
+		return AnswerStyleDefault, nil
+	}
+	style := AnswerStyle(s)
+	if _, ok := answerStyleInstructions[style]; !ok {
+		var names []string
+		for _, style := range AnswerStyles {
+			names = append(names, string(style))
+		}
+		return "", fmt.Errorf("unknown answer style %q, supported values: default, %s", s, strings.Join(names, ", "))
+	}
+	return style, nil
+}
+
+// answerStyleMessage is sent with each query to ask for answers in the style, or "" for the default style.
+func answerStyleMessage(style AnswerStyle) string {
+	instructions, ok := answerStyleInstructions[style]
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("Answer style (%s): %s", style, instructions)
+}
+
+// ExportDetail returns how much of the conversation exports include for the style: the commands without their output
+// for operators, and only the queries and answers for executive summaries.
+func (s AnswerStyle) ExportDetail() ui.ExportDetail {
+	switch s {
+	case AnswerStyleConciseOps, AnswerStyleRunbookSteps:
+		return ui.ExportDetailCommands
+	case AnswerStyleExecutiveSummary:
+		return ui.ExportDetailAnswers
+	default:
+		return ui.ExportDetailFull
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// assertFunctionName is the name of the function the LLM calls to check a condition as a step of a plan.
+const assertFunctionName = "assert"
+
+// assertFunctionDefinition describes the assert function to the LLM.
+func assertFunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name: assertFunctionName,
+		Description: `Checks a condition about the cluster as a step of a plan, by running a read-only kubectl command and comparing
+its output with what you expect. Plan remediations as check-act-check sequences: assert the precondition before a change,
+and that the change took effect after it (use wait_for first if it takes time). You can make several calls at once, e.g.
+an assertion, a change and another assertion; if an assertion fails, the calls after it are not run, and you must make a
+new plan from the actual state instead of carrying on. If no expectation is given, the assertion holds if the command succeeds.`,
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"description": {
+					Type:        gollm.TypeString,
+					Description: `What is checked, e.g. "deployment web has 3 available replicas".`,
+				},
+				"command": {
+					Type:        gollm.TypeString,
+					Description: `A read-only kubectl command; select the value to check with -o jsonpath, e.g. "kubectl get deploy web -n shop -o jsonpath={.status.availableReplicas}".`,
+				},
+				"equals": {
+					Type:        gollm.TypeString,
+					Description: `The output must be exactly this, ignoring leading and trailing whitespace.`,
+				},
+				"contains": {
+					Type:        gollm.TypeString,
+					Description: `The output must contain this text.`,
+				},
+				"not_contains": {
+					Type:        gollm.TypeString,
+					Description: `The output must not contain this text.`,
+				},
+			},
+			Required: []string{"description", "command"},
+		},
+	}
+}
+
+// assertion is an assert call.
+type assertion struct {
+	description string
+	command     string
+	// equals, contains and notContains are nil if not given.
+	equals, contains, notContains *string
+}
+
+func parseAssertion(args map[string]any) (*assertion, error) {
+	a := &assertion{}
+	a.description, _ = args["description"].(string)
+	a.command, _ = args["command"].(string)
+	if strings.TrimSpace(a.command) == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+	for key, p := range map[string]**string{"equals": &a.equals, "contains": &a.contains, "not_contains": &a.notContains} {
+		if v, ok := args[key].(string); ok {
+			*p = &v
+		}
+	}
+	if a.description == "" {
+		a.description = a.command
+	}
+	return a, nil
+}
+
+// check returns why the assertion does not hold for the output of its command, or "" if it holds.
+func (a *assertion) check(output string) string {
+	output = strings.TrimSpace(output)
+	switch {
+	case a.equals != nil && output != strings.TrimSpace(*a.equals):
+		return fmt.Sprintf("expected %q, got %q", strings.TrimSpace(*a.equals), output)
+	case a.contains != nil && !strings.Contains(output, *a.contains):
+		return fmt.Sprintf("expected the output to contain %q, got %q", *a.contains, output)
+	case a.notContains != nil && strings.Contains(output, *a.notContains):
+		return fmt.Sprintf("expected the output not to contain %q, got %q", *a.notContains, output)
+	}
+	return ""
+}
+
+// assert runs the command of an assert call and checks its output.
+// It returns the result for the LLM, and why the assertion failed, or "" if it holds.
+func (a *Agent) assert(ctx context.Context, call gollm.FunctionCall) (gollm.FunctionCallResult, string) {
+	result := gollm.FunctionCallResult{ID: call.ID, Name: call.Name}
+
+	assertion, err := parseAssertion(call.Arguments)
+	var failure string
+	if err == nil {
+		failure, err = a.checkAssertion(ctx, assertion)
+	}
+	if err != nil {
+		// Let the LLM correct the call; it is not a failed assertion.
+		result.Result = map[string]any{"error": err.Error()}
+		return result, ""
+	}
+
+	if failure == "" {
+		textBlock := ui.NewAgentTextBlock().WithText(fmt.Sprintf("Assertion holds: %s", assertion.description))
+		textBlock.SetColor(ui.ColorWhite)
+		a.doc.AddBlock(textBlock)
+		result.Result = map[string]any{"status": "passed"}
+		return result, ""
+	}
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("Assertion failed: %s: %s", assertion.description, failure)))
+	result.Result = map[string]any{
+		"status": "failed",
+		"error":  failure,
+		"note":   "The calls after this one were not run. Make a new plan from the actual state of the cluster.",
+	}
+	return result, fmt.Sprintf("%q (%s)", assertion.description, failure)
+}
+
+// checkAssertion runs the command of an assertion with the kubectl tool, and returns why the assertion does not hold,
+// or "" if it holds. It returns an error if the assertion cannot be checked, e.g. if its command is not read-only.
+func (a *Agent) checkAssertion(ctx context.Context, assertion *assertion) (string, error) {
+	if a.Tools.Lookup("kubectl") == nil {
+		return "", fmt.Errorf("the kubectl tool is not available")
+	}
+	args := map[string]any{"command": assertion.command}
+	toolCall, err := a.Tools.ParseToolInvocation(ctx, "kubectl", args)
+	if err != nil {
+		return "", err
+	}
+	if toolCall.GetTool().CheckModifiesResource(args) != "no" {
+		return "", fmt.Errorf("the command of an assertion must be read-only, %q may modify resources", assertion.command)
+	}
+
+	block := ui.NewFunctionCallRequestBlock().SetDescription(toolCall.Description())
+	a.doc.AddBlock(block)
+	toolCtx := journal.ContextWithRecorder(ctx, a.Recorder)
+	cancel := func() {}
+	if a.ToolTimeout > 0 {
+		toolCtx, cancel = context.WithTimeout(toolCtx, a.ToolTimeout)
+	}
+	output, err := toolCall.InvokeTool(toolCtx, tools.InvokeToolOptions{
+		Kubeconfig: a.Kubeconfig,
+		WorkDir:    a.workDir,
+	})
+	cancel()
+	if err != nil {
+		output = &tools.ExecResult{Command: assertion.command, Error: err.Error()}
+	}
+	block.SetResult(output)
+	a.stats.ToolCalls++
+
+	var failure string
+	execResult, _ := output.(*tools.ExecResult)
+	switch {
+	case execResult == nil:
+		failure = assertion.check(fmt.Sprintf("%v", output))
+	case execResult.Error != "" || execResult.ExitCode != 0:
+		failure = fmt.Sprintf("the command failed: %s", strings.TrimSpace(execResult.Error+"\n"+execResult.Stderr))
+	default:
+		failure = assertion.check(execResult.Stdout)
+	}
+	klog.FromContext(ctx).Info("assertion", "description", assertion.description, "failure", failure)
+	return failure, nil
+}
+
+// skippedAfterAssertion is the result of a call that was not run because an earlier assertion failed.
+func skippedAfterAssertion(call gollm.FunctionCall, failed string) gollm.FunctionCallResult {
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"status": "skipped",
+			"error":  fmt.Sprintf("Not run, because the assertion %s failed.", failed),
+		},
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attach adds content the user gave alongside the query, e.g. a file or the input piped to kubectl-ai,
+// to the next query as a message of its own. source describes the content to the LLM, e.g. `the file "deploy.yaml"`.
+func (a *Agent) Attach(source, content string) {
+	// The fence must be longer than any run of backticks in the content, so that it isn't closed early.
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	a.attachments = append(a.attachments, fmt.Sprintf("The user attached %s:\n\n%s\n%s\n%s", source, fence, strings.TrimRight(content, "\n"), fence))
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+)
+
+// defaultBranch is the name of the branch a conversation starts on.
+const defaultBranch = "main"
+
+// branchState is the saved state of a branch that is not currently active.
+type branchState struct {
+	history     []*journal.HistoryEntry
+	roundStarts []int
+}
+
+// userHistoryEntry converts the contents we send to the LLM into a history entry.
+// query is the query of the user if the contents start a round, and "" otherwise.
+func userHistoryEntry(contents []any, query string) *journal.HistoryEntry {
+	entry := &journal.HistoryEntry{Role: journal.RoleUser, Timestamp: time.Now(), Query: query}
+	for _, content := range contents {
+		switch c := content.(type) {
+		case string:
+			entry.Messages = append(entry.Messages, c)
+		case gollm.FunctionCallResult:
+			entry.FunctionCallResults = append(entry.FunctionCallResults, c)
+		}
+	}
+	return entry
+}
+
+// roundStartsOf finds the start of each round in the history: the user entries with a query.
+func roundStartsOf(history []*journal.HistoryEntry) []int {
+	var roundStarts []int
+	for i, entry := range history {
+		if entry.Role == journal.RoleUser && entry.Query != "" {
+			roundStarts = append(roundStarts, i)
+		}
+	}
+	return roundStarts
+}
+
+// Undo removes the last round (the query, the LLM responses and the tool results) from the chat history.
+// It returns the query of the round that was removed.
+// Note that changes made to the cluster by tools during the round are not reverted.
+func (a *Agent) Undo(ctx context.Context) (string, error) {
+	if len(a.roundStarts) == 0 {
+		return "", fmt.Errorf("there is nothing to undo")
+	}
+	start := a.roundStarts[len(a.roundStarts)-1]
+	query := ""
+	if start < len(a.history) {
+		query = a.history[start].Query
+	}
+
+	history := slices.Clone(a.history[:start])
+	roundStarts := slices.Clone(a.roundStarts[:len(a.roundStarts)-1])
+	if err := a.restoreHistory(ctx, history, roundStarts); err != nil {
+		return "", err
+	}
+	return query, nil
+}
+
+// Branch switches to the branch with the given name.
+// If the branch does not exist yet, it is forked from the current state of the conversation.
+// It returns true if a new branch was created.
+func (a *Agent) Branch(ctx context.Context, name string) (bool, error) {
+	if name == "" {
+		return false, fmt.Errorf("branch name must not be empty")
+	}
+	if name == a.currentBranch {
+		return false, fmt.Errorf("already on branch %q", name)
+	}
+	if a.branches == nil {
+		a.branches = make(map[string]*branchState)
+	}
+
+	current := &branchState{
+		history:     slices.Clone(a.history),
+		roundStarts: slices.Clone(a.roundStarts),
+	}
+
+	target, exists := a.branches[name]
+	if !exists {
+		// Forking: the new branch starts out with the same history, so we keep the chat as is.
+		a.branches[a.currentBranch] = current
+		a.currentBranch = name
+		return true, nil
+	}
+
+	if err := a.restoreHistory(ctx, target.history, target.roundStarts); err != nil {
+		return false, err
+	}
+	delete(a.branches, name)
+	a.branches[a.currentBranch] = current
+	a.currentBranch = name
+	return false, nil
+}
+
+// CurrentBranch returns the name of the current branch.
+func (a *Agent) CurrentBranch() string {
+	return a.currentBranch
+}
+
+// Branches returns the names of all branches, including the current one.
+func (a *Agent) Branches() []string {
+	names := []string{a.currentBranch}
+	for name := range a.branches {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// restoreHistory replaces the chat history.
+// As chats cannot be rewound, we start a new chat and send it a transcript of the history with the next query.
+func (a *Agent) restoreHistory(ctx context.Context, history []*journal.HistoryEntry, roundStarts []int) error {
+	transcript, err := historyTranscript(history)
+	if err != nil {
+		return err
+	}
+	if err := a.startChat(ctx); err != nil {
+		return err
+	}
+	a.history = history
+	a.roundStarts = roundStarts
+	a.resumedHistory = ""
+	// The new chat has no calls waiting for results.
+	a.pendingResults = nil
+	if transcript != "" {
+		a.resumedHistory = resumePrompt(transcript)
+	}
+	// We don't know the size of the new context until the LLM reports it.
+	a.contextTokens = 0
+	a.contextWarnedThreshold = 0
+	return nil
+}
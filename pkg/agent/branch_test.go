@@ -0,0 +1,186 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+)
+
+// fakeLLM starts chats that are never sent anything, for tests that only restart the chat.
+type fakeLLM struct{}
+
+func (c *fakeLLM) Close() error                                    { return nil }
+func (c *fakeLLM) StartChat(systemPrompt, model string) gollm.Chat { return &fakeChat{} }
+func (c *fakeLLM) SetResponseSchema(schema *gollm.Schema) error    { return nil }
+func (c *fakeLLM) ListModels(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+func (c *fakeLLM) GenerateCompletion(ctx context.Context, req *gollm.CompletionRequest) (gollm.CompletionResponse, error) {
+	return nil, nil
+}
+
+type fakeChat struct{}
+
+func (c *fakeChat) Send(ctx context.Context, contents ...any) (gollm.ChatResponse, error) {
+	return nil, nil
+}
+func (c *fakeChat) SendStreaming(ctx context.Context, contents ...any) (gollm.ChatResponseIterator, error) {
+	return nil, nil
+}
+func (c *fakeChat) SetFunctionDefinitions(functionDefinitions []*gollm.FunctionDefinition) error {
+	return nil
+}
+func (c *fakeChat) IsRetryableError(err error) bool { return false }
+
+// roundHistory returns the history the agent records for a round of the query, with the answer style,
+// in which the LLM runs a command before answering: with a function call, or with the tool use shim.
+func roundHistory(query string, style AnswerStyle, shim bool) []*journal.HistoryEntry {
+	contents := []any{query}
+	if message := answerStyleMessage(style); message != "" {
+		contents = append(contents, message)
+	}
+	if shim {
+		return []*journal.HistoryEntry{
+			userHistoryEntry(contents, query),
+			{Role: journal.RoleModel, Messages: []string{"```json\n{\"action\": {\"name\": \"kubectl\", \"command\": \"kubectl get pods\"}}\n```"}},
+			userHistoryEntry([]any{"Result of running \"kubectl\":\nweb-1 Running"}, ""),
+			{Role: journal.RoleModel, Messages: []string{"```json\n{\"answer\": \"The pods are running.\"}\n```"}},
+		}
+	}
+	call := gollm.FunctionCall{ID: "1", Name: "kubectl", Arguments: map[string]any{"command": "kubectl get pods"}}
+	return []*journal.HistoryEntry{
+		userHistoryEntry(contents, query),
+		{Role: journal.RoleModel, FunctionCalls: []gollm.FunctionCall{call}},
+		userHistoryEntry([]any{gollm.FunctionCallResult{ID: "1", Name: "kubectl", Result: map[string]any{"stdout": "web-1 Running"}}}, ""),
+		{Role: journal.RoleModel, Messages: []string{"The pods are running."}},
+	}
+}
+
+// roundModes are the ways the agent can record rounds: with or without an answer style, with function calls or the tool use shim.
+var roundModes = []struct {
+	name  string
+	style AnswerStyle
+	shim  bool
+}{
+	{name: "default style", style: AnswerStyleDefault},
+	{name: "answer style", style: AnswerStyleConciseOps},
+	{name: "tool use shim", style: AnswerStyleDefault, shim: true},
+	{name: "tool use shim with answer style", style: AnswerStyleConciseOps, shim: true},
+}
+
+func TestUndo(t *testing.T) {
+	for _, tt := range roundModes {
+		first, second := roundHistory("are the pods running?", tt.style, tt.shim), roundHistory("why is web-2 pending?", tt.style, tt.shim)
+		a := &Agent{LLM: &fakeLLM{}, Recorder: &journal.LogRecorder{}, AnswerStyle: tt.style, EnableToolUseShim: tt.shim}
+		a.history = slices.Concat(first, second)
+		a.roundStarts = []int{0, len(first)}
+
+		query, err := a.Undo(context.Background())
+		if err != nil {
+			t.Errorf("%s: Undo() error = %v", tt.name, err)
+			continue
+		}
+		if want := "why is web-2 pending?"; query != want {
+			t.Errorf("%s: Undo() = %q, want %q", tt.name, query, want)
+		}
+		if !slices.Equal(a.history, first) {
+			t.Errorf("%s: history after Undo() has %d entries, want the %d of the first round", tt.name, len(a.history), len(first))
+		}
+		if want := []int{0}; !slices.Equal(a.roundStarts, want) {
+			t.Errorf("%s: round starts after Undo() = %v, want %v", tt.name, a.roundStarts, want)
+		}
+	}
+}
+
+func TestRoundStartsOf(t *testing.T) {
+	for _, tt := range roundModes {
+		first, second := roundHistory("are the pods running?", tt.style, tt.shim), roundHistory("why is web-2 pending?", tt.style, tt.shim)
+		history := slices.Concat(first, second)
+
+		if got, want := roundStartsOf(history), []int{0, len(first)}; !slices.Equal(got, want) {
+			t.Errorf("%s: roundStartsOf() = %v, want %v", tt.name, got, want)
+		}
+	}
+}
+
+// writeJournal writes the events the agent records for the history to a journal, and reads them back.
+func writeJournal(t *testing.T, history []*journal.HistoryEntry) []*journal.Event {
+	path := filepath.Join(t.TempDir(), "trace.yaml")
+	recorder, err := journal.NewFileRecorder(path)
+	if err != nil {
+		t.Fatalf("NewFileRecorder() error = %v", err)
+	}
+	var events []*journal.Event
+	for _, entry := range history {
+		if entry.Role == journal.RoleModel {
+			for _, message := range entry.Messages {
+				events = append(events, &journal.Event{Action: journal.ActionLLMResponse, Payload: gollm.RecordChatResponse{Text: message}})
+			}
+			if len(entry.FunctionCalls) > 0 {
+				events = append(events, &journal.Event{Action: journal.ActionLLMResponse, Payload: gollm.RecordChatResponse{FunctionCalls: entry.FunctionCalls}})
+			}
+			continue
+		}
+		if entry.Query != "" {
+			events = append(events, &journal.Event{Action: journal.ActionUserQuery, Payload: map[string]any{"query": entry.Query}})
+		}
+		var contents []any
+		for _, message := range entry.Messages {
+			contents = append(contents, message)
+		}
+		for _, result := range entry.FunctionCallResults {
+			contents = append(contents, result)
+		}
+		events = append(events, &journal.Event{Action: journal.ActionLLMChat, Payload: []any{contents}})
+	}
+	for _, event := range events {
+		if err := recorder.Write(context.Background(), event); err != nil {
+			t.Fatalf("writing the journal: %v", err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("writing the journal: %v", err)
+	}
+	events, err = journal.ParseEventsFromFile(path)
+	if err != nil {
+		t.Fatalf("ParseEventsFromFile() error = %v", err)
+	}
+	return events
+}
+
+func TestRoundStartsOfResumedJournal(t *testing.T) {
+	for _, tt := range roundModes {
+		first, second := roundHistory("are the pods running?", tt.style, tt.shim), roundHistory("why is web-2 pending?", tt.style, tt.shim)
+
+		history, err := journal.ReconstructHistory(writeJournal(t, slices.Concat(first, second)))
+		if err != nil {
+			t.Errorf("%s: ReconstructHistory() error = %v", tt.name, err)
+			continue
+		}
+		if got, want := roundStartsOf(history), []int{0, len(first)}; !slices.Equal(got, want) {
+			t.Errorf("%s: roundStartsOf() = %v, want %v", tt.name, got, want)
+			continue
+		}
+		if got, want := history[len(first)].Query, "why is web-2 pending?"; got != want {
+			t.Errorf("%s: query of the second round = %q, want %q", tt.name, got, want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// ErrBudgetExceeded is returned by RunOneRound when the agent stopped because the next LLM request
+// would exceed the token or cost budget.
+var ErrBudgetExceeded = errors.New("budget exceeded")
+
+// Spent returns the tokens used by all LLM requests of the session, and their estimated cost in US dollars
+// (zero if the price of the model is not known). Unlike Stats, it is not reset by Init.
+func (a *Agent) Spent() (gollm.Usage, float64) {
+	return a.spent, a.spentCost
+}
+
+// addSpent adds the usage of an LLM response to the budget.
+func (a *Agent) addSpent(usage gollm.Usage) {
+	a.spent.InputTokens += usage.InputTokens
+	a.spent.OutputTokens += usage.OutputTokens
+	a.spent.TotalTokens += usage.TotalTokens
+	a.spent.CachedInputTokens += usage.CachedInputTokens
+	// The price may change when switching models, so we add up the cost of each request.
+	a.spentCost += a.TokenPrice.Cost(usage)
+}
+
+// addSessionSpent adds the tokens used by another session, and their cost, to the budget.
+func (a *Agent) addSessionSpent(usage gollm.Usage, cost float64) {
+	a.spent.InputTokens += usage.InputTokens
+	a.spent.OutputTokens += usage.OutputTokens
+	a.spent.TotalTokens += usage.TotalTokens
+	a.spent.CachedInputTokens += usage.CachedInputTokens
+	a.spentCost += cost
+}
+
+// budgetExhausted returns why the next LLM request would exceed the budget, or "" if it would not.
+// The next request sends at least the current context, so we use its size as an estimate of the request.
+func (a *Agent) budgetExhausted() string {
+	next := gollm.Usage{InputTokens: a.contextTokens, TotalTokens: a.contextTokens}
+	if a.MaxTokens > 0 && a.spent.TotalTokens+next.TotalTokens > a.MaxTokens {
+		return fmt.Sprintf("the token budget of %d tokens would be exceeded (%d tokens used so far)", a.MaxTokens, a.spent.TotalTokens)
+	}
+	if a.MaxCost > 0 && a.spentCost+a.TokenPrice.Cost(next) > a.MaxCost {
+		return fmt.Sprintf("the cost budget of $%.2f would be exceeded ($%.4f spent so far)", a.MaxCost, a.spentCost)
+	}
+	return ""
+}
+
+// stopForBudget ends the round because the budget is exhausted, keeping what the agent did so far as the answer.
+func (a *Agent) stopForBudget(reason string) error {
+	message := fmt.Sprintf("Stopping before completing the task: %s.", reason)
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(message + "\n"))
+	if a.lastAnswer == nil {
+		a.lastAnswer = &FinalAnswer{Summary: message, CommandsRun: a.roundCommands}
+	}
+	return fmt.Errorf("%w: %s", ErrBudgetExceeded, reason)
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"k8s.io/klog/v2"
+)
+
+// CandidateSelection controls how we choose between sampled candidate commands.
+type CandidateSelection string
+
+const (
+	// CandidateSelectionVerifier asks the LLM to pick the best candidate.
+	CandidateSelectionVerifier CandidateSelection = "verifier"
+	// CandidateSelectionUser presents the candidates to the user as part of the confirmation prompt.
+	CandidateSelectionUser CandidateSelection = "user"
+)
+
+// sampleCommandCandidates asks the LLM for alternative commands that accomplish the same step as call.
+// The command originally proposed by the LLM is always the first candidate; duplicates are removed.
+func (c *Agent) sampleCommandCandidates(ctx context.Context, query string, call gollm.FunctionCall) []string {
+	log := klog.FromContext(ctx)
+
+	command, ok := call.Arguments["command"].(string)
+	if !ok || command == "" {
+		return nil
+	}
+	candidates := []string{command}
+
+	prompt := fmt.Sprintf(`You are helping to operate a kubernetes cluster.
+The user asked: %q
+
+An assistant proposed running the following command with the %q tool, which modifies the cluster:
+%s
+
+Propose the single most correct and safest command to accomplish the same step.
+You may return the same command if it is already the best option.
+Respond with only the command, without any explanation or markdown formatting.`, query, call.Name, command)
+
+	for i := 1; i < c.MutationCandidates; i++ {
+		response, err := c.LLM.GenerateCompletion(ctx, &gollm.CompletionRequest{
+			Model:  c.Model,
+			Prompt: prompt,
+		})
+		if err != nil {
+			log.Error(err, "sampling alternative command candidate")
+			continue
+		}
+		candidate := cleanCommandCandidate(response.Response())
+		if candidate == "" {
+			continue
+		}
+		if !slices.Contains(candidates, candidate) {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// selectCandidateWithVerifier asks the LLM to choose the best of the candidate commands.
+// It returns the index of the chosen candidate, defaulting to the first (original) candidate.
+func (c *Agent) selectCandidateWithVerifier(ctx context.Context, query string, candidates []string) int {
+	log := klog.FromContext(ctx)
+
+	if len(candidates) < 2 {
+		return 0
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are reviewing commands that will modify a kubernetes cluster.\nThe user asked: %q\n\nCandidate commands:\n", query)
+	for i, candidate := range candidates {
+		fmt.Fprintf(&b, "%d) %s\n", i+1, candidate)
+	}
+	b.WriteString("\nWhich candidate most correctly and safely accomplishes the user's request? Respond with only the number of the candidate.")
+
+	response, err := c.LLM.GenerateCompletion(ctx, &gollm.CompletionRequest{
+		Model:  c.Model,
+		Prompt: b.String(),
+	})
+	if err != nil {
+		log.Error(err, "asking verifier to select candidate command")
+		return 0
+	}
+
+	choice, err := strconv.Atoi(strings.Trim(strings.TrimSpace(response.Response()), ".)"))
+	if err != nil || choice < 1 || choice > len(candidates) {
+		log.Info("verifier returned an invalid choice, keeping the original command", "response", response.Response())
+		return 0
+	}
+	return choice - 1
+}
+
+// chooseCandidate lets the verifier choose the best of the candidate commands for call. If it chooses an alternative,
+// the command of call is replaced with it, as by useAlternative; otherwise modifiesResource and "" are returned.
+func (c *Agent) chooseCandidate(ctx context.Context, query string, call gollm.FunctionCall, tool tools.Tool, candidates []string, modifiesResource string) (string, string) {
+	chosen := c.selectCandidateWithVerifier(ctx, query, candidates)
+	if chosen == 0 {
+		return modifiesResource, ""
+	}
+	klog.FromContext(ctx).Info("verifier chose an alternative command", "command", candidates[chosen])
+	return useAlternative(call, tool, candidates[chosen], modifiesResource, "the verifier")
+}
+
+// useAlternative replaces the command of call with an alternative, chosen by chooser (e.g. the user).
+// It returns whether the alternative modifies resources, which may differ from the proposed command,
+// and a note that tells the LLM which command ran instead of the one it proposed.
+func useAlternative(call gollm.FunctionCall, tool tools.Tool, alternative, modifiesResource, chooser string) (string, string) {
+	proposed, _ := call.Arguments["command"].(string)
+	call.Arguments["command"] = alternative
+	if modifies := tool.CheckModifiesResource(call.Arguments); modifies != "unknown" {
+		modifiesResource = modifies
+	}
+	return modifiesResource, fmt.Sprintf("An alternative command, chosen by %s, ran instead. You proposed: %s\nThis ran instead: %s", chooser, proposed, alternative)
+}
+
+// cleanCommandCandidate strips markdown code fences and surrounding whitespace from an LLM-proposed command.
+func cleanCommandCandidate(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "```") {
+		s = strings.TrimPrefix(s, "```")
+		// Drop the language tag, if any
+		if i := strings.Index(s, "\n"); i >= 0 {
+			s = s[i+1:]
+		}
+		s = strings.TrimSuffix(strings.TrimSpace(s), "```")
+	}
+	s = strings.Trim(strings.TrimSpace(s), "`")
+	return strings.TrimSpace(s)
+}
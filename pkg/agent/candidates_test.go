@@ -0,0 +1,80 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+)
+
+// fakeVerifier answers every completion request with choice, the number of the candidate it chooses.
+type fakeVerifier struct {
+	fakeLLM
+	choice string
+}
+
+func (v *fakeVerifier) GenerateCompletion(ctx context.Context, req *gollm.CompletionRequest) (gollm.CompletionResponse, error) {
+	return &fakeCompletion{response: v.choice}, nil
+}
+
+type fakeCompletion struct {
+	response string
+}
+
+func (c *fakeCompletion) Response() string   { return c.response }
+func (c *fakeCompletion) UsageMetadata() any { return nil }
+
+func TestChooseCandidate(t *testing.T) {
+	candidates := []string{"kubectl delete pod web-0", "kubectl get pod web-0", "kubectl rollout restart deployment web"}
+	tests := []struct {
+		name         string
+		choice       string
+		wantCommand  string
+		wantModifies string
+		wantNote     bool
+	}{
+		{name: "proposed command", choice: "1", wantCommand: "kubectl delete pod web-0", wantModifies: "yes"},
+		{name: "read-only alternative", choice: "2", wantCommand: "kubectl get pod web-0", wantModifies: "no", wantNote: true},
+		{name: "modifying alternative", choice: "3", wantCommand: "kubectl rollout restart deployment web", wantModifies: "yes", wantNote: true},
+		{name: "invalid choice", choice: "seven", wantCommand: "kubectl delete pod web-0", wantModifies: "yes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Agent{LLM: &fakeVerifier{choice: tt.choice}}
+			call := gollm.FunctionCall{Name: "bash", Arguments: map[string]any{"command": candidates[0]}}
+
+			modifies, note := a.chooseCandidate(context.Background(), "restart web-0", call, &tools.BashTool{}, candidates, "yes")
+			if got := call.Arguments["command"]; got != tt.wantCommand {
+				t.Errorf("command = %q, expected %q", got, tt.wantCommand)
+			}
+			if modifies != tt.wantModifies {
+				t.Errorf("modifies resource = %q, expected %q", modifies, tt.wantModifies)
+			}
+			if !tt.wantNote {
+				if note != "" {
+					t.Errorf("note = %q, expected none", note)
+				}
+				return
+			}
+			if !strings.Contains(note, candidates[0]) || !strings.Contains(note, tt.wantCommand) {
+				t.Errorf("note %q does not tell the LLM that %q ran instead of %q", note, tt.wantCommand, candidates[0])
+			}
+		})
+	}
+}
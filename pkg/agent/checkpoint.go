@@ -0,0 +1,104 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"k8s.io/klog/v2"
+)
+
+// checkpointFile is the file in the working directory that holds the last checkpoint of the conversation.
+const checkpointFile = "checkpoint.json"
+
+// workDirPattern is the pattern of the names of the working directories, created in the temporary directory.
+const workDirPattern = "agent-workdir-*"
+
+// checkpoint is the state of the conversation, written after every iteration so that a session that crashed
+// can be resumed.
+type checkpoint struct {
+	Saved time.Time `json:"saved"`
+	Model string    `json:"model"`
+	// History is the chat history, including the results of tool calls not sent to the LLM yet.
+	History []*journal.HistoryEntry `json:"history"`
+}
+
+// writeCheckpoint writes the chat history, and the contents that will be sent with the next request, to the
+// working directory. The file is replaced atomically, so a crash while writing leaves the previous checkpoint.
+func (a *Agent) writeCheckpoint(ctx context.Context, unsent []any) {
+	log := klog.FromContext(ctx)
+
+	history := a.history
+	if len(unsent) > 0 {
+		history = append(slices.Clone(history), userHistoryEntry(unsent, ""))
+	}
+	b, err := json.Marshal(&checkpoint{Saved: time.Now(), Model: a.Model, History: history})
+	if err != nil {
+		log.Error(err, "marshalling checkpoint")
+		return
+	}
+	p := filepath.Join(a.workDir, checkpointFile)
+	if err := os.WriteFile(p+".tmp", b, 0o600); err != nil {
+		log.Error(err, "writing checkpoint")
+		return
+	}
+	if err := os.Rename(p+".tmp", p); err != nil {
+		log.Error(err, "writing checkpoint")
+	}
+}
+
+// LastCheckpoint finds the most recent checkpoint written by a conversation on this machine,
+// and returns its path, or an error if there is none.
+func LastCheckpoint() (string, error) {
+	paths, err := filepath.Glob(filepath.Join(os.TempDir(), workDirPattern, checkpointFile))
+	if err != nil {
+		return "", err
+	}
+	var last string
+	var lastSaved time.Time
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue
+		}
+		if last == "" || info.ModTime().After(lastSaved) {
+			last, lastSaved = p, info.ModTime()
+		}
+	}
+	if last == "" {
+		return "", fmt.Errorf("no checkpoint found in %s", os.TempDir())
+	}
+	return last, nil
+}
+
+// LoadCheckpoint reads the chat history from a checkpoint, to resume the conversation with Resume.
+func LoadCheckpoint(p string) ([]*journal.HistoryEntry, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("reading checkpoint: %w", err)
+	}
+	var c checkpoint
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parsing checkpoint %q: %w", p, err)
+	}
+	return c.History, nil
+}
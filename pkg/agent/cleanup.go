@@ -0,0 +1,127 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// CreatedInRound returns the objects created by the tool calls of the current round, or of the last one,
+// as recorded in the change ledger. It is safe to call while a round is running, e.g. from a signal handler.
+func (a *Agent) CreatedInRound() []tools.CreatedObject {
+	return a.ledger.Created()
+}
+
+// cleanUpAbortedRound offers to delete the objects created by a round that was aborted before it completed,
+// or deletes them without asking if AutoCleanup is set.
+func (a *Agent) cleanUpAbortedRound(ctx context.Context) {
+	created := a.ledger.Created()
+	if len(created) == 0 {
+		return
+	}
+	// The round may have been aborted by cancelling ctx; the cleanup must run anyway.
+	ctx = context.WithoutCancel(ctx)
+
+	var sb strings.Builder
+	sb.WriteString("The task was aborted after creating these resources:\n")
+	for _, object := range created {
+		fmt.Fprintf(&sb, "- %s\n", object)
+	}
+
+	switch {
+	case a.AutoCleanup:
+	case a.NonInteractive:
+		sb.WriteString("They were left in place; use --auto-cleanup to delete them when a task is aborted.")
+		a.doc.AddBlock(ui.NewErrorBlock().SetText(sb.String()))
+		return
+	default:
+		optionsBlock := ui.NewInputOptionBlock().SetPrompt("  " + sb.String() + "  Do you want to delete them?")
+		optionsBlock.AddOption("yes", "Yes, delete them", "yes", "y")
+		optionsBlock.AddOption("no", "No, keep them", "no", "n")
+		a.doc.AddBlock(optionsBlock)
+
+		choice, err := optionsBlock.Selection().Wait()
+		if err != nil && !errors.Is(err, io.EOF) {
+			klog.Warningf("Reading the cleanup choice: %v", err)
+		}
+		if choice != "yes" {
+			a.ledger.Reset()
+			return
+		}
+	}
+
+	deleted, failed := a.deleteCreated(ctx, created)
+	a.ledger.Reset()
+
+	// Tell the LLM with the next query, so it doesn't assume the resources are still there.
+	if len(deleted) > 0 {
+		a.pendingResults = append(a.pendingResults, fmt.Sprintf("The previous task was aborted, and the resources it had created were deleted: %s.", strings.Join(deleted, ", ")))
+	}
+	if len(failed) > 0 {
+		a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("Could not delete: %s\n", strings.Join(failed, ", "))))
+	}
+}
+
+// deleteCreated deletes the objects with the kubectl tool, the most recently created first, as later objects
+// may depend on earlier ones (e.g. a deployment in a new namespace). It returns the objects that were deleted,
+// and those that could not be.
+func (a *Agent) deleteCreated(ctx context.Context, created []tools.CreatedObject) (deleted, failed []string) {
+	log := klog.FromContext(ctx)
+
+	for _, object := range slices.Backward(created) {
+		command := object.DeleteCommand()
+		toolCall, err := a.Tools.ParseToolInvocation(ctx, "kubectl", map[string]any{"command": command})
+		if err != nil {
+			log.Error(err, "building cleanup tool call", "command", command)
+			failed = append(failed, object.String())
+			continue
+		}
+
+		block := ui.NewFunctionCallRequestBlock().SetDescription(command)
+		a.doc.AddBlock(block)
+
+		toolCtx := journal.ContextWithRecorder(ctx, a.Recorder)
+		cancel := func() {}
+		if a.ToolTimeout > 0 {
+			toolCtx, cancel = context.WithTimeout(toolCtx, a.ToolTimeout)
+		}
+		result, err := toolCall.InvokeTool(toolCtx, tools.InvokeToolOptions{
+			Kubeconfig: a.Kubeconfig,
+			WorkDir:    a.workDir,
+		})
+		cancel()
+		if err != nil {
+			result = &tools.ExecResult{Command: command, Error: err.Error()}
+		}
+		block.SetResult(result)
+
+		if execResult, ok := result.(*tools.ExecResult); ok && (execResult.Error != "" || execResult.ExitCode != 0) {
+			failed = append(failed, object.String())
+			continue
+		}
+		deleted = append(deleted, object.String())
+	}
+	return deleted, failed
+}
@@ -0,0 +1,85 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+)
+
+// compactedPrefix introduces the summary that replaces the history of a compacted conversation.
+const compactedPrefix = "Summary of the conversation so far:\n"
+
+// Compact asks the LLM to condense the chat history into a short summary of the state of the session,
+// and continues the conversation from the summary alone, freeing up the context window.
+// instructions, if not empty, tell the LLM what to focus on. It returns the summary.
+// The rounds before the compaction can no longer be undone.
+func (a *Agent) Compact(ctx context.Context, instructions string) (string, error) {
+	if len(a.history) == 0 {
+		return "", fmt.Errorf("there is nothing to compact")
+	}
+	transcript, err := historyTranscript(a.history)
+	if err != nil {
+		return "", err
+	}
+	if a.resumedHistory != "" {
+		// The chat was resumed or restored, and the earlier history has not been sent yet.
+		transcript = a.resumedHistory + "\n" + transcript
+	}
+
+	prompt := `You are helping an assistant that operates a kubernetes cluster for a user. The conversation between them is getting long.
+Condense the transcript below into a short summary of the state of the session, which will replace the transcript: the assistant will continue the conversation from the summary alone.
+Include, as concise markdown bullet points:
+- what the user wants, and any preferences or constraints they stated,
+- what was found: the resources involved (with their kind, namespace and name), their state, and errors seen,
+- the changes made to the cluster, and the commands that made them,
+- what is still open: unanswered questions and next steps.
+Leave out tool output that is no longer relevant, but keep the facts that were learned from it.`
+	if instructions != "" {
+		prompt += fmt.Sprintf("\nThe user asked that the summary focus on: %s", instructions)
+	}
+	prompt += "\n\nTranscript:\n" + transcript
+
+	response, err := a.LLM.GenerateCompletion(ctx, &gollm.CompletionRequest{
+		Model:  a.Model,
+		Prompt: prompt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("asking the LLM to summarize the conversation: %w", err)
+	}
+	if usage, ok := gollm.UsageFromMetadata(response.UsageMetadata()); ok {
+		a.addSpent(usage)
+	}
+	summary := strings.TrimSpace(response.Response())
+	if summary == "" {
+		return "", fmt.Errorf("the LLM returned an empty summary")
+	}
+
+	// The summary is kept as a model message, so it is not a round that can be undone.
+	history := []*journal.HistoryEntry{{
+		Role:      journal.RoleModel,
+		Messages:  []string{compactedPrefix + summary},
+		Timestamp: time.Now(),
+	}}
+	if err := a.restoreHistory(ctx, history, nil); err != nil {
+		return "", err
+	}
+	return summary, nil
+}
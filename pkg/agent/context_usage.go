@@ -0,0 +1,89 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// ContextUsage returns the number of tokens in the conversation's context, and the size of the context window.
+// The window is zero if it is not known.
+func (a *Agent) ContextUsage() (used int, window int) {
+	return a.contextTokens, a.ContextWindow
+}
+
+// ContextUsageSummary describes the context usage, e.g. "context used: 42k/200k tokens (21%)".
+func (a *Agent) ContextUsageSummary() string {
+	if a.contextTokens == 0 {
+		return "context used: unknown (the model has not reported token usage)"
+	}
+	if a.ContextWindow <= 0 {
+		return fmt.Sprintf("context used: %s tokens", formatTokenCount(a.contextTokens))
+	}
+	return fmt.Sprintf("context used: %s/%s tokens (%d%%)", formatTokenCount(a.contextTokens), formatTokenCount(a.ContextWindow), a.contextPercent())
+}
+
+func (a *Agent) contextPercent() int {
+	if a.ContextWindow <= 0 {
+		return 0
+	}
+	return a.contextTokens * 100 / a.ContextWindow
+}
+
+// showContextUsage adds the context usage to the document.
+func (a *Agent) showContextUsage() {
+	if a.contextTokens == 0 {
+		// The model doesn't report usage, don't clutter the output.
+		return
+	}
+	block := ui.NewAgentTextBlock().WithText(a.ContextUsageSummary())
+	block.SetColor(ui.ColorWhite)
+	a.doc.AddBlock(block)
+}
+
+// warnOnContextUsage warns the user when the context usage crosses one of the warning thresholds.
+// We only warn once per threshold, so the user isn't nagged on every iteration.
+func (a *Agent) warnOnContextUsage() {
+	percent := a.contextPercent()
+	crossed := 0
+	for _, threshold := range a.ContextWarningThresholds {
+		if percent >= threshold && threshold > crossed {
+			crossed = threshold
+		}
+	}
+	if crossed == 0 || crossed <= a.contextWarnedThreshold {
+		return
+	}
+	a.contextWarnedThreshold = crossed
+
+	block := ui.NewAgentTextBlock().WithText(fmt.Sprintf("Warning: %s. The conversation is nearing the model's context limit; "+
+		"type `/compact` to condense it into a summary, or `/reset` to start a fresh conversation, before it runs out.", a.ContextUsageSummary()))
+	block.SetColor(ui.ColorYellow)
+	a.doc.AddBlock(block)
+}
+
+// formatTokenCount formats a token count compactly, e.g. 42k or 1.2M.
+func formatTokenCount(tokens int) string {
+	switch {
+	case tokens >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(tokens)/1_000_000)
+	case tokens >= 1_000:
+		return fmt.Sprintf("%dk", tokens/1_000)
+	default:
+		return fmt.Sprintf("%d", tokens)
+	}
+}
@@ -16,29 +16,50 @@ package agent
 
 import (
 	"context"
+	"crypto/sha256"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html/template"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"github.com/google/uuid"
 	"k8s.io/klog/v2"
 )
 
 //go:embed systemprompt_template_default.txt
 var defaultSystemPromptTemplate string
 
-type Conversation struct {
+// defaultRetryConfig is used for LLM requests when no RetryConfig is provided.
+var defaultRetryConfig = gollm.RetryConfig{
+	MaxAttempts:    3,
+	InitialBackoff: 10 * time.Second,
+	MaxBackoff:     60 * time.Second,
+	BackoffFactor:  2,
+	Jitter:         true,
+}
+
+// Agent answers queries about a Kubernetes cluster with an LLM, running tools (e.g. kubectl) on its behalf.
+// It can be embedded in other programs: set the configuration fields (at least LLM, Model and Tools),
+// call Init to start a session, then RunOneRound for each query, and Close at the end.
+// An Agent must not be used by several goroutines at once; use one per session.
+type Agent struct {
 	LLM gollm.Client
 
+	// PromptProfile is the name of the variant of the system prompt in use, recorded in the journal.
+	PromptProfile string
+
 	// PromptTemplateFile allows specifying a custom template file
 	PromptTemplateFile string
 	// ExtraPromptPaths allows specifying additional prompt templates
@@ -46,19 +67,75 @@ type Conversation struct {
 	ExtraPromptPaths []string
 	Model            string
 
-	RemoveWorkDir bool
+	// FallbackModel is used if Model still fails after all retries.
+	// The conversation continues with the fallback model, which is sent a transcript of the history.
+	// If empty, we do not fail over.
+	FallbackModel string
+
+	// RetryConfig controls retries of LLM requests.
+	// If MaxAttempts is zero, defaultRetryConfig is used.
+	RetryConfig gollm.RetryConfig
+
+	// WorkDirRetention decides whether the working directory is kept when the session is closed.
+	// If empty, it is kept.
+	WorkDirRetention WorkDirRetention
 
 	MaxIterations int
 
+	// IterationExtension is how many more iterations to allow when a round reaches MaxIterations while the agent
+	// is still making progress (running new commands). The user is asked whether to continue; non-interactive
+	// sessions continue on their own, up to AutoExtendIterations. Zero means stop at MaxIterations.
+	IterationExtension int
+
+	// AutoExtendIterations is how many iterations beyond MaxIterations a round may run in non-interactive sessions,
+	// in steps of IterationExtension, while the agent is making progress. Zero means never.
+	AutoExtendIterations int
+
+	// RoundTimeout bounds the total time spent in RunOneRound.
+	// Zero means no limit.
+	RoundTimeout time.Duration
+
+	// ToolTimeout bounds the time spent in a single tool invocation.
+	// Zero means no limit.
+	ToolTimeout time.Duration
+
 	// Kubeconfig is the path to the kubeconfig file.
 	Kubeconfig string
 
 	SkipPermissions bool
 
+	// ConfirmationPolicy overrides SkipPermissions for the tool calls its rules match:
+	// it can approve them, always ask for confirmation, or refuse to run them.
+	ConfirmationPolicy *tools.ConfirmationPolicy
+
+	// Approvals are the calls the user chose not to be asked about again, in this and previous sessions.
+	// If nil, "don't ask me again" skips all confirmations for the rest of the session.
+	Approvals *tools.Approvals
+
+	// NonInteractive is set when there is no user to confirm tool calls, e.g. in batch mode:
+	// calls that need confirmation are not run, and the LLM is told so.
+	NonInteractive bool
+
+	// Simulate doesn't run tool calls that modify resources: tools that can show what the call would change
+	// (e.g. with a server-side dry run) do so, and other tools are not run at all.
+	Simulate bool
+
+	// MutationCandidates is the number of candidate commands to sample from the LLM
+	// before running a command that modifies resources. Values below 2 disable sampling.
+	MutationCandidates int
+
+	// CandidateSelection controls how we choose between sampled candidate commands.
+	// Defaults to CandidateSelectionVerifier.
+	CandidateSelection CandidateSelection
+
 	Tools tools.Tools
 
 	EnableToolUseShim bool
 
+	// ShimCorrectionAttempts is how many times in a row we ask the LLM to correct a response that is not valid
+	// ReAct JSON, in tool-use shim mode, before giving up.
+	ShimCorrectionAttempts int
+
 	// MCPClientEnabled indicates whether MCP client mode is enabled
 	MCPClientEnabled bool
 
@@ -66,18 +143,239 @@ type Conversation struct {
 	Recorder journal.Recorder
 
 	// doc is the document which renders the conversation
-	doc *ui.Document
+	doc UI
 
 	llmChat gollm.Chat
 
+	// ContextWindow is the size of the model's context window in tokens; zero means unknown.
+	ContextWindow int
+
+	// ContextWarningThresholds are the percentages of the context window at which to warn the user.
+	ContextWarningThresholds []int
+
+	// ShowContextUsage shows the context usage after each round.
+	ShowContextUsage bool
+
+	// contextTokens is the number of tokens in the context, as reported by the last LLM response.
+	contextTokens int
+
+	// contextWarnedThreshold is the highest warning threshold we have already warned about.
+	contextWarnedThreshold int
+
+	// ClusterMetadata gathers facts about the target cluster from Init and includes them in the system prompt.
+	ClusterMetadata bool
+
+	// clusterInfo holds the facts about the target cluster, if ClusterMetadata is enabled.
+	clusterInfo *tools.ClusterInfo
+	// pendingClusterInfo receives the facts about the cluster while they are gathered in the background.
+	pendingClusterInfo chan *tools.ClusterInfo
+
+	// StablePrompt keeps the system prompt and function definitions the same across sessions, so that
+	// providers can reuse their prompt cache: they are canonicalized, and the cluster facts are sent with
+	// the first query instead of in the system prompt.
+	StablePrompt bool
+
+	// SlimToolSchemas shortens the descriptions of the function definitions offered to the LLM, to keep sessions with
+	// many tools within the limits providers put on the size of tool schemas.
+	SlimToolSchemas bool
+
+	// clusterFacts describes the cluster to the LLM with the next query, if StablePrompt keeps it out of the system prompt.
+	clusterFacts string
+
+	// history is the chat history of the current branch, used to undo rounds and to fork branches.
+	history []*journal.HistoryEntry
+
+	// roundStarts are the indexes in history at which each round started.
+	roundStarts []int
+
+	// branches holds the saved state of the branches other than the current one.
+	branches map[string]*branchState
+
+	// currentBranch is the name of the current branch.
+	currentBranch string
+
+	// resumedHistory is the transcript of a replayed session, sent to the LLM with the next query.
+	resumedHistory string
+
+	// attachments are the messages with the content attached by the user, sent ahead of the next query.
+	attachments []any
+
+	// VerifyMutations runs read-only commands after a tool call that modified resources,
+	// and asks the LLM to confirm from their output that the change took effect.
+	VerifyMutations bool
+
+	// Language is the language the LLM replies to the user in, e.g. "French" or "ja"; empty lets it reply in
+	// the language of the query. Commands, resource names and tool arguments stay as they are.
+	Language string
+
+	// AnswerStyle is the preset for the form of the answers, e.g. concise-ops for operators or executive-summary
+	// for managers; it is sent with each query, so it can be changed between queries.
+	AnswerStyle AnswerStyle
+
+	// StructuredAnswer asks the LLM to give its final answer by calling the final_answer function,
+	// which captures a summary, the resources touched and suggested follow-ups.
+	StructuredAnswer bool
+
+	// SuggestFollowUps suggests 2 or 3 queries the user is likely to ask after each answer, numbered so that
+	// the next query is one keypress away (see FollowUp).
+	SuggestFollowUps bool
+
+	// HighlightAnomalies flags anomalous values in the output of commands, such as restart counts, OOMKilled
+	// containers, ImagePullBackOff, NotReady nodes and pressure conditions: they are highlighted for the user,
+	// and listed for the LLM alongside the output, to focus its attention on the likely causes of a problem.
+	HighlightAnomalies bool
+
+	// MonitorWindow is how long to watch the namespaces changed by a kubectl or bash command for warning events,
+	// restarts and pods that cannot start, after the change. If it looks like a regression, the LLM is asked to
+	// propose a rollback. Zero disables monitoring.
+	MonitorWindow time.Duration
+
+	// SuggestAlternatives asks the LLM to propose a safer or read-only alternative when the user declines a call,
+	// rather than only telling it the call was declined.
+	SuggestAlternatives bool
+
+	// Assertions offers the LLM the assert function, which checks a condition as a step of a plan;
+	// when an assertion fails, the calls after it in the same response are not run, and the LLM replans.
+	Assertions bool
+
+	// FanOutParallelism offers the LLM the fan_out function, which runs a sub-task in each of a list of
+	// namespaces or contexts in separate sessions, this many at a time. Zero means fan_out is not offered.
+	FanOutParallelism int
+
+	// Memories, if not nil, are the facts remembered from earlier sessions: the ones relevant to a query are
+	// given to the LLM with it, and the LLM is offered the remember function to add to them.
+	Memories *tools.Memories
+
+	// EmbeddingModel is the model used to embed memories, to find the ones relevant to a query;
+	// empty means the provider's default. Memories are matched by the words they share if the provider cannot embed.
+	EmbeddingModel string
+
+	// Inventory, if not nil, indexes the names of the resources in each namespace, to give the LLM the exact names
+	// of the resources a query refers to, such as "the payments deployment", with the query.
+	Inventory *tools.Inventory
+
+	// AttributionAnnotations annotates the objects that tool calls create or modify with the session, the user,
+	// the time and a hash of the query (see tools.Attribution), so that changes can be traced back to the session.
+	AttributionAnnotations bool
+
+	// SessionID identifies the session in the attribution annotations; Init generates one if it is empty.
+	SessionID string
+
+	// User is the user recorded in the attribution annotations.
+	User string
+
+	// roundAttribution is the attribution of the changes made in the current round, if AttributionAnnotations is set.
+	roundAttribution *tools.Attribution
+
+	// AutoCleanup deletes the objects created by a round that is aborted before it completes (e.g. with Ctrl+C,
+	// or when it times out) without asking. Otherwise the user is asked whether to delete them, in interactive sessions.
+	AutoCleanup bool
+
+	// ledger is the change ledger of the current round: the objects created by its tool calls.
+	ledger tools.Ledger
+	// portForwards are the port-forwards started by the port_forward tool; they are stopped when the agent is closed.
+	portForwards tools.PortForwards
+
+	// recalledMemories are the texts of the memories already given to the LLM in the session.
+	recalledMemories map[string]bool
+
+	// roundResult is what was done in the current round, returned by RunOneRound.
+	roundResult *Result
+
+	// lastAnswer is the final answer to the last query.
+	lastAnswer *FinalAnswer
+	// followUps are the follow-up queries suggested after the last answer.
+	followUps []string
+
+	// roundCommands are the descriptions of the tool calls run in the current round.
+	roundCommands []string
+
+	// MaxRepeatedToolCalls is how many times the LLM can make the same tool call, with the same arguments,
+	// while answering a query. Further identical calls are not run; the LLM is told to try something else,
+	// and the round stops if it repeats the call once more. Zero means no limit.
+	MaxRepeatedToolCalls int
+
+	// MaxToolErrors is how many tool calls in a row can fail (e.g. an unknown tool, or invalid arguments)
+	// before the round stops. Failures are reported to the LLM, so it can correct the call. Zero means no limit.
+	MaxToolErrors int
+
+	// roundToolErrors counts the tool calls that failed in a row in the current round.
+	roundToolErrors int
+
+	// SummarizeOutputBytes is the size above which the output of a command is summarized by the LLM
+	// before it is sent to the LLM, keeping the lines that report errors. Zero means never summarize.
+	SummarizeOutputBytes int
+
+	// roundCalls counts the tool calls made in the current round, by toolCallKey.
+	roundCalls map[string]int
+
+	// LoopAction is what to do when the LLM is stuck in a loop, repeating a call or alternating between two changes.
+	// Defaults to LoopActionCorrect. Loops are detected if MaxRepeatedToolCalls is set.
+	LoopAction LoopAction
+
+	// roundMutations are the calls that may modify resources made in the current round,
+	// and roundOscillations the number of times the LLM alternated between two of them.
+	roundMutations    []mutationCall
+	roundOscillations int
+
+	// ToolQuotas limit the tool calls of the session; when a call would exceed one, the agent pauses
+	// until the user extends it, or stops the round.
+	ToolQuotas ToolQuotas
+
+	// quotaExtensions are the extensions of the quotas granted by the user, and quotaUsage the calls
+	// counted against them. Like the budget, they are not reset by Init.
+	quotaExtensions ToolQuotas
+	quotaUsage      toolQuotaUsage
+
+	// hooks are the callbacks registered with AddHooks.
+	hooks []Hooks
+
+	// feedback is the feedback given in the session; it is not reset by Init.
+	feedback []*Feedback
+
+	// stats are the counters shown by /stats.
+	stats SessionStats
+
+	// MaxTokens stops the agent before an LLM request that would take the tokens used in the session over it.
+	// Zero means no limit.
+	MaxTokens int
+
+	// MaxCost stops the agent before an LLM request that would take the cost of the session (in US dollars,
+	// estimated with TokenPrice) over it. Zero means no limit.
+	MaxCost float64
+
+	// TokenPrice is the price of the model's tokens, used to estimate the cost of the session.
+	TokenPrice gollm.TokenPrice
+
+	// spent is the usage of all LLM requests in the session, and spentCost their estimated cost.
+	spent     gollm.Usage
+	spentCost float64
+
+	// pendingResults are function call results that must be sent to the LLM with the next query,
+	// e.g. the acknowledgement of a final_answer call.
+	pendingResults []any
+
 	workDir string
+	// failed is set once a round of the session fails, for WorkDirKeepOnError.
+	failed bool
 }
 
-func (s *Conversation) Init(ctx context.Context, doc *ui.Document) error {
+// Conversation is the former name of Agent.
+//
+// Deprecated: use Agent.
+type Conversation = Agent
+
+// Init starts a new session, without any history, that shows what it does in doc (e.g. a *ui.Document).
+// It can be called again to start over; the budget, quotas and hooks carry over.
+func (s *Agent) Init(ctx context.Context, doc UI) error {
 	log := klog.FromContext(ctx)
 
+	if s.Recorder == nil {
+		s.Recorder = &journal.LogRecorder{}
+	}
+
 	// Create a temporary working directory
-	workDir, err := os.MkdirTemp("", "agent-workdir-*")
+	workDir, err := os.MkdirTemp("", workDirPattern)
 	if err != nil {
 		log.Error(err, "Failed to create temporary working directory")
 		return err
@@ -85,77 +383,288 @@ func (s *Conversation) Init(ctx context.Context, doc *ui.Document) error {
 
 	log.Info("Created temporary working directory", "workDir", workDir)
 
-	systemPrompt, err := s.generatePrompt(ctx, defaultSystemPromptTemplate, PromptData{
+	s.clusterInfo = nil
+	s.pendingClusterInfo = nil
+	if s.ClusterMetadata {
+		clusterCtx := context.WithValue(ctx, tools.KubeconfigKey, s.Kubeconfig)
+		clusterCtx = context.WithValue(clusterCtx, tools.WorkDirKey, workDir)
+		// The cluster is probed in the background, so that the user can type the first query meanwhile.
+		pending := make(chan *tools.ClusterInfo, 1)
+		go func() {
+			started := time.Now()
+			clusterInfo := tools.GatherClusterInfo(clusterCtx)
+			log.Info("Gathered cluster metadata", "clusterInfo", clusterInfo, "duration", time.Since(started))
+			pending <- clusterInfo
+		}()
+		s.pendingClusterInfo = pending
+	}
+
+	// The chat is started with the first query, as its system prompt needs the facts about the cluster.
+	s.llmChat = nil
+
+	s.workDir = workDir
+	s.doc = doc
+	if s.SessionID == "" {
+		s.SessionID = uuid.NewString()
+	}
+	s.resumedHistory = ""
+	s.attachments = nil
+	s.recalledMemories = nil
+	s.ledger.Reset()
+	s.lastAnswer = nil
+	s.followUps = nil
+	s.pendingResults = nil
+	s.stats = SessionStats{Started: time.Now()}
+	s.failed = false
+	s.writeWorkDirInfo(time.Time{})
+	s.contextTokens = 0
+	s.contextWarnedThreshold = 0
+	s.history = nil
+	s.roundStarts = nil
+	s.branches = nil
+	s.currentBranch = defaultBranch
+
+	return nil
+}
+
+// awaitClusterInfo waits for the facts about the cluster, if they are still being gathered.
+func (s *Agent) awaitClusterInfo(ctx context.Context) {
+	if s.pendingClusterInfo == nil {
+		return
+	}
+	started := time.Now()
+	s.clusterInfo = <-s.pendingClusterInfo
+	s.pendingClusterInfo = nil
+	klog.FromContext(ctx).V(1).Info("Waited for cluster metadata", "duration", time.Since(started))
+}
+
+// startChat starts a new chat session with the LLM, without any history.
+func (s *Agent) startChat(ctx context.Context) error {
+	s.awaitClusterInfo(ctx)
+
+	promptData := PromptData{
 		Tools:             s.Tools,
 		EnableToolUseShim: s.EnableToolUseShim,
-	})
+		Simulate:          s.Simulate,
+		Language:          s.Language,
+		canonical:         s.StablePrompt,
+	}
+	s.clusterFacts = ""
+	if s.clusterInfo.Known() {
+		if s.StablePrompt {
+			s.clusterFacts = clusterFactsMessage(s.clusterInfo)
+		} else {
+			promptData.Cluster = s.clusterInfo
+		}
+	}
+	systemPrompt, err := s.generatePrompt(ctx, defaultSystemPromptTemplate, promptData)
 	if err != nil {
 		return fmt.Errorf("generating system prompt: %w", err)
 	}
+	if s.StablePrompt {
+		systemPrompt = canonicalPrompt(systemPrompt)
+	}
 
-	// Start a new chat session
-	s.llmChat = gollm.NewRetryChat(
-		s.LLM.StartChat(systemPrompt, s.Model),
-		gollm.RetryConfig{
-			MaxAttempts:    3,
-			InitialBackoff: 10 * time.Second,
-			MaxBackoff:     60 * time.Second,
-			BackoffFactor:  2,
-			Jitter:         true,
-		},
-	)
-
+	var functionDefinitions []*gollm.FunctionDefinition
 	if !s.EnableToolUseShim {
-		var functionDefinitions []*gollm.FunctionDefinition
 		for _, tool := range s.Tools.AllTools() {
 			functionDefinitions = append(functionDefinitions, tool.FunctionDefinition())
 		}
+		if s.StructuredAnswer {
+			functionDefinitions = append(functionDefinitions, finalAnswerFunctionDefinition(s.SuggestFollowUps))
+		}
+		if s.Assertions {
+			functionDefinitions = append(functionDefinitions, assertFunctionDefinition())
+		}
+		if s.FanOutParallelism > 0 {
+			functionDefinitions = append(functionDefinitions, fanOutFunctionDefinition())
+		}
+		if s.Memories != nil {
+			functionDefinitions = append(functionDefinitions, rememberFunctionDefinition())
+		}
+		if s.SlimToolSchemas {
+			for i, definition := range functionDefinitions {
+				functionDefinitions[i] = tools.SlimFunctionDefinition(definition)
+			}
+		}
 		// Sort function definitions to help KV cache reuse
 		sort.Slice(functionDefinitions, func(i, j int) bool {
 			return functionDefinitions[i].Name < functionDefinitions[j].Name
 		})
+		if s.StablePrompt {
+			functionDefinitions = canonicalFunctionDefinitions(functionDefinitions)
+		}
+	}
+
+	// Record which prompt is in use, so that regressions can be traced to a prompt change,
+	// and changes to the prompt prefix can be told apart from poor prompt cache reuse.
+	s.Recorder.Write(ctx, &journal.Event{
+		Timestamp: time.Now(),
+		Action:    journal.ActionSystemPrompt,
+		Payload: map[string]any{
+			"profile":     s.PromptProfile,
+			"sha256":      fmt.Sprintf("%x", sha256.Sum256([]byte(systemPrompt))),
+			"toolsSha256": functionDefinitionsHash(functionDefinitions),
+			"toolsBytes":  tools.FunctionDefinitionsSize(functionDefinitions...),
+			"model":       s.Model,
+		},
+	})
+
+	// Start a new chat session
+	s.llmChat = gollm.NewRetryChat(
+		s.LLM.StartChat(systemPrompt, s.Model),
+		s.retryConfig(),
+	)
+
+	if !s.EnableToolUseShim {
 		if err := s.llmChat.SetFunctionDefinitions(functionDefinitions); err != nil {
 			return fmt.Errorf("setting function definitions: %w", err)
 		}
 	}
-	s.workDir = workDir
-	s.doc = doc
-
 	return nil
 }
 
-func (c *Conversation) Close() error {
+func (c *Agent) Close() error {
+	c.portForwards.Close()
 	if c.workDir != "" {
-		if c.RemoveWorkDir {
-			if err := os.RemoveAll(c.workDir); err != nil {
-				klog.Warningf("error cleaning up directory %q: %v", c.workDir, err)
-			}
+		if c.WorkDirRetention.keep(c.failed) {
+			c.writeWorkDirInfo(time.Now())
+		} else if err := os.RemoveAll(c.workDir); err != nil {
+			klog.Warningf("error cleaning up directory %q: %v", c.workDir, err)
 		}
 	}
 	return nil
 }
 
-// RunOneRound executes a chat-based agentic loop with the LLM using function calling.
-func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
+// runOneRound executes a chat-based agentic loop with the LLM using function calling.
+func (a *Agent) runOneRound(ctx context.Context, query string) error {
 	log := klog.FromContext(ctx)
 	log.Info("Starting chat loop for query:", "query", query)
 
+	if a.RoundTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, a.RoundTimeout)
+		defer cancel()
+	}
+
+	if a.llmChat == nil {
+		if err := a.startChat(ctx); err != nil {
+			return err
+		}
+	}
+
+	a.roundStarts = append(a.roundStarts, len(a.history))
+	a.stats.Rounds++
+	a.lastAnswer = nil
+	a.followUps = nil
+	a.roundCommands = nil
+	a.roundCalls = nil
+	a.roundToolErrors = 0
+	a.roundMutations = nil
+	a.roundOscillations = 0
+	a.roundAttribution = nil
+	a.ledger.Reset()
+	if a.AttributionAnnotations {
+		a.roundAttribution = &tools.Attribution{SessionID: a.SessionID, User: a.User, QueryHash: tools.QueryHash(query)}
+	}
+
 	// currChatContent tracks chat content that needs to be sent
 	// to the LLM in each iteration of  the agentic loop below
 	var currChatContent []any
 
 	// Set the initial message to start the conversation
-	currChatContent = []any{query}
+	currChatContent = append(a.pendingResults, a.attachments...)
+	if memories := a.recallMemories(ctx, query); memories != "" {
+		currChatContent = append(currChatContent, memories)
+	}
+	if references := a.resolveReferences(ctx, query); references != "" {
+		currChatContent = append(currChatContent, references)
+	}
+	currChatContent = append(currChatContent, query)
+	if message := answerStyleMessage(a.AnswerStyle); message != "" {
+		currChatContent = append(currChatContent, message)
+	}
+	a.pendingResults = nil
+	a.attachments = nil
 
 	currentIteration := 0
 	maxIterations := a.MaxIterations
+	progress := newIterationProgress()
+
+	// shimCorrections is the number of malformed shim responses in a row.
+	shimCorrections := 0
+
+	// roundQuery is recorded with the first contents we send, which start the round.
+	roundQuery := query
 
-	for currentIteration < maxIterations {
+	for {
+		if currentIteration >= maxIterations {
+			extension, err := a.extendIterations(maxIterations, progress)
+			if err != nil {
+				return err
+			}
+			if extension == 0 {
+				break
+			}
+			maxIterations += extension
+		}
 		log.Info("Starting iteration", "iteration", currentIteration)
 
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			errorBlock := ui.NewErrorBlock().SetText(fmt.Sprintf("Sorry, couldn't complete the task within %s.\n", a.doc.TimeFormat().Duration(a.RoundTimeout)))
+			a.doc.AddBlock(errorBlock)
+			return fmt.Errorf("round timeout of %v exceeded: %w", a.RoundTimeout, ctx.Err())
+		}
+
+		// Pick up any guidance the user typed while we were working, so they can steer without restarting.
+		for _, interjection := range a.doc.TakeInterjections() {
+			log.Info("user interjected", "text", interjection)
+			block := ui.NewAgentTextBlock().WithText(fmt.Sprintf("Taking your guidance into account: %s", interjection))
+			block.SetColor(ui.ColorWhite)
+			a.doc.AddBlock(block)
+			currChatContent = append(currChatContent, fmt.Sprintf("While you were working, the user added this guidance: %s", interjection))
+		}
+
+		if reason := a.budgetExhausted(); reason != "" {
+			// Keep the results of the calls we made, so they can be sent with the next query.
+			for _, content := range currChatContent {
+				if _, ok := content.(gollm.FunctionCallResult); ok {
+					a.pendingResults = append(a.pendingResults, content)
+				}
+			}
+			if currentIteration == 0 {
+				// The query was not sent, so there is no round to undo.
+				a.roundStarts = a.roundStarts[:len(a.roundStarts)-1]
+			}
+			return a.stopForBudget(reason)
+		}
+
+		a.history = append(a.history, userHistoryEntry(currChatContent, roundQuery))
+		if roundQuery != "" {
+			// The query is recorded on its own, as the contents we send also hold other messages, so that resumed
+			// sessions know where each round starts.
+			a.Recorder.Write(ctx, &journal.Event{
+				Timestamp: time.Now(),
+				Action:    journal.ActionUserQuery,
+				Payload:   map[string]any{"query": roundQuery},
+			})
+			roundQuery = ""
+		}
+
+		if a.resumedHistory != "" {
+			// Give the LLM the history of the session we are resuming, ahead of the first query.
+			currChatContent = append([]any{a.resumedHistory}, currChatContent...)
+			a.resumedHistory = ""
+		}
+		if a.clusterFacts != "" {
+			// The cluster facts are kept out of the system prompt (see StablePrompt), so we give them ahead of the first query.
+			currChatContent = append([]any{a.clusterFacts}, currChatContent...)
+			a.clusterFacts = ""
+		}
+
 		a.Recorder.Write(ctx, &journal.Event{
 			Timestamp: time.Now(),
-			Action:    "llm-chat",
+			Action:    journal.ActionLLMChat,
 			Payload:   []any{currChatContent},
 		})
 
@@ -167,7 +676,9 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 		agentTextBlock.SetStreaming(true)
 		a.doc.AddBlock(agentTextBlock)
 
-		stream, err := a.llmChat.SendStreaming(ctx, currChatContent...)
+		a.stats.LLMRequests++
+		requestStarted := time.Now()
+		stream, err := a.sendStreaming(ctx, currChatContent...)
 		if err != nil {
 			return err
 		}
@@ -185,8 +696,28 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 
 		// Process each part of the response
 		var functionCalls []gollm.FunctionCall
+		// usage is the token usage of the response; streamed responses report it (cumulatively) in the last chunks.
+		var usage gollm.Usage
+		modelEntry := &journal.HistoryEntry{Role: journal.RoleModel, Timestamp: time.Now()}
+		a.history = append(a.history, modelEntry)
+
+		// malformed is set if the response was not valid ReAct JSON, and we asked the LLM to correct it.
+		malformed := false
 
 		for response, err := range stream {
+			var parseErr *shimParseError
+			if errors.As(err, &parseErr) && shimCorrections < a.ShimCorrectionAttempts {
+				shimCorrections++
+				log.Info("asking the LLM to correct a malformed response", "attempt", shimCorrections, "error", parseErr.err)
+				// The thought may have been streamed already; we keep the whole response instead.
+				modelEntry.Messages = []string{parseErr.response}
+				block := ui.NewAgentTextBlock().WithText(fmt.Sprintf("The response could not be parsed, asking the model to correct it (attempt %d of %d)", shimCorrections, a.ShimCorrectionAttempts))
+				block.SetColor(ui.ColorYellow)
+				a.doc.AddBlock(block)
+				currChatContent = append(currChatContent, shimCorrectionPrompt(parseErr))
+				malformed = true
+				break
+			}
 			if err != nil {
 				log.Error(err, "error reading streaming LLM response")
 				return fmt.Errorf("reading streaming LLM response: %w", err)
@@ -196,19 +727,40 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 				break
 			}
 			klog.Infof("response: %+v", response)
-			a.Recorder.Write(ctx, &journal.Event{
-				Timestamp: time.Now(),
-				Action:    "llm-response",
-				Payload:   response,
-			})
 
 			if len(response.Candidates()) == 0 {
+				a.Recorder.Write(ctx, &journal.Event{
+					Timestamp: time.Now(),
+					Action:    journal.ActionLLMResponse,
+					Payload:   gollm.RecordChatResponse{Raw: response},
+				})
 				log.Error(nil, "No candidates in response")
 				return fmt.Errorf("no candidates in LLM response")
 			}
 
 			candidate := response.Candidates()[0]
 
+			// Record the response in a provider-independent form, so the journal can be replayed.
+			record := gollm.RecordChatResponse{Raw: response}
+			for _, part := range candidate.Parts() {
+				if text, ok := part.AsText(); ok {
+					record.Text += text
+				}
+				if calls, ok := part.AsFunctionCalls(); ok {
+					record.FunctionCalls = append(record.FunctionCalls, calls...)
+				}
+			}
+			a.Recorder.Write(ctx, &journal.Event{
+				Timestamp: time.Now(),
+				Action:    journal.ActionLLMResponse,
+				Payload:   record,
+			})
+
+			if responseUsage, ok := gollm.UsageFromMetadata(response.UsageMetadata()); ok {
+				usage = responseUsage
+				a.contextTokens = usage.TotalTokens
+			}
+
 			for _, part := range candidate.Parts() {
 				// Check if it's a text response
 				if text, ok := part.AsText(); ok {
@@ -219,12 +771,17 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 						a.doc.AddBlock(agentTextBlock)
 					}
 					agentTextBlock.AppendText(text)
+					if len(modelEntry.Messages) == 0 {
+						modelEntry.Messages = append(modelEntry.Messages, "")
+					}
+					modelEntry.Messages[0] += text
 				}
 
 				// Check if it's a function call
 				if calls, ok := part.AsFunctionCalls(); ok && len(calls) > 0 {
 					log.Info("function calls", "calls", calls)
 					functionCalls = append(functionCalls, calls...)
+					modelEntry.FunctionCalls = append(modelEntry.FunctionCalls, calls...)
 				}
 			}
 		}
@@ -232,6 +789,25 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 		if agentTextBlock != nil {
 			agentTextBlock.SetStreaming(false)
 		}
+		a.stats.LLMTime += time.Since(requestStarted)
+		a.stats.addUsage(usage)
+		a.addSpent(usage)
+		if !malformed {
+			response := LLMResponse{Model: a.Model, FunctionCalls: functionCalls, Usage: usage}
+			if len(modelEntry.Messages) > 0 {
+				response.Text = modelEntry.Messages[0]
+			}
+			a.recordResponse(response)
+			a.onLLMResponse(ctx, response)
+		}
+
+		a.warnOnContextUsage()
+
+		if malformed {
+			currentIteration++
+			continue
+		}
+		shimCorrections = 0
 
 		// TODO(droot): Run all function calls in parallel
 		// (may have to specify in the prompt to make these function calls independent)
@@ -239,10 +815,88 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 		// Suggestion: Use goroutines and sync.WaitGroup to parallelize execution if tool calls are independent.
 		// Be careful with shared state and UI updates if running in parallel.
 
+		// loopStopped is set to why we stop the round, if the LLM is stuck in a loop.
+		loopStopped := ""
+
+		// quotaStop is set to why a call could not run because of a tool quota; the remaining calls are not run either.
+		quotaStop := ""
+
+		// assertionFailed is set to the assertion that failed, if any; the calls after it are not run.
+		assertionFailed := ""
+
 		for _, call := range functionCalls {
+			if assertionFailed != "" {
+				currChatContent = append(currChatContent, skippedAfterAssertion(call, assertionFailed))
+				continue
+			}
+			if a.Assertions && !a.EnableToolUseShim && call.Name == assertFunctionName {
+				result, failed := a.assert(ctx, call)
+				currChatContent = append(currChatContent, result)
+				assertionFailed = failed
+				continue
+			}
+			if a.StructuredAnswer && call.Name == finalAnswerFunctionName {
+				answer, err := parseFinalAnswer(call.Arguments)
+				if err != nil {
+					// Let the LLM correct its answer.
+					currChatContent = append(currChatContent, gollm.FunctionCallResult{
+						ID:     call.ID,
+						Name:   call.Name,
+						Result: map[string]any{"error": err.Error()},
+					})
+					continue
+				}
+				answer.CommandsRun = a.roundCommands
+				answer.Language = a.Language
+				a.lastAnswer = answer
+				a.doc.AddBlock(ui.NewAgentTextBlock().WithText(answer.Markdown()))
+				currChatContent = append(currChatContent, gollm.FunctionCallResult{
+					ID:     call.ID,
+					Name:   call.Name,
+					Result: map[string]any{"status": "delivered"},
+				})
+				continue
+			}
+			if a.FanOutParallelism > 0 && !a.EnableToolUseShim && call.Name == fanOutFunctionName {
+				currChatContent = append(currChatContent, a.fanOut(ctx, call))
+				continue
+			}
+			if a.Memories != nil && !a.EnableToolUseShim && call.Name == rememberFunctionName {
+				currChatContent = append(currChatContent, a.remember(ctx, call))
+				continue
+			}
+
 			toolCall, err := a.Tools.ParseToolInvocation(ctx, call.Name, call.Arguments)
 			if err != nil {
-				return fmt.Errorf("building tool call: %w", err)
+				result, tooMany := a.toolFailed(call, call.Name, err)
+				currChatContent = append(currChatContent, result)
+				if tooMany {
+					a.pendingResults = currChatContent
+					return fmt.Errorf("%d tool calls failed in a row, the last one: building tool call: %w", a.roundToolErrors, err)
+				}
+				continue
+			}
+
+			// Don't let the LLM loop on the same call: tell it to stop repeating the call, and give up if it doesn't.
+			if a.MaxRepeatedToolCalls > 0 {
+				if previous := a.repeatedCall(call); previous >= a.MaxRepeatedToolCalls {
+					description := toolCall.Description()
+					log.Info("repeated tool call", "call", description, "previous", previous)
+					decision, err := a.onLoop(fmt.Sprintf("%s was already run %d times for this query", description, previous), previous > a.MaxRepeatedToolCalls)
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading input: %w", err)
+					}
+					if decision != loopRun {
+						if decision == loopStop {
+							loopStopped = fmt.Sprintf("the model kept repeating the same call (%s)", description)
+						}
+						currChatContent = append(currChatContent, loopResult(a.EnableToolUseShim, call, repeatedCallResult(description, previous)))
+						continue
+					}
+				}
 			}
 
 			// Check if the command is interactive using the tool's implementation
@@ -276,7 +930,6 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 			functionCallRequestBlock := ui.NewFunctionCallRequestBlock().SetDescription(toolDescription)
 			a.doc.AddBlock(functionCallRequestBlock)
 
-			// Ask for confirmation only if SkipPermissions is false AND the tool modifies resources.
 			// Use the tool's CheckModifiesResource method to determine if the command modifies resources
 			modifiesResourceStr := toolCall.GetTool().CheckModifiesResource(call.Arguments)
 
@@ -288,40 +941,184 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 				}
 			}
 
-			if !a.SkipPermissions && modifiesResourceStr != "no" {
-				confirmationPrompt := `  Do you want to proceed ?`
+			// The confirmation policy decides whether to ask the user, or refuses to run the call.
+			confirm, denied := a.checkPolicy(ctx, call, modifiesResourceStr)
+			if denied {
+				currChatContent = append(currChatContent, a.denyCall(call, toolCall.Description()))
+				continue
+			}
 
-				optionsBlock := ui.NewInputOptionBlock().SetPrompt(confirmationPrompt)
-				optionsBlock.AddOption("yes", "Yes", "yes", "y")
-				optionsBlock.AddOption("yes_and_dont_ask_me_again", "Yes, and don't ask me again")
-				optionsBlock.AddOption("no", "No", "no", "n")
-				a.doc.AddBlock(optionsBlock)
+			// Don't let the LLM flip-flop between two changes that undo each other.
+			if a.MaxRepeatedToolCalls > 0 && modifiesResourceStr != "no" {
+				description := toolCall.Description()
+				if other := a.oscillatingCall(call, description); other != "" {
+					log.Info("oscillating tool calls", "call", description, "other", other)
+					decision, err := a.onLoop(fmt.Sprintf("it is alternating between %s and %s", other, description), a.roundOscillations > 0)
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading input: %w", err)
+					}
+					a.roundOscillations++
+					if decision != loopRun {
+						if decision == loopStop {
+							loopStopped = fmt.Sprintf("the model kept alternating between %s and %s", other, description)
+						}
+						currChatContent = append(currChatContent, loopResult(a.EnableToolUseShim, call, oscillationResult(other, description)))
+						continue
+					}
+				}
+			}
 
-				selectedChoice, err := optionsBlock.Selection().Wait()
+			// Pause before a call that would exceed a tool quota, until the user extends it.
+			if quotaStop == "" {
+				quotaStop, err = a.checkQuotas(call, modifiesResourceStr != "no" && !a.Simulate)
 				if err != nil {
 					if err == io.EOF {
 						return nil
 					}
 					return fmt.Errorf("reading input: %w", err)
 				}
+			}
+			if quotaStop != "" {
+				currChatContent = append(currChatContent, a.quotaExceededResult(call, quotaStop))
+				continue
+			}
+			// replacedCommand tells the LLM that another command ran than the one it proposed, if one did:
+			// the command as the user edited it, or an alternative chosen by the verifier or the user.
+			var replacedCommand string
+
+			// Nothing is changed in simulate mode, so there is nothing to confirm.
+			simulate := a.Simulate && modifiesResourceStr != "no"
+			if simulate {
+				confirm = false
+			}
+
+			// For commands that modify resources, optionally sample alternative commands,
+			// and either let the LLM pick the best one or offer them to the user.
+			var candidates []string
+			if a.MutationCandidates > 1 && modifiesResourceStr != "no" {
+				candidates = a.sampleCommandCandidates(ctx, query, call)
+				askUser := a.CandidateSelection == CandidateSelectionUser && confirm
+				if len(candidates) > 1 && !askUser {
+					modifiesResourceStr, replacedCommand = a.chooseCandidate(ctx, query, call, toolCall.GetTool(), candidates, modifiesResourceStr)
+					if replacedCommand != "" {
+						functionCallRequestBlock.SetDescription(toolCall.Description())
+						// The alternative may fall under a different rule of the policy.
+						confirm, denied = a.checkPolicy(ctx, call, modifiesResourceStr)
+						simulate = a.Simulate && modifiesResourceStr != "no"
+						if simulate {
+							confirm = false
+						}
+						if denied {
+							currChatContent = append(currChatContent, a.denyCall(call, toolCall.Description()))
+							continue
+						}
+					}
+					candidates = nil
+				}
+			}
+
+			if confirm && a.NonInteractive {
+				currChatContent = append(currChatContent, a.skipUnconfirmedCall(call, toolCall.Description()))
+				continue
+			}
+
+			if confirm {
+				confirmationPrompt := `  Do you want to proceed ?`
+
+				// The user can ask for an explanation of the call, and is then asked again.
+				var selectedChoice string
+				explained := false
+				for {
+					optionsBlock := ui.NewInputOptionBlock().SetPrompt(confirmationPrompt)
+					optionsBlock.AddOption("yes", "Yes", "yes", "y")
+					if a.Approvals != nil {
+						scope := tools.ApprovalPattern(call.Name, call.Arguments)
+						if kubeContext := a.currentContext(ctx); kubeContext != "" {
+							scope += fmt.Sprintf(" in context %s", kubeContext)
+						}
+						optionsBlock.AddOption("yes_and_dont_ask_me_again", fmt.Sprintf("Yes, and don't ask me again for %s", scope))
+					} else {
+						optionsBlock.AddOption("yes_and_dont_ask_me_again", "Yes, and don't ask me again")
+					}
+					optionsBlock.AddOption("no", "No", "no", "n")
+					if _, ok := editableCommand(call.Arguments); ok {
+						optionsBlock.AddOption("edit", "Edit the command first", "edit", "e")
+					}
+					if !explained {
+						optionsBlock.AddOption("explain", "Explain what it will do first", "explain", "?")
+					}
+					for i := 1; i < len(candidates); i++ {
+						optionsBlock.AddOption(fmt.Sprintf("alternative_%d", i), fmt.Sprintf("Run this alternative instead: %s", candidates[i]))
+					}
+					a.doc.AddBlock(optionsBlock)
+
+					choice, err := optionsBlock.Selection().Wait()
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading input: %w", err)
+					}
+					if choice != "explain" {
+						selectedChoice = choice
+						break
+					}
+					explained = true
+					a.explainCall(ctx, query, call, toolCall.Description())
+				}
+
+				if alternative, ok := strings.CutPrefix(selectedChoice, "alternative_"); ok {
+					if i, err := strconv.Atoi(alternative); err == nil && i > 0 && i < len(candidates) {
+						modifiesResourceStr, replacedCommand = useAlternative(call, toolCall.GetTool(), candidates[i], modifiesResourceStr, "the user")
+						functionCallRequestBlock.SetDescription(toolCall.Description())
+						selectedChoice = "yes"
+						if _, denied := a.checkPolicy(ctx, call, modifiesResourceStr); denied {
+							currChatContent = append(currChatContent, a.denyCall(call, toolCall.Description()))
+							continue
+						}
+					}
+				}
+
+				if selectedChoice == "edit" {
+					proposed, _ := editableCommand(call.Arguments)
+					edited, err := a.editCommand(proposed)
+					if err != nil {
+						if err == io.EOF {
+							return nil
+						}
+						return fmt.Errorf("reading input: %w", err)
+					}
+					if edited != proposed {
+						call.Arguments["command"] = edited
+						functionCallRequestBlock.SetDescription(toolCall.Description())
+						replacedCommand = userEdit(proposed, edited)
+						if modifies := toolCall.GetTool().CheckModifiesResource(call.Arguments); modifies != "unknown" {
+							modifiesResourceStr = modifies
+						}
+						if _, denied := a.checkPolicy(ctx, call, modifiesResourceStr); denied {
+							currChatContent = append(currChatContent, a.denyCall(call, toolCall.Description()))
+							continue
+						}
+					}
+					selectedChoice = "yes"
+				}
 
 				// Normalize the input
 				switch selectedChoice {
 				case "yes":
 					// Proceed with the operation
 				case "yes_and_dont_ask_me_again":
-					a.SkipPermissions = true
+					if a.Approvals == nil {
+						a.SkipPermissions = true
+					} else if err := a.Approvals.Add(call.Name, call.Arguments, a.currentContext(ctx)); err != nil {
+						log.Error(err, "Failed to store the approval")
+						a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("Could not store the approval, you will be asked again: %v", err)))
+					}
 				case "no":
-					a.doc.AddBlock(ui.NewAgentTextBlock().WithText("Operation was skipped. User declined to run this operation."))
-					currChatContent = append(currChatContent, gollm.FunctionCallResult{
-						ID:   call.ID,
-						Name: call.Name,
-						Result: map[string]any{
-							"error":     "User declined to run this operation.",
-							"status":    "declined",
-							"retryable": false,
-						},
-					})
+					currChatContent = append(currChatContent, a.declineCall(call, toolCall.Description()))
 					continue
 				default:
 					// This case should technically not be reachable due to AskForConfirmation loop
@@ -332,35 +1129,141 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 				}
 			}
 
-			ctx := journal.ContextWithRecorder(ctx, a.Recorder)
-			output, err := toolCall.InvokeTool(ctx, tools.InvokeToolOptions{
+			callInfo := ToolCallInfo{
+				Name:             call.Name,
+				Arguments:        call.Arguments,
+				Description:      toolCall.Description(),
+				ModifiesResource: modifiesResourceStr,
+				Simulated:        simulate,
+			}
+			if err := a.beforeToolCall(ctx, callInfo); err != nil {
+				log.Info("tool call refused by a hook", "call", callInfo.Description, "error", err)
+				currChatContent = append(currChatContent, a.refuseCall(call, callInfo.Description, err))
+				continue
+			}
+
+			var monitor *tools.ChangeMonitor
+			if modifiesResourceStr != "no" && !simulate {
+				monitor = a.startChangeMonitor(ctx, call)
+			}
+
+			toolCtx := journal.ContextWithRecorder(ctx, a.Recorder)
+			cancelTool := func() {}
+			if a.ToolTimeout > 0 {
+				toolCtx, cancelTool = context.WithTimeout(toolCtx, a.ToolTimeout)
+			}
+			output, err := toolCall.InvokeTool(toolCtx, tools.InvokeToolOptions{
 				Kubeconfig: a.Kubeconfig,
 				WorkDir:    a.workDir,
+				Progress: func(progress tools.Progress) {
+					functionCallRequestBlock.SetProgress(progress.Message, progress.Fraction())
+				},
+				Output:       functionCallRequestBlock,
+				Simulate:     simulate,
+				Attribution:  a.roundAttribution,
+				Ledger:       &a.ledger,
+				PortForwards: &a.portForwards,
 			})
+			toolTimedOut := a.ToolTimeout > 0 && errors.Is(toolCtx.Err(), context.DeadlineExceeded)
+			cancelTool()
+			if toolTimedOut {
+				// Report the timeout to the LLM as a result, so it can try a different approach.
+				timeoutMessage := fmt.Sprintf("tool call timed out after %v", a.ToolTimeout)
+				a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("  %s\n", timeoutMessage)))
+				if execResult, ok := output.(*tools.ExecResult); ok && execResult != nil {
+					execResult.Error = timeoutMessage
+				} else if err != nil {
+					output = &tools.ExecResult{Error: timeoutMessage}
+				}
+				err = nil
+			}
+			if blocked := tools.TakeEgressViolations(ctx); len(blocked) > 0 {
+				a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("  Blocked connections to hosts not allowed by the egress policy: %s\n", strings.Join(blocked, ", "))))
+			}
+			a.recordToolCall(callInfo, output, err)
+			a.afterToolCall(ctx, callInfo, output, err)
 			if err != nil {
 				log.Error(err, "error executing action", "output", output)
-				return fmt.Errorf("executing action: %w", err)
+				result, tooMany := a.toolFailed(call, toolCall.Description(), err)
+				currChatContent = append(currChatContent, result)
+				if tooMany {
+					a.pendingResults = currChatContent
+					return fmt.Errorf("%d tool calls failed in a row, the last one: executing action: %w", a.roundToolErrors, err)
+				}
+				continue
 			}
+			a.roundToolErrors = 0
+			if simulate {
+				a.roundCommands = append(a.roundCommands, toolCall.Description()+" (simulated)")
+			} else {
+				a.roundCommands = append(a.roundCommands, toolCall.Description())
+			}
+			a.stats.ToolCalls++
+			a.quotaUsage.add(call, modifiesResourceStr != "no" && !simulate)
 
 			// Handle timeout message using UI blocks
 			if execResult, ok := output.(*tools.ExecResult); ok && execResult != nil && execResult.StreamType == "timeout" {
 				a.doc.AddBlock(ui.NewAgentTextBlock().WithText("\nTimeout reached after 7 seconds\n"))
 			}
 
+			verify := a.VerifyMutations && modifiesResourceStr != "no" && !simulate
+
+			// The user sees the whole output, but oversized output is summarized for the LLM.
+			modelOutput := a.summarizeOutput(ctx, query, toolCall.Description(), output)
+			anomalies := a.flagAnomalies(output)
+
 			// Add the tool call result to maintain conversation flow
 			if a.EnableToolUseShim {
-				// If shim is enabled, format the result as a text observation
-				observation := fmt.Sprintf("Result of running %q:\n%v", call.Name, output)
+				// If shim is enabled, format the result as a text observation.
+				// Results with a schema are sent as JSON, as described in the prompt.
+				observation := fmt.Sprintf("Result of running %q:\n%v", call.Name, modelOutput)
+				if tools.ResultSchema(toolCall.GetTool()) != nil {
+					if b, err := json.MarshalIndent(modelOutput, "", "  "); err == nil {
+						observation = fmt.Sprintf("Result of running %q:\n%s", call.Name, b)
+					}
+				}
+				if replacedCommand != "" {
+					observation += "\n\n" + replacedCommand
+				}
+				if len(anomalies) > 0 {
+					observation += "\n\nAnomalies in the output:\n" + strings.Join(anomalies, "\n")
+				}
+				if verify {
+					if verification := a.verifyMutation(ctx, call, output); verification != "" {
+						observation += "\n\n" + verification
+					}
+				}
+				if monitor != nil {
+					if monitoring := a.monitorChange(ctx, monitor, output); monitoring != "" {
+						observation += "\n\n" + monitoring
+					}
+				}
 				currChatContent = append(currChatContent, observation)
 			} else {
 				functionCallRequestBlock.SetResult(output)
 
 				// If shim is disabled, convert the result to a map and append FunctionCallResult
-				result, err := tools.ToolResultToMap(output)
+				result, err := tools.ToolResultToMap(modelOutput)
 				if err != nil {
-					log.Error(err, "error converting tool result to map", "output", output)
+					log.Error(err, "error converting tool result to map", "output", modelOutput)
 					return err
 				}
+				if replacedCommand != "" {
+					result["replaced_command"] = replacedCommand
+				}
+				if len(anomalies) > 0 {
+					result["anomalies"] = anomalies
+				}
+				if verify {
+					if verification := a.verifyMutation(ctx, call, output); verification != "" {
+						result["verification"] = verification
+					}
+				}
+				if monitor != nil {
+					if monitoring := a.monitorChange(ctx, monitor, output); monitoring != "" {
+						result["monitoring"] = monitoring
+					}
+				}
 
 				currChatContent = append(currChatContent, gollm.FunctionCallResult{
 					ID:     call.ID,
@@ -370,12 +1273,44 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 			}
 		}
 
+		// Checkpoint the iteration, so that the session can be resumed with --resume-last if we crash.
+		a.writeCheckpoint(ctx, currChatContent)
+
+		if loopStopped != "" {
+			a.pendingResults = currChatContent
+			return a.stopForLoop(loopStopped)
+		}
+
+		if quotaStop != "" {
+			a.pendingResults = currChatContent
+			return a.stopForQuota(quotaStop)
+		}
+
+		// If the LLM gave its final answer, we're done.
+		// The results of this iteration are sent with the next query, as some providers require a result for every call.
+		if a.lastAnswer != nil {
+			a.pendingResults = currChatContent
+			if a.ShowContextUsage {
+				a.showContextUsage()
+			}
+			return nil
+		}
+
 		// If no function calls were made, we're done
 		if len(functionCalls) == 0 {
 			log.Info("No function calls were made, so most likely the task is completed, so we're done.")
+			var text string
+			if len(modelEntry.Messages) > 0 {
+				text = modelEntry.Messages[0]
+			}
+			a.lastAnswer = &FinalAnswer{Summary: text, CommandsRun: a.roundCommands}
+			if a.ShowContextUsage {
+				a.showContextUsage()
+			}
 			return nil
 		}
 
+		progress.record(currentIteration, a.roundCommands)
 		currentIteration++
 	}
 
@@ -387,10 +1322,10 @@ func (a *Conversation) RunOneRound(ctx context.Context, query string) error {
 }
 
 // generateFromTemplate generates a prompt for LLM. It uses the prompt from the provides template file or default.
-func (a *Conversation) generatePrompt(_ context.Context, defaultPromptTemplate string, data PromptData) (string, error) {
+func (a *Agent) generatePrompt(_ context.Context, defaultPromptTemplate string, data PromptData) (string, error) {
 	promptTemplate := defaultPromptTemplate
 	if a.PromptTemplateFile != "" {
-		content, err := os.ReadFile(a.PromptTemplateFile)
+		content, err := tools.ReadArtifact(a.PromptTemplateFile)
 		if err != nil {
 			return "", fmt.Errorf("error reading template file: %v", err)
 		}
@@ -398,14 +1333,21 @@ func (a *Conversation) generatePrompt(_ context.Context, defaultPromptTemplate s
 	}
 
 	for _, extraPromptPath := range a.ExtraPromptPaths {
-		content, err := os.ReadFile(extraPromptPath)
+		content, err := tools.ReadArtifact(extraPromptPath)
 		if err != nil {
 			return "", fmt.Errorf("error reading extra prompt path: %v", err)
 		}
 		promptTemplate += "\n" + string(content)
 	}
 
-	tmpl, err := template.New("promptTemplate").Parse(promptTemplate)
+	// Relative paths in templates are resolved against the directory of the prompt template file.
+	baseDir := ""
+	if a.PromptTemplateFile != "" {
+		baseDir = filepath.Dir(a.PromptTemplateFile)
+	}
+
+	tmpl := template.New("promptTemplate")
+	tmpl, err := tmpl.Funcs(promptFuncs(tmpl, baseDir)).Parse(promptTemplate)
 	if err != nil {
 		return "", fmt.Errorf("building template for prompt: %w", err)
 	}
@@ -424,6 +1366,18 @@ type PromptData struct {
 	Tools tools.Tools
 
 	EnableToolUseShim bool
+
+	// Cluster holds facts about the target cluster; nil if they are not known.
+	Cluster *tools.ClusterInfo
+
+	// Simulate is set if tool calls that modify resources are simulated rather than run.
+	Simulate bool
+
+	// Language is the language to reply to the user in; empty if not set.
+	Language string
+
+	// canonical is set if the tool definitions must be canonicalized (see Agent.StablePrompt).
+	canonical bool
 }
 
 func (a *PromptData) ToolsAsJSON() string {
@@ -432,6 +1386,9 @@ func (a *PromptData) ToolsAsJSON() string {
 	for _, tool := range a.Tools.AllTools() {
 		toolDefinitions = append(toolDefinitions, tool.FunctionDefinition())
 	}
+	if a.canonical {
+		toolDefinitions = canonicalFunctionDefinitions(toolDefinitions)
+	}
 
 	json, err := json.MarshalIndent(toolDefinitions, "", "  ")
 	if err != nil {
@@ -440,6 +1397,23 @@ func (a *PromptData) ToolsAsJSON() string {
 	return string(json)
 }
 
+// ToolResultSchemas returns the JSON schemas of the results of the tools that declare one, one tool per line.
+func (a *PromptData) ToolResultSchemas() string {
+	var sb strings.Builder
+	for _, name := range a.Tools.Names() {
+		schema := tools.ResultSchema(a.Tools.Lookup(name))
+		if schema == nil {
+			continue
+		}
+		b, err := json.Marshal(schema)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", name, b)
+	}
+	return sb.String()
+}
+
 func (a *PromptData) ToolNames() string {
 	return strings.Join(a.Tools.Names(), ", ")
 }
@@ -477,7 +1451,7 @@ func extractJSON(s string) (string, bool) {
 func parseReActResponse(input string) (*ReActResponse, error) {
 	cleaned, found := extractJSON(input)
 	if !found {
-		return nil, fmt.Errorf("no JSON code block found in %q", cleaned)
+		return nil, fmt.Errorf("no ```json code block found")
 	}
 
 	cleaned = strings.ReplaceAll(cleaned, "\n", "")
@@ -503,14 +1477,20 @@ func toMap(v any) (map[string]any, error) {
 	return m, nil
 }
 
+// candidateToShimCandidate converts the streamed text of a ReAct response into a function-calling response.
+// The thought is streamed as it arrives; the rest of the response is parsed once it is complete.
 func candidateToShimCandidate(iterator gollm.ChatResponseIterator) (gollm.ChatResponseIterator, error) {
 	return func(yield func(gollm.ChatResponse, error) bool) {
-		buffer := ""
+		thoughts := newThoughtStreamer()
+		var usageMetadata any
 		for response, err := range iterator {
 			if err != nil {
 				yield(nil, err)
 				return
 			}
+			if metadata := response.UsageMetadata(); metadata != nil {
+				usageMetadata = metadata
+			}
 
 			if len(response.Candidates()) == 0 {
 				yield(nil, fmt.Errorf("no candidates in LLM response"))
@@ -521,8 +1501,12 @@ func candidateToShimCandidate(iterator gollm.ChatResponseIterator) (gollm.ChatRe
 
 			for _, part := range candidate.Parts() {
 				if text, ok := part.AsText(); ok {
-					buffer += text
 					klog.Infof("text is %q", text)
+					if thought := thoughts.write(text); thought != "" {
+						if !yield(&ShimResponse{candidate: &ReActResponse{Thought: thought}}, nil) {
+							return
+						}
+					}
 				} else {
 					yield(nil, fmt.Errorf("no text part found in candidate"))
 					return
@@ -530,6 +1514,7 @@ func candidateToShimCandidate(iterator gollm.ChatResponseIterator) (gollm.ChatRe
 			}
 		}
 
+		buffer := thoughts.buffer
 		if buffer == "" {
 			yield(nil, nil)
 			return
@@ -537,20 +1522,44 @@ func candidateToShimCandidate(iterator gollm.ChatResponseIterator) (gollm.ChatRe
 
 		parsedReActResp, err := parseReActResponse(buffer)
 		if err != nil {
-			yield(nil, fmt.Errorf("parsing ReAct response %q: %w", buffer, err))
+			yield(nil, &shimParseError{response: buffer, err: err})
 			return
 		}
-		buffer = "" // TODO: any trailing text?
-		yield(&ShimResponse{candidate: parsedReActResp}, nil)
+		// TODO: any trailing text?
+		parsedReActResp.Thought = thoughts.remainder(parsedReActResp.Thought)
+		yield(&ShimResponse{candidate: parsedReActResp, usageMetadata: usageMetadata}, nil)
 	}, nil
 }
 
+// shimParseError is returned when the response of the LLM is not valid ReAct JSON, in tool-use shim mode.
+type shimParseError struct {
+	// response is the text of the response.
+	response string
+	err      error
+}
+
+func (e *shimParseError) Error() string {
+	return fmt.Sprintf("parsing ReAct response %q: %v", e.response, e.err)
+}
+
+func (e *shimParseError) Unwrap() error {
+	return e.err
+}
+
+// shimCorrectionPrompt asks the LLM to send its response again, in the expected format.
+func shimCorrectionPrompt(err *shimParseError) string {
+	return fmt.Sprintf("Your last response could not be parsed: %v\n"+
+		"Respond again with exactly one ```json code block, and no text outside of it. The block must contain a single JSON object "+
+		"with a \"thought\" field, and either an \"answer\" field or an \"action\" object with the fields \"name\", \"reason\", \"command\" and \"modifies_resource\".", err.err)
+}
+
 type ShimResponse struct {
-	candidate *ReActResponse
+	candidate     *ReActResponse
+	usageMetadata any
 }
 
 func (r *ShimResponse) UsageMetadata() any {
-	return nil
+	return r.usageMetadata
 }
 
 func (r *ShimResponse) Candidates() []gollm.Candidate {
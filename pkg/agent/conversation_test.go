@@ -0,0 +1,75 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+)
+
+func TestGeneratePromptVerifiesSignatures(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	template := write("template.txt", "You are a Kubernetes assistant.")
+	extra := write("extra.txt", "Always answer in French.")
+	included := write("included.txt", "Never delete namespaces.")
+
+	tests := []struct {
+		name            string
+		defaultTemplate string
+		template        string
+		extraPaths      []string
+		want            string
+	}{
+		{name: "template", template: template, want: "You are a Kubernetes assistant."},
+		{name: "extra prompt", extraPaths: []string{extra}, want: "Always answer in French."},
+		{name: "included file", defaultTemplate: `{{ file "` + included + `" }}`, want: "Never delete namespaces."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Agent{PromptTemplateFile: tt.template, ExtraPromptPaths: tt.extraPaths}
+
+			tools.SetArtifactPolicy(tools.ArtifactPolicy{})
+			prompt, err := a.generatePrompt(context.Background(), tt.defaultTemplate, PromptData{})
+			if err != nil {
+				t.Fatalf("generatePrompt() without trusted signers: %v", err)
+			}
+			if !strings.Contains(prompt, tt.want) {
+				t.Errorf("prompt %q does not contain %q", prompt, tt.want)
+			}
+
+			// The files are not signed, so they must not be loaded when signatures are required.
+			tools.SetArtifactPolicy(tools.ArtifactPolicy{
+				TrustedSigners:    []tools.TrustedSigner{{Identity: "platform-team@example.com", Issuer: "https://accounts.google.com"}},
+				RequireSignatures: true,
+			})
+			defer tools.SetArtifactPolicy(tools.ArtifactPolicy{})
+			if prompt, err := a.generatePrompt(context.Background(), tt.defaultTemplate, PromptData{}); err == nil {
+				t.Errorf("generatePrompt() with unsigned files = %q, expected an error", prompt)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// editableCommand returns the command of a call that the user can edit before it runs, e.g. of the kubectl and
+// bash tools; ok is false if the call has no command.
+func editableCommand(arguments map[string]any) (command string, ok bool) {
+	command, ok = arguments["command"].(string)
+	return command, ok && command != ""
+}
+
+// editCommand lets the user edit the command proposed by the LLM, and returns the command to run.
+// If the user leaves the input empty, the proposed command is run.
+func (a *Agent) editCommand(command string) (string, error) {
+	a.doc.AddBlock(ui.NewAgentTextBlock().WithText("  Edit the command, and press Enter to run it:"))
+	input := ui.NewInputTextBlock().SetInitialText(command)
+	input.SetEditable(true)
+	a.doc.AddBlock(input)
+
+	edited, err := input.Observable().Wait()
+	if err != nil {
+		return "", err
+	}
+	if edited = strings.TrimSpace(edited); edited == "" {
+		return command, nil
+	}
+	return edited, nil
+}
+
+// userEdit describes the edit of a command for the LLM, so it knows what actually ran.
+func userEdit(proposed, executed string) string {
+	return fmt.Sprintf("The user edited the command before running it. You proposed: %s\nThe user ran instead: %s", proposed, executed)
+}
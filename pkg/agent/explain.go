@@ -0,0 +1,59 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// explainCall asks the LLM to explain what a call that is waiting for the user's confirmation will do,
+// and what could go wrong, and shows the explanation to the user.
+func (c *Agent) explainCall(ctx context.Context, query string, call gollm.FunctionCall, description string) {
+	log := klog.FromContext(ctx)
+
+	arguments, err := json.Marshal(call.Arguments)
+	if err != nil {
+		arguments = []byte(fmt.Sprintf("%v", call.Arguments))
+	}
+	prompt := fmt.Sprintf(`You are helping a kubernetes operator, who may be new to kubernetes, decide whether to run a command.
+The user asked: %q
+
+The assistant wants to run %s, with the %q tool and these arguments:
+%s
+
+Explain, in a few short markdown bullet points:
+- exactly what it will do, step by step, including which resources it reads or changes,
+- what could go wrong, and what the impact would be (e.g. downtime, lost data), and
+- whether and how it can be undone.
+Do not suggest running it or not; the operator decides.`, query, description, call.Name, arguments)
+
+	response, err := c.LLM.GenerateCompletion(ctx, &gollm.CompletionRequest{
+		Model:  c.Model,
+		Prompt: prompt,
+	})
+	if err != nil {
+		log.Error(err, "asking the LLM to explain the command")
+		c.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("  Could not explain the command: %v\n", err)))
+		return
+	}
+	c.doc.AddBlock(ui.NewAgentTextBlock().WithText(strings.TrimSpace(response.Response())))
+}
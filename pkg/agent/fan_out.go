@@ -0,0 +1,282 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// fanOutFunctionName is the name of the function the LLM calls to run a sub-task across namespaces or contexts.
+const fanOutFunctionName = "fan_out"
+
+// maxFanOutTargets bounds the number of namespaces or contexts of a fan_out call.
+const maxFanOutTargets = 50
+
+// fanOutFunctionDefinition describes the fan_out function to the LLM.
+func fanOutFunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name: fanOutFunctionName,
+		Description: `Runs the same sub-task in each of a list of namespaces, or of kubeconfig contexts, in parallel,
+each in a separate session that only sees that namespace or context, and returns the answer for each of them.
+Use this to answer questions about many namespaces or clusters (e.g. "which namespaces have pods in CrashLoopBackOff and why"),
+then consolidate the answers. List the namespaces or contexts first if you don't know them.
+Give either namespaces or contexts, not both. The sessions cannot ask the user to confirm changes, so use this to investigate,
+not to modify resources.`,
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"task": {
+					Type:        gollm.TypeString,
+					Description: `The sub-task, phrased for a single namespace or context, e.g. "Find the pods in CrashLoopBackOff and explain why they crash."`,
+				},
+				"namespaces": {
+					Type:        gollm.TypeArray,
+					Items:       &gollm.Schema{Type: gollm.TypeString},
+					Description: `The namespaces to run the sub-task in.`,
+				},
+				"contexts": {
+					Type:        gollm.TypeArray,
+					Items:       &gollm.Schema{Type: gollm.TypeString},
+					Description: `The kubeconfig contexts to run the sub-task in.`,
+				},
+			},
+			Required: []string{"task"},
+		},
+	}
+}
+
+// fanOutTarget is a namespace or context that a sub-task runs in.
+type fanOutTarget struct {
+	// kind is "namespace" or "context".
+	kind string
+	name string
+}
+
+// scope tells the LLM to keep to the target.
+func (t fanOutTarget) scope() string {
+	if t.kind == "context" {
+		return fmt.Sprintf("Work only in the kubeconfig context %q: pass `--context %s` to every kubectl command, and don't look at other contexts.", t.name, t.name)
+	}
+	return fmt.Sprintf("Work only in the namespace %q: pass `-n %s` to every kubectl command, and don't look at other namespaces.", t.name, t.name)
+}
+
+// parseFanOut parses the arguments of a fan_out call.
+func parseFanOut(arguments map[string]any) (string, []fanOutTarget, error) {
+	task, _ := arguments["task"].(string)
+	if strings.TrimSpace(task) == "" {
+		return "", nil, fmt.Errorf("task is required")
+	}
+	names := func(key string) []string {
+		values, _ := arguments[key].([]any)
+		var names []string
+		for _, value := range values {
+			if name, ok := value.(string); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+	namespaces, contexts := names("namespaces"), names("contexts")
+	if len(namespaces) > 0 && len(contexts) > 0 {
+		return "", nil, fmt.Errorf("give either namespaces or contexts, not both")
+	}
+	var targets []fanOutTarget
+	for _, name := range namespaces {
+		targets = append(targets, fanOutTarget{kind: "namespace", name: name})
+	}
+	for _, name := range contexts {
+		targets = append(targets, fanOutTarget{kind: "context", name: name})
+	}
+	if len(targets) == 0 {
+		return "", nil, fmt.Errorf("namespaces or contexts are required")
+	}
+	if len(targets) > maxFanOutTargets {
+		return "", nil, fmt.Errorf("at most %d namespaces or contexts can be given, got %d", maxFanOutTargets, len(targets))
+	}
+	return task, targets, nil
+}
+
+// fanOutChild is the session that runs the sub-task in one target.
+type fanOutChild struct {
+	target       fanOutTarget
+	conversation *Agent
+	answer       *FinalAnswer
+	err          error
+}
+
+// fanOut runs the sub-task of a fan_out call in each target, FanOutParallelism at a time, and returns their answers.
+// The sessions run non-interactively: calls that need confirmation are not run.
+func (a *Agent) fanOut(ctx context.Context, call gollm.FunctionCall) gollm.FunctionCallResult {
+	result := gollm.FunctionCallResult{ID: call.ID, Name: call.Name}
+	task, targets, err := parseFanOut(call.Arguments)
+	if err != nil {
+		result.Result = map[string]any{"error": err.Error()}
+		return result
+	}
+
+	block := ui.NewFunctionCallRequestBlock().SetDescription(fmt.Sprintf("Running in %d %ss: %s", len(targets), targets[0].kind, task))
+	a.doc.AddBlock(block)
+	block.SetProgress(fmt.Sprintf("0 of %d done", len(targets)), 0)
+
+	children := make([]*fanOutChild, len(targets))
+	for i, target := range targets {
+		children[i] = &fanOutChild{target: target, conversation: a.newFanOutChild(len(targets))}
+	}
+
+	var mutex sync.Mutex
+	done := 0
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, max(a.FanOutParallelism, 1))
+	for _, child := range children {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			child.answer, child.err = child.run(ctx, task)
+
+			mutex.Lock()
+			defer mutex.Unlock()
+			done++
+			block.SetProgress(fmt.Sprintf("%d of %d done", done, len(targets)), float64(done)/float64(len(targets)))
+		}()
+	}
+	wg.Wait()
+
+	var results []map[string]any
+	var text strings.Builder
+	for _, child := range children {
+		// The sessions count against the budget and statistics of this one.
+		a.addSessionSpent(child.conversation.Spent())
+		a.stats.add(child.conversation.Stats())
+
+		entry := map[string]any{child.target.kind: child.target.name}
+		fmt.Fprintf(&text, "%s %s:\n", child.target.kind, child.target.name)
+		if child.answer != nil {
+			entry["answer"] = child.answer.Summary
+			fmt.Fprintf(&text, "%s\n\n", strings.TrimSpace(child.answer.Summary))
+			for _, command := range child.answer.CommandsRun {
+				a.roundCommands = append(a.roundCommands, fmt.Sprintf("[%s %s] %s", child.target.kind, child.target.name, command))
+			}
+		}
+		if child.err != nil {
+			klog.FromContext(ctx).Info("fan-out session failed", "target", child.target.name, "err", child.err)
+			entry["error"] = child.err.Error()
+			if child.answer == nil {
+				fmt.Fprintf(&text, "failed: %v\n\n", child.err)
+			}
+		}
+		results = append(results, entry)
+	}
+	block.SetProgress("", 1)
+	block.SetResult(text.String())
+
+	result.Result = map[string]any{"results": results}
+	return result
+}
+
+// newFanOutChild creates the session for a sub-task, with the configuration of this one.
+// The remaining budget, if any, is divided between the sessions.
+func (a *Agent) newFanOutChild(sessions int) *Agent {
+	child := &Agent{
+		LLM:                  a.LLM,
+		PromptProfile:        a.PromptProfile,
+		PromptTemplateFile:   a.PromptTemplateFile,
+		ExtraPromptPaths:     a.ExtraPromptPaths,
+		Model:                a.Model,
+		FallbackModel:        a.FallbackModel,
+		RetryConfig:          a.RetryConfig,
+		WorkDirRetention:     a.WorkDirRetention,
+		MaxIterations:        a.MaxIterations,
+		RoundTimeout:         a.RoundTimeout,
+		ToolTimeout:          a.ToolTimeout,
+		Kubeconfig:           a.Kubeconfig,
+		SkipPermissions:      a.SkipPermissions,
+		ConfirmationPolicy:   a.ConfirmationPolicy,
+		Approvals:            a.Approvals,
+		NonInteractive:       true,
+		Simulate:             a.Simulate,
+		AutoCleanup:          a.AutoCleanup,
+		Assertions:           a.Assertions,
+		SuggestAlternatives:  a.SuggestAlternatives,
+		Language:             a.Language,
+		AnswerStyle:          a.AnswerStyle,
+		Inventory:            a.Inventory,
+		MonitorWindow:        a.MonitorWindow,
+		HighlightAnomalies:   a.HighlightAnomalies,
+		Tools:                a.Tools,
+		MCPClientEnabled:     a.MCPClientEnabled,
+		Recorder:             a.Recorder,
+		ContextWindow:        a.ContextWindow,
+		StablePrompt:         a.StablePrompt,
+		SlimToolSchemas:      a.SlimToolSchemas,
+		VerifyMutations:      a.VerifyMutations,
+		StructuredAnswer:     true,
+		MaxRepeatedToolCalls: a.MaxRepeatedToolCalls,
+		MaxToolErrors:        a.MaxToolErrors,
+		SummarizeOutputBytes: a.SummarizeOutputBytes,
+		LoopAction:           a.LoopAction,
+		ToolQuotas:           a.ToolQuotas,
+		TokenPrice:           a.TokenPrice,
+		// The changes of the sub-tasks are attributed to this session.
+		AttributionAnnotations: a.AttributionAnnotations,
+		SessionID:              a.SessionID,
+		User:                   a.User,
+		// The policies and audit of the hooks apply to the calls of the sub-tasks too.
+		// The sessions run in parallel, so the hooks may be called concurrently.
+		hooks: slices.Clone(a.hooks),
+	}
+	if a.MaxTokens > 0 {
+		child.MaxTokens = max(a.MaxTokens-a.spent.TotalTokens, 0) / sessions
+	}
+	if a.MaxCost > 0 {
+		child.MaxCost = max(a.MaxCost-a.spentCost, 0) / float64(sessions)
+	}
+	return child
+}
+
+// run runs the sub-task in the child's target, and returns its answer.
+// The answer may not be nil even if there is an error, if the session stopped before completing the task.
+func (c *fanOutChild) run(ctx context.Context, task string) (*FinalAnswer, error) {
+	doc := ui.NewDocument()
+	if err := c.conversation.Init(ctx, doc); err != nil {
+		return nil, fmt.Errorf("starting session: %w", err)
+	}
+	defer c.conversation.Close()
+
+	query := fmt.Sprintf("This is one part of a task that runs across several %ss. %s\n\n%s", c.target.kind, c.target.scope(), task)
+	result, err := c.conversation.RunOneRound(ctx, query)
+	if result.Answer != nil {
+		return result.Answer, err
+	}
+	for i := len(result.Messages) - 1; i >= 0; i-- {
+		if message := strings.TrimSpace(result.Messages[i]); message != "" {
+			return &FinalAnswer{Summary: message}, err
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("the session ended without an answer")
+	}
+	return nil, err
+}
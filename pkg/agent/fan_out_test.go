@@ -0,0 +1,64 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestNewFanOutChildHooks(t *testing.T) {
+	var before, after []string
+	parent := &Agent{}
+	parent.AddHooks(Hooks{
+		BeforeToolCall: func(ctx context.Context, call ToolCallInfo) error {
+			before = append(before, call.Description)
+			if call.ModifiesResource == "yes" {
+				return errors.New("refused")
+			}
+			return nil
+		},
+		AfterToolCall: func(ctx context.Context, call ToolCallInfo, result any, err error) {
+			after = append(after, call.Description)
+		},
+	})
+	child := parent.newFanOutChild(2)
+
+	tests := []struct {
+		call    ToolCallInfo
+		wantErr bool
+	}{
+		{call: ToolCallInfo{Name: "kubectl", Description: "kubectl get pods -n a", ModifiesResource: "no"}},
+		{call: ToolCallInfo{Name: "kubectl", Description: "kubectl delete pod web -n a", ModifiesResource: "yes"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := child.beforeToolCall(context.Background(), tt.call)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("beforeToolCall(%q) error = %v, wantErr %v", tt.call.Description, err, tt.wantErr)
+		}
+		if err == nil {
+			child.afterToolCall(context.Background(), tt.call, "ok", nil)
+		}
+	}
+	if want := []string{"kubectl get pods -n a", "kubectl delete pod web -n a"}; !slices.Equal(before, want) {
+		t.Errorf("BeforeToolCall saw %q, want %q", before, want)
+	}
+	if want := []string{"kubectl get pods -n a"}; !slices.Equal(after, want) {
+		t.Errorf("AfterToolCall saw %q, want %q", after, want)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// Ratings of an answer.
+const (
+	FeedbackGood = "good"
+	FeedbackBad  = "bad"
+)
+
+// Feedback is the user's rating of the answer to a query. It is recorded in the journal,
+// so that the journals of many sessions can be used to improve prompts and model routing.
+type Feedback struct {
+	// Rating is FeedbackGood or FeedbackBad.
+	Rating  string `json:"rating"`
+	Comment string `json:"comment,omitempty"`
+	Query   string `json:"query"`
+	// Answer is the summary of the final answer, or the last text the model wrote.
+	Answer string    `json:"answer,omitempty"`
+	Model  string    `json:"model"`
+	Time   time.Time `json:"time"`
+}
+
+// ParseFeedbackRating parses a rating given by the user: good, bad, or a thumbs up or down.
+func ParseFeedbackRating(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case FeedbackGood, "up", "+", "+1", "👍":
+		return FeedbackGood, nil
+	case FeedbackBad, "down", "-", "-1", "👎":
+		return FeedbackBad, nil
+	}
+	return "", fmt.Errorf("invalid rating %q, use good (or up, 👍) or bad (or down, 👎)", s)
+}
+
+// RecordFeedback rates the answer to the last query. The feedback is written to the journal,
+// and shown in the document so that it is part of the session's transcript.
+func (a *Agent) RecordFeedback(ctx context.Context, rating, comment string) (*Feedback, error) {
+	if len(a.roundStarts) == 0 {
+		return nil, fmt.Errorf("there is no answer to rate yet")
+	}
+	feedback := &Feedback{
+		Rating:  rating,
+		Comment: comment,
+		Model:   a.Model,
+		Time:    time.Now(),
+	}
+	round := a.history[a.roundStarts[len(a.roundStarts)-1]:]
+	if len(round) > 0 {
+		feedback.Query = round[0].Query
+	}
+	if a.lastAnswer != nil {
+		feedback.Answer = a.lastAnswer.Summary
+	} else {
+		for _, entry := range round {
+			if entry.Role == journal.RoleModel && len(entry.Messages) > 0 {
+				feedback.Answer = strings.TrimSpace(strings.Join(entry.Messages, ""))
+			}
+		}
+	}
+
+	if err := a.Recorder.Write(ctx, &journal.Event{
+		Timestamp: feedback.Time,
+		Action:    journal.ActionFeedback,
+		Payload:   feedback,
+	}); err != nil {
+		return nil, fmt.Errorf("recording feedback: %w", err)
+	}
+	a.feedback = append(a.feedback, feedback)
+
+	text := "Thanks! Recorded 👍 for this answer."
+	if rating == FeedbackBad {
+		text = "Thanks! Recorded 👎 for this answer."
+	}
+	if comment != "" {
+		text += " Comment: " + comment
+	}
+	a.doc.AddBlock(ui.NewAgentTextBlock().WithText(text))
+	return feedback, nil
+}
+
+// AskFeedback asks the user to rate the answer to the last query, and records their rating.
+// The user can skip the question.
+func (a *Agent) AskFeedback(ctx context.Context) error {
+	optionsBlock := ui.NewInputOptionBlock().SetPrompt("  Was this answer helpful?")
+	optionsBlock.AddOption(FeedbackGood, "👍 Yes", "yes", "y", "up")
+	optionsBlock.AddOption(FeedbackBad, "👎 No", "no", "n", "down")
+	optionsBlock.AddOption("skip", "Skip", "skip", "s")
+	a.doc.AddBlock(optionsBlock)
+
+	choice, err := optionsBlock.Selection().Wait()
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return fmt.Errorf("reading feedback: %w", err)
+	}
+	if choice == "skip" {
+		return nil
+	}
+	_, err = a.RecordFeedback(ctx, choice, "")
+	return err
+}
+
+// Feedback returns the feedback given in the session.
+func (a *Agent) Feedback() []*Feedback {
+	return a.feedback
+}
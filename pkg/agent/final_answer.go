@@ -0,0 +1,130 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// finalAnswerFunctionName is the name of the function the LLM calls to give its final answer,
+// when structured answers are enabled.
+const finalAnswerFunctionName = "final_answer"
+
+// FinalAnswer is the structured final answer to a query.
+type FinalAnswer struct {
+	// Summary is the answer to the query, in markdown.
+	Summary string `json:"summary"`
+	// CommandsRun are the commands that were run while answering the query.
+	CommandsRun []string `json:"commands_run"`
+	// ResourcesTouched are the resources that were created, modified or deleted, e.g. "deployment/nginx in namespace default".
+	ResourcesTouched []string `json:"resources_touched"`
+	// FollowUps are suggested next steps for the user.
+	FollowUps []string `json:"follow_ups"`
+	// SuggestedQueries are queries the user is likely to ask next, when follow-ups are suggested.
+	SuggestedQueries []string `json:"suggested_queries,omitempty"`
+
+	// Language is the language the answer is written in (see Agent.Language); the headings are rendered in it.
+	Language string `json:"-"`
+}
+
+// finalAnswerFunctionDefinition describes the final_answer function to the LLM.
+// The commands run are tracked by the agent, so the LLM does not provide them.
+// With suggestQueries, the LLM also gives the queries the user is likely to ask next.
+func finalAnswerFunctionDefinition(suggestQueries bool) *gollm.FunctionDefinition {
+	definition := &gollm.FunctionDefinition{
+		Name: finalAnswerFunctionName,
+		Description: `Gives the final answer to the user's query. Call this exactly once, when the task is complete
+or you need more information from the user, instead of replying with plain text.`,
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"summary": {
+					Type:        gollm.TypeString,
+					Description: `The answer to the user's query, in markdown.`,
+				},
+				"resources_touched": {
+					Type:        gollm.TypeArray,
+					Items:       &gollm.Schema{Type: gollm.TypeString},
+					Description: `The kubernetes resources that were created, modified or deleted, e.g. "deployment/nginx in namespace default". Empty if nothing was changed.`,
+				},
+				"follow_ups": {
+					Type:        gollm.TypeArray,
+					Items:       &gollm.Schema{Type: gollm.TypeString},
+					Description: `Suggested next steps for the user, if any.`,
+				},
+			},
+			Required: []string{"summary"},
+		},
+	}
+	if suggestQueries {
+		definition.Parameters.Properties["suggested_queries"] = &gollm.Schema{
+			Type:        gollm.TypeArray,
+			Items:       &gollm.Schema{Type: gollm.TypeString},
+			Description: `2 or 3 short questions or requests the user is likely to send next, written as the user would, e.g. to dig into a cause you found or fix a problem.`,
+		}
+	}
+	return definition
+}
+
+// parseFinalAnswer parses the arguments of a final_answer function call.
+func parseFinalAnswer(arguments map[string]any) (*FinalAnswer, error) {
+	j, err := json.Marshal(arguments)
+	if err != nil {
+		return nil, fmt.Errorf("converting arguments to json: %w", err)
+	}
+	answer := &FinalAnswer{}
+	if err := json.Unmarshal(j, answer); err != nil {
+		return nil, fmt.Errorf("parsing final answer: %w", err)
+	}
+	if strings.TrimSpace(answer.Summary) == "" {
+		return nil, fmt.Errorf("summary is required")
+	}
+	return answer, nil
+}
+
+// Markdown renders the answer for display.
+func (f *FinalAnswer) Markdown() string {
+	var sb strings.Builder
+	sb.WriteString(strings.TrimSpace(f.Summary))
+	sb.WriteString("\n")
+
+	writeList := func(title string, items []string, code bool) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "\n**%s**\n\n", title)
+		for _, item := range items {
+			if code {
+				fmt.Fprintf(&sb, "* `%s`\n", item)
+			} else {
+				fmt.Fprintf(&sb, "* %s\n", item)
+			}
+		}
+	}
+	headings := localizedAnswerHeadings(f.Language)
+	writeList(headings[0], f.CommandsRun, true)
+	writeList(headings[1], f.ResourcesTouched, false)
+	writeList(headings[2], f.FollowUps, false)
+	return sb.String()
+}
+
+// LastAnswer returns the final answer to the last query, or nil if the last query was not answered.
+func (a *Agent) LastAnswer() *FinalAnswer {
+	return a.lastAnswer
+}
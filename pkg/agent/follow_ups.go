@@ -0,0 +1,104 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// maxFollowUps is how many follow-up queries are suggested at most.
+const maxFollowUps = 3
+
+// suggestFollowUps shows the queries the user is likely to ask after the answer to query, numbered so that
+// entering a number asks one (see FollowUp). They are taken from the structured answer if the LLM gave them,
+// and asked of the LLM otherwise.
+func (a *Agent) suggestFollowUps(ctx context.Context, query string) {
+	a.followUps = nil
+	if a.lastAnswer == nil || strings.TrimSpace(a.lastAnswer.Summary) == "" {
+		return
+	}
+
+	followUps := a.lastAnswer.SuggestedQueries
+	if len(followUps) == 0 {
+		prompt := fmt.Sprintf(`You are helping to operate a kubernetes cluster.
+The user asked: %q
+
+You answered:
+%s
+
+Suggest 2 or 3 short questions or requests the user is likely to send you next, e.g. to dig into a cause you found,
+check the effect of a change, or fix a problem. Write them as the user would.
+Respond with one per line, without numbering, explanation or markdown formatting.`, query, a.lastAnswer.Summary)
+		response, err := a.LLM.GenerateCompletion(ctx, &gollm.CompletionRequest{
+			Model:  a.Model,
+			Prompt: prompt,
+		})
+		if err != nil {
+			klog.FromContext(ctx).Error(err, "asking for follow-up queries")
+			return
+		}
+		followUps = strings.Split(response.Response(), "\n")
+	}
+	a.followUps = cleanFollowUps(followUps)
+	if len(a.followUps) == 0 {
+		return
+	}
+
+	var sb strings.Builder
+	sb.WriteString("**Suggested follow-ups** (enter a number to ask one)\n\n")
+	for i, followUp := range a.followUps {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, followUp)
+	}
+	a.doc.AddBlock(ui.NewAgentTextBlock().WithText(sb.String()))
+}
+
+// followUpPrefix matches the numbering, bullets and quotes the LLM may put around a follow-up query.
+var followUpPrefix = regexp.MustCompile(`^(?:\d+[.)]|[-*•])\s*`)
+
+// cleanFollowUps strips numbering, bullets and quotes from the follow-up queries, and drops empty and
+// duplicate ones; it returns at most maxFollowUps of them.
+func cleanFollowUps(followUps []string) []string {
+	var cleaned []string
+	for _, followUp := range followUps {
+		followUp = followUpPrefix.ReplaceAllString(strings.TrimSpace(followUp), "")
+		followUp = strings.TrimSpace(strings.Trim(followUp, "\"'`"))
+		if followUp == "" || slices.Contains(cleaned, followUp) {
+			continue
+		}
+		cleaned = append(cleaned, followUp)
+		if len(cleaned) == maxFollowUps {
+			break
+		}
+	}
+	return cleaned
+}
+
+// FollowUp returns the suggested follow-up query that input selects, if input is the number of one.
+func (a *Agent) FollowUp(input string) (string, bool) {
+	n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(input), "."))
+	if err != nil || n < 1 || n > len(a.followUps) {
+		return "", false
+	}
+	return a.followUps[n-1], true
+}
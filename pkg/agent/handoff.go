@@ -0,0 +1,132 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+)
+
+// Handoff writes an escalation document for the next responder: a summary of the session written by the LLM,
+// followed by the evidence it is based on, i.e. the commands that ran and the files in the working directory.
+// note, if not empty, is what the user wants to tell the next responder. Unlike Compact, the conversation is unchanged.
+func (a *Agent) Handoff(ctx context.Context, note string) (string, error) {
+	if len(a.history) == 0 && a.resumedHistory == "" {
+		return "", fmt.Errorf("there is nothing to hand off yet")
+	}
+	transcript, err := historyTranscript(a.history)
+	if err != nil {
+		return "", err
+	}
+	if a.resumedHistory != "" {
+		transcript = a.resumedHistory + "\n" + transcript
+	}
+
+	prompt := `You are helping an assistant that operates a kubernetes cluster for a user. The user is escalating the issue they are working on to another responder, who was not part of the conversation.
+Write a handoff document from the transcript below, in markdown, with these sections:
+## Summary: the problem, its impact and its current status, in a few sentences.
+## Findings: what was found, with the resources involved (kind, namespace and name), their state and the errors seen. Quote the relevant output.
+## Changes made: the changes made to the cluster and the commands that made them, or "None".
+## Pending plan: the next steps that were planned or proposed but not done yet, including commands that were declined or not run.
+## Open questions: what is still unknown, and what the next responder should check first.
+Be factual and concise: only state what the transcript supports, and say when something is a hypothesis.`
+	if note != "" {
+		prompt += fmt.Sprintf("\nThe user added this note for the next responder, include it in the summary: %s", note)
+	}
+	prompt += "\n\nTranscript:\n" + transcript
+
+	response, err := a.LLM.GenerateCompletion(ctx, &gollm.CompletionRequest{
+		Model:  a.Model,
+		Prompt: prompt,
+	})
+	if err != nil {
+		return "", fmt.Errorf("asking the LLM to write the handoff: %w", err)
+	}
+	if usage, ok := gollm.UsageFromMetadata(response.UsageMetadata()); ok {
+		a.addSpent(usage)
+	}
+	body := strings.TrimSpace(response.Response())
+	if body == "" {
+		return "", fmt.Errorf("the LLM returned an empty handoff")
+	}
+
+	var doc strings.Builder
+	fmt.Fprintf(&doc, "# Handoff: session %s\n\n", a.SessionID)
+	fmt.Fprintf(&doc, "* Started: %s\n", a.stats.Started.Format(time.RFC3339))
+	fmt.Fprintf(&doc, "* Handed off: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&doc, "* Model: `%s`\n", a.Model)
+	if a.User != "" {
+		fmt.Fprintf(&doc, "* Handed off by: %s\n", a.User)
+	}
+	fmt.Fprintf(&doc, "\n%s\n", body)
+
+	if commands := commandsRun(a.history); len(commands) > 0 {
+		doc.WriteString("\n## Commands run\n\n")
+		for _, command := range commands {
+			fmt.Fprintf(&doc, "* `%s`\n", command)
+		}
+	}
+	if artifacts := a.artifacts(); len(artifacts) > 0 {
+		fmt.Fprintf(&doc, "\n## Artifacts\n\nGenerated manifests and tool output, kept in `%s` on the machine the session ran on:\n\n", a.workDir)
+		for _, artifact := range artifacts {
+			fmt.Fprintf(&doc, "* `%s`\n", artifact)
+		}
+	}
+	return doc.String(), nil
+}
+
+// commandsRun returns the commands the LLM called tools with, in order.
+func commandsRun(history []*journal.HistoryEntry) []string {
+	var commands []string
+	for _, entry := range history {
+		if entry.Role != journal.RoleModel {
+			continue
+		}
+		for _, call := range entry.FunctionCalls {
+			if command, ok := call.Arguments["command"].(string); ok && command != "" {
+				commands = append(commands, command)
+			}
+		}
+	}
+	return commands
+}
+
+// artifacts returns the files the session wrote to its working directory, relative to it,
+// leaving out the files the agent keeps for itself.
+func (a *Agent) artifacts() []string {
+	if a.workDir == "" {
+		return nil
+	}
+	var artifacts []string
+	filepath.WalkDir(a.workDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(a.workDir, p)
+		if err != nil || rel == sessionInfoFile || rel == checkpointFile {
+			return nil
+		}
+		artifacts = append(artifacts, rel)
+		return nil
+	})
+	return artifacts
+}
@@ -0,0 +1,111 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// Hooks are callbacks that embedders and plugins register on a conversation with AddHooks, to apply custom
+// policies, collect metrics or send notifications without changing the agent loop. Any of them can be nil.
+// They are called synchronously from RunOneRound, so they should return quickly.
+type Hooks struct {
+	// BeforeToolCall is called before a tool call runs, once it has been confirmed. If it returns an error,
+	// the call is not run, and the error is reported to the LLM as the result of the call.
+	BeforeToolCall func(ctx context.Context, call ToolCallInfo) error
+
+	// AfterToolCall is called after a tool call ran, with its result, or the error if it failed.
+	AfterToolCall func(ctx context.Context, call ToolCallInfo, result any, err error)
+
+	// OnLLMResponse is called when the LLM has finished responding to a request.
+	OnLLMResponse func(ctx context.Context, response LLMResponse)
+}
+
+// ToolCallInfo describes a tool call for hooks.
+type ToolCallInfo struct {
+	// Name is the name of the tool, and Arguments the arguments the LLM called it with.
+	Name      string
+	Arguments map[string]any
+	// Description is how the call is shown to the user, e.g. the kubectl command.
+	Description string
+	// ModifiesResource is "yes", "no" or "unknown".
+	ModifiesResource string
+	// Simulated is set if the call runs as a dry run.
+	Simulated bool
+}
+
+// LLMResponse describes a response of the LLM for hooks.
+type LLMResponse struct {
+	Model         string
+	Text          string
+	FunctionCalls []gollm.FunctionCall
+	Usage         gollm.Usage
+}
+
+// AddHooks registers hooks on the conversation. Hooks are called in the order they were added.
+func (a *Agent) AddHooks(hooks Hooks) {
+	a.hooks = append(a.hooks, hooks)
+}
+
+// beforeToolCall calls the BeforeToolCall hooks, and returns the error of the first one that refuses the call.
+func (a *Agent) beforeToolCall(ctx context.Context, call ToolCallInfo) error {
+	for _, hooks := range a.hooks {
+		if hooks.BeforeToolCall == nil {
+			continue
+		}
+		if err := hooks.BeforeToolCall(ctx, call); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *Agent) afterToolCall(ctx context.Context, call ToolCallInfo, result any, err error) {
+	for _, hooks := range a.hooks {
+		if hooks.AfterToolCall != nil {
+			hooks.AfterToolCall(ctx, call, result, err)
+		}
+	}
+}
+
+func (a *Agent) onLLMResponse(ctx context.Context, response LLMResponse) {
+	for _, hooks := range a.hooks {
+		if hooks.OnLLMResponse != nil {
+			hooks.OnLLMResponse(ctx, response)
+		}
+	}
+}
+
+// refuseCall tells the user that a hook refused the call, and returns the result that tells the LLM.
+func (a *Agent) refuseCall(call gollm.FunctionCall, description string, err error) any {
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("  Not running %s: %v\n", description, err)))
+	message := fmt.Sprintf("%s was not run: %v", description, err)
+	if a.EnableToolUseShim {
+		return fmt.Sprintf("Result of running %q:\n%s", call.Name, message)
+	}
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"error":     message,
+			"status":    "refused",
+			"retryable": false,
+		},
+	}
+}
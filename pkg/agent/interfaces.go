@@ -15,15 +15,21 @@
 package agent
 
 import (
-	"context"
-	"io"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
 )
 
-type Agent interface {
-	// Close should be called to free up resources
-	io.Closer
+// UI is where the agent shows what it does, and asks the user for input. The agent adds blocks for its messages
+// and tool calls; blocks that need input, such as confirmations, are answered by setting their result.
+// *ui.Document implements it, and the terminal and HTML UIs render a Document.
+type UI interface {
+	// AddBlock adds a block to the UI.
+	AddBlock(block ui.Block)
 
-	// RunOneRound will send the query to the LLM, and go through cycles with the LLM,
-	// evaluating requested functions until we reach a stopping point.
-	RunOneRound(ctx context.Context, query string) error
+	// TakeInterjections returns the guidance the user typed while the agent was working, and forgets it.
+	TakeInterjections() []string
+
+	// TimeFormat is how times and durations are shown.
+	TimeFormat() ui.TimeFormat
 }
+
+var _ UI = &ui.Document{}
@@ -0,0 +1,57 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// resolveReferences returns a message with the names of the resources that the query refers to, such as
+// "the payments deployment", from the inventory of the namespace, or an empty string if there are none.
+// This saves the LLM from listing resources to find their exact names.
+func (a *Agent) resolveReferences(ctx context.Context, query string) string {
+	if a.Inventory == nil {
+		return ""
+	}
+	defaultNamespace := ""
+	if a.clusterInfo != nil {
+		defaultNamespace = a.clusterInfo.Namespace
+	}
+	namespace, references, err := a.Inventory.ResolveReferences(a.toolContext(ctx), query, defaultNamespace)
+	if err != nil {
+		klog.FromContext(ctx).Info("Could not resolve the references to resources in the query", "err", err)
+		return ""
+	}
+	if len(references) == 0 {
+		return ""
+	}
+	klog.FromContext(ctx).Info("Resolved references to resources", "count", len(references))
+	if namespace == "" {
+		namespace = "the current namespace"
+	} else {
+		namespace = "namespace " + namespace
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Resources in %s that the query may refer to, from a recent listing of their names:\n", namespace)
+	for _, reference := range references {
+		fmt.Fprintf(&sb, "- %q: %s %s\n", reference.Text, reference.Kind, strings.Join(reference.Matches, ", "))
+	}
+	sb.WriteString("Use these exact names rather than listing resources to find them; if it is not clear which one the user means, ask them.\n")
+	return sb.String()
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// progressWindow is how many of the last iterations must have run a new command for the agent to be making progress.
+const progressWindow = 3
+
+// iterationProgress tracks whether the agent is making progress in a round: running commands it has not run before,
+// rather than repeating itself or failing.
+type iterationProgress struct {
+	seen map[string]bool
+	// lastProgress is the last iteration that ran a new command, or -1.
+	lastProgress int
+	// autoExtended counts the iterations added without asking the user, in non-interactive sessions.
+	autoExtended int
+}
+
+func newIterationProgress() *iterationProgress {
+	return &iterationProgress{seen: make(map[string]bool), lastProgress: -1}
+}
+
+// record notes the commands run so far in the round, at the end of an iteration.
+func (p *iterationProgress) record(iteration int, commands []string) {
+	for _, command := range commands {
+		if !p.seen[command] {
+			p.seen[command] = true
+			p.lastProgress = iteration
+		}
+	}
+}
+
+// progressing returns true if one of the last iterations before iteration ran a new command.
+func (p *iterationProgress) progressing(iteration int) bool {
+	return p.lastProgress >= 0 && iteration-p.lastProgress <= progressWindow
+}
+
+// extendIterations is called when the round reaches its limit of iterations. If the agent is making progress,
+// it asks the user whether to continue for IterationExtension more iterations, or in non-interactive sessions
+// continues on its own up to AutoExtendIterations. It returns how many iterations to add, zero to stop.
+func (a *Agent) extendIterations(limit int, progress *iterationProgress) (int, error) {
+	if a.IterationExtension <= 0 || !progress.progressing(limit) {
+		return 0, nil
+	}
+	extension := a.IterationExtension
+
+	if a.NonInteractive {
+		if progress.autoExtended+extension > a.AutoExtendIterations {
+			extension = a.AutoExtendIterations - progress.autoExtended
+		}
+		if extension <= 0 {
+			return 0, nil
+		}
+		progress.autoExtended += extension
+		a.doc.AddBlock(ui.NewAgentTextBlock().WithText(fmt.Sprintf("Reached %d iterations, but the agent is still making progress; continuing for %d more.", limit, extension)))
+		return extension, nil
+	}
+
+	prompt := fmt.Sprintf("  Reached the limit of %d iterations, but the agent is still making progress (it ran new commands in the last %d iterations). Do you want to continue for %d more iterations?", limit, progressWindow, extension)
+	optionsBlock := ui.NewInputOptionBlock().SetPrompt(prompt)
+	optionsBlock.AddOption("yes", fmt.Sprintf("Yes, continue for %d more iterations", extension), "yes", "y")
+	optionsBlock.AddOption("no", "No, stop here", "no", "n")
+	a.doc.AddBlock(optionsBlock)
+
+	choice, err := optionsBlock.Selection().Wait()
+	if errors.Is(err, io.EOF) {
+		// No one is there to answer, e.g. in quiet mode with stdin closed.
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if choice != "yes" {
+		return 0, nil
+	}
+	return extension, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import "strings"
+
+// languageCodes maps the names of the languages with translated answer headings to their ISO 639-1 code.
+var languageCodes = map[string]string{
+	"english":    "en",
+	"spanish":    "es",
+	"español":    "es",
+	"french":     "fr",
+	"français":   "fr",
+	"german":     "de",
+	"deutsch":    "de",
+	"italian":    "it",
+	"italiano":   "it",
+	"portuguese": "pt",
+	"português":  "pt",
+	"japanese":   "ja",
+	"日本語":        "ja",
+	"korean":     "ko",
+	"한국어":        "ko",
+	"chinese":    "zh",
+	"中文":         "zh",
+	"hindi":      "hi",
+	"हिन्दी":     "hi",
+	"vietnamese": "vi",
+	"tiếng việt": "vi",
+}
+
+// answerHeadings are the headings of the lists of a structured final answer, by language code:
+// commands run, resources touched and follow-ups.
+var answerHeadings = map[string][3]string{
+	"en": {"Commands run", "Resources touched", "Follow-ups"},
+	"es": {"Comandos ejecutados", "Recursos modificados", "Próximos pasos"},
+	"fr": {"Commandes exécutées", "Ressources modifiées", "Prochaines étapes"},
+	"de": {"Ausgeführte Befehle", "Geänderte Ressourcen", "Nächste Schritte"},
+	"it": {"Comandi eseguiti", "Risorse modificate", "Prossimi passi"},
+	"pt": {"Comandos executados", "Recursos alterados", "Próximos passos"},
+	"ja": {"実行したコマンド", "変更したリソース", "次のステップ"},
+	"ko": {"실행한 명령", "변경한 리소스", "다음 단계"},
+	"zh": {"已执行的命令", "已变更的资源", "后续步骤"},
+	"hi": {"चलाए गए कमांड", "बदले गए संसाधन", "अगले कदम"},
+	"vi": {"Lệnh đã chạy", "Tài nguyên đã thay đổi", "Bước tiếp theo"},
+}
+
+// languageCode returns the ISO 639-1 code of language, which is a code (e.g. "fr" or "pt-BR") or a name
+// (e.g. "French" or "français"), or "" if it is not known.
+func languageCode(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if code, ok := languageCodes[language]; ok {
+		return code
+	}
+	code, _, _ := strings.Cut(strings.ReplaceAll(language, "_", "-"), "-")
+	if _, ok := answerHeadings[code]; ok {
+		return code
+	}
+	return ""
+}
+
+// localizedAnswerHeadings returns the headings of the lists of a final answer in language,
+// or in English if there is no translation for it.
+func localizedAnswerHeadings(language string) [3]string {
+	if headings, ok := answerHeadings[languageCode(language)]; ok {
+		return headings
+	}
+	return answerHeadings["en"]
+}
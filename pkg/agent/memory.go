@@ -0,0 +1,95 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// rememberFunctionName is the name of the function the LLM calls to remember a fact for later sessions.
+const rememberFunctionName = "remember"
+
+// maxRecalledMemories is the number of memories recalled for a query, at most.
+const maxRecalledMemories = 5
+
+// rememberFunctionDefinition describes the remember function to the LLM.
+func rememberFunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name: rememberFunctionName,
+		Description: `Remembers a durable fact that you learned, so that it is recalled in later sessions when it is relevant:
+e.g. a quirk of a cluster, a naming convention, or the cause and fix of an incident. Only remember facts that will still
+be true and useful later, not the current state of resources. Never remember secrets, tokens or credentials.`,
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"fact": {
+					Type:        gollm.TypeString,
+					Description: `The fact, in one or two self-contained sentences that name the cluster, namespace or resources it is about, e.g. "In the prod-eu cluster, the ingress controller runs in the ingress-system namespace."`,
+				},
+			},
+			Required: []string{"fact"},
+		},
+	}
+}
+
+// embedder returns the LLM client as an embedder, or nil if it cannot compute embeddings.
+func (a *Agent) embedder() gollm.Embedder {
+	embedder, _ := a.LLM.(gollm.Embedder)
+	return embedder
+}
+
+// remember saves the fact of a remember call in the memories.
+func (a *Agent) remember(ctx context.Context, call gollm.FunctionCall) gollm.FunctionCallResult {
+	result := gollm.FunctionCallResult{ID: call.ID, Name: call.Name}
+	fact, _ := call.Arguments["fact"].(string)
+	if err := a.Memories.Add(ctx, a.embedder(), a.EmbeddingModel, fact); err != nil {
+		result.Result = map[string]any{"error": err.Error()}
+		return result
+	}
+	block := ui.NewAgentTextBlock().WithText(fmt.Sprintf("Remembered for later sessions: %s", strings.TrimSpace(fact)))
+	block.SetColor(ui.ColorWhite)
+	a.doc.AddBlock(block)
+	result.Result = map[string]any{"status": "remembered"}
+	return result
+}
+
+// recallMemories returns a message with the memories relevant to the query that were not recalled earlier
+// in the session, or an empty string if there are none.
+func (a *Agent) recallMemories(ctx context.Context, query string) string {
+	if a.Memories == nil {
+		return ""
+	}
+	memories := a.Memories.Recall(ctx, a.embedder(), a.EmbeddingModel, query, maxRecalledMemories, a.recalledMemories)
+	if len(memories) == 0 {
+		return ""
+	}
+	klog.FromContext(ctx).Info("Recalled memories", "count", len(memories))
+	var sb strings.Builder
+	sb.WriteString("Facts remembered from earlier sessions that may be relevant. They may be out of date, so check them against the cluster before relying on them:\n")
+	for _, memory := range memories {
+		if a.recalledMemories == nil {
+			a.recalledMemories = make(map[string]bool)
+		}
+		a.recalledMemories[memory.Text] = true
+		fmt.Fprintf(&sb, "- %s (remembered %s)\n", memory.Text, memory.Created.Format("2006-01-02"))
+	}
+	return sb.String()
+}
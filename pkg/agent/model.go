@@ -0,0 +1,144 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"math/rand"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"k8s.io/klog/v2"
+)
+
+// SwitchModel continues the conversation with another model, e.g. to escalate to a stronger model when stuck.
+// It starts a new chat with the model, and sends it a transcript of the history with the next query.
+func (a *Agent) SwitchModel(ctx context.Context, model string) error {
+	if model == "" {
+		return fmt.Errorf("model name must not be empty")
+	}
+	if model == a.Model {
+		return fmt.Errorf("already using model %q", model)
+	}
+
+	previous := a.Model
+	a.Model = model
+	if err := a.restoreHistory(ctx, a.history, a.roundStarts); err != nil {
+		a.Model = previous
+		// The chat with the previous model may have been replaced already.
+		if restoreErr := a.restoreHistory(ctx, a.history, a.roundStarts); restoreErr != nil {
+			klog.Warningf("Failed to restart chat with model %q: %v", previous, restoreErr)
+		}
+		return fmt.Errorf("switching to model %q: %w", model, err)
+	}
+	return nil
+}
+
+// sendStreaming sends contents to the LLM.
+// If the request fails before the LLM responds, and a FallbackModel is configured, we continue with the fallback model.
+func (a *Agent) sendStreaming(ctx context.Context, contents ...any) (gollm.ChatResponseIterator, error) {
+	stream, err := a.sendStreamingWithRetries(ctx, contents...)
+	if err != nil {
+		fallbackContents := a.failOver(ctx, err)
+		if fallbackContents == nil {
+			return nil, err
+		}
+		return a.sendStreamingWithRetries(ctx, fallbackContents...)
+	}
+	return stream, nil
+}
+
+// sendStreamingWithRetries sends contents to the LLM, and waits for the first chunk of the response.
+// Requests that fail before then are retried with the backoff of the RetryConfig; errors after the first chunk
+// are returned in the stream, as retrying would repeat the chunks already returned.
+func (a *Agent) sendStreamingWithRetries(ctx context.Context, contents ...any) (gollm.ChatResponseIterator, error) {
+	config := a.retryConfig()
+	backoff := config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		stream, err := a.llmChat.SendStreaming(ctx, contents...)
+		if err == nil {
+			next, stop := iter.Pull2(iter.Seq2[gollm.ChatResponse, error](stream))
+			response, responseErr, ok := next()
+			if responseErr == nil {
+				return func(yield func(gollm.ChatResponse, error) bool) {
+					defer stop()
+					for ; ok; response, responseErr, ok = next() {
+						if !yield(response, responseErr) {
+							return
+						}
+					}
+				}, nil
+			}
+			stop()
+			err = responseErr
+		}
+
+		if attempt >= config.MaxAttempts || ctx.Err() != nil || !a.llmChat.IsRetryableError(err) {
+			return nil, err
+		}
+		waitTime := backoff
+		if config.Jitter {
+			waitTime += time.Duration(rand.Float64() * float64(backoff) / 2)
+		}
+		klog.FromContext(ctx).Info("LLM request failed, retrying", "model", a.Model, "attempt", attempt, "maxAttempts", config.MaxAttempts, "waitTime", waitTime, "error", err)
+		select {
+		case <-time.After(waitTime):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		backoff = time.Duration(float64(backoff) * config.BackoffFactor)
+		if config.MaxBackoff > 0 && backoff > config.MaxBackoff {
+			backoff = config.MaxBackoff
+		}
+	}
+}
+
+// retryConfig returns the RetryConfig, with the defaults filled in.
+func (a *Agent) retryConfig() gollm.RetryConfig {
+	config := a.RetryConfig
+	if config.MaxAttempts == 0 {
+		config = defaultRetryConfig
+	}
+	if config.BackoffFactor == 0 {
+		config.BackoffFactor = defaultRetryConfig.BackoffFactor
+	}
+	return config
+}
+
+// failOver switches to the FallbackModel after a request to the LLM failed, if we are not using it already.
+// The new chat cannot continue the calls the failed model made, so it is sent a transcript of the history instead,
+// which includes the contents of the failed request. failOver returns the contents to send, or nil if we did not fail over.
+func (a *Agent) failOver(ctx context.Context, err error) []any {
+	if a.FallbackModel == "" || a.FallbackModel == a.Model || ctx.Err() != nil {
+		return nil
+	}
+	klog.FromContext(ctx).Info("LLM request failed, failing over to the fallback model", "model", a.Model, "fallbackModel", a.FallbackModel, "error", err)
+	if switchErr := a.SwitchModel(ctx, a.FallbackModel); switchErr != nil {
+		klog.Warningf("Failed to fail over to model %q: %v", a.FallbackModel, switchErr)
+		return nil
+	}
+	var contents []any
+	if a.clusterFacts != "" {
+		contents = append(contents, a.clusterFacts)
+		a.clusterFacts = ""
+	}
+	if a.resumedHistory != "" {
+		contents = append(contents, a.resumedHistory)
+		a.resumedHistory = ""
+	}
+	return contents
+}
@@ -0,0 +1,113 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+)
+
+var errTransient = errors.New("transient error")
+
+// flakyChat fails the first failures requests, when they are sent or in the stream, then answers "ok".
+type flakyChat struct {
+	fakeChat
+	failures     int
+	failInStream bool
+	retryable    bool
+	requests     int
+}
+
+func (c *flakyChat) SendStreaming(ctx context.Context, contents ...any) (gollm.ChatResponseIterator, error) {
+	c.requests++
+	failed := c.requests <= c.failures
+	if failed && !c.failInStream {
+		return nil, errTransient
+	}
+	return func(yield func(gollm.ChatResponse, error) bool) {
+		if failed {
+			yield(nil, errTransient)
+			return
+		}
+		yield(&ShimResponse{candidate: &ReActResponse{Answer: "ok"}}, nil)
+	}, nil
+}
+
+func (c *flakyChat) IsRetryableError(err error) bool { return c.retryable }
+
+// fallbackLLM starts chats that answer at once, for the fallback model.
+type fallbackLLM struct {
+	fakeLLM
+}
+
+func (c *fallbackLLM) StartChat(systemPrompt, model string) gollm.Chat { return &flakyChat{} }
+
+func TestSendStreamingRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		failInStream bool
+		retryable    bool
+		wantRequests int
+		wantModel    string
+	}{
+		{name: "no failures", wantRequests: 1, wantModel: "primary"},
+		{name: "failed requests", failures: 2, retryable: true, wantRequests: 3, wantModel: "primary"},
+		{name: "failed streams", failures: 2, failInStream: true, retryable: true, wantRequests: 3, wantModel: "primary"},
+		{name: "attempts exhausted", failures: 3, retryable: true, wantRequests: 3, wantModel: "fallback"},
+		{name: "not retryable", failures: 1, failInStream: true, wantRequests: 1, wantModel: "fallback"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chat := &flakyChat{failures: tt.failures, failInStream: tt.failInStream, retryable: tt.retryable}
+			a := &Agent{
+				LLM:           &fallbackLLM{},
+				Recorder:      &journal.LogRecorder{},
+				Model:         "primary",
+				FallbackModel: "fallback",
+				RetryConfig:   gollm.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+				llmChat:       chat,
+				// The history has the query, which is sent to the fallback model in a transcript.
+				history: []*journal.HistoryEntry{userHistoryEntry([]any{"query"}, "query")},
+			}
+
+			stream, err := a.sendStreaming(context.Background(), "query")
+			if err != nil {
+				t.Fatalf("sendStreaming() error = %v", err)
+			}
+			var answers []string
+			for response, err := range stream {
+				if err != nil {
+					t.Fatalf("stream error = %v", err)
+				}
+				answers = append(answers, response.Candidates()[0].String())
+			}
+			if len(answers) != 1 {
+				t.Errorf("got responses %q, expected one", answers)
+			}
+			if chat.requests != tt.wantRequests {
+				t.Errorf("sent %d requests to the primary model, expected %d", chat.requests, tt.wantRequests)
+			}
+			if a.Model != tt.wantModel {
+				t.Errorf("model = %q, expected %q", a.Model, tt.wantModel)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// toolContext returns the context in which the agent runs kubectl itself, with the kubeconfig and working directory
+// that tool calls get.
+func (a *Agent) toolContext(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, tools.KubeconfigKey, a.Kubeconfig)
+	return context.WithValue(ctx, tools.WorkDirKey, a.workDir)
+}
+
+// startChangeMonitor records the state of the pods in the namespaces that a call is about to change, so that the
+// change can be monitored once it is made. It returns nil if changes are not monitored, or the call is not a
+// kubectl or bash command that changes objects.
+func (a *Agent) startChangeMonitor(ctx context.Context, call gollm.FunctionCall) *tools.ChangeMonitor {
+	if a.MonitorWindow <= 0 {
+		return nil
+	}
+	command, ok := call.Arguments["command"].(string)
+	if !ok {
+		return nil
+	}
+	return tools.NewChangeMonitor(a.toolContext(ctx), command)
+}
+
+// monitorChange watches the namespaces a change was made in for MonitorWindow, for warning events, restarts and
+// pods that cannot start. It returns a note for the LLM with what it saw, which asks it to propose a rollback
+// if the change appears to have caused a regression.
+func (a *Agent) monitorChange(ctx context.Context, monitor *tools.ChangeMonitor, output any) string {
+	if execResult, ok := output.(*tools.ExecResult); ok && execResult != nil && (execResult.Error != "" || execResult.ExitCode != 0) {
+		// The change failed, there is nothing to monitor.
+		return ""
+	}
+
+	block := ui.NewFunctionCallRequestBlock().SetDescription(fmt.Sprintf("Monitoring the change for %v", a.MonitorWindow))
+	a.doc.AddBlock(block)
+	monitorCtx := tools.WithProgress(a.toolContext(ctx), func(progress tools.Progress) {
+		block.SetProgress(progress.Message, progress.Fraction())
+	})
+	report := monitor.Watch(monitorCtx, a.MonitorWindow)
+	block.SetResult(report.String())
+	klog.FromContext(ctx).Info("monitored change", "healthy", report.Healthy(), "watched", report.Watched)
+
+	if report.Healthy() {
+		textBlock := ui.NewAgentTextBlock().WithText(fmt.Sprintf("The change looks healthy after %v.", report.Watched.Round(time.Second)))
+		textBlock.SetColor(ui.ColorWhite)
+		a.doc.AddBlock(textBlock)
+		return "The namespaces the change was made in were monitored after it. " + report.String()
+	}
+	a.doc.AddBlock(ui.NewErrorBlock().SetText("The change may have caused a regression, see above."))
+	return "The namespaces the change was made in were monitored after it, and the change may have caused a regression. " + report.String() +
+		"\n\nCheck whether these are caused by the change. If they are, tell the user and propose rolling it back " +
+		"(e.g. with kubectl rollout undo, or by reverting the change), but do not roll it back without the user's confirmation."
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// checkPolicy returns whether the user must confirm the call before it runs, and whether the confirmation policy
+// forbids it altogether. modifies is the result of CheckModifiesResource for the call.
+func (a *Agent) checkPolicy(ctx context.Context, call gollm.FunctionCall, modifies string) (confirm bool, denied bool) {
+	if action, ok := a.ConfirmationPolicy.Decide(call.Name, call.Arguments, a.currentNamespace(ctx)); ok {
+		switch action {
+		case tools.PolicyDeny:
+			return false, true
+		case tools.PolicyApprove:
+			return false, false
+		case tools.PolicyConfirm:
+			return true, false
+		}
+	}
+	if a.Approvals.Approved(call.Name, call.Arguments, a.currentContext(ctx)) {
+		return false, false
+	}
+	return !a.SkipPermissions && modifies != "no", false
+}
+
+// currentNamespace returns the namespace of the current kubeconfig context, that commands which don't name one run in.
+// It returns "" if the namespace cannot be determined, so that the confirmation policy does not assume one.
+func (a *Agent) currentNamespace(ctx context.Context) string {
+	if a.ConfirmationPolicy == nil || len(a.ConfirmationPolicy.Rules) == 0 {
+		return ""
+	}
+	// The context can be changed by the commands we run, so we don't rely on the cluster info gathered at the start.
+	namespace, err := tools.CurrentNamespace(context.WithValue(ctx, tools.KubeconfigKey, a.Kubeconfig))
+	if err != nil {
+		klog.FromContext(ctx).Info("could not determine the current namespace for the confirmation policy", "error", err)
+		return ""
+	}
+	return namespace
+}
+
+// currentContext returns the name of the current kubeconfig context, that stored approvals are tied to.
+// It returns "" if the context cannot be determined, which only matches approvals given when it could not be either.
+func (a *Agent) currentContext(ctx context.Context) string {
+	if a.Approvals == nil {
+		return ""
+	}
+	kubeContext, err := tools.CurrentContext(context.WithValue(ctx, tools.KubeconfigKey, a.Kubeconfig))
+	if err != nil {
+		klog.FromContext(ctx).Info("could not determine the current context for stored approvals", "error", err)
+		return ""
+	}
+	return kubeContext
+}
+
+// denyCall tells the user that the confirmation policy does not allow the call,
+// and returns the result that tells the LLM.
+func (a *Agent) denyCall(call gollm.FunctionCall, description string) any {
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("  Not running %s, the confirmation policy does not allow it.\n", description)))
+	message := fmt.Sprintf("The confirmation policy does not allow running %s.", description)
+	if a.EnableToolUseShim {
+		return fmt.Sprintf("Result of running %q:\n%s", call.Name, message)
+	}
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"error":     message,
+			"status":    "denied",
+			"retryable": false,
+		},
+	}
+}
+
+// declineCall tells the user that a call they declined was skipped, and returns the result that tells the LLM.
+// With SuggestAlternatives, the LLM is asked to propose a safer alternative rather than giving up.
+func (a *Agent) declineCall(call gollm.FunctionCall, description string) any {
+	text := "Operation was skipped. User declined to run this operation."
+	message := "User declined to run this operation."
+	if a.SuggestAlternatives {
+		text += " Asking for a safer alternative."
+		message = fmt.Sprintf("The user declined to run %s. Do not retry it. Keep the task moving with a safer alternative: "+
+			"prefer read-only commands that gather the information the change was meant to act on, or propose a less risky change "+
+			"(e.g. scoped to fewer resources, or reversible) and explain how it differs.", description)
+	}
+	a.doc.AddBlock(ui.NewAgentTextBlock().WithText(text))
+	if a.EnableToolUseShim {
+		return fmt.Sprintf("Result of running %q:\n%s", call.Name, message)
+	}
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"error":     message,
+			"status":    "declined",
+			"retryable": false,
+		},
+	}
+}
+
+// skipUnconfirmedCall tells the user that a call that needs confirmation was not run, as no one can confirm it,
+// and returns the result that tells the LLM.
+func (a *Agent) skipUnconfirmedCall(call gollm.FunctionCall, description string) any {
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("  Not running %s, it needs confirmation and the agent is running non-interactively.\n", description)))
+	message := fmt.Sprintf("%s needs the user's confirmation, which cannot be given in non-interactive mode, so it was not run. Do not retry it; complete the task without it if you can, or explain what the user should run.", description)
+	if a.EnableToolUseShim {
+		return fmt.Sprintf("Result of running %q:\n%s", call.Name, message)
+	}
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"error":     message,
+			"status":    "unconfirmed",
+			"retryable": false,
+		},
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+)
+
+// promptFuncs returns the functions available in prompt templates.
+// They follow the names and argument order of the sprig library (as used by Helm),
+// so that users can compose prompts dynamically: e.g. {{ env "TEAM" }}, {{ file "runbook.md" }},
+// {{ include "section" . }} or {{ .Cluster.Context | default "unknown" }}.
+// Relative paths in file are resolved against baseDir.
+func promptFuncs(tmpl *template.Template, baseDir string) template.FuncMap {
+	return template.FuncMap{
+		// Environment and files
+		"env": os.Getenv,
+		"file": func(p string) (string, error) {
+			if !filepath.IsAbs(p) && baseDir != "" {
+				p = filepath.Join(baseDir, p)
+			}
+			b, err := tools.ReadArtifact(p)
+			if err != nil {
+				return "", fmt.Errorf("including file: %w", err)
+			}
+			return string(b), nil
+		},
+		"include": func(name string, data any) (string, error) {
+			var b strings.Builder
+			if err := tmpl.ExecuteTemplate(&b, name, data); err != nil {
+				return "", err
+			}
+			return b.String(), nil
+		},
+
+		// Defaults and conditionals
+		"default": func(defaultValue any, value ...any) any {
+			if len(value) == 0 || isEmpty(value[0]) {
+				return defaultValue
+			}
+			return value[0]
+		},
+		"empty": isEmpty,
+		"ternary": func(trueValue, falseValue any, condition bool) any {
+			if condition {
+				return trueValue
+			}
+			return falseValue
+		},
+
+		// Strings
+		"lower":     strings.ToLower,
+		"upper":     strings.ToUpper,
+		"trim":      strings.TrimSpace,
+		"contains":  func(substr, s string) bool { return strings.Contains(s, substr) },
+		"hasPrefix": func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+		"hasSuffix": func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+		"replace":   func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"quote":     func(s any) string { return fmt.Sprintf("%q", fmt.Sprint(s)) },
+		"indent": func(spaces int, s string) string {
+			pad := strings.Repeat(" ", spaces)
+			return pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"nindent": func(spaces int, s string) string {
+			pad := strings.Repeat(" ", spaces)
+			return "\n" + pad + strings.ReplaceAll(s, "\n", "\n"+pad)
+		},
+		"split": func(sep, s string) []string { return strings.Split(s, sep) },
+		"join": func(sep string, list any) string {
+			var items []string
+			v := reflect.ValueOf(list)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return fmt.Sprint(list)
+			}
+			for i := 0; i < v.Len(); i++ {
+				items = append(items, fmt.Sprint(v.Index(i).Interface()))
+			}
+			return strings.Join(items, sep)
+		},
+
+		// Collections
+		"list": func(items ...any) []any { return items },
+		"dict": func(keysAndValues ...any) (map[string]any, error) {
+			if len(keysAndValues)%2 != 0 {
+				return nil, fmt.Errorf("dict requires an even number of arguments")
+			}
+			m := make(map[string]any, len(keysAndValues)/2)
+			for i := 0; i < len(keysAndValues); i += 2 {
+				m[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+			}
+			return m, nil
+		},
+
+		// Encoding and time
+		"toJson": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"now":  time.Now,
+		"date": func(layout string, t time.Time) string { return t.Format(layout) },
+	}
+}
+
+// isEmpty returns true for nil, zero values and empty collections.
+func isEmpty(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return rv.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return rv.IsZero()
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// ErrQuotaExceeded is returned by RunOneRound when the agent stopped because a tool call would exceed a quota,
+// and the user did not extend it.
+var ErrQuotaExceeded = errors.New("tool quota exceeded")
+
+// ToolQuotas limit the tool calls of a session, to keep a runaway loop from hammering the cluster.
+// Zero means no limit.
+type ToolQuotas struct {
+	// MaxToolCalls limits the number of tool calls.
+	MaxToolCalls int
+	// MaxMutatingCalls limits the number of tool calls that may modify resources.
+	MaxMutatingCalls int
+	// MaxBashCalls limits the number of calls of the bash tool.
+	MaxBashCalls int
+}
+
+// toolQuotaUsage counts the tool calls that count against the quotas.
+type toolQuotaUsage struct {
+	toolCalls     int
+	mutatingCalls int
+	bashCalls     int
+}
+
+// add counts a tool call that was run.
+func (u *toolQuotaUsage) add(call gollm.FunctionCall, mutating bool) {
+	u.toolCalls++
+	if mutating {
+		u.mutatingCalls++
+	}
+	if call.Name == "bash" {
+		u.bashCalls++
+	}
+}
+
+// quota is one of the quotas, with the usage it limits.
+type quota struct {
+	// name describes what the quota limits, e.g. "tool calls".
+	name string
+	// limit is the configured limit, and extended the total of the extensions granted by the user.
+	limit, extended *int
+	used            int
+	// applies is whether the call counts against the quota.
+	applies bool
+}
+
+// quotas returns the quotas that the call counts against.
+func (a *Agent) quotas(call gollm.FunctionCall, mutating bool) []quota {
+	return []quota{
+		{name: "tool calls", limit: &a.ToolQuotas.MaxToolCalls, extended: &a.quotaExtensions.MaxToolCalls, used: a.quotaUsage.toolCalls, applies: true},
+		{name: "tool calls that modify resources", limit: &a.ToolQuotas.MaxMutatingCalls, extended: &a.quotaExtensions.MaxMutatingCalls, used: a.quotaUsage.mutatingCalls, applies: mutating},
+		{name: "bash commands", limit: &a.ToolQuotas.MaxBashCalls, extended: &a.quotaExtensions.MaxBashCalls, used: a.quotaUsage.bashCalls, applies: call.Name == "bash"},
+	}
+}
+
+// checkQuotas returns "" if the call can run without exceeding a quota.
+// Otherwise it asks the user whether to extend the quota, and returns why the call cannot run if they don't
+// (or if no one can be asked).
+func (a *Agent) checkQuotas(call gollm.FunctionCall, mutating bool) (string, error) {
+	for _, q := range a.quotas(call, mutating) {
+		if !q.applies || *q.limit == 0 || q.used < *q.limit+*q.extended {
+			continue
+		}
+		reason := fmt.Sprintf("the session has used its quota of %d %s", *q.limit+*q.extended, q.name)
+		if a.NonInteractive {
+			return reason, nil
+		}
+
+		prompt := fmt.Sprintf("  Paused: %s. Do you want to allow %d more?", reason, *q.limit)
+		optionsBlock := ui.NewInputOptionBlock().SetPrompt(prompt)
+		optionsBlock.AddOption("yes", fmt.Sprintf("Yes, allow %d more %s", *q.limit, q.name), "yes", "y")
+		optionsBlock.AddOption("no", "No, stop here", "no", "n")
+		a.doc.AddBlock(optionsBlock)
+
+		choice, err := optionsBlock.Selection().Wait()
+		if err != nil {
+			return "", err
+		}
+		if choice != "yes" {
+			return reason, nil
+		}
+		*q.extended += *q.limit
+	}
+	return "", nil
+}
+
+// quotaExceededResult tells the LLM that the call was not run because of the quota.
+func (a *Agent) quotaExceededResult(call gollm.FunctionCall, reason string) any {
+	message := fmt.Sprintf("Not run: %s. Stop, and summarize what you did so far for the user.", reason)
+	if a.EnableToolUseShim {
+		return fmt.Sprintf("Result of running %q:\n%s", call.Name, message)
+	}
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"error":     message,
+			"status":    "quota_exceeded",
+			"retryable": false,
+		},
+	}
+}
+
+// stopForQuota ends the round because a quota is exhausted, keeping what the agent did so far as the answer.
+func (a *Agent) stopForQuota(reason string) error {
+	message := fmt.Sprintf("Stopping before completing the task: %s.", reason)
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(message + "\n"))
+	if a.lastAnswer == nil {
+		a.lastAnswer = &FinalAnswer{Summary: message, CommandsRun: a.roundCommands}
+	}
+	return fmt.Errorf("%w: %s", ErrQuotaExceeded, reason)
+}
+
+// ToolQuotaSummary describes the usage of the tool quotas, or returns "" if there are none.
+func (a *Agent) ToolQuotaSummary() string {
+	var parts []string
+	for _, q := range a.quotas(gollm.FunctionCall{}, false) {
+		if *q.limit != 0 {
+			parts = append(parts, fmt.Sprintf("%d of %d %s", q.used, *q.limit+*q.extended, q.name))
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Tool quotas: " + strings.Join(parts, ", ")
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// ErrRepeatedToolCall is returned by RunOneRound when the agent stopped because the LLM was stuck in a loop:
+// it kept making the same tool call, or alternating between two changes, even after being told that it would not help.
+var ErrRepeatedToolCall = errors.New("repeated tool call")
+
+// LoopAction is what to do when the LLM is stuck in a loop.
+type LoopAction string
+
+const (
+	// LoopActionCorrect doesn't run the call, and tells the LLM to try something else; the round stops if the LLM
+	// keeps looping.
+	LoopActionCorrect LoopAction = "correct"
+	// LoopActionAsk asks the user whether to run the call anyway, tell the LLM to try something else, or stop.
+	LoopActionAsk LoopAction = "ask"
+)
+
+// loopDecision is what we do about a call that is part of a loop.
+type loopDecision string
+
+const (
+	loopCorrect loopDecision = "correct"
+	loopRun     loopDecision = "run"
+	loopStop    loopDecision = "stop"
+)
+
+// onLoop decides what to do about a call that is part of a loop; situation describes the loop to the user,
+// and again is set if the LLM was already told to try something else.
+func (a *Agent) onLoop(situation string, again bool) (loopDecision, error) {
+	if a.LoopAction == LoopActionAsk && !a.NonInteractive {
+		optionsBlock := ui.NewInputOptionBlock().SetPrompt(fmt.Sprintf("  The model seems to be stuck in a loop: %s. What do you want to do?", situation))
+		optionsBlock.AddOption(string(loopCorrect), "Tell the model to try something else")
+		optionsBlock.AddOption(string(loopRun), "Run the call anyway")
+		optionsBlock.AddOption(string(loopStop), "Stop")
+		a.doc.AddBlock(optionsBlock)
+		choice, err := optionsBlock.Selection().Wait()
+		if err != nil {
+			return "", err
+		}
+		return loopDecision(choice), nil
+	}
+	if again {
+		return loopStop, nil
+	}
+	block := ui.NewAgentTextBlock().WithText(fmt.Sprintf("Not running the call: %s. Telling the model to try something else.", situation))
+	block.SetColor(ui.ColorYellow)
+	a.doc.AddBlock(block)
+	return loopCorrect, nil
+}
+
+// toolCallKey identifies a tool call by its name and arguments, to detect identical calls.
+// The reason and modifies_resource arguments of the tool-use shim are not part of the call itself, so they are ignored.
+func toolCallKey(call gollm.FunctionCall) string {
+	args := maps.Clone(call.Arguments)
+	delete(args, "reason")
+	delete(args, "modifies_resource")
+	// Maps are marshaled with sorted keys, so the same arguments give the same key.
+	b, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Sprintf("%s %v", call.Name, args)
+	}
+	return call.Name + " " + string(b)
+}
+
+// repeatedCall records a tool call in the current round, and returns how many times the same call was made before.
+func (a *Agent) repeatedCall(call gollm.FunctionCall) int {
+	if a.roundCalls == nil {
+		a.roundCalls = make(map[string]int)
+	}
+	key := toolCallKey(call)
+	previous := a.roundCalls[key]
+	a.roundCalls[key]++
+	return previous
+}
+
+// repeatedCallResult is the result we send instead of running a call that the LLM keeps repeating.
+func repeatedCallResult(description string, previous int) string {
+	return fmt.Sprintf("You already made this exact call (%s) %d times while working on this query, and it was not run again. "+
+		"Running it again will give the same result. Use the results you already have, try a different approach, or give your answer.",
+		description, previous)
+}
+
+// mutationCall is a tool call that may modify resources, made in the current round.
+type mutationCall struct {
+	key         string
+	description string
+}
+
+// oscillatingCall records a call that may modify resources in the current round, and returns the description of
+// the other call if the LLM is alternating between two calls (A, B, A, B), e.g. changes that undo each other.
+func (a *Agent) oscillatingCall(call gollm.FunctionCall, description string) string {
+	a.roundMutations = append(a.roundMutations, mutationCall{key: toolCallKey(call), description: description})
+	n := len(a.roundMutations)
+	if n < 4 {
+		return ""
+	}
+	last := a.roundMutations[n-4:]
+	if last[0].key == last[2].key && last[1].key == last[3].key && last[0].key != last[1].key {
+		return last[2].description
+	}
+	return ""
+}
+
+// loopResult is the result we send instead of running a call that is part of a loop.
+func loopResult(shim bool, call gollm.FunctionCall, message string) any {
+	if shim {
+		return fmt.Sprintf("Result of running %q:\n%s", call.Name, message)
+	}
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"error":     message,
+			"status":    "repeated",
+			"retryable": false,
+		},
+	}
+}
+
+// oscillationResult is the result we send instead of running a call that alternates with another one.
+func oscillationResult(other, description string) string {
+	return fmt.Sprintf("You are alternating between %s and %s, which undo each other, so the call was not run. "+
+		"Check the current state of the resources, and decide on one change, try a different approach, or give your answer.",
+		other, description)
+}
+
+// stopForLoop ends the round because the LLM is stuck in a loop, keeping what the agent did so far as the answer.
+func (a *Agent) stopForLoop(reason string) error {
+	message := fmt.Sprintf("Stopping before completing the task: %s.", reason)
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(message + "\n"))
+	if a.lastAnswer == nil {
+		a.lastAnswer = &FinalAnswer{Summary: message, CommandsRun: a.roundCommands}
+	}
+	return fmt.Errorf("%w: %s", ErrRepeatedToolCall, reason)
+}
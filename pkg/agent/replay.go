@@ -0,0 +1,141 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// Resume restores the state of a previous session from its reconstructed history.
+// The history is rendered into the document, and sent to the LLM along with the next query,
+// so the agent continues where the previous session left off.
+// Tool calls are not re-executed; their recorded results are used instead.
+func (a *Agent) Resume(ctx context.Context, history []*journal.HistoryEntry) error {
+	if a.doc == nil {
+		return fmt.Errorf("conversation is not initialized")
+	}
+
+	transcript, err := historyTranscript(history)
+	if err != nil {
+		return err
+	}
+	if transcript == "" {
+		return fmt.Errorf("no chat history found to resume from")
+	}
+
+	a.renderHistory(ctx, history)
+	a.history = history
+	a.roundStarts = roundStartsOf(history)
+	a.resumedHistory = resumePrompt(transcript)
+	return nil
+}
+
+// resumePrompt builds the message that gives the LLM the history of the session it is resuming.
+func resumePrompt(transcript string) string {
+	return "You are resuming an earlier session. This is the transcript of that session; " +
+		"continue from where it left off, and do not repeat tool calls that have already completed.\n\n" +
+		transcript
+}
+
+// renderHistory adds blocks for the history to the document.
+func (a *Agent) renderHistory(ctx context.Context, history []*journal.HistoryEntry) {
+	if len(history) > 0 && !history[0].Timestamp.IsZero() {
+		timeFormat := a.doc.TimeFormat()
+		started, lastActive := history[0].Timestamp, history[len(history)-1].Timestamp
+		a.doc.AddBlock(ui.NewAgentTextBlock().WithText(fmt.Sprintf("Resuming the session started %s (last active %s)", timeFormat.Time(started), timeFormat.Time(lastActive))))
+	}
+
+	// pendingCalls are the rendered function calls that are still waiting for their results.
+	var pendingCalls []*replayedCall
+	for _, entry := range history {
+		switch entry.Role {
+		case journal.RoleUser:
+			for _, message := range entry.Messages {
+				a.doc.AddBlock(ui.NewAgentTextBlock().WithText(">>> " + message))
+			}
+			for _, result := range entry.FunctionCallResults {
+				for i, pending := range pendingCalls {
+					if pending.id == result.ID && pending.name == result.Name {
+						pending.block.SetResult(result.Result)
+						pendingCalls = append(pendingCalls[:i], pendingCalls[i+1:]...)
+						break
+					}
+				}
+			}
+
+		case journal.RoleModel:
+			for _, message := range entry.Messages {
+				a.doc.AddBlock(ui.NewAgentTextBlock().WithText(message))
+			}
+			for _, call := range entry.FunctionCalls {
+				description := call.Name
+				if toolCall, err := a.Tools.ParseToolInvocation(ctx, call.Name, call.Arguments); err == nil {
+					description = toolCall.Description()
+				} else {
+					klog.Warningf("replaying call to unknown tool %q: %v", call.Name, err)
+				}
+				block := ui.NewFunctionCallRequestBlock().SetDescription(description)
+				a.doc.AddBlock(block)
+				pendingCalls = append(pendingCalls, &replayedCall{id: call.ID, name: call.Name, block: block})
+			}
+		}
+	}
+}
+
+// historyTranscript formats the history as a plain-text transcript, which can be given to any LLM.
+func historyTranscript(history []*journal.HistoryEntry) (string, error) {
+	var transcript strings.Builder
+	for _, entry := range history {
+		switch entry.Role {
+		case journal.RoleUser:
+			for _, message := range entry.Messages {
+				fmt.Fprintf(&transcript, "User: %s\n", message)
+			}
+			for _, result := range entry.FunctionCallResults {
+				resultJSON, err := json.Marshal(result.Result)
+				if err != nil {
+					return "", fmt.Errorf("marshalling result of %q: %w", result.Name, err)
+				}
+				fmt.Fprintf(&transcript, "Result of %s: %s\n", result.Name, resultJSON)
+			}
+
+		case journal.RoleModel:
+			for _, message := range entry.Messages {
+				fmt.Fprintf(&transcript, "Assistant: %s\n", message)
+			}
+			for _, call := range entry.FunctionCalls {
+				argsJSON, err := json.Marshal(call.Arguments)
+				if err != nil {
+					return "", fmt.Errorf("marshalling arguments of %q: %w", call.Name, err)
+				}
+				fmt.Fprintf(&transcript, "Assistant called %s with arguments %s\n", call.Name, argsJSON)
+			}
+		}
+	}
+	return transcript.String(), nil
+}
+
+type replayedCall struct {
+	id    string
+	name  string
+	block *ui.FunctionCallRequestBlock
+}
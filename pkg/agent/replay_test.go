@@ -0,0 +1,126 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+func TestResume(t *testing.T) {
+	// The events of a session of two rounds; the first runs a command, the second has the answer style.
+	events := []*journal.Event{
+		{Action: journal.ActionUserQuery, Payload: map[string]any{"query": "list pods"}},
+		{Action: journal.ActionLLMChat, Payload: []any{[]any{"list pods"}}},
+		{Action: journal.ActionLLMResponse, Payload: map[string]any{
+			"functionCalls": []any{map[string]any{"id": "call-1", "name": "kubectl", "arguments": map[string]any{"command": "kubectl get pods"}}},
+		}},
+		{Action: journal.ActionLLMChat, Payload: []any{[]any{
+			map[string]any{"id": "call-1", "name": "kubectl", "result": map[string]any{"stdout": "web-0 Running"}},
+		}}},
+		{Action: journal.ActionLLMResponse, Payload: map[string]any{"text": "web-0 is running."}},
+		{Action: journal.ActionUserQuery, Payload: map[string]any{"query": "is it ready?"}},
+		{Action: journal.ActionLLMChat, Payload: []any{[]any{"is it ready?", "Answer briefly."}}},
+		{Action: journal.ActionLLMResponse, Payload: map[string]any{"text": "Yes."}},
+	}
+
+	tests := []struct {
+		name            string
+		events          []*journal.Event
+		wantErr         bool
+		wantRoundStarts []int
+		wantTranscript  []string
+		wantBlocks      []string
+	}{
+		{
+			name:            "two rounds",
+			events:          events,
+			wantRoundStarts: []int{0, 4},
+			wantTranscript: []string{
+				"User: list pods",
+				`Assistant called kubectl with arguments {"command":"kubectl get pods"}`,
+				`Result of kubectl: {"stdout":"web-0 Running"}`,
+				"Assistant: web-0 is running.",
+				"User: is it ready?",
+				"Assistant: Yes.",
+			},
+			wantBlocks: []string{">>> list pods", "kubectl", "web-0 is running.", ">>> is it ready?", ">>> Answer briefly.", "Yes."},
+		},
+		{
+			name:    "no history",
+			events:  []*journal.Event{{Action: journal.ActionUserQuery, Payload: map[string]any{"query": "list pods"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history, err := journal.ReconstructHistory(tt.events)
+			if err != nil {
+				t.Fatalf("ReconstructHistory() error = %v", err)
+			}
+			a := &Agent{}
+			if err := a.Resume(context.Background(), history); err == nil {
+				t.Errorf("Resume() before Init succeeded, expected an error")
+			}
+			doc := ui.NewDocument()
+			a.doc = doc
+
+			err = a.Resume(context.Background(), history)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Resume() succeeded, expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resume() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(a.history, history) {
+				t.Errorf("history = %+v, expected %+v", a.history, history)
+			}
+			if !slices.Equal(a.roundStarts, tt.wantRoundStarts) {
+				t.Errorf("round starts = %v, expected %v", a.roundStarts, tt.wantRoundStarts)
+			}
+			for _, line := range tt.wantTranscript {
+				if !strings.Contains(a.resumedHistory, line+"\n") {
+					t.Errorf("the resumed history does not have %q:\n%s", line, a.resumedHistory)
+				}
+			}
+
+			var blocks []string
+			for _, block := range doc.Blocks() {
+				switch block := block.(type) {
+				case *ui.AgentTextBlock:
+					blocks = append(blocks, block.Text())
+				case *ui.FunctionCallRequestBlock:
+					blocks = append(blocks, block.Description())
+					if block.Result() == nil {
+						t.Errorf("the call %q was rendered without its result", block.Description())
+					}
+				}
+			}
+			if !slices.Equal(blocks, tt.wantBlocks) {
+				t.Errorf("rendered blocks = %q, expected %q", blocks, tt.wantBlocks)
+			}
+		})
+	}
+}
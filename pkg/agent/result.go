@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// Result is what the agent did to answer a query, returned by RunOneRound.
+type Result struct {
+	// Query is the query that was answered.
+	Query string
+
+	// Messages are the texts of the responses of the LLM, in order; responses with only tool calls have none.
+	Messages []string
+
+	// ToolCalls are the tool calls that ran, in order. Calls that were declined or refused are not included.
+	ToolCalls []ToolCallResult
+
+	// Answer is the structured final answer, if StructuredAnswer is set and the LLM gave one.
+	Answer *FinalAnswer
+
+	// Usage is the tokens used by the LLM requests made to answer the query, including those of fanned-out
+	// sub-tasks, and Cost their estimated cost in US dollars (zero if the price of the model is not known).
+	Usage gollm.Usage
+	Cost  float64
+}
+
+// ToolCallResult is a tool call that ran, with what it returned.
+type ToolCallResult struct {
+	ToolCallInfo
+
+	// Output is what the tool returned, e.g. a *tools.ExecResult for the kubectl and bash tools.
+	Output any
+
+	// Error is set if the tool could not run.
+	Error string
+}
+
+// RunOneRound sends the query to the LLM, and runs the tool calls it makes until it answers the query,
+// or the round stops (e.g. it times out, or the budget is exhausted). It returns what was done to answer the query,
+// even if it returns an error. If the round stops before it completes, the objects it created may be deleted
+// (see AutoCleanup).
+func (a *Agent) RunOneRound(ctx context.Context, query string) (*Result, error) {
+	a.roundResult = &Result{Query: query}
+	spent, spentCost := a.spent, a.spentCost
+
+	err := a.runOneRound(ctx, query)
+	if err != nil {
+		a.failed = true
+		a.cleanUpAbortedRound(ctx)
+	} else if a.SuggestFollowUps && !a.NonInteractive {
+		a.suggestFollowUps(ctx, query)
+	}
+
+	result := a.roundResult
+	a.roundResult = nil
+	result.Answer = a.lastAnswer
+	result.Usage = gollm.Usage{
+		InputTokens:       a.spent.InputTokens - spent.InputTokens,
+		OutputTokens:      a.spent.OutputTokens - spent.OutputTokens,
+		TotalTokens:       a.spent.TotalTokens - spent.TotalTokens,
+		CachedInputTokens: a.spent.CachedInputTokens - spent.CachedInputTokens,
+	}
+	result.Cost = a.spentCost - spentCost
+	return result, err
+}
+
+// recordResponse adds the text of an LLM response to the result of the round.
+func (a *Agent) recordResponse(response LLMResponse) {
+	if a.roundResult != nil && response.Text != "" {
+		a.roundResult.Messages = append(a.roundResult.Messages, response.Text)
+	}
+}
+
+// recordToolCall adds a tool call that ran to the result of the round.
+func (a *Agent) recordToolCall(call ToolCallInfo, output any, err error) {
+	if a.roundResult == nil {
+		return
+	}
+	result := ToolCallResult{ToolCallInfo: call, Output: output}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	a.roundResult.ToolCalls = append(a.roundResult.ToolCalls, result)
+}
@@ -0,0 +1,380 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"sigs.k8s.io/yaml"
+)
+
+// scenarioPollInterval is how often the expectations of a scenario step are checked while waiting for them to hold.
+const scenarioPollInterval = 5 * time.Second
+
+// scenarioVar matches the {{name}} placeholders of scenario variables.
+var scenarioVar = regexp.MustCompile(`\{\{([A-Za-z0-9_]+)\}\}`)
+
+// Scenario is a scripted runbook that the agent works through in a single session: each step is a query,
+// followed by conditions that must hold once it is done, e.g. "drain the node", "check that the workloads
+// were rescheduled", "uncordon the node".
+type Scenario struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Vars are the variables of the scenario, with their default values; {{name}} is replaced by the value
+	// in the steps. Variables without a default must be set when the scenario is run.
+	Vars  map[string]string `json:"vars,omitempty"`
+	Steps []ScenarioStep    `json:"steps"`
+}
+
+// ScenarioStep is a step of a scenario.
+type ScenarioStep struct {
+	Name string `json:"name"`
+	// Prompt is the query for the agent.
+	Prompt string `json:"prompt"`
+	// Confirm asks the user before the step starts.
+	Confirm bool `json:"confirm,omitempty"`
+	// AutoApprove runs the tool calls of the step without asking for confirmation.
+	AutoApprove bool `json:"autoApprove,omitempty"`
+	// Timeout bounds the time the agent spends on the step, e.g. "10m".
+	Timeout string `json:"timeout,omitempty"`
+	// Expect are the conditions that must hold once the agent is done.
+	Expect []ScenarioExpectation `json:"expect,omitempty"`
+	// ContinueOnFailure goes on with the next step if this one fails, when no one can be asked what to do;
+	// otherwise the scenario stops.
+	ContinueOnFailure bool `json:"continueOnFailure,omitempty"`
+
+	timeout time.Duration
+}
+
+// ScenarioExpectation is a condition that must hold after a step: the output of a read-only kubectl command
+// equals, contains or does not contain a value, or just the command succeeds.
+type ScenarioExpectation struct {
+	Description string  `json:"description,omitempty"`
+	Command     string  `json:"command"`
+	Equals      *string `json:"equals,omitempty"`
+	Contains    *string `json:"contains,omitempty"`
+	NotContains *string `json:"notContains,omitempty"`
+	// Timeout is how long to wait for the condition to hold, e.g. "2m"; by default it is checked once.
+	Timeout string `json:"timeout,omitempty"`
+
+	timeout time.Duration
+}
+
+func (e *ScenarioExpectation) assertion() *assertion {
+	description := e.Description
+	if description == "" {
+		description = e.Command
+	}
+	return &assertion{description: description, command: e.Command, equals: e.Equals, contains: e.Contains, notContains: e.NotContains}
+}
+
+// LoadScenario reads a scenario file, and replaces its variables with their values in vars, or their defaults.
+func LoadScenario(path string, vars map[string]string) (*Scenario, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading scenario: %w", err)
+	}
+	scenario := &Scenario{}
+	if err := yaml.UnmarshalStrict(b, scenario); err != nil {
+		return nil, fmt.Errorf("parsing scenario %q: %w", path, err)
+	}
+	if err := scenario.resolve(vars); err != nil {
+		return nil, fmt.Errorf("scenario %q: %w", path, err)
+	}
+	return scenario, nil
+}
+
+// resolve checks the scenario, and replaces its variables with their values.
+func (s *Scenario) resolve(vars map[string]string) error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	values := make(map[string]string)
+	for name, value := range s.Vars {
+		values[name] = value
+	}
+	for name, value := range vars {
+		if _, ok := s.Vars[name]; !ok {
+			return fmt.Errorf("unknown variable %q", name)
+		}
+		values[name] = value
+	}
+	var missing []string
+	for name, value := range values {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		slices.Sort(missing)
+		return fmt.Errorf("variables without a value: %s", strings.Join(missing, ", "))
+	}
+
+	var err error
+	substitute := func(s *string) {
+		*s = scenarioVar.ReplaceAllStringFunc(*s, func(placeholder string) string {
+			name := scenarioVar.FindStringSubmatch(placeholder)[1]
+			value, ok := values[name]
+			if !ok && err == nil {
+				err = fmt.Errorf("undeclared variable %q", name)
+			}
+			return value
+		})
+	}
+	parseTimeout := func(s string) time.Duration {
+		if s == "" {
+			return 0
+		}
+		d, parseErr := time.ParseDuration(s)
+		if parseErr != nil && err == nil {
+			err = fmt.Errorf("invalid timeout %q: %w", s, parseErr)
+		}
+		return d
+	}
+	for i := range s.Steps {
+		step := &s.Steps[i]
+		if step.Name == "" {
+			step.Name = fmt.Sprintf("step %d", i+1)
+		}
+		if strings.TrimSpace(step.Prompt) == "" {
+			return fmt.Errorf("%s has no prompt", step.Name)
+		}
+		substitute(&step.Name)
+		substitute(&step.Prompt)
+		step.timeout = parseTimeout(step.Timeout)
+		for j := range step.Expect {
+			expectation := &step.Expect[j]
+			if strings.TrimSpace(expectation.Command) == "" {
+				return fmt.Errorf("%s: expectation %d has no command", step.Name, j+1)
+			}
+			substitute(&expectation.Description)
+			substitute(&expectation.Command)
+			for _, value := range []*string{expectation.Equals, expectation.Contains, expectation.NotContains} {
+				if value != nil {
+					substitute(value)
+				}
+			}
+			expectation.timeout = parseTimeout(expectation.Timeout)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", step.Name, err)
+		}
+	}
+	return nil
+}
+
+// Statuses of the steps of a scenario.
+const (
+	ScenarioStepPassed  = "passed"
+	ScenarioStepFailed  = "failed"
+	ScenarioStepSkipped = "skipped"
+)
+
+// ScenarioStepResult is the outcome of a step of a scenario.
+type ScenarioStepResult struct {
+	Name   string
+	Status string
+	// Error is why the step failed.
+	Error    string
+	Attempts int
+}
+
+// errScenarioStopped is returned by RunScenario when the user stops the scenario.
+var errScenarioStopped = errors.New("scenario stopped")
+
+// RunScenario works through the steps of a scenario in the current session. When a step fails, the user is asked
+// whether to retry it, skip it or stop; when no one can be asked, the scenario stops unless the step continues on
+// failure. It returns the outcome of each step, and an error if a step failed.
+func (a *Agent) RunScenario(ctx context.Context, scenario *Scenario) ([]*ScenarioStepResult, error) {
+	title := fmt.Sprintf("Running scenario %q (%d steps)", scenario.Name, len(scenario.Steps))
+	if scenario.Description != "" {
+		title += ": " + scenario.Description
+	}
+	a.doc.AddBlock(ui.NewAgentTextBlock().WithText(title))
+
+	results := make([]*ScenarioStepResult, len(scenario.Steps))
+	for i := range scenario.Steps {
+		results[i] = &ScenarioStepResult{Name: scenario.Steps[i].Name, Status: ScenarioStepSkipped}
+	}
+
+	var failed []string
+	for i := range scenario.Steps {
+		step := &scenario.Steps[i]
+		result := results[i]
+		a.doc.AddBlock(ui.NewAgentTextBlock().WithText(fmt.Sprintf("Step %d/%d: %s", i+1, len(scenario.Steps), step.Name)))
+
+		var err error
+		if step.Confirm && a.NonInteractive {
+			err = fmt.Errorf("the step needs confirmation, which cannot be given in non-interactive mode")
+		} else if step.Confirm {
+			choice, askErr := a.askScenario("  Run this step?", "run", "Yes, run it", "skip", "Skip it")
+			if askErr != nil {
+				a.showScenarioResults(results)
+				return results, askErr
+			}
+			if choice == "skip" {
+				continue
+			}
+		}
+		if err == nil {
+			err = a.runScenarioStep(ctx, step, result)
+		}
+		if err == nil {
+			result.Status = ScenarioStepPassed
+			continue
+		}
+		result.Status = ScenarioStepFailed
+		result.Error = err.Error()
+		if errors.Is(err, errScenarioStopped) {
+			a.showScenarioResults(results)
+			return results, err
+		}
+		failed = append(failed, step.Name)
+		// When the user is asked about a failed step, they chose to skip it.
+		if ctx.Err() != nil || (a.NonInteractive && !step.ContinueOnFailure) {
+			a.showScenarioResults(results)
+			return results, fmt.Errorf("step %q failed: %w", step.Name, err)
+		}
+	}
+	a.showScenarioResults(results)
+	if len(failed) > 0 {
+		return results, fmt.Errorf("steps failed: %s", strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+// runScenarioStep runs a step until its expectations hold, asking the user what to do when they don't.
+// It returns nil if the step passed, errScenarioStopped if the user stopped the scenario, or why it failed.
+func (a *Agent) runScenarioStep(ctx context.Context, step *ScenarioStep, result *ScenarioStepResult) error {
+	prompt := step.Prompt
+	for {
+		result.Attempts++
+		err := a.runScenarioPrompt(ctx, step, prompt)
+		if err == nil {
+			err = a.checkExpectations(ctx, step.Expect)
+		}
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+		a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("Step %q failed: %v", step.Name, err)))
+		if a.NonInteractive {
+			return err
+		}
+
+		choice, askErr := a.askScenario("  What do you want to do?", "retry", "Retry the step", "skip", "Skip it and go on", "stop", "Stop the scenario")
+		if askErr != nil {
+			return askErr
+		}
+		switch choice {
+		case "retry":
+			prompt = fmt.Sprintf("The step %q did not complete: %v. Find out why and fix it, then finish the step: %s", step.Name, err, step.Prompt)
+		case "skip":
+			return err
+		default:
+			return errScenarioStopped
+		}
+	}
+}
+
+// runScenarioPrompt runs a round for the prompt of a step, with the step's timeout and approvals.
+func (a *Agent) runScenarioPrompt(ctx context.Context, step *ScenarioStep, prompt string) error {
+	roundTimeout, skipPermissions := a.RoundTimeout, a.SkipPermissions
+	defer func() { a.RoundTimeout, a.SkipPermissions = roundTimeout, skipPermissions }()
+	if step.timeout > 0 {
+		a.RoundTimeout = step.timeout
+	}
+	if step.AutoApprove {
+		a.SkipPermissions = true
+	}
+	_, err := a.RunOneRound(ctx, prompt)
+	return err
+}
+
+// checkExpectations checks the expectations of a step, waiting up to their timeout for each of them to hold.
+func (a *Agent) checkExpectations(ctx context.Context, expectations []ScenarioExpectation) error {
+	for i := range expectations {
+		expectation := &expectations[i]
+		assertion := expectation.assertion()
+		deadline := time.Now().Add(expectation.timeout)
+		for {
+			failure, err := a.checkAssertion(ctx, assertion)
+			if err != nil {
+				return fmt.Errorf("checking %q: %w", assertion.description, err)
+			}
+			if failure == "" {
+				textBlock := ui.NewAgentTextBlock().WithText(fmt.Sprintf("Expectation holds: %s", assertion.description))
+				textBlock.SetColor(ui.ColorWhite)
+				a.doc.AddBlock(textBlock)
+				break
+			}
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return fmt.Errorf("%s: %s", assertion.description, failure)
+			}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(min(scenarioPollInterval, remaining)):
+			}
+		}
+	}
+	return nil
+}
+
+// askScenario asks the user to choose between options, given as pairs of choice and label.
+func (a *Agent) askScenario(prompt string, options ...string) (string, error) {
+	optionsBlock := ui.NewInputOptionBlock().SetPrompt(prompt)
+	for i := 0; i+1 < len(options); i += 2 {
+		optionsBlock.AddOption(options[i], options[i+1])
+	}
+	a.doc.AddBlock(optionsBlock)
+	choice, err := optionsBlock.Selection().Wait()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return "", errScenarioStopped
+		}
+		return "", fmt.Errorf("reading input: %w", err)
+	}
+	return choice, nil
+}
+
+// showScenarioResults shows the outcome of each step.
+func (a *Agent) showScenarioResults(results []*ScenarioStepResult) {
+	var sb strings.Builder
+	sb.WriteString("Scenario results:\n")
+	for i, result := range results {
+		fmt.Fprintf(&sb, "%d. %s: %s", i+1, result.Name, result.Status)
+		if result.Attempts > 1 {
+			fmt.Fprintf(&sb, " after %d attempts", result.Attempts)
+		}
+		if result.Error != "" {
+			fmt.Fprintf(&sb, " (%s)", result.Error)
+		}
+		sb.WriteString("\n")
+	}
+	a.doc.AddBlock(ui.NewAgentTextBlock().WithText(sb.String()))
+}
@@ -0,0 +1,137 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// thoughtKey matches the start of the value of the "thought" field in a ReAct response.
+var thoughtKey = regexp.MustCompile(`"thought"\s*:\s*"`)
+
+// thoughtStreamer extracts the value of the "thought" field of a ReAct response while it is streamed,
+// so that the reasoning of the LLM can be shown as it arrives; the rest of the response (e.g. the action)
+// is only used once the whole response has been received and parsed.
+type thoughtStreamer struct {
+	// buffer is the response received so far.
+	buffer string
+	// start is the position of the thought value in buffer, or -1 if it has not been found yet.
+	start int
+	// pos is the position in buffer up to which the thought has been decoded.
+	pos int
+	// done is set once the end of the thought value has been seen.
+	done bool
+	// streamed is the thought text returned so far.
+	streamed strings.Builder
+}
+
+func newThoughtStreamer() *thoughtStreamer {
+	return &thoughtStreamer{start: -1}
+}
+
+// write adds a chunk of the response, and returns the text of the thought that is complete in it.
+// Escape sequences that are split between chunks are held back until the next chunk.
+func (s *thoughtStreamer) write(text string) string {
+	s.buffer += text
+	if s.done {
+		return ""
+	}
+	if s.start < 0 {
+		marker := strings.Index(s.buffer, "```json")
+		if marker < 0 {
+			return ""
+		}
+		loc := thoughtKey.FindStringIndex(s.buffer[marker:])
+		if loc == nil {
+			return ""
+		}
+		s.start = marker + loc[1]
+		s.pos = s.start
+	}
+
+	end := s.pos
+	for end < len(s.buffer) {
+		c := s.buffer[end]
+		if c == '"' {
+			s.done = true
+			break
+		}
+		if c != '\\' {
+			end++
+			continue
+		}
+		n := escapeLength(s.buffer[end:])
+		if n == 0 {
+			// The escape sequence is not complete yet.
+			break
+		}
+		end += n
+	}
+
+	decoded := decodeJSONStringFragment(s.buffer[s.pos:end])
+	s.pos = end
+	s.streamed.WriteString(decoded)
+	return decoded
+}
+
+// escapeLength returns the length of the JSON escape sequence at the start of s, or 0 if it is incomplete.
+// A \u escape of a high surrogate includes the escape of the low surrogate that follows it, so that they are decoded together.
+func escapeLength(s string) int {
+	if len(s) < 2 {
+		return 0
+	}
+	if s[1] != 'u' {
+		return 2
+	}
+	if len(s) < 6 {
+		return 0
+	}
+	if r, err := strconv.ParseUint(s[2:6], 16, 16); err == nil && r >= 0xD800 && r < 0xDC00 {
+		if len(s) < 12 {
+			return 0
+		}
+		return 12
+	}
+	return 6
+}
+
+// decodeJSONStringFragment decodes part of the contents of a JSON string, which ends on a complete escape sequence.
+// Like parseReActResponse, it ignores newlines, which LLMs sometimes put in JSON strings.
+func decodeJSONStringFragment(fragment string) string {
+	fragment = strings.ReplaceAll(fragment, "\n", "")
+	if fragment == "" {
+		return ""
+	}
+	var decoded string
+	if err := json.Unmarshal([]byte(`"`+fragment+`"`), &decoded); err != nil {
+		// We show what we have; the whole response is parsed (and checked) at the end.
+		return fragment
+	}
+	return decoded
+}
+
+// remainder returns the part of the parsed thought that has not been streamed yet.
+func (s *thoughtStreamer) remainder(thought string) string {
+	streamed := s.streamed.String()
+	if rest, ok := strings.CutPrefix(thought, streamed); ok {
+		return rest
+	}
+	// We streamed something that was not the thought after all (e.g. a "thought" key in another field);
+	// it is better to show the thought again than to lose part of it.
+	return thought
+}
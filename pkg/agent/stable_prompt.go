@@ -0,0 +1,115 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+)
+
+// Providers reuse the cached computation of a prompt prefix when a request starts with the same bytes as an
+// earlier one. With StablePrompt, the system prompt and function definitions are canonicalized, and the facts
+// gathered from the cluster are sent with the first query rather than in the system prompt, so that the
+// prefix is the same across sessions and clusters.
+
+// blankLines matches runs of more than one blank line.
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// canonicalPrompt normalizes the whitespace of the system prompt, which templates and extra prompt files
+// often vary in: line endings, trailing spaces and runs of blank lines.
+func canonicalPrompt(prompt string) string {
+	prompt = strings.ReplaceAll(prompt, "\r\n", "\n")
+	lines := strings.Split(prompt, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	prompt = blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(prompt) + "\n"
+}
+
+// canonicalFunctionDefinitions returns copies of the function definitions with trimmed descriptions and
+// sorted required properties, as tools (MCP servers in particular) don't always list them in the same order.
+// Properties need no sorting, as JSON objects are serialized with sorted keys.
+func canonicalFunctionDefinitions(definitions []*gollm.FunctionDefinition) []*gollm.FunctionDefinition {
+	canonical := make([]*gollm.FunctionDefinition, 0, len(definitions))
+	for _, definition := range definitions {
+		canonical = append(canonical, &gollm.FunctionDefinition{
+			Name:        definition.Name,
+			Description: strings.TrimSpace(definition.Description),
+			Parameters:  canonicalSchema(definition.Parameters),
+		})
+	}
+	return canonical
+}
+
+func canonicalSchema(schema *gollm.Schema) *gollm.Schema {
+	if schema == nil {
+		return nil
+	}
+	canonical := &gollm.Schema{
+		Type:        schema.Type,
+		Items:       canonicalSchema(schema.Items),
+		Description: strings.TrimSpace(schema.Description),
+	}
+	if len(schema.Properties) > 0 {
+		canonical.Properties = make(map[string]*gollm.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			canonical.Properties[name] = canonicalSchema(property)
+		}
+	}
+	if len(schema.Required) > 0 {
+		canonical.Required = slices.Sorted(slices.Values(schema.Required))
+	}
+	return canonical
+}
+
+// functionDefinitionsHash returns a hash of the serialized function definitions, to tell whether two
+// sessions offered the LLM the same tools.
+func functionDefinitionsHash(definitions []*gollm.FunctionDefinition) string {
+	b, err := json.Marshal(definitions)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", sha256.Sum256(b))
+}
+
+// clusterFactsMessage describes the cluster for the first query, in the words of the default system prompt.
+func clusterFactsMessage(info *tools.ClusterInfo) string {
+	var sb strings.Builder
+	sb.WriteString("Facts about the target cluster, gathered when the session started. Only suggest API versions and features that this cluster supports.\n")
+	if info.Context != "" {
+		fmt.Fprintf(&sb, "- Current context: %s\n", info.Context)
+	}
+	if info.Namespace != "" {
+		fmt.Fprintf(&sb, "- Current namespace: %s\n", info.Namespace)
+	}
+	if info.ServerVersion != "" {
+		fmt.Fprintf(&sb, "- Kubernetes server version: %s\n", info.ServerVersion)
+	}
+	if info.NodeCount >= 0 {
+		fmt.Fprintf(&sb, "- Number of nodes: %d\n", info.NodeCount)
+	}
+	if len(info.APIGroupVersions) > 0 {
+		fmt.Fprintf(&sb, "- Enabled API group versions: %s\n", strings.Join(info.APIGroupVersions, ", "))
+	}
+	return sb.String()
+}
@@ -0,0 +1,62 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// SessionStats are counters for the conversation, since it was (re)initialized.
+type SessionStats struct {
+	// Started is when the conversation was initialized.
+	Started time.Time
+	// Rounds is the number of queries answered (or attempted).
+	Rounds int
+	// LLMRequests is the number of requests sent to the LLM.
+	LLMRequests int
+	// LLMTime is the time spent waiting for the LLM's responses.
+	LLMTime time.Duration
+	// ToolCalls is the number of tool calls that were run.
+	ToolCalls int
+	// InputTokens and OutputTokens are the tokens used, as reported by the LLM.
+	InputTokens  int
+	OutputTokens int
+	// CachedInputTokens are the input tokens read from the provider's prompt cache, if it reports them.
+	CachedInputTokens int
+}
+
+// Stats returns the counters for the conversation.
+func (a *Agent) Stats() SessionStats {
+	return a.stats
+}
+
+// add adds the counters of another session, e.g. one that ran a sub-task.
+func (s *SessionStats) add(other SessionStats) {
+	s.LLMRequests += other.LLMRequests
+	s.LLMTime += other.LLMTime
+	s.ToolCalls += other.ToolCalls
+	s.InputTokens += other.InputTokens
+	s.OutputTokens += other.OutputTokens
+	s.CachedInputTokens += other.CachedInputTokens
+}
+
+// addUsage adds the token usage of an LLM response to the counters.
+func (s *SessionStats) addUsage(usage gollm.Usage) {
+	s.InputTokens += usage.InputTokens
+	s.OutputTokens += usage.OutputTokens
+	s.CachedInputTokens += usage.CachedInputTokens
+}
@@ -0,0 +1,100 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// maxSummarizedBytes bounds the output sent to the LLM to be summarized; the middle of longer output is left out.
+	maxSummarizedBytes = 400_000
+	// maxKeptErrorLines is how many error lines are kept verbatim alongside a summary.
+	maxKeptErrorLines = 50
+)
+
+// summarizeOutput returns the output of a tool call to send to the LLM. If the output of a command is longer than
+// SummarizeOutputBytes, it is replaced by a summary written by the LLM, followed by the lines that report errors,
+// verbatim, so that a long `kubectl describe` does not fill the context window. Other output is returned unchanged,
+// as is the output if it cannot be summarized.
+func (c *Agent) summarizeOutput(ctx context.Context, query string, description string, output any) any {
+	execResult, ok := output.(*tools.ExecResult)
+	if !ok || execResult == nil || c.SummarizeOutputBytes <= 0 {
+		return output
+	}
+	if len(execResult.Stdout) <= c.SummarizeOutputBytes && len(execResult.Stderr) <= c.SummarizeOutputBytes {
+		return output
+	}
+
+	summarized := *execResult
+	for _, text := range []*string{&summarized.Stdout, &summarized.Stderr} {
+		if len(*text) <= c.SummarizeOutputBytes {
+			continue
+		}
+		summary, err := c.summarizeText(ctx, query, description, *text)
+		if err != nil {
+			klog.FromContext(ctx).Error(err, "summarizing tool output", "command", description)
+			return output
+		}
+		c.doc.AddBlock(ui.NewAgentTextBlock().WithText(fmt.Sprintf("  Summarized %d bytes of output for the model\n", len(*text))))
+		*text = summary
+	}
+	return &summarized
+}
+
+// summarizeText asks the LLM to summarize the output of a command, and appends the lines that report errors.
+func (c *Agent) summarizeText(ctx context.Context, query string, description string, text string) (string, error) {
+	prompt := fmt.Sprintf(`You are helping an assistant that is answering a kubernetes operator's question.
+The user asked: %q
+
+The assistant ran %s, and the output is too long to read in full. Summarize the output below, so that the assistant can answer the question from the summary alone.
+- Keep the name, namespace and kind of every resource mentioned, and the status of each.
+- Keep every error, warning and unhealthy status verbatim.
+- Keep numbers, versions, images and timestamps that could matter to the question.
+- Leave out repetitive and healthy detail, saying how much was left out (e.g. "42 other pods are Running").
+Reply with the summary only.
+
+Output:
+%s`, query, description, tools.ElideMiddle(text, maxSummarizedBytes))
+
+	response, err := c.LLM.GenerateCompletion(ctx, &gollm.CompletionRequest{
+		Model:  c.Model,
+		Prompt: prompt,
+	})
+	if err != nil {
+		return "", err
+	}
+	summary := strings.TrimSpace(response.Response())
+	if summary == "" {
+		return "", fmt.Errorf("the LLM returned an empty summary")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "[The output was %d bytes (%d lines), so it was summarized.]\n%s\n", len(text), strings.Count(text, "\n")+1, summary)
+	if errorLines := tools.ErrorLines(text, maxKeptErrorLines); len(errorLines) > 0 {
+		b.WriteString("\nLines of the output that report errors, verbatim:\n")
+		for _, line := range errorLines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"fmt"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+// toolFailed counts a tool call that could not be run, and tells the user. It returns the result that tells the LLM,
+// so that it can retry with corrected arguments, and whether too many calls failed in a row to go on.
+func (a *Agent) toolFailed(call gollm.FunctionCall, description string, err error) (any, bool) {
+	a.roundToolErrors++
+	a.doc.AddBlock(ui.NewErrorBlock().SetText(fmt.Sprintf("  Error running %s: %v\n", description, err)))
+
+	message := fmt.Sprintf("The tool call failed: %v. Check the tool name and arguments, and try again with corrected ones.", err)
+	if a.EnableToolUseShim {
+		return fmt.Sprintf("Result of running %q:\n%s", call.Name, message), a.tooManyToolErrors()
+	}
+	return gollm.FunctionCallResult{
+		ID:   call.ID,
+		Name: call.Name,
+		Result: map[string]any{
+			"error":     message,
+			"status":    "failed",
+			"retryable": true,
+		},
+	}, a.tooManyToolErrors()
+}
+
+// tooManyToolErrors returns whether the last MaxToolErrors tool calls failed.
+func (a *Agent) tooManyToolErrors() bool {
+	return a.MaxToolErrors > 0 && a.roundToolErrors >= a.MaxToolErrors
+}
@@ -0,0 +1,83 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/tools"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// verifyMutation runs read-only commands that show whether the changes made by a tool call took effect,
+// e.g. `kubectl get` and `kubectl rollout status` of the touched resources.
+// It returns a note for the LLM with their output, or "" if we don't know how to verify the call.
+func (a *Agent) verifyMutation(ctx context.Context, call gollm.FunctionCall, output any) string {
+	log := klog.FromContext(ctx)
+
+	if execResult, ok := output.(*tools.ExecResult); ok && execResult != nil && (execResult.Error != "" || execResult.ExitCode != 0) {
+		// The change failed, the LLM will see the error anyway.
+		return ""
+	}
+	command, ok := call.Arguments["command"].(string)
+	if !ok {
+		return ""
+	}
+	commands := tools.VerificationCommands(command)
+	if len(commands) == 0 || a.Tools.Lookup("kubectl") == nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("The previous command modified resources. To verify that the change took effect, these commands were run:\n")
+	for _, command := range commands {
+		toolCall, err := a.Tools.ParseToolInvocation(ctx, "kubectl", map[string]any{"command": command})
+		if err != nil {
+			log.Error(err, "building verification tool call", "command", command)
+			continue
+		}
+
+		block := ui.NewFunctionCallRequestBlock().SetDescription(command)
+		a.doc.AddBlock(block)
+
+		toolCtx := journal.ContextWithRecorder(ctx, a.Recorder)
+		cancel := func() {}
+		if a.ToolTimeout > 0 {
+			toolCtx, cancel = context.WithTimeout(toolCtx, a.ToolTimeout)
+		}
+		result, err := toolCall.InvokeTool(toolCtx, tools.InvokeToolOptions{
+			Kubeconfig: a.Kubeconfig,
+			WorkDir:    a.workDir,
+		})
+		cancel()
+		if err != nil {
+			result = &tools.ExecResult{Command: command, Error: err.Error()}
+		}
+		block.SetResult(result)
+
+		text := fmt.Sprintf("%v", result)
+		if textResult, ok := result.(ui.CanFormatAsText); ok {
+			text = textResult.FormatAsText()
+		}
+		fmt.Fprintf(&sb, "\n$ %s\n%s\n", command, strings.TrimSpace(text))
+	}
+	sb.WriteString("\nCheck that this output matches the intended change. If it does not, tell the user about the discrepancy.")
+	return sb.String()
+}
@@ -0,0 +1,188 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"syscall"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// WorkDirRetention is the policy that decides whether the working directory of a session is kept when the
+// session ends.
+type WorkDirRetention string
+
+const (
+	// WorkDirKeep keeps the working directory, so generated manifests and checkpoints can be inspected later.
+	WorkDirKeep WorkDirRetention = "keep"
+	// WorkDirKeepOnError keeps the working directory only if a round of the session failed.
+	WorkDirKeepOnError WorkDirRetention = "keep-on-error"
+	// WorkDirRemove removes the working directory when the session ends.
+	WorkDirRemove WorkDirRetention = "remove"
+)
+
+// keep reports whether a working directory is kept when its session ends.
+func (r WorkDirRetention) keep(failed bool) bool {
+	switch r {
+	case WorkDirRemove:
+		return false
+	case WorkDirKeepOnError:
+		return failed
+	}
+	return true
+}
+
+// sessionInfoFile is the file in the working directory that describes the session it belongs to.
+const sessionInfoFile = "session.json"
+
+// WorkDirInfo describes the session a working directory belongs to.
+type WorkDirInfo struct {
+	SessionID string    `json:"sessionID"`
+	Model     string    `json:"model"`
+	PID       int       `json:"pid"`
+	Started   time.Time `json:"started"`
+	// Ended is zero while the session is running, or if it crashed.
+	Ended time.Time `json:"ended,omitzero"`
+	// Failed is set if a round of the session failed.
+	Failed bool `json:"failed,omitempty"`
+}
+
+// writeWorkDirInfo writes the description of the session to its working directory.
+func (a *Agent) writeWorkDirInfo(ended time.Time) {
+	info := &WorkDirInfo{
+		SessionID: a.SessionID,
+		Model:     a.Model,
+		PID:       os.Getpid(),
+		Started:   a.stats.Started,
+		Ended:     ended,
+		Failed:    a.failed,
+	}
+	b, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		klog.Warningf("error marshalling session info: %v", err)
+		return
+	}
+	if err := os.WriteFile(filepath.Join(a.workDir, sessionInfoFile), b, 0o600); err != nil {
+		klog.Warningf("error writing session info: %v", err)
+	}
+}
+
+// WorkDir is a working directory left by a session on this machine.
+type WorkDir struct {
+	Path string
+	// Info describes the session; it is nil for directories written by older versions.
+	Info *WorkDirInfo
+	// Modified is the last time a file in the directory was changed.
+	Modified time.Time
+	Files    int
+	Size     int64
+}
+
+// Running reports whether the session of the working directory is still running.
+func (w *WorkDir) Running() bool {
+	if w.Info == nil || !w.Info.Ended.IsZero() || w.Info.PID <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(w.Info.PID)
+	if err != nil {
+		return false
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// ListWorkDirs returns the working directories of the sessions on this machine, the most recently modified first.
+func ListWorkDirs() ([]*WorkDir, error) {
+	paths, err := filepath.Glob(filepath.Join(os.TempDir(), workDirPattern))
+	if err != nil {
+		return nil, err
+	}
+	var dirs []*WorkDir
+	for _, p := range paths {
+		dir, err := readWorkDir(p)
+		if err != nil {
+			klog.Warningf("error reading working directory %q: %v", p, err)
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	slices.SortFunc(dirs, func(a, b *WorkDir) int { return b.Modified.Compare(a.Modified) })
+	return dirs, nil
+}
+
+func readWorkDir(path string) (*WorkDir, error) {
+	dir := &WorkDir{Path: path}
+	err := filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().After(dir.Modified) {
+			dir.Modified = info.ModTime()
+		}
+		if !d.IsDir() {
+			dir.Files++
+			dir.Size += info.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(filepath.Join(path, sessionInfoFile))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return dir, nil
+		}
+		return nil, err
+	}
+	dir.Info = &WorkDirInfo{}
+	if err := json.Unmarshal(b, dir.Info); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", sessionInfoFile, err)
+	}
+	return dir, nil
+}
+
+// CleanWorkDirs removes the working directories not modified for olderThan (all of them if it is zero),
+// skipping those of sessions that are still running. It returns the removed directories.
+func CleanWorkDirs(olderThan time.Duration) ([]*WorkDir, error) {
+	dirs, err := ListWorkDirs()
+	if err != nil {
+		return nil, err
+	}
+	var removed []*WorkDir
+	var errs []error
+	for _, dir := range dirs {
+		if dir.Running() || time.Since(dir.Modified) < olderThan {
+			continue
+		}
+		if err := os.RemoveAll(dir.Path); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, dir)
+	}
+	return removed, errors.Join(errs...)
+}
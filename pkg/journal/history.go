@@ -0,0 +1,131 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+const (
+	RoleUser  = "user"
+	RoleModel = "model"
+)
+
+// HistoryEntry is a single turn of a chat, reconstructed from journal events.
+type HistoryEntry struct {
+	// Role is either RoleUser or RoleModel.
+	Role string
+
+	// Timestamp is when the turn started, as recorded in the journal.
+	Timestamp time.Time
+
+	// Messages are the text messages of the turn.
+	Messages []string
+
+	// FunctionCalls are the function calls requested by the model.
+	FunctionCalls []gollm.FunctionCall
+
+	// FunctionCallResults are the results of function calls, sent by the user turn.
+	FunctionCallResults []gollm.FunctionCallResult
+
+	// Query is the query of the user if the turn starts a round; it is one of Messages,
+	// which can also hold other messages, such as the answer style, or tool results with the tool use shim.
+	Query string
+}
+
+// ReconstructHistory rebuilds the chat history from the user-query, llm-chat and llm-response events in a journal.
+// Streamed responses are merged into a single model turn.
+func ReconstructHistory(events []*Event) ([]*HistoryEntry, error) {
+	var history []*HistoryEntry
+	// query is the query of the round started by the next llm-chat event.
+	query := ""
+
+	for _, event := range events {
+		switch event.Action {
+		case ActionUserQuery:
+			query, _ = event.GetString("query")
+
+		case ActionLLMChat:
+			entry := &HistoryEntry{Role: RoleUser, Timestamp: event.Timestamp, Query: query}
+			query = ""
+			if err := addChatContents(entry, event.Payload); err != nil {
+				return nil, fmt.Errorf("parsing %s event at %v: %w", event.Action, event.Timestamp, err)
+			}
+			history = append(history, entry)
+
+		case ActionLLMResponse:
+			var response gollm.RecordChatResponse
+			if err := convertPayload(event.Payload, &response); err != nil {
+				return nil, fmt.Errorf("parsing %s event at %v: %w", event.Action, event.Timestamp, err)
+			}
+			if len(history) == 0 || history[len(history)-1].Role != RoleModel {
+				history = append(history, &HistoryEntry{Role: RoleModel, Timestamp: event.Timestamp})
+			}
+			entry := history[len(history)-1]
+			if response.Text != "" {
+				// Streamed text arrives in chunks; join them into one message.
+				if len(entry.Messages) == 0 {
+					entry.Messages = append(entry.Messages, "")
+				}
+				entry.Messages[len(entry.Messages)-1] += response.Text
+			}
+			entry.FunctionCalls = append(entry.FunctionCalls, response.FunctionCalls...)
+		}
+	}
+
+	return history, nil
+}
+
+// addChatContents adds the contents sent to the LLM to entry.
+// The payload is a (possibly nested) list of strings and function call results.
+func addChatContents(entry *HistoryEntry, payload any) error {
+	switch v := payload.(type) {
+	case nil:
+		return nil
+	case string:
+		entry.Messages = append(entry.Messages, v)
+	case []any:
+		for _, item := range v {
+			if err := addChatContents(entry, item); err != nil {
+				return err
+			}
+		}
+	case map[string]any:
+		var result gollm.FunctionCallResult
+		if err := convertPayload(v, &result); err != nil {
+			return err
+		}
+		entry.FunctionCallResults = append(entry.FunctionCallResults, result)
+	default:
+		return fmt.Errorf("unexpected chat content of type %T", payload)
+	}
+	return nil
+}
+
+// convertPayload converts a generic payload (as parsed from the journal) to a typed value.
+func convertPayload(payload any, out any) error {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshalling payload: %w", err)
+	}
+	if err := json.Unmarshal(b, out); err != nil {
+		return fmt.Errorf("unmarshalling payload: %w", err)
+	}
+	return nil
+}
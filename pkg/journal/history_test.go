@@ -0,0 +1,120 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package journal
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func TestReconstructHistory(t *testing.T) {
+	t0 := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return t0.Add(time.Duration(seconds) * time.Second) }
+
+	// The payloads are as parsed from a journal file.
+	tests := []struct {
+		name    string
+		events  []*Event
+		want    []*HistoryEntry
+		wantErr bool
+	}{
+		{
+			name: "streamed answer",
+			events: []*Event{
+				{Timestamp: at(0), Action: ActionUserQuery, Payload: map[string]any{"query": "are the pods running?"}},
+				{Timestamp: at(1), Action: ActionLLMChat, Payload: []any{[]any{"are the pods running?"}}},
+				{Timestamp: at(2), Action: ActionLLMResponse, Payload: map[string]any{"text": "All pods "}},
+				{Timestamp: at(3), Action: ActionLLMResponse, Payload: map[string]any{"text": "are running."}},
+			},
+			want: []*HistoryEntry{
+				{Role: RoleUser, Timestamp: at(1), Messages: []string{"are the pods running?"}, Query: "are the pods running?"},
+				{Role: RoleModel, Timestamp: at(2), Messages: []string{"All pods are running."}},
+			},
+		},
+		{
+			name: "function call",
+			events: []*Event{
+				{Timestamp: at(0), Action: ActionUserQuery, Payload: map[string]any{"query": "list pods"}},
+				{Timestamp: at(1), Action: ActionLLMChat, Payload: []any{[]any{"list pods", "Answer briefly."}}},
+				{Timestamp: at(2), Action: ActionLLMResponse, Payload: map[string]any{
+					"functionCalls": []any{map[string]any{"id": "call-1", "name": "kubectl", "arguments": map[string]any{"command": "kubectl get pods"}}},
+				}},
+				{Timestamp: at(3), Action: ActionLLMChat, Payload: []any{[]any{
+					map[string]any{"id": "call-1", "name": "kubectl", "result": map[string]any{"stdout": "web-0 Running"}},
+				}}},
+				{Timestamp: at(4), Action: ActionLLMResponse, Payload: map[string]any{"text": "web-0 is running."}},
+			},
+			want: []*HistoryEntry{
+				{Role: RoleUser, Timestamp: at(1), Messages: []string{"list pods", "Answer briefly."}, Query: "list pods"},
+				{Role: RoleModel, Timestamp: at(2), FunctionCalls: []gollm.FunctionCall{
+					{ID: "call-1", Name: "kubectl", Arguments: map[string]any{"command": "kubectl get pods"}},
+				}},
+				{Role: RoleUser, Timestamp: at(3), FunctionCallResults: []gollm.FunctionCallResult{
+					{ID: "call-1", Name: "kubectl", Result: map[string]any{"stdout": "web-0 Running"}},
+				}},
+				{Role: RoleModel, Timestamp: at(4), Messages: []string{"web-0 is running."}},
+			},
+		},
+		{
+			name: "journal without queries",
+			events: []*Event{
+				{Timestamp: at(0), Action: ActionLLMChat, Payload: []any{[]any{"are the pods running?"}}},
+				{Timestamp: at(1), Action: ActionLLMResponse, Payload: map[string]any{"text": "Yes."}},
+			},
+			want: []*HistoryEntry{
+				{Role: RoleUser, Timestamp: at(0), Messages: []string{"are the pods running?"}},
+				{Role: RoleModel, Timestamp: at(1), Messages: []string{"Yes."}},
+			},
+		},
+		{
+			name: "other events are ignored",
+			events: []*Event{
+				{Timestamp: at(0), Action: ActionUserQuery, Payload: map[string]any{"query": "hi"}},
+				{Timestamp: at(1), Action: ActionUIRender, Payload: map[string]any{"text": "thinking"}},
+				{Timestamp: at(2), Action: ActionLLMChat, Payload: []any{[]any{"hi"}}},
+			},
+			want: []*HistoryEntry{
+				{Role: RoleUser, Timestamp: at(2), Messages: []string{"hi"}, Query: "hi"},
+			},
+		},
+		{
+			name: "invalid chat contents",
+			events: []*Event{
+				{Timestamp: at(0), Action: ActionLLMChat, Payload: []any{42}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReconstructHistory(tt.events)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ReconstructHistory() = %v, expected an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReconstructHistory() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReconstructHistory() =\n%+v\nexpected\n%+v", got, tt.want)
+			}
+		})
+	}
+}
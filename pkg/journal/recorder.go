@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync"
 	"time"
 
 	"sigs.k8s.io/yaml"
@@ -34,8 +35,10 @@ type Recorder interface {
 }
 
 // FileRecorder writes a structured log of the agent's actions and observations to a file.
+// It is safe for concurrent use, e.g. by the conversations of a batch run.
 type FileRecorder struct {
-	f *os.File
+	mu sync.Mutex
+	f  *os.File
 }
 
 // NewFileRecorder creates a new FileRecorder that writes to the given file.
@@ -66,6 +69,8 @@ func (r *FileRecorder) Write(ctx context.Context, event *Event) error {
 	var b bytes.Buffer
 	b.Write(yamlBytes)
 	b.Write([]byte("\n\n---\n\n"))
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	_, err = r.f.Write(b.Bytes())
 	return err
 }
@@ -79,6 +84,23 @@ type Event struct {
 // ActionUIRender is for an event that indicates we wrote output to the UI
 const ActionUIRender = "ui.render"
 
+// ActionLLMChat records the contents sent to the LLM; the payload is a list of user messages and function call results.
+const ActionLLMChat = "llm-chat"
+
+// ActionUserQuery records the query of the user that starts a round, ahead of the llm-chat event that sends it;
+// the payload has the query.
+const ActionUserQuery = "user-query"
+
+// ActionLLMResponse records a (streamed) response from the LLM; the payload is a gollm.RecordChatResponse.
+const ActionLLMResponse = "llm-response"
+
+// ActionSystemPrompt records the system prompt a chat was started with; the payload has the name of the prompt profile
+// and the SHA-256 of the prompt.
+const ActionSystemPrompt = "system-prompt"
+
+// ActionFeedback records the user's rating of an answer; the payload is an agent.Feedback.
+const ActionFeedback = "feedback"
+
 // GetString is a helper to get a string value from the Payload
 func (e *Event) GetString(key string) (string, bool) {
 	if e.Payload == nil {
@@ -19,6 +19,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 
 	"k8s.io/klog/v2"
@@ -234,6 +235,20 @@ func atomicWriteFile(path string, data []byte, perm os.FileMode) error {
 	return os.Rename(tmpPath, path)
 }
 
+// SelectServers keeps only the servers with the given names, and returns an error if one of them is not configured.
+func (c *Config) SelectServers(names []string) error {
+	var servers []ServerConfig
+	for _, name := range names {
+		i := slices.IndexFunc(c.Servers, func(server ServerConfig) bool { return server.Name == name })
+		if i < 0 {
+			return fmt.Errorf("MCP server %q is not configured", name)
+		}
+		servers = append(servers, c.Servers[i])
+	}
+	c.Servers = servers
+	return nil
+}
+
 // ===================================================================
 // Configuration validation functions
 // ===================================================================
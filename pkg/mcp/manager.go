@@ -17,6 +17,9 @@ package mcp
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -265,8 +268,12 @@ func (m *Manager) RegisterTools(ctx context.Context, registerCallback func(serve
 		return err
 	}
 
+	// Register the tools in a stable order, so that the tools offered to the LLM don't change between runs.
 	toolCount := 0
-	for serverName, tools := range serverTools {
+	for _, serverName := range slices.Sorted(maps.Keys(serverTools)) {
+		tools := slices.SortedFunc(slices.Values(serverTools[serverName]), func(a, b Tool) int {
+			return strings.Compare(a.Name, b.Name)
+		})
 		for _, toolInfo := range tools {
 			// Use the callback to register each tool
 			if err := registerCallback(serverName, toolInfo); err != nil {
@@ -294,15 +301,21 @@ func (m *Manager) GetStatus(ctx context.Context, mcpClientEnabled bool) (*MCPSta
 		ClientEnabled: mcpClientEnabled,
 	}
 
-	mcpConfigPath, err := DefaultConfigPath()
-	if err != nil {
-		klog.V(2).Infof("Failed to get MCP config path: %v", err)
-		return status, nil // Return empty status
-	}
+	// The manager's config only has the servers selected for the session, if any were.
+	var mcpConfig *Config
+	if m != nil && m.config != nil {
+		mcpConfig = m.config
+	} else {
+		mcpConfigPath, err := DefaultConfigPath()
+		if err != nil {
+			klog.V(2).Infof("Failed to get MCP config path: %v", err)
+			return status, nil // Return empty status
+		}
 
-	mcpConfig, err := LoadConfig(mcpConfigPath)
-	if err != nil {
-		return status, nil // Return empty status
+		mcpConfig, err = LoadConfig(mcpConfigPath)
+		if err != nil {
+			return status, nil // Return empty status
+		}
 	}
 
 	status.TotalServers = len(mcpConfig.Servers)
@@ -322,6 +335,7 @@ func (m *Manager) GetStatus(ctx context.Context, mcpClientEnabled bool) (*MCPSta
 		toolsCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 		defer cancel()
 
+		var err error
 		serverTools, err = m.ListAvailableTools(toolsCtx)
 		if err != nil {
 			klog.V(2).InfoS("Failed to get tools from MCP manager", "error", err)
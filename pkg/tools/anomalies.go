@@ -0,0 +1,122 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// anomalousRestarts is the number of restarts from which a container is flagged.
+	anomalousRestarts = 3
+	// maxAnomalies is the most anomalies reported for an output.
+	maxAnomalies = 20
+)
+
+var (
+	// anomalousStatePattern matches the states and reasons of containers, pods and nodes that are likely causes of problems.
+	anomalousStatePattern = regexp.MustCompile(`\b(OOMKilled|CrashLoopBackOff|ImagePullBackOff|ErrImagePull|InvalidImageName|CreateContainerConfigError|CreateContainerError|RunContainerError|ContainerStatusUnknown|Evicted|NotReady|FailedScheduling|FailedMount|FailedAttachVolume)\b`)
+	// pressureConditionPattern matches the node conditions that are unhealthy when true, as shown by kubectl describe node.
+	pressureConditionPattern = regexp.MustCompile(`\b(MemoryPressure|DiskPressure|PIDPressure|NetworkUnavailable)\s+True\b`)
+	// restartCountPattern matches the restart count of a container in kubectl describe pod, or in JSON output.
+	restartCountPattern = regexp.MustCompile(`(?:Restart Count:\s+|"restartCount":\s*)(\d+)`)
+)
+
+// Anomaly is a line of the output of a command with values that are likely to matter, such as a container that was OOMKilled.
+type Anomaly struct {
+	// Line is the line, trimmed of surrounding space.
+	Line string `json:"line"`
+	// Reasons say what is anomalous, e.g. "OOMKilled" or "12 restarts".
+	Reasons []string `json:"reasons"`
+}
+
+func (a Anomaly) String() string {
+	return fmt.Sprintf("%s: %s", strings.Join(a.Reasons, ", "), a.Line)
+}
+
+// DetectAnomalies returns the lines of output, typically of kubectl get or describe, that show restarting containers,
+// failing containers or pods (e.g. OOMKilled or ImagePullBackOff), nodes that are NotReady and pressure conditions.
+// It returns at most maxAnomalies, in order.
+func DetectAnomalies(output string) []Anomaly {
+	var anomalies []Anomaly
+	// restartsColumn is the index of the RESTARTS column of the table being read, or -1.
+	restartsColumn := -1
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			restartsColumn = -1
+			continue
+		}
+		if isTableHeader(fields) {
+			restartsColumn = -1
+			for i, field := range fields {
+				if field == "RESTARTS" {
+					restartsColumn = i
+				}
+			}
+			continue
+		}
+
+		var reasons []string
+		for _, match := range anomalousStatePattern.FindAllString(line, -1) {
+			if !slices.Contains(reasons, match) {
+				reasons = append(reasons, match)
+			}
+		}
+		for _, match := range pressureConditionPattern.FindAllStringSubmatch(line, -1) {
+			reasons = append(reasons, match[1])
+		}
+		restarts := -1
+		if match := restartCountPattern.FindStringSubmatch(line); match != nil {
+			restarts, _ = strconv.Atoi(match[1])
+		} else if restartsColumn >= 0 && restartsColumn < len(fields) {
+			if n, err := strconv.Atoi(fields[restartsColumn]); err == nil {
+				restarts = n
+			}
+		}
+		if restarts >= anomalousRestarts {
+			reasons = append(reasons, fmt.Sprintf("%d restarts", restarts))
+		}
+
+		line = strings.TrimSpace(line)
+		if len(reasons) == 0 || seen[line] {
+			continue
+		}
+		seen[line] = true
+		anomalies = append(anomalies, Anomaly{Line: line, Reasons: reasons})
+		if len(anomalies) == maxAnomalies {
+			break
+		}
+	}
+	return anomalies
+}
+
+// isTableHeader reports whether the fields are the header of a table printed by kubectl, e.g. "NAME READY STATUS".
+func isTableHeader(fields []string) bool {
+	if fields[0] != "NAME" && fields[0] != "NAMESPACE" {
+		return false
+	}
+	for _, field := range fields {
+		if strings.ToUpper(field) != field {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	testCases := []struct {
+		name     string
+		output   string
+		expected []Anomaly
+	}{
+		{
+			name: "get pods",
+			output: `NAME                   READY   STATUS             RESTARTS       AGE
+web-7d4b9c8f6d-abcde   1/1     Running            0              3d
+web-7d4b9c8f6d-fghij   0/1     CrashLoopBackOff   12 (2m ago)    3d
+api-5f6d7c8b9-klmno    1/1     Running            4 (1h ago)     2d
+worker-0               0/1     ImagePullBackOff   0              5m`,
+			expected: []Anomaly{
+				{Line: "web-7d4b9c8f6d-fghij   0/1     CrashLoopBackOff   12 (2m ago)    3d", Reasons: []string{"CrashLoopBackOff", "12 restarts"}},
+				{Line: "api-5f6d7c8b9-klmno    1/1     Running            4 (1h ago)     2d", Reasons: []string{"4 restarts"}},
+				{Line: "worker-0               0/1     ImagePullBackOff   0              5m", Reasons: []string{"ImagePullBackOff"}},
+			},
+		},
+		{
+			name: "get pods in all namespaces",
+			output: `NAMESPACE   NAME    READY   STATUS    RESTARTS   AGE
+shop        web-0   1/1     Running   7          1d
+kube-system dns-0   1/1     Running   1          9d`,
+			expected: []Anomaly{
+				{Line: "shop        web-0   1/1     Running   7          1d", Reasons: []string{"7 restarts"}},
+			},
+		},
+		{
+			name: "get nodes",
+			output: `NAME     STATUS                     ROLES    AGE   VERSION
+node-1   Ready                      <none>   10d   v1.30.1
+node-2   NotReady                   <none>   10d   v1.30.1
+node-3   Ready,SchedulingDisabled   <none>   10d   v1.30.1`,
+			expected: []Anomaly{
+				{Line: "node-2   NotReady                   <none>   10d   v1.30.1", Reasons: []string{"NotReady"}},
+			},
+		},
+		{
+			name: "describe pod",
+			output: `    Last State:     Terminated
+      Reason:       OOMKilled
+      Exit Code:    137
+    Ready:          True
+    Restart Count:  5
+    Restart Count:  1`,
+			expected: []Anomaly{
+				{Line: "Reason:       OOMKilled", Reasons: []string{"OOMKilled"}},
+				{Line: "Restart Count:  5", Reasons: []string{"5 restarts"}},
+			},
+		},
+		{
+			name: "describe node",
+			output: `  Type             Status  LastHeartbeatTime                 Reason
+  MemoryPressure   True    Sun, 01 Jun 2025 14:00:00 +0000   KubeletHasInsufficientMemory
+  DiskPressure     False   Sun, 01 Jun 2025 14:00:00 +0000   KubeletHasNoDiskPressure`,
+			expected: []Anomaly{
+				{Line: "MemoryPressure   True    Sun, 01 Jun 2025 14:00:00 +0000   KubeletHasInsufficientMemory", Reasons: []string{"MemoryPressure"}},
+			},
+		},
+		{
+			name:     "json",
+			output:   `{"name": "web", "restartCount": 9, "ready": false}`,
+			expected: []Anomaly{{Line: `{"name": "web", "restartCount": 9, "ready": false}`, Reasons: []string{"9 restarts"}}},
+		},
+		{
+			name: "healthy",
+			output: `NAME    READY   STATUS    RESTARTS   AGE
+web-0   1/1     Running   0          1d`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			anomalies := DetectAnomalies(tc.output)
+			if !reflect.DeepEqual(anomalies, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, anomalies)
+			}
+		})
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"k8s.io/klog/v2"
+)
+
+func init() {
+	RegisterTool(&ApplyTool{})
+}
+
+// ApplyFieldManager is the field manager of the changes applied by the apply_manifest tool,
+// so that they can be told apart from changes made by others, e.g. with `kubectl get --show-managed-fields`.
+const ApplyFieldManager = "kubectl-ai"
+
+// ApplyTool applies manifests with server-side apply. Fields owned by other managers are not taken over:
+// the conflicts are reported, with a conflict_id that the LLM must pass back, after the user has seen them,
+// to force the apply.
+type ApplyTool struct{}
+
+func (t *ApplyTool) Name() string {
+	return "apply_manifest"
+}
+
+func (t *ApplyTool) Description() string {
+	return `Creates or updates Kubernetes resources from a manifest, with server-side apply under the "` + ApplyFieldManager + `" field manager.
+Prefer this tool over kubectl apply in bash, in particular for large manifests: the manifest is passed as is, without shell quoting.
+If fields are owned by another manager (e.g. a controller, helm or another user), nothing is applied: the conflicts are returned with a conflict_id.
+Show the conflicts to the user, and only if they agree, call the tool again with the same arguments, force_conflicts and the conflict_id to take the fields over.`
+}
+
+func (t *ApplyTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"manifest": {
+					Type:        gollm.TypeString,
+					Description: `The manifest to apply, in YAML or JSON; several resources can be separated with "---".`,
+				},
+				"path": {
+					Type:        gollm.TypeString,
+					Description: `Instead of manifest, a file or directory of manifests to apply, e.g. one written earlier in the working directory.`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of resources that don't specify one. Defaults to the current namespace.`,
+				},
+				"force_conflicts": {
+					Type:        gollm.TypeBoolean,
+					Description: `Take over the fields owned by other managers. Requires conflict_id; only set this if the user agreed.`,
+				},
+				"conflict_id": {
+					Type:        gollm.TypeString,
+					Description: `The conflict_id returned when the apply reported conflicts.`,
+				},
+			},
+		},
+	}
+}
+
+// ApplyResult is the result of the apply_manifest tool.
+type ApplyResult struct {
+	// Applied is true if the manifest was applied (or, in simulate mode, would be).
+	Applied      bool   `json:"applied"`
+	FieldManager string `json:"field_manager"`
+	// Objects are the applied objects, e.g. "deployment.apps/web serverside-applied".
+	Objects []string `json:"objects,omitempty"`
+	// Conflicts are the fields owned by other managers, if the apply was refused because of them.
+	Conflicts  []ApplyConflict `json:"conflicts,omitempty"`
+	ConflictID string          `json:"conflict_id,omitempty"`
+	// Diff is what the apply would change, in simulate mode.
+	Diff    string `json:"diff,omitempty"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ApplyConflict is a field that the apply would change, owned by another manager.
+type ApplyConflict struct {
+	Manager string `json:"manager"`
+	Field   string `json:"field"`
+}
+
+func (t *ApplyTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ApplyResult]()
+}
+
+// applySource is what to apply: a manifest passed on stdin, or a path.
+type applySource struct {
+	manifest string
+	path     string
+}
+
+// args returns the kubectl apply arguments for the source and namespace, with the extra arguments.
+func (s applySource) args(namespace string, extra ...string) []string {
+	args := []string{"apply", "--server-side", "--field-manager=" + ApplyFieldManager}
+	if s.path != "" {
+		args = append(args, "-f", s.path)
+	} else {
+		args = append(args, "-f", "-")
+	}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	return append(args, extra...)
+}
+
+func (s applySource) run(ctx context.Context, args []string) (*ExecResult, error) {
+	if s.path != "" {
+		return runKubectl(ctx, args...)
+	}
+	return runKubectlWithInput(ctx, strings.NewReader(s.manifest), args...)
+}
+
+func parseApplyArgs(args map[string]any) (applySource, string, error) {
+	manifest, _ := args["manifest"].(string)
+	source := applySource{manifest: manifest, path: stringArg(args, "path")}
+	switch {
+	case strings.TrimSpace(source.manifest) == "" && source.path == "":
+		return source, "", fmt.Errorf("either manifest or path is required")
+	case strings.TrimSpace(source.manifest) != "" && source.path != "":
+		return source, "", fmt.Errorf("give either manifest or path, not both")
+	case strings.Contains(source.path, "://"):
+		return source, "", fmt.Errorf("path must be a local file or directory, not a URL")
+	}
+	return source, stringArg(args, "namespace"), nil
+}
+
+func (t *ApplyTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &ApplyResult{FieldManager: ApplyFieldManager}
+	source, namespace, err := parseApplyArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	var extra []string
+	if boolArg(args, "force_conflicts") {
+		// Only take fields over if they are the conflicts the user was shown.
+		dryRun, err := source.run(ctx, source.args(namespace, "--dry-run=server"))
+		if err != nil {
+			return nil, err
+		}
+		conflicts := parseApplyConflicts(dryRun.Stderr)
+		if len(conflicts) > 0 {
+			if id := stringArg(args, "conflict_id"); id != applyConflictID(source, namespace, conflicts) {
+				result.Conflicts = conflicts
+				result.ConflictID = applyConflictID(source, namespace, conflicts)
+				result.Error = "the conflicts differ from the ones with the given conflict_id (or it is missing); show these conflicts to the user before forcing the apply"
+				return result, nil
+			}
+			extra = append(extra, "--force-conflicts")
+		}
+	}
+
+	// Server-side apply doesn't tell created objects from updated ones, so we look for them before and after.
+	ledger := ledgerFromContext(ctx)
+	var existing []workloadObject
+	if ledger != nil {
+		if existing, err = source.objects(ctx, namespace); err != nil {
+			klog.Warningf("Not recording the objects created by apply_manifest: %v", err)
+			ledger = nil
+		}
+	}
+
+	out, err := source.run(ctx, source.args(namespace, extra...))
+	if err != nil {
+		return nil, err
+	}
+	result = applyOutcome(result, source, namespace, out)
+	if result.Applied {
+		source.attribute(ctx, namespace)
+	}
+	if ledger != nil {
+		ledger.add(source.created(ctx, namespace, existing)...)
+	}
+	return result, nil
+}
+
+// objects returns the objects of the source that exist in the cluster.
+func (s applySource) objects(ctx context.Context, namespace string) ([]workloadObject, error) {
+	args := []string{"get", "--ignore-not-found", "-o", "json"}
+	if s.path != "" {
+		args = append(args, "-f", s.path)
+	} else {
+		args = append(args, "-f", "-")
+	}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	out, err := s.run(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if out.Error != "" || out.ExitCode != 0 {
+		return nil, fmt.Errorf("%s %s", out.Error, strings.TrimSpace(out.Stderr))
+	}
+	if strings.TrimSpace(out.Stdout) == "" {
+		return nil, nil
+	}
+	var list workloadObject
+	if err := json.Unmarshal([]byte(out.Stdout), &list); err != nil {
+		return nil, fmt.Errorf("parsing the objects of the manifest: %w", err)
+	}
+	return list.Objects(), nil
+}
+
+// created returns the objects of the source that exist in the cluster, and are not among the existing ones.
+func (s applySource) created(ctx context.Context, namespace string, existing []workloadObject) []CreatedObject {
+	objects, err := s.objects(ctx, namespace)
+	if err != nil {
+		klog.Warningf("Not recording the objects created by apply_manifest: %v", err)
+		return nil
+	}
+	var created []CreatedObject
+	for _, object := range objects {
+		existed := slices.ContainsFunc(existing, func(o workloadObject) bool {
+			return o.Kind == object.Kind && o.Metadata.Name == object.Metadata.Name && o.Metadata.Namespace == object.Metadata.Namespace
+		})
+		if !existed {
+			created = append(created, CreatedObject{Resource: strings.ToLower(object.Kind), Name: object.Metadata.Name, Namespace: object.Metadata.Namespace})
+		}
+	}
+	return created
+}
+
+// attribute annotates the applied objects with the attribution of the context, if any.
+func (s applySource) attribute(ctx context.Context, namespace string) {
+	attribution := attributionFromContext(ctx)
+	if attribution == nil {
+		return
+	}
+	args := []string{"annotate", "--overwrite"}
+	var input io.Reader
+	if s.path != "" {
+		args = append(args, "-f", s.path)
+	} else {
+		args = append(args, "-f", "-")
+		input = strings.NewReader(s.manifest)
+	}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	attributeWith(ctx, input, append(args, attribution.annotations()...))
+}
+
+// applyOutcome fills in the result from the output of kubectl apply.
+func applyOutcome(result *ApplyResult, source applySource, namespace string, out *ExecResult) *ApplyResult {
+	result.Objects = outputLines(out.Stdout)
+	if out.Error == "" && out.ExitCode == 0 {
+		result.Applied = true
+		return result
+	}
+	if conflicts := parseApplyConflicts(out.Stderr); len(conflicts) > 0 {
+		result.Conflicts = conflicts
+		result.ConflictID = applyConflictID(source, namespace, conflicts)
+		result.Message = "Not applied: fields are owned by other managers. Show the conflicts to the user; " +
+			"if they agree to take the fields over, call apply_manifest again with the same arguments, force_conflicts and this conflict_id."
+		return result
+	}
+	result.Error = strings.TrimSpace(out.Error + " " + out.Stderr)
+	return result
+}
+
+// Simulate shows what the apply would change, with a server-side dry run and kubectl diff.
+func (t *ApplyTool) Simulate(ctx context.Context, args map[string]any) (any, error) {
+	result := &ApplyResult{FieldManager: ApplyFieldManager}
+	source, namespace, err := parseApplyArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	var extra []string
+	if boolArg(args, "force_conflicts") {
+		extra = append(extra, "--force-conflicts")
+	}
+	out, err := source.run(ctx, source.args(namespace, append(extra, "--dry-run=server")...))
+	if err != nil {
+		return nil, err
+	}
+	result = applyOutcome(result, source, namespace, out)
+	if result.Applied {
+		diffArgs := append([]string{"diff"}, source.args(namespace, extra...)[1:]...)
+		diff, err := source.run(ctx, diffArgs)
+		if err != nil {
+			return nil, err
+		}
+		// kubectl diff exits with 1 when there are differences.
+		result.Diff = diff.Stdout
+		result.Message = "Simulate mode is on, so nothing was applied; this is what the apply would change."
+	}
+	return result, nil
+}
+
+func (t *ApplyTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *ApplyTool) CheckModifiesResource(args map[string]any) string {
+	return "yes"
+}
+
+// applyConflictRegexp matches the start of the conflicts with a manager in the error of a server-side apply, e.g.
+// `conflict with "helm" using apps/v1: .spec.replicas` or `conflicts with "helm":`, followed by "- <field>" lines.
+var applyConflictRegexp = regexp.MustCompile(`conflicts? with "([^"]+)"(?: using [^:\s]+)?:\s*(.*)$`)
+
+// parseApplyConflicts returns the conflicts reported in the error output of a server-side apply.
+func parseApplyConflicts(stderr string) []ApplyConflict {
+	var conflicts []ApplyConflict
+	manager := ""
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if m := applyConflictRegexp.FindStringSubmatch(line); m != nil {
+			manager = m[1]
+			if field := strings.TrimSpace(m[2]); field != "" {
+				conflicts = append(conflicts, ApplyConflict{Manager: manager, Field: field})
+			}
+			continue
+		}
+		if field, ok := strings.CutPrefix(line, "- "); ok && manager != "" {
+			conflicts = append(conflicts, ApplyConflict{Manager: manager, Field: strings.TrimSpace(field)})
+			continue
+		}
+		manager = ""
+	}
+	return conflicts
+}
+
+// applyConflictID identifies the conflicts of applying a source, so that forcing the apply can be tied to them.
+func applyConflictID(source applySource, namespace string, conflicts []ApplyConflict) string {
+	var lines []string
+	for _, c := range conflicts {
+		lines = append(lines, c.Manager+"\n"+c.Field)
+	}
+	sort.Strings(lines)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n", source.manifest, source.path, namespace)
+	for _, line := range lines {
+		fmt.Fprintf(h, "%s\n", line)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
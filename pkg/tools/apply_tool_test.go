@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseApplyConflicts(t *testing.T) {
+	tests := []struct {
+		name     string
+		stderr   string
+		expected []ApplyConflict
+	}{
+		{
+			name:     "single conflict",
+			stderr:   `error: Apply failed with 1 conflict: conflict with "kubectl-client-side-apply" using apps/v1: .spec.replicas`,
+			expected: []ApplyConflict{{Manager: "kubectl-client-side-apply", Field: ".spec.replicas"}},
+		},
+		{
+			name: "conflicts with several managers",
+			stderr: `error: Apply failed with 3 conflicts: conflicts with "helm" using apps/v1:
+- .spec.replicas
+- .spec.template.spec.containers[name="web"].image
+conflicts with "hpa-controller":
+- .spec.replicas
+Please review the fields above--they currently have other managers. Here
+are the ways you can resolve this warning:
+- If you intend to manage all of these fields, please re-run the apply
+  command with the ` + "`--force-conflicts`" + ` flag.`,
+			expected: []ApplyConflict{
+				{Manager: "helm", Field: ".spec.replicas"},
+				{Manager: "helm", Field: `.spec.template.spec.containers[name="web"].image`},
+				{Manager: "hpa-controller", Field: ".spec.replicas"},
+			},
+		},
+		{
+			name:   "other error",
+			stderr: `error: unable to recognize "STDIN": no matches for kind "Deploymnt" in version "apps/v1"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseApplyConflicts(tt.stderr)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseApplyConflicts() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestApplySourceArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      map[string]any
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "manifest",
+			args:     map[string]any{"manifest": "kind: ConfigMap", "namespace": "shop"},
+			expected: []string{"apply", "--server-side", "--field-manager=kubectl-ai", "-f", "-", "--namespace", "shop"},
+		},
+		{
+			name:     "path",
+			args:     map[string]any{"path": "manifests/"},
+			expected: []string{"apply", "--server-side", "--field-manager=kubectl-ai", "-f", "manifests/"},
+		},
+		{
+			name:      "both",
+			args:      map[string]any{"manifest": "kind: ConfigMap", "path": "cm.yaml"},
+			expectErr: true,
+		},
+		{
+			name:      "neither",
+			args:      map[string]any{"namespace": "shop"},
+			expectErr: true,
+		},
+		{
+			name:      "url",
+			args:      map[string]any{"path": "https://example.com/cm.yaml"},
+			expectErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source, namespace, err := parseApplyArgs(tt.args)
+			if (err != nil) != tt.expectErr {
+				t.Fatalf("parseApplyArgs() error = %v, expectErr %v", err, tt.expectErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := source.args(namespace); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("args() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
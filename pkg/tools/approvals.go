@@ -0,0 +1,156 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Approval records that the user chose not to be asked again before running calls of a tool that match a pattern.
+type Approval struct {
+	Tool string `json:"tool"`
+	// Pattern is the command pattern the approval applies to, as returned by ApprovalPattern.
+	Pattern string `json:"pattern"`
+	// Context is the kubeconfig context the approval was given in, and applies to; empty if it was not known.
+	Context string    `json:"context,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+// Approvals are the approvals stored in a file, so that they apply to later sessions too.
+type Approvals struct {
+	path string
+	List []Approval `json:"approvals"`
+}
+
+// LoadApprovals reads the approvals stored in path; a missing file has no approvals.
+func LoadApprovals(path string) (*Approvals, error) {
+	approvals := &Approvals{path: path}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return approvals, nil
+		}
+		return nil, fmt.Errorf("reading approvals: %w", err)
+	}
+	if err := json.Unmarshal(b, approvals); err != nil {
+		return nil, fmt.Errorf("parsing approvals %q: %w", path, err)
+	}
+	return approvals, nil
+}
+
+// Save writes the approvals to the file they were loaded from.
+func (a *Approvals) Save() error {
+	if err := os.MkdirAll(filepath.Dir(a.path), 0o700); err != nil {
+		return fmt.Errorf("creating approvals directory: %w", err)
+	}
+	b, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(a.path, b, 0o600); err != nil {
+		return fmt.Errorf("writing approvals: %w", err)
+	}
+	return nil
+}
+
+// Approved returns true if the user approved calls of the tool that match the arguments, in the kubeconfig context.
+func (a *Approvals) Approved(tool string, args map[string]any, kubeContext string) bool {
+	if a == nil {
+		return false
+	}
+	pattern := ApprovalPattern(tool, args)
+	return slices.ContainsFunc(a.List, func(approval Approval) bool {
+		return approval.Tool == tool && approval.Pattern == pattern && approval.Context == kubeContext
+	})
+}
+
+// Add approves calls of the tool that match the arguments in the kubeconfig context, and saves the approvals.
+func (a *Approvals) Add(tool string, args map[string]any, kubeContext string) error {
+	if a.Approved(tool, args, kubeContext) {
+		return nil
+	}
+	a.List = append(a.List, Approval{
+		Tool:    tool,
+		Pattern: ApprovalPattern(tool, args),
+		Context: kubeContext,
+		Created: time.Now(),
+	})
+	return a.Save()
+}
+
+// ApprovalPattern returns the pattern of the commands that an approval of a call with the arguments applies to:
+//   - "kubectl <verb> [<kind>] [-n <namespace> | -A] [--context <context>] [--cluster <cluster>] [--kubeconfig <path>] *"
+//     for a single kubectl command on a single kind, which applies to the same verb on any resource of the kind
+//     in the namespace,
+//   - the command itself for other commands, e.g. pipelines, other programs, commands on several kinds
+//     (kubectl delete pod,secret --all), or commands that write files with redirections, so that the approval
+//     applies to nothing wider than what was approved,
+//   - the arguments as JSON for tools without a command, which applies to calls with the same arguments.
+func ApprovalPattern(tool string, args map[string]any) string {
+	command, _ := args["command"].(string)
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return argumentsPattern(args)
+	}
+	calls, onlyKubectl := ParseKubectlCalls(command)
+	if !onlyKubectl || len(calls) != 1 || calls[0].Verb == "" {
+		return command
+	}
+	call := calls[0]
+	words := []string{"kubectl", call.Verb}
+	if call.Kind != "" {
+		words = append(words, call.Kind)
+	}
+	switch {
+	case call.AllNamespaces:
+		words = append(words, "-A")
+	case call.Namespace != "":
+		words = append(words, "-n", call.Namespace)
+	}
+	if call.Context != "" {
+		words = append(words, "--context", call.Context)
+	}
+	if call.Cluster != "" {
+		words = append(words, "--cluster", call.Cluster)
+	}
+	if call.Kubeconfig != "" {
+		words = append(words, "--kubeconfig", call.Kubeconfig)
+	}
+	return strings.Join(append(words, "*"), " ")
+}
+
+// argumentsPattern returns the arguments of a call as JSON, with sorted keys, leaving out the LLM's assessment
+// of whether the call modifies resources, which does not change what the call does.
+func argumentsPattern(args map[string]any) string {
+	filtered := make(map[string]any, len(args))
+	for key, value := range args {
+		if key != "modifies_resource" {
+			filtered[key] = value
+		}
+	}
+	b, err := json.Marshal(filtered)
+	if err != nil {
+		// The arguments came from JSON, so this should not happen; a pattern that matches nothing is safe.
+		return fmt.Sprintf("<invalid arguments: %v>", err)
+	}
+	return string(b)
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import "testing"
+
+func TestApprovalPattern(t *testing.T) {
+	tests := []struct {
+		tool string
+		args map[string]any
+		want string
+	}{
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl scale deployment web --replicas=3"},
+			want: "kubectl scale deployment *",
+		},
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl -n staging delete pods web-1"},
+			want: "kubectl delete pod -n staging *",
+		},
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl rollout restart deploy/web -A"},
+			want: "kubectl rollout deployment -A *",
+		},
+		{
+			tool: "bash",
+			args: map[string]any{"command": "kubectl delete pod web-1 && kubectl delete pod web-2"},
+			want: "kubectl delete pod web-1 && kubectl delete pod web-2",
+		},
+		{
+			tool: "bash",
+			args: map[string]any{"command": "helm upgrade web ./chart"},
+			want: "helm upgrade web ./chart",
+		},
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl --context=prod delete pod web-1 -n shop --kubeconfig /tmp/kc"},
+			want: "kubectl delete pod -n shop --context prod --kubeconfig /tmp/kc *",
+		},
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl delete pod web-1 --cluster prod-eu"},
+			want: "kubectl delete pod --cluster prod-eu *",
+		},
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl delete pod,secret --all"},
+			want: "kubectl delete pod,secret --all",
+		},
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl delete pod/web-1 deployment/web"},
+			want: "kubectl delete pod/web-1 deployment/web",
+		},
+		{
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl get pods > pods.txt"},
+			want: "kubectl get pods > pods.txt",
+		},
+		{
+			tool: "bulk_update_metadata",
+			args: map[string]any{"selector": "app=web", "operation": "label", "modifies_resource": "yes"},
+			want: `{"operation":"label","selector":"app=web"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		if got := ApprovalPattern(tt.tool, tt.args); got != tt.want {
+			t.Errorf("ApprovalPattern(%q, %v) = %q, want %q", tt.tool, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestApproved(t *testing.T) {
+	approvals := &Approvals{List: []Approval{
+		{Tool: "kubectl", Pattern: "kubectl scale deployment -n staging *", Context: "dev"},
+		{Tool: "kubectl", Pattern: "kubectl delete pod *", Context: "dev"},
+		{Tool: "bulk_update_metadata", Pattern: `{"operation":"label","selector":"app=web"}`, Context: "dev"},
+		// Approvals for any call of a tool are no longer given, and don't apply.
+		{Tool: "apply_manifest", Pattern: "*", Context: "dev"},
+	}}
+
+	tests := []struct {
+		tool    string
+		args    map[string]any
+		context string
+		want    bool
+	}{
+		{tool: "kubectl", args: map[string]any{"command": "kubectl scale deploy api --replicas=2 -n staging"}, context: "dev", want: true},
+		{tool: "kubectl", args: map[string]any{"command": "kubectl scale deploy api --replicas=2 -n staging"}, context: "prod", want: false},
+		{tool: "kubectl", args: map[string]any{"command": "kubectl scale deploy api --replicas=2 -n staging --context prod"}, context: "dev", want: false},
+		{tool: "kubectl", args: map[string]any{"command": "kubectl scale deployment api --replicas=2 -n prod"}, context: "dev", want: false},
+		{tool: "kubectl", args: map[string]any{"command": "kubectl scale deployment api --replicas=2 -n staging; kubectl delete ns prod"}, context: "dev", want: false},
+		{tool: "bash", args: map[string]any{"command": "kubectl scale deployment api --replicas=2 -n staging"}, context: "dev", want: false},
+		{tool: "kubectl", args: map[string]any{"command": "kubectl delete pod web-2"}, context: "dev", want: true},
+		{tool: "kubectl", args: map[string]any{"command": "kubectl delete pod,secret,deployment --all"}, context: "dev", want: false},
+		{tool: "kubectl", args: map[string]any{"command": "kubectl delete pod/web-2 secret/db"}, context: "dev", want: false},
+		{tool: "bulk_update_metadata", args: map[string]any{"operation": "label", "selector": "app=web"}, context: "dev", want: true},
+		{tool: "bulk_update_metadata", args: map[string]any{"operation": "label", "selector": "app=db"}, context: "dev", want: false},
+		{tool: "apply_manifest", args: map[string]any{"manifest": "kind: Namespace"}, context: "dev", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := approvals.Approved(tt.tool, tt.args, tt.context); got != tt.want {
+			t.Errorf("Approved(%q, %v, %q) = %v, want %v", tt.tool, tt.args, tt.context, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// The annotations that attribute the objects created or modified by the agent to the session that changed them.
+const (
+	SessionIDAnnotation = "kubectl-ai/session-id"
+	UserAnnotation      = "kubectl-ai/user"
+	TimestampAnnotation = "kubectl-ai/timestamp"
+	QueryHashAnnotation = "kubectl-ai/query-hash"
+)
+
+// Attribution identifies the session, user and query on whose behalf tools change objects.
+// Tools that create or modify objects annotate them with it.
+type Attribution struct {
+	SessionID string
+	User      string
+	// QueryHash is the hash of the query being answered, as returned by QueryHash.
+	QueryHash string
+}
+
+// QueryHash returns the hash of a query recorded in the attribution annotations:
+// the first 16 hex digits of its SHA-256, so that the query itself is not stored in the cluster.
+func QueryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// annotations returns the attribution annotations as kubectl annotate arguments, with the current time.
+func (a *Attribution) annotations() []string {
+	annotations := []string{
+		SessionIDAnnotation + "=" + a.SessionID,
+		TimestampAnnotation + "=" + time.Now().UTC().Format(time.RFC3339),
+	}
+	if a.User != "" {
+		annotations = append(annotations, UserAnnotation+"="+a.User)
+	}
+	if a.QueryHash != "" {
+		annotations = append(annotations, QueryHashAnnotation+"="+a.QueryHash)
+	}
+	return annotations
+}
+
+type attributionKey struct{}
+
+// WithAttribution returns a context in which tools annotate the objects they change with the attribution.
+func WithAttribution(ctx context.Context, attribution *Attribution) context.Context {
+	return context.WithValue(ctx, attributionKey{}, attribution)
+}
+
+// attributionFromContext returns the attribution of the context, or nil if changes are not attributed.
+func attributionFromContext(ctx context.Context) *Attribution {
+	attribution, _ := ctx.Value(attributionKey{}).(*Attribution)
+	return attribution
+}
+
+// attributedVerbs are the kubectl verbs after which we annotate the changed objects; the others delete objects,
+// or don't change their definition.
+var attributedVerbs = map[string]bool{
+	"create": true, "apply": true, "patch": true, "replace": true, "scale": true, "autoscale": true,
+	"expose": true, "rollout": true, "run": true, "set": true, "label": true, "annotate": true,
+	"taint": true, "drain": true, "cordon": true, "uncordon": true,
+}
+
+// attributedRolloutSubcommands are the kubectl rollout subcommands that change objects.
+var attributedRolloutSubcommands = map[string]bool{
+	"restart": true, "undo": true, "pause": true, "resume": true,
+}
+
+// attributionArgs returns the arguments of the kubectl commands that annotate the objects changed by command
+// with the annotations.
+func attributionArgs(command string, annotations []string) [][]string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		klog.V(2).Infof("attributionArgs: cannot parse command %q: %v", command, err)
+		return nil
+	}
+
+	var commands [][]string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		call, ok := node.(*syntax.CallExpr)
+		if !ok {
+			return true
+		}
+		inv, targets, ok := mutationTargets(callArgs(call))
+		if !ok || !attributedVerbs[inv.verb] {
+			return true
+		}
+		if inv.verb == "rollout" && (len(inv.positional) == 0 || !attributedRolloutSubcommands[inv.positional[0]]) {
+			return true
+		}
+		for _, target := range targets {
+			args := append([]string{"annotate", "--overwrite"}, target...)
+			args = append(args, inv.flags...)
+			args = append(args, annotations...)
+			if !slices.ContainsFunc(commands, func(c []string) bool { return slices.Equal(c, args) }) {
+				commands = append(commands, args)
+			}
+		}
+		return true
+	})
+	return commands
+}
+
+// attributeCommand annotates the objects changed by a command that succeeded with the attribution of the context.
+// Failures are logged: the change was made, and the annotations are only a record of who made it.
+func attributeCommand(ctx context.Context, command string, result *ExecResult) {
+	attribution := attributionFromContext(ctx)
+	if attribution == nil || result == nil || result.Error != "" || result.ExitCode != 0 {
+		return
+	}
+	for _, args := range attributionArgs(command, attribution.annotations()) {
+		attributeWith(ctx, nil, args)
+	}
+}
+
+// attributeObject annotates an object that was changed with the attribution of the context, if any.
+func attributeObject(ctx context.Context, resource, name, namespace string) {
+	attribution := attributionFromContext(ctx)
+	if attribution == nil {
+		return
+	}
+	args := []string{"annotate", "--overwrite", resource, name}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	attributeWith(ctx, nil, append(args, attribution.annotations()...))
+}
+
+// attributeWith runs kubectl with the arguments that annotate objects, reading stdin from input if it is not nil.
+func attributeWith(ctx context.Context, input io.Reader, args []string) {
+	out, err := runKubectlWithInput(ctx, input, args...)
+	if err == nil && (out.Error != "" || out.ExitCode != 0) {
+		err = fmt.Errorf("%s %s", out.Error, strings.TrimSpace(out.Stderr))
+	}
+	if err != nil {
+		klog.Warningf("Annotating the changed objects with kubectl %s: %v", strings.Join(args, " "), err)
+	}
+}
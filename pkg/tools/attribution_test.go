@@ -0,0 +1,96 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAttributionArgs(t *testing.T) {
+	annotations := []string{"kubectl-ai/session-id=s1"}
+	testCases := []struct {
+		name     string
+		command  string
+		expected [][]string
+	}{
+		{
+			name:     "read-only command",
+			command:  "kubectl get pods -n default",
+			expected: nil,
+		},
+		{
+			name:    "scale",
+			command: "kubectl scale deployment nginx --replicas=3 -n web",
+			expected: [][]string{
+				{"annotate", "--overwrite", "deployment/nginx", "-n=web", "kubectl-ai/session-id=s1"},
+			},
+		},
+		{
+			name:    "apply from a file",
+			command: "kubectl apply -f app.yaml --context prod",
+			expected: [][]string{
+				{"annotate", "--overwrite", "-f", "app.yaml", "--context=prod", "kubectl-ai/session-id=s1"},
+			},
+		},
+		{
+			name:    "label with a selector",
+			command: "kubectl label pods -l app=web tier=frontend",
+			expected: [][]string{
+				{"annotate", "--overwrite", "pods", "-l", "app=web", "kubectl-ai/session-id=s1"},
+			},
+		},
+		{
+			name:    "rollout restart",
+			command: "kubectl rollout restart deployment/nginx && kubectl rollout status deployment/nginx",
+			expected: [][]string{
+				{"annotate", "--overwrite", "deployment/nginx", "kubectl-ai/session-id=s1"},
+			},
+		},
+		{
+			name:     "delete",
+			command:  "kubectl delete pod nginx-1",
+			expected: nil,
+		},
+		{
+			name:     "dry run",
+			command:  "kubectl apply -f app.yaml --dry-run=server",
+			expected: nil,
+		},
+		{
+			name:     "stdin",
+			command:  "cat app.yaml | kubectl apply -f -",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := attributionArgs(tc.command, annotations)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("attributionArgs(%q) = %q, want %q", tc.command, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestQueryHash(t *testing.T) {
+	if got, want := QueryHash("scale nginx to 3 replicas"), QueryHash("scale nginx to 3 replicas"); got != want || len(got) != 16 {
+		t.Errorf("QueryHash() = %q, want 16 stable hex digits", got)
+	}
+	if QueryHash("scale nginx to 3 replicas") == QueryHash("scale nginx to 4 replicas") {
+		t.Errorf("QueryHash() is the same for different queries")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"html/template"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -100,10 +101,17 @@ Possible values:
 	}
 }
 
+func (t *BashTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ExecResult]()
+}
+
 func (t *BashTool) Run(ctx context.Context, args map[string]any) (any, error) {
 	kubeconfig := ctx.Value(KubeconfigKey).(string)
 	workDir := ctx.Value(WorkDirKey).(string)
-	command := args["command"].(string)
+	command, ok := args["command"].(string)
+	if !ok || command == "" {
+		return &ExecResult{Error: "bash command not provided or is not a string"}, nil
+	}
 
 	if strings.Contains(command, "kubectl edit") {
 		return &ExecResult{Command: command, Error: "interactive mode not supported for kubectl, please use non-interactive commands"}, nil
@@ -112,23 +120,23 @@ func (t *BashTool) Run(ctx context.Context, args map[string]any) (any, error) {
 		return &ExecResult{Command: command, Error: "port-forwarding is not allowed because assistant is running in an unattended mode, please try some other alternative"}, nil
 	}
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(ctx, os.Getenv("COMSPEC"), "/c", command)
-	} else {
-		cmd = exec.CommandContext(ctx, lookupBashBin(), "-c", command)
+	if result := checkOffline(ctx, command); result != nil {
+		return result, nil
 	}
-	cmd.Dir = workDir
-	cmd.Env = os.Environ()
-	if kubeconfig != "" {
-		kubeconfig, err := expandShellVar(kubeconfig)
-		if err != nil {
-			return nil, err
-		}
-		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	if result := checkFilesystemPolicy(ctx, command, workDir); result != nil {
+		return result, nil
 	}
 
-	return executeCommand(cmd)
+	cmd, err := shellCommand(ctx, command, workDir, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	result, err := executeCommand(ctx, cmd)
+	if err == nil {
+		attributeCommand(ctx, command, result)
+		recordCreated(ctx, command, result)
+	}
+	return result, err
 }
 
 type ExecResult struct {
@@ -138,6 +146,8 @@ type ExecResult struct {
 	Stderr     string `json:"stderr,omitempty"`
 	ExitCode   int    `json:"exit_code,omitempty"`
 	StreamType string `json:"stream_type,omitempty"`
+	// Simulated is set if the command was run as a dry run, in simulate mode.
+	Simulated bool `json:"simulated,omitempty"`
 }
 
 func (e *ExecResult) String() string {
@@ -150,6 +160,26 @@ func (e *ExecResult) FormatAsHTML() template.HTML {
 	return template.HTML("<pre><code>" + template.HTMLEscapeString(e.Stdout) + "</code></pre>")
 }
 
+var _ ui.CanFormatAsText = &ExecResult{}
+
+func (e *ExecResult) FormatAsText() string {
+	var b strings.Builder
+	b.WriteString(e.Stdout)
+	if e.Stderr != "" {
+		if b.Len() != 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString(e.Stderr)
+	}
+	if e.Error != "" {
+		if b.Len() != 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("error: " + e.Error)
+	}
+	return b.String()
+}
+
 func IsInteractiveCommand(command string) (bool, error) {
 	// Inline isKubectlCommand logic
 	words := strings.Fields(command)
@@ -171,9 +201,24 @@ func IsInteractiveCommand(command string) (bool, error) {
 	return false, nil
 }
 
-func executeCommand(cmd *exec.Cmd) (*ExecResult, error) {
+// waitDelay bounds how long we wait for the output of a command to be closed
+// after the command has been killed (for example because its context was cancelled).
+// Without this, child processes that inherited stdout/stderr could block us forever.
+const waitDelay = 5 * time.Second
+
+// executeCommand runs the command, and writes its output to the output writer of the context (if any) as it is produced.
+func executeCommand(ctx context.Context, cmd *exec.Cmd) (*ExecResult, error) {
+	output := outputWriter(ctx)
 	command := strings.Join(cmd.Args, " ")
 
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = waitDelay
+	}
+	if cmd.Cancel != nil {
+		// The command was created with a context; kill what it started as well when the context is done.
+		killProcessGroupOnCancel(cmd)
+	}
+
 	if isInteractive, err := IsInteractiveCommand(command); isInteractive {
 		return &ExecResult{Command: command, Error: err.Error()}, nil
 	}
@@ -216,7 +261,11 @@ func executeCommand(cmd *exec.Cmd) (*ExecResult, error) {
 					return
 				}
 				line := scanner.Text() + "\n"
-				fmt.Print(line)
+				if output != nil {
+					io.WriteString(output, line)
+				} else {
+					fmt.Print(line)
+				}
 				stdoutBuilder.WriteString(line)
 			}
 			close(stdoutDone)
@@ -230,7 +279,11 @@ func executeCommand(cmd *exec.Cmd) (*ExecResult, error) {
 					return
 				}
 				line := scanner.Text() + "\n"
-				fmt.Fprint(os.Stderr, line)
+				if output != nil {
+					io.WriteString(output, line)
+				} else {
+					fmt.Fprint(os.Stderr, line)
+				}
 				stderrBuilder.WriteString(line)
 			}
 			close(stderrDone)
@@ -242,7 +295,7 @@ func executeCommand(cmd *exec.Cmd) (*ExecResult, error) {
 			isTimeout = true
 			// Kill the process immediately on timeout
 			if cmd.Process != nil {
-				cmd.Process.Kill()
+				killProcessGroup(cmd)
 				cmd.Wait()
 			}
 			// Return timeout message to be displayed via UI
@@ -259,7 +312,7 @@ func executeCommand(cmd *exec.Cmd) (*ExecResult, error) {
 
 		// Ensure the command is terminated
 		if cmd.Process != nil {
-			cmd.Process.Kill()
+			killProcessGroup(cmd)
 			cmd.Wait()
 		}
 
@@ -282,6 +335,10 @@ func executeCommand(cmd *exec.Cmd) (*ExecResult, error) {
 	cmd.Stdout = &stdout
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
+	if output != nil {
+		cmd.Stdout = io.MultiWriter(&stdout, output)
+		cmd.Stderr = io.MultiWriter(&stderr, output)
+	}
 
 	results := &ExecResult{
 		Command: command,
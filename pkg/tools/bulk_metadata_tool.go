@@ -0,0 +1,350 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&BulkMetadataTool{})
+}
+
+// BulkMetadataTool adds, changes or removes labels or annotations on all resources matching a selector.
+// Changes are always previewed first: applying them requires the preview_id returned by the preview,
+// which also guarantees that the objects have not changed in the meantime.
+type BulkMetadataTool struct{}
+
+func (t *BulkMetadataTool) Name() string {
+	return "bulk_update_metadata"
+}
+
+func (t *BulkMetadataTool) Description() string {
+	return `Adds, changes or removes labels or annotations on all Kubernetes resources of a type that match a selector.
+Prefer this tool over building kubectl/xargs pipelines in bash for bulk label or annotate operations.
+Calling the tool without preview_id only previews the change: it returns the affected objects, the planned change for each object, and a preview_id.
+Show the preview to the user, then call the tool again with exactly the same arguments plus the preview_id to apply the change.`
+}
+
+func (t *BulkMetadataTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"operation": {
+					Type:        gollm.TypeString,
+					Description: `Either "label" or "annotate".`,
+				},
+				"resource": {
+					Type:        gollm.TypeString,
+					Description: `The type of resource to update, e.g. "deployments" or "pods".`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the resources. Defaults to the current namespace.`,
+				},
+				"all_namespaces": {
+					Type:        gollm.TypeBoolean,
+					Description: `Update matching resources in all namespaces.`,
+				},
+				"selector": {
+					Type:        gollm.TypeString,
+					Description: `Label selector to choose the resources, e.g. "app=web,tier!=db". If empty, all resources of the type are selected.`,
+				},
+				"changes": {
+					Type: gollm.TypeArray,
+					Items: &gollm.Schema{
+						Type: gollm.TypeString,
+					},
+					Description: `The changes to make, in kubectl syntax: "key=value" to set a value, "key-" to remove a key.`,
+				},
+				"overwrite": {
+					Type:        gollm.TypeBoolean,
+					Description: `Allow changing keys that already have a different value. Without it, such objects are skipped.`,
+				},
+				"preview_id": {
+					Type:        gollm.TypeString,
+					Description: `The preview_id returned by the preview. Only set this to apply the change, after the user has seen the preview.`,
+				},
+			},
+			Required: []string{"operation", "resource", "changes"},
+		},
+	}
+}
+
+// BulkMetadataResult is the result of the bulk_update_metadata tool.
+type BulkMetadataResult struct {
+	Operation string `json:"operation"`
+	// Applied is true if the changes were applied, false for a preview.
+	Applied   bool                 `json:"applied"`
+	PreviewID string               `json:"preview_id,omitempty"`
+	Objects   []BulkMetadataObject `json:"objects,omitempty"`
+	Message   string               `json:"message,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// BulkMetadataObject is the planned change, and after applying the outcome, for a single object.
+type BulkMetadataObject struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	// Changes describe the planned changes, e.g. `team: "a" -> "b"`.
+	Changes []string `json:"changes,omitempty"`
+	// Status is one of "pending", "unchanged", "conflict", "updated" or "failed".
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	resourceVersion string
+}
+
+// metadataChange is a single requested change to a label or annotation.
+type metadataChange struct {
+	Key    string
+	Value  string
+	Remove bool
+}
+
+func (c metadataChange) String() string {
+	if c.Remove {
+		return c.Key + "-"
+	}
+	return c.Key + "=" + c.Value
+}
+
+// parseMetadataChanges parses changes in kubectl syntax: "key=value" or "key-".
+func parseMetadataChanges(changes []string) ([]metadataChange, error) {
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no changes specified")
+	}
+	var parsed []metadataChange
+	seen := make(map[string]bool)
+	for _, change := range changes {
+		var c metadataChange
+		if key, value, ok := strings.Cut(change, "="); ok {
+			c = metadataChange{Key: key, Value: value}
+		} else if key, ok := strings.CutSuffix(change, "-"); ok {
+			c = metadataChange{Key: key, Remove: true}
+		} else {
+			return nil, fmt.Errorf("invalid change %q, expected key=value or key-", change)
+		}
+		if c.Key == "" || strings.ContainsAny(c.Key, " \t\n") {
+			return nil, fmt.Errorf("invalid key in change %q", change)
+		}
+		if seen[c.Key] {
+			return nil, fmt.Errorf("key %q is changed more than once", c.Key)
+		}
+		seen[c.Key] = true
+		parsed = append(parsed, c)
+	}
+	return parsed, nil
+}
+
+// planMetadataChanges works out the effect of the changes on an object with the current values.
+// It returns a description of the effective changes, and of changes that conflict with existing values.
+func planMetadataChanges(current map[string]string, changes []metadataChange, overwrite bool) (planned []string, conflicts []string) {
+	for _, c := range changes {
+		oldValue, exists := current[c.Key]
+		switch {
+		case c.Remove && !exists:
+			// nothing to remove
+		case c.Remove:
+			planned = append(planned, fmt.Sprintf("%s: %q -> (removed)", c.Key, oldValue))
+		case !exists:
+			planned = append(planned, fmt.Sprintf("%s: (none) -> %q", c.Key, c.Value))
+		case oldValue == c.Value:
+			// already set
+		case !overwrite:
+			conflicts = append(conflicts, fmt.Sprintf("%s is already set to %q", c.Key, oldValue))
+		default:
+			planned = append(planned, fmt.Sprintf("%s: %q -> %q", c.Key, oldValue, c.Value))
+		}
+	}
+	return planned, conflicts
+}
+
+// computePreviewID identifies a preview by the requested changes and the state of the affected objects.
+func computePreviewID(operation string, changes []metadataChange, overwrite bool, objects []BulkMetadataObject) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%t\n", operation, overwrite)
+	for _, c := range changes {
+		fmt.Fprintf(h, "%s\n", c)
+	}
+	for _, obj := range objects {
+		fmt.Fprintf(h, "%s/%s/%s@%s\n", obj.Namespace, obj.Kind, obj.Name, obj.resourceVersion)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:12]
+}
+
+func (t *BulkMetadataTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*BulkMetadataResult]()
+}
+
+func (t *BulkMetadataTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	operation := stringArg(args, "operation")
+	result := &BulkMetadataResult{Operation: operation}
+	if operation != "label" && operation != "annotate" {
+		result.Error = fmt.Sprintf("invalid operation %q, must be label or annotate", operation)
+		return result, nil
+	}
+	resource := stringArg(args, "resource")
+	if resource == "" || strings.ContainsAny(resource, ",/ ") {
+		result.Error = "resource must be a single resource type, e.g. deployments"
+		return result, nil
+	}
+	changes, err := parseMetadataChanges(stringSliceArg(args, "changes"))
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	overwrite := boolArg(args, "overwrite")
+
+	getArgs := []string{resource}
+	if boolArg(args, "all_namespaces") {
+		getArgs = append(getArgs, "--all-namespaces")
+	} else if namespace := stringArg(args, "namespace"); namespace != "" {
+		getArgs = append(getArgs, "--namespace", namespace)
+	}
+	if selector := stringArg(args, "selector"); selector != "" {
+		getArgs = append(getArgs, "--selector", selector)
+	}
+
+	var list struct {
+		Items []struct {
+			Kind     string     `json:"kind"`
+			Metadata objectMeta `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := kubectlGetJSON(ctx, &list, getArgs...); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	for _, item := range list.Items {
+		current := item.Metadata.Labels
+		if operation == "annotate" {
+			current = item.Metadata.Annotations
+		}
+		planned, conflicts := planMetadataChanges(current, changes, overwrite)
+		obj := BulkMetadataObject{
+			Kind:            item.Kind,
+			Name:            item.Metadata.Name,
+			Namespace:       item.Metadata.Namespace,
+			Changes:         planned,
+			resourceVersion: item.Metadata.ResourceVersion,
+		}
+		switch {
+		case len(conflicts) > 0:
+			obj.Status = "conflict"
+			obj.Error = strings.Join(conflicts, "; ") + " (use overwrite to change it)"
+		case len(planned) == 0:
+			obj.Status = "unchanged"
+		default:
+			obj.Status = "pending"
+		}
+		result.Objects = append(result.Objects, obj)
+	}
+	sort.Slice(result.Objects, func(i, j int) bool {
+		if result.Objects[i].Namespace != result.Objects[j].Namespace {
+			return result.Objects[i].Namespace < result.Objects[j].Namespace
+		}
+		return result.Objects[i].Name < result.Objects[j].Name
+	})
+
+	previewID := computePreviewID(operation, changes, overwrite, result.Objects)
+	requestedID := stringArg(args, "preview_id")
+	if requestedID == "" {
+		result.PreviewID = previewID
+		result.Message = fmt.Sprintf("Preview only, nothing was changed. %d object(s) matched. "+
+			"Show this preview to the user, then call %s again with the same arguments and preview_id %q to apply the change.",
+			len(result.Objects), t.Name(), previewID)
+		return result, nil
+	}
+	if requestedID != previewID {
+		result.Error = "the matching objects or the requested changes differ from the preview; preview the change again"
+		return result, nil
+	}
+
+	result.Applied = true
+	updated, failed := 0, 0
+	pending := 0
+	for _, obj := range result.Objects {
+		if obj.Status == "pending" {
+			pending++
+		}
+	}
+	for i := range result.Objects {
+		obj := &result.Objects[i]
+		if obj.Status != "pending" {
+			continue
+		}
+		ReportProgress(ctx, Progress{
+			Message: fmt.Sprintf("Updating %s %s", resource, obj.Name),
+			Current: float64(updated + failed),
+			Total:   float64(pending),
+		})
+		kubectlArgs := []string{operation, resource, obj.Name}
+		if obj.Namespace != "" {
+			kubectlArgs = append(kubectlArgs, "--namespace", obj.Namespace)
+		}
+		if overwrite {
+			kubectlArgs = append(kubectlArgs, "--overwrite")
+		}
+		if obj.resourceVersion != "" {
+			// Fail rather than update an object that changed since the preview.
+			kubectlArgs = append(kubectlArgs, "--resource-version", obj.resourceVersion)
+		}
+		for _, c := range changes {
+			kubectlArgs = append(kubectlArgs, c.String())
+		}
+
+		out, err := runKubectl(ctx, kubectlArgs...)
+		switch {
+		case err != nil:
+			obj.Status, obj.Error = "failed", err.Error()
+		case out.Error != "" || out.ExitCode != 0:
+			obj.Status, obj.Error = "failed", strings.TrimSpace(out.Error+" "+out.Stderr)
+		default:
+			obj.Status = "updated"
+		}
+		if obj.Status == "updated" {
+			attributeObject(ctx, resource, obj.Name, obj.Namespace)
+			updated++
+		} else {
+			failed++
+		}
+	}
+	result.Message = fmt.Sprintf("Updated %d object(s), %d failed.", updated, failed)
+	return result, nil
+}
+
+func (t *BulkMetadataTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *BulkMetadataTool) CheckModifiesResource(args map[string]any) string {
+	if stringArg(args, "preview_id") == "" {
+		return "no"
+	}
+	return "yes"
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseMetadataChanges(t *testing.T) {
+	tests := []struct {
+		name        string
+		changes     []string
+		expected    []metadataChange
+		expectError bool
+	}{
+		{
+			name:     "set and remove",
+			changes:  []string{"team=payments", "legacy-"},
+			expected: []metadataChange{{Key: "team", Value: "payments"}, {Key: "legacy", Remove: true}},
+		},
+		{
+			name:     "empty value",
+			changes:  []string{"example.com/owner="},
+			expected: []metadataChange{{Key: "example.com/owner", Value: ""}},
+		},
+		{
+			name:        "no changes",
+			expectError: true,
+		},
+		{
+			name:        "missing operator",
+			changes:     []string{"team"},
+			expectError: true,
+		},
+		{
+			name:        "duplicate key",
+			changes:     []string{"team=a", "team-"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := parseMetadataChanges(tt.changes)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected an error, got %v", changes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !slices.Equal(changes, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, changes)
+			}
+		})
+	}
+}
+
+func TestPlanMetadataChanges(t *testing.T) {
+	current := map[string]string{"team": "a", "tier": "web", "legacy": "true"}
+	changes := []metadataChange{
+		{Key: "team", Value: "b"},
+		{Key: "tier", Value: "web"},
+		{Key: "owner", Value: "alice"},
+		{Key: "legacy", Remove: true},
+		{Key: "missing", Remove: true},
+	}
+
+	planned, conflicts := planMetadataChanges(current, changes, false)
+	expectedPlanned := []string{`owner: (none) -> "alice"`, `legacy: "true" -> (removed)`}
+	if !slices.Equal(planned, expectedPlanned) {
+		t.Errorf("expected planned changes %v, got %v", expectedPlanned, planned)
+	}
+	expectedConflicts := []string{`team is already set to "a"`}
+	if !slices.Equal(conflicts, expectedConflicts) {
+		t.Errorf("expected conflicts %v, got %v", expectedConflicts, conflicts)
+	}
+
+	planned, conflicts = planMetadataChanges(current, changes, true)
+	expectedPlanned = []string{`team: "a" -> "b"`, `owner: (none) -> "alice"`, `legacy: "true" -> (removed)`}
+	if !slices.Equal(planned, expectedPlanned) {
+		t.Errorf("expected planned changes with overwrite %v, got %v", expectedPlanned, planned)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("expected no conflicts with overwrite, got %v", conflicts)
+	}
+}
@@ -0,0 +1,283 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// monitorPollInterval is how often a ChangeMonitor looks at the pods and events.
+const monitorPollInterval = 10 * time.Second
+
+// failingWaitingReasons are the reasons a container is waiting that show that it cannot start,
+// as opposed to it still starting.
+var failingWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"InvalidImageName":           true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+}
+
+// ChangeMonitor watches the namespaces that a command changed, for signs that the change caused a regression:
+// warning events, containers that restart and pods that cannot start.
+type ChangeMonitor struct {
+	// scopes are the kubectl flags that select the namespaces to watch, e.g. ["--namespace=shop"];
+	// an empty scope is the current namespace.
+	scopes   [][]string
+	started  time.Time
+	baseline map[string]podHealth
+}
+
+// ChangeReport is what a ChangeMonitor saw after a change.
+type ChangeReport struct {
+	Watched time.Duration
+	// Warnings are the warning events since the change, e.g. "Pod shop/web-1: BackOff: Back-off restarting failed container (x3)".
+	Warnings []string
+	// Restarts are the containers that restarted since the change, e.g. "shop/web-1: 2 restarts".
+	Restarts []string
+	// Failing are the pods with containers that cannot start, e.g. "shop/web-2: CrashLoopBackOff".
+	Failing []string
+}
+
+// Healthy reports whether nothing suggests that the change caused a regression.
+func (r *ChangeReport) Healthy() bool {
+	return len(r.Warnings) == 0 && len(r.Restarts) == 0 && len(r.Failing) == 0
+}
+
+func (r *ChangeReport) String() string {
+	if r.Healthy() {
+		return fmt.Sprintf("No warning events, restarts or failing pods in the %v after the change.", r.Watched.Round(time.Second))
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "In the %v after the change:\n", r.Watched.Round(time.Second))
+	writeSection := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(&sb, "%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(&sb, "- %s\n", line)
+		}
+	}
+	writeSection("Pods that cannot start", r.Failing)
+	writeSection("Restarted containers", r.Restarts)
+	writeSection("Warning events", r.Warnings)
+	return strings.TrimSpace(sb.String())
+}
+
+// NewChangeMonitor returns a monitor for the namespaces that the kubectl calls in command change,
+// with the state of their pods before the change, or nil if command does not change objects with kubectl.
+// It must be called before the command runs.
+func NewChangeMonitor(ctx context.Context, command string) *ChangeMonitor {
+	scopes := monitorScopes(command)
+	if len(scopes) == 0 {
+		return nil
+	}
+	m := &ChangeMonitor{scopes: scopes, started: time.Now()}
+	m.baseline = m.podHealth(ctx)
+	return m
+}
+
+// monitorScopes returns the kubectl flags that select the namespaces that the kubectl calls in command change.
+func monitorScopes(command string) [][]string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil
+	}
+	var scopes [][]string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok {
+			if inv, _, ok := mutationTargets(callArgs(call)); ok {
+				scope := slices.Clone(inv.flags)
+				slices.Sort(scope)
+				if !slices.ContainsFunc(scopes, func(s []string) bool { return slices.Equal(s, scope) }) {
+					scopes = append(scopes, scope)
+				}
+			}
+		}
+		return true
+	})
+	return scopes
+}
+
+// Watch watches the namespaces for window, or until a regression shows, and reports what it saw.
+func (m *ChangeMonitor) Watch(ctx context.Context, window time.Duration) *ChangeReport {
+	deadline := m.started.Add(window)
+	for {
+		report := m.report(ctx)
+		remaining := time.Until(deadline)
+		if !report.Healthy() || remaining <= 0 {
+			return report
+		}
+		ReportProgress(ctx, Progress{
+			Message: "Watching for warning events and restarts",
+			Current: (window - remaining).Seconds(),
+			Total:   window.Seconds(),
+		})
+		select {
+		case <-ctx.Done():
+			return report
+		case <-time.After(min(monitorPollInterval, remaining)):
+		}
+	}
+}
+
+func (m *ChangeMonitor) report(ctx context.Context) *ChangeReport {
+	report := &ChangeReport{Watched: time.Since(m.started)}
+	report.Restarts, report.Failing = compareHealth(m.baseline, m.podHealth(ctx))
+	for _, scope := range m.scopes {
+		var events eventList
+		if err := kubectlGetJSON(ctx, &events, append([]string{"events", "--field-selector=type=Warning"}, scope...)...); err != nil {
+			klog.Warningf("listing events for change monitoring: %v", err)
+			continue
+		}
+		report.Warnings = append(report.Warnings, events.since(m.started)...)
+	}
+	return report
+}
+
+// podHealth returns the health of the pods in the scopes, by namespace/name.
+func (m *ChangeMonitor) podHealth(ctx context.Context) map[string]podHealth {
+	health := make(map[string]podHealth)
+	for _, scope := range m.scopes {
+		var pods podStatusList
+		if err := kubectlGetJSON(ctx, &pods, append([]string{"pods"}, scope...)...); err != nil {
+			klog.Warningf("listing pods for change monitoring: %v", err)
+			continue
+		}
+		for key, h := range pods.health() {
+			health[key] = h
+		}
+	}
+	return health
+}
+
+// podHealth is the state of a pod that shows whether it is doing well.
+type podHealth struct {
+	Restarts int
+	// Failing is the reason a container cannot start, e.g. "CrashLoopBackOff", or "" if none.
+	Failing string
+}
+
+type podStatusList struct {
+	Items []podStatusObject `json:"items"`
+}
+
+type podStatusObject struct {
+	Metadata objectMeta `json:"metadata"`
+	Status   podStatus  `json:"status"`
+}
+
+type podStatus struct {
+	ContainerStatuses []containerStatus `json:"containerStatuses,omitempty"`
+}
+
+type containerStatus struct {
+	Name         string         `json:"name"`
+	RestartCount int            `json:"restartCount"`
+	State        containerState `json:"state"`
+}
+
+type containerState struct {
+	Waiting *containerStateReason `json:"waiting,omitempty"`
+}
+
+type containerStateReason struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+func (l *podStatusList) health() map[string]podHealth {
+	health := make(map[string]podHealth)
+	for _, pod := range l.Items {
+		var h podHealth
+		for _, status := range pod.Status.ContainerStatuses {
+			h.Restarts += status.RestartCount
+			if waiting := status.State.Waiting; waiting != nil && failingWaitingReasons[waiting.Reason] && h.Failing == "" {
+				h.Failing = waiting.Reason
+			}
+		}
+		health[pod.Metadata.Namespace+"/"+pod.Metadata.Name] = h
+	}
+	return health
+}
+
+// compareHealth returns the pods that restarted, and those that cannot start, since the baseline.
+// Pods that were already failing before the change are not reported.
+func compareHealth(baseline, current map[string]podHealth) (restarts, failing []string) {
+	for key, h := range current {
+		before := baseline[key]
+		if n := h.Restarts - before.Restarts; n > 0 {
+			restarts = append(restarts, fmt.Sprintf("%s: %d restarts", key, n))
+		}
+		if h.Failing != "" && before.Failing == "" {
+			failing = append(failing, fmt.Sprintf("%s: %s", key, h.Failing))
+		}
+	}
+	slices.Sort(restarts)
+	slices.Sort(failing)
+	return restarts, failing
+}
+
+type eventList struct {
+	Items []eventObject `json:"items"`
+}
+
+type eventObject struct {
+	Type           string          `json:"type"`
+	InvolvedObject objectReference `json:"involvedObject"`
+	Reason         string          `json:"reason"`
+	Message        string          `json:"message"`
+	Count          int             `json:"count,omitempty"`
+	FirstTimestamp time.Time       `json:"firstTimestamp,omitzero"`
+	LastTimestamp  time.Time       `json:"lastTimestamp,omitzero"`
+	EventTime      time.Time       `json:"eventTime,omitzero"`
+}
+
+type objectReference struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// since returns the events last seen after t, one line each.
+func (l *eventList) since(t time.Time) []string {
+	var lines []string
+	for _, event := range l.Items {
+		last := event.LastTimestamp
+		if last.IsZero() {
+			last = event.EventTime
+		}
+		if last.Before(t.Truncate(time.Second)) {
+			continue
+		}
+		object := event.InvolvedObject
+		line := fmt.Sprintf("%s %s/%s: %s: %s", object.Kind, object.Namespace, object.Name, event.Reason, strings.TrimSpace(event.Message))
+		if event.Count > 1 {
+			line += fmt.Sprintf(" (x%d)", event.Count)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
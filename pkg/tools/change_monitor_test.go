@@ -0,0 +1,83 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMonitorScopes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		command  string
+		expected [][]string
+	}{
+		{
+			name:     "namespace",
+			command:  "kubectl set image deployment/web web=nginx:1.27 -n shop",
+			expected: [][]string{{"-n=shop"}},
+		},
+		{
+			name:     "current namespace",
+			command:  "kubectl rollout restart deployment/web",
+			expected: [][]string{nil},
+		},
+		{
+			name:     "several namespaces",
+			command:  "kubectl scale deploy/web --replicas=3 -n a && kubectl scale deploy/api --replicas=2 -n b && kubectl delete pod x -n a",
+			expected: [][]string{{"-n=a"}, {"-n=b"}},
+		},
+		{
+			name:    "read-only",
+			command: "kubectl get pods -n shop",
+		},
+		{
+			name:    "dry run",
+			command: "kubectl apply -f web.yaml --dry-run=server",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scopes := monitorScopes(tc.command)
+			if !reflect.DeepEqual(scopes, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, scopes)
+			}
+		})
+	}
+}
+
+func TestCompareHealth(t *testing.T) {
+	baseline := map[string]podHealth{
+		"shop/web-1": {Restarts: 2},
+		"shop/web-2": {Restarts: 5, Failing: "CrashLoopBackOff"},
+		"shop/db-0":  {},
+	}
+	current := map[string]podHealth{
+		"shop/web-1": {Restarts: 4},
+		"shop/web-2": {Restarts: 5, Failing: "CrashLoopBackOff"},
+		"shop/db-0":  {},
+		"shop/web-3": {Failing: "ImagePullBackOff"},
+	}
+
+	restarts, failing := compareHealth(baseline, current)
+	if expected := []string{"shop/web-1: 2 restarts"}; !reflect.DeepEqual(restarts, expected) {
+		t.Errorf("expected restarts %v, got %v", expected, restarts)
+	}
+	if expected := []string{"shop/web-3: ImagePullBackOff"}; !reflect.DeepEqual(failing, expected) {
+		t.Errorf("expected failing pods %v, got %v", expected, failing)
+	}
+}
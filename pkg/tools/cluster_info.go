@@ -0,0 +1,143 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// clusterInfoTimeout bounds the time spent gathering cluster information, so an unreachable cluster doesn't block startup.
+const clusterInfoTimeout = 10 * time.Second
+
+// ClusterInfo holds facts about the target cluster, to ground the LLM in what the cluster actually supports.
+// Fields are left empty if they could not be determined.
+type ClusterInfo struct {
+	Context       string
+	Namespace     string
+	ServerVersion string
+	// APIGroupVersions are the enabled API group versions, e.g. apps/v1
+	APIGroupVersions []string
+	// NodeCount is the number of nodes, or -1 if unknown.
+	NodeCount int
+}
+
+// GatherClusterInfo queries the cluster with kubectl.
+// The kubeconfig is taken from the context (KubeconfigKey); failures are logged and leave fields empty.
+func GatherClusterInfo(ctx context.Context) *ClusterInfo {
+	ctx, cancel := context.WithTimeout(ctx, clusterInfoTimeout)
+	defer cancel()
+
+	info := &ClusterInfo{NodeCount: -1}
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	gather := func(name string, args []string, parse func(stdout string) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := runKubectl(ctx, args...)
+			if err == nil && (result.Error != "" || result.ExitCode != 0) {
+				err = fmt.Errorf("%s %s", result.Error, strings.TrimSpace(result.Stderr))
+			}
+			if err != nil {
+				klog.V(2).Infof("could not determine cluster %s: %v", name, err)
+				return
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err := parse(result.Stdout); err != nil {
+				klog.V(2).Infof("could not parse cluster %s: %v", name, err)
+			}
+		}()
+	}
+
+	gather("context", []string{"config", "current-context"}, func(stdout string) error {
+		info.Context = strings.TrimSpace(stdout)
+		return nil
+	})
+	gather("namespace", []string{"config", "view", "--minify", "-o", "jsonpath={..namespace}"}, func(stdout string) error {
+		info.Namespace = strings.TrimSpace(stdout)
+		return nil
+	})
+	gather("version", []string{"version", "-o", "json"}, func(stdout string) error {
+		var version struct {
+			ServerVersion struct {
+				GitVersion string `json:"gitVersion"`
+			} `json:"serverVersion"`
+		}
+		if err := json.Unmarshal([]byte(stdout), &version); err != nil {
+			return err
+		}
+		info.ServerVersion = version.ServerVersion.GitVersion
+		return nil
+	})
+	gather("api versions", []string{"api-versions"}, func(stdout string) error {
+		info.APIGroupVersions = strings.Fields(stdout)
+		sort.Strings(info.APIGroupVersions)
+		return nil
+	})
+	gather("node count", []string{"get", "nodes", "-o", "name"}, func(stdout string) error {
+		info.NodeCount = len(strings.Fields(stdout))
+		return nil
+	})
+
+	wg.Wait()
+
+	if info.Namespace == "" && info.Context != "" {
+		info.Namespace = "default"
+	}
+	return info
+}
+
+// CurrentContext returns the name of the current kubeconfig context.
+// The kubeconfig is taken from the context (KubeconfigKey).
+func CurrentContext(ctx context.Context) (string, error) {
+	result, err := runKubectl(ctx, "config", "current-context")
+	if err == nil && (result.Error != "" || result.ExitCode != 0) {
+		err = fmt.Errorf("%s %s", result.Error, strings.TrimSpace(result.Stderr))
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the current context: %w", err)
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// CurrentNamespace returns the namespace of the current kubeconfig context, or "default" if the context doesn't set one.
+// The kubeconfig is taken from the context (KubeconfigKey).
+func CurrentNamespace(ctx context.Context) (string, error) {
+	result, err := runKubectl(ctx, "config", "view", "--minify", "-o", "jsonpath={..namespace}")
+	if err == nil && (result.Error != "" || result.ExitCode != 0) {
+		err = fmt.Errorf("%s %s", result.Error, strings.TrimSpace(result.Stderr))
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading the namespace of the current context: %w", err)
+	}
+	if namespace := strings.TrimSpace(result.Stdout); namespace != "" {
+		return namespace, nil
+	}
+	return "default", nil
+}
+
+// Known returns true if any information about the cluster could be gathered.
+func (c *ClusterInfo) Known() bool {
+	return c != nil && (c.ServerVersion != "" || len(c.APIGroupVersions) > 0 || c.NodeCount >= 0 || c.Context != "")
+}
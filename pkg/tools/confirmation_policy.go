@@ -0,0 +1,499 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+	"sigs.k8s.io/yaml"
+)
+
+// PolicyAction is what a confirmation policy rule does with the tool calls it matches.
+type PolicyAction string
+
+const (
+	// PolicyApprove runs the call without asking the user.
+	PolicyApprove PolicyAction = "approve"
+	// PolicyConfirm asks the user before running the call, even if they chose not to be asked again.
+	PolicyConfirm PolicyAction = "confirm"
+	// PolicyDeny refuses to run the call.
+	PolicyDeny PolicyAction = "deny"
+)
+
+// ConfirmationPolicy decides which tool calls need the user's confirmation.
+// Calls that no rule matches are confirmed if they (might) modify resources, unless permissions are skipped.
+type ConfirmationPolicy struct {
+	// Rules are evaluated in order; the first rule that matches a kubectl command decides what happens to it.
+	// Tools that take a namespace, kind or resource as arguments (see structuredToolVerbs) are matched like
+	// the kubectl commands they amount to.
+	Rules []PolicyRule `json:"rules,omitempty"`
+}
+
+// PolicyRule matches tool calls; empty fields match anything.
+type PolicyRule struct {
+	// Tools are tool names, e.g. kubectl or bash.
+	Tools []string `json:"tools,omitempty"`
+	// Verbs are kubectl verbs, e.g. get or delete.
+	Verbs []string `json:"verbs,omitempty"`
+	// Kinds are resource kinds; singular, plural and short names are equivalent, e.g. deployment, deployments or deploy.
+	Kinds []string `json:"kinds,omitempty"`
+	// Namespaces are namespace patterns, e.g. prod or prod-*.
+	Namespaces []string     `json:"namespaces,omitempty"`
+	Action     PolicyAction `json:"action"`
+}
+
+// Validate checks that the rules of the policy are well-formed.
+func (p *ConfirmationPolicy) Validate() error {
+	for i, rule := range p.Rules {
+		switch rule.Action {
+		case PolicyApprove, PolicyConfirm, PolicyDeny:
+		default:
+			return fmt.Errorf("confirmation policy rule %d: action %q is not known, must be %q, %q or %q", i+1, rule.Action, PolicyApprove, PolicyConfirm, PolicyDeny)
+		}
+		for _, pattern := range rule.Namespaces {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("confirmation policy rule %d: invalid namespace pattern %q: %w", i+1, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// kubectlFields returns true if the rule matches on fields of kubectl commands.
+func (r *PolicyRule) kubectlFields() bool {
+	return len(r.Verbs) != 0 || len(r.Kinds) != 0 || len(r.Namespaces) != 0
+}
+
+// matches returns true if the rule matches a kubectl call of the tool.
+func (r *PolicyRule) matches(tool string, call *KubectlCall) bool {
+	if len(r.Tools) != 0 && !slices.Contains(r.Tools, tool) {
+		return false
+	}
+	if !r.kubectlFields() {
+		return true
+	}
+	if call == nil {
+		return false
+	}
+	if len(r.Verbs) != 0 && !slices.Contains(r.Verbs, call.Verb) {
+		return false
+	}
+	if len(r.Kinds) != 0 && !slices.ContainsFunc(r.Kinds, func(kind string) bool {
+		return call.Kind != "" && normalizeKind(kind) == call.Kind
+	}) {
+		return false
+	}
+	if len(r.Namespaces) != 0 && !slices.ContainsFunc(r.Namespaces, func(pattern string) bool {
+		if call.AllNamespaces || call.Namespace == "" {
+			// A call in all namespaces is in every namespace, and a call in an unknown namespace could be in any;
+			// we never approve it because of one of them.
+			return r.Action != PolicyApprove
+		}
+		ok, _ := path.Match(pattern, call.Namespace)
+		return ok
+	}) {
+		return false
+	}
+	return true
+}
+
+// Decide returns what the policy does with a call of the tool with the given arguments.
+// Commands that don't name a namespace run in defaultNamespace. If it is empty, as the namespace could not be
+// determined, rules for namespaces never approve these commands, and ask for confirmation instead of denying them.
+// ok is false if no rule decides, i.e. the usual confirmation applies.
+func (p *ConfirmationPolicy) Decide(tool string, args map[string]any, defaultNamespace string) (action PolicyAction, ok bool) {
+	if p == nil || len(p.Rules) == 0 {
+		return "", false
+	}
+
+	calls, onlyKubectl := structuredToolCalls(tool, args)
+	if calls == nil {
+		command, _ := args["command"].(string)
+		calls, onlyKubectl = ParseKubectlCalls(command)
+	}
+	for i := range calls {
+		if calls[i].Namespace == "" {
+			calls[i].Namespace = defaultNamespace
+		}
+	}
+
+	decide := func(call *KubectlCall) (PolicyAction, bool) {
+		for _, rule := range p.Rules {
+			if !rule.matches(tool, call) {
+				continue
+			}
+			if rule.Action == PolicyDeny && len(rule.Namespaces) != 0 && call.Namespace == "" && !call.AllNamespaces {
+				// We can't tell whether the rule applies, so we ask rather than refuse.
+				return PolicyConfirm, true
+			}
+			return rule.Action, true
+		}
+		return "", false
+	}
+
+	// Each kubectl call is decided on its own, as is the rest of the command (e.g. other programs in a pipeline).
+	// The strictest decision wins, and we only approve if every part of the command is approved.
+	parts := make([]*KubectlCall, 0, len(calls)+1)
+	for i := range calls {
+		parts = append(parts, &calls[i])
+	}
+	if len(calls) == 0 || !onlyKubectl {
+		parts = append(parts, nil)
+	}
+
+	// A command that writes files with redirections is never approved, even by a rule for its tool.
+	approved := !redirectsToFile(args)
+	for _, part := range parts {
+		action, ok := decide(part)
+		switch {
+		case ok && action == PolicyDeny:
+			return PolicyDeny, true
+		case ok && action == PolicyConfirm:
+			return PolicyConfirm, true
+		case !ok:
+			approved = false
+		}
+	}
+	if approved {
+		return PolicyApprove, true
+	}
+	return "", false
+}
+
+// redirectsToFile returns true if the command in the arguments writes to a file with a redirection,
+// or cannot be parsed.
+func redirectsToFile(args map[string]any) bool {
+	command, _ := args["command"].(string)
+	if command == "" {
+		return false
+	}
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return true
+	}
+	redirects := false
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if redirect, ok := node.(*syntax.Redirect); ok && writesFile(redirect) {
+			redirects = true
+		}
+		return !redirects
+	})
+	return redirects
+}
+
+// KubectlCall describes a kubectl invocation in a command.
+type KubectlCall struct {
+	// Verb is the kubectl verb, e.g. get or delete.
+	Verb string
+	// Kind is the normalized kind of the resource, e.g. deployment; empty if it is not named (e.g. with -f).
+	Kind string
+	// Namespace is the namespace given with -n or --namespace; empty if none.
+	Namespace string
+	// AllNamespaces is set for -A or --all-namespaces.
+	AllNamespaces bool
+	// Context, Cluster and Kubeconfig are given with --context, --cluster and --kubeconfig; empty if not.
+	Context    string
+	Cluster    string
+	Kubeconfig string
+}
+
+// structuredToolVerbs are the kubectl verbs that the tools taking a namespace, kind or resource as arguments,
+// rather than a command, amount to. An empty verb is taken from the operation argument.
+var structuredToolVerbs = map[string]string{
+	"apply_manifest":            "apply",
+	"bulk_update_metadata":      "",
+	"pod_exec":                  "exec",
+	"port_forward":              "port-forward",
+	"wait_for":                  "wait",
+	"pod_logs":                  "logs",
+	"follow_logs":               "logs",
+	"cluster_events":            "get",
+	"check_iac_drift":           "get",
+	"check_resource_health":     "get",
+	"resolve_resource":          "get",
+	"resource_usage":            "top",
+	"audit_pod_security":        "get",
+	"incident_timeline":         "get",
+	"inspect_workload_identity": "get",
+}
+
+// structuredToolCalls describes a call of one of the tools in structuredToolVerbs as the kubectl calls it amounts to;
+// it returns nil for other tools. onlyKubectl is always true, as these tools run nothing else.
+func structuredToolCalls(tool string, args map[string]any) (calls []KubectlCall, onlyKubectl bool) {
+	verb, ok := structuredToolVerbs[tool]
+	if !ok {
+		return nil, false
+	}
+	if verb == "" {
+		verb, _ = args["operation"].(string)
+	}
+
+	call := KubectlCall{Verb: verb}
+	call.Namespace, _ = args["namespace"].(string)
+	call.AllNamespaces, _ = args["all_namespaces"].(bool)
+	kind, _ := args["kind"].(string)
+	if kind == "" {
+		kind, _ = args["resource"].(string)
+		kind, _, _ = strings.Cut(kind, "/")
+	}
+	if pod, _ := args["pod"].(string); kind == "" && pod != "" {
+		kind = "pod"
+	}
+	if tool == "cluster_events" {
+		kind = "event"
+	}
+	if kind != "" {
+		call.Kind = normalizeKind(kind)
+	}
+
+	manifest, _ := args["manifest"].(string)
+	if tool != "apply_manifest" || manifest == "" {
+		return []KubectlCall{call}, true
+	}
+	// Each object of a manifest is applied on its own, in its own namespace if it names one.
+	for _, object := range manifestObjects(manifest) {
+		objectCall := call
+		if object.Kind != "" {
+			objectCall.Kind = normalizeKind(object.Kind)
+		}
+		if object.Metadata.Namespace != "" {
+			objectCall.Namespace = object.Metadata.Namespace
+		}
+		calls = append(calls, objectCall)
+	}
+	if len(calls) == 0 {
+		calls = append(calls, call)
+	}
+	return calls, true
+}
+
+// manifestObject holds the fields of an object in a manifest that the confirmation policy looks at.
+type manifestObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Items []manifestObject `json:"items"`
+}
+
+// manifestSeparator separates the documents of a YAML manifest.
+var manifestSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// manifestObjects returns the objects of a manifest, with the items of lists in place of the lists.
+// Documents that cannot be parsed are returned without a kind or namespace.
+func manifestObjects(manifest string) []manifestObject {
+	var objects []manifestObject
+	var add func(object manifestObject)
+	add = func(object manifestObject) {
+		if strings.HasSuffix(object.Kind, "List") && len(object.Items) != 0 {
+			for _, item := range object.Items {
+				add(item)
+			}
+			return
+		}
+		objects = append(objects, object)
+	}
+	for _, document := range manifestSeparator.Split(manifest, -1) {
+		if strings.TrimSpace(document) == "" {
+			continue
+		}
+		var object manifestObject
+		if err := yaml.Unmarshal([]byte(document), &object); err != nil {
+			object = manifestObject{}
+		}
+		add(object)
+	}
+	return objects
+}
+
+// kubectlFlagsWithValues are the kubectl flags that take a separate value, so we don't mistake it for an argument.
+var kubectlFlagsWithValues = map[string]bool{
+	"-n": true, "--namespace": true, "-l": true, "--selector": true, "-o": true, "--output": true,
+	"-f": true, "--filename": true, "-c": true, "--container": true, "--context": true, "--cluster": true,
+	"--user": true, "--kubeconfig": true, "--field-selector": true, "--sort-by": true, "--type": true,
+	"-p": true, "--patch": true, "--replicas": true, "--timeout": true, "--since": true, "--tail": true,
+	"-k": true, "--kustomize": true, "--image": true, "--server": true, "-s": true, "--as": true,
+}
+
+// kubectlVerbsWithSubcommands are the kubectl verbs whose first argument is a subcommand rather than a resource.
+var kubectlVerbsWithSubcommands = map[string]bool{
+	"rollout": true, "set": true, "auth": true, "certificate": true, "config": true,
+}
+
+// ParseKubectlCalls finds the kubectl invocations in a shell command, with a call for each resource kind
+// that an invocation names, e.g. two for kubectl delete pod,secret --all.
+// onlyKubectl is false if the command also runs other programs, writes files with redirections, or cannot be parsed.
+func ParseKubectlCalls(command string) (calls []KubectlCall, onlyKubectl bool) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return nil, false
+	}
+
+	onlyKubectl = true
+	syntax.Walk(file, func(node syntax.Node) bool {
+		switch node := node.(type) {
+		case *syntax.Redirect:
+			if writesFile(node) {
+				onlyKubectl = false
+			}
+		case *syntax.CallExpr:
+			if len(node.Args) == 0 {
+				return true
+			}
+			args := callArgs(node)
+			if len(args) == 0 || path.Base(args[0]) != "kubectl" {
+				onlyKubectl = false
+				return true
+			}
+			calls = append(calls, parseKubectlArgs(args[1:])...)
+		}
+		return true
+	})
+	return calls, onlyKubectl
+}
+
+// writesFile returns true if the redirection writes to a file, rather than reading one or duplicating a file descriptor.
+func writesFile(redirect *syntax.Redirect) bool {
+	switch redirect.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll, syntax.RdrInOut:
+		return true
+	case syntax.DplOut:
+		// >&2 duplicates a file descriptor, but >&file writes to a file.
+		return !isFileDescriptor(redirect.Word.Lit())
+	}
+	return false
+}
+
+// parseKubectlArgs extracts the verb, kinds and namespace from the arguments of kubectl.
+// It returns a call for each kind named, in a list (pod,secret) or in separate kind/name arguments, and a single call
+// if there is none.
+func parseKubectlArgs(args []string) []KubectlCall {
+	var call KubectlCall
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			// What follows is a command run in a container (e.g. kubectl exec), not resources.
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case name == "-A" || name == "--all-namespaces":
+			call.AllNamespaces = value != "false"
+		case name == "-n" || name == "--namespace":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			call.Namespace = value
+		case name == "--context" || name == "--cluster" || name == "--kubeconfig":
+			if !hasValue && i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+			switch name {
+			case "--context":
+				call.Context = value
+			case "--cluster":
+				call.Cluster = value
+			default:
+				call.Kubeconfig = value
+			}
+		case strings.HasPrefix(name, "-n") && !strings.HasPrefix(name, "--"):
+			// -nprod
+			call.Namespace = strings.TrimPrefix(arg, "-n")
+		case kubectlFlagsWithValues[name] && !hasValue:
+			i++
+		}
+	}
+
+	if len(positional) == 0 {
+		return []KubectlCall{call}
+	}
+	call.Verb = positional[0]
+	resourceIndex := 1
+	if kubectlVerbsWithSubcommands[call.Verb] {
+		resourceIndex = 2
+	}
+	if resourceIndex >= len(positional) {
+		return []KubectlCall{call}
+	}
+
+	// The resources are either a list of kinds followed by names (pod,secret web-1), or kind/name arguments,
+	// possibly in lists (pod/web-1,deployment/web).
+	var kinds []string
+	addKind := func(kind string) {
+		if kind = normalizeKind(kind); kind != "" && !slices.Contains(kinds, kind) {
+			kinds = append(kinds, kind)
+		}
+	}
+	for i, resource := range positional[resourceIndex:] {
+		for _, part := range strings.Split(resource, ",") {
+			kind, _, hasName := strings.Cut(part, "/")
+			if i == 0 || hasName {
+				addKind(kind)
+			}
+		}
+	}
+	if len(kinds) == 0 {
+		return []KubectlCall{call}
+	}
+	calls := make([]KubectlCall, 0, len(kinds))
+	for _, kind := range kinds {
+		kindCall := call
+		kindCall.Kind = kind
+		calls = append(calls, kindCall)
+	}
+	return calls
+}
+
+// kindShortNames maps the short names of common kinds to their singular names.
+var kindShortNames = map[string]string{
+	"po": "pod", "deploy": "deployment", "svc": "service", "ns": "namespace", "cm": "configmap",
+	"sts": "statefulset", "ds": "daemonset", "rs": "replicaset", "pvc": "persistentvolumeclaim",
+	"pv": "persistentvolume", "ing": "ingress", "sa": "serviceaccount", "no": "node", "cj": "cronjob",
+	"hpa": "horizontalpodautoscaler", "pdb": "poddisruptionbudget", "netpol": "networkpolicy",
+	"crd": "customresourcedefinition", "ep": "endpoints", "sc": "storageclass", "ev": "event",
+}
+
+// normalizeKind returns the lowercase singular name of a kind, given its singular, plural or short name,
+// optionally qualified by its group (e.g. deployments.apps).
+func normalizeKind(kind string) string {
+	kind = strings.ToLower(kind)
+	kind, _, _ = strings.Cut(kind, ".")
+	if name, ok := kindShortNames[kind]; ok {
+		return name
+	}
+	switch {
+	case kind == "endpoints":
+		return kind
+	case strings.HasSuffix(kind, "ies"):
+		return strings.TrimSuffix(kind, "ies") + "y"
+	case strings.HasSuffix(kind, "sses") || strings.HasSuffix(kind, "ches") || strings.HasSuffix(kind, "shes") || strings.HasSuffix(kind, "xes"):
+		return strings.TrimSuffix(kind, "es")
+	case strings.HasSuffix(kind, "s") && !strings.HasSuffix(kind, "ss"):
+		return strings.TrimSuffix(kind, "s")
+	}
+	return kind
+}
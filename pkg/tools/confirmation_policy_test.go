@@ -0,0 +1,262 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseKubectlCalls(t *testing.T) {
+	tests := []struct {
+		command         string
+		want            []KubectlCall
+		wantOnlyKubectl bool
+	}{
+		{
+			command:         "kubectl get pods -n web",
+			want:            []KubectlCall{{Verb: "get", Kind: "pod", Namespace: "web"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl --namespace=prod delete deploy/api",
+			want:            []KubectlCall{{Verb: "delete", Kind: "deployment", Namespace: "prod"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl rollout restart deployments.apps/api -nprod",
+			want:            []KubectlCall{{Verb: "rollout", Kind: "deployment", Namespace: "prod"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl get -o yaml -A ingresses",
+			want:            []KubectlCall{{Verb: "get", Kind: "ingress", AllNamespaces: true}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl apply -f app.yaml",
+			want:            []KubectlCall{{Verb: "apply"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl get pods | grep Error",
+			want:            []KubectlCall{{Verb: "get", Kind: "pod"}},
+			wantOnlyKubectl: false,
+		},
+		{
+			command:         "kubectl get ns && kubectl delete namespace test",
+			want:            []KubectlCall{{Verb: "get", Kind: "namespace"}, {Verb: "delete", Kind: "namespace"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl delete pod,secret --all -n web",
+			want:            []KubectlCall{{Verb: "delete", Kind: "pod", Namespace: "web"}, {Verb: "delete", Kind: "secret", Namespace: "web"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl delete pod/x deployment/y",
+			want:            []KubectlCall{{Verb: "delete", Kind: "pod"}, {Verb: "delete", Kind: "deployment"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl get pod web-1 web-2",
+			want:            []KubectlCall{{Verb: "get", Kind: "pod"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl exec web-1 -- cat /etc/hosts",
+			want:            []KubectlCall{{Verb: "exec", Kind: "web-1"}},
+			wantOnlyKubectl: true,
+		},
+		{
+			command:         "kubectl get pods > ~/.bashrc",
+			want:            []KubectlCall{{Verb: "get", Kind: "pod"}},
+			wantOnlyKubectl: false,
+		},
+		{
+			command:         "kubectl get pods 2>&1",
+			want:            []KubectlCall{{Verb: "get", Kind: "pod"}},
+			wantOnlyKubectl: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, onlyKubectl := ParseKubectlCalls(tt.command)
+			if !slices.Equal(got, tt.want) || onlyKubectl != tt.wantOnlyKubectl {
+				t.Errorf("ParseKubectlCalls(%q) = %+v, %v, want %+v, %v", tt.command, got, onlyKubectl, tt.want, tt.wantOnlyKubectl)
+			}
+		})
+	}
+}
+
+func TestConfirmationPolicyDecide(t *testing.T) {
+	policy := &ConfirmationPolicy{
+		Rules: []PolicyRule{
+			{Kinds: []string{"namespaces"}, Verbs: []string{"delete"}, Action: PolicyDeny},
+			{Verbs: []string{"delete"}, Namespaces: []string{"prod", "prod-*"}, Action: PolicyConfirm},
+			{Verbs: []string{"get", "describe"}, Action: PolicyApprove},
+			{Tools: []string{"check_resource_health"}, Action: PolicyApprove},
+		},
+	}
+	tests := []struct {
+		name       string
+		tool       string
+		command    string
+		want       PolicyAction
+		wantDecide bool
+	}{
+		{name: "approved verb", tool: "kubectl", command: "kubectl get pods -n web", want: PolicyApprove, wantDecide: true},
+		{name: "confirmed in prod", tool: "kubectl", command: "kubectl delete pod web-1 -n prod-eu", want: PolicyConfirm, wantDecide: true},
+		{name: "default namespace", tool: "kubectl", command: "kubectl delete pod web-1", want: PolicyConfirm, wantDecide: true},
+		{name: "all namespaces", tool: "kubectl", command: "kubectl delete pods -A -l app=web", want: PolicyConfirm, wantDecide: true},
+		{name: "other namespace", tool: "kubectl", command: "kubectl delete pod web-1 -n dev", wantDecide: false},
+		{name: "denied", tool: "kubectl", command: "kubectl delete ns test", want: PolicyDeny, wantDecide: true},
+		{name: "strictest part wins", tool: "bash", command: "kubectl get ns && kubectl delete ns test", want: PolicyDeny, wantDecide: true},
+		{name: "other programs are not approved", tool: "bash", command: "kubectl get pods | xargs rm", wantDecide: false},
+		{name: "tool rule", tool: "check_resource_health", want: PolicyApprove, wantDecide: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := policy.Decide(tt.tool, map[string]any{"command": tt.command}, "prod")
+			if got != tt.want || ok != tt.wantDecide {
+				t.Errorf("Decide(%q, %q) = %q, %v, want %q, %v", tt.tool, tt.command, got, ok, tt.want, tt.wantDecide)
+			}
+		})
+	}
+}
+
+func TestConfirmationPolicyDecideSeveralResources(t *testing.T) {
+	policy := &ConfirmationPolicy{
+		Rules: []PolicyRule{
+			{Verbs: []string{"delete"}, Kinds: []string{"secret"}, Action: PolicyDeny},
+			{Verbs: []string{"delete"}, Kinds: []string{"pod"}, Action: PolicyApprove},
+			{Verbs: []string{"get"}, Action: PolicyApprove},
+			{Tools: []string{"bash"}, Action: PolicyApprove},
+		},
+	}
+	tests := []struct {
+		name       string
+		tool       string
+		command    string
+		want       PolicyAction
+		wantDecide bool
+	}{
+		{name: "single kind", tool: "kubectl", command: "kubectl delete pod web-1", want: PolicyApprove, wantDecide: true},
+		{name: "list with a denied kind", tool: "kubectl", command: "kubectl delete pod,secret --all", want: PolicyDeny, wantDecide: true},
+		{name: "list with an unknown kind", tool: "kubectl", command: "kubectl delete pod,deployment --all", wantDecide: false},
+		{name: "kind/name arguments", tool: "kubectl", command: "kubectl delete pod/x deployment/y", wantDecide: false},
+		{name: "kind/name arguments with a denied kind", tool: "kubectl", command: "kubectl delete pod/x secret/y", want: PolicyDeny, wantDecide: true},
+		{name: "output redirection", tool: "kubectl", command: "kubectl get pods > ~/.bashrc", wantDecide: false},
+		{name: "appending redirection", tool: "kubectl", command: "kubectl get pods >> /tmp/pods", wantDecide: false},
+		{name: "redirection to a file descriptor", tool: "kubectl", command: "kubectl get pods 2>&1", want: PolicyApprove, wantDecide: true},
+		{name: "redirection with a tool rule", tool: "bash", command: "echo hi > ~/.bashrc", wantDecide: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := policy.Decide(tt.tool, map[string]any{"command": tt.command}, "default")
+			if got != tt.want || ok != tt.wantDecide {
+				t.Errorf("Decide(%q, %q) = %q, %v, want %q, %v", tt.tool, tt.command, got, ok, tt.want, tt.wantDecide)
+			}
+		})
+	}
+}
+
+func TestConfirmationPolicyDecideStructuredTools(t *testing.T) {
+	policy := &ConfirmationPolicy{
+		Rules: []PolicyRule{
+			{Kinds: []string{"secrets"}, Action: PolicyDeny},
+			{Namespaces: []string{"prod"}, Verbs: []string{"apply", "exec", "label"}, Action: PolicyDeny},
+			{Namespaces: []string{"kube-system"}, Action: PolicyConfirm},
+			{Namespaces: []string{"dev"}, Action: PolicyApprove},
+		},
+	}
+	tests := []struct {
+		name             string
+		tool             string
+		args             map[string]any
+		defaultNamespace string
+		want             PolicyAction
+		wantDecide       bool
+	}{
+		{
+			name: "namespace argument",
+			tool: "pod_exec",
+			args: map[string]any{"pod": "web-1", "namespace": "prod", "shell_command": "ls"},
+			want: PolicyDeny, wantDecide: true,
+		},
+		{
+			name:             "default namespace",
+			tool:             "pod_exec",
+			args:             map[string]any{"pod": "web-1", "shell_command": "ls"},
+			defaultNamespace: "kube-system",
+			want:             PolicyConfirm, wantDecide: true,
+		},
+		{
+			name:             "approved namespace",
+			tool:             "port_forward",
+			args:             map[string]any{"action": "start", "resource": "svc/web", "port": "8080"},
+			defaultNamespace: "dev",
+			want:             PolicyApprove, wantDecide: true,
+		},
+		{
+			name:             "verb from operation",
+			tool:             "bulk_update_metadata",
+			args:             map[string]any{"operation": "label", "resource": "deployments", "namespace": "prod"},
+			defaultNamespace: "dev",
+			want:             PolicyDeny, wantDecide: true,
+		},
+		{
+			name:             "kind and namespace of manifest objects",
+			tool:             "apply_manifest",
+			args:             map[string]any{"manifest": "kind: ConfigMap\nmetadata:\n  name: a\n---\nkind: Secret\nmetadata:\n  name: b\n  namespace: dev\n"},
+			defaultNamespace: "dev",
+			want:             PolicyDeny, wantDecide: true,
+		},
+		{
+			name:             "namespace of manifest object",
+			tool:             "apply_manifest",
+			args:             map[string]any{"manifest": "kind: ConfigMap\nmetadata:\n  name: a\n  namespace: prod\n", "namespace": "dev"},
+			defaultNamespace: "dev",
+			want:             PolicyDeny, wantDecide: true,
+		},
+		{
+			name: "unknown namespace is not approved",
+			tool: "wait_for",
+			args: map[string]any{"condition": "ready", "resource": "deployment/web"},
+			want: PolicyConfirm, wantDecide: true,
+		},
+		{
+			name: "unknown namespace is confirmed rather than denied",
+			tool: "kubectl",
+			args: map[string]any{"command": "kubectl apply -f web.yaml"},
+			want: PolicyConfirm, wantDecide: true,
+		},
+		{
+			name:             "other tools",
+			tool:             "bash",
+			args:             map[string]any{"command": "ls"},
+			defaultNamespace: "dev",
+			wantDecide:       false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := policy.Decide(tt.tool, tt.args, tt.defaultNamespace)
+			if got != tt.want || ok != tt.wantDecide {
+				t.Errorf("Decide(%q, %v, %q) = %q, %v, want %q, %v", tt.tool, tt.args, tt.defaultNamespace, got, ok, tt.want, tt.wantDecide)
+			}
+		})
+	}
+}
@@ -17,9 +17,12 @@ package tools
 import (
 	"context"
 	"fmt"
-	"os"
 	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 	"mvdan.cc/sh/v3/syntax"
@@ -27,16 +30,37 @@ import (
 
 // CustomToolConfig defines the structure for configuring a custom tool.
 type CustomToolConfig struct {
-	Name          string `yaml:"name"`
-	Description   string `yaml:"description"`
-	Command       string `yaml:"command"`
-	CommandDesc   string `yaml:"command_desc"`
-	IsInteractive bool   `yaml:"is_interactive"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	// Command is the command that the LLM gives the arguments of, e.g. gcloud, or, with Parameters, the template
+	// of the command to run, e.g. ./scripts/restart.sh {{.service}}.
+	Command       string `json:"command" yaml:"command"`
+	CommandDesc   string `json:"command_desc,omitempty" yaml:"command_desc"`
+	IsInteractive bool   `json:"is_interactive,omitempty" yaml:"is_interactive"`
+	// Parameters are the arguments of a tool whose Command is a template. The values are shell-quoted, and
+	// parameters that are not given are empty.
+	Parameters []CustomToolParameter `json:"parameters,omitempty" yaml:"parameters"`
+	// ModifiesResource tells whether the command modifies resources: "yes", "no" or "unknown" (the default).
+	ModifiesResource string `json:"modifies_resource,omitempty" yaml:"modifies_resource"`
 }
 
+// CustomToolParameter is a parameter of a custom tool whose command is a template.
+type CustomToolParameter struct {
+	Name string `json:"name" yaml:"name"`
+	// Type is string (the default), integer, number, boolean or array (of strings).
+	Type        gollm.SchemaType `json:"type,omitempty" yaml:"type"`
+	Description string           `json:"description,omitempty" yaml:"description"`
+	Required    bool             `json:"required,omitempty" yaml:"required"`
+}
+
+// parameterNameRegexp matches the names of parameters, which the command template refers to as {{.name}}.
+var parameterNameRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // CustomTool implements the Tool interface for external commands.
 type CustomTool struct {
 	config CustomToolConfig
+	// command is the parsed template of the command, for tools with parameters.
+	command *template.Template
 }
 
 // NewCustomTool creates a new CustomTool instance.
@@ -47,8 +71,34 @@ func NewCustomTool(config CustomToolConfig) (*CustomTool, error) {
 	if len(config.Command) == 0 {
 		return nil, fmt.Errorf("custom tool command cannot be empty for tool %q", config.Name)
 	}
+	switch config.ModifiesResource {
+	case "", "yes", "no", "unknown":
+	default:
+		return nil, fmt.Errorf("modifies_resource of tool %q must be yes, no or unknown, not %q", config.Name, config.ModifiesResource)
+	}
 
-	return &CustomTool{config: config}, nil
+	tool := &CustomTool{config: config}
+	if len(config.Parameters) == 0 {
+		return tool, nil
+	}
+	var names []string
+	for i, parameter := range config.Parameters {
+		if !parameterNameRegexp.MatchString(parameter.Name) || slices.Contains(names, parameter.Name) {
+			return nil, fmt.Errorf("parameter %d of tool %q: invalid or duplicate name %q", i+1, config.Name, parameter.Name)
+		}
+		names = append(names, parameter.Name)
+		switch parameter.Type {
+		case "", gollm.TypeString, gollm.TypeInteger, gollm.TypeNumber, gollm.TypeBoolean, gollm.TypeArray:
+		default:
+			return nil, fmt.Errorf("parameter %q of tool %q: type must be string, integer, number, boolean or array, not %q", parameter.Name, config.Name, parameter.Type)
+		}
+	}
+	command, err := template.New(config.Name).Option("missingkey=zero").Parse(config.Command)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the command template of tool %q: %w", config.Name, err)
+	}
+	tool.command = command
+	return tool, nil
 }
 
 // Name returns the tool's name.
@@ -63,30 +113,108 @@ func (t *CustomTool) Description() string {
 
 // FunctionDefinition returns the tool's function definition.
 func (t *CustomTool) FunctionDefinition() *gollm.FunctionDefinition {
-	return &gollm.FunctionDefinition{
-		Name:        t.Name(),
-		Description: t.Description(),
-		Parameters: &gollm.Schema{
-			Type: gollm.TypeObject,
-			Properties: map[string]*gollm.Schema{
-				"command": {
-					Type:        gollm.TypeString,
-					Description: t.config.CommandDesc,
-				},
-				"modifies_resource": {
-					Type: gollm.TypeString,
-					Description: `Whether the command modifies a resource.
+	if t.command != nil {
+		parameters := &gollm.Schema{Type: gollm.TypeObject, Properties: map[string]*gollm.Schema{}}
+		for _, parameter := range t.config.Parameters {
+			schema := &gollm.Schema{Type: parameter.Type, Description: parameter.Description}
+			switch parameter.Type {
+			case "":
+				schema.Type = gollm.TypeString
+			case gollm.TypeArray:
+				schema.Items = &gollm.Schema{Type: gollm.TypeString}
+			}
+			parameters.Properties[parameter.Name] = schema
+			if parameter.Required {
+				parameters.Required = append(parameters.Required, parameter.Name)
+			}
+		}
+		return &gollm.FunctionDefinition{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Parameters:  parameters,
+		}
+	}
+
+	properties := map[string]*gollm.Schema{
+		"command": {
+			Type:        gollm.TypeString,
+			Description: t.config.CommandDesc,
+		},
+	}
+	if t.config.ModifiesResource == "" {
+		properties["modifies_resource"] = &gollm.Schema{
+			Type: gollm.TypeString,
+			Description: `Whether the command modifies a resource.
 Possible values:
 - "yes" if the command modifies a resource
 - "no" if the command does not modify a resource
 - "unknown" if the command's effect on the resource is unknown
 `,
-				},
-			},
+		}
+	}
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type:       gollm.TypeObject,
+			Properties: properties,
 		},
 	}
 }
 
+// renderCommand returns the command of a tool with parameters, from its template and the arguments.
+func (t *CustomTool) renderCommand(args map[string]any) (string, error) {
+	values := make(map[string]string)
+	for _, parameter := range t.config.Parameters {
+		value, ok := args[parameter.Name]
+		if !ok || value == nil {
+			if parameter.Required {
+				return "", fmt.Errorf("%s is required", parameter.Name)
+			}
+			continue
+		}
+		quoted, err := quoteArgument(value)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", parameter.Name, err)
+		}
+		values[parameter.Name] = quoted
+	}
+	var command strings.Builder
+	if err := t.command.Execute(&command, values); err != nil {
+		return "", fmt.Errorf("rendering the command: %w", err)
+	}
+	return strings.TrimSpace(command.String()), nil
+}
+
+// quoteArgument returns the value of an argument, shell-quoted; the elements of arrays are quoted and separated by spaces.
+func quoteArgument(value any) (string, error) {
+	switch value := value.(type) {
+	case string:
+		return shellQuote(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case []any:
+		var quoted []string
+		for _, element := range value {
+			if _, ok := element.([]any); ok {
+				return "", fmt.Errorf("nested arrays are not supported")
+			}
+			q, err := quoteArgument(element)
+			if err != nil {
+				return "", err
+			}
+			quoted = append(quoted, q)
+		}
+		return strings.Join(quoted, " "), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v of type %T", value, value)
+	}
+}
+
 // addCommandPrefix adds the tool's command prefix to the input command if needed.
 // It only adds the prefix if the command is a simple command (no pipes, etc.)
 // and doesn't already start with the prefix.
@@ -122,34 +250,53 @@ func (t *CustomTool) addCommandPrefix(inputCmd string) (string, error) {
 	return t.config.Command + " " + inputCmd, nil
 }
 
+func (t *CustomTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ExecResult]()
+}
+
 // Run executes the external command defined for the custom tool.
 func (t *CustomTool) Run(ctx context.Context, args map[string]any) (any, error) {
 	var command string
-	cmdVal, ok := args["command"]
-	if !ok {
-		return nil, fmt.Errorf("command not found in args")
+	if t.command != nil {
+		var err error
+		if command, err = t.renderCommand(args); err != nil {
+			return &ExecResult{Error: err.Error()}, nil
+		}
+	} else {
+		cmdVal, ok := args["command"]
+		if !ok {
+			return nil, fmt.Errorf("command not found in args")
+		}
+		command = cmdVal.(string)
+
+		var err error
+		command, err = t.addCommandPrefix(command)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process command: %w", err)
+		}
 	}
-	command = cmdVal.(string)
 
-	command, err := t.addCommandPrefix(command)
-	if err != nil {
-		return nil, fmt.Errorf("failed to process command: %w", err)
+	if result := checkOffline(ctx, command); result != nil {
+		return result, nil
 	}
 
 	workDir := ctx.Value(WorkDirKey).(string)
 
 	cmd := exec.CommandContext(ctx, lookupBashBin(), "-c", command)
 	cmd.Dir = workDir
-	cmd.Env = os.Environ()
+	cmd.Env = commandEnv(ctx)
 
-	return executeCommand(cmd)
+	return executeCommand(ctx, cmd)
 }
 
 // CheckModifiesResource determines if the command modifies resources
 // For custom tools, we'll conservatively assume they might modify resources
-// unless we have specific knowledge otherwise
+// unless the config says otherwise
 // Returns "yes", "no", or "unknown"
 func (t *CustomTool) CheckModifiesResource(args map[string]any) string {
+	if t.config.ModifiesResource != "" {
+		return t.config.ModifiesResource
+	}
 	// For custom tools, we'll conservatively use "unknown" since we can't
 	return "unknown"
 }
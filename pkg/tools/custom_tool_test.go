@@ -15,7 +15,11 @@
 package tools
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"sigs.k8s.io/yaml"
 )
 
 func TestCustomTool_AddCommandPrefix(t *testing.T) {
@@ -111,3 +115,153 @@ func TestCustomTool_AddCommandPrefix(t *testing.T) {
 		})
 	}
 }
+
+func TestCustomToolConfig_Parse(t *testing.T) {
+	config := `
+- name: restart_service
+  description: Restarts a service of the platform.
+  command: ./scripts/restart.sh {{.service}}{{if .environment}} --env {{.environment}}{{end}}
+  modifies_resource: "yes"
+  parameters:
+    - name: service
+      description: The name of the service.
+      required: true
+    - name: environment
+      description: The environment, e.g. staging.
+- name: gcloud
+  description: Runs gcloud.
+  command: gcloud
+  command_desc: The gcloud arguments.
+`
+	var configs []CustomToolConfig
+	if err := yaml.Unmarshal([]byte(config), &configs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []CustomToolConfig{
+		{
+			Name:             "restart_service",
+			Description:      "Restarts a service of the platform.",
+			Command:          "./scripts/restart.sh {{.service}}{{if .environment}} --env {{.environment}}{{end}}",
+			ModifiesResource: "yes",
+			Parameters: []CustomToolParameter{
+				{Name: "service", Description: "The name of the service.", Required: true},
+				{Name: "environment", Description: "The environment, e.g. staging."},
+			},
+		},
+		{Name: "gcloud", Description: "Runs gcloud.", Command: "gcloud", CommandDesc: "The gcloud arguments."},
+	}
+	if !reflect.DeepEqual(configs, expected) {
+		t.Errorf("expected %+v, got %+v", expected, configs)
+	}
+}
+
+func TestCustomTool_RenderCommand(t *testing.T) {
+	tool, err := NewCustomTool(CustomToolConfig{
+		Name:    "restart_service",
+		Command: "./scripts/restart.sh {{.service}}{{if .environment}} --env {{.environment}}{{end}} {{.replicas}} {{.regions}}",
+		Parameters: []CustomToolParameter{
+			{Name: "service", Required: true},
+			{Name: "environment"},
+			{Name: "replicas", Type: gollm.TypeInteger},
+			{Name: "regions", Type: gollm.TypeArray},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		args        map[string]any
+		expected    string
+		expectError bool
+	}{
+		{
+			name:     "all arguments",
+			args:     map[string]any{"service": "web", "environment": "prod eu", "replicas": float64(3), "regions": []any{"eu-west1", "us-east1"}},
+			expected: "./scripts/restart.sh web --env 'prod eu' 3 eu-west1 us-east1",
+		},
+		{
+			name:     "optional arguments left out",
+			args:     map[string]any{"service": "web"},
+			expected: "./scripts/restart.sh web",
+		},
+		{
+			name:     "arguments are quoted",
+			args:     map[string]any{"service": "web; rm -rf /"},
+			expected: "./scripts/restart.sh 'web; rm -rf /'",
+		},
+		{
+			name:        "required argument missing",
+			args:        map[string]any{"environment": "prod"},
+			expectError: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			command, err := tool.renderCommand(tt.args)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if command != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, command)
+			}
+		})
+	}
+}
+
+func TestNewCustomTool_Invalid(t *testing.T) {
+	configs := map[string]CustomToolConfig{
+		"modifies_resource":  {Name: "t", Command: "c", ModifiesResource: "maybe"},
+		"parameter name":     {Name: "t", Command: "c", Parameters: []CustomToolParameter{{Name: "my-param"}}},
+		"duplicate":          {Name: "t", Command: "c", Parameters: []CustomToolParameter{{Name: "a"}, {Name: "a"}}},
+		"parameter type":     {Name: "t", Command: "c", Parameters: []CustomToolParameter{{Name: "a", Type: gollm.TypeObject}}},
+		"template":           {Name: "t", Command: "c {{.a", Parameters: []CustomToolParameter{{Name: "a"}}},
+		"command is missing": {Name: "t", Parameters: []CustomToolParameter{{Name: "a"}}},
+	}
+	for name, config := range configs {
+		if _, err := NewCustomTool(config); err == nil {
+			t.Errorf("%s: expected error but got none", name)
+		}
+	}
+}
+
+func TestCustomTool_FunctionDefinition(t *testing.T) {
+	tool, err := NewCustomTool(CustomToolConfig{
+		Name:             "restart_service",
+		Description:      "Restarts a service.",
+		Command:          "./restart.sh {{.service}} {{.regions}}",
+		ModifiesResource: "yes",
+		Parameters: []CustomToolParameter{
+			{Name: "service", Description: "The service.", Required: true},
+			{Name: "regions", Type: gollm.TypeArray},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := &gollm.FunctionDefinition{
+		Name:        "restart_service",
+		Description: "Restarts a service.",
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"service": {Type: gollm.TypeString, Description: "The service."},
+				"regions": {Type: gollm.TypeArray, Items: &gollm.Schema{Type: gollm.TypeString}},
+			},
+			Required: []string{"service"},
+		},
+	}
+	if definition := tool.FunctionDefinition(); !reflect.DeepEqual(definition, expected) {
+		t.Errorf("expected %+v, got %+v", expected, definition)
+	}
+	if modifies := tool.CheckModifiesResource(nil); modifies != "yes" {
+		t.Errorf("expected yes, got %q", modifies)
+	}
+}
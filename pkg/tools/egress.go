@@ -0,0 +1,315 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+// EgressPolicy controls which hosts outside the cluster the commands run by tools may reach over HTTP and HTTPS.
+type EgressPolicy struct {
+	// AllowedHosts are the hosts that commands may reach: host names, *.example.com for the subdomains of a domain,
+	// or IP addresses. This machine and the names of the cluster (see isEgressExempt) are always allowed.
+	AllowedHosts []string
+	// DirectHosts are reached without going through the proxy, e.g. the API servers of the clusters.
+	DirectHosts []string
+}
+
+// Allows returns true if the policy allows commands to reach host.
+func (p *EgressPolicy) Allows(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if isEgressExempt(host) {
+		return true
+	}
+	for _, allowed := range slices.Concat(p.AllowedHosts, p.DirectHosts) {
+		allowed = strings.TrimSuffix(strings.ToLower(allowed), ".")
+		if domain, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return true
+			}
+		} else if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// isEgressExempt returns true if host is this machine or a service of the cluster: a loopback address, localhost,
+// or a name under .svc or .cluster.local. Unlike IsLocalHost, private and link-local addresses and names that are not
+// fully qualified are not exempt, as they include the metadata servers of clouds (169.254.169.254,
+// metadata.google.internal), which hand out credentials; they must be allowed explicitly.
+func isEgressExempt(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback()
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	return strings.HasSuffix(host, ".svc") || strings.HasSuffix(host, ".cluster.local")
+}
+
+type egressProxyKey struct{}
+
+// WithEgressProxy returns a context in which the commands run by tools on this machine go through the proxy.
+func WithEgressProxy(ctx context.Context, proxy *EgressProxy) context.Context {
+	return context.WithValue(ctx, egressProxyKey{}, proxy)
+}
+
+// egressProxyFromContext returns the egress proxy of the context, or nil if egress is not restricted.
+func egressProxyFromContext(ctx context.Context) *EgressProxy {
+	proxy, _ := ctx.Value(egressProxyKey{}).(*EgressProxy)
+	return proxy
+}
+
+// commandEnv returns the environment of the commands run by tools on this machine:
+// our environment, with the proxy settings of the egress proxy if there is one.
+func commandEnv(ctx context.Context) []string {
+	env := os.Environ()
+	if proxy := egressProxyFromContext(ctx); proxy != nil {
+		env = append(env, proxy.Env()...)
+	}
+	return env
+}
+
+// EnforcesEgress returns true if the commands run by the executor can be made to go through the egress proxy,
+// which listens on a loopback address of this machine: locally, or in a container on the network of the host.
+func EnforcesEgress(executor Executor) bool {
+	switch e := executor.(type) {
+	case *LocalExecutor:
+		return true
+	case *DockerExecutor:
+		return e.Image != ""
+	}
+	return false
+}
+
+// checkEgress returns an error if egress is restricted and the commands of the executor would go around the proxy.
+func checkEgress(ctx context.Context, executor Executor) error {
+	if egressProxyFromContext(ctx) != nil && !EnforcesEgress(executor) {
+		return fmt.Errorf("egress is restricted, and commands run by this executor cannot be made to go through the egress proxy")
+	}
+	return nil
+}
+
+// TakeEgressViolations returns the hosts that commands tried to reach against the egress policy
+// since the last call, or nil if egress is not restricted.
+func TakeEgressViolations(ctx context.Context) []string {
+	if proxy := egressProxyFromContext(ctx); proxy != nil {
+		return proxy.TakeViolations()
+	}
+	return nil
+}
+
+// EgressProxy is an HTTP proxy, for the commands run by tools, that only lets them reach the hosts the policy allows.
+// Commands are pointed at it with the HTTP_PROXY and HTTPS_PROXY environment variables, which most programs
+// (curl, wget, helm, kubectl...) honor; it is not a sandbox, as programs can ignore them.
+type EgressProxy struct {
+	policy   *EgressPolicy
+	listener net.Listener
+	server   *http.Server
+
+	mu sync.Mutex
+	// violations are the hosts that were refused since the last call to TakeViolations.
+	violations []string
+}
+
+// StartEgressProxy starts a proxy that enforces the policy, listening on a loopback address.
+func StartEgressProxy(policy *EgressPolicy) (*EgressProxy, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting egress proxy: %w", err)
+	}
+	p := &EgressProxy{policy: policy, listener: listener}
+	p.server = &http.Server{Handler: p}
+	go func() {
+		if err := p.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			klog.Errorf("egress proxy stopped: %v", err)
+		}
+	}()
+	return p, nil
+}
+
+// Close stops the proxy.
+func (p *EgressProxy) Close() error {
+	return p.server.Close()
+}
+
+// Env returns the environment variables that point commands at the proxy.
+func (p *EgressProxy) Env() []string {
+	proxyURL := "http://" + p.listener.Addr().String()
+	noProxy := strings.Join(slices.Concat([]string{"localhost", "127.0.0.1", "::1"}, p.policy.DirectHosts), ",")
+	return []string{
+		"HTTP_PROXY=" + proxyURL, "http_proxy=" + proxyURL,
+		"HTTPS_PROXY=" + proxyURL, "https_proxy=" + proxyURL,
+		"NO_PROXY=" + noProxy, "no_proxy=" + noProxy,
+	}
+}
+
+// TakeViolations returns the hosts that were refused since the last call.
+func (p *EgressProxy) TakeViolations() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	violations := p.violations
+	p.violations = nil
+	return violations
+}
+
+// refuse reports that a connection to host was refused.
+func (p *EgressProxy) refuse(w http.ResponseWriter, host string) {
+	klog.Warningf("egress policy: refused connection to %q", host)
+	p.mu.Lock()
+	if !slices.Contains(p.violations, host) {
+		p.violations = append(p.violations, host)
+	}
+	p.mu.Unlock()
+	http.Error(w, fmt.Sprintf("kubectl-ai egress policy: connections to %s are not allowed", host), http.StatusForbidden)
+}
+
+func (p *EgressProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.tunnel(w, r)
+		return
+	}
+	if r.URL.Host == "" {
+		http.Error(w, "this is a proxy", http.StatusBadRequest)
+		return
+	}
+	if !p.policy.Allows(r.URL.Hostname()) {
+		p.refuse(w, r.URL.Hostname())
+		return
+	}
+
+	outgoing := r.Clone(r.Context())
+	outgoing.RequestURI = ""
+	outgoing.Header.Del("Proxy-Connection")
+	outgoing.Header.Del("Proxy-Authorization")
+	transport := &http.Transport{Proxy: nil, DialContext: (&net.Dialer{Timeout: 30 * time.Second}).DialContext}
+	response, err := transport.RoundTrip(outgoing)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer response.Body.Close()
+	for key, values := range response.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+	io.Copy(w, response.Body)
+}
+
+// tunnel handles a CONNECT request, e.g. for HTTPS, by connecting the client to the host if the policy allows it.
+func (p *EgressProxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	if !p.policy.Allows(host) {
+		p.refuse(w, host)
+		return
+	}
+	upstream, err := net.DialTimeout("tcp", r.Host, 30*time.Second)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		http.Error(w, "tunneling is not supported", http.StatusInternalServerError)
+		return
+	}
+	client, _, err := hijacker.Hijack()
+	if err != nil {
+		upstream.Close()
+		return
+	}
+	client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+	go func() {
+		defer upstream.Close()
+		defer client.Close()
+		io.Copy(upstream, client)
+	}()
+	go func() {
+		defer upstream.Close()
+		defer client.Close()
+		io.Copy(client, upstream)
+	}()
+}
+
+// KubeconfigServerHosts returns the hosts of the API servers of the clusters in the kubeconfig files
+// (a list separated like KUBECONFIG). Files that do not exist are skipped.
+func KubeconfigServerHosts(kubeconfig string) ([]string, error) {
+	var hosts []string
+	for _, path := range filepath.SplitList(kubeconfig) {
+		b, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fileHosts, err := kubeconfigServerHosts(b)
+		if err != nil {
+			return nil, fmt.Errorf("parsing kubeconfig %s: %w", path, err)
+		}
+		for _, host := range fileHosts {
+			if !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	return hosts, nil
+}
+
+// kubeconfigServerHosts returns the hosts of the API servers of the clusters in a kubeconfig file.
+func kubeconfigServerHosts(b []byte) ([]string, error) {
+	var kubeconfig struct {
+		Clusters []struct {
+			Cluster struct {
+				Server string `json:"server"`
+			} `json:"cluster"`
+		} `json:"clusters"`
+	}
+	if err := yaml.Unmarshal(b, &kubeconfig); err != nil {
+		return nil, err
+	}
+	var hosts []string
+	for _, cluster := range kubeconfig.Clusters {
+		u, err := url.Parse(cluster.Cluster.Server)
+		if err != nil || u.Hostname() == "" {
+			continue
+		}
+		if !slices.Contains(hosts, u.Hostname()) {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts, nil
+}
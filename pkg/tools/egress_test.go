@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEgressPolicyAllows(t *testing.T) {
+	policy := &EgressPolicy{
+		AllowedHosts: []string{"charts.example.com", "*.github.com"},
+		DirectHosts:  []string{"34.1.2.3"},
+	}
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{host: "charts.example.com", expected: true},
+		{host: "Charts.Example.com.", expected: true},
+		{host: "api.github.com", expected: true},
+		{host: "github.com", expected: false},
+		{host: "34.1.2.3", expected: true},
+		{host: "127.0.0.1", expected: true},
+		{host: "::1", expected: true},
+		{host: "localhost", expected: true},
+		{host: "web.default.svc.cluster.local", expected: true},
+		{host: "web.default.svc", expected: true},
+		{host: "10.0.0.1", expected: false},
+		{host: "169.254.169.254", expected: false},
+		{host: "fe80::1", expected: false},
+		{host: "metadata.google.internal", expected: false},
+		{host: "metadata", expected: false},
+		{host: "db.corp.internal", expected: false},
+		{host: "example.com", expected: false},
+		{host: "pastebin.com", expected: false},
+		{host: "evilgithub.com", expected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := policy.Allows(tt.host); got != tt.expected {
+				t.Errorf("Allows(%q) = %v, expected %v", tt.host, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestKubeconfigServerHosts(t *testing.T) {
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://34.1.2.3
+- name: dev
+  cluster:
+    server: https://dev.k8s.example.com:6443
+- name: dev-again
+  cluster:
+    server: https://dev.k8s.example.com:6443
+`
+	got, err := kubeconfigServerHosts([]byte(kubeconfig))
+	if err != nil {
+		t.Fatalf("kubeconfigServerHosts() returned an error: %v", err)
+	}
+	expected := []string{"34.1.2.3", "dev.k8s.example.com"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("kubeconfigServerHosts() = %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&EventsTool{})
+}
+
+// How cluster_events sorts the events.
+const (
+	sortEventsLastSeen  = "last_seen"
+	sortEventsFirstSeen = "first_seen"
+	sortEventsCount     = "count"
+)
+
+const (
+	// defaultEventsLimit is how many events cluster_events returns if no limit is given, and maxEventsLimit the most.
+	defaultEventsLimit = 50
+	maxEventsLimit     = 200
+)
+
+// eventKinds maps the kinds and short names used in object references to the kinds of involved objects.
+var eventKinds = map[string]string{
+	"pod": "Pod", "po": "Pod",
+	"deployment": "Deployment", "deploy": "Deployment",
+	"replicaset": "ReplicaSet", "rs": "ReplicaSet",
+	"statefulset": "StatefulSet", "sts": "StatefulSet",
+	"daemonset": "DaemonSet", "ds": "DaemonSet",
+	"job": "Job", "cronjob": "CronJob", "cj": "CronJob",
+	"node": "Node", "no": "Node",
+	"service": "Service", "svc": "Service",
+	"ingress": "Ingress", "ing": "Ingress",
+	"persistentvolumeclaim": "PersistentVolumeClaim", "pvc": "PersistentVolumeClaim",
+	"persistentvolume": "PersistentVolume", "pv": "PersistentVolume",
+	"horizontalpodautoscaler": "HorizontalPodAutoscaler", "hpa": "HorizontalPodAutoscaler",
+}
+
+// eventNumbers matches the numbers in event messages, which differ between otherwise identical events.
+var eventNumbers = regexp.MustCompile(`\d+`)
+
+// EventsTool lists the events of the cluster, filtered, deduplicated and sorted.
+type EventsTool struct{}
+
+func (t *EventsTool) Name() string {
+	return "cluster_events"
+}
+
+func (t *EventsTool) Description() string {
+	return `Lists the events of a namespace or of the whole cluster, optionally only those of an object, of a type
+(Warning or Normal) or with a reason, or newer than a duration. Identical events are merged, with their total count
+and when they were first and last seen, and the events are sorted, the most recent first by default.
+Use this tool to triage events instead of running kubectl get events, whose raw output is long and repetitive.`
+}
+
+func (t *EventsTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the events. Defaults to the namespace of the current context.`,
+				},
+				"all_namespaces": {
+					Type:        gollm.TypeBoolean,
+					Description: `List the events of all the namespaces.`,
+				},
+				"object": {
+					Type:        gollm.TypeString,
+					Description: `Only the events about this object, as kind/name, e.g. "pod/web-0" or "deployment/web"; or a name alone for objects of any kind.`,
+				},
+				"type": {
+					Type:        gollm.TypeString,
+					Description: `Only the events of this type: Warning or Normal.`,
+				},
+				"reason": {
+					Type:        gollm.TypeString,
+					Description: `Only the events with this reason, e.g. "BackOff", "FailedScheduling" or "Unhealthy".`,
+				},
+				"field_selector": {
+					Type:        gollm.TypeString,
+					Description: `An additional field selector for kubectl get events, e.g. "source=kubelet".`,
+				},
+				"since": {
+					Type:        gollm.TypeString,
+					Description: `Only the events last seen in this duration, e.g. "30m" or "2h".`,
+				},
+				"sort_by": {
+					Type:        gollm.TypeString,
+					Description: `How to sort the events: last_seen (the most recent first, the default), first_seen (the oldest first) or count (the most repeated first).`,
+				},
+				"limit": {
+					Type:        gollm.TypeInteger,
+					Description: `How many events to return at most. Defaults to 50; at most 200.`,
+				},
+			},
+		},
+	}
+}
+
+// EventsResult is the result of the cluster_events tool.
+type EventsResult struct {
+	// Command is the kubectl command that listed the events.
+	Command string `json:"command"`
+	// Listed is how many events were listed, before they were merged and limited.
+	Listed int `json:"listed"`
+	// Events are the events, merged and sorted.
+	Events []ClusterEvent `json:"events"`
+	// Truncated is set if there were more events than the limit.
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ClusterEvent is an event, or identical events merged.
+type ClusterEvent struct {
+	Namespace string `json:"namespace,omitempty"`
+	// Object is the object the event is about, as kind/name.
+	Object  string `json:"object"`
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// Count is how many times the event occurred.
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+	LastSeen  time.Time `json:"last_seen,omitzero"`
+}
+
+func (t *EventsTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*EventsResult]()
+}
+
+// eventsRequest is a parsed cluster_events call.
+type eventsRequest struct {
+	namespace      string
+	allNamespaces  bool
+	fieldSelectors []string
+	since          time.Duration
+	sortBy         string
+	limit          int
+}
+
+func parseEventsArgs(args map[string]any) (*eventsRequest, error) {
+	r := &eventsRequest{
+		namespace:     stringArg(args, "namespace"),
+		allNamespaces: boolArg(args, "all_namespaces"),
+		sortBy:        stringArg(args, "sort_by"),
+		limit:         defaultEventsLimit,
+	}
+	if object := stringArg(args, "object"); object != "" {
+		kind, name, ok := strings.Cut(object, "/")
+		if !ok {
+			kind, name = "", object
+		}
+		if kind != "" {
+			if known, ok := eventKinds[strings.ToLower(kind)]; ok {
+				kind = known
+			} else if strings.ToLower(kind) == kind {
+				// The kinds of involved objects are capitalized, e.g. ConfigMap; we only know those above.
+				kind = ""
+			}
+		}
+		if kind != "" {
+			r.fieldSelectors = append(r.fieldSelectors, "involvedObject.kind="+kind)
+		}
+		r.fieldSelectors = append(r.fieldSelectors, "involvedObject.name="+name)
+	}
+	switch eventType := stringArg(args, "type"); strings.ToLower(eventType) {
+	case "":
+	case "warning", "normal":
+		r.fieldSelectors = append(r.fieldSelectors, "type="+strings.ToUpper(eventType[:1])+strings.ToLower(eventType[1:]))
+	default:
+		return nil, fmt.Errorf("invalid type %q: use Warning or Normal", eventType)
+	}
+	if reason := stringArg(args, "reason"); reason != "" {
+		r.fieldSelectors = append(r.fieldSelectors, "reason="+reason)
+	}
+	if fieldSelector := stringArg(args, "field_selector"); fieldSelector != "" {
+		r.fieldSelectors = append(r.fieldSelectors, fieldSelector)
+	}
+	if since := stringArg(args, "since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid since %q: give a duration such as 30m or 2h", since)
+		}
+		r.since = d
+	}
+	switch r.sortBy {
+	case "":
+		r.sortBy = sortEventsLastSeen
+	case sortEventsLastSeen, sortEventsFirstSeen, sortEventsCount:
+	default:
+		return nil, fmt.Errorf("invalid sort_by %q: use last_seen, first_seen or count", r.sortBy)
+	}
+	if limit, ok := intArg(args, "limit"); ok {
+		if limit <= 0 {
+			return nil, fmt.Errorf("invalid limit %d: give a positive number of events", limit)
+		}
+		r.limit = min(limit, maxEventsLimit)
+	}
+	return r, nil
+}
+
+// kubectlArgs returns the arguments of kubectl get that list the events, without the output format.
+func (r *eventsRequest) kubectlArgs() []string {
+	args := []string{"events"}
+	switch {
+	case r.allNamespaces:
+		args = append(args, "--all-namespaces")
+	case r.namespace != "":
+		args = append(args, "--namespace", r.namespace)
+	}
+	if len(r.fieldSelectors) > 0 {
+		args = append(args, "--field-selector", strings.Join(r.fieldSelectors, ","))
+	}
+	return args
+}
+
+// mergeEvents merges the identical events, that is, the events about the same object with the same type,
+// reason and message (up to the numbers in it), leaves out those last seen before since (if not zero), and
+// sorts them.
+func mergeEvents(events []eventObject, since time.Time, sortBy string) []ClusterEvent {
+	var merged []ClusterEvent
+	indexes := make(map[string]int)
+	for _, event := range events {
+		first, last := event.FirstTimestamp, event.LastTimestamp
+		if first.IsZero() {
+			first = event.EventTime
+		}
+		if last.IsZero() {
+			last = event.EventTime
+		}
+		if last.IsZero() {
+			last = first
+		}
+		if !since.IsZero() && last.Before(since) {
+			continue
+		}
+		count := max(event.Count, 1)
+		object := event.InvolvedObject
+		message := strings.TrimSpace(event.Message)
+		key := strings.Join([]string{object.Namespace, object.Kind, object.Name, event.Type, event.Reason, eventNumbers.ReplaceAllString(message, "#")}, "\x00")
+		if i, ok := indexes[key]; ok {
+			m := &merged[i]
+			m.Count += count
+			if !first.IsZero() && (m.FirstSeen.IsZero() || first.Before(m.FirstSeen)) {
+				m.FirstSeen = first
+			}
+			if last.After(m.LastSeen) {
+				// The latest message is the most relevant one.
+				m.LastSeen, m.Message = last, message
+			}
+			continue
+		}
+		indexes[key] = len(merged)
+		merged = append(merged, ClusterEvent{
+			Namespace: object.Namespace,
+			Object:    strings.ToLower(object.Kind) + "/" + object.Name,
+			Type:      event.Type,
+			Reason:    event.Reason,
+			Message:   message,
+			Count:     count,
+			FirstSeen: first,
+			LastSeen:  last,
+		})
+	}
+
+	slices.SortStableFunc(merged, func(a, b ClusterEvent) int {
+		switch sortBy {
+		case sortEventsFirstSeen:
+			return a.FirstSeen.Compare(b.FirstSeen)
+		case sortEventsCount:
+			if a.Count != b.Count {
+				return b.Count - a.Count
+			}
+		}
+		return b.LastSeen.Compare(a.LastSeen)
+	})
+	return merged
+}
+
+func (t *EventsTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &EventsResult{}
+	request, err := parseEventsArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	kubectlArgs := request.kubectlArgs()
+	result.Command = "kubectl get " + strings.Join(kubectlArgs, " ")
+
+	var events eventList
+	if err := kubectlGetJSON(ctx, &events, kubectlArgs...); err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	var since time.Time
+	if request.since > 0 {
+		since = time.Now().Add(-request.since)
+	}
+	result.Listed = len(events.Items)
+	result.Events = mergeEvents(events.Items, since, request.sortBy)
+	if len(result.Events) > request.limit {
+		result.Events = result.Events[:request.limit]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+func (t *EventsTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *EventsTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
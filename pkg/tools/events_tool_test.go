@@ -0,0 +1,125 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEventsArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     map[string]any
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "namespace",
+			args:     map[string]any{"namespace": "shop"},
+			expected: []string{"events", "--namespace", "shop"},
+		},
+		{
+			name:     "object and type",
+			args:     map[string]any{"object": "deploy/web", "type": "warning", "all_namespaces": true},
+			expected: []string{"events", "--all-namespaces", "--field-selector", "involvedObject.kind=Deployment,involvedObject.name=web,type=Warning"},
+		},
+		{
+			name:     "name alone and reason",
+			args:     map[string]any{"object": "web-0", "reason": "BackOff", "field_selector": "source=kubelet"},
+			expected: []string{"events", "--field-selector", "involvedObject.name=web-0,reason=BackOff,source=kubelet"},
+		},
+		{
+			name:     "unknown lowercase kind",
+			args:     map[string]any{"object": "widget/w1"},
+			expected: []string{"events", "--field-selector", "involvedObject.name=w1"},
+		},
+		{
+			name:     "capitalized kind",
+			args:     map[string]any{"object": "ConfigMap/settings"},
+			expected: []string{"events", "--field-selector", "involvedObject.kind=ConfigMap,involvedObject.name=settings"},
+		},
+		{
+			name: "invalid type",
+			args: map[string]any{"type": "Error"},
+			err:  true,
+		},
+		{
+			name: "invalid sort",
+			args: map[string]any{"sort_by": "name"},
+			err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := parseEventsArgs(tc.args)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := request.kubectlArgs(); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMergeEvents(t *testing.T) {
+	at := func(minute int) time.Time {
+		return time.Date(2025, 6, 1, 12, minute, 0, 0, time.UTC)
+	}
+	pod := objectReference{Kind: "Pod", Namespace: "shop", Name: "web-0"}
+	events := []eventObject{
+		{Type: "Warning", InvolvedObject: pod, Reason: "BackOff", Message: "Back-off restarting failed container", Count: 5, FirstTimestamp: at(1), LastTimestamp: at(10)},
+		{Type: "Normal", InvolvedObject: pod, Reason: "Pulled", Message: "Successfully pulled image in 1.2s", Count: 1, FirstTimestamp: at(2), LastTimestamp: at(2)},
+		{Type: "Normal", InvolvedObject: pod, Reason: "Pulled", Message: "Successfully pulled image in 3.4s", Count: 1, FirstTimestamp: at(8), LastTimestamp: at(8)},
+		{Type: "Warning", InvolvedObject: objectReference{Kind: "Node", Name: "n1"}, Reason: "NodeNotReady", Message: "Node is not ready", EventTime: at(5)},
+		{Type: "Normal", InvolvedObject: pod, Reason: "Scheduled", Message: "Successfully assigned", FirstTimestamp: at(0), LastTimestamp: at(0)},
+	}
+
+	backOff := ClusterEvent{Namespace: "shop", Object: "pod/web-0", Type: "Warning", Reason: "BackOff", Message: "Back-off restarting failed container", Count: 5, FirstSeen: at(1), LastSeen: at(10)}
+	pulled := ClusterEvent{Namespace: "shop", Object: "pod/web-0", Type: "Normal", Reason: "Pulled", Message: "Successfully pulled image in 3.4s", Count: 2, FirstSeen: at(2), LastSeen: at(8)}
+	notReady := ClusterEvent{Object: "node/n1", Type: "Warning", Reason: "NodeNotReady", Message: "Node is not ready", Count: 1, FirstSeen: at(5), LastSeen: at(5)}
+	// Only the second Pulled event is recent enough.
+	recentlyPulled := ClusterEvent{Namespace: "shop", Object: "pod/web-0", Type: "Normal", Reason: "Pulled", Message: "Successfully pulled image in 3.4s", Count: 1, FirstSeen: at(8), LastSeen: at(8)}
+	scheduled := ClusterEvent{Namespace: "shop", Object: "pod/web-0", Type: "Normal", Reason: "Scheduled", Message: "Successfully assigned", Count: 1, FirstSeen: at(0), LastSeen: at(0)}
+
+	testCases := []struct {
+		name     string
+		since    time.Time
+		sortBy   string
+		expected []ClusterEvent
+	}{
+		{name: "last seen", sortBy: sortEventsLastSeen, expected: []ClusterEvent{backOff, pulled, notReady, scheduled}},
+		{name: "first seen", sortBy: sortEventsFirstSeen, expected: []ClusterEvent{scheduled, backOff, pulled, notReady}},
+		{name: "count", sortBy: sortEventsCount, expected: []ClusterEvent{backOff, pulled, notReady, scheduled}},
+		{name: "since", since: at(6), sortBy: sortEventsLastSeen, expected: []ClusterEvent{backOff, recentlyPulled}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := mergeEvents(events, tc.since, tc.sortBy); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
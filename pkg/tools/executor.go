@@ -0,0 +1,253 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Executor runs the commands of the kubectl and bash tools, e.g. locally, or on another host.
+type Executor interface {
+	// Command returns the command that runs a bash command with the kubeconfig, in the working directory.
+	// Executors that don't run commands locally may use a kubeconfig and working directory of their own instead.
+	Command(ctx context.Context, command, workDir, kubeconfig string) (*exec.Cmd, error)
+}
+
+// LocalExecutor runs commands on this machine.
+type LocalExecutor struct{}
+
+var _ Executor = &LocalExecutor{}
+
+func (e *LocalExecutor) Command(ctx context.Context, command, workDir, kubeconfig string) (*exec.Cmd, error) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, os.Getenv("COMSPEC"), "/c", command)
+	} else {
+		cmd = exec.CommandContext(ctx, lookupBashBin(), "-c", command)
+	}
+	cmd.Dir = workDir
+	cmd.Env = commandEnv(ctx)
+	if kubeconfig != "" {
+		kubeconfig, err := expandShellVar(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	}
+	return cmd, nil
+}
+
+// isLocal returns true if the executor runs commands on this machine.
+func isLocal(executor Executor) bool {
+	_, ok := executor.(*LocalExecutor)
+	return ok
+}
+
+// UsesKubeconfig returns true if the commands run by the executor use the kubeconfig they are given, rather than one
+// of their own: locally, or in a new container of an image, where it is mounted.
+func UsesKubeconfig(executor Executor) bool {
+	switch e := executor.(type) {
+	case *LocalExecutor:
+		return true
+	case *DockerExecutor:
+		return e.Image != ""
+	}
+	return false
+}
+
+var _ Executor = &SSHTarget{}
+
+// PodExecutor runs commands with kubectl exec in a pod, e.g. a toolbox pod with kubectl and other tools installed.
+// The pod is reached with the local kubeconfig; commands in the pod use its service account, unless Kubeconfig is set.
+type PodExecutor struct {
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+	// Kubeconfig is the path of the kubeconfig in the pod.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// WorkDir is the directory in the pod that commands are run in.
+	WorkDir string `json:"workDir,omitempty"`
+}
+
+var _ Executor = &PodExecutor{}
+
+func (e *PodExecutor) Command(ctx context.Context, command, workDir, kubeconfig string) (*exec.Cmd, error) {
+	if err := checkEgress(ctx, e); err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, "kubectl", e.kubectlArgs(command)...)
+	cmd.Env = os.Environ()
+	if kubeconfig != "" {
+		kubeconfig, err := expandShellVar(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	}
+	return cmd, nil
+}
+
+// kubectlArgs returns the arguments of kubectl to run the bash command in the pod.
+func (e *PodExecutor) kubectlArgs(command string) []string {
+	args := []string{"exec", "-i"}
+	if e.Namespace != "" {
+		args = append(args, "-n", e.Namespace)
+	}
+	args = append(args, e.Pod)
+	if e.Container != "" {
+		args = append(args, "-c", e.Container)
+	}
+	return append(args, "--", "sh", "-c", remoteScript(command, e.WorkDir, e.Kubeconfig))
+}
+
+// DockerExecutor runs commands in a docker container: either in a running container, with docker exec,
+// or in a new container of an image for each command, with docker run.
+type DockerExecutor struct {
+	// Container is the name or ID of a running container.
+	Container string `json:"container,omitempty"`
+	// Image is the image to run commands in. The container uses the network of the host, and the local kubeconfig
+	// and working directory are mounted in it, so commands behave as they would locally, and go through the egress proxy.
+	Image string `json:"image,omitempty"`
+	// Kubeconfig is the path of the kubeconfig in a running container.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// WorkDir is the directory in a running container that commands are run in.
+	WorkDir string `json:"workDir,omitempty"`
+}
+
+var _ Executor = &DockerExecutor{}
+
+func (e *DockerExecutor) Command(ctx context.Context, command, workDir, kubeconfig string) (*exec.Cmd, error) {
+	if err := checkEgress(ctx, e); err != nil {
+		return nil, err
+	}
+	if kubeconfig != "" {
+		var err error
+		if kubeconfig, err = expandShellVar(kubeconfig); err != nil {
+			return nil, err
+		}
+	}
+	var env []string
+	if proxy := egressProxyFromContext(ctx); proxy != nil {
+		env = proxy.Env()
+	}
+	return exec.CommandContext(ctx, "docker", e.dockerArgs(command, workDir, kubeconfig, env)...), nil
+}
+
+// dockerArgs returns the arguments of docker to run the bash command, with the environment variables env.
+func (e *DockerExecutor) dockerArgs(command, workDir, kubeconfig string, env []string) []string {
+	if e.Image == "" {
+		args := []string{"exec", "-i"}
+		if e.WorkDir != "" {
+			args = append(args, "-w", e.WorkDir)
+		}
+		if e.Kubeconfig != "" {
+			args = append(args, "-e", "KUBECONFIG="+e.Kubeconfig)
+		}
+		return append(args, e.Container, "bash", "-c", command)
+	}
+
+	args := []string{"run", "--rm", "-i", "--network", "host"}
+	if workDir != "" {
+		args = append(args, "-v", workDir+":/work", "-w", "/work")
+	}
+	if kubeconfig != "" {
+		args = append(args, "-v", kubeconfig+":/kubeconfig:ro", "-e", "KUBECONFIG=/kubeconfig")
+	}
+	for _, v := range env {
+		args = append(args, "-e", v)
+	}
+	return append(args, e.Image, "bash", "-c", command)
+}
+
+// remoteScript returns a shell script that runs the bash command with the kubeconfig, in the working directory,
+// for executors that run commands through a shell that might not be bash.
+func remoteScript(command, workDir, kubeconfig string) string {
+	var script strings.Builder
+	if workDir != "" {
+		script.WriteString("cd " + shellQuote(workDir) + " && ")
+	}
+	if kubeconfig != "" {
+		script.WriteString("KUBECONFIG=" + shellQuote(kubeconfig) + " ")
+	}
+	script.WriteString("bash -c " + shellQuote(command))
+	return script.String()
+}
+
+// ParseExecutor parses the executor given by spec:
+//   - local, or "", runs commands locally,
+//   - ssh:<target> runs them over SSH, on one of sshTargets or on [user@]host[:port],
+//   - pod:[<namespace>/]<pod>[/<container>] runs them in a pod with kubectl exec,
+//   - docker:<container> runs them in a running container with docker exec,
+//   - docker-run:<image> runs them in a new container of the image with docker run.
+func ParseExecutor(spec string, sshTargets map[string]SSHTarget) (Executor, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	if kind != "" && kind != "local" && arg == "" {
+		return nil, fmt.Errorf("executor %q: missing target, e.g. %s:<name>", spec, kind)
+	}
+	switch kind {
+	case "", "local":
+		return &LocalExecutor{}, nil
+	case "ssh":
+		if target, ok := sshTargets[arg]; ok {
+			if err := target.Validate(); err != nil {
+				return nil, fmt.Errorf("SSH target %q: %w", arg, err)
+			}
+			return &target, nil
+		}
+		return ParseSSHTarget(arg)
+	case "pod":
+		parts := strings.Split(arg, "/")
+		switch len(parts) {
+		case 1:
+			return &PodExecutor{Pod: parts[0]}, nil
+		case 2:
+			return &PodExecutor{Namespace: parts[0], Pod: parts[1]}, nil
+		case 3:
+			return &PodExecutor{Namespace: parts[0], Pod: parts[1], Container: parts[2]}, nil
+		}
+		return nil, fmt.Errorf("executor %q: expected pod:[<namespace>/]<pod>[/<container>]", spec)
+	case "docker":
+		return &DockerExecutor{Container: arg}, nil
+	case "docker-run":
+		return &DockerExecutor{Image: arg}, nil
+	}
+	return nil, fmt.Errorf("executor %q is not known, supported executors: local, ssh, pod, docker, docker-run", spec)
+}
+
+type executorKey struct{}
+
+// WithExecutor returns a context in which the commands of the kubectl and bash tools are run by the executor.
+func WithExecutor(ctx context.Context, executor Executor) context.Context {
+	return context.WithValue(ctx, executorKey{}, executor)
+}
+
+// executorFromContext returns the executor that runs commands; by default, commands are run locally.
+func executorFromContext(ctx context.Context) Executor {
+	if executor, ok := ctx.Value(executorKey{}).(Executor); ok {
+		return executor
+	}
+	return &LocalExecutor{}
+}
+
+// shellCommand returns the command that runs a bash command, with the kubeconfig, in the working directory,
+// using the executor of the context.
+func shellCommand(ctx context.Context, command, workDir, kubeconfig string) (*exec.Cmd, error) {
+	return executorFromContext(ctx).Command(ctx, command, workDir, kubeconfig)
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseExecutor(t *testing.T) {
+	sshTargets := map[string]SSHTarget{
+		"prod": {Host: "bastion.prod", User: "ops"},
+	}
+
+	tests := []struct {
+		spec    string
+		want    Executor
+		wantErr bool
+	}{
+		{spec: "", want: &LocalExecutor{}},
+		{spec: "local", want: &LocalExecutor{}},
+		{spec: "ssh:prod", want: &SSHTarget{Host: "bastion.prod", User: "ops"}},
+		{spec: "ssh:admin@10.0.0.5:2222", want: &SSHTarget{Host: "10.0.0.5", User: "admin", Port: 2222}},
+		{spec: "pod:toolbox", want: &PodExecutor{Pod: "toolbox"}},
+		{spec: "pod:ops/toolbox", want: &PodExecutor{Namespace: "ops", Pod: "toolbox"}},
+		{spec: "pod:ops/toolbox/kubectl", want: &PodExecutor{Namespace: "ops", Pod: "toolbox", Container: "kubectl"}},
+		{spec: "docker:toolbox", want: &DockerExecutor{Container: "toolbox"}},
+		{spec: "docker-run:bitnami/kubectl:1.33", want: &DockerExecutor{Image: "bitnami/kubectl:1.33"}},
+		{spec: "pod:a/b/c/d", wantErr: true},
+		{spec: "ssh", wantErr: true},
+		{spec: "vm:foo", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseExecutor(tt.spec, sshTargets)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseExecutor(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseExecutor(%q) = %#v, want %#v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestEnforcesEgress(t *testing.T) {
+	tests := []struct {
+		executor Executor
+		want     bool
+	}{
+		{executor: &LocalExecutor{}, want: true},
+		{executor: &DockerExecutor{Image: "bitnami/kubectl"}, want: true},
+		{executor: &DockerExecutor{Container: "toolbox"}, want: false},
+		{executor: &PodExecutor{Pod: "toolbox"}, want: false},
+		{executor: &SSHTarget{Host: "bastion"}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := EnforcesEgress(tt.executor); got != tt.want {
+			t.Errorf("EnforcesEgress(%#v) = %v, want %v", tt.executor, got, tt.want)
+		}
+	}
+}
+
+func TestUsesKubeconfig(t *testing.T) {
+	tests := []struct {
+		executor Executor
+		want     bool
+	}{
+		{executor: &LocalExecutor{}, want: true},
+		{executor: &DockerExecutor{Image: "bitnami/kubectl"}, want: true},
+		{executor: &DockerExecutor{Container: "toolbox"}, want: false},
+		{executor: &PodExecutor{Pod: "toolbox"}, want: false},
+		{executor: &SSHTarget{Host: "bastion"}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := UsesKubeconfig(tt.executor); got != tt.want {
+			t.Errorf("UsesKubeconfig(%#v) = %v, want %v", tt.executor, got, tt.want)
+		}
+	}
+}
+
+func TestExecutorArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{
+			name: "pod",
+			got:  (&PodExecutor{Namespace: "ops", Pod: "toolbox", Container: "kubectl", WorkDir: "/tmp"}).kubectlArgs("kubectl get pods"),
+			want: []string{"exec", "-i", "-n", "ops", "toolbox", "-c", "kubectl", "--", "sh", "-c", "cd /tmp && bash -c 'kubectl get pods'"},
+		},
+		{
+			name: "docker exec",
+			got:  (&DockerExecutor{Container: "toolbox", Kubeconfig: "/root/.kube/config"}).dockerArgs("kubectl get pods", "/tmp/work", "/home/me/.kube/config", nil),
+			want: []string{"exec", "-i", "-e", "KUBECONFIG=/root/.kube/config", "toolbox", "bash", "-c", "kubectl get pods"},
+		},
+		{
+			name: "docker run",
+			got:  (&DockerExecutor{Image: "bitnami/kubectl"}).dockerArgs("kubectl get pods", "/tmp/work", "/home/me/.kube/config", nil),
+			want: []string{
+				"run", "--rm", "-i", "--network", "host", "-v", "/tmp/work:/work", "-w", "/work",
+				"-v", "/home/me/.kube/config:/kubeconfig:ro", "-e", "KUBECONFIG=/kubeconfig",
+				"bitnami/kubectl", "bash", "-c", "kubectl get pods",
+			},
+		},
+		{
+			name: "docker run with egress proxy",
+			got:  (&DockerExecutor{Image: "bitnami/kubectl"}).dockerArgs("kubectl get pods", "", "", []string{"HTTPS_PROXY=http://127.0.0.1:3128"}),
+			want: []string{"run", "--rm", "-i", "--network", "host", "-e", "HTTPS_PROXY=http://127.0.0.1:3128", "bitnami/kubectl", "bash", "-c", "kubectl get pods"},
+		},
+	}
+
+	for _, tt := range tests {
+		if !slices.Equal(tt.got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&ExplainTool{})
+}
+
+const (
+	// maxExplainBytes is how much of the kubectl explain output and of the schema is returned.
+	maxExplainBytes = 30000
+	// schemaRefDepth is how many levels of references to other schemas are inlined in the schema of a field.
+	schemaRefDepth = 2
+)
+
+// ExplainTool returns the documentation of a resource or field from kubectl explain, and its OpenAPI schema as
+// served by the cluster, including CRDs, so that manifests are written against the API of the target cluster.
+type ExplainTool struct{}
+
+func (t *ExplainTool) Name() string {
+	return "explain_resource"
+}
+
+func (t *ExplainTool) Description() string {
+	return `Explains a resource kind or one of its fields as served by the API server of the cluster (kubectl explain),
+including custom resources: its group, version, kind, type and description, and the fields below it. Optionally also
+returns the OpenAPI v3 schema of the field from the cluster, as JSON, with its types, required fields, enums and formats.
+Use this tool before writing or patching manifests, especially of custom resources or with fields you are unsure of,
+to check which fields exist and what they accept in the cluster's version of the API.`
+}
+
+func (t *ExplainTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"resource": {
+					Type:        gollm.TypeString,
+					Description: `The resource type, e.g. "deployment", "pods" or "certificates".`,
+				},
+				"field": {
+					Type:        gollm.TypeString,
+					Description: `The path of a field of the resource, e.g. "spec.template.spec.containers.resources". Empty explains the resource itself.`,
+				},
+				"api_version": {
+					Type:        gollm.TypeString,
+					Description: `The group/version to explain, e.g. "autoscaling/v2", when the resource is served in several versions. Defaults to the preferred version.`,
+				},
+				"recursive": {
+					Type:        gollm.TypeBoolean,
+					Description: `List all the fields below the field, recursively, with their types but without descriptions.`,
+				},
+				"schema": {
+					Type:        gollm.TypeBoolean,
+					Description: `Also return the OpenAPI v3 schema of the field from the cluster, as JSON.`,
+				},
+			},
+			Required: []string{"resource"},
+		},
+	}
+}
+
+// ExplainResult is the result of the explain_resource tool.
+type ExplainResult struct {
+	// Command is the kubectl explain command that was run.
+	Command string `json:"command"`
+	Group   string `json:"group,omitempty"`
+	Version string `json:"version,omitempty"`
+	Kind    string `json:"kind,omitempty"`
+	// Explanation is the output of kubectl explain.
+	Explanation string `json:"explanation,omitempty"`
+	// Schema is the OpenAPI v3 schema of the field, as JSON, with references to other schemas inlined to a depth.
+	Schema string `json:"schema,omitempty"`
+	// Truncated is set if the explanation or the schema was too long, and only its beginning is returned.
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (t *ExplainTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ExplainResult]()
+}
+
+// explainArgs returns the arguments of the kubectl explain command for an explain_resource call.
+func explainArgs(args map[string]any) ([]string, error) {
+	resource := strings.TrimSpace(stringArg(args, "resource"))
+	if resource == "" || strings.ContainsAny(resource, ". ") {
+		return nil, fmt.Errorf("invalid resource %q: give a resource type such as deployment, and the path of the field separately", resource)
+	}
+	path := resource
+	if field := strings.Trim(strings.TrimSpace(stringArg(args, "field")), "."); field != "" {
+		path += "." + field
+	}
+	kubectlArgs := []string{"explain", path}
+	if apiVersion := stringArg(args, "api_version"); apiVersion != "" {
+		kubectlArgs = append(kubectlArgs, "--api-version", apiVersion)
+	}
+	if boolArg(args, "recursive") {
+		kubectlArgs = append(kubectlArgs, "--recursive")
+	}
+	return kubectlArgs, nil
+}
+
+// explainHeader matches the lines at the top of kubectl explain output, e.g. "KIND:     Deployment".
+var explainHeader = regexp.MustCompile(`(?m)^(GROUP|KIND|VERSION):\s*(\S+)\s*$`)
+
+// parseExplainHeader returns the group, version and kind of the resource from kubectl explain output.
+// Recent versions of kubectl print the group on its own line, older ones as part of the version (e.g. apps/v1).
+func parseExplainHeader(output string) (group, version, kind string) {
+	for _, m := range explainHeader.FindAllStringSubmatch(output, -1) {
+		switch m[1] {
+		case "GROUP":
+			group = m[2]
+		case "KIND":
+			kind = m[2]
+		case "VERSION":
+			version = m[2]
+		}
+	}
+	if g, v, ok := strings.Cut(version, "/"); ok {
+		group, version = g, v
+	}
+	return group, version, kind
+}
+
+// openAPIPath returns the path of the OpenAPI v3 document of a group version on the API server.
+func openAPIPath(group, version string) string {
+	if group == "" || group == "core" {
+		return "/openapi/v3/api/" + version
+	}
+	return "/openapi/v3/apis/" + group + "/" + version
+}
+
+// openAPIDocument is the part of an OpenAPI v3 document we use: the schemas of the kinds.
+type openAPIDocument struct {
+	Components struct {
+		Schemas map[string]map[string]any `json:"schemas"`
+	} `json:"components"`
+}
+
+// kindSchema returns the name of the schema of a kind in the document.
+func (d *openAPIDocument) kindSchema(group, version, kind string) (string, bool) {
+	for name, schema := range d.Components.Schemas {
+		gvks, _ := schema["x-kubernetes-group-version-kind"].([]any)
+		for _, gvk := range gvks {
+			gvk, _ := gvk.(map[string]any)
+			if gvk["group"] == group && gvk["version"] == version && gvk["kind"] == kind {
+				return name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// deref follows the reference of a schema, which OpenAPI v3 documents from the API server wrap in allOf
+// when the property has other attributes, e.g. {"allOf": [{"$ref": "..."}], "default": {}}.
+func (d *openAPIDocument) deref(schema map[string]any) (map[string]any, string) {
+	ref, _ := schema["$ref"].(string)
+	if allOf, ok := schema["allOf"].([]any); ok && len(allOf) == 1 && ref == "" {
+		if inner, ok := allOf[0].(map[string]any); ok {
+			ref, _ = inner["$ref"].(string)
+		}
+	}
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		return schema, ""
+	}
+	if target, ok := d.Components.Schemas[name]; ok {
+		return target, name
+	}
+	return schema, ""
+}
+
+// fieldSchema returns the schema of the field at path below the schema with the given name.
+// Like kubectl explain, the fields of the items of arrays and the values of maps are reached directly,
+// e.g. "containers.image".
+func (d *openAPIDocument) fieldSchema(name string, path []string) (map[string]any, error) {
+	schema, ok := d.Components.Schemas[name]
+	if !ok {
+		return nil, fmt.Errorf("schema %q not found", name)
+	}
+	for i, field := range path {
+		schema, _ = d.deref(schema)
+		for {
+			if items, ok := schema["items"].(map[string]any); ok {
+				schema, _ = d.deref(items)
+			} else if values, ok := schema["additionalProperties"].(map[string]any); ok {
+				schema, _ = d.deref(values)
+			} else {
+				break
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		next, ok := properties[field].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("field %q not found", strings.Join(path[:i+1], "."))
+		}
+		schema = next
+	}
+	return schema, nil
+}
+
+// inline returns a copy of the schema with the references to other schemas replaced by the schemas, to depth
+// levels of references; deeper references are left as they are.
+func (d *openAPIDocument) inline(schema any, depth int) any {
+	switch v := schema.(type) {
+	case map[string]any:
+		if target, name := d.deref(v); name != "" {
+			if depth == 0 {
+				return v
+			}
+			inlined := d.inline(target, depth-1).(map[string]any)
+			// Keep the attributes of the property, e.g. its description, along with the referenced schema.
+			for key, value := range v {
+				if key != "allOf" && key != "$ref" {
+					inlined[key] = d.inline(value, depth)
+				}
+			}
+			return inlined
+		}
+		inlined := make(map[string]any, len(v))
+		for key, value := range v {
+			inlined[key] = d.inline(value, depth)
+		}
+		return inlined
+	case []any:
+		inlined := make([]any, len(v))
+		for i, value := range v {
+			inlined[i] = d.inline(value, depth)
+		}
+		return inlined
+	default:
+		return v
+	}
+}
+
+// truncateText returns at most max bytes of s, and whether it was cut.
+func truncateText(s string, max int) (string, bool) {
+	if len(s) <= max {
+		return s, false
+	}
+	return s[:max] + "\n...", true
+}
+
+func (t *ExplainTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &ExplainResult{}
+	kubectlArgs, err := explainArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Command = "kubectl " + strings.Join(kubectlArgs, " ")
+
+	output, err := runKubectl(ctx, kubectlArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if output.Error != "" || output.ExitCode != 0 {
+		result.Error = strings.TrimSpace(output.Error + " " + output.Stderr)
+		return result, nil
+	}
+	result.Group, result.Version, result.Kind = parseExplainHeader(output.Stdout)
+	var truncated bool
+	result.Explanation, truncated = truncateText(strings.TrimSpace(output.Stdout), maxExplainBytes)
+	result.Truncated = truncated
+
+	if boolArg(args, "schema") {
+		schema, err := t.schema(ctx, result.Group, result.Version, result.Kind, stringArg(args, "field"))
+		if err != nil {
+			// The explanation is still useful without the schema.
+			result.Error = fmt.Sprintf("could not get the OpenAPI schema: %v", err)
+			return result, nil
+		}
+		result.Schema, truncated = truncateText(schema, maxExplainBytes)
+		result.Truncated = result.Truncated || truncated
+	}
+	return result, nil
+}
+
+// schema returns the OpenAPI v3 schema of the field of a kind, from the API server, as JSON.
+func (t *ExplainTool) schema(ctx context.Context, group, version, kind, field string) (string, error) {
+	if version == "" || kind == "" {
+		return "", fmt.Errorf("kubectl explain did not print the version and kind of the resource")
+	}
+	path := openAPIPath(group, version)
+	output, err := runKubectl(ctx, "get", "--raw", path)
+	if err != nil {
+		return "", err
+	}
+	if output.Error != "" || output.ExitCode != 0 {
+		return "", fmt.Errorf("reading %s: %s", path, strings.TrimSpace(output.Error+" "+output.Stderr))
+	}
+	var doc openAPIDocument
+	if err := json.Unmarshal([]byte(output.Stdout), &doc); err != nil {
+		return "", fmt.Errorf("parsing %s: %w", path, err)
+	}
+	name, ok := doc.kindSchema(group, version, kind)
+	if !ok {
+		return "", fmt.Errorf("no schema for %s in %s", kind, path)
+	}
+	var fields []string
+	if field = strings.Trim(strings.TrimSpace(field), "."); field != "" {
+		fields = strings.Split(field, ".")
+	}
+	schema, err := doc.fieldSchema(name, fields)
+	if err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(doc.inline(schema, schemaRefDepth), "", " ")
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (t *ExplainTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *ExplainTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,133 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestExplainArgs(t *testing.T) {
+	args, err := explainArgs(map[string]any{"resource": "deployment", "field": ".spec.template.", "api_version": "apps/v1", "recursive": true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []string{"explain", "deployment.spec.template", "--api-version", "apps/v1", "--recursive"}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+	for _, resource := range []string{"", "deployment.spec"} {
+		if _, err := explainArgs(map[string]any{"resource": resource}); err == nil {
+			t.Errorf("expected an error for resource %q", resource)
+		}
+	}
+}
+
+func TestParseExplainHeader(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		output               string
+		group, version, kind string
+	}{
+		{
+			name:   "recent kubectl",
+			output: "GROUP:      apps\nKIND:       Deployment\nVERSION:    v1\n\nFIELD: replicas <integer>\n",
+			group:  "apps", version: "v1", kind: "Deployment",
+		},
+		{
+			name:   "older kubectl",
+			output: "KIND:     Deployment\nVERSION:  apps/v1\n\nDESCRIPTION:\n     Deployment enables declarative updates.\n",
+			group:  "apps", version: "v1", kind: "Deployment",
+		},
+		{
+			name:    "core group",
+			output:  "KIND:       Pod\nVERSION:    v1\n",
+			version: "v1", kind: "Pod",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			group, version, kind := parseExplainHeader(tc.output)
+			if group != tc.group || version != tc.version || kind != tc.kind {
+				t.Errorf("expected %q %q %q, got %q %q %q", tc.group, tc.version, tc.kind, group, version, kind)
+			}
+		})
+	}
+}
+
+func TestFieldSchema(t *testing.T) {
+	var doc openAPIDocument
+	err := json.Unmarshal([]byte(`{"components": {"schemas": {
+		"io.k8s.api.apps.v1.Deployment": {
+			"x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}],
+			"properties": {"spec": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.apps.v1.DeploymentSpec"}], "description": "Desired behavior."}}
+		},
+		"io.k8s.api.apps.v1.DeploymentSpec": {
+			"properties": {
+				"replicas": {"type": "integer"},
+				"containers": {"type": "array", "items": {"allOf": [{"$ref": "#/components/schemas/io.k8s.api.core.v1.Container"}]}}
+			}
+		},
+		"io.k8s.api.core.v1.Container": {
+			"required": ["name"],
+			"properties": {"name": {"type": "string"}, "resources": {"$ref": "#/components/schemas/io.k8s.api.core.v1.ResourceRequirements"}}
+		},
+		"io.k8s.api.core.v1.ResourceRequirements": {"properties": {"limits": {"type": "object"}}}
+	}}}`), &doc)
+	if err != nil {
+		t.Fatalf("parsing document: %v", err)
+	}
+
+	name, ok := doc.kindSchema("apps", "v1", "Deployment")
+	if !ok || name != "io.k8s.api.apps.v1.Deployment" {
+		t.Fatalf("expected the Deployment schema, got %q, %v", name, ok)
+	}
+
+	schema, err := doc.fieldSchema(name, []string{"spec", "containers", "name"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(schema, map[string]any{"type": "string"}) {
+		t.Errorf("unexpected schema for spec.containers.name: %v", schema)
+	}
+	if _, err := doc.fieldSchema(name, []string{"spec", "replica"}); err == nil {
+		t.Errorf("expected an error for an unknown field")
+	}
+
+	// References are inlined to the given depth; the attributes of the property are kept.
+	schema, err = doc.fieldSchema(name, []string{"spec"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := doc.inline(schema, 2)
+	expected := map[string]any{
+		"description": "Desired behavior.",
+		"properties": map[string]any{
+			"replicas": map[string]any{"type": "integer"},
+			"containers": map[string]any{"type": "array", "items": map[string]any{
+				"required": []any{"name"},
+				"properties": map[string]any{
+					"name":      map[string]any{"type": "string"},
+					"resources": map[string]any{"$ref": "#/components/schemas/io.k8s.api.core.v1.ResourceRequirements"},
+				},
+			}},
+		},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
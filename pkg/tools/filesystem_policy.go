@@ -0,0 +1,607 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+type filesystemPolicyKey struct{}
+
+// FilesystemPolicy confines the files that bash commands may write to: the working directory of the agent,
+// and the allowed paths. Commands are checked before they run, by finding the files they write to
+// (redirections, and the arguments of programs such as rm, cp or tee). Programs that are not known to only read
+// their arguments may not be given paths outside the allowed ones. It is not a sandbox, and cannot see what programs
+// write to of their own accord.
+type FilesystemPolicy struct {
+	// AllowedPaths are the files and directories, besides the working directory, that commands may write to.
+	// A leading ~ is the home directory.
+	AllowedPaths []string
+}
+
+// WithFilesystemPolicy returns a context in which the bash tool only runs commands that write where the policy allows.
+func WithFilesystemPolicy(ctx context.Context, policy *FilesystemPolicy) context.Context {
+	return context.WithValue(ctx, filesystemPolicyKey{}, policy)
+}
+
+// filesystemPolicyFromContext returns the filesystem policy of the context, or nil if writes are not restricted.
+func filesystemPolicyFromContext(ctx context.Context) *FilesystemPolicy {
+	policy, _ := ctx.Value(filesystemPolicyKey{}).(*FilesystemPolicy)
+	return policy
+}
+
+// checkFilesystemPolicy returns the result of a command that is not run because it writes outside the allowed paths,
+// or nil if it may run. Commands run by remote executors are not checked, as the paths are not on this machine.
+func checkFilesystemPolicy(ctx context.Context, command string, workDir string) *ExecResult {
+	policy := filesystemPolicyFromContext(ctx)
+	if policy == nil || !isLocal(executorFromContext(ctx)) {
+		return nil
+	}
+	home, _ := os.UserHomeDir()
+	if reason := policy.writeViolation(command, workDir, home); reason != "" {
+		return &ExecResult{
+			Command: command,
+			Error: fmt.Sprintf("commands may only write to the working directory (%s) and the allowed paths (--bash-write-paths): %s. "+
+				"Write files to the working directory instead.", workDir, reason),
+		}
+	}
+	return nil
+}
+
+// alwaysWritablePaths are the device files that commands may always write to.
+var alwaysWritablePaths = []string{"/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty", "/dev/fd"}
+
+// writeViolation returns why the command, run in workDir, would write outside the allowed paths, or "" if it would not.
+// Commands that cannot be parsed are not allowed, as we cannot tell what they write to.
+func (p *FilesystemPolicy) writeViolation(command string, workDir string, home string) string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return "the command could not be parsed"
+	}
+
+	// The allowed paths are compared with the targets after resolving symbolic links in both.
+	var allowed []string
+	for _, allowedPath := range append([]string{workDir}, p.AllowedPaths...) {
+		allowedPath = filepath.Clean(expandHome(allowedPath, home))
+		allowed = append(allowed, allowedPath, resolveSymlinks(allowedPath))
+	}
+	within := func(target string, roots []string) bool {
+		for _, root := range roots {
+			if rel, err := filepath.Rel(root, target); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+				return true
+			}
+		}
+		return false
+	}
+
+	// dir is the directory the command is in, following cd and pushd; it is empty if we cannot tell.
+	dir := workDir
+	// dirStack holds the directories saved by pushd.
+	var dirStack []string
+	var reason string
+	// check sets reason if target is outside the allowed paths; verb says what what does with it, e.g. writes to.
+	check := func(what string, verb string, target string, static bool) {
+		if reason != "" {
+			return
+		}
+		if !static {
+			reason = fmt.Sprintf("cannot tell where %s %s (%s)", what, verb, target)
+			return
+		}
+		target = expandHome(target, home)
+		if !filepath.IsAbs(target) {
+			if dir == "" {
+				reason = fmt.Sprintf("cannot tell where %s %s after cd", what, verb)
+				return
+			}
+			target = filepath.Join(dir, target)
+		}
+		target = filepath.Clean(target)
+		if within(target, alwaysWritablePaths) {
+			return
+		}
+		// A symbolic link in an allowed directory may point anywhere, so we check where the write ends up.
+		if resolved := resolveSymlinks(target); !within(resolved, allowed) {
+			if resolved != target {
+				target = fmt.Sprintf("%s (through a symbolic link, %s)", resolved, target)
+			}
+			reason = fmt.Sprintf("%s %s %s", what, verb, target)
+		}
+	}
+
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if reason != "" {
+			return false
+		}
+		switch node := node.(type) {
+		case *syntax.Redirect:
+			switch node.Op {
+			case syntax.RdrOut, syntax.AppOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll, syntax.RdrInOut:
+				target, static := wordValue(node.Word, home)
+				check("the redirection "+node.Op.String(), "writes to", target, static)
+			case syntax.DplOut:
+				// >&2 duplicates a file descriptor, but >&file writes to a file.
+				if target, static := wordValue(node.Word, home); !static || !isFileDescriptor(target) {
+					check("the redirection "+node.Op.String(), "writes to", target, static)
+				}
+			}
+		case *syntax.CallExpr:
+			if len(node.Args) == 0 {
+				return true
+			}
+			var args []commandArg
+			for _, word := range node.Args {
+				value, static := wordValue(word, home)
+				args = append(args, commandArg{value: value, static: static})
+			}
+			switch path.Base(args[0].value) {
+			case "cd":
+				dir = changeDir(dir, args[1:], home)
+				return true
+			case "pushd":
+				dirStack = append(dirStack, dir)
+				dir = changeDir(dir, args[1:], home)
+				if len(args) == 1 || strings.HasPrefix(args[1].value, "+") || strings.HasPrefix(args[1].value, "-") {
+					// pushd without a directory, or with a position in the stack, rotates the stack.
+					dir = ""
+				}
+				return true
+			case "popd":
+				dir = ""
+				if len(args) == 1 && len(dirStack) > 0 {
+					dir = dirStack[len(dirStack)-1]
+					dirStack = dirStack[:len(dirStack)-1]
+				}
+				return true
+			}
+			unwrapped, written, unknown := unwrapCommand(args)
+			if unknown != "" {
+				reason = unknown
+				return false
+			}
+			for _, arg := range written {
+				check(path.Base(args[0].value), "writes to", arg.value, arg.static)
+			}
+			if len(unwrapped) == 0 {
+				return true
+			}
+			program := path.Base(unwrapped[0].value)
+			if shellPrograms[program] && slices.ContainsFunc(unwrapped[1:], isShellCommandFlag) {
+				reason = fmt.Sprintf("cannot tell what %s -c writes to", program)
+				return false
+			}
+			if program == "eval" {
+				reason = "cannot tell what eval writes to"
+				return false
+			}
+			if changesDir(args, unwrapped) {
+				// The wrapped command runs in another directory, which we don't follow.
+				saved := dir
+				dir = ""
+				defer func() { dir = saved }()
+			}
+			written, known := writtenArgs(program, unwrapped)
+			for _, arg := range written {
+				check(program, "writes to", arg.value, arg.static)
+			}
+			if !known {
+				// We don't know what the program writes to, so it may only be given paths in the allowed ones.
+				for _, arg := range pathArgs(unwrapped[1:]) {
+					check(program, "is given", arg.value, arg.static)
+				}
+			}
+		}
+		return true
+	})
+	return reason
+}
+
+// shellPrograms are the shells that run the commands given with -c, which we don't look into.
+var shellPrograms = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "ash": true, "fish": true,
+}
+
+// isShellCommandFlag returns true for -c, alone or with other single-letter flags of a shell, e.g. -ec.
+func isShellCommandFlag(arg commandArg) bool {
+	return strings.HasPrefix(arg.value, "-") && !strings.HasPrefix(arg.value, "--") && strings.Contains(arg.value, "c")
+}
+
+// commandWrappers are the programs that run a command given as their arguments, with the flags of each that take
+// a separate value.
+var commandWrappers = map[string][]string{
+	"sudo":    {"-u", "--user", "-g", "--group", "-C", "--close-from", "-D", "--chdir", "-h", "--host", "-p", "--prompt", "-r", "--role", "-t", "--type", "-U", "--other-user", "-T", "--command-timeout"},
+	"doas":    {"-u", "-C"},
+	"env":     {"-u", "--unset", "-C", "--chdir"},
+	"xargs":   {"-I", "-n", "--max-args", "-P", "--max-procs", "-d", "--delimiter", "-a", "--arg-file", "-E", "-L", "--max-lines", "-s", "--max-chars"},
+	"nohup":   nil,
+	"timeout": {"-s", "--signal", "-k", "--kill-after"},
+	"nice":    {"-n", "--adjustment"},
+	"ionice":  {"-c", "--class", "-n", "--classdata"},
+	"stdbuf":  {"-i", "--input", "-o", "--output", "-e", "--error"},
+	"time":    {"-f", "--format", "-o", "--output"},
+	"command": nil,
+	"exec":    {"-a"},
+	"setsid":  nil,
+}
+
+// chdirFlags are the flags of command wrappers that run the command in another directory.
+var chdirFlags = []string{"-D", "-C", "--chdir"}
+
+// unwrapCommand returns the command that wrappers such as sudo, env, xargs or timeout run, and the files the wrappers
+// write to themselves (time -o). The arguments that xargs adds are only known when the command runs.
+// unknown is set if we cannot tell what the wrapper runs, e.g. with env -S.
+func unwrapCommand(args []commandArg) (unwrapped []commandArg, written []commandArg, unknown string) {
+	for len(args) > 0 {
+		wrapper := path.Base(args[0].value)
+		flags, ok := commandWrappers[wrapper]
+		if !ok {
+			break
+		}
+		i := 1
+		for ; i < len(args); i++ {
+			arg := args[i].value
+			if arg == "--" {
+				i++
+				break
+			}
+			if wrapper == "env" && !strings.HasPrefix(arg, "-") && strings.Contains(arg, "=") {
+				// An environment variable.
+				continue
+			}
+			if !strings.HasPrefix(arg, "-") || arg == "-" {
+				break
+			}
+			name, _, hasValue := strings.Cut(arg, "=")
+			switch {
+			case wrapper == "env" && (name == "-S" || name == "--split-string" || strings.HasPrefix(name, "-S")):
+				return nil, nil, "cannot tell what env -S runs"
+			case wrapper == "sudo" && (name == "-s" || name == "--shell" || name == "-i" || name == "--login"):
+				return nil, nil, fmt.Sprintf("cannot tell what sudo %s writes to", name)
+			case wrapper == "time" && (name == "-o" || name == "--output"):
+				if hasValue {
+					_, value, _ := strings.Cut(arg, "=")
+					written = append(written, commandArg{value: value, static: args[i].static})
+				} else if i+1 < len(args) {
+					written = append(written, args[i+1])
+				}
+			}
+			if slices.Contains(flags, name) && !hasValue {
+				i++
+			}
+		}
+		if wrapper == "timeout" && i < len(args) {
+			// The duration.
+			i++
+		}
+		if i >= len(args) {
+			return nil, written, ""
+		}
+		args = args[i:]
+		if wrapper == "xargs" {
+			args = append(slices.Clone(args), commandArg{value: "<arguments read by xargs>", static: false})
+		}
+	}
+	return args, written, ""
+}
+
+// changesDir returns true if the wrappers of a command (the arguments before unwrapped) run it in another directory.
+func changesDir(args []commandArg, unwrapped []commandArg) bool {
+	for _, arg := range args[:len(args)-len(unwrapped)] {
+		name, _, _ := strings.Cut(arg.value, "=")
+		if slices.Contains(chdirFlags, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveSymlinks returns p with the symbolic links in it resolved, including links to files that don't exist yet,
+// so that a write through a link is checked where it ends up. The part of p that doesn't exist is kept as it is.
+func resolveSymlinks(p string) string {
+	// Links are followed a bounded number of times, in case of loops.
+	for range 40 {
+		existing, rest := p, ""
+		for {
+			if _, err := os.Lstat(existing); err == nil {
+				break
+			}
+			parent := filepath.Dir(existing)
+			if parent == existing {
+				return p
+			}
+			rest = filepath.Join(filepath.Base(existing), rest)
+			existing = parent
+		}
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		// existing is a link to a file that doesn't exist: follow it, and resolve where it points to.
+		target, err := os.Readlink(existing)
+		if err != nil {
+			return p
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(existing), target)
+		}
+		p = filepath.Join(target, rest)
+	}
+	return p
+}
+
+// commandArg is an argument of a command.
+type commandArg struct {
+	value string
+	// static is false if the value depends on expansions done when the command runs, e.g. $DIR.
+	static bool
+}
+
+// readOnlyPrograms are the programs known not to write to the files they are given.
+var readOnlyPrograms = map[string]bool{
+	"cat": true, "head": true, "tail": true, "less": true, "more": true, "grep": true, "egrep": true, "fgrep": true,
+	"ls": true, "wc": true, "diff": true, "stat": true, "file": true, "jq": true, "echo": true, "printf": true,
+	"test": true, "[": true, "basename": true, "dirname": true, "realpath": true, "readlink": true, "which": true,
+	"du": true, "df": true, "md5sum": true, "sha1sum": true, "sha256sum": true, "base64": true, "cut": true,
+	"tr": true, "uniq": true, "column": true, "true": true, "false": true, "sleep": true, "date": true,
+}
+
+// writtenArgs returns the arguments of a call to program that are files or directories it writes to.
+// known is false if the program is not known, so we cannot tell what it writes to.
+func writtenArgs(program string, args []commandArg) (written []commandArg, known bool) {
+	var operands []commandArg
+	for _, arg := range args[1:] {
+		if !strings.HasPrefix(arg.value, "-") || arg.value == "-" {
+			operands = append(operands, arg)
+		}
+	}
+	// flagValues returns the values of the flags, given as -f value, -fvalue, --flag value or --flag=value,
+	// or as the last of combined short flags, e.g. -rt value.
+	flagValues := func(names ...string) []commandArg {
+		var values []commandArg
+		for i := 1; i < len(args); i++ {
+			arg := args[i].value
+			if arg == "--" {
+				break
+			}
+			next := func() {
+				if i+1 < len(args) {
+					values = append(values, args[i+1])
+					i++
+				}
+			}
+			if strings.HasPrefix(arg, "--") {
+				name, value, hasValue := strings.Cut(arg, "=")
+				if slices.Contains(names, name) {
+					if hasValue {
+						values = append(values, commandArg{value: value, static: args[i].static})
+					} else {
+						next()
+					}
+				}
+				continue
+			}
+			if !strings.HasPrefix(arg, "-") || arg == "-" {
+				continue
+			}
+			for j := 1; j < len(arg); j++ {
+				if !slices.Contains(names, "-"+arg[j:j+1]) {
+					continue
+				}
+				if value := arg[j+1:]; value != "" {
+					values = append(values, commandArg{value: value, static: args[i].static})
+				} else {
+					next()
+				}
+				break
+			}
+		}
+		return values
+	}
+
+	if readOnlyPrograms[program] {
+		return nil, true
+	}
+	switch program {
+	case "tee", "touch", "rm", "rmdir", "mkdir", "truncate", "shred", "unlink", "mkfifo":
+		return operands, true
+	case "chmod", "chown", "chgrp":
+		// The first operand is the mode or owner.
+		if len(operands) > 1 {
+			return operands[1:], true
+		}
+		return nil, true
+	case "cp", "mv", "install", "ln", "rsync", "scp":
+		if target := flagValues("-t", "--target-directory"); len(target) > 0 {
+			return target, true
+		}
+		if len(operands) > 1 {
+			last := operands[len(operands)-1]
+			if (program == "rsync" || program == "scp") && strings.Contains(last.value, ":") {
+				// A remote destination.
+				return nil, true
+			}
+			return []commandArg{last}, true
+		}
+		return nil, true
+	case "dd":
+		for _, arg := range args[1:] {
+			if output, ok := strings.CutPrefix(arg.value, "of="); ok {
+				return []commandArg{{value: output, static: arg.static}}, true
+			}
+		}
+		return nil, true
+	case "sed", "perl":
+		inPlace, script := false, false
+		for _, arg := range args[1:] {
+			arg := arg.value
+			inPlace = inPlace || strings.HasPrefix(arg, "-i") || arg == "--in-place" || (program == "perl" && strings.HasPrefix(arg, "-p") && strings.Contains(arg, "i"))
+			script = script || arg == "-e" || arg == "-f" || strings.HasPrefix(arg, "--expression") || strings.HasPrefix(arg, "--file")
+		}
+		if !inPlace {
+			return nil, true
+		}
+		if !script && len(operands) > 0 {
+			// The first operand is the script.
+			return operands[1:], true
+		}
+		return operands, true
+	case "sort":
+		return flagValues("-o", "--output"), true
+	case "curl":
+		return flagValues("-o", "--output"), true
+	case "wget":
+		return flagValues("-O", "--output-document", "-P", "--directory-prefix"), true
+	case "tar":
+		return flagValues("-C", "--directory"), true
+	case "unzip":
+		return flagValues("-d"), true
+	case "git":
+		// git writes to the repository it runs in, which -C changes; clone writes to its destination.
+		written := flagValues("-C")
+		if len(operands) > 2 && operands[0].value == "clone" {
+			written = append(written, operands[len(operands)-1])
+		}
+		return written, true
+	case "find":
+		// find writes to the files it finds with -delete, and may with -exec.
+		for _, arg := range args[1:] {
+			if arg.value == "-delete" || arg.value == "-exec" || arg.value == "-execdir" {
+				var roots []commandArg
+				for _, root := range args[1:] {
+					if strings.HasPrefix(root.value, "-") {
+						break
+					}
+					roots = append(roots, root)
+				}
+				return roots, true
+			}
+		}
+		return nil, true
+	case "kubectl":
+		// kubectl cp <pod>:<path> <local path>
+		if len(operands) > 2 && operands[0].value == "cp" {
+			if last := operands[len(operands)-1]; !strings.Contains(last.value, ":") {
+				return []commandArg{last}, true
+			}
+		}
+		return nil, true
+	}
+	return nil, false
+}
+
+// pathArgs returns the arguments, or values of --flag=value arguments, that are paths outside the directory
+// the command runs in: absolute, in the home directory, or going up with "..".
+// Arguments that depend on expansions are left out, as most are not paths.
+func pathArgs(args []commandArg) []commandArg {
+	var paths []commandArg
+	for _, arg := range args {
+		if !arg.static {
+			continue
+		}
+		value := arg.value
+		if strings.HasPrefix(value, "-") {
+			_, flagValue, ok := strings.Cut(value, "=")
+			if !ok {
+				continue
+			}
+			value = flagValue
+		}
+		if filepath.IsAbs(value) || value == "~" || strings.HasPrefix(value, "~/") || slices.Contains(strings.Split(value, "/"), "..") {
+			paths = append(paths, commandArg{value: value, static: true})
+		}
+	}
+	return paths
+}
+
+// wordValue returns the value of a word, and whether it is static: made of literal and quoted text, and $HOME,
+// rather than of expansions whose value we only know when the command runs.
+func wordValue(word *syntax.Word, home string) (string, bool) {
+	var b strings.Builder
+	static := true
+	var addParts func(parts []syntax.WordPart)
+	addParts = func(parts []syntax.WordPart) {
+		for _, part := range parts {
+			switch part := part.(type) {
+			case *syntax.Lit:
+				b.WriteString(part.Value)
+			case *syntax.SglQuoted:
+				b.WriteString(part.Value)
+			case *syntax.DblQuoted:
+				addParts(part.Parts)
+			case *syntax.ParamExp:
+				if part.Param != nil && part.Param.Value == "HOME" && part.Exp == nil && part.Repl == nil && part.Index == nil && !part.Length && !part.Excl {
+					b.WriteString(home)
+					continue
+				}
+				static = false
+				syntax.NewPrinter().Print(&b, part)
+			default:
+				static = false
+				syntax.NewPrinter().Print(&b, part)
+			}
+		}
+	}
+	addParts(word.Parts)
+	return b.String(), static
+}
+
+// changeDir returns the directory after running cd with args in dir, or "" if we cannot tell.
+func changeDir(dir string, args []commandArg, home string) string {
+	if len(args) == 0 {
+		return home
+	}
+	if !args[0].static || args[0].value == "-" {
+		return ""
+	}
+	target := expandHome(args[0].value, home)
+	if filepath.IsAbs(target) {
+		return filepath.Clean(target)
+	}
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, target)
+}
+
+// expandHome replaces a leading ~ in p with the home directory.
+func expandHome(p string, home string) string {
+	if p == "~" {
+		return home
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return filepath.Join(home, rest)
+	}
+	return p
+}
+
+// isFileDescriptor returns true if target of a >& redirection is a file descriptor (e.g. 2) or - (closing it).
+func isFileDescriptor(target string) bool {
+	if target == "-" {
+		return true
+	}
+	for _, r := range target {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return target != ""
+}
@@ -0,0 +1,140 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteViolation(t *testing.T) {
+	policy := &FilesystemPolicy{AllowedPaths: []string{"~/reports", "/data/out"}}
+	tests := []struct {
+		command string
+		// violates is true if the command writes outside the working directory and the allowed paths.
+		violates bool
+	}{
+		{command: "kubectl get pods -o yaml > pods.yaml"},
+		{command: "kubectl get pods 2>&1 | tee -a /work/log.txt"},
+		{command: "kubectl get pods > /dev/null 2>&1"},
+		{command: "echo done >&2"},
+		{command: "mkdir -p manifests && cp deploy.yaml manifests/"},
+		{command: "kubectl get events > ~/reports/events.txt"},
+		{command: "kubectl get events > $HOME/reports/events.txt"},
+		{command: "curl -o /data/out/chart.tgz https://example.com/chart.tgz"},
+		{command: "sed 's/a/b/' /etc/hosts"},
+		{command: "cat ~/.kube/config | grep server"},
+		{command: "bash -c 'kubectl get pods > pods.txt'", violates: true},
+		{command: "sudo kubectl get pods > pods.txt"},
+		{command: "timeout 10s kubectl get pods -w | tee events.txt"},
+		{command: "env KUBECONFIG=/tmp/kc nohup kubectl get pods"},
+		{command: "sudo rm -rf /etc/kubernetes", violates: true},
+		{command: "sudo -u root tee /etc/hosts", violates: true},
+		{command: "env -i PATH=/bin rm /etc/hosts", violates: true},
+		{command: "timeout -s KILL 5 cp a.yaml /etc/", violates: true},
+		{command: "nohup nice -n 10 rm -rf /var/lib/app", violates: true},
+		{command: "kubectl get pods -o name | xargs rm", violates: true},
+		{command: "kubectl get pods -o name | xargs -n 1 kubectl describe"},
+		{command: "env -C /etc touch hosts", violates: true},
+		{command: "env -S 'rm /etc/hosts'", violates: true},
+		{command: "sudo -s rm /etc/hosts", violates: true},
+		{command: "/usr/bin/time -o /etc/timing kubectl get pods", violates: true},
+		{command: "bash -ec 'echo x > /work/out.txt'", violates: true},
+		{command: "eval 'echo x > out.txt'", violates: true},
+		{command: "kubectl get pods > ~/.bashrc", violates: true},
+		{command: "kubectl get pods >> ../../pods.txt", violates: true},
+		{command: "rm -rf /home/user/project", violates: true},
+		{command: "cp manifest.yaml /etc/kubernetes/", violates: true},
+		{command: "mv a.yaml -t /opt", violates: true},
+		{command: "sed -i 's/a/b/' ~/.profile", violates: true},
+		{command: "dd if=/dev/zero of=/var/disk.img bs=1M count=1", violates: true},
+		{command: "cd /tmp && rm -rf *", violates: true},
+		{command: "cd manifests && rm -rf *"},
+		{command: "kubectl get pods > $OUT", violates: true},
+		{command: "find / -name '*.log' -delete", violates: true},
+		{command: "kubectl cp default/web-0:/var/log/app.log /root/app.log", violates: true},
+		{command: "pushd /root && rm -rf .bashrc", violates: true},
+		{command: "pushd /data/out && rm -rf old && popd && rm -rf /root/.bashrc", violates: true},
+		{command: "pushd /data/out && rm -rf old && popd && rm -rf old"},
+		{command: "cp --target-directory=/root/.ssh evil", violates: true},
+		{command: "cp -rt /root a", violates: true},
+		{command: "cp -rt manifests a"},
+		{command: "cp -t/root a", violates: true},
+		{command: "tar -xf x.tar --directory=/root", violates: true},
+		{command: "tar -xf x.tar --directory=/data/out"},
+		{command: "git -C /root clean -fdx", violates: true},
+		{command: "git clone https://example.com/repo.git /root/repo", violates: true},
+		{command: "git status"},
+		{command: "rsync evil /root/.bashrc", violates: true},
+		{command: "rsync -a manifests/ backup:/srv/manifests"},
+		{command: "unzip -o x.zip -d /root", violates: true},
+		{command: "unzip -o x.zip -d out"},
+		{command: "sort -o /root/.bashrc list", violates: true},
+		{command: "python3 script.py /root/.bashrc", violates: true},
+		{command: "python3 script.py --out=../secrets", violates: true},
+		{command: "python3 script.py ~/reports/out.txt"},
+		{command: "python3 script.py input.txt"},
+		{command: "grep -r server /etc/kubernetes"},
+		{command: "sh -c \"echo x > /etc/motd\"", violates: true},
+		{command: "echo 'unterminated", violates: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			reason := policy.writeViolation(tt.command, "/work", "/home/user")
+			if got := reason != ""; got != tt.violates {
+				t.Errorf("writeViolation(%q) = %q, expected a violation: %v", tt.command, reason, tt.violates)
+			}
+		})
+	}
+}
+
+func TestWriteViolationSymlinks(t *testing.T) {
+	workDir := t.TempDir()
+	outside := t.TempDir()
+	for name, target := range map[string]string{
+		"outside":  outside,
+		"dangling": filepath.Join(outside, "new.txt"),
+		"inside":   filepath.Join(workDir, "reports"),
+	} {
+		if err := os.Symlink(target, filepath.Join(workDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(workDir, "reports"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := &FilesystemPolicy{}
+	tests := []struct {
+		command  string
+		violates bool
+	}{
+		{command: "kubectl get pods > outside/pods.txt", violates: true},
+		{command: "kubectl get pods > dangling", violates: true},
+		{command: "rm -rf outside/*", violates: true},
+		{command: "kubectl get pods > inside/pods.txt"},
+		{command: "kubectl get pods > reports/new/pods.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			reason := policy.writeViolation(tt.command, workDir, "/home/user")
+			if got := reason != ""; got != tt.violates {
+				t.Errorf("writeViolation(%q) = %q, expected a violation: %v", tt.command, reason, tt.violates)
+			}
+		})
+	}
+}
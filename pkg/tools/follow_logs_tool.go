@@ -0,0 +1,314 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&FollowLogsTool{})
+}
+
+// Why follow_logs stopped following the logs.
+const (
+	followMatched  = "matched"
+	followTimedOut = "timed_out"
+	followEnded    = "ended"
+)
+
+const (
+	// defaultFollowDuration is how long follow_logs follows the logs if no duration is given, and maxFollowDuration the longest.
+	defaultFollowDuration = time.Minute
+	maxFollowDuration     = 10 * time.Minute
+
+	// maxFollowedPods is how many pods matching a selector are followed at once.
+	maxFollowedPods = 10
+	// maxFollowLines is how many of the latest lines are returned, and maxFollowErrors how many of the first error lines.
+	maxFollowLines  = 50
+	maxFollowErrors = 10
+)
+
+// FollowLogsTool follows the logs of pods until a line matches a pattern or a duration elapses, showing them as they
+// arrive, and returns a digest of them.
+type FollowLogsTool struct{}
+
+func (t *FollowLogsTool) Name() string {
+	return "follow_logs"
+}
+
+func (t *FollowLogsTool) Description() string {
+	return `Follows the logs of a pod, or of all the pods matching a label selector, as they are written, until a line matches
+a regular expression or a duration elapses, and returns a digest: why it stopped, the matching line, the number of lines
+and of error lines, the first error lines and the latest lines. The user sees the logs as they arrive.
+Use this tool to watch something happen, e.g. to wait until new pods log that they are ready after a rollout, or to
+catch the error when a request is retried; use pod_logs to read logs that were already written.`
+}
+
+func (t *FollowLogsTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"pod": {
+					Type:        gollm.TypeString,
+					Description: `The name of the pod, or a workload as kind/name (e.g. "deployment/web") to follow one of its pods. Give either pod or selector.`,
+				},
+				"selector": {
+					Type:        gollm.TypeString,
+					Description: `A label selector, e.g. "app=web", to follow all the matching pods (at most 10); lines are prefixed with the pod and container.`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the pods. Defaults to the namespace of the current context.`,
+				},
+				"container": {
+					Type:        gollm.TypeString,
+					Description: `The container to follow. Defaults to the default container of the pod.`,
+				},
+				"until": {
+					Type:        gollm.TypeString,
+					Description: `A regular expression (RE2 syntax); following stops at the first line that matches it, e.g. "(?i)server started|listening on".`,
+				},
+				"duration": {
+					Type:        gollm.TypeString,
+					Description: `How long to follow the logs at most, as a duration such as "30s" or "5m". Defaults to 1m; at most 10m.`,
+				},
+				"since": {
+					Type:        gollm.TypeString,
+					Description: `Also include the lines written in this duration before following, e.g. "1m". By default, only new lines are followed.`,
+				},
+			},
+		},
+	}
+}
+
+// FollowLogsResult is the result of the follow_logs tool.
+type FollowLogsResult struct {
+	// Command is the kubectl command that followed the logs.
+	Command string `json:"command"`
+	// StoppedBecause is why following stopped: matched (a line matched until), timed_out (the duration elapsed)
+	// or ended (the logs ended, e.g. the container exited).
+	StoppedBecause string `json:"stopped_because,omitempty"`
+	// MatchedLine is the line that matched until.
+	MatchedLine string `json:"matched_line,omitempty"`
+	// Followed is how long the logs were followed, e.g. "12s".
+	Followed string `json:"followed"`
+	// LinesSeen is how many lines were written while following.
+	LinesSeen int `json:"lines_seen"`
+	// ErrorLines is how many of them report errors.
+	ErrorLines int `json:"error_lines"`
+	// FirstErrors are the first lines that report errors.
+	FirstErrors []LogLine `json:"first_errors,omitempty"`
+	// LatestLines are the last lines seen.
+	LatestLines []LogLine `json:"latest_lines,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+func (t *FollowLogsTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*FollowLogsResult]()
+}
+
+// followRequest is a parsed follow_logs call.
+type followRequest struct {
+	pod       string
+	selector  string
+	namespace string
+	container string
+	until     *regexp.Regexp
+	duration  time.Duration
+	since     time.Duration
+}
+
+func parseFollowArgs(args map[string]any) (*followRequest, error) {
+	r := &followRequest{
+		pod:       stringArg(args, "pod"),
+		selector:  stringArg(args, "selector"),
+		namespace: stringArg(args, "namespace"),
+		container: stringArg(args, "container"),
+		duration:  defaultFollowDuration,
+	}
+	if (r.pod == "") == (r.selector == "") {
+		return nil, fmt.Errorf("give either a pod or a selector")
+	}
+	if duration := stringArg(args, "duration"); duration != "" {
+		d, err := time.ParseDuration(duration)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid duration %q: give a duration such as 30s or 5m", duration)
+		}
+		r.duration = min(d, maxFollowDuration)
+	}
+	if since := stringArg(args, "since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid since %q: give a duration such as 1m", since)
+		}
+		r.since = d
+	}
+	if until := stringArg(args, "until"); until != "" {
+		re, err := regexp.Compile(until)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until %q: %w", until, err)
+		}
+		r.until = re
+	}
+	return r, nil
+}
+
+// kubectlArgs returns the arguments of the kubectl logs command that follows the logs.
+func (r *followRequest) kubectlArgs() []string {
+	args := []string{"logs", "--follow"}
+	if r.pod != "" {
+		args = append(args, r.pod)
+	} else {
+		args = append(args, "--selector", r.selector, "--prefix", fmt.Sprintf("--max-log-requests=%d", maxFollowedPods))
+	}
+	if r.namespace != "" {
+		args = append(args, "--namespace", r.namespace)
+	}
+	if r.container != "" {
+		args = append(args, "--container", r.container)
+	}
+	if r.since > 0 {
+		return append(args, "--since="+r.since.String())
+	}
+	return append(args, "--tail=0")
+}
+
+// logDigest summarizes the lines seen while following logs.
+type logDigest struct {
+	until *regexp.Regexp
+
+	linesSeen   int
+	errorLines  int
+	firstErrors []LogLine
+	latestLines []LogLine
+	matchedLine string
+}
+
+// add adds a line to the digest, and returns true if it matches until.
+func (d *logDigest) add(text string) bool {
+	d.linesSeen++
+	line := LogLine{Number: d.linesSeen, Text: text, IsError: errorLogLine.MatchString(text)}
+	if len(line.Text) > maxLogLineLength {
+		line.Text = line.Text[:maxLogLineLength] + "..."
+	}
+	if line.IsError {
+		d.errorLines++
+		if len(d.firstErrors) < maxFollowErrors {
+			d.firstErrors = append(d.firstErrors, line)
+		}
+	}
+	d.latestLines = append(d.latestLines, line)
+	if len(d.latestLines) > maxFollowLines {
+		d.latestLines = d.latestLines[1:]
+	}
+	if d.until != nil && d.until.MatchString(text) {
+		d.matchedLine = line.Text
+		return true
+	}
+	return false
+}
+
+func (t *FollowLogsTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &FollowLogsResult{}
+	request, err := parseFollowArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	kubectlArgs := request.kubectlArgs()
+	result.Command = "kubectl " + strings.Join(kubectlArgs, " ")
+
+	followCtx, cancel := context.WithTimeout(ctx, request.duration)
+	defer cancel()
+	cmd, err := kubectlCommand(followCtx, kubectlArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = waitDelay
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	started := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting kubectl logs: %w", err)
+	}
+
+	digest := &logDigest{until: request.until}
+	output := outputWriter(ctx)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if output != nil {
+			io.WriteString(output, line+"\n")
+		}
+		ReportProgress(ctx, Progress{
+			Message: fmt.Sprintf("Following logs: %d lines, %d errors", digest.linesSeen+1, digest.errorLines),
+			Current: time.Since(started).Seconds(),
+			Total:   request.duration.Seconds(),
+		})
+		if digest.add(line) {
+			result.StoppedBecause = followMatched
+			break
+		}
+	}
+	result.Followed = time.Since(started).Round(time.Second).String()
+	cancel()
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	result.MatchedLine = digest.matchedLine
+	result.LinesSeen, result.ErrorLines = digest.linesSeen, digest.errorLines
+	result.FirstErrors, result.LatestLines = digest.firstErrors, digest.latestLines
+	switch {
+	case result.StoppedBecause != "":
+	case errors.Is(followCtx.Err(), context.DeadlineExceeded):
+		result.StoppedBecause = followTimedOut
+	case waitErr != nil:
+		result.Error = strings.TrimSpace(waitErr.Error() + " " + stderr.String())
+	default:
+		result.StoppedBecause = followEnded
+	}
+	return result, nil
+}
+
+func (t *FollowLogsTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *FollowLogsTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
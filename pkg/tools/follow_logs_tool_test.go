@@ -0,0 +1,110 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestFollowArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     map[string]any
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "pod",
+			args:     map[string]any{"pod": "web-0", "namespace": "shop"},
+			expected: []string{"logs", "--follow", "web-0", "--namespace", "shop", "--tail=0"},
+		},
+		{
+			name:     "selector",
+			args:     map[string]any{"selector": "app=web", "container": "app", "since": "1m"},
+			expected: []string{"logs", "--follow", "--selector", "app=web", "--prefix", "--max-log-requests=10", "--container", "app", "--since=1m0s"},
+		},
+		{
+			name: "pod and selector",
+			args: map[string]any{"pod": "web-0", "selector": "app=web"},
+			err:  true,
+		},
+		{
+			name: "neither pod nor selector",
+			args: map[string]any{"namespace": "shop"},
+			err:  true,
+		},
+		{
+			name: "invalid duration",
+			args: map[string]any{"pod": "web-0", "duration": "forever"},
+			err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := parseFollowArgs(tc.args)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := request.kubectlArgs(); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLogDigest(t *testing.T) {
+	digest := &logDigest{until: regexp.MustCompile(`listening on`)}
+	lines := []string{
+		"starting",
+		"error: cannot reach db, retrying",
+		"connected to db",
+		"listening on :8080",
+	}
+	var matched []bool
+	for _, line := range lines {
+		matched = append(matched, digest.add(line))
+	}
+	if expected := []bool{false, false, false, true}; !reflect.DeepEqual(matched, expected) {
+		t.Errorf("expected matches %v, got %v", expected, matched)
+	}
+	if digest.linesSeen != 4 || digest.errorLines != 1 || digest.matchedLine != "listening on :8080" {
+		t.Errorf("expected 4 lines, 1 error and a match, got %d lines, %d errors and match %q", digest.linesSeen, digest.errorLines, digest.matchedLine)
+	}
+	expectedErrors := []LogLine{{Number: 2, Text: "error: cannot reach db, retrying", IsError: true}}
+	if !reflect.DeepEqual(digest.firstErrors, expectedErrors) {
+		t.Errorf("expected errors %+v, got %+v", expectedErrors, digest.firstErrors)
+	}
+}
+
+func TestLogDigestKeepsLatestLines(t *testing.T) {
+	digest := &logDigest{}
+	for i := 0; i < maxFollowLines+5; i++ {
+		if digest.add("line") {
+			t.Fatalf("unexpected match without until")
+		}
+	}
+	if len(digest.latestLines) != maxFollowLines || digest.latestLines[0].Number != 6 {
+		t.Errorf("expected the latest %d lines from line 6, got %d lines from line %d", maxFollowLines, len(digest.latestLines), digest.latestLines[0].Number)
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed default_health_rules.yaml
+var defaultHealthRules []byte
+
+// HealthRuleSet maps the status of a kind of resource to human-readable health states.
+type HealthRuleSet struct {
+	// Group is the API group of the resource, e.g. kafka.strimzi.io
+	Group string `json:"group"`
+	// Kind is the kind of the resource, e.g. Kafka
+	Kind string `json:"kind"`
+	// Rules are evaluated in order, the first matching rule determines the health state.
+	Rules []HealthRule `json:"rules"`
+}
+
+// HealthRule matches the status of a resource to a health state.
+// A rule matches if all of its conditions and fields match; a rule with no conditions or fields always matches.
+type HealthRule struct {
+	// State is the health state, e.g. Healthy, Progressing, Degraded or Unhealthy.
+	State string `json:"state"`
+	// Message is a human-readable description of the state.
+	Message string `json:"message,omitempty"`
+	// Conditions match entries in status.conditions.
+	Conditions []ConditionMatcher `json:"conditions,omitempty"`
+	// Fields match arbitrary fields of the resource.
+	Fields []FieldMatcher `json:"fields,omitempty"`
+}
+
+// ConditionMatcher matches an entry in status.conditions; empty fields match anything.
+type ConditionMatcher struct {
+	Type   string `json:"type"`
+	Status string `json:"status,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// FieldMatcher matches a field of the resource, identified by a dotted path such as status.phase.
+type FieldMatcher struct {
+	Path      string  `json:"path"`
+	Equals    *string `json:"equals,omitempty"`
+	NotEquals *string `json:"notEquals,omitempty"`
+	Exists    *bool   `json:"exists,omitempty"`
+}
+
+// HealthRuleRegistry holds the known health rules, keyed by group and kind.
+type HealthRuleRegistry struct {
+	mutex    sync.Mutex
+	ruleSets map[string]HealthRuleSet
+}
+
+var healthRules = newDefaultHealthRuleRegistry()
+
+func newDefaultHealthRuleRegistry() *HealthRuleRegistry {
+	r := &HealthRuleRegistry{ruleSets: make(map[string]HealthRuleSet)}
+	if err := r.load(defaultHealthRules); err != nil {
+		panic(fmt.Sprintf("loading default health rules: %v", err))
+	}
+	return r
+}
+
+func healthRuleKey(group, kind string) string {
+	return strings.ToLower(kind + "." + group)
+}
+
+// load parses the rules and adds them to the registry, replacing existing rules for the same kind.
+func (r *HealthRuleRegistry) load(b []byte) error {
+	var ruleSets []HealthRuleSet
+	if err := yaml.Unmarshal(b, &ruleSets); err != nil {
+		return fmt.Errorf("parsing health rules: %w", err)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, ruleSet := range ruleSets {
+		if ruleSet.Kind == "" {
+			return fmt.Errorf("health rules must specify a kind")
+		}
+		r.ruleSets[healthRuleKey(ruleSet.Group, ruleSet.Kind)] = ruleSet
+	}
+	return nil
+}
+
+// Lookup returns the rules for the given group and kind.
+func (r *HealthRuleRegistry) Lookup(group, kind string) (HealthRuleSet, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	ruleSet, ok := r.ruleSets[healthRuleKey(group, kind)]
+	return ruleSet, ok
+}
+
+// LoadHealthRules loads health rules from a YAML file, or all files in a directory,
+// in addition to the built-in rules. Rules for the same kind replace existing ones.
+func LoadHealthRules(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to describe health rules file %s: %w", path, err)
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("failed to read health rules dir %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			if isSignatureFile(entry.Name()) {
+				continue
+			}
+			if err := LoadHealthRules(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	b, err := ReadArtifact(path)
+	if err != nil {
+		return fmt.Errorf("failed to read health rules file %s: %w", path, err)
+	}
+	if err := healthRules.load(b); err != nil {
+		return fmt.Errorf("loading health rules from %s: %w", path, err)
+	}
+	return nil
+}
+
+// HealthEvaluation is the result of evaluating the health of a single resource.
+type HealthEvaluation struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	State     string `json:"state"`
+	Message   string `json:"message,omitempty"`
+	// Rules describes which rules were used: "custom" for a matching rule set, "generic" otherwise.
+	Rules string `json:"rules"`
+	// Conditions summarizes status.conditions, to give additional detail.
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+// evaluateHealth determines the health of an (unstructured) resource.
+// If there are no rules for the kind, we fall back to the conventional Ready and Available conditions.
+func evaluateHealth(obj map[string]any) HealthEvaluation {
+	kind, _ := obj["kind"].(string)
+	apiVersion, _ := obj["apiVersion"].(string)
+	group := ""
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		group = apiVersion[:i]
+	}
+
+	eval := HealthEvaluation{Kind: kind, State: "Unknown", Rules: "generic"}
+	if v, ok := lookupField(obj, "metadata.name"); ok {
+		eval.Name = fmt.Sprint(v)
+	}
+	if v, ok := lookupField(obj, "metadata.namespace"); ok {
+		eval.Namespace = fmt.Sprint(v)
+	}
+
+	conditions := statusConditions(obj)
+	for _, c := range conditions {
+		summary := fmt.Sprintf("%s=%s", c["type"], c["status"])
+		if c["reason"] != "" {
+			summary += " (" + c["reason"] + ")"
+		}
+		if c["message"] != "" {
+			summary += ": " + c["message"]
+		}
+		eval.Conditions = append(eval.Conditions, summary)
+	}
+
+	if ruleSet, ok := healthRules.Lookup(group, kind); ok {
+		eval.Rules = "custom"
+		for _, rule := range ruleSet.Rules {
+			if ruleMatches(rule, obj, conditions) {
+				eval.State = rule.State
+				eval.Message = rule.Message
+				return eval
+			}
+		}
+		eval.Message = "no health rule matched the current status"
+		return eval
+	}
+
+	for _, c := range conditions {
+		if c["type"] != "Ready" && c["type"] != "Available" {
+			continue
+		}
+		switch c["status"] {
+		case "True":
+			eval.State = "Healthy"
+		case "False":
+			eval.State = "Unhealthy"
+		}
+		eval.Message = c["message"]
+		return eval
+	}
+	eval.Message = "no health rules are defined for this kind, and it has no Ready or Available condition"
+	return eval
+}
+
+func ruleMatches(rule HealthRule, obj map[string]any, conditions []map[string]string) bool {
+	for _, matcher := range rule.Conditions {
+		found := false
+		for _, c := range conditions {
+			if c["type"] != matcher.Type {
+				continue
+			}
+			if matcher.Status != "" && c["status"] != matcher.Status {
+				continue
+			}
+			if matcher.Reason != "" && c["reason"] != matcher.Reason {
+				continue
+			}
+			found = true
+			break
+		}
+		if !found {
+			return false
+		}
+	}
+
+	for _, matcher := range rule.Fields {
+		value, exists := lookupField(obj, matcher.Path)
+		if matcher.Exists != nil && exists != *matcher.Exists {
+			return false
+		}
+		if matcher.Equals != nil && (!exists || fmt.Sprint(value) != *matcher.Equals) {
+			return false
+		}
+		if matcher.NotEquals != nil && exists && fmt.Sprint(value) == *matcher.NotEquals {
+			return false
+		}
+	}
+	return true
+}
+
+// statusConditions returns status.conditions, with all values converted to strings.
+func statusConditions(obj map[string]any) []map[string]string {
+	v, ok := lookupField(obj, "status.conditions")
+	if !ok {
+		return nil
+	}
+	list, ok := v.([]any)
+	if !ok {
+		return nil
+	}
+	var conditions []map[string]string
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		c := make(map[string]string)
+		for k, v := range m {
+			c[k] = fmt.Sprint(v)
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions
+}
+
+// lookupField returns the value at the dotted path in obj.
+func lookupField(obj map[string]any, path string) (any, bool) {
+	var current any = obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
@@ -0,0 +1,80 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEvaluateHealth(t *testing.T) {
+	tests := []struct {
+		name          string
+		obj           string
+		expectedState string
+		expectedRules string
+	}{
+		{
+			name: "certificate ready",
+			obj: `{"apiVersion": "cert-manager.io/v1", "kind": "Certificate", "metadata": {"name": "tls"},
+				"status": {"conditions": [{"type": "Ready", "status": "True"}]}}`,
+			expectedState: "Healthy",
+			expectedRules: "custom",
+		},
+		{
+			name: "kafka creating",
+			obj: `{"apiVersion": "kafka.strimzi.io/v1beta2", "kind": "Kafka", "metadata": {"name": "events"},
+				"status": {"conditions": [{"type": "NotReady", "status": "True", "reason": "Creating"}]}}`,
+			expectedState: "Progressing",
+			expectedRules: "custom",
+		},
+		{
+			name: "argo application out of sync",
+			obj: `{"apiVersion": "argoproj.io/v1alpha1", "kind": "Application", "metadata": {"name": "app"},
+				"status": {"health": {"status": "Healthy"}, "sync": {"status": "OutOfSync"}}}`,
+			expectedState: "Degraded",
+			expectedRules: "custom",
+		},
+		{
+			name: "unknown kind falls back to Ready condition",
+			obj: `{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "w"},
+				"status": {"conditions": [{"type": "Ready", "status": "False", "message": "broken"}]}}`,
+			expectedState: "Unhealthy",
+			expectedRules: "generic",
+		},
+		{
+			name:          "unknown kind without conditions",
+			obj:           `{"apiVersion": "example.com/v1", "kind": "Widget", "metadata": {"name": "w"}}`,
+			expectedState: "Unknown",
+			expectedRules: "generic",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := make(map[string]any)
+			if err := json.Unmarshal([]byte(tt.obj), &obj); err != nil {
+				t.Fatalf("parsing object: %v", err)
+			}
+			eval := evaluateHealth(obj)
+			if eval.State != tt.expectedState {
+				t.Errorf("expected state %q, got %q (%s)", tt.expectedState, eval.State, eval.Message)
+			}
+			if eval.Rules != tt.expectedRules {
+				t.Errorf("expected rules %q, got %q", tt.expectedRules, eval.Rules)
+			}
+		})
+	}
+}
@@ -0,0 +1,580 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// IaCResource is a Kubernetes object declared in infrastructure-as-code state.
+type IaCResource struct {
+	// Address identifies the resource in the IaC tool: the Terraform address or the Pulumi URN.
+	Address string
+	// Source is the state file that declares the resource.
+	Source string
+	// Resource is the resource type, as accepted by kubectl get, e.g. "deployment.apps" or "configmap".
+	Resource  string
+	Namespace string
+	Name      string
+	// Desired holds the fields set by the IaC, in the shape of the Kubernetes object.
+	Desired map[string]any
+}
+
+// String returns the resource as kubectl names it, e.g. deployment.apps/web.
+func (r *IaCResource) String() string {
+	return r.Resource + "/" + r.Name
+}
+
+var (
+	// iacResources are the resources of the loaded IaC state files.
+	iacResources []IaCResource
+	// iacDriftToolRegistered is set once the check_iac_drift tool is registered, when the first state file is loaded.
+	iacDriftToolRegistered bool
+)
+
+// LoadIaCState loads rendered IaC state, the output of `terraform show -json` or `pulumi stack export`,
+// and makes the check_iac_drift tool available to compare the Kubernetes resources it declares with the cluster.
+func LoadIaCState(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read IaC state file %s: %w", path, err)
+	}
+	resources, err := parseIaCState(b)
+	if err != nil {
+		return fmt.Errorf("failed to parse IaC state file %s: %w", path, err)
+	}
+	for i := range resources {
+		resources[i].Source = path
+	}
+	iacResources = append(iacResources, resources...)
+	if !iacDriftToolRegistered {
+		RegisterTool(&IaCDriftTool{})
+		iacDriftToolRegistered = true
+	}
+	return nil
+}
+
+// terraformModule is a module in the output of `terraform show -json`.
+type terraformModule struct {
+	Resources []struct {
+		Address string         `json:"address"`
+		Mode    string         `json:"mode"`
+		Type    string         `json:"type"`
+		Values  map[string]any `json:"values"`
+	} `json:"resources"`
+	ChildModules []terraformModule `json:"child_modules"`
+}
+
+// pulumiResource is a resource in the output of `pulumi stack export`.
+type pulumiResource struct {
+	URN     string         `json:"urn"`
+	Type    string         `json:"type"`
+	Inputs  map[string]any `json:"inputs"`
+	Outputs map[string]any `json:"outputs"`
+}
+
+// terraformPatchTypes are the Terraform resource types that patch objects managed elsewhere
+// rather than declaring them, so they are not compared with the cluster.
+var terraformPatchTypes = map[string]bool{
+	"kubernetes_annotations":             true,
+	"kubernetes_labels":                  true,
+	"kubernetes_env":                     true,
+	"kubernetes_node_taint":              true,
+	"kubernetes_config_map_v1_data":      true,
+	"kubernetes_default_service_account": true,
+}
+
+// terraformVersionSuffix matches the version suffix of Terraform resource types, e.g. the _v1 in kubernetes_deployment_v1.
+var terraformVersionSuffix = regexp.MustCompile(`_v\d+(beta\d+)?$`)
+
+// parseIaCState extracts the Kubernetes resources from the output of `terraform show -json` or `pulumi stack export`.
+func parseIaCState(b []byte) ([]IaCResource, error) {
+	var state struct {
+		Values *struct {
+			RootModule terraformModule `json:"root_module"`
+		} `json:"values"`
+		Deployment *struct {
+			Resources []pulumiResource `json:"resources"`
+		} `json:"deployment"`
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, err
+	}
+	switch {
+	case state.Values != nil:
+		return terraformResources(state.Values.RootModule), nil
+	case state.Deployment != nil:
+		return pulumiResources(state.Deployment.Resources), nil
+	default:
+		return nil, fmt.Errorf("not the output of terraform show -json or pulumi stack export")
+	}
+}
+
+// terraformResources returns the Kubernetes resources managed by the module and its child modules.
+func terraformResources(module terraformModule) []IaCResource {
+	var resources []IaCResource
+	for _, r := range module.Resources {
+		if r.Mode != "managed" || !strings.HasPrefix(r.Type, "kubernetes_") || terraformPatchTypes[r.Type] {
+			continue
+		}
+		var resource IaCResource
+		if r.Type == "kubernetes_manifest" {
+			manifest, _ := r.Values["manifest"].(map[string]any)
+			if manifest == nil {
+				continue
+			}
+			resource = manifestResource(manifest)
+		} else {
+			resource = terraformTypedResource(r.Type, r.Values)
+		}
+		if resource.Name == "" {
+			continue
+		}
+		resource.Address = r.Address
+		resources = append(resources, resource)
+	}
+	for _, child := range module.ChildModules {
+		resources = append(resources, terraformResources(child)...)
+	}
+	return resources
+}
+
+// terraformTypedResource converts a typed Terraform resource (e.g. kubernetes_deployment_v1) to a resource.
+// Its schema differs from the Kubernetes object (nested blocks are lists, names are snake_case),
+// so only the fields most often changed by hand are compared: labels, annotations, replicas, container images and data.
+func terraformTypedResource(resourceType string, values map[string]any) IaCResource {
+	kind := strings.TrimPrefix(terraformVersionSuffix.ReplaceAllString(resourceType, ""), "kubernetes_")
+	resource := IaCResource{Resource: strings.ReplaceAll(kind, "_", "")}
+	desired := make(map[string]any)
+
+	metadata := firstBlock(values["metadata"])
+	resource.Name, _ = metadata["name"].(string)
+	resource.Namespace, _ = metadata["namespace"].(string)
+	desiredMetadata := make(map[string]any)
+	for _, key := range []string{"labels", "annotations"} {
+		if m, ok := metadata[key].(map[string]any); ok && len(m) > 0 {
+			desiredMetadata[key] = m
+		}
+	}
+	if len(desiredMetadata) > 0 {
+		desired["metadata"] = desiredMetadata
+	}
+
+	if data, ok := values["data"].(map[string]any); ok && len(data) > 0 && resource.Resource == "configmap" {
+		desired["data"] = data
+	}
+
+	spec := firstBlock(values["spec"])
+	desiredSpec := make(map[string]any)
+	if replicas, ok := spec["replicas"]; ok && replicas != nil && replicas != "" {
+		desiredSpec["replicas"] = replicas
+	}
+	podSpec, inTemplate := spec, false
+	if template := firstBlock(spec["template"]); template != nil {
+		podSpec, inTemplate = firstBlock(template["spec"]), true
+	}
+	var containers []any
+	for _, c := range blocks(podSpec["container"]) {
+		if c["name"] != nil && c["image"] != nil {
+			containers = append(containers, map[string]any{"name": c["name"], "image": c["image"]})
+		}
+	}
+	if len(containers) > 0 {
+		if inTemplate {
+			desiredSpec["template"] = map[string]any{"spec": map[string]any{"containers": containers}}
+		} else {
+			desiredSpec["containers"] = containers
+		}
+	}
+	if len(desiredSpec) > 0 {
+		desired["spec"] = desiredSpec
+	}
+
+	resource.Desired = desired
+	return resource
+}
+
+// blocks returns the nested blocks of a typed Terraform resource, which are lists of objects.
+func blocks(v any) []map[string]any {
+	list, _ := v.([]any)
+	var result []map[string]any
+	for _, item := range list {
+		if m, ok := item.(map[string]any); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
+
+// firstBlock returns the first of the nested blocks, or nil if there are none.
+func firstBlock(v any) map[string]any {
+	if b := blocks(v); len(b) > 0 {
+		return b[0]
+	}
+	return nil
+}
+
+// pulumiSkippedGroups are the groups of Pulumi Kubernetes types that are not Kubernetes objects themselves,
+// e.g. kubernetes:yaml:ConfigFile or kubernetes:helm.sh/v3:Chart, whose objects are exported separately.
+var pulumiSkippedGroups = map[string]bool{
+	"yaml":      true,
+	"helm.sh":   true,
+	"kustomize": true,
+}
+
+// pulumiResources returns the Kubernetes resources in a Pulumi stack, e.g. those of type kubernetes:apps/v1:Deployment.
+func pulumiResources(stack []pulumiResource) []IaCResource {
+	var resources []IaCResource
+	for _, r := range stack {
+		parts := strings.Split(r.Type, ":")
+		if len(parts) != 3 || parts[0] != "kubernetes" {
+			continue
+		}
+		group, _, _ := strings.Cut(parts[1], "/")
+		if pulumiSkippedGroups[group] {
+			continue
+		}
+		// Inputs are what the program declares; outputs add what the cluster filled in, including generated names.
+		desired := r.Inputs
+		if len(desired) == 0 {
+			desired = r.Outputs
+		}
+		resource := manifestResource(desired)
+		resource.Resource = strings.ToLower(parts[2])
+		if group != "core" {
+			resource.Resource += "." + group
+		}
+		if outputMetadata, ok := r.Outputs["metadata"].(map[string]any); ok {
+			if name, ok := outputMetadata["name"].(string); ok {
+				resource.Name = name
+			}
+			if namespace, ok := outputMetadata["namespace"].(string); ok {
+				resource.Namespace = namespace
+			}
+		}
+		if resource.Name == "" {
+			continue
+		}
+		resource.Address = r.URN
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// manifestResource converts a Kubernetes object declared in full, e.g. by a kubernetes_manifest, to a resource.
+// Fields that the cluster manages (status, and metadata other than labels and annotations) are not compared.
+func manifestResource(manifest map[string]any) IaCResource {
+	var resource IaCResource
+	apiVersion, _ := manifest["apiVersion"].(string)
+	kind, _ := manifest["kind"].(string)
+	resource.Resource = strings.ToLower(kind)
+	if group, _, ok := strings.Cut(apiVersion, "/"); ok {
+		resource.Resource += "." + group
+	}
+
+	desired := make(map[string]any)
+	for key, value := range manifest {
+		switch {
+		case key == "apiVersion" || key == "kind" || key == "status" || strings.HasPrefix(key, "__"):
+		case key == "metadata":
+			metadata, _ := value.(map[string]any)
+			resource.Name, _ = metadata["name"].(string)
+			resource.Namespace, _ = metadata["namespace"].(string)
+			desiredMetadata := make(map[string]any)
+			for _, key := range []string{"labels", "annotations"} {
+				if m, ok := metadata[key].(map[string]any); ok && len(m) > 0 {
+					desiredMetadata[key] = m
+				}
+			}
+			if len(desiredMetadata) > 0 {
+				desired["metadata"] = desiredMetadata
+			}
+		default:
+			desired[key] = value
+		}
+	}
+	resource.Desired = desired
+	return resource
+}
+
+// IaCFieldDrift is a field whose live value differs from the value in the IaC.
+type IaCFieldDrift struct {
+	Field string `json:"field"`
+	// IaC and Live are the values in the IaC and in the cluster, as JSON; an empty Live means the field is not set.
+	IaC  string `json:"iac"`
+	Live string `json:"live"`
+}
+
+// maxIaCDriftFields bounds the drifted fields reported for a single resource.
+const maxIaCDriftFields = 20
+
+// diffIaCFields compares the fields set by the IaC with the live object, and returns those that differ.
+// Fields that are only set in the cluster are ignored, as they are defaulted or managed by controllers.
+// Lists of objects with a name, such as containers, are matched by name rather than by position.
+func diffIaCFields(path string, desired, live any) []IaCFieldDrift {
+	switch desired := desired.(type) {
+	case nil:
+		return nil
+	case map[string]any:
+		liveMap, ok := live.(map[string]any)
+		if !ok {
+			return []IaCFieldDrift{fieldDrift(path, desired, live)}
+		}
+		keys := make([]string, 0, len(desired))
+		for key := range desired {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		var drift []IaCFieldDrift
+		for _, key := range keys {
+			drift = append(drift, diffIaCFields(joinFieldPath(path, key), desired[key], liveMap[key])...)
+		}
+		return drift
+	case []any:
+		liveList, ok := live.([]any)
+		if !ok {
+			return []IaCFieldDrift{fieldDrift(path, desired, live)}
+		}
+		if names, ok := itemNames(desired); ok {
+			liveByName := make(map[string]any)
+			if liveNames, ok := itemNames(liveList); ok {
+				for i, name := range liveNames {
+					liveByName[name] = liveList[i]
+				}
+			}
+			var drift []IaCFieldDrift
+			for i, name := range names {
+				itemPath := fmt.Sprintf("%s[name=%s]", path, name)
+				if liveItem, ok := liveByName[name]; ok {
+					drift = append(drift, diffIaCFields(itemPath, desired[i], liveItem)...)
+				} else {
+					drift = append(drift, fieldDrift(itemPath, desired[i], nil))
+				}
+			}
+			return drift
+		}
+		if len(desired) != len(liveList) {
+			return []IaCFieldDrift{fieldDrift(path, desired, live)}
+		}
+		var drift []IaCFieldDrift
+		for i := range desired {
+			drift = append(drift, diffIaCFields(fmt.Sprintf("%s[%d]", path, i), desired[i], liveList[i])...)
+		}
+		return drift
+	default:
+		if fieldValue(desired) != fieldValue(live) {
+			return []IaCFieldDrift{fieldDrift(path, desired, live)}
+		}
+		return nil
+	}
+}
+
+// itemNames returns the names of the items of a list, if all of them are objects with a name.
+func itemNames(list []any) ([]string, bool) {
+	var names []string
+	for _, item := range list {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		name, ok := m["name"].(string)
+		if !ok {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	return names, len(names) > 0
+}
+
+func joinFieldPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func fieldDrift(path string, desired, live any) IaCFieldDrift {
+	return IaCFieldDrift{Field: path, IaC: fieldValue(desired), Live: fieldValue(live)}
+}
+
+// fieldValue formats a value for comparison and display. Strings are not quoted, so that
+// the numbers Terraform stores as strings (e.g. replicas = "3") compare equal to the live numbers.
+func fieldValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// IaCDriftTool compares live resources with the IaC state that declares them.
+type IaCDriftTool struct{}
+
+func (t *IaCDriftTool) Name() string {
+	return "check_iac_drift"
+}
+
+// maxListedIaCResources bounds the managed resources listed in the description of the tool.
+const maxListedIaCResources = 30
+
+func (t *IaCDriftTool) Description() string {
+	var b strings.Builder
+	b.WriteString(`Compares live Kubernetes resources with the infrastructure-as-code (Terraform or Pulumi) state that manages them, and reports drift:
+fields whose live value differs from the IaC, and resources that are missing from the cluster.
+Before suggesting or making a change to a resource, check whether it is managed by IaC. If it is, warn the user that the next terraform apply or pulumi up
+will revert the change, and suggest changing the IaC code instead, or as well. Drift found while troubleshooting may also explain the problem.
+Resources managed by IaC:`)
+	for i, r := range iacResources {
+		if i == maxListedIaCResources {
+			fmt.Fprintf(&b, "\n- and %d more", len(iacResources)-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n- %s", r.String())
+		if r.Namespace != "" {
+			fmt.Fprintf(&b, " in namespace %s", r.Namespace)
+		}
+	}
+	return b.String()
+}
+
+func (t *IaCDriftTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"kind": {
+					Type:        gollm.TypeString,
+					Description: `Only check resources of this kind, e.g. "deployment".`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `Only check resources in this namespace.`,
+				},
+				"name": {
+					Type:        gollm.TypeString,
+					Description: `Only check resources with this name.`,
+				},
+			},
+		},
+	}
+}
+
+// IaCDriftResult is the result of the check_iac_drift tool.
+type IaCDriftResult struct {
+	Resources []IaCDriftResource `json:"resources"`
+	Message   string             `json:"message,omitempty"`
+}
+
+// IaCDriftResource is the drift of a single resource.
+type IaCDriftResource struct {
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	// Address identifies the resource in the IaC: the Terraform address or the Pulumi URN.
+	Address string `json:"address"`
+	Source  string `json:"source"`
+	// Status is one of "in_sync", "drifted", "missing" or "error".
+	Status string          `json:"status"`
+	Drift  []IaCFieldDrift `json:"drift,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+func (t *IaCDriftTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*IaCDriftResult]()
+}
+
+func (t *IaCDriftTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	kind := strings.ToLower(stringArg(args, "kind"))
+	namespace := stringArg(args, "namespace")
+	name := stringArg(args, "name")
+
+	var selected []IaCResource
+	for _, r := range iacResources {
+		resourceKind, _, _ := strings.Cut(r.Resource, ".")
+		if kind != "" && resourceKind != kind && resourceKind != strings.TrimSuffix(kind, "s") {
+			continue
+		}
+		if (namespace != "" && r.Namespace != namespace) || (name != "" && r.Name != name) {
+			continue
+		}
+		selected = append(selected, r)
+	}
+
+	result := &IaCDriftResult{Resources: []IaCDriftResource{}}
+	drifted := 0
+	for i, r := range selected {
+		ReportProgress(ctx, Progress{
+			Message: fmt.Sprintf("Checking %s", r.String()),
+			Current: float64(i),
+			Total:   float64(len(selected)),
+		})
+		report := IaCDriftResource{
+			Resource:  r.String(),
+			Namespace: r.Namespace,
+			Address:   r.Address,
+			Source:    r.Source,
+		}
+		getArgs := []string{r.String()}
+		if r.Namespace != "" {
+			getArgs = append(getArgs, "--namespace", r.Namespace)
+		}
+		var live map[string]any
+		err := kubectlGetJSON(ctx, &live, getArgs...)
+		switch {
+		case err != nil && strings.Contains(err.Error(), "NotFound"):
+			report.Status = "missing"
+		case err != nil:
+			report.Status, report.Error = "error", err.Error()
+		default:
+			report.Drift = diffIaCFields("", r.Desired, live)
+			report.Status = "in_sync"
+			if len(report.Drift) > 0 {
+				report.Status = "drifted"
+			}
+			if len(report.Drift) > maxIaCDriftFields {
+				report.Drift = report.Drift[:maxIaCDriftFields]
+			}
+		}
+		if report.Status == "drifted" || report.Status == "missing" {
+			drifted++
+		}
+		result.Resources = append(result.Resources, report)
+	}
+	result.Message = fmt.Sprintf("Checked %d resource(s) managed by IaC, %d drifted or missing.", len(selected), drifted)
+	return result, nil
+}
+
+func (t *IaCDriftTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *IaCDriftTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,157 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseIaCState(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    string
+		expected []IaCResource
+	}{
+		{
+			name: "terraform",
+			state: `{"values": {"root_module": {
+				"resources": [
+					{"address": "kubernetes_deployment_v1.web", "mode": "managed", "type": "kubernetes_deployment_v1", "values": {
+						"metadata": [{"name": "web", "namespace": "shop", "labels": {"app": "web"}, "annotations": {}, "uid": "123"}],
+						"spec": [{"replicas": "3", "template": [{"spec": [{"container": [{"name": "web", "image": "web:1.2", "args": []}]}]}]}]
+					}},
+					{"address": "data.kubernetes_namespace.shop", "mode": "data", "type": "kubernetes_namespace", "values": {"metadata": [{"name": "shop"}]}},
+					{"address": "kubernetes_labels.extra", "mode": "managed", "type": "kubernetes_labels", "values": {"metadata": [{"name": "web"}]}},
+					{"address": "google_container_cluster.main", "mode": "managed", "type": "google_container_cluster", "values": {"name": "main"}}
+				],
+				"child_modules": [{"resources": [
+					{"address": "module.cache.kubernetes_manifest.redis", "mode": "managed", "type": "kubernetes_manifest", "values": {"manifest": {
+						"apiVersion": "apps/v1", "kind": "StatefulSet",
+						"metadata": {"name": "redis", "namespace": "cache"},
+						"spec": {"replicas": 1}
+					}}}
+				]}]
+			}}}`,
+			expected: []IaCResource{
+				{
+					Address:   "kubernetes_deployment_v1.web",
+					Resource:  "deployment",
+					Namespace: "shop",
+					Name:      "web",
+					Desired: map[string]any{
+						"metadata": map[string]any{"labels": map[string]any{"app": "web"}},
+						"spec": map[string]any{
+							"replicas": "3",
+							"template": map[string]any{"spec": map[string]any{"containers": []any{
+								map[string]any{"name": "web", "image": "web:1.2"},
+							}}},
+						},
+					},
+				},
+				{
+					Address:   "module.cache.kubernetes_manifest.redis",
+					Resource:  "statefulset.apps",
+					Namespace: "cache",
+					Name:      "redis",
+					Desired:   map[string]any{"spec": map[string]any{"replicas": float64(1)}},
+				},
+			},
+		},
+		{
+			name: "pulumi",
+			state: `{"version": 3, "deployment": {"resources": [
+				{"urn": "urn:pulumi:dev::shop::pulumi:providers:kubernetes::k8s", "type": "pulumi:providers:kubernetes"},
+				{"urn": "urn:pulumi:dev::shop::kubernetes:yaml:ConfigFile::app", "type": "kubernetes:yaml:ConfigFile"},
+				{"urn": "urn:pulumi:dev::shop::kubernetes:core/v1:ConfigMap::settings", "type": "kubernetes:core/v1:ConfigMap",
+					"inputs": {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "shop"}, "data": {"mode": "fast"}},
+					"outputs": {"metadata": {"name": "settings-1a2b3c", "namespace": "shop", "uid": "456"}, "data": {"mode": "fast"}, "__inputs": {}}}
+			]}}`,
+			expected: []IaCResource{
+				{
+					Address:   "urn:pulumi:dev::shop::kubernetes:core/v1:ConfigMap::settings",
+					Resource:  "configmap",
+					Namespace: "shop",
+					Name:      "settings-1a2b3c",
+					Desired:   map[string]any{"data": map[string]any{"mode": "fast"}},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIaCState([]byte(tt.state))
+			if err != nil {
+				t.Fatalf("parseIaCState() returned an error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("parseIaCState() = %#v, expected %#v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDiffIaCFields(t *testing.T) {
+	live := map[string]any{
+		"metadata": map[string]any{"name": "web", "labels": map[string]any{"app": "web", "pod-template-hash": "abc"}},
+		"spec": map[string]any{
+			"replicas": float64(5),
+			"template": map[string]any{"spec": map[string]any{"containers": []any{
+				map[string]any{"name": "sidecar", "image": "proxy:1"},
+				map[string]any{"name": "web", "image": "web:1.3"},
+			}}},
+		},
+	}
+	tests := []struct {
+		name     string
+		desired  map[string]any
+		expected []IaCFieldDrift
+	}{
+		{
+			name: "in sync",
+			desired: map[string]any{
+				"metadata": map[string]any{"labels": map[string]any{"app": "web"}},
+				"spec":     map[string]any{"replicas": "5"},
+			},
+		},
+		{
+			name: "drifted",
+			desired: map[string]any{
+				"metadata": map[string]any{"labels": map[string]any{"app": "web", "team": "shop"}},
+				"spec": map[string]any{
+					"replicas": "3",
+					"template": map[string]any{"spec": map[string]any{"containers": []any{
+						map[string]any{"name": "web", "image": "web:1.2"},
+					}}},
+				},
+			},
+			expected: []IaCFieldDrift{
+				{Field: "metadata.labels.team", IaC: "shop", Live: ""},
+				{Field: "spec.replicas", IaC: "3", Live: "5"},
+				{Field: "spec.template.spec.containers[name=web].image", IaC: "web:1.2", Live: "web:1.3"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffIaCFields("", tt.desired, live)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("diffIaCFields() = %#v, expected %#v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,435 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"sigs.k8s.io/yaml"
+)
+
+func init() {
+	RegisterTool(&IncidentTimelineTool{})
+}
+
+const (
+	// defaultTimelineWindow is how far before and after the time of the incident the timeline goes by default.
+	defaultTimelineWindow = time.Hour
+	// maxTimelineEntries is the most entries returned; the ones closest to the time of the incident are kept.
+	maxTimelineEntries = 200
+)
+
+// The sources of the entries of a timeline.
+const (
+	timelineSourceEvent   = "event"
+	timelineSourceRollout = "rollout"
+	timelineSourceNode    = "node"
+	timelineSourceDeploy  = "deploy"
+)
+
+// deployMarkersPath is the file with the deploy markers, set by SetDeployMarkersPath.
+var deployMarkersPath string
+
+// SetDeployMarkersPath sets the file the incident_timeline tool reads deploy markers from: a YAML or JSON list of
+// objects with a time, a description and optionally a namespace, e.g. appended to by the CD pipeline.
+// It is read every time the tool runs, so it can change during the session.
+func SetDeployMarkersPath(path string) {
+	deployMarkersPath = path
+}
+
+// DeployMarker records a deployment made outside the cluster's own objects, e.g. by a CD pipeline.
+type DeployMarker struct {
+	Time        time.Time `json:"time"`
+	Description string    `json:"description"`
+	// Namespace is the namespace deployed to, if the deployment was to a single namespace.
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// IncidentTimelineTool merges events, rollouts, node lifecycle changes and deploy markers into a single timeline.
+type IncidentTimelineTool struct{}
+
+func (t *IncidentTimelineTool) Name() string {
+	return "incident_timeline"
+}
+
+func (t *IncidentTimelineTool) Description() string {
+	return `Builds a chronological timeline of what happened in the cluster around a given time, merging events, rollouts
+(new revisions of deployments, statefulsets and daemonsets, with their images), node lifecycle changes (nodes joining,
+condition changes such as NotReady or MemoryPressure, taints) and, if configured, deploy markers from the CD pipeline.
+Use this tool when investigating an incident, to establish what changed before the problem started rather than guessing.
+Note that the API server only keeps events for a limited time (one hour by default).`
+}
+
+func (t *IncidentTimelineTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"time": {
+					Type:        gollm.TypeString,
+					Description: `The time of the incident, in RFC 3339 format (e.g. "2025-06-01T14:30:00Z") or relative to now (e.g. "45m ago"). Defaults to now.`,
+				},
+				"window": {
+					Type:        gollm.TypeString,
+					Description: `How far before and after the time to look, as a duration, e.g. "30m" or "2h". Defaults to "1h".`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace to build the timeline for. Node changes are always included. Defaults to all namespaces.`,
+				},
+			},
+		},
+	}
+}
+
+// IncidentTimeline is the result of the incident_timeline tool.
+type IncidentTimeline struct {
+	From    time.Time       `json:"from"`
+	To      time.Time       `json:"to"`
+	Entries []TimelineEntry `json:"entries,omitempty"`
+	// Omitted is the number of entries left out because there were too many; the ones furthest from the time are left out.
+	Omitted int `json:"omitted,omitempty"`
+	// Errors are the sources that could not be read; the timeline is built from the others.
+	Errors []string `json:"errors,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// TimelineEntry is something that happened at a given time.
+type TimelineEntry struct {
+	Time time.Time `json:"time"`
+	// Source is where the entry comes from: "event", "rollout", "node" or "deploy".
+	Source string `json:"source"`
+	// Object is the object the entry is about, as kind/name, e.g. "deployment/web" or "node/pool-1-abcd".
+	Object    string `json:"object,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Message   string `json:"message"`
+	// Warning is set for warning events, and for node conditions that are unhealthy.
+	Warning bool `json:"warning,omitempty"`
+}
+
+func (t *IncidentTimelineTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*IncidentTimeline]()
+}
+
+func (t *IncidentTimelineTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	timeline := &IncidentTimeline{}
+	around, err := parseTimelineTime(stringArg(args, "time"), time.Now())
+	if err != nil {
+		timeline.Error = err.Error()
+		return timeline, nil
+	}
+	window := defaultTimelineWindow
+	if s := stringArg(args, "window"); s != "" {
+		window, err = time.ParseDuration(s)
+		if err != nil || window <= 0 {
+			timeline.Error = fmt.Sprintf("invalid window %q, must be a positive duration such as 30m", s)
+			return timeline, nil
+		}
+	}
+	timeline.From, timeline.To = around.Add(-window), around.Add(window)
+
+	namespace := stringArg(args, "namespace")
+	namespaceArgs := []string{"--all-namespaces"}
+	if namespace != "" {
+		namespaceArgs = []string{"--namespace", namespace}
+	}
+
+	var entries []TimelineEntry
+	events := &timelineEventList{}
+	if err := kubectlGetJSON(ctx, events, append([]string{"events"}, namespaceArgs...)...); err != nil {
+		timeline.Errors = append(timeline.Errors, fmt.Sprintf("events: %v", err))
+	} else {
+		entries = append(entries, events.entries()...)
+	}
+	revisions := &revisionList{}
+	if err := kubectlGetJSON(ctx, revisions, append([]string{"replicasets,controllerrevisions"}, namespaceArgs...)...); err != nil {
+		timeline.Errors = append(timeline.Errors, fmt.Sprintf("rollouts: %v", err))
+	} else {
+		entries = append(entries, revisions.entries()...)
+	}
+	nodes := &timelineNodeList{}
+	if err := kubectlGetJSON(ctx, nodes, "nodes"); err != nil {
+		timeline.Errors = append(timeline.Errors, fmt.Sprintf("nodes: %v", err))
+	} else {
+		entries = append(entries, nodes.entries()...)
+	}
+	if deployMarkersPath != "" {
+		markers, err := loadDeployMarkers(deployMarkersPath)
+		if err != nil {
+			timeline.Errors = append(timeline.Errors, fmt.Sprintf("deploy markers: %v", err))
+		} else {
+			entries = append(entries, deployMarkerEntries(markers, namespace)...)
+		}
+	}
+
+	timeline.Entries, timeline.Omitted = buildTimeline(entries, around, window, maxTimelineEntries)
+	return timeline, nil
+}
+
+// parseTimelineTime parses the time of an incident: an RFC 3339 time, or a duration before now such as "45m ago".
+func parseTimelineTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "now" {
+		return now, nil
+	}
+	if ago, ok := strings.CutSuffix(s, " ago"); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(ago))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", s, err)
+		}
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q, must be in RFC 3339 format (e.g. 2025-06-01T14:30:00Z) or relative (e.g. 45m ago)", s)
+	}
+	return t, nil
+}
+
+// buildTimeline returns the entries within window of around, sorted by time. If there are more than max,
+// the ones furthest from around are left out, and their number is returned.
+func buildTimeline(entries []TimelineEntry, around time.Time, window time.Duration, max int) ([]TimelineEntry, int) {
+	var timeline []TimelineEntry
+	for _, entry := range entries {
+		if entry.Time.IsZero() || entry.Time.Before(around.Add(-window)) || entry.Time.After(around.Add(window)) {
+			continue
+		}
+		timeline = append(timeline, entry)
+	}
+	omitted := 0
+	if len(timeline) > max {
+		distance := func(entry TimelineEntry) time.Duration {
+			return entry.Time.Sub(around).Abs()
+		}
+		sort.SliceStable(timeline, func(i, j int) bool {
+			return distance(timeline[i]) < distance(timeline[j])
+		})
+		omitted = len(timeline) - max
+		timeline = timeline[:max]
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Time.Before(timeline[j].Time)
+	})
+	return timeline, omitted
+}
+
+type timelineEventList struct {
+	Items []eventObject `json:"items"`
+}
+
+func (l *timelineEventList) entries() []TimelineEntry {
+	var entries []TimelineEntry
+	for _, event := range l.Items {
+		// Repeated events are placed when they were first seen, which is when the problem started.
+		t := event.FirstTimestamp
+		if t.IsZero() {
+			t = event.EventTime
+		}
+		if t.IsZero() {
+			t = event.LastTimestamp
+		}
+		message := fmt.Sprintf("%s: %s", event.Reason, strings.TrimSpace(event.Message))
+		if event.Count > 1 {
+			message += fmt.Sprintf(" (x%d, last at %s)", event.Count, event.LastTimestamp.Format(time.RFC3339))
+		}
+		entries = append(entries, TimelineEntry{
+			Time:      t,
+			Source:    timelineSourceEvent,
+			Object:    strings.ToLower(event.InvolvedObject.Kind) + "/" + event.InvolvedObject.Name,
+			Namespace: event.InvolvedObject.Namespace,
+			Message:   message,
+			Warning:   event.Type == "Warning",
+		})
+	}
+	return entries
+}
+
+// revisionList holds the replicasets and controllerrevisions, which record the revisions of rollouts.
+type revisionList struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name              string            `json:"name"`
+			Namespace         string            `json:"namespace"`
+			CreationTimestamp time.Time         `json:"creationTimestamp"`
+			Annotations       map[string]string `json:"annotations"`
+			OwnerReferences   []struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"ownerReferences"`
+		} `json:"metadata"`
+		// Revision is set for controllerrevisions.
+		Revision int `json:"revision"`
+		// Spec is set for replicasets.
+		Spec struct {
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Name  string `json:"name"`
+						Image string `json:"image"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+func (l *revisionList) entries() []TimelineEntry {
+	var entries []TimelineEntry
+	for _, item := range l.Items {
+		// Revisions without an owner were not created by a rollout.
+		if len(item.Metadata.OwnerReferences) == 0 {
+			continue
+		}
+		owner := item.Metadata.OwnerReferences[0]
+		revision := item.Metadata.Annotations["deployment.kubernetes.io/revision"]
+		if item.Kind == "ControllerRevision" {
+			revision = fmt.Sprint(item.Revision)
+		}
+		message := fmt.Sprintf("rolled out revision %s (%s/%s)", revision, strings.ToLower(item.Kind), item.Metadata.Name)
+		var images []string
+		for _, container := range item.Spec.Template.Spec.Containers {
+			images = append(images, container.Name+"="+container.Image)
+		}
+		if len(images) > 0 {
+			message += ": " + strings.Join(images, ", ")
+		}
+		if cause := item.Metadata.Annotations["kubernetes.io/change-cause"]; cause != "" {
+			message += fmt.Sprintf(" (change cause: %s)", cause)
+		}
+		entries = append(entries, TimelineEntry{
+			Time:      item.Metadata.CreationTimestamp,
+			Source:    timelineSourceRollout,
+			Object:    strings.ToLower(owner.Kind) + "/" + owner.Name,
+			Namespace: item.Metadata.Namespace,
+			Message:   message,
+		})
+	}
+	return entries
+}
+
+type timelineNodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name              string    `json:"name"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+		} `json:"metadata"`
+		Spec struct {
+			Taints []struct {
+				Key       string    `json:"key"`
+				Effect    string    `json:"effect"`
+				TimeAdded time.Time `json:"timeAdded,omitzero"`
+			} `json:"taints"`
+		} `json:"spec"`
+		Status struct {
+			Conditions []struct {
+				Type               string    `json:"type"`
+				Status             string    `json:"status"`
+				Reason             string    `json:"reason"`
+				Message            string    `json:"message"`
+				LastTransitionTime time.Time `json:"lastTransitionTime"`
+			} `json:"conditions"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+func (l *timelineNodeList) entries() []TimelineEntry {
+	var entries []TimelineEntry
+	for _, node := range l.Items {
+		object := "node/" + node.Metadata.Name
+		entries = append(entries, TimelineEntry{
+			Time:    node.Metadata.CreationTimestamp,
+			Source:  timelineSourceNode,
+			Object:  object,
+			Message: "node joined the cluster",
+		})
+		for _, condition := range node.Status.Conditions {
+			message := fmt.Sprintf("%s became %s", condition.Type, condition.Status)
+			if condition.Reason != "" {
+				message += fmt.Sprintf(" (%s)", condition.Reason)
+			}
+			if condition.Message != "" {
+				message += ": " + condition.Message
+			}
+			// Ready is the only condition that is healthy when true.
+			healthy := (condition.Type == "Ready") == (condition.Status == "True")
+			entries = append(entries, TimelineEntry{
+				Time:    condition.LastTransitionTime,
+				Source:  timelineSourceNode,
+				Object:  object,
+				Message: message,
+				Warning: !healthy,
+			})
+		}
+		for _, taint := range node.Spec.Taints {
+			// Only the taints added by the node lifecycle controller record when they were added.
+			entries = append(entries, TimelineEntry{
+				Time:    taint.TimeAdded,
+				Source:  timelineSourceNode,
+				Object:  object,
+				Message: fmt.Sprintf("taint %s:%s added", taint.Key, taint.Effect),
+				Warning: true,
+			})
+		}
+	}
+	return entries
+}
+
+// loadDeployMarkers reads the deploy markers from a YAML or JSON file.
+func loadDeployMarkers(path string) ([]DeployMarker, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var markers []DeployMarker
+	if err := yaml.Unmarshal(b, &markers); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return markers, nil
+}
+
+// deployMarkerEntries returns the entries for the markers of deployments to namespace, or to all namespaces if it is empty.
+// Markers without a namespace are always included.
+func deployMarkerEntries(markers []DeployMarker, namespace string) []TimelineEntry {
+	var entries []TimelineEntry
+	for _, marker := range markers {
+		if namespace != "" && marker.Namespace != "" && marker.Namespace != namespace {
+			continue
+		}
+		entries = append(entries, TimelineEntry{
+			Time:      marker.Time,
+			Source:    timelineSourceDeploy,
+			Namespace: marker.Namespace,
+			Message:   marker.Description,
+		})
+	}
+	return entries
+}
+
+func (t *IncidentTimelineTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *IncidentTimelineTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,159 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTimelineTime(t *testing.T) {
+	now := time.Date(2025, 6, 1, 15, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{name: "empty", input: "", expected: now},
+		{name: "now", input: "now", expected: now},
+		{name: "rfc3339", input: "2025-06-01T14:30:00Z", expected: time.Date(2025, 6, 1, 14, 30, 0, 0, time.UTC)},
+		{name: "relative", input: "45m ago", expected: time.Date(2025, 6, 1, 14, 15, 0, 0, time.UTC)},
+		{name: "invalid duration", input: "yesterday ago", wantErr: true},
+		{name: "invalid", input: "14:30", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseTimelineTime(tc.input, now)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+			if !got.Equal(tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	around := time.Date(2025, 6, 1, 14, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return around.Add(time.Duration(minutes) * time.Minute)
+	}
+	entries := []TimelineEntry{
+		{Time: at(10), Message: "after"},
+		{Time: at(-90), Message: "too early"},
+		{Time: at(-20), Message: "before"},
+		{Time: at(-1), Message: "just before"},
+		{Message: "no time"},
+		{Time: at(50), Message: "late"},
+	}
+
+	timeline, omitted := buildTimeline(entries, around, time.Hour, 3)
+	var messages []string
+	for _, entry := range timeline {
+		messages = append(messages, entry.Message)
+	}
+	if expected := []string{"before", "just before", "after"}; !reflect.DeepEqual(messages, expected) {
+		t.Errorf("expected %v, got %v", expected, messages)
+	}
+	if omitted != 1 {
+		t.Errorf("expected 1 omitted entry, got %d", omitted)
+	}
+}
+
+func TestRevisionEntries(t *testing.T) {
+	input := `{"items": [
+		{"kind": "ReplicaSet", "metadata": {"name": "web-5d4f", "namespace": "shop", "creationTimestamp": "2025-06-01T13:55:00Z",
+			"annotations": {"deployment.kubernetes.io/revision": "7", "kubernetes.io/change-cause": "bump nginx"},
+			"ownerReferences": [{"kind": "Deployment", "name": "web"}]},
+			"spec": {"template": {"spec": {"containers": [{"name": "web", "image": "nginx:1.27"}]}}}},
+		{"kind": "ControllerRevision", "metadata": {"name": "db-6b7c", "namespace": "shop", "creationTimestamp": "2025-06-01T13:40:00Z",
+			"ownerReferences": [{"kind": "StatefulSet", "name": "db"}]}, "revision": 3},
+		{"kind": "ReplicaSet", "metadata": {"name": "standalone", "namespace": "shop", "creationTimestamp": "2025-06-01T13:00:00Z"}}
+	]}`
+	var revisions revisionList
+	if err := json.Unmarshal([]byte(input), &revisions); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []TimelineEntry{
+		{
+			Time:      time.Date(2025, 6, 1, 13, 55, 0, 0, time.UTC),
+			Source:    timelineSourceRollout,
+			Object:    "deployment/web",
+			Namespace: "shop",
+			Message:   "rolled out revision 7 (replicaset/web-5d4f): web=nginx:1.27 (change cause: bump nginx)",
+		},
+		{
+			Time:      time.Date(2025, 6, 1, 13, 40, 0, 0, time.UTC),
+			Source:    timelineSourceRollout,
+			Object:    "statefulset/db",
+			Namespace: "shop",
+			Message:   "rolled out revision 3 (controllerrevision/db-6b7c)",
+		},
+	}
+	if got := revisions.entries(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestNodeEntries(t *testing.T) {
+	input := `{"items": [
+		{"metadata": {"name": "pool-1", "creationTimestamp": "2025-06-01T10:00:00Z"},
+			"spec": {"taints": [{"key": "node.kubernetes.io/unreachable", "effect": "NoExecute", "timeAdded": "2025-06-01T13:51:00Z"}]},
+			"status": {"conditions": [
+				{"type": "MemoryPressure", "status": "False", "lastTransitionTime": "2025-06-01T10:00:00Z"},
+				{"type": "Ready", "status": "Unknown", "reason": "NodeStatusUnknown", "message": "Kubelet stopped posting node status.", "lastTransitionTime": "2025-06-01T13:50:00Z"}
+			]}}
+	]}`
+	var nodes timelineNodeList
+	if err := json.Unmarshal([]byte(input), &nodes); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, entry := range nodes.entries() {
+		got = append(got, entry.Time.Format("15:04")+" "+entry.Object+" "+entry.Message+map[bool]string{true: " (warning)"}[entry.Warning])
+	}
+	expected := []string{
+		"10:00 node/pool-1 node joined the cluster",
+		"10:00 node/pool-1 MemoryPressure became False",
+		"13:50 node/pool-1 Ready became Unknown (NodeStatusUnknown): Kubelet stopped posting node status. (warning)",
+		"13:51 node/pool-1 taint node.kubernetes.io/unreachable:NoExecute added (warning)",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestDeployMarkerEntries(t *testing.T) {
+	markers := []DeployMarker{
+		{Description: "shop v2.3.1", Namespace: "shop"},
+		{Description: "payments v1.0.4", Namespace: "payments"},
+		{Description: "cluster-wide CNI upgrade"},
+	}
+
+	var got []string
+	for _, entry := range deployMarkerEntries(markers, "shop") {
+		got = append(got, entry.Message)
+	}
+	if expected := []string{"shop v2.3.1", "cluster-wide CNI upgrade"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -34,6 +34,7 @@ type Tool interface {
 	FunctionDefinition() *gollm.FunctionDefinition
 
 	// Run invokes the tool, the agent calls this when the LLM requests tool invocation.
+	// Tools that take a while can report their progress with ReportProgress(ctx, ...).
 	Run(ctx context.Context, args map[string]any) (any, error)
 
 	// IsInteractive checks if a command is interactive
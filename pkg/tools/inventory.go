@@ -0,0 +1,230 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// inventoryKinds are the kinds of resources whose names are indexed, in the form kubectl get takes them.
+const inventoryKinds = "deployments,statefulsets,daemonsets,pods,services,ingresses,jobs,cronjobs,configmaps,secrets,persistentvolumeclaims,serviceaccounts"
+
+// maxReferenceMatches is how many names are given for a reference, at most.
+const maxReferenceMatches = 5
+
+// kindWords maps the words used for kinds in queries to the kinds in the inventory.
+var kindWords = map[string]string{
+	"deployment": "deployment", "deployments": "deployment", "deploy": "deployment",
+	"statefulset": "statefulset", "statefulsets": "statefulset", "sts": "statefulset",
+	"daemonset": "daemonset", "daemonsets": "daemonset", "ds": "daemonset",
+	"pod": "pod", "pods": "pod",
+	"service": "service", "services": "service", "svc": "service",
+	"ingress": "ingress", "ingresses": "ingress", "ing": "ingress",
+	"job": "job", "jobs": "job",
+	"cronjob": "cronjob", "cronjobs": "cronjob", "cj": "cronjob",
+	"configmap": "configmap", "configmaps": "configmap", "cm": "configmap",
+	"secret": "secret", "secrets": "secret",
+	"pvc": "persistentvolumeclaim", "pvcs": "persistentvolumeclaim",
+	"persistentvolumeclaim": "persistentvolumeclaim", "persistentvolumeclaims": "persistentvolumeclaim",
+	"serviceaccount": "serviceaccount", "serviceaccounts": "serviceaccount", "sa": "serviceaccount",
+}
+
+// referenceStopWords are words next to a kind that are not names, e.g. in "the pods of all deployments".
+var referenceStopWords = map[string]bool{
+	"the": true, "a": true, "an": true, "my": true, "our": true, "this": true, "that": true, "these": true, "those": true,
+	"all": true, "any": true, "every": true, "each": true, "some": true, "which": true, "what": true, "of": true,
+	"in": true, "on": true, "for": true, "to": true, "from": true, "with": true, "and": true, "or": true, "is": true,
+	"are": true, "new": true, "old": true, "its": true, "their": true, "namespace": true, "ns": true, "called": true, "named": true,
+}
+
+// queryNamespacePatterns find the namespace a query is about, e.g. "in the shop namespace" or "-n shop".
+var queryNamespacePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bin (?:the )?([a-z0-9][a-z0-9-]*) (?:namespace|ns)\b`),
+	regexp.MustCompile(`(?i)\b(?:namespace|ns)[: ]+([a-z0-9][a-z0-9-]*)`),
+	regexp.MustCompile(`(?:^|\s)(?:-n|--namespace)[ =]([a-z0-9][a-z0-9-]*)`),
+}
+
+// Inventory is a short-lived index of the names of the resources in each namespace, listed on the first lookup,
+// used to resolve references to resources in queries, such as "the payments deployment", to their exact names.
+type Inventory struct {
+	ttl time.Duration
+
+	mutex sync.Mutex
+	// namespaces are the indexes of the namespaces, by kubeconfig and namespace.
+	namespaces map[string]*namespaceInventory
+}
+
+// namespaceInventory is the index of a namespace.
+type namespaceInventory struct {
+	listed time.Time
+	// names are the names of the resources by kind, e.g. "deployment".
+	names map[string][]string
+}
+
+// NewInventory creates an inventory whose indexes are listed again when they are older than ttl.
+func NewInventory(ttl time.Duration) *Inventory {
+	return &Inventory{ttl: ttl, namespaces: make(map[string]*namespaceInventory)}
+}
+
+// Names returns the names of the resources in the namespace by kind, listing them if the index is missing or stale.
+// The empty namespace is the namespace of the current context.
+func (i *Inventory) Names(ctx context.Context, namespace string) (map[string][]string, error) {
+	kubeconfig, _ := ctx.Value(KubeconfigKey).(string)
+	key := kubeconfig + "\x00" + namespace
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if index, ok := i.namespaces[key]; ok && time.Since(index.listed) < i.ttl {
+		return index.names, nil
+	}
+	args := []string{"get", inventoryKinds, "--ignore-not-found", "-o", "name"}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	result, err := runKubectl(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	// Kinds we may not list (e.g. secrets) fail the command, but the names of the others are still listed.
+	if result.Stdout == "" && (result.Error != "" || result.ExitCode != 0) {
+		return nil, fmt.Errorf("listing resources in namespace %q: %s %s", namespace, result.Error, strings.TrimSpace(result.Stderr))
+	}
+	names := parseInventoryNames(result.Stdout)
+	i.namespaces[key] = &namespaceInventory{listed: time.Now(), names: names}
+	return names, nil
+}
+
+// parseInventoryNames parses the output of kubectl get -o name, e.g. "deployment.apps/web", into names by kind.
+func parseInventoryNames(output string) map[string][]string {
+	names := make(map[string][]string)
+	for _, line := range strings.Split(output, "\n") {
+		resource, name, ok := strings.Cut(strings.TrimSpace(line), "/")
+		if !ok || name == "" {
+			continue
+		}
+		kind, _, _ := strings.Cut(resource, ".")
+		names[kind] = append(names[kind], name)
+	}
+	return names
+}
+
+// Reference is a reference to a resource in a query, e.g. "payments deployment".
+type Reference struct {
+	// Text is the reference as written in the query.
+	Text string
+	// Kind is the kind of the resource, e.g. "deployment".
+	Kind string
+	// Name is the word that names the resource, e.g. "payments".
+	Name string
+	// Matches are the names of the resources that it may refer to, best first.
+	Matches []string
+}
+
+// ResolveReferences finds the references to resources in the query, and the names of the resources they may refer to
+// in the namespace the query is about, or defaultNamespace, and returns the namespace.
+// Only references that match resources are returned.
+func (i *Inventory) ResolveReferences(ctx context.Context, query, defaultNamespace string) (string, []Reference, error) {
+	namespace := queryNamespace(query)
+	if namespace == "" {
+		namespace = defaultNamespace
+	}
+	references := findReferences(query)
+	if len(references) == 0 {
+		return namespace, nil, nil
+	}
+	names, err := i.Names(ctx, namespace)
+	if err != nil {
+		return namespace, nil, err
+	}
+	var resolved []Reference
+	for _, reference := range references {
+		reference.Matches = matchNames(reference.Name, names[reference.Kind])
+		if len(reference.Matches) > 0 {
+			resolved = append(resolved, reference)
+		}
+	}
+	return namespace, resolved, nil
+}
+
+// queryNamespace returns the namespace named in the query, or "" if there is none.
+func queryNamespace(query string) string {
+	for _, pattern := range queryNamespacePatterns {
+		if match := pattern.FindStringSubmatch(query); match != nil && !referenceStopWords[strings.ToLower(match[1])] {
+			return strings.ToLower(match[1])
+		}
+	}
+	return ""
+}
+
+// findReferences returns the words next to a kind in the query, which may name a resource of the kind:
+// "payments" in "restart the payments deployment" or in "scale deployment payments".
+func findReferences(query string) []Reference {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.' || r == '_')
+	})
+	for i, word := range words {
+		words[i] = strings.Trim(word, ".-_")
+	}
+	var references []Reference
+	seen := make(map[string]bool)
+	add := func(kind, name, text string) {
+		if len(name) < 2 || referenceStopWords[name] || kindWords[name] != "" || seen[kind+"/"+name] {
+			return
+		}
+		seen[kind+"/"+name] = true
+		references = append(references, Reference{Text: text, Kind: kind, Name: name})
+	}
+	for i, word := range words {
+		kind, ok := kindWords[word]
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			add(kind, words[i-1], words[i-1]+" "+word)
+		}
+		if i+1 < len(words) {
+			add(kind, words[i+1], word+" "+words[i+1])
+		}
+	}
+	return references
+}
+
+// matchNames returns the names that name may refer to, best first: the name itself, names starting with it,
+// and names containing it.
+func matchNames(name string, names []string) []string {
+	if slices.Contains(names, name) {
+		return []string{name}
+	}
+	var prefixed, containing []string
+	for _, candidate := range names {
+		switch {
+		case strings.HasPrefix(candidate, name):
+			prefixed = append(prefixed, candidate)
+		case strings.Contains(candidate, name):
+			containing = append(containing, candidate)
+		}
+	}
+	matches := append(prefixed, containing...)
+	if len(matches) > maxReferenceMatches {
+		matches = matches[:maxReferenceMatches]
+	}
+	return matches
+}
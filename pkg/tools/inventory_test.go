@@ -0,0 +1,119 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInventoryNames(t *testing.T) {
+	output := `deployment.apps/payments-api
+deployment.apps/web
+pod/payments-api-7d9f8-x2k4q
+service/web
+ingress.networking.k8s.io/web
+`
+	expected := map[string][]string{
+		"deployment": {"payments-api", "web"},
+		"pod":        {"payments-api-7d9f8-x2k4q"},
+		"service":    {"web"},
+		"ingress":    {"web"},
+	}
+	if got := parseInventoryNames(output); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestFindReferences(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected []Reference
+	}{
+		{
+			query:    "restart the payments deployment",
+			expected: []Reference{{Text: "payments deployment", Kind: "deployment", Name: "payments"}},
+		},
+		{
+			// Words that do not name resources are left out when the references are resolved.
+			query:    "scale deploy web to 3 replicas",
+			expected: []Reference{{Text: "scale deploy", Kind: "deployment", Name: "scale"}, {Text: "deploy web", Kind: "deployment", Name: "web"}},
+		},
+		{
+			query: "why are the checkout pods crashing?",
+			expected: []Reference{
+				{Text: "checkout pods", Kind: "pod", Name: "checkout"},
+				{Text: "pods crashing", Kind: "pod", Name: "crashing"},
+			},
+		},
+		{
+			query:    "list all the pods in the shop namespace",
+			expected: nil,
+		},
+		{
+			query:    "what does the web svc point to",
+			expected: []Reference{{Text: "web svc", Kind: "service", Name: "web"}, {Text: "svc point", Kind: "service", Name: "point"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			if got := findReferences(tc.query); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMatchNames(t *testing.T) {
+	names := []string{"web", "payments-api", "payments-worker", "legacy-payments", "web-canary"}
+	testCases := []struct {
+		name     string
+		expected []string
+	}{
+		{name: "web", expected: []string{"web"}},
+		{name: "payments", expected: []string{"payments-api", "payments-worker", "legacy-payments"}},
+		{name: "canary", expected: []string{"web-canary"}},
+		{name: "checkout", expected: nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := matchNames(tc.name, names); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestQueryNamespace(t *testing.T) {
+	testCases := []struct {
+		query    string
+		expected string
+	}{
+		{query: "restart the payments deployment in the shop namespace", expected: "shop"},
+		{query: "list pods in namespace kube-system", expected: "kube-system"},
+		{query: "kubectl get pods -n monitoring", expected: "monitoring"},
+		{query: "restart the payments deployment", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.query, func(t *testing.T) {
+			if got := queryNamespace(tc.query); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -98,18 +98,7 @@ func analyzeCall(call *syntax.CallExpr) string {
 	}
 
 	// Extract command and arguments
-	var args []string
-	for _, arg := range call.Args {
-		lit := arg.Lit()
-		if lit == "" {
-			var sb strings.Builder
-			syntax.NewPrinter().Print(&sb, arg)
-			lit = strings.Trim(sb.String(), `"'`)
-		}
-		if lit != "" {
-			args = append(args, lit)
-		}
-	}
+	args := callArgs(call)
 
 	if len(args) == 0 {
 		klog.Warning("analyzeCall: no arguments extracted from call")
@@ -163,6 +152,23 @@ func analyzeCall(call *syntax.CallExpr) string {
 	return "unknown"
 }
 
+// callArgs returns the words of a shell call, with quotes removed.
+func callArgs(call *syntax.CallExpr) []string {
+	var args []string
+	for _, arg := range call.Args {
+		lit := arg.Lit()
+		if lit == "" {
+			var sb strings.Builder
+			syntax.NewPrinter().Print(&sb, arg)
+			lit = strings.Trim(sb.String(), `"'`)
+		}
+		if lit != "" {
+			args = append(args, lit)
+		}
+	}
+	return args
+}
+
 func hasDryRunFlag(command string) bool {
 	tokens := strings.Fields(command)
 	for _, token := range tokens {
@@ -0,0 +1,317 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// runKubectl runs kubectl with the given arguments, without going through a shell.
+// The kubeconfig and working directory are taken from the context.
+// This is intended for tools that build kubectl invocations themselves,
+// rather than executing a command provided by the LLM.
+func runKubectl(ctx context.Context, args ...string) (*ExecResult, error) {
+	return runKubectlWithInput(ctx, nil, args...)
+}
+
+// runKubectlWithInput runs kubectl like runKubectl, with stdin read from input (if not nil), e.g. for `-f -`.
+func runKubectlWithInput(ctx context.Context, input io.Reader, args ...string) (*ExecResult, error) {
+	cmd, err := kubectlCommand(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	if input != nil {
+		cmd.Stdin = input
+	}
+	return executeCommand(ctx, cmd)
+}
+
+// kubectlCommand returns the command that runs kubectl with the given arguments, with the executor,
+// kubeconfig and working directory taken from the context, for tools that read its output as it is produced.
+func kubectlCommand(ctx context.Context, args ...string) (*exec.Cmd, error) {
+	if executor := executorFromContext(ctx); !isLocal(executor) {
+		quoted := []string{"kubectl"}
+		for _, arg := range args {
+			quoted = append(quoted, shellQuote(arg))
+		}
+		workDir, _ := ctx.Value(WorkDirKey).(string)
+		kubeconfig, _ := ctx.Value(KubeconfigKey).(string)
+		return executor.Command(ctx, strings.Join(quoted, " "), workDir, kubeconfig)
+	}
+
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	cmd.Env = commandEnv(ctx)
+	if workDir, ok := ctx.Value(WorkDirKey).(string); ok {
+		cmd.Dir = workDir
+	}
+	if kubeconfig, ok := ctx.Value(KubeconfigKey).(string); ok && kubeconfig != "" {
+		kubeconfig, err := expandShellVar(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	}
+	return cmd, nil
+}
+
+// kubectlGetJSON runs `kubectl get <args> -o json` and decodes the output into out.
+func kubectlGetJSON(ctx context.Context, out any, args ...string) error {
+	args = append([]string{"get"}, args...)
+	args = append(args, "-o", "json")
+	result, err := runKubectl(ctx, args...)
+	if err != nil {
+		return err
+	}
+	if result.Error != "" || result.ExitCode != 0 {
+		return fmt.Errorf("running kubectl %s: %s %s", strings.Join(args, " "), result.Error, strings.TrimSpace(result.Stderr))
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), out); err != nil {
+		return fmt.Errorf("parsing output of kubectl %s: %w", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// stringArg returns the string argument with the given key, or "" if it is missing or not a string.
+func stringArg(args map[string]any, key string) string {
+	s, _ := args[key].(string)
+	return strings.TrimSpace(s)
+}
+
+// boolArg returns the boolean argument with the given key, accepting "true"/"false" strings as well.
+func boolArg(args map[string]any, key string) bool {
+	switch v := args[key].(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(strings.TrimSpace(v), "true")
+	}
+	return false
+}
+
+// intArg returns the integer argument with the given key, accepting numeric strings as well,
+// and whether it is set.
+func intArg(args map[string]any, key string) (int, bool) {
+	switch v := args[key].(type) {
+	case float64:
+		return int(v), true
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// stringSliceArg returns the list of strings with the given key.
+// A single string is treated as a comma-separated list.
+func stringSliceArg(args map[string]any, key string) []string {
+	var values []string
+	switch v := args[key].(type) {
+	case []any:
+		for _, item := range v {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+	case []string:
+		for _, s := range v {
+			if strings.TrimSpace(s) != "" {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+	case string:
+		for _, s := range strings.Split(v, ",") {
+			if strings.TrimSpace(s) != "" {
+				values = append(values, strings.TrimSpace(s))
+			}
+		}
+	}
+	return values
+}
+
+// The types below are minimal views of Kubernetes objects, holding only the fields our tools inspect.
+// We decode kubectl JSON output into these rather than depending on k8s.io/api.
+
+type objectMeta struct {
+	Name            string            `json:"name"`
+	Namespace       string            `json:"namespace,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+type podObject struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     podSpec    `json:"spec"`
+}
+
+type podSpec struct {
+	ServiceAccountName string              `json:"serviceAccountName,omitempty"`
+	HostNetwork        bool                `json:"hostNetwork,omitempty"`
+	HostPID            bool                `json:"hostPID,omitempty"`
+	HostIPC            bool                `json:"hostIPC,omitempty"`
+	SecurityContext    *podSecurityContext `json:"securityContext,omitempty"`
+	Containers         []container         `json:"containers,omitempty"`
+	InitContainers     []container         `json:"initContainers,omitempty"`
+	Volumes            []volume            `json:"volumes,omitempty"`
+}
+
+type podSecurityContext struct {
+	RunAsNonRoot   *bool           `json:"runAsNonRoot,omitempty"`
+	RunAsUser      *int64          `json:"runAsUser,omitempty"`
+	SeccompProfile *seccompProfile `json:"seccompProfile,omitempty"`
+}
+
+type container struct {
+	Name            string           `json:"name"`
+	Image           string           `json:"image,omitempty"`
+	Env             []envVar         `json:"env,omitempty"`
+	Ports           []containerPort  `json:"ports,omitempty"`
+	SecurityContext *securityContext `json:"securityContext,omitempty"`
+	Resources       *resources       `json:"resources,omitempty"`
+}
+
+// resources are the requests and limits of a container, as quantities such as "250m" or "512Mi".
+type resources struct {
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+}
+
+type containerPort struct {
+	ContainerPort int32 `json:"containerPort"`
+	HostPort      int32 `json:"hostPort,omitempty"`
+}
+
+type securityContext struct {
+	Privileged               *bool           `json:"privileged,omitempty"`
+	AllowPrivilegeEscalation *bool           `json:"allowPrivilegeEscalation,omitempty"`
+	RunAsNonRoot             *bool           `json:"runAsNonRoot,omitempty"`
+	RunAsUser                *int64          `json:"runAsUser,omitempty"`
+	Capabilities             *capabilities   `json:"capabilities,omitempty"`
+	SeccompProfile           *seccompProfile `json:"seccompProfile,omitempty"`
+	ProcMount                string          `json:"procMount,omitempty"`
+}
+
+type capabilities struct {
+	Add  []string `json:"add,omitempty"`
+	Drop []string `json:"drop,omitempty"`
+}
+
+type seccompProfile struct {
+	Type string `json:"type"`
+}
+
+type envVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+type volume struct {
+	Name      string           `json:"name"`
+	HostPath  *hostPathVolume  `json:"hostPath,omitempty"`
+	Projected *projectedVolume `json:"projected,omitempty"`
+}
+
+type hostPathVolume struct {
+	Path string `json:"path"`
+}
+
+type projectedVolume struct {
+	Sources []volumeProjection `json:"sources,omitempty"`
+}
+
+type volumeProjection struct {
+	ServiceAccountToken *serviceAccountTokenProjection `json:"serviceAccountToken,omitempty"`
+}
+
+type serviceAccountTokenProjection struct {
+	Audience          string `json:"audience,omitempty"`
+	ExpirationSeconds *int64 `json:"expirationSeconds,omitempty"`
+	Path              string `json:"path"`
+}
+
+type serviceAccountObject struct {
+	Metadata objectMeta `json:"metadata"`
+}
+
+// workloadObject is any object embedding a pod template (or a pod itself).
+// Lists (e.g. from `kubectl get pods -o json`) are decoded into Items.
+type workloadObject struct {
+	Kind     string           `json:"kind"`
+	Metadata objectMeta       `json:"metadata"`
+	Spec     json.RawMessage  `json:"spec,omitempty"`
+	Items    []workloadObject `json:"items,omitempty"`
+}
+
+// PodSpec extracts the pod spec from a pod, a workload with a pod template, or a CronJob.
+func (o *workloadObject) PodSpec() (*podSpec, error) {
+	if len(o.Spec) == 0 {
+		return nil, fmt.Errorf("%s %q has no spec", o.Kind, o.Metadata.Name)
+	}
+	switch o.Kind {
+	case "Pod":
+		spec := &podSpec{}
+		if err := json.Unmarshal(o.Spec, spec); err != nil {
+			return nil, fmt.Errorf("parsing pod spec: %w", err)
+		}
+		return spec, nil
+	case "CronJob":
+		var cronJobSpec struct {
+			JobTemplate struct {
+				Spec struct {
+					Template struct {
+						Spec podSpec `json:"spec"`
+					} `json:"template"`
+				} `json:"spec"`
+			} `json:"jobTemplate"`
+		}
+		if err := json.Unmarshal(o.Spec, &cronJobSpec); err != nil {
+			return nil, fmt.Errorf("parsing cronjob spec: %w", err)
+		}
+		return &cronJobSpec.JobTemplate.Spec.Template.Spec, nil
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		var workloadSpec struct {
+			Template *struct {
+				Spec podSpec `json:"spec"`
+			} `json:"template"`
+		}
+		if err := json.Unmarshal(o.Spec, &workloadSpec); err != nil {
+			return nil, fmt.Errorf("parsing %s spec: %w", o.Kind, err)
+		}
+		if workloadSpec.Template == nil {
+			return nil, fmt.Errorf("%s %q has no pod template", o.Kind, o.Metadata.Name)
+		}
+		return &workloadSpec.Template.Spec, nil
+	default:
+		return nil, fmt.Errorf("kind %q does not contain a pod spec", o.Kind)
+	}
+}
+
+// Objects returns the objects in a list, or the object itself if it is not a list.
+func (o *workloadObject) Objects() []workloadObject {
+	if strings.HasSuffix(o.Kind, "List") {
+		return o.Items
+	}
+	return []workloadObject{*o}
+}
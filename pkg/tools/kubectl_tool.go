@@ -16,9 +16,6 @@ package tools
 
 import (
 	"context"
-	"os"
-	"os/exec"
-	"runtime"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
 )
@@ -94,6 +91,10 @@ Possible values:
 	}
 }
 
+func (t *Kubectl) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ExecResult]()
+}
+
 func (t *Kubectl) Run(ctx context.Context, args map[string]any) (any, error) {
 	kubeconfig := ctx.Value(KubeconfigKey).(string)
 	workDir := ctx.Value(WorkDirKey).(string)
@@ -109,7 +110,12 @@ func (t *Kubectl) Run(ctx context.Context, args map[string]any) (any, error) {
 		return &ExecResult{Error: "kubectl command must be a string"}, nil
 	}
 
-	return runKubectlCommand(ctx, command, workDir, kubeconfig)
+	result, err := runKubectlCommand(ctx, command, workDir, kubeconfig)
+	if err == nil {
+		attributeCommand(ctx, command, result)
+		recordCreated(ctx, command, result)
+	}
+	return result, err
 }
 
 func runKubectlCommand(ctx context.Context, command, workDir, kubeconfig string) (*ExecResult, error) {
@@ -118,23 +124,19 @@ func runKubectlCommand(ctx context.Context, command, workDir, kubeconfig string)
 		return &ExecResult{Error: err.Error()}, nil
 	}
 
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(ctx, os.Getenv("COMSPEC"), "/c", command)
-	} else {
-		cmd = exec.CommandContext(ctx, lookupBashBin(), "-c", command)
+	if result := checkOffline(ctx, command); result != nil {
+		return result, nil
 	}
-	cmd.Env = os.Environ()
-	cmd.Dir = workDir
-	if kubeconfig != "" {
-		kubeconfig, err := expandShellVar(kubeconfig)
-		if err != nil {
-			return nil, err
-		}
-		cmd.Env = append(cmd.Env, "KUBECONFIG="+kubeconfig)
+	// kubectl commands run in a shell too, so they could redirect output anywhere.
+	if result := checkFilesystemPolicy(ctx, command, workDir); result != nil {
+		return result, nil
 	}
 
-	return executeCommand(cmd)
+	cmd, err := shellCommand(ctx, command, workDir, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	return executeCommand(ctx, cmd)
 }
 
 func (t *Kubectl) IsInteractive(args map[string]any) (bool, error) {
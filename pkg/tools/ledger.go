@@ -0,0 +1,217 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// CreatedObject is an object created by a tool call, recorded in the change ledger.
+type CreatedObject struct {
+	// Resource is the type of the object as kubectl prints it, e.g. "deployment.apps".
+	Resource string `json:"resource"`
+	Name     string `json:"name"`
+	// Namespace is empty for cluster-scoped objects, and for objects in the default namespace of the context.
+	Namespace string `json:"namespace,omitempty"`
+	// Flags are the kubectl flags that select the cluster of the object, e.g. "--context=prod".
+	Flags []string `json:"flags,omitempty"`
+}
+
+func (o CreatedObject) String() string {
+	if o.Namespace == "" {
+		return o.Resource + "/" + o.Name
+	}
+	return fmt.Sprintf("%s/%s in namespace %s", o.Resource, o.Name, o.Namespace)
+}
+
+// DeleteCommand returns the kubectl command that deletes the object.
+func (o CreatedObject) DeleteCommand() string {
+	args := []string{"kubectl", "delete", shellQuote(o.Resource + "/" + o.Name), "--ignore-not-found"}
+	if o.Namespace != "" {
+		args = append(args, "--namespace="+shellQuote(o.Namespace))
+	}
+	for _, flag := range o.Flags {
+		args = append(args, shellQuote(flag))
+	}
+	return strings.Join(args, " ")
+}
+
+// Ledger is the change ledger: it records the objects that tool calls create, so that they can be deleted
+// if the task is aborted before it completes. It is safe for concurrent use.
+type Ledger struct {
+	mutex   sync.Mutex
+	created []CreatedObject
+}
+
+// Created returns the objects created since the ledger was last reset, in the order they were created.
+func (l *Ledger) Created() []CreatedObject {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return slices.Clone(l.created)
+}
+
+// Reset forgets the recorded objects.
+func (l *Ledger) Reset() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.created = nil
+}
+
+func (l *Ledger) add(objects ...CreatedObject) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, object := range objects {
+		if !slices.ContainsFunc(l.created, func(o CreatedObject) bool {
+			return o.String() == object.String() && slices.Equal(o.Flags, object.Flags)
+		}) {
+			l.created = append(l.created, object)
+		}
+	}
+}
+
+type ledgerKey struct{}
+
+// WithLedger returns a context in which tools record the objects they create in the ledger.
+func WithLedger(ctx context.Context, ledger *Ledger) context.Context {
+	return context.WithValue(ctx, ledgerKey{}, ledger)
+}
+
+// ledgerFromContext returns the ledger of the context, or nil if created objects are not recorded.
+func ledgerFromContext(ctx context.Context) *Ledger {
+	ledger, _ := ctx.Value(ledgerKey{}).(*Ledger)
+	return ledger
+}
+
+// creatingVerbs are the kubectl verbs that print "<resource>/<name> created" for the objects they create.
+var creatingVerbs = map[string]bool{
+	"create": true, "apply": true, "run": true, "expose": true, "autoscale": true,
+}
+
+// createdLine matches the lines kubectl prints for the objects it creates, e.g. "deployment.apps/nginx created".
+var createdLine = regexp.MustCompile(`^(\S+)/(\S+) created$`)
+
+// creatingInvocation returns the kubectl invocation in command that creates objects. ok is false if there is none,
+// or more than one, as we could not tell which one printed each object.
+func creatingInvocation(command string) (inv *kubectlInvocation, ok bool) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		klog.V(2).Infof("creatingInvocation: cannot parse command %q: %v", command, err)
+		return nil, false
+	}
+
+	var found []*kubectlInvocation
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok {
+			if inv, _, ok := mutationTargets(callArgs(call)); ok && creatingVerbs[inv.verb] {
+				found = append(found, inv)
+			}
+		}
+		return true
+	})
+	if len(found) != 1 {
+		return nil, false
+	}
+	return found[0], true
+}
+
+// createdObjects returns the objects that kubectl reported creating in stdout, in the namespace and cluster
+// selected by the flags of inv.
+func createdObjects(inv *kubectlInvocation, stdout string) []CreatedObject {
+	var objects []CreatedObject
+	for _, line := range outputLines(stdout) {
+		match := createdLine.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		object := CreatedObject{Resource: match[1], Name: match[2]}
+		for _, flag := range inv.flags {
+			name, value, _ := strings.Cut(flag, "=")
+			switch name {
+			case "-n", "--namespace":
+				object.Namespace = value
+			case "--context", "--kubeconfig":
+				object.Flags = append(object.Flags, flag)
+			}
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+// hasNamespaceFlag reports whether inv selects a namespace.
+func (inv *kubectlInvocation) hasNamespaceFlag() bool {
+	return slices.ContainsFunc(inv.flags, func(flag string) bool {
+		return strings.HasPrefix(flag, "-n=") || strings.HasPrefix(flag, "--namespace=")
+	})
+}
+
+// recordCreated records the objects created by a command in the ledger of the context, if any.
+// The command may have failed after creating some of them.
+func recordCreated(ctx context.Context, command string, result *ExecResult) {
+	ledger := ledgerFromContext(ctx)
+	if ledger == nil || result == nil {
+		return
+	}
+	inv, ok := creatingInvocation(command)
+	if !ok {
+		return
+	}
+	objects := createdObjects(inv, result.Stdout)
+	if len(objects) == 0 {
+		return
+	}
+	if inv.stdin {
+		// The objects may be in any namespace, and we can't read them again to find out which.
+		klog.Warningf("Not recording the objects created by %q: they were read from stdin", command)
+		return
+	}
+	if len(inv.sources) != 0 && !inv.hasNamespaceFlag() {
+		// The files may put the objects in other namespaces than the default one.
+		objects = resolveNamespaces(ctx, inv, objects)
+	}
+	ledger.add(objects...)
+}
+
+// resolveNamespaces sets the namespaces of objects created from the files of inv, as read back from the cluster.
+// Objects we cannot find are dropped, rather than deleted from the wrong namespace later.
+func resolveNamespaces(ctx context.Context, inv *kubectlInvocation, objects []CreatedObject) []CreatedObject {
+	var resolved []CreatedObject
+	for _, source := range inv.sources {
+		var list workloadObject
+		args := append(slices.Clone(source), inv.flags...)
+		if err := kubectlGetJSON(ctx, &list, args...); err != nil {
+			klog.Warningf("Finding the namespaces of the objects created from %s: %v", strings.Join(source, " "), err)
+			continue
+		}
+		for _, found := range list.Objects() {
+			for _, object := range objects {
+				kind, _, _ := strings.Cut(object.Resource, ".")
+				if object.Name == found.Metadata.Name && strings.EqualFold(kind, found.Kind) {
+					object.Namespace = found.Metadata.Namespace
+					resolved = append(resolved, object)
+				}
+			}
+		}
+	}
+	return resolved
+}
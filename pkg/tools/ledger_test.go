@@ -0,0 +1,97 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestRecordCreated(t *testing.T) {
+	testCases := []struct {
+		name     string
+		command  string
+		stdout   string
+		expected []CreatedObject
+	}{
+		{
+			name:    "create",
+			command: "kubectl create deployment nginx --image=nginx -n web",
+			stdout:  "deployment.apps/nginx created\n",
+			expected: []CreatedObject{
+				{Resource: "deployment.apps", Name: "nginx", Namespace: "web"},
+			},
+		},
+		{
+			name:    "apply to another cluster",
+			command: "kubectl apply -f app.yaml --namespace=web --context prod",
+			stdout:  "deployment.apps/web created\nservice/web unchanged\nconfigmap/web-config configured\n",
+			expected: []CreatedObject{
+				{Resource: "deployment.apps", Name: "web", Namespace: "web", Flags: []string{"--context=prod"}},
+			},
+		},
+		{
+			name:    "run in the default namespace",
+			command: "kubectl run debug --image=busybox -- sleep 3600",
+			stdout:  "pod/debug created\n",
+			expected: []CreatedObject{
+				{Resource: "pod", Name: "debug"},
+			},
+		},
+		{
+			name:     "dry run",
+			command:  "kubectl create namespace web --dry-run=client",
+			stdout:   "namespace/web created (dry run)\n",
+			expected: nil,
+		},
+		{
+			name:     "read from stdin",
+			command:  "cat app.yaml | kubectl apply -n web -f -",
+			stdout:   "deployment.apps/web created\n",
+			expected: nil,
+		},
+		{
+			name:     "several creating commands",
+			command:  "kubectl create namespace web && kubectl create deployment web --image=nginx -n web",
+			stdout:   "namespace/web created\ndeployment.apps/web created\n",
+			expected: nil,
+		},
+		{
+			name:     "read-only command",
+			command:  "kubectl get events | grep created",
+			stdout:   "pod/web created\n",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ledger := &Ledger{}
+			recordCreated(WithLedger(context.Background(), ledger), tc.command, &ExecResult{Stdout: tc.stdout})
+			if got := ledger.Created(); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("recordCreated(%q) recorded %#v, expected %#v", tc.command, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCreatedObjectDeleteCommand(t *testing.T) {
+	object := CreatedObject{Resource: "deployment.apps", Name: "web", Namespace: "shop", Flags: []string{"--kubeconfig=/home/me/my config"}}
+	expected := "kubectl delete deployment.apps/web --ignore-not-found --namespace=shop '--kubeconfig=/home/me/my config'"
+	if got := object.DeleteCommand(); got != expected {
+		t.Errorf("DeleteCommand() = %q, expected %q", got, expected)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// Formats of tool manifests.
+const (
+	// ManifestFormatOpenAI is the tools array of the OpenAI chat completions API.
+	ManifestFormatOpenAI = "openai"
+	// ManifestFormatLangChain lists the MCP servers to connect to (as langchain-mcp-adapters connections)
+	// and the tools they serve, with their argument schemas.
+	ManifestFormatLangChain = "langchain"
+)
+
+// ManifestServerName is the name of the kubectl-ai MCP server in manifests.
+const ManifestServerName = "kubectl-ai"
+
+// ManifestServer is how to connect to an MCP server: the command that starts a stdio server, or the URL of
+// a streamable HTTP server.
+type ManifestServer struct {
+	Command string
+	Args    []string
+	URL     string
+}
+
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIToolFunction `json:"function"`
+}
+
+type openAIToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type langChainManifest struct {
+	// MCPServers are the connections to the MCP servers, keyed by server name.
+	MCPServers map[string]langChainConnection `json:"mcp_servers"`
+	Tools      []langChainTool                `json:"tools"`
+}
+
+type langChainConnection struct {
+	Transport string   `json:"transport"`
+	Command   string   `json:"command,omitempty"`
+	Args      []string `json:"args,omitempty"`
+	URL       string   `json:"url,omitempty"`
+}
+
+type langChainTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	ArgsSchema  json.RawMessage `json:"args_schema"`
+	// Server is the MCP server that serves the tool.
+	Server string `json:"server"`
+}
+
+// ToolManifest describes the tools in a format that other agent frameworks can import, sorted by name.
+// Tools from MCP servers that kubectl-ai is a client of are attributed to those servers; the others are
+// served by the kubectl-ai MCP server. servers are the connections to the MCP servers, by name, including
+// ManifestServerName.
+func ToolManifest(tools []Tool, format string, servers map[string]ManifestServer) (any, error) {
+	tools = append([]Tool(nil), tools...)
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name() < tools[j].Name() })
+
+	switch format {
+	case ManifestFormatOpenAI:
+		manifest := []openAITool{}
+		for _, tool := range tools {
+			definition := tool.FunctionDefinition()
+			parameters, err := manifestSchema(definition)
+			if err != nil {
+				return nil, err
+			}
+			manifest = append(manifest, openAITool{
+				Type: "function",
+				Function: openAIToolFunction{
+					Name:        definition.Name,
+					Description: definition.Description,
+					Parameters:  parameters,
+				},
+			})
+		}
+		return manifest, nil
+
+	case ManifestFormatLangChain:
+		manifest := &langChainManifest{
+			MCPServers: map[string]langChainConnection{},
+			Tools:      []langChainTool{},
+		}
+		for name, server := range servers {
+			if server.URL != "" {
+				manifest.MCPServers[name] = langChainConnection{Transport: "streamable_http", URL: server.URL}
+			} else {
+				manifest.MCPServers[name] = langChainConnection{Transport: "stdio", Command: server.Command, Args: server.Args}
+			}
+		}
+		for _, tool := range tools {
+			definition := tool.FunctionDefinition()
+			schema, err := manifestSchema(definition)
+			if err != nil {
+				return nil, err
+			}
+			serverName := ManifestServerName
+			if mcpTool, ok := tool.(*MCPTool); ok {
+				serverName = mcpTool.ServerName()
+			}
+			manifest.Tools = append(manifest.Tools, langChainTool{
+				Name:        definition.Name,
+				Description: definition.Description,
+				ArgsSchema:  schema,
+				Server:      serverName,
+			})
+		}
+		return manifest, nil
+	}
+	return nil, fmt.Errorf("invalid manifest format %q, supported values: %s, %s", format, ManifestFormatOpenAI, ManifestFormatLangChain)
+}
+
+// manifestSchema returns the JSON schema of the parameters of a function; functions without parameters
+// take an empty object, as frameworks require a schema.
+func manifestSchema(definition *gollm.FunctionDefinition) (json.RawMessage, error) {
+	parameters := definition.Parameters
+	if parameters == nil {
+		parameters = &gollm.Schema{Type: gollm.TypeObject}
+	}
+	schema, err := parameters.ToRawSchema()
+	if err != nil {
+		return nil, fmt.Errorf("converting the schema of tool %q: %w", definition.Name, err)
+	}
+	return schema, nil
+}
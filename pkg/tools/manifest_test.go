@@ -0,0 +1,102 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func TestToolManifest(t *testing.T) {
+	mcpTool := NewMCPTool("github", "list_issues", "Lists issues", &gollm.FunctionDefinition{Name: "list_issues", Description: "Lists issues"}, nil)
+	tools := []Tool{&Kubectl{}, mcpTool, &BashTool{}}
+	servers := map[string]ManifestServer{
+		ManifestServerName: {Command: "kubectl-ai", Args: []string{"--mcp-server"}},
+		"github":           {URL: "https://mcp.example.com/mcp"},
+	}
+
+	tests := []struct {
+		format  string
+		want    string
+		wantErr bool
+	}{
+		{
+			format: ManifestFormatOpenAI,
+			want:   `[{"type":"function","function":{"name":"bash"}},{"type":"function","function":{"name":"kubectl"}},{"type":"function","function":{"name":"list_issues","parameters":{"type":"object"}}}]`,
+		},
+		{
+			format: ManifestFormatLangChain,
+			want: `{"mcp_servers":{"github":{"transport":"streamable_http","url":"https://mcp.example.com/mcp"},"kubectl-ai":{"transport":"stdio","command":"kubectl-ai","args":["--mcp-server"]}},` +
+				`"tools":[{"name":"bash","server":"kubectl-ai"},{"name":"kubectl","server":"kubectl-ai"},{"name":"list_issues","args_schema":{"type":"object"},"server":"github"}]}`,
+		},
+		{
+			format:  "yaml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			manifest, err := ToolManifest(tools, tt.format, servers)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ToolManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := summarizeManifest(t, manifest)
+			want := summarizeManifest(t, json.RawMessage(tt.want))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ToolManifest() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// summarizeManifest decodes a manifest, keeping only the parameters of tools without any, as the schemas
+// of the built-in tools change more often than the format of the manifest.
+func summarizeManifest(t *testing.T, manifest any) any {
+	t.Helper()
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded any
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	var toolList []any
+	switch m := decoded.(type) {
+	case []any:
+		toolList = m
+	case map[string]any:
+		toolList, _ = m["tools"].([]any)
+	}
+	for _, tool := range toolList {
+		tool := tool.(map[string]any)
+		if function, ok := tool["function"].(map[string]any); ok {
+			tool = function
+		}
+		delete(tool, "description")
+		for _, key := range []string{"parameters", "args_schema"} {
+			if schema, ok := tool[key].(map[string]any); ok && schema["properties"] != nil && len(schema["properties"].(map[string]any)) > 0 {
+				delete(tool, key)
+			}
+		}
+	}
+	return decoded
+}
@@ -0,0 +1,264 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"k8s.io/klog/v2"
+)
+
+// minMemorySimilarity is the cosine similarity of embeddings below which a memory is not relevant to a query.
+const minMemorySimilarity = 0.3
+
+// Memory is a durable fact learned in a session, e.g. a quirk of a cluster, a naming convention or how a past
+// incident was resolved, that is recalled in later sessions when it is relevant.
+type Memory struct {
+	Text    string    `json:"text"`
+	Created time.Time `json:"created"`
+	// Embedding is the embedding of Text computed with EmbeddingModel, used to find the memories relevant to a query.
+	Embedding      []float32 `json:"embedding,omitempty"`
+	EmbeddingModel string    `json:"embeddingModel,omitempty"`
+}
+
+// Memories are the memories stored in a file. They are safe for concurrent use, except for List,
+// which is only changed directly before the memories are used (e.g. by kubectl-ai memories).
+type Memories struct {
+	path  string
+	mutex sync.Mutex
+	List  []Memory `json:"memories"`
+}
+
+// LoadMemories reads the memories stored in path; a missing file has no memories.
+func LoadMemories(path string) (*Memories, error) {
+	memories := &Memories{path: path}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return memories, nil
+		}
+		return nil, fmt.Errorf("reading memories: %w", err)
+	}
+	if err := json.Unmarshal(b, memories); err != nil {
+		return nil, fmt.Errorf("parsing memories %q: %w", path, err)
+	}
+	return memories, nil
+}
+
+// Save writes the memories to the file they were loaded from.
+func (m *Memories) Save() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.save()
+}
+
+func (m *Memories) save() error {
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o700); err != nil {
+		return fmt.Errorf("creating memories directory: %w", err)
+	}
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(m.path, b, 0o600); err != nil {
+		return fmt.Errorf("writing memories: %w", err)
+	}
+	return nil
+}
+
+// Add remembers the fact, unless it is already remembered, and saves the memories.
+// The fact is embedded with the model if embedder is not nil; if that fails, it is remembered without an embedding,
+// which is computed when the memories are next recalled.
+func (m *Memories) Add(ctx context.Context, embedder gollm.Embedder, model, text string) error {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return fmt.Errorf("the memory is empty")
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if slices.ContainsFunc(m.List, func(memory Memory) bool { return strings.EqualFold(memory.Text, text) }) {
+		return nil
+	}
+	memory := Memory{Text: text, Created: time.Now()}
+	if embedder != nil {
+		embeddings, err := embedder.Embed(ctx, model, []string{text})
+		if err != nil {
+			klog.FromContext(ctx).Info("Not embedding the memory", "err", err)
+		} else {
+			memory.Embedding, memory.EmbeddingModel = embeddings[0], model
+		}
+	}
+	m.List = append(m.List, memory)
+	return m.save()
+}
+
+// Recall returns up to limit memories relevant to the query, most relevant first, skipping those whose text is in skip.
+// Memories are compared to the query by the similarity of their embeddings if embedder is not nil, computing the
+// embeddings that are missing or were computed with another model; otherwise, or if that fails, by the words they share.
+func (m *Memories) Recall(ctx context.Context, embedder gollm.Embedder, model, query string, limit int, skip map[string]bool) []Memory {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var candidates []Memory
+	for _, memory := range m.List {
+		if !skip[memory.Text] {
+			candidates = append(candidates, memory)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	if embedder != nil {
+		scores, err := m.embeddingScores(ctx, embedder, model, query, candidates)
+		if err == nil {
+			return topMemories(candidates, scores, minMemorySimilarity, limit)
+		}
+		klog.FromContext(ctx).Info("Recalling memories by the words they share, as embedding failed", "err", err)
+	}
+	scores := make([]float64, len(candidates))
+	queryWords := memoryWords(query)
+	for i, memory := range candidates {
+		scores[i] = wordSimilarity(queryWords, memoryWords(memory.Text))
+	}
+	return topMemories(candidates, scores, 0, limit)
+}
+
+// embeddingScores returns the cosine similarity of the embeddings of the query and of each candidate.
+// The embeddings of the candidates that have none for the model are computed and saved.
+func (m *Memories) embeddingScores(ctx context.Context, embedder gollm.Embedder, model, query string, candidates []Memory) ([]float64, error) {
+	texts := []string{query}
+	var missing []int
+	for i, memory := range candidates {
+		if memory.EmbeddingModel != model || len(memory.Embedding) == 0 {
+			texts = append(texts, memory.Text)
+			missing = append(missing, i)
+		}
+	}
+	embeddings, err := embedder.Embed(ctx, model, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		for j, i := range missing {
+			candidates[i].Embedding, candidates[i].EmbeddingModel = embeddings[j+1], model
+			for k := range m.List {
+				if m.List[k].Text == candidates[i].Text {
+					m.List[k].Embedding, m.List[k].EmbeddingModel = embeddings[j+1], model
+				}
+			}
+		}
+		if err := m.save(); err != nil {
+			klog.FromContext(ctx).Info("Not saving the embeddings of the memories", "err", err)
+		}
+	}
+	scores := make([]float64, len(candidates))
+	for i, memory := range candidates {
+		scores[i] = cosineSimilarity(embeddings[0], memory.Embedding)
+	}
+	return scores, nil
+}
+
+// topMemories returns up to limit memories whose score is above threshold, highest score first.
+func topMemories(memories []Memory, scores []float64, threshold float64, limit int) []Memory {
+	var indexes []int
+	for i, score := range scores {
+		if score > threshold {
+			indexes = append(indexes, i)
+		}
+	}
+	slices.SortStableFunc(indexes, func(i, j int) int {
+		switch {
+		case scores[i] > scores[j]:
+			return -1
+		case scores[i] < scores[j]:
+			return 1
+		}
+		return 0
+	})
+	var top []Memory
+	for _, i := range indexes {
+		if len(top) == limit {
+			break
+		}
+		top = append(top, memories[i])
+	}
+	return top
+}
+
+// cosineSimilarity returns the cosine similarity of two embeddings, or 0 if they have different dimensions
+// (e.g. were computed with different models).
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(normA*normB)
+}
+
+// memoryStopWords are common words that don't make a memory relevant to a query.
+var memoryStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "are": true, "was": true, "were": true, "not": true,
+	"you": true, "all": true, "any": true, "how": true, "why": true, "what": true, "which": true, "this": true,
+	"that": true, "from": true, "has": true, "have": true, "its": true, "into": true, "can": true, "should": true,
+	"there": true, "their": true, "they": true, "when": true, "where": true, "does": true, "use": true, "used": true,
+}
+
+// memoryWords returns the distinct words of the text that can relate it to another, in lowercase:
+// words of three or more letters, digits, dashes or dots, other than stop words.
+func memoryWords(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '.'
+	}) {
+		word = strings.Trim(word, "-.")
+		if len(word) >= 3 && !memoryStopWords[word] {
+			words[word] = true
+		}
+	}
+	return words
+}
+
+// wordSimilarity returns the cosine similarity of two sets of words.
+func wordSimilarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for word := range a {
+		if b[word] {
+			shared++
+		}
+	}
+	return float64(shared) / math.Sqrt(float64(len(a)*len(b)))
+}
@@ -0,0 +1,161 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// fakeEmbedder embeds a text as the counts of the given words in it.
+type fakeEmbedder struct {
+	words []string
+	err   error
+}
+
+func (e *fakeEmbedder) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	var embeddings [][]float32
+	for _, text := range texts {
+		words := memoryWords(text)
+		embedding := make([]float32, len(e.words))
+		for i, word := range e.words {
+			if words[word] {
+				embedding[i] = 1
+			}
+		}
+		embeddings = append(embeddings, embedding)
+	}
+	return embeddings, nil
+}
+
+func memoryTexts(memories []Memory) []string {
+	var texts []string
+	for _, memory := range memories {
+		texts = append(texts, memory.Text)
+	}
+	return texts
+}
+
+func TestMemoriesRecall(t *testing.T) {
+	facts := []string{
+		"The ingress controller in prod runs in the ingress-system namespace, not ingress-nginx.",
+		"Deployments are named <team>-<service>, e.g. payments-api.",
+		"The payments-api pods crashed in May because the database secret was rotated; restarting them fixed it.",
+	}
+	embedder := &fakeEmbedder{words: []string{"ingress", "payments-api", "secret", "deployments"}}
+
+	tests := []struct {
+		name     string
+		embedder *fakeEmbedder
+		query    string
+		limit    int
+		skip     map[string]bool
+		want     []string
+	}{
+		{
+			name:     "embeddings",
+			embedder: embedder,
+			query:    "why is payments-api crashing?",
+			limit:    5,
+			want:     []string{facts[1], facts[2]},
+		},
+		{
+			name:     "embeddings, limited",
+			embedder: embedder,
+			query:    "the ingress returns 404",
+			limit:    1,
+			want:     []string{facts[0]},
+		},
+		{
+			name:     "embeddings, skipped",
+			embedder: embedder,
+			query:    "why is payments-api crashing?",
+			limit:    5,
+			skip:     map[string]bool{facts[1]: true},
+			want:     []string{facts[2]},
+		},
+		{
+			name:  "words",
+			query: "Which namespace is the ingress controller in?",
+			limit: 5,
+			want:  []string{facts[0]},
+		},
+		{
+			name:     "words, as embedding fails",
+			embedder: &fakeEmbedder{err: errors.New("no embeddings")},
+			query:    "rotate the database secret",
+			limit:    5,
+			want:     []string{facts[2]},
+		},
+		{
+			name:  "nothing relevant",
+			query: "list the nodes",
+			limit: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			memories, err := LoadMemories(filepath.Join(t.TempDir(), "memories.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, fact := range facts {
+				if err := memories.Add(context.Background(), nil, "", fact); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var embedder gollm.Embedder
+			if tt.embedder != nil {
+				embedder = tt.embedder
+			}
+			got := memoryTexts(memories.Recall(context.Background(), embedder, "", tt.query, tt.limit, tt.skip))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Recall(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoriesAdd(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "memories.json")
+	memories, err := LoadMemories(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedder := &fakeEmbedder{words: []string{"ingress"}}
+	for _, fact := range []string{"The ingress is in ingress-system.", "the ingress is in ingress-system.", "  "} {
+		memories.Add(context.Background(), embedder, "m", fact)
+	}
+
+	loaded, err := LoadMemories(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := memoryTexts(loaded.List), []string{"The ingress is in ingress-system."}; !slices.Equal(got, want) {
+		t.Errorf("memories = %q, want %q", got, want)
+	}
+	if got := loaded.List[0]; got.EmbeddingModel != "m" || !slices.Equal(got.Embedding, []float32{1}) {
+		t.Errorf("embedding = %v with %q, want [1] with \"m\"", got.Embedding, got.EmbeddingModel)
+	}
+}
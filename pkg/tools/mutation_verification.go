@@ -0,0 +1,278 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"strings"
+
+	"k8s.io/klog/v2"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// kubectlValueFlags are the kubectl flags that take a value as the next argument.
+// Other flags are assumed to be boolean, unless they are given as --flag=value.
+var kubectlValueFlags = map[string]bool{
+	"-n": true, "--namespace": true,
+	"-f": true, "--filename": true,
+	"-k": true, "--kustomize": true,
+	"-l": true, "--selector": true,
+	"-o": true, "--output": true,
+	"-p": true, "--patch": true,
+	"-c": true, "--container": true,
+	"--context": true, "--kubeconfig": true,
+	"--replicas": true, "--image": true, "--type": true,
+	"--port": true, "--target-port": true, "--name": true,
+	"--timeout": true, "--field-manager": true,
+	"--min": true, "--max": true, "--cpu-percent": true,
+}
+
+// kubectlSubcommandVerbs are the verbs whose first argument is a subcommand rather than a resource,
+// e.g. `kubectl rollout restart deployment/nginx`.
+var kubectlSubcommandVerbs = map[string]bool{
+	"rollout": true, "set": true,
+}
+
+// kubectlRolloutKinds are the kinds that `kubectl rollout status` supports.
+var kubectlRolloutKinds = []string{
+	"deployment", "deployments", "deploy", "deployment.apps",
+	"statefulset", "statefulsets", "sts", "statefulset.apps",
+	"daemonset", "daemonsets", "ds", "daemonset.apps",
+}
+
+// VerificationCommands returns read-only kubectl commands that show whether the changes made by command took effect,
+// e.g. `kubectl get deployment/nginx -n default` and `kubectl rollout status deployment/nginx -n default`.
+// It returns nil if it does not know how to verify the command.
+func VerificationCommands(command string) []string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		klog.V(2).Infof("VerificationCommands: cannot parse command %q: %v", command, err)
+		return nil
+	}
+
+	var commands []string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if call, ok := node.(*syntax.CallExpr); ok {
+			for _, c := range verificationCommandsForCall(callArgs(call)) {
+				if !slices.Contains(commands, c) {
+					commands = append(commands, c)
+				}
+			}
+		}
+		return true
+	})
+	return commands
+}
+
+// kubectlInvocation is a parsed kubectl command line.
+type kubectlInvocation struct {
+	verb       string
+	positional []string
+	// flags are the flags that select the cluster and namespace, which carry over to the commands we run
+	// on the same objects, e.g. "--namespace=default".
+	flags []string
+	// sources are the -f and -k flags, e.g. ["-f", "deployment.yaml"].
+	sources [][]string
+	// stdin is set if the objects are read from stdin (-f -), which we can't read again.
+	stdin    bool
+	selector string
+}
+
+func parseKubectlInvocation(args []string) (*kubectlInvocation, bool) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || !strings.HasSuffix(strings.TrimSuffix(args[0], ".exe"), "kubectl") {
+		return nil, false
+	}
+
+	inv := &kubectlInvocation{}
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			if inv.verb == "" {
+				inv.verb = arg
+			} else {
+				inv.positional = append(inv.positional, arg)
+			}
+			continue
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		if !hasValue && kubectlValueFlags[name] {
+			if i+1 >= len(args) {
+				break
+			}
+			i++
+			value = args[i]
+		}
+		switch name {
+		case "-n", "--namespace", "--context", "--kubeconfig":
+			inv.flags = append(inv.flags, name+"="+value)
+		case "-A", "--all-namespaces":
+			inv.flags = append(inv.flags, "--all-namespaces")
+		case "-f", "--filename":
+			if value == "-" {
+				inv.stdin = true
+			} else {
+				inv.sources = append(inv.sources, []string{"-f", value})
+			}
+		case "-k", "--kustomize":
+			inv.sources = append(inv.sources, []string{"-k", value})
+		case "-l", "--selector":
+			inv.selector = value
+		}
+	}
+	return inv, inv.verb != ""
+}
+
+// mutationTargets returns the objects that a kubectl command line changes, each as the kubectl arguments that select
+// them: a resource (["deployment/nginx"]), the objects of a kind that match a selector (["deployment", "-l", "app=web"]),
+// or the objects of a file or kustomization (["-f", "nginx.yaml"]). ok is false if the command line does not change
+// objects, e.g. a read or a dry run; the targets are empty if we don't know which objects it changes.
+func mutationTargets(args []string) (inv *kubectlInvocation, targets [][]string, ok bool) {
+	inv, ok = parseKubectlInvocation(args)
+	if !ok || !writeOps[inv.verb] || hasDryRunFlag(strings.Join(args, " ")) {
+		return nil, nil, false
+	}
+
+	targets = append(targets, inv.sources...)
+	if inv.stdin || len(inv.sources) != 0 {
+		return inv, targets, true
+	}
+
+	positional := inv.positional
+	if kubectlSubcommandVerbs[inv.verb] && len(positional) > 0 {
+		positional = positional[1:]
+	}
+
+	var kind string
+	var names []string
+	switch inv.verb {
+	case "cordon", "uncordon", "drain":
+		kind, names = "node", positional
+	case "run":
+		kind, names = "pod", positional[:min(1, len(positional))]
+	case "expose":
+		// expose creates a service named after the exposed resource.
+		kind, names = "service", resourceNames(positional)
+		if name := flagValue(args, "--name"); name != "" {
+			names = []string{name}
+		}
+	case "autoscale":
+		kind, names = "horizontalpodautoscaler", resourceNames(positional)
+		if name := flagValue(args, "--name"); name != "" {
+			names = []string{name}
+		}
+	case "create":
+		switch {
+		case len(positional) >= 3 && (positional[0] == "secret" || positional[0] == "service"):
+			// e.g. `kubectl create secret generic <name>`
+			kind, names = positional[0], positional[2:3]
+		case len(positional) >= 2:
+			kind, names = positional[0], positional[1:2]
+		}
+	default:
+		if len(positional) == 0 {
+			return inv, nil, true
+		}
+		if strings.Contains(positional[0], "/") {
+			// kind/name [kind/name ...]
+			for _, ref := range positional {
+				if strings.Contains(ref, "/") && !strings.ContainsAny(ref, "=:") {
+					targets = append(targets, []string{ref})
+				}
+			}
+			return inv, targets, true
+		}
+		kind, names = positional[0], resourceNames(positional[1:])
+	}
+
+	if kind == "" {
+		return inv, nil, true
+	}
+	if len(names) == 0 {
+		if inv.selector == "" {
+			return inv, nil, true
+		}
+		return inv, [][]string{{kind, "-l", inv.selector}}, true
+	}
+	for _, name := range names {
+		targets = append(targets, []string{kind + "/" + name})
+	}
+	return inv, targets, true
+}
+
+func verificationCommandsForCall(args []string) []string {
+	inv, targets, ok := mutationTargets(args)
+	if !ok {
+		return nil
+	}
+
+	flags := ""
+	if len(inv.flags) != 0 {
+		flags = " " + strings.Join(inv.flags, " ")
+	}
+
+	var commands []string
+	for _, target := range targets {
+		quoted := make([]string, len(target))
+		for i, arg := range target {
+			quoted[i] = shellQuote(arg)
+		}
+		commands = append(commands, "kubectl get "+strings.Join(quoted, " ")+flags)
+		if len(target) == 1 && inv.verb != "delete" {
+			kind, _, _ := strings.Cut(target[0], "/")
+			if slices.Contains(kubectlRolloutKinds, strings.ToLower(kind)) {
+				commands = append(commands, "kubectl rollout status "+shellQuote(target[0])+flags+" --timeout=60s")
+			}
+		}
+	}
+	return commands
+}
+
+// resourceNames returns the resource names in the positional arguments,
+// skipping label and annotation changes (key=value, key-) and taints (key=value:effect).
+func resourceNames(positional []string) []string {
+	var names []string
+	for _, arg := range positional {
+		if strings.ContainsAny(arg, "=:") || strings.HasSuffix(arg, "-") {
+			continue
+		}
+		names = append(names, arg)
+	}
+	return names
+}
+
+// flagValue returns the value of the given flag in args, or "" if it is not set.
+func flagValue(args []string, flag string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return value
+		}
+		if arg == flag && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// shellQuote quotes s for bash, if needed.
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestVerificationCommands(t *testing.T) {
+	testCases := []struct {
+		name     string
+		command  string
+		expected []string
+	}{
+		{
+			name:     "read-only command",
+			command:  "kubectl get pods -n default",
+			expected: nil,
+		},
+		{
+			name:     "dry run",
+			command:  "kubectl apply -f deployment.yaml --dry-run=server",
+			expected: nil,
+		},
+		{
+			name:     "apply file",
+			command:  "kubectl apply -f deployment.yaml -n web",
+			expected: []string{"kubectl get -f deployment.yaml -n=web"},
+		},
+		{
+			name:     "apply stdin",
+			command:  "cat deployment.yaml | kubectl apply -f -",
+			expected: nil,
+		},
+		{
+			name:    "scale deployment",
+			command: "kubectl scale deployment nginx --replicas=3 -n web",
+			expected: []string{
+				"kubectl get deployment/nginx -n=web",
+				"kubectl rollout status deployment/nginx -n=web --timeout=60s",
+			},
+		},
+		{
+			name:    "set image",
+			command: "kubectl set image deployment/nginx nginx=registry.k8s.io/nginx:1.27",
+			expected: []string{
+				"kubectl get deployment/nginx",
+				"kubectl rollout status deployment/nginx --timeout=60s",
+			},
+		},
+		{
+			name:     "delete pod",
+			command:  "kubectl delete pod nginx --namespace default",
+			expected: []string{"kubectl get pod/nginx --namespace=default"},
+		},
+		{
+			name:     "label with selector",
+			command:  "kubectl label pods -l app=nginx tier=frontend",
+			expected: []string{"kubectl get pods -l app=nginx"},
+		},
+		{
+			name:     "create secret",
+			command:  "kubectl create secret generic db-password --from-literal=password=hunter2",
+			expected: []string{"kubectl get secret/db-password"},
+		},
+		{
+			name:     "expose",
+			command:  "kubectl expose deployment nginx --port 80 --name web",
+			expected: []string{"kubectl get service/web"},
+		},
+		{
+			name:     "cordon",
+			command:  "kubectl cordon node-1",
+			expected: []string{"kubectl get node/node-1"},
+		},
+		{
+			name:     "taint",
+			command:  "kubectl taint nodes node-1 dedicated=gpu:NoSchedule",
+			expected: []string{"kubectl get nodes/node-1"},
+		},
+		{
+			name:    "multiple commands",
+			command: "kubectl rollout restart deployment/api && kubectl annotate configmap settings owner=team-a",
+			expected: []string{
+				"kubectl get deployment/api",
+				"kubectl rollout status deployment/api --timeout=60s",
+				"kubectl get configmap/settings",
+			},
+		},
+		{
+			name:     "not kubectl",
+			command:  "helm upgrade nginx bitnami/nginx",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := VerificationCommands(tc.command)
+			if !slices.Equal(got, tc.expected) {
+				t.Errorf("VerificationCommands(%q) = %q, want %q", tc.command, got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+type offlineKey struct{}
+
+// WithOffline returns a context in which tools don't run commands that reach the network outside the cluster.
+func WithOffline(ctx context.Context) context.Context {
+	return context.WithValue(ctx, offlineKey{}, true)
+}
+
+// offlineFromContext returns true if tools must not reach the network outside the cluster.
+func offlineFromContext(ctx context.Context) bool {
+	offline, _ := ctx.Value(offlineKey{}).(bool)
+	return offline
+}
+
+// checkOffline returns the result of a command that is not run because of offline mode, or nil if it may run.
+func checkOffline(ctx context.Context, command string) *ExecResult {
+	if !offlineFromContext(ctx) {
+		return nil
+	}
+	if reason := offlineViolation(command); reason != "" {
+		return &ExecResult{
+			Command: command,
+			Error:   fmt.Sprintf("offline mode is on, so commands may not reach the network outside the cluster: %s", reason),
+		}
+	}
+	return nil
+}
+
+// networkPrograms are programs whose purpose is to reach other hosts.
+var networkPrograms = map[string]bool{
+	"curl": true, "wget": true, "nc": true, "ncat": true, "netcat": true, "telnet": true, "ssh": true, "scp": true,
+	"sftp": true, "rsync": true, "ftp": true, "git": true, "pip": true, "pip3": true, "npm": true, "apt": true,
+	"apt-get": true, "yum": true, "dnf": true, "apk": true, "brew": true, "gcloud": true, "aws": true, "az": true,
+	"dig": true, "nslookup": true, "socat": true,
+}
+
+// networkSubcommands are the subcommands of programs that reach other hosts, e.g. to download charts or plugins.
+var networkSubcommands = map[string]map[string]bool{
+	"helm":    {"repo": true, "pull": true, "search": true, "plugin": true, "push": true, "registry": true},
+	"kubectl": {"krew": true},
+	"docker":  {"pull": true, "push": true, "login": true, "search": true},
+}
+
+// offlineViolation returns why the command would reach the network outside the cluster, or "" if it would not.
+// Commands that cannot be parsed are not allowed, as we cannot tell what they do.
+func offlineViolation(command string) string {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return "the command could not be parsed"
+	}
+
+	var reason string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if reason != "" {
+			return false
+		}
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		args := callArgs(call)
+		if len(args) == 0 {
+			reason = "the command could not be parsed"
+			return false
+		}
+		program := path.Base(args[0])
+		if networkPrograms[program] {
+			reason = fmt.Sprintf("%s reaches other hosts", program)
+			return false
+		}
+		for _, arg := range args[1:] {
+			if strings.HasPrefix(arg, "-") {
+				continue
+			}
+			if networkSubcommands[program][arg] {
+				reason = fmt.Sprintf("%s %s reaches other hosts", program, arg)
+				return false
+			}
+			break
+		}
+		for _, arg := range args[1:] {
+			// e.g. kubectl apply -f https://example.com/manifest.yaml, or --filename=https://...
+			name, value, _ := strings.Cut(arg, "=")
+			if name == "--server" {
+				// The API server of the cluster.
+				continue
+			}
+			for _, s := range []string{arg, value} {
+				if u, err := url.Parse(s); err == nil && u.Host != "" && !IsLocalHost(u.Hostname()) {
+					reason = fmt.Sprintf("%s is outside the cluster", u.Host)
+					return false
+				}
+			}
+		}
+		return true
+	})
+	return reason
+}
+
+// IsLocalHost returns true if host is this machine, or on a private network: a loopback, private or link-local
+// address, or a name that is not fully qualified or is under a suffix used for local or cluster names.
+func IsLocalHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified()
+	}
+	if host == "" {
+		return false
+	}
+	if host == "localhost" || !strings.Contains(host, ".") {
+		return true
+	}
+	for _, suffix := range []string{".localhost", ".local", ".svc", ".cluster.local", ".internal", ".lan", ".home.arpa"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
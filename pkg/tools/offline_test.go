@@ -0,0 +1,70 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import "testing"
+
+func TestOfflineViolation(t *testing.T) {
+	tests := []struct {
+		command string
+		allowed bool
+	}{
+		{command: "kubectl get pods -A", allowed: true},
+		{command: "kubectl get pods -o json | jq '.items[].metadata.name'", allowed: true},
+		{command: "kubectl apply -f ./manifest.yaml", allowed: true},
+		{command: "kubectl apply -f http://charts.svc.cluster.local/app.yaml", allowed: true},
+		{command: "kubectl --server=https://api.prod.example.com get nodes", allowed: true},
+		{command: "helm list -A", allowed: true},
+		{command: "kubectl apply -f https://raw.githubusercontent.com/org/repo/main/app.yaml", allowed: false},
+		{command: "kubectl apply --filename=https://example.com/app.yaml", allowed: false},
+		{command: "curl -s http://localhost:8080/healthz", allowed: false},
+		{command: "kubectl get pods && wget -q -O- http://example.com", allowed: false},
+		{command: "helm repo update", allowed: false},
+		{command: "kubectl krew install neat", allowed: false},
+		{command: "echo $(curl example.com)", allowed: false},
+	}
+
+	for _, tt := range tests {
+		reason := offlineViolation(tt.command)
+		if (reason == "") != tt.allowed {
+			t.Errorf("offlineViolation(%q) = %q, want allowed %v", tt.command, reason, tt.allowed)
+		}
+	}
+}
+
+func TestIsLocalHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{host: "localhost", want: true},
+		{host: "127.0.0.1", want: true},
+		{host: "::1", want: true},
+		{host: "10.4.0.12", want: true},
+		{host: "192.168.1.20", want: true},
+		{host: "vllm", want: true},
+		{host: "vllm.ai.svc.cluster.local", want: true},
+		{host: "ollama.internal", want: true},
+		{host: "8.8.8.8", want: false},
+		{host: "api.openai.com", want: false},
+		{host: "", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsLocalHost(tt.host); got != tt.want {
+			t.Errorf("IsLocalHost(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// errorLinePattern matches the lines of command output that report errors or unhealthy resources,
+// which must survive when the output is summarized.
+var errorLinePattern = regexp.MustCompile(`(?i)\b(error|errors|failed|failure|fatal|panic|denied|forbidden|refused|timeout|timed out|unhealthy|evicted|backoff|oomkilled|crashloopbackoff|imagepullbackoff|errimagepull|warning)\b`)
+
+// ErrorLines returns the lines of output that report errors, warnings or unhealthy resources (e.g. CrashLoopBackOff),
+// trimmed of surrounding space and without duplicates, in order. It returns at most max lines; zero means no limit.
+func ErrorLines(output string, max int) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || seen[line] || !errorLinePattern.MatchString(line) {
+			continue
+		}
+		seen[line] = true
+		lines = append(lines, line)
+		if max > 0 && len(lines) == max {
+			break
+		}
+	}
+	return lines
+}
+
+// ElideMiddle shortens s to about max bytes by replacing its middle with a note of how much was left out,
+// cutting at line boundaries where possible. It returns s unchanged if it is not longer than max.
+func ElideMiddle(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	head := s[:max/2]
+	if i := strings.LastIndex(head, "\n"); i > 0 {
+		head = head[:i+1]
+	}
+	tail := s[len(s)-max/2:]
+	if i := strings.Index(tail, "\n"); i >= 0 && i < len(tail)-1 {
+		tail = tail[i+1:]
+	}
+	return fmt.Sprintf("%s... (%d bytes left out) ...\n%s", head, len(s)-len(head)-len(tail), tail)
+}
@@ -0,0 +1,79 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestErrorLines(t *testing.T) {
+	output := `NAME    READY   STATUS             RESTARTS
+web-1   1/1     Running            0
+web-2   0/1     CrashLoopBackOff   12
+web-2   0/1     CrashLoopBackOff   12
+  Warning  BackOff  3m  kubelet  Back-off restarting failed container
+db-0    0/1     ImagePullBackOff   0
+Events: <none>
+`
+	tests := []struct {
+		name     string
+		max      int
+		expected []string
+	}{
+		{
+			name: "all",
+			expected: []string{
+				"web-2   0/1     CrashLoopBackOff   12",
+				"Warning  BackOff  3m  kubelet  Back-off restarting failed container",
+				"db-0    0/1     ImagePullBackOff   0",
+			},
+		},
+		{
+			name:     "limited",
+			max:      1,
+			expected: []string{"web-2   0/1     CrashLoopBackOff   12"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorLines(output, tt.max)
+			if !slices.Equal(got, tt.expected) {
+				t.Errorf("ErrorLines() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestElideMiddle(t *testing.T) {
+	short := "line 1\nline 2\n"
+	if got := ElideMiddle(short, 100); got != short {
+		t.Errorf("ElideMiddle() of a short string = %q, expected it unchanged", got)
+	}
+
+	var b strings.Builder
+	for i := 0; i < 100; i++ {
+		b.WriteString("0123456789\n")
+	}
+	long := b.String()
+	got := ElideMiddle(long, 100)
+	if !strings.HasPrefix(got, "0123456789\n") || !strings.HasSuffix(got, "0123456789\n") {
+		t.Errorf("ElideMiddle() = %q, expected it to start and end with whole lines", got)
+	}
+	if !strings.Contains(got, "bytes left out") || len(got) > 150 {
+		t.Errorf("ElideMiddle() = %q, expected about 100 bytes with a note of what was left out", got)
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// A pack is a set of prompts and recipes, published as an OCI artifact (e.g. with oras push) and installed
+// with kubectl-ai pack install. The artifact holds a prompts/ directory, whose files are added to the system prompt,
+// and/or a recipes/ directory, which is loaded like --recipes-config.
+const (
+	packPromptsDir = "prompts"
+	packRecipesDir = "recipes"
+)
+
+// packsIndexFile is the file in the packs directory that records the installed packs.
+const packsIndexFile = "packs.json"
+
+// pullTimeout bounds how long we wait for oras and cosign to reach the registry.
+const pullTimeout = 5 * time.Minute
+
+// PackRef is a reference to a version of a pack in an OCI registry: oci://<repository>:<tag> or
+// oci://<repository>@<digest>.
+type PackRef struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+var digestRegexp = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// ParsePackRef parses a reference to a pack. The version must be pinned, with a tag other than latest or a digest.
+func ParsePackRef(s string) (PackRef, error) {
+	rest, ok := strings.CutPrefix(s, "oci://")
+	if !ok {
+		return PackRef{}, fmt.Errorf("pack reference %q must start with oci://", s)
+	}
+	var ref PackRef
+	if repository, digest, ok := strings.Cut(rest, "@"); ok {
+		if !digestRegexp.MatchString(digest) {
+			return PackRef{}, fmt.Errorf("pack reference %q has an invalid digest, expected sha256:<64 hex digits>", s)
+		}
+		ref.Repository, ref.Digest = repository, digest
+	} else if i := strings.LastIndex(rest, ":"); i > strings.LastIndex(rest, "/") {
+		ref.Repository, ref.Tag = rest[:i], rest[i+1:]
+	} else {
+		ref.Repository = rest
+	}
+	if ref.Repository == "" || !strings.Contains(ref.Repository, "/") {
+		return PackRef{}, fmt.Errorf("pack reference %q must include a registry and repository, e.g. oci://ghcr.io/org/pack:v1", s)
+	}
+	if ref.Digest == "" && (ref.Tag == "" || ref.Tag == "latest") {
+		return PackRef{}, fmt.Errorf("pack reference %q must pin a version, with a tag (other than latest) or a digest", s)
+	}
+	return ref, nil
+}
+
+// Name is the name the pack is installed under: the last part of its repository.
+func (r PackRef) Name() string {
+	return path.Base(r.Repository)
+}
+
+// Pinned is the reference to the exact version of the pack, by digest.
+func (r PackRef) Pinned(digest string) string {
+	return r.Repository + "@" + digest
+}
+
+func (r PackRef) String() string {
+	if r.Digest != "" {
+		return "oci://" + r.Repository + "@" + r.Digest
+	}
+	return "oci://" + r.Repository + ":" + r.Tag
+}
+
+// Pack is an installed pack.
+type Pack struct {
+	Name string `json:"name"`
+	// Ref is the reference the pack was installed from.
+	Ref string `json:"ref"`
+	// Digest is the digest of the installed version; it does not change until the pack is installed again.
+	Digest    string    `json:"digest"`
+	Verified  bool      `json:"verified"`
+	Installed time.Time `json:"installed"`
+}
+
+// Packs are the packs installed in a directory, each in a subdirectory named after the pack.
+type Packs struct {
+	dir  string
+	List []*Pack `json:"packs"`
+}
+
+// LoadPacks loads the index of the packs installed in dir; there are none if dir does not exist.
+func LoadPacks(dir string) (*Packs, error) {
+	packs := &Packs{dir: dir}
+	b, err := os.ReadFile(filepath.Join(dir, packsIndexFile))
+	if os.IsNotExist(err) {
+		return packs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading installed packs: %w", err)
+	}
+	if err := json.Unmarshal(b, packs); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", filepath.Join(dir, packsIndexFile), err)
+	}
+	return packs, nil
+}
+
+// Save writes the index of the installed packs.
+func (p *Packs) Save() error {
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return fmt.Errorf("creating packs directory: %w", err)
+	}
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(p.dir, packsIndexFile), b, 0o644); err != nil {
+		return fmt.Errorf("writing installed packs: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the installed pack with the given name, or nil.
+func (p *Packs) Lookup(name string) *Pack {
+	for _, pack := range p.List {
+		if pack.Name == name {
+			return pack
+		}
+	}
+	return nil
+}
+
+// Install pulls the pack with oras, verifies its signature with cosign according to the artifact policy,
+// and installs it, replacing any installed version of the same pack.
+func (p *Packs) Install(ctx context.Context, ref PackRef) (*Pack, error) {
+	ctx, cancel := context.WithTimeout(ctx, pullTimeout)
+	defer cancel()
+
+	digest := ref.Digest
+	if digest == "" {
+		output, err := runTool(ctx, "oras", "resolve", ref.Repository+":"+ref.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("resolving %s: %w", ref, err)
+		}
+		digest = strings.TrimSpace(output)
+		if !digestRegexp.MatchString(digest) {
+			return nil, fmt.Errorf("resolving %s: unexpected digest %q", ref, digest)
+		}
+	}
+	pinned := ref.Pinned(digest)
+
+	verified, err := verifyPack(ctx, pinned)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(p.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating packs directory: %w", err)
+	}
+	pullDir, err := os.MkdirTemp(p.dir, ".pull-*")
+	if err != nil {
+		return nil, fmt.Errorf("creating temporary directory: %w", err)
+	}
+	defer os.RemoveAll(pullDir)
+	if _, err := runTool(ctx, "oras", "pull", "--output", pullDir, pinned); err != nil {
+		return nil, fmt.Errorf("pulling %s: %w", pinned, err)
+	}
+	if !isDir(filepath.Join(pullDir, packPromptsDir)) && !isDir(filepath.Join(pullDir, packRecipesDir)) {
+		return nil, fmt.Errorf("%s is not a pack: it has neither a %s/ nor a %s/ directory", ref, packPromptsDir, packRecipesDir)
+	}
+
+	pack := &Pack{
+		Name:      ref.Name(),
+		Ref:       ref.String(),
+		Digest:    digest,
+		Verified:  verified,
+		Installed: time.Now(),
+	}
+	packDir := p.Dir(pack)
+	if err := os.RemoveAll(packDir); err != nil {
+		return nil, fmt.Errorf("removing the installed version of %s: %w", pack.Name, err)
+	}
+	if err := os.Rename(pullDir, packDir); err != nil {
+		return nil, fmt.Errorf("installing %s: %w", pack.Name, err)
+	}
+
+	p.List = slices.DeleteFunc(p.List, func(installed *Pack) bool { return installed.Name == pack.Name })
+	p.List = append(p.List, pack)
+	sort.Slice(p.List, func(i, j int) bool { return p.List[i].Name < p.List[j].Name })
+	return pack, p.Save()
+}
+
+// Remove uninstalls the pack with the given name.
+func (p *Packs) Remove(name string) error {
+	pack := p.Lookup(name)
+	if pack == nil {
+		return fmt.Errorf("pack %q is not installed", name)
+	}
+	if err := os.RemoveAll(p.Dir(pack)); err != nil {
+		return fmt.Errorf("removing pack %q: %w", name, err)
+	}
+	p.List = slices.DeleteFunc(p.List, func(installed *Pack) bool { return installed == pack })
+	return p.Save()
+}
+
+// Dir is the directory the pack is installed in.
+func (p *Packs) Dir(pack *Pack) string {
+	return filepath.Join(p.dir, pack.Name)
+}
+
+// PromptPaths returns the prompt files of the installed packs, in the order they are added to the system prompt.
+func (p *Packs) PromptPaths() ([]string, error) {
+	var paths []string
+	for _, pack := range p.List {
+		dir := filepath.Join(p.Dir(pack), packPromptsDir)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("reading prompts of pack %q: %w", pack.Name, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || isSignatureFile(entry.Name()) {
+				continue
+			}
+			paths = append(paths, filepath.Join(dir, entry.Name()))
+		}
+	}
+	return paths, nil
+}
+
+// RecipesPaths returns the recipes directories of the installed packs.
+func (p *Packs) RecipesPaths() []string {
+	var paths []string
+	for _, pack := range p.List {
+		if dir := filepath.Join(p.Dir(pack), packRecipesDir); isDir(dir) {
+			paths = append(paths, dir)
+		}
+	}
+	return paths
+}
+
+// verifyPack checks that the pack is signed by one of the trusted signers of the artifact policy,
+// and returns whether it was verified. Without trusted signers, packs are installed without verification,
+// unless signatures are required.
+func verifyPack(ctx context.Context, pinned string) (bool, error) {
+	if len(artifactPolicy.TrustedSigners) == 0 {
+		klog.Warningf("Installing %s without verifying its signature, as there are no trusted signers", pinned)
+		return false, nil
+	}
+	var errs []error
+	for _, signer := range artifactPolicy.TrustedSigners {
+		_, err := runTool(ctx, "cosign", signer.cosignImageArgs(pinned)...)
+		if err == nil {
+			return true, nil
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return false, fmt.Errorf("verifying the signature of %s: %w", pinned, err)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", signer, err))
+	}
+	return false, fmt.Errorf("the signature of %s is not from a trusted signer: %w", pinned, errors.Join(errs...))
+}
+
+// runTool runs a program, and returns its standard output, or an error with its standard error.
+func runTool(ctx context.Context, name string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", fmt.Errorf("%s is not installed: %w", name, err)
+		}
+		return "", fmt.Errorf("%s %s: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+func isDir(p string) bool {
+	info, err := os.Stat(p)
+	return err == nil && info.IsDir()
+}
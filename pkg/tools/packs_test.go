@@ -0,0 +1,84 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParsePackRef(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		ref     string
+		want    PackRef
+		wantErr bool
+	}{
+		{ref: "oci://ghcr.io/org/sre-pack:v1.2.0", want: PackRef{Repository: "ghcr.io/org/sre-pack", Tag: "v1.2.0"}},
+		{ref: "oci://localhost:5000/sre-pack:v1", want: PackRef{Repository: "localhost:5000/sre-pack", Tag: "v1"}},
+		{ref: "oci://ghcr.io/org/sre-pack@" + digest, want: PackRef{Repository: "ghcr.io/org/sre-pack", Digest: digest}},
+		{ref: "oci://localhost:5000/sre-pack", wantErr: true},
+		{ref: "oci://ghcr.io/org/sre-pack:latest", wantErr: true},
+		{ref: "oci://ghcr.io/org/sre-pack@sha256:1234", wantErr: true},
+		{ref: "oci://sre-pack:v1", wantErr: true},
+		{ref: "ghcr.io/org/sre-pack:v1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := ParsePackRef(tt.ref)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePackRef(%q) error = %v, wantErr %v", tt.ref, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got != tt.want {
+				t.Errorf("ParsePackRef(%q) = %+v, want %+v", tt.ref, got, tt.want)
+			}
+			if got.String() != tt.ref {
+				t.Errorf("String() = %q, want %q", got.String(), tt.ref)
+			}
+			if got.Name() != "sre-pack" {
+				t.Errorf("Name() = %q, want %q", got.Name(), "sre-pack")
+			}
+		})
+	}
+}
+
+func TestCosignImageArgs(t *testing.T) {
+	const ref = "ghcr.io/org/sre-pack@sha256:0123"
+	tests := []struct {
+		signer TrustedSigner
+		want   []string
+	}{
+		{
+			signer: TrustedSigner{Key: "cosign.pub"},
+			want:   []string{"verify", "--key", "cosign.pub", ref},
+		},
+		{
+			signer: TrustedSigner{Identity: "ops@example.com", Issuer: "https://accounts.google.com"},
+			want:   []string{"verify", "--certificate-identity", "ops@example.com", "--certificate-oidc-issuer", "https://accounts.google.com", ref},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.signer.String(), func(t *testing.T) {
+			if got := tt.signer.cosignImageArgs(ref); !slices.Equal(got, tt.want) {
+				t.Errorf("cosignImageArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+)
+
+func init() {
+	RegisterTool(&PipelineTool{})
+}
+
+const (
+	// maxPipelineSteps is the maximum number of steps in a pipeline.
+	maxPipelineSteps = 10
+	// maxPipelineItems is the maximum number of lines a for_each step runs for.
+	maxPipelineItems = 25
+)
+
+// PipelineTool runs a short pipeline of read-only tool calls in one go, piping the output of each step
+// into the next, so the model can gather information in a single round-trip instead of one per call.
+type PipelineTool struct{}
+
+func (t *PipelineTool) Name() string {
+	return "compose_pipeline"
+}
+
+func (t *PipelineTool) Description() string {
+	return `Runs a short pipeline of read-only tool calls in one go, instead of calling the tools one at a time; for example: list pods, keep the failing ones, describe each of them.
+Each step either runs a tool, or filters the lines of the previous step's output with a regular expression.
+A step with for_each runs once for each line of the previous step's output; in its command or arguments, {{item}} is replaced by the line, and {{item.N}} by its N-th whitespace-separated field (starting at 1).
+Use --no-headers or -o name in kubectl commands whose output is used by a for_each step.
+Only tools that do not modify resources can be used. The result is the output of the last step.`
+}
+
+func (t *PipelineTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"steps": {
+					Type:        gollm.TypeArray,
+					Description: fmt.Sprintf(`The steps of the pipeline, in order; at most %d.`, maxPipelineSteps),
+					Items: &gollm.Schema{
+						Type: gollm.TypeObject,
+						Properties: map[string]*gollm.Schema{
+							"tool": {
+								Type:        gollm.TypeString,
+								Description: `The tool to run, e.g. "kubectl" (the default). Leave empty for a filter step.`,
+							},
+							"command": {
+								Type:        gollm.TypeString,
+								Description: `The command, for tools that take a command such as kubectl, e.g. "kubectl describe pod {{item.1}} -n web".`,
+							},
+							"arguments": {
+								Type:        gollm.TypeString,
+								Description: `The arguments of other tools, as a JSON object, e.g. {"resource": "deployments"}.`,
+							},
+							"filter": {
+								Type:        gollm.TypeString,
+								Description: `For a filter step: a regular expression; only the lines of the previous step's output that match are kept.`,
+							},
+							"for_each": {
+								Type:        gollm.TypeBoolean,
+								Description: fmt.Sprintf(`Run the tool once for each line of the previous step's output (at most %d lines).`, maxPipelineItems),
+							},
+						},
+					},
+				},
+			},
+			Required: []string{"steps"},
+		},
+	}
+}
+
+// pipelineStep is a step of a pipeline, as requested by the model.
+type pipelineStep struct {
+	Tool      string `json:"tool,omitempty"`
+	Command   string `json:"command,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	Filter    string `json:"filter,omitempty"`
+	ForEach   bool   `json:"for_each,omitempty"`
+}
+
+// PipelineResult is the result of the compose_pipeline tool.
+type PipelineResult struct {
+	// Output is the output of the last step.
+	Output string `json:"output"`
+	// Steps describes what each step did.
+	Steps []string `json:"steps,omitempty"`
+	Error string   `json:"error,omitempty"`
+}
+
+var _ ui.CanFormatAsText = &PipelineResult{}
+
+func (r *PipelineResult) FormatAsText() string {
+	if r.Error != "" {
+		return strings.TrimSpace(r.Output + "\nerror: " + r.Error)
+	}
+	return r.Output
+}
+
+func (t *PipelineTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*PipelineResult]()
+}
+
+func (t *PipelineTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	steps, err := parsePipelineSteps(args)
+	if err != nil {
+		return &PipelineResult{Error: err.Error()}, nil
+	}
+
+	result := &PipelineResult{}
+	input := ""
+	for i, step := range steps {
+		if step.Filter != "" {
+			re, err := regexp.Compile(step.Filter)
+			if err != nil {
+				result.Error = fmt.Sprintf("step %d: invalid filter: %v", i+1, err)
+				return result, nil
+			}
+			input = filterLines(input, re)
+			result.Steps = append(result.Steps, fmt.Sprintf("filter %q: %d line(s) kept", step.Filter, len(outputLines(input))))
+			continue
+		}
+
+		items := []string{""}
+		if step.ForEach {
+			items = outputLines(input)
+			if len(items) > maxPipelineItems {
+				result.Error = fmt.Sprintf("step %d: the previous step returned %d lines, a for_each step runs for at most %d; filter the output first", i+1, len(items), maxPipelineItems)
+				return result, nil
+			}
+		}
+
+		var output strings.Builder
+		for j, item := range items {
+			ReportProgress(ctx, Progress{
+				Message: fmt.Sprintf("Step %d of %d", i+1, len(steps)),
+				Current: float64(i) + float64(j)/float64(len(items)),
+				Total:   float64(len(steps)),
+			})
+			description, text, err := runPipelineStep(ctx, step, item)
+			if err != nil {
+				result.Output = input
+				result.Error = fmt.Sprintf("step %d: %v", i+1, err)
+				return result, nil
+			}
+			result.Steps = append(result.Steps, description)
+			if step.ForEach {
+				fmt.Fprintf(&output, "## %s\n", item)
+			}
+			output.WriteString(text)
+			if text != "" && !strings.HasSuffix(text, "\n") {
+				output.WriteString("\n")
+			}
+		}
+		input = output.String()
+	}
+	result.Output = input
+	return result, nil
+}
+
+// parsePipelineSteps reads the steps from the arguments of the tool.
+func parsePipelineSteps(args map[string]any) ([]pipelineStep, error) {
+	b, err := json.Marshal(args["steps"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid steps: %w", err)
+	}
+	var steps []pipelineStep
+	if err := json.Unmarshal(b, &steps); err != nil {
+		return nil, fmt.Errorf("invalid steps: %w", err)
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("the pipeline has no steps")
+	}
+	if len(steps) > maxPipelineSteps {
+		return nil, fmt.Errorf("the pipeline has %d steps, at most %d are allowed", len(steps), maxPipelineSteps)
+	}
+	for i, step := range steps {
+		if step.Filter != "" && (step.Tool != "" || step.Command != "" || step.Arguments != "") {
+			return nil, fmt.Errorf("step %d: a step either runs a tool or filters, not both", i+1)
+		}
+		if step.Tool == (&PipelineTool{}).Name() {
+			return nil, fmt.Errorf("step %d: pipelines cannot be nested", i+1)
+		}
+	}
+	return steps, nil
+}
+
+// pipelineCall returns the tool and arguments of a step, applying expand to the command and the string arguments.
+func pipelineCall(step pipelineStep, expand func(string) string) (Tool, map[string]any, error) {
+	name := step.Tool
+	if name == "" {
+		name = "kubectl"
+	}
+	tool := Lookup(name)
+	if tool == nil {
+		return nil, nil, fmt.Errorf("tool %q not recognized", name)
+	}
+
+	toolArgs := make(map[string]any)
+	if step.Arguments != "" {
+		if err := json.Unmarshal([]byte(step.Arguments), &toolArgs); err != nil {
+			return nil, nil, fmt.Errorf("arguments must be a JSON object: %w", err)
+		}
+		for key, value := range toolArgs {
+			if s, ok := value.(string); ok {
+				toolArgs[key] = expand(s)
+			}
+		}
+	}
+	if step.Command != "" {
+		toolArgs["command"] = expand(step.Command)
+	}
+	return tool, toolArgs, nil
+}
+
+// runPipelineStep runs the tool of a step, with {{item}} placeholders replaced by item.
+// It returns a description of the call, and its output as text.
+func runPipelineStep(ctx context.Context, step pipelineStep, item string) (string, string, error) {
+	tool, toolArgs, err := pipelineCall(step, func(s string) string { return substituteItem(s, item) })
+	if err != nil {
+		return "", "", err
+	}
+
+	description := tool.Name()
+	if command, ok := toolArgs["command"].(string); ok {
+		description = command
+	} else if len(toolArgs) != 0 {
+		b, _ := json.Marshal(toolArgs)
+		description = fmt.Sprintf("%s %s", tool.Name(), b)
+	}
+
+	// Pipelines run without asking the user, so we only allow calls that we know don't change anything.
+	if modifies := tool.CheckModifiesResource(toolArgs); modifies != "no" {
+		return description, "", fmt.Errorf("%q might modify resources; pipelines can only run read-only tool calls", description)
+	}
+	if interactive, _ := tool.IsInteractive(toolArgs); interactive {
+		return description, "", fmt.Errorf("%q is interactive, which is not supported in pipelines", description)
+	}
+
+	output, err := tool.Run(ctx, toolArgs)
+	if err != nil {
+		return description, "", err
+	}
+	return description, pipelineOutputText(output), nil
+}
+
+// pipelineOutputText converts the result of a tool to text, for the next step.
+func pipelineOutputText(output any) string {
+	switch output := output.(type) {
+	case nil:
+		return ""
+	case *ExecResult:
+		// The next step works on the output; errors are kept so the model can see them.
+		text := output.Stdout
+		if output.Error != "" || output.ExitCode != 0 {
+			text = output.FormatAsText()
+		}
+		return text
+	case ui.CanFormatAsText:
+		return output.FormatAsText()
+	case string:
+		return output
+	default:
+		b, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Sprintf("%v", output)
+		}
+		return string(b)
+	}
+}
+
+// outputLines splits the output of a step into its non-empty lines.
+func outputLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// filterLines keeps the lines of text that match re.
+func filterLines(text string, re *regexp.Regexp) string {
+	var kept strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" && re.MatchString(line) {
+			kept.WriteString(line + "\n")
+		}
+	}
+	return kept.String()
+}
+
+var itemPlaceholder = regexp.MustCompile(`\{\{\s*item(?:\.(\d+))?\s*\}\}`)
+
+// substituteItem replaces {{item}} in s with the line, and {{item.N}} with the N-th whitespace-separated field of the line.
+// Placeholders for fields that the line does not have are replaced with nothing.
+func substituteItem(s, line string) string {
+	fields := strings.Fields(line)
+	return itemPlaceholder.ReplaceAllStringFunc(s, func(placeholder string) string {
+		match := itemPlaceholder.FindStringSubmatch(placeholder)
+		if match[1] == "" {
+			return line
+		}
+		n, _ := strconv.Atoi(match[1])
+		if n < 1 || n > len(fields) {
+			return ""
+		}
+		return fields[n-1]
+	})
+}
+
+func (t *PipelineTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *PipelineTool) CheckModifiesResource(args map[string]any) string {
+	steps, err := parsePipelineSteps(args)
+	if err != nil {
+		return "unknown"
+	}
+	// Each call is checked again when it runs, with the placeholders replaced.
+	for _, step := range steps {
+		if step.Filter != "" {
+			continue
+		}
+		tool, toolArgs, err := pipelineCall(step, func(s string) string { return s })
+		if err != nil {
+			return "unknown"
+		}
+		if modifies := tool.CheckModifiesResource(toolArgs); modifies != "no" {
+			return modifies
+		}
+	}
+	return "no"
+}
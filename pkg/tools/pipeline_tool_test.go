@@ -0,0 +1,115 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+func TestSubstituteItem(t *testing.T) {
+	testCases := []struct {
+		name     string
+		template string
+		line     string
+		expected string
+	}{
+		{
+			name:     "whole line",
+			template: "kubectl describe {{item}}",
+			line:     "pod/web-1",
+			expected: "kubectl describe pod/web-1",
+		},
+		{
+			name:     "fields",
+			template: "kubectl describe pod {{item.2}} -n {{ item.1 }}",
+			line:     "web   web-1   0/1   CrashLoopBackOff",
+			expected: "kubectl describe pod web-1 -n web",
+		},
+		{
+			name:     "missing field",
+			template: "kubectl logs {{item.5}}",
+			line:     "web-1 Running",
+			expected: "kubectl logs ",
+		},
+		{
+			name:     "no placeholder",
+			template: "kubectl get nodes",
+			line:     "web-1",
+			expected: "kubectl get nodes",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := substituteItem(tc.template, tc.line)
+			if got != tc.expected {
+				t.Errorf("substituteItem(%q, %q) = %q, want %q", tc.template, tc.line, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestFilterLines(t *testing.T) {
+	output := "web-1   0/1   CrashLoopBackOff\nweb-2   1/1   Running\n\nweb-3   0/1   Error\n"
+	got := outputLines(filterLines(output, regexp.MustCompile(`CrashLoopBackOff|Error`)))
+	expected := []string{"web-1   0/1   CrashLoopBackOff", "web-3   0/1   Error"}
+	if !slices.Equal(got, expected) {
+		t.Errorf("filterLines() = %q, want %q", got, expected)
+	}
+}
+
+func TestPipelineCheckModifiesResource(t *testing.T) {
+	testCases := []struct {
+		name     string
+		steps    []any
+		expected string
+	}{
+		{
+			name: "read-only",
+			steps: []any{
+				map[string]any{"command": "kubectl get pods -n web --no-headers"},
+				map[string]any{"filter": "CrashLoopBackOff"},
+				map[string]any{"command": "kubectl describe pod {{item.1}} -n web", "for_each": true},
+			},
+			expected: "no",
+		},
+		{
+			name: "mutating step",
+			steps: []any{
+				map[string]any{"command": "kubectl get pods -o name"},
+				map[string]any{"command": "kubectl delete {{item}}", "for_each": true},
+			},
+			expected: "yes",
+		},
+		{
+			name: "nested pipeline",
+			steps: []any{
+				map[string]any{"tool": "compose_pipeline"},
+			},
+			expected: "unknown",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := (&PipelineTool{}).CheckModifiesResource(map[string]any{"steps": tc.steps})
+			if got != tc.expected {
+				t.Errorf("CheckModifiesResource() = %q, want %q", got, tc.expected)
+			}
+		})
+	}
+}
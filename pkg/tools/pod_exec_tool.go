@@ -0,0 +1,158 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&PodExecTool{})
+}
+
+const (
+	// defaultExecTimeout is how long pod_exec lets the command run if no timeout is given, and maxExecTimeout the longest.
+	defaultExecTimeout = 30 * time.Second
+	maxExecTimeout     = 5 * time.Minute
+)
+
+// PodExecTool runs a command in a container of a pod with kubectl exec, without a terminal or input,
+// and returns its output. The command can change the container, so calls are confirmed like changes.
+type PodExecTool struct{}
+
+func (t *PodExecTool) Name() string {
+	return "pod_exec"
+}
+
+func (t *PodExecTool) Description() string {
+	return `Runs a command inside a container of a pod (kubectl exec, without a terminal or input) and returns its output
+and exit code, e.g. to check the files, processes, environment or DNS resolution seen by a workload. The command is
+run with /bin/sh -c, unless no_shell is set for images without a shell. It is stopped after the timeout.
+The user is asked to confirm the call, as the command can change the container: prefer read-only commands.`
+}
+
+func (t *PodExecTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"pod": {
+					Type:        gollm.TypeString,
+					Description: `The name of the pod, or a workload as kind/name (e.g. "deployment/web") to run the command in one of its pods.`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the pod. Defaults to the namespace of the current context.`,
+				},
+				"container": {
+					Type:        gollm.TypeString,
+					Description: `The container to run the command in. Defaults to the default container of the pod.`,
+				},
+				"shell_command": {
+					Type:        gollm.TypeString,
+					Description: `The command to run, e.g. "cat /etc/resolv.conf" or "ps aux | head".`,
+				},
+				"no_shell": {
+					Type:        gollm.TypeBoolean,
+					Description: `Run the command directly, split into words, instead of with /bin/sh -c; for images without a shell. Pipes, quotes and variables are then not interpreted.`,
+				},
+				"timeout": {
+					Type:        gollm.TypeString,
+					Description: `How long the command may run, as a duration such as "10s". Defaults to 30s; at most 5m.`,
+				},
+			},
+			Required: []string{"pod", "shell_command"},
+		},
+	}
+}
+
+func (t *PodExecTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ExecResult]()
+}
+
+// execArgs returns the arguments of the kubectl exec command for a pod_exec call, and how long it may run.
+func execArgs(args map[string]any) ([]string, time.Duration, error) {
+	pod := stringArg(args, "pod")
+	if pod == "" {
+		return nil, 0, fmt.Errorf("give the pod to run the command in")
+	}
+	command := strings.TrimSpace(stringArg(args, "shell_command"))
+	if command == "" {
+		return nil, 0, fmt.Errorf("give the command to run")
+	}
+	timeout := defaultExecTimeout
+	if s := stringArg(args, "timeout"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, 0, fmt.Errorf("invalid timeout %q: give a duration such as 10s", s)
+		}
+		timeout = min(d, maxExecTimeout)
+	}
+
+	kubectlArgs := []string{"exec", pod}
+	if namespace := stringArg(args, "namespace"); namespace != "" {
+		kubectlArgs = append(kubectlArgs, "--namespace", namespace)
+	}
+	if container := stringArg(args, "container"); container != "" {
+		kubectlArgs = append(kubectlArgs, "--container", container)
+	}
+	kubectlArgs = append(kubectlArgs, "--")
+	if boolArg(args, "no_shell") {
+		return append(kubectlArgs, strings.Fields(command)...), timeout, nil
+	}
+	return append(kubectlArgs, "/bin/sh", "-c", command), timeout, nil
+}
+
+func (t *PodExecTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	kubectlArgs, timeout, err := execArgs(args)
+	if err != nil {
+		return &ExecResult{Error: err.Error()}, nil
+	}
+
+	execCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result, err := runKubectl(execCtx, kubectlArgs...)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if errors.Is(execCtx.Err(), context.DeadlineExceeded) {
+		if result == nil {
+			result = &ExecResult{}
+		}
+		result.Error = fmt.Sprintf("the command was stopped after the timeout of %v", timeout)
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (t *PodExecTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+// CheckModifiesResource returns "yes": we can't tell what a command does in the container, so it is confirmed like a change.
+func (t *PodExecTool) CheckModifiesResource(args map[string]any) string {
+	return "yes"
+}
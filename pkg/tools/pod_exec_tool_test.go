@@ -0,0 +1,81 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExecArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     map[string]any
+		expected []string
+		timeout  time.Duration
+		err      bool
+	}{
+		{
+			name:     "shell",
+			args:     map[string]any{"pod": "web-0", "namespace": "shop", "shell_command": "ps aux | head"},
+			expected: []string{"exec", "web-0", "--namespace", "shop", "--", "/bin/sh", "-c", "ps aux | head"},
+			timeout:  30 * time.Second,
+		},
+		{
+			name:     "no shell",
+			args:     map[string]any{"pod": "deploy/web", "container": "app", "shell_command": "/app/healthcheck --verbose", "no_shell": true, "timeout": "10s"},
+			expected: []string{"exec", "deploy/web", "--container", "app", "--", "/app/healthcheck", "--verbose"},
+			timeout:  10 * time.Second,
+		},
+		{
+			name:     "timeout capped",
+			args:     map[string]any{"pod": "web-0", "shell_command": "sleep 600", "timeout": "1h"},
+			expected: []string{"exec", "web-0", "--", "/bin/sh", "-c", "sleep 600"},
+			timeout:  5 * time.Minute,
+		},
+		{
+			name: "no command",
+			args: map[string]any{"pod": "web-0"},
+			err:  true,
+		},
+		{
+			name: "invalid timeout",
+			args: map[string]any{"pod": "web-0", "shell_command": "ls", "timeout": "soon"},
+			err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args, timeout, err := execArgs(tc.args)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(args, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, args)
+			}
+			if timeout != tc.timeout {
+				t.Errorf("expected timeout %v, got %v", tc.timeout, timeout)
+			}
+		})
+	}
+}
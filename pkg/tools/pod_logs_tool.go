@@ -0,0 +1,255 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&PodLogsTool{})
+}
+
+const (
+	// defaultLogTailLines is how many lines pod_logs reads if no tail_lines is given, and maxLogTailLines the most it reads.
+	defaultLogTailLines = 500
+	maxLogTailLines     = 10000
+
+	// maxLogEntries is how many of the matching lines are returned; the latest ones are kept.
+	maxLogEntries = 200
+	// maxLogLineLength is the length at which lines are cut, e.g. for large JSON payloads.
+	maxLogLineLength = 1000
+)
+
+// errorLogLine matches lines that report an error, in plain text, logfmt, JSON or klog format.
+var errorLogLine = regexp.MustCompile(`(?i)(\b(error|fatal|panic|exception|critical|failed|failure)\b|level=(error|fatal|crit)|"level":\s*"(error|fatal|crit)"|traceback \(most recent call last\))|^[EF]\d{4} `)
+
+// PodLogsTool reads the logs of a container, filters them and flags the error lines.
+type PodLogsTool struct{}
+
+func (t *PodLogsTool) Name() string {
+	return "pod_logs"
+}
+
+func (t *PodLogsTool) Description() string {
+	return `Reads the logs of a container of a pod, and returns the lines that match an optional regular expression,
+with their line numbers, flagging the lines that report errors. Use this tool to read logs instead of composing
+kubectl logs commands: give the container, how far back to read (since or tail_lines), whether to read the logs of
+the previous instance of a container that restarted, and a filter to find the relevant lines in long logs.`
+}
+
+func (t *PodLogsTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"pod": {
+					Type:        gollm.TypeString,
+					Description: `The name of the pod, or a workload as kind/name (e.g. "deployment/web") to read the logs of one of its pods.`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the pod. Defaults to the namespace of the current context.`,
+				},
+				"container": {
+					Type:        gollm.TypeString,
+					Description: `The container to read the logs of. Defaults to the default container of the pod.`,
+				},
+				"since": {
+					Type:        gollm.TypeString,
+					Description: `Only read the logs newer than this duration, e.g. "10m" or "2h".`,
+				},
+				"tail_lines": {
+					Type:        gollm.TypeInteger,
+					Description: `How many of the latest lines to read, before filtering. Defaults to 500; at most 10000.`,
+				},
+				"previous": {
+					Type:        gollm.TypeBoolean,
+					Description: `Read the logs of the previous instance of the container, e.g. to see why it crashed.`,
+				},
+				"filter": {
+					Type:        gollm.TypeString,
+					Description: `A regular expression (RE2 syntax) that the returned lines must match, e.g. "(?i)timeout|refused". Without a filter, all the lines read are returned.`,
+				},
+			},
+			Required: []string{"pod"},
+		},
+	}
+}
+
+// PodLogsResult is the result of the pod_logs tool.
+type PodLogsResult struct {
+	Pod       string `json:"pod"`
+	Container string `json:"container,omitempty"`
+	Previous  bool   `json:"previous,omitempty"`
+	// Command is the kubectl command that read the logs.
+	Command string `json:"command"`
+	// LinesRead is how many lines were read, before filtering.
+	LinesRead int `json:"lines_read"`
+	// Matched is how many of the lines read match the filter.
+	Matched int `json:"matched"`
+	// ErrorLines is how many of the matching lines report errors.
+	ErrorLines int `json:"error_lines"`
+	// Lines are the matching lines, the latest ones if there are too many to return.
+	Lines []LogLine `json:"lines"`
+	// Truncated is set if some matching lines are not returned.
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// LogLine is a line of logs.
+type LogLine struct {
+	// Number is the number of the line among the lines read, starting at 1.
+	Number int    `json:"number"`
+	Text   string `json:"text"`
+	// IsError is set if the line reports an error.
+	IsError bool `json:"is_error,omitempty"`
+}
+
+func (t *PodLogsTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*PodLogsResult]()
+}
+
+// logsRequest is a parsed pod_logs call.
+type logsRequest struct {
+	pod       string
+	namespace string
+	container string
+	since     time.Duration
+	tailLines int
+	previous  bool
+	filter    *regexp.Regexp
+}
+
+func parseLogsArgs(args map[string]any) (*logsRequest, error) {
+	r := &logsRequest{
+		pod:       stringArg(args, "pod"),
+		namespace: stringArg(args, "namespace"),
+		container: stringArg(args, "container"),
+		tailLines: defaultLogTailLines,
+		previous:  boolArg(args, "previous"),
+	}
+	if r.pod == "" {
+		return nil, fmt.Errorf("pod is required")
+	}
+	if since := stringArg(args, "since"); since != "" {
+		d, err := time.ParseDuration(since)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid since %q: give a duration such as 10m or 2h", since)
+		}
+		r.since = d
+	}
+	if tailLines, ok := intArg(args, "tail_lines"); ok {
+		if tailLines <= 0 {
+			return nil, fmt.Errorf("invalid tail_lines %d: give a positive number of lines", tailLines)
+		}
+		r.tailLines = min(tailLines, maxLogTailLines)
+	}
+	if filter := stringArg(args, "filter"); filter != "" {
+		re, err := regexp.Compile(filter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter %q: %w", filter, err)
+		}
+		r.filter = re
+	}
+	return r, nil
+}
+
+// kubectlArgs returns the arguments of the kubectl logs command.
+func (r *logsRequest) kubectlArgs() []string {
+	args := []string{"logs", r.pod}
+	if r.namespace != "" {
+		args = append(args, "--namespace", r.namespace)
+	}
+	if r.container != "" {
+		args = append(args, "--container", r.container)
+	}
+	if r.previous {
+		args = append(args, "--previous")
+	}
+	if r.since > 0 {
+		args = append(args, "--since="+r.since.String())
+	}
+	return append(args, "--tail="+strconv.Itoa(r.tailLines))
+}
+
+// filterLogLines returns the lines of output matching filter (all the lines if it is nil), the latest limit of them,
+// along with the number of lines read, of matching lines and of matching lines that report errors.
+func filterLogLines(output string, filter *regexp.Regexp, limit int) (lines []LogLine, read, matched, errors int) {
+	output = strings.TrimRight(output, "\n")
+	if output == "" {
+		return nil, 0, 0, 0
+	}
+	for i, text := range strings.Split(output, "\n") {
+		read++
+		text = strings.TrimRight(text, "\r")
+		if filter != nil && !filter.MatchString(text) {
+			continue
+		}
+		matched++
+		line := LogLine{Number: i + 1, Text: text, IsError: errorLogLine.MatchString(text)}
+		if line.IsError {
+			errors++
+		}
+		if len(line.Text) > maxLogLineLength {
+			line.Text = line.Text[:maxLogLineLength] + "..."
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+	return lines, read, matched, errors
+}
+
+func (t *PodLogsTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &PodLogsResult{Pod: stringArg(args, "pod"), Container: stringArg(args, "container"), Previous: boolArg(args, "previous")}
+	request, err := parseLogsArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	kubectlArgs := request.kubectlArgs()
+	result.Command = "kubectl " + strings.Join(kubectlArgs, " ")
+	out, err := runKubectl(ctx, kubectlArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if out.Error != "" || out.ExitCode != 0 {
+		result.Error = strings.TrimSpace(out.Error + " " + out.Stderr)
+		return result, nil
+	}
+	result.Lines, result.LinesRead, result.Matched, result.ErrorLines = filterLogLines(out.Stdout, request.filter, maxLogEntries)
+	result.Truncated = result.Matched > len(result.Lines)
+	return result, nil
+}
+
+func (t *PodLogsTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *PodLogsTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestLogsArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     map[string]any
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "defaults",
+			args:     map[string]any{"pod": "web-0"},
+			expected: []string{"logs", "web-0", "--tail=500"},
+		},
+		{
+			name:     "all options",
+			args:     map[string]any{"pod": "deployment/web", "namespace": "shop", "container": "app", "previous": true, "since": "10m", "tail_lines": float64(50)},
+			expected: []string{"logs", "deployment/web", "--namespace", "shop", "--container", "app", "--previous", "--since=10m0s", "--tail=50"},
+		},
+		{
+			name:     "tail lines are capped",
+			args:     map[string]any{"pod": "web-0", "tail_lines": "100000"},
+			expected: []string{"logs", "web-0", "--tail=10000"},
+		},
+		{
+			name: "missing pod",
+			args: map[string]any{"container": "app"},
+			err:  true,
+		},
+		{
+			name: "invalid since",
+			args: map[string]any{"pod": "web-0", "since": "yesterday"},
+			err:  true,
+		},
+		{
+			name: "invalid filter",
+			args: map[string]any{"pod": "web-0", "filter": "("},
+			err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := parseLogsArgs(tc.args)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := request.kubectlArgs(); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestFilterLogLines(t *testing.T) {
+	output := `starting server
+level=info msg="listening on :8080"
+level=error msg="connection refused" host=db
+GET /healthz 200
+E1017 10:00:00.000000       1 main.go:42] timeout talking to db
+`
+	testCases := []struct {
+		name            string
+		filter          string
+		limit           int
+		expected        []LogLine
+		matched, errors int
+	}{
+		{
+			name:  "all lines",
+			limit: 10,
+			expected: []LogLine{
+				{Number: 1, Text: "starting server"},
+				{Number: 2, Text: `level=info msg="listening on :8080"`},
+				{Number: 3, Text: `level=error msg="connection refused" host=db`, IsError: true},
+				{Number: 4, Text: "GET /healthz 200"},
+				{Number: 5, Text: "E1017 10:00:00.000000       1 main.go:42] timeout talking to db", IsError: true},
+			},
+			matched: 5,
+			errors:  2,
+		},
+		{
+			name:   "filtered",
+			filter: "db",
+			limit:  10,
+			expected: []LogLine{
+				{Number: 3, Text: `level=error msg="connection refused" host=db`, IsError: true},
+				{Number: 5, Text: "E1017 10:00:00.000000       1 main.go:42] timeout talking to db", IsError: true},
+			},
+			matched: 2,
+			errors:  2,
+		},
+		{
+			name:  "latest lines are kept",
+			limit: 1,
+			expected: []LogLine{
+				{Number: 5, Text: "E1017 10:00:00.000000       1 main.go:42] timeout talking to db", IsError: true},
+			},
+			matched: 5,
+			errors:  2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var filter *regexp.Regexp
+			if tc.filter != "" {
+				filter = regexp.MustCompile(tc.filter)
+			}
+			lines, read, matched, errors := filterLogLines(output, filter, tc.limit)
+			if !reflect.DeepEqual(lines, tc.expected) {
+				t.Errorf("expected lines %+v, got %+v", tc.expected, lines)
+			}
+			if read != 5 || matched != tc.matched || errors != tc.errors {
+				t.Errorf("expected 5 read, %d matched, %d errors; got %d, %d, %d", tc.matched, tc.errors, read, matched, errors)
+			}
+		})
+	}
+}
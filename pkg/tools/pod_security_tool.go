@@ -0,0 +1,298 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&PodSecurityAuditTool{})
+}
+
+// Pod Security Standards levels, see https://kubernetes.io/docs/concepts/security/pod-security-standards/
+const (
+	podSecurityLevelBaseline   = "baseline"
+	podSecurityLevelRestricted = "restricted"
+)
+
+// baselineAllowedCapabilities are the capabilities that may be added under the baseline level.
+var baselineAllowedCapabilities = []string{
+	"AUDIT_WRITE", "CHOWN", "DAC_OVERRIDE", "FOWNER", "FSETID", "KILL", "MKNOD",
+	"NET_BIND_SERVICE", "SETFCAP", "SETGID", "SETPCAP", "SETUID", "SYS_CHROOT",
+}
+
+// PodSecurityAuditTool evaluates workloads against the Pod Security Standards.
+type PodSecurityAuditTool struct{}
+
+func (t *PodSecurityAuditTool) Name() string {
+	return "audit_pod_security"
+}
+
+func (t *PodSecurityAuditTool) Description() string {
+	return `Evaluates pods and workloads (deployments, statefulsets, daemonsets, jobs, cronjobs...) against a Pod Security Standards level (baseline or restricted),
+covering host namespaces, privileged containers, capabilities, seccomp, hostPath volumes, host ports and running as root.
+Returns the violations found along with a suggested securityContext for each container.
+Use this tool to audit workloads, or to fix "violates PodSecurity" admission errors.`
+}
+
+func (t *PodSecurityAuditTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace to audit. Defaults to the current namespace.`,
+				},
+				"resource": {
+					Type:        gollm.TypeString,
+					Description: `The resource to audit, in kubectl format, e.g. "deployment/web", "pod/nginx" or "statefulsets". Defaults to all pods in the namespace.`,
+				},
+				"level": {
+					Type:        gollm.TypeString,
+					Description: `The Pod Security Standards level to evaluate against: "baseline" or "restricted". Defaults to "restricted".`,
+				},
+			},
+		},
+	}
+}
+
+// PodSecurityAuditReport is the result of the audit_pod_security tool.
+type PodSecurityAuditReport struct {
+	Level     string                      `json:"level"`
+	Workloads []PodSecurityWorkloadResult `json:"workloads,omitempty"`
+	Error     string                      `json:"error,omitempty"`
+}
+
+// PodSecurityWorkloadResult holds the violations for a single workload.
+type PodSecurityWorkloadResult struct {
+	Kind       string                 `json:"kind"`
+	Name       string                 `json:"name"`
+	Namespace  string                 `json:"namespace,omitempty"`
+	Violations []PodSecurityViolation `json:"violations,omitempty"`
+	// SuggestedSecurityContexts maps container names to a securityContext that fixes the container-level violations.
+	SuggestedSecurityContexts map[string]map[string]any `json:"suggested_security_contexts,omitempty"`
+	Error                     string                    `json:"error,omitempty"`
+}
+
+// PodSecurityViolation is a single Pod Security Standards violation.
+type PodSecurityViolation struct {
+	Check     string `json:"check"`
+	Container string `json:"container,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (t *PodSecurityAuditTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*PodSecurityAuditReport]()
+}
+
+func (t *PodSecurityAuditTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	level := stringArg(args, "level")
+	if level == "" {
+		level = podSecurityLevelRestricted
+	}
+	report := &PodSecurityAuditReport{Level: level}
+	if level != podSecurityLevelBaseline && level != podSecurityLevelRestricted {
+		report.Error = fmt.Sprintf("unknown level %q, must be %q or %q", level, podSecurityLevelBaseline, podSecurityLevelRestricted)
+		return report, nil
+	}
+
+	resource := stringArg(args, "resource")
+	if resource == "" {
+		resource = "pods"
+	}
+	kubectlArgs := []string{resource}
+	if namespace := stringArg(args, "namespace"); namespace != "" {
+		kubectlArgs = append(kubectlArgs, "--namespace", namespace)
+	}
+
+	obj := &workloadObject{}
+	if err := kubectlGetJSON(ctx, obj, kubectlArgs...); err != nil {
+		report.Error = err.Error()
+		return report, nil
+	}
+
+	for _, o := range obj.Objects() {
+		result := PodSecurityWorkloadResult{
+			Kind:      o.Kind,
+			Name:      o.Metadata.Name,
+			Namespace: o.Metadata.Namespace,
+		}
+		spec, err := o.PodSpec()
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Violations, result.SuggestedSecurityContexts = auditPodSpec(spec, level)
+		}
+		report.Workloads = append(report.Workloads, result)
+	}
+	return report, nil
+}
+
+// auditPodSpec checks the pod spec against the given Pod Security Standards level.
+// It returns the violations, and a suggested securityContext for each container with container-level violations.
+func auditPodSpec(spec *podSpec, level string) ([]PodSecurityViolation, map[string]map[string]any) {
+	var violations []PodSecurityViolation
+	suggestions := make(map[string]map[string]any)
+
+	addViolation := func(check, containerName, format string, args ...any) {
+		violations = append(violations, PodSecurityViolation{
+			Check:     check,
+			Container: containerName,
+			Message:   fmt.Sprintf(format, args...),
+		})
+	}
+	suggest := func(containerName, key string, value any) {
+		if suggestions[containerName] == nil {
+			suggestions[containerName] = make(map[string]any)
+		}
+		suggestions[containerName][key] = value
+	}
+
+	// Pod-level baseline checks
+	if spec.HostNetwork {
+		addViolation("hostNamespaces", "", "hostNetwork must not be set to true")
+	}
+	if spec.HostPID {
+		addViolation("hostNamespaces", "", "hostPID must not be set to true")
+	}
+	if spec.HostIPC {
+		addViolation("hostNamespaces", "", "hostIPC must not be set to true")
+	}
+	for _, v := range spec.Volumes {
+		if v.HostPath != nil {
+			addViolation("hostPathVolumes", "", "volume %q must not use hostPath (%s)", v.Name, v.HostPath.Path)
+		}
+	}
+
+	podSeccompType := ""
+	var podRunAsNonRoot *bool
+	var podRunAsUser *int64
+	if sc := spec.SecurityContext; sc != nil {
+		if sc.SeccompProfile != nil {
+			podSeccompType = sc.SeccompProfile.Type
+		}
+		podRunAsNonRoot = sc.RunAsNonRoot
+		podRunAsUser = sc.RunAsUser
+	}
+	if podSeccompType == "Unconfined" {
+		addViolation("seccompProfile", "", "pod seccompProfile.type must not be Unconfined")
+	}
+	if podRunAsUser != nil && *podRunAsUser == 0 && level == podSecurityLevelRestricted {
+		addViolation("runAsUser", "", "pod runAsUser must not be 0")
+	}
+
+	containers := slices.Concat(spec.InitContainers, spec.Containers)
+	for _, c := range containers {
+		sc := c.SecurityContext
+		if sc == nil {
+			sc = &securityContext{}
+		}
+
+		// Baseline checks
+		if sc.Privileged != nil && *sc.Privileged {
+			addViolation("privileged", c.Name, "container must not be privileged")
+			suggest(c.Name, "privileged", false)
+		}
+		if sc.Capabilities != nil {
+			var disallowed []string
+			for _, capability := range sc.Capabilities.Add {
+				allowed := slices.Contains(baselineAllowedCapabilities, capability)
+				if level == podSecurityLevelRestricted {
+					allowed = capability == "NET_BIND_SERVICE"
+				}
+				if !allowed {
+					disallowed = append(disallowed, capability)
+				}
+			}
+			if len(disallowed) > 0 {
+				addViolation("capabilities", c.Name, "container must not add capabilities %s", strings.Join(disallowed, ", "))
+			}
+		}
+		for _, port := range c.Ports {
+			if port.HostPort != 0 {
+				addViolation("hostPorts", c.Name, "container must not use hostPort %d", port.HostPort)
+			}
+		}
+		if sc.SeccompProfile != nil && sc.SeccompProfile.Type == "Unconfined" {
+			addViolation("seccompProfile", c.Name, "container seccompProfile.type must not be Unconfined")
+			suggest(c.Name, "seccompProfile", map[string]any{"type": "RuntimeDefault"})
+		}
+		if sc.ProcMount != "" && sc.ProcMount != "Default" {
+			addViolation("procMount", c.Name, "container procMount must be Default, not %s", sc.ProcMount)
+		}
+
+		if level != podSecurityLevelRestricted {
+			continue
+		}
+
+		// Restricted checks
+		if sc.AllowPrivilegeEscalation == nil || *sc.AllowPrivilegeEscalation {
+			addViolation("allowPrivilegeEscalation", c.Name, "container must set allowPrivilegeEscalation to false")
+			suggest(c.Name, "allowPrivilegeEscalation", false)
+		}
+		runAsNonRoot := podRunAsNonRoot
+		if sc.RunAsNonRoot != nil {
+			runAsNonRoot = sc.RunAsNonRoot
+		}
+		if runAsNonRoot == nil || !*runAsNonRoot {
+			addViolation("runAsNonRoot", c.Name, "container must set runAsNonRoot to true (in the container or pod securityContext)")
+			suggest(c.Name, "runAsNonRoot", true)
+		}
+		if sc.RunAsUser != nil && *sc.RunAsUser == 0 {
+			addViolation("runAsUser", c.Name, "container runAsUser must not be 0")
+			suggest(c.Name, "runAsUser", 1000)
+		}
+		seccompType := podSeccompType
+		if sc.SeccompProfile != nil {
+			seccompType = sc.SeccompProfile.Type
+		}
+		if seccompType != "RuntimeDefault" && seccompType != "Localhost" {
+			addViolation("seccompProfile", c.Name, "container must set seccompProfile.type to RuntimeDefault or Localhost (in the container or pod securityContext)")
+			suggest(c.Name, "seccompProfile", map[string]any{"type": "RuntimeDefault"})
+		}
+		if sc.Capabilities == nil || !slices.Contains(sc.Capabilities.Drop, "ALL") {
+			addViolation("capabilities", c.Name, "container must drop ALL capabilities")
+		}
+		if sc.Capabilities == nil || !slices.Contains(sc.Capabilities.Drop, "ALL") || len(sc.Capabilities.Add) > 0 {
+			suggestedCapabilities := map[string]any{"drop": []string{"ALL"}}
+			if sc.Capabilities != nil && slices.Contains(sc.Capabilities.Add, "NET_BIND_SERVICE") {
+				suggestedCapabilities["add"] = []string{"NET_BIND_SERVICE"}
+			}
+			suggest(c.Name, "capabilities", suggestedCapabilities)
+		}
+	}
+
+	if len(suggestions) == 0 {
+		suggestions = nil
+	}
+	return violations, suggestions
+}
+
+func (t *PodSecurityAuditTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *PodSecurityAuditTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
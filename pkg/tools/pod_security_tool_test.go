@@ -0,0 +1,82 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"slices"
+	"sort"
+	"testing"
+)
+
+func TestAuditPodSpec(t *testing.T) {
+	tests := []struct {
+		name           string
+		spec           string
+		level          string
+		expectedChecks []string
+	}{
+		{
+			name:           "default pod is baseline compliant",
+			spec:           `{"containers": [{"name": "app"}]}`,
+			level:          podSecurityLevelBaseline,
+			expectedChecks: nil,
+		},
+		{
+			name:           "default pod violates restricted",
+			spec:           `{"containers": [{"name": "app"}]}`,
+			level:          podSecurityLevelRestricted,
+			expectedChecks: []string{"allowPrivilegeEscalation", "capabilities", "runAsNonRoot", "seccompProfile"},
+		},
+		{
+			name: "restricted compliant pod",
+			spec: `{
+				"securityContext": {"runAsNonRoot": true, "seccompProfile": {"type": "RuntimeDefault"}},
+				"containers": [{"name": "app", "securityContext": {"allowPrivilegeEscalation": false, "capabilities": {"drop": ["ALL"]}}}]
+			}`,
+			level:          podSecurityLevelRestricted,
+			expectedChecks: nil,
+		},
+		{
+			name: "baseline violations",
+			spec: `{
+				"hostNetwork": true,
+				"volumes": [{"name": "host", "hostPath": {"path": "/var/run"}}],
+				"containers": [{"name": "app", "ports": [{"containerPort": 80, "hostPort": 80}],
+					"securityContext": {"privileged": true, "capabilities": {"add": ["SYS_ADMIN", "CHOWN"]}}}]
+			}`,
+			level:          podSecurityLevelBaseline,
+			expectedChecks: []string{"capabilities", "hostNamespaces", "hostPathVolumes", "hostPorts", "privileged"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &podSpec{}
+			if err := json.Unmarshal([]byte(tt.spec), spec); err != nil {
+				t.Fatalf("parsing spec: %v", err)
+			}
+			violations, _ := auditPodSpec(spec, tt.level)
+			var checks []string
+			for _, v := range violations {
+				checks = append(checks, v.Check)
+			}
+			sort.Strings(checks)
+			if !slices.Equal(tt.expectedChecks, checks) {
+				t.Errorf("expected violations %v, got %v: %v", tt.expectedChecks, checks, violations)
+			}
+		})
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"k8s.io/klog/v2"
+)
+
+func init() {
+	RegisterTool(&PortForwardTool{})
+}
+
+const (
+	// maxPortForwards is how many port-forwards can run at once.
+	maxPortForwards = 10
+	// portForwardStartTimeout is how long to wait for kubectl port-forward to start listening.
+	portForwardStartTimeout = 20 * time.Second
+)
+
+// PortForwards are the port-forwards started by the port_forward tool, which run in the background until they are
+// stopped or the conversation is closed. It is safe for concurrent use.
+type PortForwards struct {
+	mutex    sync.Mutex
+	forwards []*portForward
+	nextID   int
+}
+
+// portForward is a kubectl port-forward running in the background.
+type portForward struct {
+	info   PortForwardInfo
+	cancel context.CancelFunc
+	// done is closed once kubectl has exited; err and stderr are set by then.
+	done   chan struct{}
+	err    error
+	stderr bytes.Buffer
+}
+
+// PortForwardInfo describes a port-forward.
+type PortForwardInfo struct {
+	// ID identifies the port-forward, to stop it.
+	ID int `json:"id"`
+	// Resource is what is forwarded to, e.g. "svc/web".
+	Resource  string `json:"resource"`
+	Namespace string `json:"namespace,omitempty"`
+	// LocalAddress is the address that is forwarded, e.g. "127.0.0.1:43567".
+	LocalAddress string `json:"local_address"`
+	// RemotePort is the port of the resource it is forwarded to.
+	RemotePort string `json:"remote_port"`
+	// Running is false once the port-forward has stopped, and Error is why it stopped, if it failed.
+	Running bool   `json:"running"`
+	Age     string `json:"age"`
+	Error   string `json:"error,omitempty"`
+
+	started time.Time
+}
+
+// exited returns true if kubectl has exited.
+func (f *portForward) exited() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// describe returns the info of the port-forward as of now.
+func (f *portForward) describe() PortForwardInfo {
+	info := f.info
+	info.Age = time.Since(info.started).Round(time.Second).String()
+	info.Running = !f.exited()
+	if !info.Running && f.err != nil {
+		info.Error = strings.TrimSpace(f.err.Error() + " " + f.stderr.String())
+	}
+	return info
+}
+
+// List returns the port-forwards, in the order they were started.
+func (p *PortForwards) List() []PortForwardInfo {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	var infos []PortForwardInfo
+	for _, forward := range p.forwards {
+		infos = append(infos, forward.describe())
+	}
+	return infos
+}
+
+// Stop stops the port-forward with the given ID, and returns its info; it returns false if there is no such port-forward.
+func (p *PortForwards) Stop(id int) (PortForwardInfo, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for i, forward := range p.forwards {
+		if forward.info.ID == id {
+			forward.stop()
+			p.forwards = append(p.forwards[:i], p.forwards[i+1:]...)
+			info := forward.describe()
+			info.Error = ""
+			return info, true
+		}
+	}
+	return PortForwardInfo{}, false
+}
+
+// Close stops all the port-forwards.
+func (p *PortForwards) Close() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for _, forward := range p.forwards {
+		klog.Infof("Stopping port-forward to %s on %s", forward.info.Resource, forward.info.LocalAddress)
+		forward.stop()
+	}
+	p.forwards = nil
+}
+
+// stop stops kubectl and waits for it to exit.
+func (f *portForward) stop() {
+	f.cancel()
+	<-f.done
+}
+
+// add registers a port-forward that has started, and assigns its ID.
+func (p *PortForwards) add(forward *portForward) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	// Forget the port-forwards that have stopped on their own, they can't be restarted.
+	running := p.forwards[:0]
+	for _, f := range p.forwards {
+		if !f.exited() {
+			running = append(running, f)
+		}
+	}
+	p.forwards = running
+	if len(p.forwards) >= maxPortForwards {
+		return fmt.Errorf("there are already %d port-forwards running, stop one first", len(p.forwards))
+	}
+	p.nextID++
+	forward.info.ID = p.nextID
+	p.forwards = append(p.forwards, forward)
+	return nil
+}
+
+type portForwardsKey struct{}
+
+// WithPortForwards returns a context in which the port_forward tool registers the port-forwards it starts in forwards.
+func WithPortForwards(ctx context.Context, forwards *PortForwards) context.Context {
+	return context.WithValue(ctx, portForwardsKey{}, forwards)
+}
+
+// portForwardsFromContext returns the port-forwards of the context, or nil if port-forwards can't be started.
+func portForwardsFromContext(ctx context.Context) *PortForwards {
+	forwards, _ := ctx.Value(portForwardsKey{}).(*PortForwards)
+	return forwards
+}
+
+// PortForwardTool starts kubectl port-forward in the background, so that later commands can reach a pod or service
+// in the cluster on a local port, and lists and stops the port-forwards it started.
+type PortForwardTool struct{}
+
+func (t *PortForwardTool) Name() string {
+	return "port_forward"
+}
+
+func (t *PortForwardTool) Description() string {
+	return `Starts kubectl port-forward in the background, so that a pod or service in the cluster can be reached on a local
+port, and lists or stops the port-forwards started this way. Port-forwards keep running until they are stopped or the
+session ends. Use this tool to probe a service from inside the cluster network, e.g. start a port-forward to svc/web
+port 80, then run curl against the local_address it returns with the bash tool (e.g. curl -s http://127.0.0.1:43567/healthz),
+and stop it when done.`
+}
+
+func (t *PortForwardTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"action": {
+					Type:        gollm.TypeString,
+					Description: `What to do: "start" a port-forward (the default), "stop" one, or "list" them.`,
+				},
+				"resource": {
+					Type:        gollm.TypeString,
+					Description: `What to forward to, to start a port-forward: a pod name, or kind/name such as "svc/web" or "deployment/web".`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the resource. Defaults to the namespace of the current context.`,
+				},
+				"port": {
+					Type:        gollm.TypeString,
+					Description: `The port of the resource to forward to, e.g. "80"; a free local port is picked. Give "local:remote", e.g. "8080:80", to choose the local port.`,
+				},
+				"id": {
+					Type:        gollm.TypeInteger,
+					Description: `The id of the port-forward to stop, as returned when it was started or listed.`,
+				},
+			},
+		},
+	}
+}
+
+// PortForwardResult is the result of the port_forward tool.
+type PortForwardResult struct {
+	// Command is the kubectl command that was started.
+	Command string `json:"command,omitempty"`
+	// Forwards are the port-forward that was started or stopped, or all of them for list.
+	Forwards []PortForwardInfo `json:"forwards,omitempty"`
+	Error    string            `json:"error,omitempty"`
+}
+
+func (t *PortForwardTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*PortForwardResult]()
+}
+
+// portSpecPattern matches the port argument: a remote port, or local:remote.
+var portSpecPattern = regexp.MustCompile(`^(?:(\d+):)?(\d+)$`)
+
+// parsePortSpec parses the port argument into the port argument of kubectl port-forward, and the remote port.
+// Without a local port, ":remote" lets kubectl pick a free local port.
+func parsePortSpec(port string) (spec string, remote string, err error) {
+	m := portSpecPattern.FindStringSubmatch(strings.TrimSpace(port))
+	if m == nil {
+		return "", "", fmt.Errorf("invalid port %q: give a port such as 80, or local:remote such as 8080:80", port)
+	}
+	for _, p := range m[1:] {
+		if n, err := strconv.Atoi(p); p != "" && (err != nil || n < 1 || n > 65535) {
+			return "", "", fmt.Errorf("invalid port %q: ports are between 1 and 65535", port)
+		}
+	}
+	return m[1] + ":" + m[2], m[2], nil
+}
+
+// forwardingLine matches the line kubectl port-forward prints once it listens, e.g.
+// "Forwarding from 127.0.0.1:43567 -> 80".
+var forwardingLine = regexp.MustCompile(`^Forwarding from (\S+:\d+) -> (\d+)`)
+
+// parseForwardingLine returns the local address from a line printed by kubectl port-forward, if it says where it listens.
+func parseForwardingLine(line string) (string, bool) {
+	m := forwardingLine.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+func (t *PortForwardTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &PortForwardResult{}
+	forwards := portForwardsFromContext(ctx)
+	if forwards == nil {
+		result.Error = "port-forwards can only be started in a conversation"
+		return result, nil
+	}
+
+	switch action := stringArg(args, "action"); action {
+	case "", "start":
+		return t.start(ctx, forwards, args)
+	case "list":
+		result.Forwards = forwards.List()
+	case "stop":
+		id, ok := intArg(args, "id")
+		if !ok {
+			result.Error = "give the id of the port-forward to stop"
+			break
+		}
+		info, ok := forwards.Stop(id)
+		if !ok {
+			result.Error = fmt.Sprintf("there is no port-forward with id %d; list them to see their ids", id)
+			break
+		}
+		result.Forwards = []PortForwardInfo{info}
+	default:
+		result.Error = fmt.Sprintf("unknown action %q: use start, stop or list", action)
+	}
+	return result, nil
+}
+
+// start starts a port-forward, and returns once kubectl listens on the local port.
+func (t *PortForwardTool) start(ctx context.Context, forwards *PortForwards, args map[string]any) (any, error) {
+	result := &PortForwardResult{}
+	resource := stringArg(args, "resource")
+	if resource == "" {
+		result.Error = "give the resource to forward to, e.g. svc/web"
+		return result, nil
+	}
+	spec, remotePort, err := parsePortSpec(stringArg(args, "port"))
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	namespace := stringArg(args, "namespace")
+	kubectlArgs := []string{"port-forward", resource, spec, "--address", "127.0.0.1"}
+	if namespace != "" {
+		kubectlArgs = append(kubectlArgs, "--namespace", namespace)
+	}
+	result.Command = "kubectl " + strings.Join(kubectlArgs, " ")
+
+	// The port-forward outlives the tool call, until it is stopped or the conversation is closed.
+	forwardCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	cmd, err := kubectlCommand(forwardCtx, kubectlArgs...)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if cmd.WaitDelay == 0 {
+		cmd.WaitDelay = waitDelay
+	}
+	forward := &portForward{
+		info:   PortForwardInfo{Resource: resource, Namespace: namespace, RemotePort: remotePort, started: time.Now()},
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	cmd.Stderr = &forward.stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("creating stdout pipe: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("starting kubectl port-forward: %w", err)
+	}
+
+	listening := make(chan string, 1)
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			if address, ok := parseForwardingLine(scanner.Text()); ok {
+				select {
+				case listening <- address:
+				default:
+				}
+			}
+		}
+		// Keep reading, so that kubectl doesn't block writing the lines it prints for each connection.
+		io.Copy(io.Discard, stdout)
+		forward.err = cmd.Wait()
+		close(forward.done)
+	}()
+
+	ReportProgress(ctx, Progress{Message: "Starting port-forward to " + resource})
+	select {
+	case address := <-listening:
+		forward.info.LocalAddress = address
+	case <-forward.done:
+		cancel()
+		result.Error = strings.TrimSpace(fmt.Sprintf("kubectl port-forward exited: %v %s", forward.err, forward.stderr.String()))
+		return result, nil
+	case <-time.After(portForwardStartTimeout):
+		forward.stop()
+		result.Error = strings.TrimSpace(fmt.Sprintf("kubectl port-forward did not start listening within %v %s", portForwardStartTimeout, forward.stderr.String()))
+		return result, nil
+	case <-ctx.Done():
+		forward.stop()
+		return nil, ctx.Err()
+	}
+
+	if err := forwards.add(forward); err != nil {
+		forward.stop()
+		result.Error = err.Error()
+		return result, nil
+	}
+	result.Forwards = []PortForwardInfo{forward.describe()}
+	return result, nil
+}
+
+func (t *PortForwardTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *PortForwardTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
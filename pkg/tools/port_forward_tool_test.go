@@ -0,0 +1,72 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"testing"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	testCases := []struct {
+		port   string
+		spec   string
+		remote string
+		err    bool
+	}{
+		{port: "80", spec: ":80", remote: "80"},
+		{port: " 8080:80 ", spec: "8080:80", remote: "80"},
+		{port: "", err: true},
+		{port: "http", err: true},
+		{port: "0", err: true},
+		{port: "8080:70000", err: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.port, func(t *testing.T) {
+			spec, remote, err := parsePortSpec(tc.port)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", spec)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if spec != tc.spec || remote != tc.remote {
+				t.Errorf("expected %q and %q, got %q and %q", tc.spec, tc.remote, spec, remote)
+			}
+		})
+	}
+}
+
+func TestParseForwardingLine(t *testing.T) {
+	testCases := []struct {
+		line     string
+		expected string
+		ok       bool
+	}{
+		{line: "Forwarding from 127.0.0.1:43567 -> 80", expected: "127.0.0.1:43567", ok: true},
+		{line: "Forwarding from [::1]:8080 -> 8080\n", expected: "[::1]:8080", ok: true},
+		{line: "Handling connection for 43567"},
+	}
+
+	for _, tc := range testCases {
+		got, ok := parseForwardingLine(tc.line)
+		if got != tc.expected || ok != tc.ok {
+			t.Errorf("parseForwardingLine(%q): expected %q, %v, got %q, %v", tc.line, tc.expected, tc.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build unix
+
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExecuteCommandKillsBackgroundProcesses(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	// The background child would create the marker after the timeout, if it were still running.
+	cmd := exec.CommandContext(ctx, lookupBashBin(), "-c", "(sleep 1; touch "+marker+") & sleep 30")
+	started := time.Now()
+	if _, err := executeCommand(ctx, cmd); err != nil {
+		t.Fatalf("executeCommand() error = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed >= waitDelay {
+		t.Errorf("executeCommand() returned after %v, expected it to return at the timeout", elapsed)
+	}
+
+	time.Sleep(1500 * time.Millisecond)
+	if _, err := os.Stat(marker); err == nil {
+		t.Errorf("the background child of the command was still running after the timeout")
+	}
+}
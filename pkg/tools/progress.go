@@ -0,0 +1,88 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"io"
+	"sync"
+)
+
+// ProgressKey is the context key of the ProgressFunc that receives the progress of a tool call.
+const ProgressKey ContextKey = "progress"
+
+// Progress is a progress update from a long-running tool.
+type Progress struct {
+	// Message describes the current step, e.g. "Evicting pod web-1".
+	Message string
+	// Current is the progress so far, in a unit that suits the tool (e.g. objects processed).
+	Current float64
+	// Total is the total amount of work in the same unit, or zero if it is not known.
+	Total float64
+}
+
+// Fraction returns the part of the work that is done, between 0 and 1, or -1 if the total is not known.
+func (p Progress) Fraction() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+	return min(max(p.Current/p.Total, 0), 1)
+}
+
+// ProgressFunc receives progress updates from a tool; it must not block.
+type ProgressFunc func(Progress)
+
+// WithProgress returns a context in which tools report their progress to fn.
+func WithProgress(ctx context.Context, fn ProgressFunc) context.Context {
+	return context.WithValue(ctx, ProgressKey, fn)
+}
+
+// ReportProgress reports the progress of the tool call running in ctx.
+// Tools that take a while (e.g. that process many objects) should call it as they go;
+// it does nothing if nobody is interested in the progress.
+func ReportProgress(ctx context.Context, progress Progress) {
+	if fn, ok := ctx.Value(ProgressKey).(ProgressFunc); ok && fn != nil {
+		fn(progress)
+	}
+}
+
+// OutputKey is the context key of the io.Writer that receives the output of a tool call as it is produced.
+const OutputKey ContextKey = "output"
+
+// WithOutput returns a context in which tools that run commands write their output to w as it is produced,
+// e.g. so long-running commands can be followed in the UI. w may be written to from several goroutines at once.
+func WithOutput(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, OutputKey, &syncWriter{w: w})
+}
+
+// outputWriter returns the writer that receives the output of the tool call running in ctx, or nil if none.
+func outputWriter(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(OutputKey).(io.Writer); ok {
+		return w
+	}
+	return nil
+}
+
+// syncWriter serializes writes, as the stdout and stderr of commands are copied concurrently.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,364 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"sigs.k8s.io/yaml"
+)
+
+//go:embed default_recipes.yaml
+var defaultRecipes []byte
+
+func init() {
+	if err := loadRecipes(defaultRecipes); err != nil {
+		panic(fmt.Sprintf("loading default recipes: %v", err))
+	}
+}
+
+// Recipe is a parameterized, multi-step procedure for a common operation (e.g. restarting a deployment),
+// exposed to the model as a single tool. Each step runs a kubectl command, and can check its output
+// before the next step runs.
+type Recipe struct {
+	// Name is the name of the tool, e.g. restart_deployment_with_checks.
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Parameters  []RecipeParameter `json:"parameters,omitempty"`
+	Steps       []RecipeStep      `json:"steps"`
+}
+
+// RecipeParameter is a parameter of a recipe; {{name}} is replaced by its value in the steps.
+type RecipeParameter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	// Default is the value used when the parameter is not given.
+	Default string `json:"default,omitempty"`
+}
+
+// RecipeStep is a step of a recipe.
+// The step fails if kubectl fails, if the output does not match Expect, or if it matches FailIf.
+type RecipeStep struct {
+	Description string `json:"description"`
+	// Kubectl are the arguments of kubectl, e.g. "rollout restart deployment/{{deployment}} -n {{namespace}}".
+	// They are not run in a shell.
+	Kubectl string `json:"kubectl"`
+	// Expect is a regular expression that the output must match.
+	Expect string `json:"expect,omitempty"`
+	// FailIf is a regular expression that the output must not match.
+	FailIf string `json:"failIf,omitempty"`
+	// Message explains why the recipe stopped when the step fails.
+	Message string `json:"message,omitempty"`
+}
+
+var recipePlaceholder = regexp.MustCompile(`\{\{([A-Za-z0-9_]+)\}\}`)
+
+// safeRecipeValue matches the values that can be passed as recipe parameters.
+// Values are substituted into kubectl arguments and JSON patches, so we keep them to a conservative set of characters.
+var safeRecipeValue = regexp.MustCompile(`^[A-Za-z0-9._:/=@+,%~-]*$`)
+
+// validate checks that the recipe is well-formed, so that mistakes are reported when it is loaded.
+func (r *Recipe) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("recipes must specify a name")
+	}
+	if len(r.Steps) == 0 {
+		return fmt.Errorf("recipe %q has no steps", r.Name)
+	}
+	params := make(map[string]bool)
+	for _, param := range r.Parameters {
+		params[param.Name] = true
+	}
+	for i, step := range r.Steps {
+		if strings.TrimSpace(step.Kubectl) == "" {
+			return fmt.Errorf("recipe %q: step %d has no kubectl command", r.Name, i+1)
+		}
+		for _, s := range []string{step.Kubectl, step.Expect, step.FailIf} {
+			for _, match := range recipePlaceholder.FindAllStringSubmatch(s, -1) {
+				if !params[match[1]] {
+					return fmt.Errorf("recipe %q: step %d uses undeclared parameter %q", r.Name, i+1, match[1])
+				}
+			}
+		}
+		for _, pattern := range []string{step.Expect, step.FailIf} {
+			if _, err := regexp.Compile(recipePlaceholder.ReplaceAllString(pattern, "x")); err != nil {
+				return fmt.Errorf("recipe %q: step %d: invalid regular expression %q: %w", r.Name, i+1, pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// recipeValues returns the value of each parameter of the recipe, from the arguments of the tool call.
+func (r *Recipe) recipeValues(args map[string]any) (map[string]string, error) {
+	values := make(map[string]string)
+	for _, param := range r.Parameters {
+		value := stringArg(args, param.Name)
+		if value == "" {
+			value = param.Default
+		}
+		if value == "" && param.Required {
+			return nil, fmt.Errorf("parameter %q is required", param.Name)
+		}
+		if !safeRecipeValue.MatchString(value) {
+			return nil, fmt.Errorf("parameter %q contains characters that are not allowed; only letters, digits and ._:/=@+,%%~- can be used", param.Name)
+		}
+		values[param.Name] = value
+	}
+	return values, nil
+}
+
+// expandRecipeArgs splits the kubectl arguments of a step, and replaces the placeholders in each argument.
+// Splitting before substituting means a value can never add arguments.
+func expandRecipeArgs(kubectl string, values map[string]string) []string {
+	args := strings.Fields(kubectl)
+	for i, arg := range args {
+		args[i] = recipePlaceholder.ReplaceAllStringFunc(arg, func(placeholder string) string {
+			return values[recipePlaceholder.FindStringSubmatch(placeholder)[1]]
+		})
+	}
+	return args
+}
+
+// expandRecipePattern replaces the placeholders in a regular expression with the quoted values.
+func expandRecipePattern(pattern string, values map[string]string) *regexp.Regexp {
+	if pattern == "" {
+		return nil
+	}
+	expanded := recipePlaceholder.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		return regexp.QuoteMeta(values[recipePlaceholder.FindStringSubmatch(placeholder)[1]])
+	})
+	// The pattern was checked by validate, and quoted values cannot make it invalid.
+	return regexp.MustCompile(expanded)
+}
+
+// RecipeTool runs a recipe.
+type RecipeTool struct {
+	recipe Recipe
+}
+
+func (t *RecipeTool) Name() string {
+	return t.recipe.Name
+}
+
+func (t *RecipeTool) Description() string {
+	var sb strings.Builder
+	sb.WriteString(t.recipe.Description)
+	sb.WriteString("\nSteps:")
+	for i, step := range t.recipe.Steps {
+		fmt.Fprintf(&sb, "\n%d. %s", i+1, step.Description)
+	}
+	sb.WriteString("\nThe recipe stops at the first step that fails.")
+	return sb.String()
+}
+
+func (t *RecipeTool) FunctionDefinition() *gollm.FunctionDefinition {
+	properties := make(map[string]*gollm.Schema)
+	var required []string
+	for _, param := range t.recipe.Parameters {
+		description := param.Description
+		if param.Default != "" {
+			description += fmt.Sprintf(" Defaults to %q.", param.Default)
+		}
+		properties[param.Name] = &gollm.Schema{
+			Type:        gollm.TypeString,
+			Description: strings.TrimSpace(description),
+		}
+		if param.Required {
+			required = append(required, param.Name)
+		}
+	}
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type:       gollm.TypeObject,
+			Properties: properties,
+			Required:   required,
+		},
+	}
+}
+
+// RecipeResult is the result of running a recipe.
+type RecipeResult struct {
+	Recipe string             `json:"recipe"`
+	Steps  []RecipeStepResult `json:"steps"`
+	// Completed is true if all steps ran and passed their checks.
+	Completed bool   `json:"completed"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RecipeStepResult is the outcome of a step of a recipe.
+type RecipeStepResult struct {
+	Description string `json:"description"`
+	Command     string `json:"command"`
+	Output      string `json:"output,omitempty"`
+	Failed      bool   `json:"failed,omitempty"`
+}
+
+var _ ui.CanFormatAsText = &RecipeResult{}
+
+func (r *RecipeResult) FormatAsText() string {
+	var sb strings.Builder
+	for i, step := range r.Steps {
+		status := "ok"
+		if step.Failed {
+			status = "failed"
+		}
+		fmt.Fprintf(&sb, "%d. %s (%s): %s\n", i+1, step.Description, step.Command, status)
+		if output := strings.TrimSpace(step.Output); output != "" {
+			sb.WriteString(output + "\n")
+		}
+	}
+	if r.Error != "" {
+		sb.WriteString("error: " + r.Error + "\n")
+	} else if r.Completed {
+		sb.WriteString("recipe completed\n")
+	}
+	return sb.String()
+}
+
+func (t *RecipeTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*RecipeResult]()
+}
+
+func (t *RecipeTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &RecipeResult{Recipe: t.recipe.Name}
+	values, err := t.recipe.recipeValues(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	for i, step := range t.recipe.Steps {
+		ReportProgress(ctx, Progress{Message: step.Description, Current: float64(i), Total: float64(len(t.recipe.Steps))})
+
+		kubectlArgs := expandRecipeArgs(step.Kubectl, values)
+		stepResult := RecipeStepResult{
+			Description: step.Description,
+			Command:     "kubectl " + strings.Join(kubectlArgs, " "),
+		}
+		reason := ""
+		execResult, err := runKubectl(ctx, kubectlArgs...)
+		if err != nil {
+			reason = err.Error()
+		} else {
+			stepResult.Output = execResult.Stdout
+			if execResult.Error != "" || execResult.ExitCode != 0 {
+				stepResult.Output = execResult.FormatAsText()
+				reason = "the command failed"
+			} else if re := expandRecipePattern(step.Expect, values); re != nil && !re.MatchString(execResult.Stdout) {
+				reason = "the output did not match the expected result"
+			} else if re := expandRecipePattern(step.FailIf, values); re != nil && re.MatchString(execResult.Stdout) {
+				reason = "the output matched a failure condition"
+			}
+		}
+
+		if reason != "" {
+			stepResult.Failed = true
+			result.Steps = append(result.Steps, stepResult)
+			if step.Message != "" {
+				reason = step.Message
+			}
+			result.Error = fmt.Sprintf("step %d (%s) failed: %s; the remaining steps were not run", i+1, step.Description, reason)
+			return result, nil
+		}
+		result.Steps = append(result.Steps, stepResult)
+	}
+	result.Completed = true
+	return result, nil
+}
+
+func (t *RecipeTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+// CheckModifiesResource returns "yes" if any step of the recipe modifies resources.
+// The steps are checked as written, so the result does not depend on the arguments.
+func (t *RecipeTool) CheckModifiesResource(args map[string]any) string {
+	result := "no"
+	for _, step := range t.recipe.Steps {
+		switch kubectlModifiesResource("kubectl " + recipePlaceholder.ReplaceAllString(step.Kubectl, "x")) {
+		case "yes":
+			return "yes"
+		case "unknown":
+			result = "unknown"
+		}
+	}
+	return result
+}
+
+// loadRecipes parses the recipes and registers them as tools, replacing existing recipes with the same name.
+func loadRecipes(b []byte) error {
+	var recipes []Recipe
+	if err := yaml.Unmarshal(b, &recipes); err != nil {
+		return fmt.Errorf("parsing recipes: %w", err)
+	}
+	for _, recipe := range recipes {
+		if err := recipe.validate(); err != nil {
+			return err
+		}
+		if existing, exists := allTools.tools[recipe.Name]; exists {
+			if _, isRecipe := existing.(*RecipeTool); !isRecipe {
+				return fmt.Errorf("recipe %q has the same name as a tool", recipe.Name)
+			}
+		}
+		allTools.tools[recipe.Name] = &RecipeTool{recipe: recipe}
+	}
+	return nil
+}
+
+// LoadRecipes loads recipes from a YAML file, or all files in a directory, in addition to the built-in recipes.
+// Recipes with the same name as an existing recipe replace it.
+func LoadRecipes(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to describe recipes file %s: %w", path, err)
+	}
+
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return fmt.Errorf("failed to read recipes dir %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			if isSignatureFile(entry.Name()) {
+				continue
+			}
+			if err := LoadRecipes(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	b, err := ReadArtifact(path)
+	if err != nil {
+		return fmt.Errorf("failed to read recipes file %s: %w", path, err)
+	}
+	if err := loadRecipes(b); err != nil {
+		return fmt.Errorf("loading recipes from %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExpandRecipeArgs(t *testing.T) {
+	values := map[string]string{"deployment": "web", "namespace": "prod", "key": "password", "value": "s3cret"}
+	tests := []struct {
+		name    string
+		kubectl string
+		want    []string
+	}{
+		{
+			name:    "placeholders",
+			kubectl: "rollout restart deployment/{{deployment}} -n {{namespace}}",
+			want:    []string{"rollout", "restart", "deployment/web", "-n", "prod"},
+		},
+		{
+			name:    "json patch",
+			kubectl: `patch secret db --type=merge -p {"stringData":{"{{key}}":"{{value}}"}}`,
+			want:    []string{"patch", "secret", "db", "--type=merge", "-p", `{"stringData":{"password":"s3cret"}}`},
+		},
+		{
+			name:    "unknown placeholder",
+			kubectl: "get pods -l app={{app}}",
+			want:    []string{"get", "pods", "-l", "app="},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandRecipeArgs(tt.kubectl, values); !slices.Equal(got, tt.want) {
+				t.Errorf("expandRecipeArgs(%q) = %q, want %q", tt.kubectl, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecipeValues(t *testing.T) {
+	recipe := Recipe{
+		Name: "test",
+		Parameters: []RecipeParameter{
+			{Name: "deployment", Required: true},
+			{Name: "namespace", Default: "default"},
+		},
+	}
+	tests := []struct {
+		name    string
+		args    map[string]any
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "default",
+			args: map[string]any{"deployment": "web"},
+			want: map[string]string{"deployment": "web", "namespace": "default"},
+		},
+		{
+			name: "given",
+			args: map[string]any{"deployment": "web", "namespace": "prod"},
+			want: map[string]string{"deployment": "web", "namespace": "prod"},
+		},
+		{
+			name:    "missing required",
+			args:    map[string]any{"namespace": "prod"},
+			wantErr: true,
+		},
+		{
+			name:    "whitespace",
+			args:    map[string]any{"deployment": "web --all"},
+			wantErr: true,
+		},
+		{
+			name:    "quotes",
+			args:    map[string]any{"deployment": `web"}`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := recipe.recipeValues(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("recipeValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for key, want := range tt.want {
+				if got[key] != want {
+					t.Errorf("recipeValues()[%q] = %q, want %q", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultRecipesModifyResources(t *testing.T) {
+	for _, name := range []string{"scale_down_safely", "rotate_secret", "restart_deployment_with_checks"} {
+		tool := Lookup(name)
+		if tool == nil {
+			t.Fatalf("recipe %q is not registered", name)
+		}
+		if got := tool.CheckModifiesResource(nil); got != "yes" {
+			t.Errorf("%s.CheckModifiesResource() = %q, want %q", name, got, "yes")
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&ResolveResourceTool{})
+}
+
+const (
+	// maxResourceCandidates is how many candidates resolve_resource returns at most.
+	maxResourceCandidates = 10
+	// minResourceScore is the lowest score of a candidate that resolve_resource returns.
+	minResourceScore = 0.4
+)
+
+// ResolveResourceTool matches a partial or misspelled name against the names of the resources in the cluster.
+type ResolveResourceTool struct{}
+
+func (t *ResolveResourceTool) Name() string {
+	return "resolve_resource"
+}
+
+func (t *ResolveResourceTool) Description() string {
+	return `Finds the resources whose names match a partial or misspelled name, e.g. "nginx" or "paymnets", and returns
+them ranked from the best match, with a score between 0 and 1 and how they matched (exact, prefix, substring, or similar).
+Use this tool when the user refers to a resource by an approximate name, instead of listing all the resources to find it;
+if several candidates are likely, ask the user which one they mean.`
+}
+
+func (t *ResolveResourceTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"name": {
+					Type:        gollm.TypeString,
+					Description: `The name as the user gave it, e.g. "nginx" or "paymnets-api".`,
+				},
+				"kind": {
+					Type:        gollm.TypeString,
+					Description: `The kind of the resource, as kubectl get takes it, e.g. "pod", "deployment" or "svc". Defaults to the common kinds of workloads, pods, services, ingresses, jobs, configmaps, secrets, persistent volume claims and service accounts.`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace to look in. Defaults to the namespace of the current context.`,
+				},
+				"all_namespaces": {
+					Type:        gollm.TypeBoolean,
+					Description: `Look in all the namespaces.`,
+				},
+			},
+			Required: []string{"name"},
+		},
+	}
+}
+
+// ResolveResourceResult is the result of the resolve_resource tool.
+type ResolveResourceResult struct {
+	Name string `json:"name"`
+	// Candidates are the matching resources, the best match first.
+	Candidates []ResourceCandidate `json:"candidates"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// ResourceCandidate is a resource that a name may refer to.
+type ResourceCandidate struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Score is how well the name matches, between 0 and 1.
+	Score float64 `json:"score"`
+	// Match is how the name matches: exact, prefix, substring or similar.
+	Match string `json:"match"`
+}
+
+func (t *ResolveResourceTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ResolveResourceResult]()
+}
+
+func (t *ResolveResourceTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	name := strings.ToLower(stringArg(args, "name"))
+	result := &ResolveResourceResult{Name: name}
+	if name == "" {
+		result.Error = "name is required"
+		return result, nil
+	}
+	kind := stringArg(args, "kind")
+	if kind == "" {
+		kind = inventoryKinds
+	}
+	kubectlArgs := []string{"get", kind, "--ignore-not-found", "--no-headers",
+		"-o", "custom-columns=KIND:.kind,NAMESPACE:.metadata.namespace,NAME:.metadata.name"}
+	if boolArg(args, "all_namespaces") {
+		kubectlArgs = append(kubectlArgs, "--all-namespaces")
+	} else if namespace := stringArg(args, "namespace"); namespace != "" {
+		kubectlArgs = append(kubectlArgs, "--namespace", namespace)
+	}
+	out, err := runKubectl(ctx, kubectlArgs...)
+	if err != nil {
+		return nil, err
+	}
+	// Kinds we may not list (e.g. secrets) fail the command, but the others are still listed.
+	if out.Stdout == "" && (out.Error != "" || out.ExitCode != 0) {
+		result.Error = strings.TrimSpace(out.Error + " " + out.Stderr)
+		return result, nil
+	}
+	result.Candidates = rankResources(name, parseResourceColumns(out.Stdout))
+	return result, nil
+}
+
+// parseResourceColumns parses the kind, namespace and name columns of the resources listed by kubectl get.
+func parseResourceColumns(output string) []ResourceCandidate {
+	var resources []ResourceCandidate
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		namespace := fields[1]
+		if namespace == "<none>" {
+			namespace = ""
+		}
+		resources = append(resources, ResourceCandidate{Kind: strings.ToLower(fields[0]), Namespace: namespace, Name: fields[2]})
+	}
+	return resources
+}
+
+// rankResources scores how well name matches each of the resources, and returns the best matches first.
+func rankResources(name string, resources []ResourceCandidate) []ResourceCandidate {
+	var candidates []ResourceCandidate
+	for _, resource := range resources {
+		resource.Score, resource.Match = matchScore(name, resource.Name)
+		if resource.Score >= minResourceScore {
+			candidates = append(candidates, resource)
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b ResourceCandidate) int {
+		if a.Score != b.Score {
+			return cmp.Compare(b.Score, a.Score)
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+	if len(candidates) > maxResourceCandidates {
+		candidates = candidates[:maxResourceCandidates]
+	}
+	return candidates
+}
+
+// matchScore returns how well name matches the name of a resource, between 0 and 1, and how it matches.
+// Shorter names that contain it score higher, as it is more of them. Misspellings are matched by their edit
+// distance to the whole name or to one of its dash-separated parts, e.g. "paymnets" to "payments-api".
+func matchScore(name, resourceName string) (float64, string) {
+	resourceName = strings.ToLower(resourceName)
+	coverage := float64(len(name)) / float64(max(len(resourceName), 1))
+	switch {
+	case name == resourceName:
+		return 1, "exact"
+	case strings.HasPrefix(resourceName, name):
+		return round2(0.8 + 0.15*coverage), "prefix"
+	case strings.Contains(resourceName, name):
+		return round2(0.7 + 0.15*coverage), "substring"
+	}
+	best := similarity(name, resourceName)
+	for _, part := range strings.Split(resourceName, "-") {
+		// A misspelled part is worth a bit less than the whole name.
+		best = max(best, 0.9*similarity(name, part))
+	}
+	return round2(0.7 * best), "similar"
+}
+
+// similarity is 1 minus the edit distance between a and b relative to the length of the longer one.
+func similarity(a, b string) float64 {
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(editDistance(a, b))/float64(longest)
+}
+
+// editDistance returns the Levenshtein distance between a and b, in bytes.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+// round2 rounds the score to two decimals, to keep results readable.
+func round2(score float64) float64 {
+	return float64(int(score*100+0.5)) / 100
+}
+
+func (t *ResolveResourceTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *ResolveResourceTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,99 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResourceColumns(t *testing.T) {
+	output := `Deployment   shop     payments-api
+Pod          shop     payments-api-7d9f8-x2k4q
+Node         <none>   node-1
+`
+	expected := []ResourceCandidate{
+		{Kind: "deployment", Namespace: "shop", Name: "payments-api"},
+		{Kind: "pod", Namespace: "shop", Name: "payments-api-7d9f8-x2k4q"},
+		{Kind: "node", Name: "node-1"},
+	}
+	if got := parseResourceColumns(output); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+}
+
+func TestRankResources(t *testing.T) {
+	resources := []ResourceCandidate{
+		{Kind: "deployment", Name: "nginx"},
+		{Kind: "deployment", Name: "nginx-ingress-controller"},
+		{Kind: "deployment", Name: "payments-api"},
+		{Kind: "deployment", Name: "legacy-nginx"},
+		{Kind: "service", Name: "redis"},
+	}
+	testCases := []struct {
+		name     string
+		expected []ResourceCandidate
+	}{
+		{
+			name: "nginx",
+			expected: []ResourceCandidate{
+				{Kind: "deployment", Name: "nginx", Score: 1, Match: "exact"},
+				{Kind: "deployment", Name: "nginx-ingress-controller", Score: 0.83, Match: "prefix"},
+				{Kind: "deployment", Name: "legacy-nginx", Score: 0.76, Match: "substring"},
+			},
+		},
+		{
+			name: "paymnets",
+			expected: []ResourceCandidate{
+				{Kind: "deployment", Name: "payments-api", Score: 0.47, Match: "similar"},
+			},
+		},
+		{
+			name: "redsi",
+			expected: []ResourceCandidate{
+				{Kind: "service", Name: "redis", Score: 0.42, Match: "similar"},
+			},
+		},
+		{
+			name:     "mysql",
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := rankResources(tc.name, resources); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a, b     string
+		expected int
+	}{
+		{a: "", b: "web", expected: 3},
+		{a: "web", b: "web", expected: 0},
+		{a: "paymnets", b: "payments", expected: 2},
+		{a: "kitten", b: "sitting", expected: 3},
+	}
+	for _, tc := range testCases {
+		if got := editDistance(tc.a, tc.b); got != tc.expected {
+			t.Errorf("editDistance(%q, %q): expected %d, got %d", tc.a, tc.b, tc.expected, got)
+		}
+	}
+}
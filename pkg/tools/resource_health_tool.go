@@ -0,0 +1,107 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&ResourceHealthTool{})
+}
+
+// ResourceHealthTool interprets the health of (custom) resources using health rules.
+type ResourceHealthTool struct{}
+
+func (t *ResourceHealthTool) Name() string {
+	return "check_resource_health"
+}
+
+func (t *ResourceHealthTool) Description() string {
+	return `Interprets the health of Kubernetes resources, including custom resources managed by operators (e.g. Kafka, Postgres, cert-manager certificates),
+using health rules that map their status and conditions to a health state (Healthy, Progressing, Degraded, Unhealthy or Unknown).
+Prefer this tool over reading the raw status of custom resources whose status conventions you are not sure about.`
+}
+
+func (t *ResourceHealthTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"resource": {
+					Type:        gollm.TypeString,
+					Description: `The resource(s) to check, in kubectl format, e.g. "kafkas.kafka.strimzi.io/my-cluster" or "certificates".`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the resources. Defaults to the current namespace.`,
+				},
+			},
+			Required: []string{"resource"},
+		},
+	}
+}
+
+// ResourceHealthReport is the result of the check_resource_health tool.
+type ResourceHealthReport struct {
+	Resources []HealthEvaluation `json:"resources,omitempty"`
+	Error     string             `json:"error,omitempty"`
+}
+
+func (t *ResourceHealthTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ResourceHealthReport]()
+}
+
+func (t *ResourceHealthTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	resource := stringArg(args, "resource")
+	if resource == "" {
+		return &ResourceHealthReport{Error: "resource must be specified"}, nil
+	}
+	kubectlArgs := []string{resource}
+	if namespace := stringArg(args, "namespace"); namespace != "" {
+		kubectlArgs = append(kubectlArgs, "--namespace", namespace)
+	}
+
+	obj := make(map[string]any)
+	if err := kubectlGetJSON(ctx, &obj, kubectlArgs...); err != nil {
+		return &ResourceHealthReport{Error: err.Error()}, nil
+	}
+
+	report := &ResourceHealthReport{}
+	kind, _ := obj["kind"].(string)
+	if items, ok := obj["items"].([]any); ok && strings.HasSuffix(kind, "List") {
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok {
+				report.Resources = append(report.Resources, evaluateHealth(m))
+			}
+		}
+	} else {
+		report.Resources = append(report.Resources, evaluateHealth(obj))
+	}
+	return report, nil
+}
+
+func (t *ResourceHealthTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *ResourceHealthTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,497 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&ResourceUsageTool{})
+}
+
+// How resource_usage sorts the pods and nodes, the highest first.
+const (
+	sortUsageCPU         = "cpu"
+	sortUsageMemory      = "memory"
+	sortUsageCPULimit    = "cpu_limit"
+	sortUsageMemoryLimit = "memory_limit"
+)
+
+const (
+	// defaultUsageLimit is how many rows resource_usage returns if no limit is given, and maxUsageLimit the most.
+	defaultUsageLimit = 20
+	maxUsageLimit     = 200
+)
+
+// ResourceUsageTool reports the CPU and memory used by nodes or pods, from metrics-server (kubectl top),
+// as numbers, and for pods how close they are to their requests and limits.
+type ResourceUsageTool struct{}
+
+func (t *ResourceUsageTool) Name() string {
+	return "resource_usage"
+}
+
+func (t *ResourceUsageTool) Description() string {
+	return `Reports the current CPU and memory usage of nodes or pods from metrics-server (kubectl top), as numbers
+(millicores and bytes). For pods, or their containers, it also gives their requests and limits, and their usage as a
+percentage of the limits; for nodes, the usage as a percentage of what is allocatable. The rows are sorted, the
+highest first, by CPU, memory, or closeness to the CPU or memory limit.
+Use this tool for questions such as "which pods are closest to their memory limits" or "which nodes are the busiest".`
+}
+
+func (t *ResourceUsageTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"kind": {
+					Type:        gollm.TypeString,
+					Description: `What to report on: "pods" (the default) or "nodes".`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the pods. Defaults to the namespace of the current context.`,
+				},
+				"all_namespaces": {
+					Type:        gollm.TypeBoolean,
+					Description: `Report on the pods of all namespaces.`,
+				},
+				"selector": {
+					Type:        gollm.TypeString,
+					Description: `A label selector for the pods or nodes, e.g. "app=web".`,
+				},
+				"containers": {
+					Type:        gollm.TypeBoolean,
+					Description: `Report on each container of the pods rather than on the pods as a whole.`,
+				},
+				"sort_by": {
+					Type:        gollm.TypeString,
+					Description: `How to sort the rows, the highest first: "memory" (the default), "cpu", "memory_limit" or "cpu_limit" (usage as a percentage of the limit; pods without a limit come last).`,
+				},
+				"limit": {
+					Type:        gollm.TypeInteger,
+					Description: fmt.Sprintf(`How many rows to return. Defaults to %d; at most %d.`, defaultUsageLimit, maxUsageLimit),
+				},
+			},
+		},
+	}
+}
+
+// ResourceUsage is the usage of a node, pod or container. Usage is in millicores of CPU and bytes of memory.
+type ResourceUsage struct {
+	Namespace string `json:"namespace,omitempty"`
+	// Name is the name of the node or pod.
+	Name string `json:"name"`
+	// Container is the name of the container, when reporting on containers.
+	Container string `json:"container,omitempty"`
+
+	CPUMillicores int64 `json:"cpu_millicores"`
+	MemoryBytes   int64 `json:"memory_bytes"`
+	// CPU and Memory are the usage as reported by kubectl top, e.g. "250m" and "512Mi".
+	CPU    string `json:"cpu"`
+	Memory string `json:"memory"`
+
+	// The requests and limits of pods and containers; 0 if they are not set (for a pod, on any of its containers).
+	CPURequestMillicores int64 `json:"cpu_request_millicores,omitempty"`
+	CPULimitMillicores   int64 `json:"cpu_limit_millicores,omitempty"`
+	MemoryRequestBytes   int64 `json:"memory_request_bytes,omitempty"`
+	MemoryLimitBytes     int64 `json:"memory_limit_bytes,omitempty"`
+
+	// CPUPercent and MemoryPercent are the usage as a percentage of the limits for pods and containers,
+	// and of what is allocatable for nodes; they are omitted if there is no limit.
+	CPUPercent    float64 `json:"cpu_percent,omitempty"`
+	MemoryPercent float64 `json:"memory_percent,omitempty"`
+}
+
+// ResourceUsageResult is the result of the resource_usage tool.
+type ResourceUsageResult struct {
+	// Command is the kubectl top command that was run.
+	Command string `json:"command"`
+	// Rows are the nodes, pods or containers, sorted.
+	Rows []ResourceUsage `json:"rows"`
+	// Listed is how many rows kubectl top returned; Truncated is set if only the first of them are returned.
+	Listed    int    `json:"listed"`
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (t *ResourceUsageTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*ResourceUsageResult]()
+}
+
+// usageRequest is a parsed resource_usage call.
+type usageRequest struct {
+	nodes         bool
+	namespace     string
+	allNamespaces bool
+	selector      string
+	containers    bool
+	sortBy        string
+	limit         int
+}
+
+func parseUsageArgs(args map[string]any) (*usageRequest, error) {
+	r := &usageRequest{
+		namespace:     stringArg(args, "namespace"),
+		allNamespaces: boolArg(args, "all_namespaces"),
+		selector:      stringArg(args, "selector"),
+		containers:    boolArg(args, "containers"),
+		sortBy:        sortUsageMemory,
+		limit:         defaultUsageLimit,
+	}
+	switch kind := strings.ToLower(stringArg(args, "kind")); kind {
+	case "", "pod", "pods":
+	case "node", "nodes":
+		r.nodes = true
+	default:
+		return nil, fmt.Errorf("unknown kind %q: use pods or nodes", kind)
+	}
+	if sortBy := stringArg(args, "sort_by"); sortBy != "" {
+		switch sortBy {
+		case sortUsageCPU, sortUsageMemory, sortUsageCPULimit, sortUsageMemoryLimit:
+			r.sortBy = sortBy
+		default:
+			return nil, fmt.Errorf("unknown sort_by %q: use memory, cpu, memory_limit or cpu_limit", sortBy)
+		}
+	}
+	if limit, ok := intArg(args, "limit"); ok && limit > 0 {
+		r.limit = min(limit, maxUsageLimit)
+	}
+	return r, nil
+}
+
+// scopeArgs returns the kubectl arguments that select the pods or nodes.
+func (r *usageRequest) scopeArgs() []string {
+	var args []string
+	if !r.nodes {
+		if r.allNamespaces {
+			args = append(args, "--all-namespaces")
+		} else if r.namespace != "" {
+			args = append(args, "--namespace", r.namespace)
+		}
+	}
+	if r.selector != "" {
+		args = append(args, "--selector", r.selector)
+	}
+	return args
+}
+
+// topArgs returns the arguments of the kubectl top command.
+func (r *usageRequest) topArgs() []string {
+	if r.nodes {
+		return append([]string{"top", "nodes", "--no-headers"}, r.scopeArgs()...)
+	}
+	args := []string{"top", "pods", "--no-headers"}
+	if r.containers {
+		args = append(args, "--containers")
+	}
+	return append(args, r.scopeArgs()...)
+}
+
+// parseCPUMillis parses a CPU quantity, e.g. "250m", "2" or "1500000n", into millicores.
+func parseCPUMillis(quantity string) (int64, error) {
+	quantity = strings.TrimSpace(quantity)
+	scale := 1000.0
+	for suffix, s := range map[string]float64{"m": 1, "u": 1e-3, "n": 1e-6} {
+		if number, ok := strings.CutSuffix(quantity, suffix); ok {
+			quantity, scale = number, s
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(quantity, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid CPU quantity %q", quantity)
+	}
+	return int64(math.Ceil(value * scale)), nil
+}
+
+// memorySuffixes are the suffixes of memory quantities, the longest first so that "Mi" is tried before "M".
+var memorySuffixes = []struct {
+	suffix string
+	scale  float64
+}{
+	{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30}, {"Ti", 1 << 40}, {"Pi", 1 << 50},
+	{"k", 1e3}, {"K", 1e3}, {"M", 1e6}, {"G", 1e9}, {"T", 1e12}, {"P", 1e15},
+}
+
+// parseMemoryBytes parses a memory quantity, e.g. "512Mi", "1G" or "1048576", into bytes.
+func parseMemoryBytes(quantity string) (int64, error) {
+	number, scale := strings.TrimSpace(quantity), 1.0
+	for _, s := range memorySuffixes {
+		if n, ok := strings.CutSuffix(number, s.suffix); ok {
+			number, scale = n, s.scale
+			break
+		}
+	}
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid memory quantity %q", quantity)
+	}
+	return int64(value * scale), nil
+}
+
+// parseTopNodes parses the output of kubectl top nodes --no-headers: the name, CPU, CPU%, memory and memory% of
+// each node. Nodes whose usage is unknown are skipped.
+func parseTopNodes(output string) ([]ResourceUsage, error) {
+	var rows []ResourceUsage
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 5 {
+			return nil, fmt.Errorf("unexpected line in kubectl top output: %q", line)
+		}
+		if slices.Contains(fields, "<unknown>") {
+			continue
+		}
+		row, err := newUsage(fields[1], fields[3])
+		if err != nil {
+			return nil, err
+		}
+		row.Name = fields[0]
+		row.CPUPercent, _ = strconv.ParseFloat(strings.TrimSuffix(fields[2], "%"), 64)
+		row.MemoryPercent, _ = strconv.ParseFloat(strings.TrimSuffix(fields[4], "%"), 64)
+		rows = append(rows, *row)
+	}
+	return rows, nil
+}
+
+// parseTopPods parses the output of kubectl top pods --no-headers, whose columns are the namespace (with
+// --all-namespaces), the pod, the container (with --containers), the CPU and the memory.
+func parseTopPods(output string, allNamespaces, containers bool) ([]ResourceUsage, error) {
+	columns := 3
+	if allNamespaces {
+		columns++
+	}
+	if containers {
+		columns++
+	}
+	var rows []ResourceUsage
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != columns {
+			return nil, fmt.Errorf("unexpected line in kubectl top output: %q", line)
+		}
+		row, err := newUsage(fields[columns-2], fields[columns-1])
+		if err != nil {
+			return nil, err
+		}
+		if allNamespaces {
+			row.Namespace, fields = fields[0], fields[1:]
+		}
+		row.Name = fields[0]
+		if containers {
+			row.Container = fields[1]
+		}
+		rows = append(rows, *row)
+	}
+	return rows, nil
+}
+
+// newUsage returns the usage of CPU and memory quantities.
+func newUsage(cpu, memory string) (*ResourceUsage, error) {
+	cpuMillis, err := parseCPUMillis(cpu)
+	if err != nil {
+		return nil, err
+	}
+	memoryBytes, err := parseMemoryBytes(memory)
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceUsage{CPU: cpu, Memory: memory, CPUMillicores: cpuMillis, MemoryBytes: memoryBytes}, nil
+}
+
+// podResources are the requests and limits of a pod or container, 0 where they are not set.
+type podResources struct {
+	cpuRequest, cpuLimit, memoryRequest, memoryLimit int64
+}
+
+// containerResources returns the requests and limits of a container.
+func containerResources(c container) podResources {
+	var r podResources
+	if c.Resources == nil {
+		return r
+	}
+	r.cpuRequest, _ = parseCPUMillis(c.Resources.Requests["cpu"])
+	r.cpuLimit, _ = parseCPUMillis(c.Resources.Limits["cpu"])
+	r.memoryRequest, _ = parseMemoryBytes(c.Resources.Requests["memory"])
+	r.memoryLimit, _ = parseMemoryBytes(c.Resources.Limits["memory"])
+	return r
+}
+
+// addResources adds the requests and limits of the pods to the rows of their pods or containers, and the usage as
+// a percentage of the limits. The requests and limits of a pod are the sums of those of its containers; a pod has
+// no limit if any of its containers has none, as it can then use as much as the node allows.
+func addResources(rows []ResourceUsage, pods []podObject) {
+	type key struct{ namespace, name, container string }
+	byKey := map[key]podResources{}
+	// Without --all-namespaces, kubectl top doesn't print the namespace, which we take from the pods.
+	namespaces := map[string]string{}
+	for _, pod := range pods {
+		namespace, name := pod.Metadata.Namespace, pod.Metadata.Name
+		namespaces[name] = namespace
+		var total podResources
+		noCPULimit, noMemoryLimit := false, false
+		for _, c := range pod.Spec.Containers {
+			r := containerResources(c)
+			byKey[key{namespace, name, c.Name}] = r
+			total.cpuRequest += r.cpuRequest
+			total.memoryRequest += r.memoryRequest
+			total.cpuLimit += r.cpuLimit
+			total.memoryLimit += r.memoryLimit
+			noCPULimit = noCPULimit || r.cpuLimit == 0
+			noMemoryLimit = noMemoryLimit || r.memoryLimit == 0
+		}
+		if noCPULimit {
+			total.cpuLimit = 0
+		}
+		if noMemoryLimit {
+			total.memoryLimit = 0
+		}
+		byKey[key{namespace, name, ""}] = total
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		if row.Namespace == "" {
+			row.Namespace = namespaces[row.Name]
+		}
+		r, ok := byKey[key{row.Namespace, row.Name, row.Container}]
+		if !ok {
+			continue
+		}
+		row.CPURequestMillicores, row.CPULimitMillicores = r.cpuRequest, r.cpuLimit
+		row.MemoryRequestBytes, row.MemoryLimitBytes = r.memoryRequest, r.memoryLimit
+		if r.cpuLimit > 0 {
+			row.CPUPercent = percent(row.CPUMillicores, r.cpuLimit)
+		}
+		if r.memoryLimit > 0 {
+			row.MemoryPercent = percent(row.MemoryBytes, r.memoryLimit)
+		}
+	}
+}
+
+// percent returns part as a percentage of whole, rounded to one decimal.
+func percent(part, whole int64) float64 {
+	return math.Round(float64(part)*1000/float64(whole)) / 10
+}
+
+// sortUsage sorts the rows, the highest first. For nodes, the limits are what is allocatable.
+// Rows without a limit come last when sorting by closeness to the limit.
+func sortUsage(rows []ResourceUsage, sortBy string) {
+	value := func(row ResourceUsage) float64 {
+		switch sortBy {
+		case sortUsageCPU:
+			return float64(row.CPUMillicores)
+		case sortUsageCPULimit:
+			return row.CPUPercent
+		case sortUsageMemoryLimit:
+			return row.MemoryPercent
+		default:
+			return float64(row.MemoryBytes)
+		}
+	}
+	slices.SortStableFunc(rows, func(a, b ResourceUsage) int {
+		if c := compareFloats(value(b), value(a)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Namespace+"/"+a.Name+"/"+a.Container, b.Namespace+"/"+b.Name+"/"+b.Container)
+	})
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func (t *ResourceUsageTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &ResourceUsageResult{}
+	request, err := parseUsageArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+	topArgs := request.topArgs()
+	result.Command = "kubectl " + strings.Join(topArgs, " ")
+
+	output, err := runKubectl(ctx, topArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if output.Error != "" || output.ExitCode != 0 {
+		result.Error = strings.TrimSpace(output.Error + " " + output.Stderr)
+		if strings.Contains(result.Error, "Metrics API not available") {
+			result.Error += " (metrics-server is probably not installed in the cluster)"
+		}
+		return result, nil
+	}
+
+	if request.nodes {
+		result.Rows, err = parseTopNodes(output.Stdout)
+	} else {
+		result.Rows, err = parseTopPods(output.Stdout, request.allNamespaces, request.containers)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	if !request.nodes && len(result.Rows) > 0 {
+		var pods struct {
+			Items []podObject `json:"items"`
+		}
+		if err := kubectlGetJSON(ctx, &pods, append([]string{"pods"}, request.scopeArgs()...)...); err != nil {
+			// The usage is still useful without the limits.
+			result.Error = fmt.Sprintf("could not read the requests and limits of the pods: %v", err)
+		} else {
+			addResources(result.Rows, pods.Items)
+		}
+	}
+
+	result.Listed = len(result.Rows)
+	sortUsage(result.Rows, request.sortBy)
+	if len(result.Rows) > request.limit {
+		result.Rows = result.Rows[:request.limit]
+		result.Truncated = true
+	}
+	return result, nil
+}
+
+func (t *ResourceUsageTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *ResourceUsageTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuantities(t *testing.T) {
+	cpuCases := map[string]int64{"250m": 250, "2": 2000, "0.5": 500, "1500000n": 2, "0": 0}
+	for quantity, expected := range cpuCases {
+		if got, err := parseCPUMillis(quantity); err != nil || got != expected {
+			t.Errorf("parseCPUMillis(%q): expected %d, got %d, %v", quantity, expected, got, err)
+		}
+	}
+	memoryCases := map[string]int64{"512Mi": 512 << 20, "1Gi": 1 << 30, "1G": 1e9, "100k": 1e5, "1048576": 1 << 20, "1.5Gi": 3 << 29}
+	for quantity, expected := range memoryCases {
+		if got, err := parseMemoryBytes(quantity); err != nil || got != expected {
+			t.Errorf("parseMemoryBytes(%q): expected %d, got %d, %v", quantity, expected, got, err)
+		}
+	}
+	for _, invalid := range []string{"", "lots", "-1"} {
+		if _, err := parseCPUMillis(invalid); err == nil {
+			t.Errorf("parseCPUMillis(%q): expected an error", invalid)
+		}
+		if _, err := parseMemoryBytes(invalid); err == nil {
+			t.Errorf("parseMemoryBytes(%q): expected an error", invalid)
+		}
+	}
+}
+
+func TestParseTopNodes(t *testing.T) {
+	output := `node-a   250m   12%   1024Mi   25%
+node-b   <unknown>   <unknown>   <unknown>   <unknown>
+node-c   1500m   75%   3Gi   80%
+`
+	rows, err := parseTopNodes(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ResourceUsage{
+		{Name: "node-a", CPU: "250m", Memory: "1024Mi", CPUMillicores: 250, MemoryBytes: 1 << 30, CPUPercent: 12, MemoryPercent: 25},
+		{Name: "node-c", CPU: "1500m", Memory: "3Gi", CPUMillicores: 1500, MemoryBytes: 3 << 30, CPUPercent: 75, MemoryPercent: 80},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rows)
+	}
+}
+
+func TestPodUsage(t *testing.T) {
+	output := `shop   web-0   app       100m   900Mi
+shop   web-0   sidecar   5m     20Mi
+shop   db-0    db        300m   1Gi
+`
+	rows, err := parseTopPods(output, true, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pods := []podObject{
+		{
+			Metadata: objectMeta{Name: "web-0", Namespace: "shop"},
+			Spec: podSpec{Containers: []container{
+				{Name: "app", Resources: &resources{Requests: map[string]string{"memory": "512Mi"}, Limits: map[string]string{"cpu": "500m", "memory": "1Gi"}}},
+				{Name: "sidecar", Resources: &resources{Limits: map[string]string{"memory": "64Mi"}}},
+			}},
+		},
+		{
+			Metadata: objectMeta{Name: "db-0", Namespace: "shop"},
+			Spec:     podSpec{Containers: []container{{Name: "db"}}},
+		},
+	}
+	addResources(rows, pods)
+	sortUsage(rows, sortUsageMemoryLimit)
+
+	expected := []ResourceUsage{
+		{Namespace: "shop", Name: "web-0", Container: "app", CPU: "100m", Memory: "900Mi", CPUMillicores: 100, MemoryBytes: 900 << 20,
+			CPULimitMillicores: 500, MemoryRequestBytes: 512 << 20, MemoryLimitBytes: 1 << 30, CPUPercent: 20, MemoryPercent: 87.9},
+		{Namespace: "shop", Name: "web-0", Container: "sidecar", CPU: "5m", Memory: "20Mi", CPUMillicores: 5, MemoryBytes: 20 << 20,
+			MemoryLimitBytes: 64 << 20, MemoryPercent: 31.3},
+		{Namespace: "shop", Name: "db-0", Container: "db", CPU: "300m", Memory: "1Gi", CPUMillicores: 300, MemoryBytes: 1 << 30},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rows)
+	}
+
+	// Pods add up the limits of their containers, and have no limit if one of them has none.
+	rows, err = parseTopPods("web-0   105m   920Mi\n", false, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addResources(rows, pods)
+	expected = []ResourceUsage{
+		{Namespace: "shop", Name: "web-0", CPU: "105m", Memory: "920Mi", CPUMillicores: 105, MemoryBytes: 920 << 20,
+			MemoryRequestBytes: 512 << 20, MemoryLimitBytes: 1088 << 20, CPULimitMillicores: 0, MemoryPercent: 84.6},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected %+v, got %+v", expected, rows)
+	}
+}
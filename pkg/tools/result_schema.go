@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// HasResultSchema is implemented by tools whose results have a fixed shape.
+// The schema is given to the LLM so it can rely on the fields of the result, and results are checked against it.
+type HasResultSchema interface {
+	// ResultSchema describes the result of the tool, as it is sent to the LLM (i.e. converted to JSON).
+	ResultSchema() *gollm.Schema
+}
+
+// ResultSchema returns the schema of the results of the tool, or nil if the tool does not declare one.
+func ResultSchema(tool Tool) *gollm.Schema {
+	if t, ok := tool.(HasResultSchema); ok {
+		return t.ResultSchema()
+	}
+	return nil
+}
+
+// resultSchemaFor builds the schema of a result type from its JSON tags.
+func resultSchemaFor[T any]() *gollm.Schema {
+	return gollm.BuildSchemaFor(reflect.TypeFor[T]())
+}
+
+// ValidateResult checks that the result of a tool, converted to JSON as it is sent to the LLM, matches the schema.
+func ValidateResult(schema *gollm.Schema, result any) error {
+	m, err := ToolResultToMap(result)
+	if err != nil {
+		return err
+	}
+	return validateValue(schema, m, "result")
+}
+
+// validateValue checks a value decoded from JSON against the schema; path identifies the value in errors.
+func validateValue(schema *gollm.Schema, value any, path string) error {
+	if schema == nil {
+		return nil
+	}
+	switch schema.Type {
+	case gollm.TypeObject:
+		if value == nil {
+			// nil maps and pointers are marshaled as null.
+			return nil
+		}
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object, got %T", path, value)
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required field %q", path, name)
+			}
+		}
+		for name, property := range schema.Properties {
+			if v, ok := obj[name]; ok {
+				if err := validateValue(property, v, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case gollm.TypeArray:
+		if value == nil {
+			return nil
+		}
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array, got %T", path, value)
+		}
+		for i, item := range items {
+			if err := validateValue(schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case gollm.TypeString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected a string, got %T", path, value)
+		}
+	case gollm.TypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean, got %T", path, value)
+		}
+	case gollm.TypeNumber:
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected a number, got %T", path, value)
+		}
+	case gollm.TypeInteger:
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) {
+			return fmt.Errorf("%s: expected an integer, got %v", path, value)
+		}
+	}
+	return nil
+}
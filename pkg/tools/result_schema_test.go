@@ -0,0 +1,103 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		tool    Tool
+		result  any
+		wantErr bool
+	}{
+		{
+			name:   "exec result",
+			tool:   &Kubectl{},
+			result: &ExecResult{Command: "kubectl get pods", Stdout: "No resources found", ExitCode: 1},
+		},
+		{
+			name:   "pipeline result",
+			tool:   &PipelineTool{},
+			result: &PipelineResult{Output: "web-1", Steps: []string{"kubectl get pods"}},
+		},
+		{
+			name: "nested result",
+			tool: &PodSecurityAuditTool{},
+			result: &PodSecurityAuditReport{
+				Level: "restricted",
+				Workloads: []PodSecurityWorkloadResult{{
+					Kind:                      "Deployment",
+					Name:                      "web",
+					Violations:                []PodSecurityViolation{{Check: "privileged", Message: "privileged container"}},
+					SuggestedSecurityContexts: map[string]map[string]any{"web": {"privileged": false}},
+				}},
+			},
+		},
+		{
+			name:    "missing required field",
+			tool:    &PipelineTool{},
+			result:  map[string]any{"steps": []string{"kubectl get pods"}},
+			wantErr: true,
+		},
+		{
+			name:    "wrong type",
+			tool:    &Kubectl{},
+			result:  map[string]any{"stdout": 42},
+			wantErr: true,
+		},
+		{
+			name:    "not an integer",
+			tool:    &Kubectl{},
+			result:  map[string]any{"exit_code": 1.5},
+			wantErr: true,
+		},
+		{
+			name:    "plain string",
+			tool:    &PipelineTool{},
+			result:  "web-1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateResult(ResultSchema(tt.tool), tt.result)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateResult() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResultSchemaRequired(t *testing.T) {
+	tests := []struct {
+		tool Tool
+		want []string
+	}{
+		{tool: &Kubectl{}, want: nil},
+		{tool: &PipelineTool{}, want: []string{"output"}},
+		{tool: &BulkMetadataTool{}, want: []string{"operation", "applied"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool.Name(), func(t *testing.T) {
+			if got := ResultSchema(tt.tool).Required; !slices.Equal(got, tt.want) {
+				t.Errorf("ResultSchema(%s).Required = %q, want %q", tt.tool.Name(), got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+// maxSlimDescription is the length in bytes above which slimmed descriptions are cut.
+const maxSlimDescription = 160
+
+// SlimFunctionDefinition returns a copy of the function definition with shorter descriptions, to keep sessions
+// with many tools (MCP servers in particular) within the limits providers put on the size of tool schemas.
+// Descriptions are cut to their first sentence, and lists of quoted values, which is how our schemas give
+// enums, are collapsed to the values.
+func SlimFunctionDefinition(definition *gollm.FunctionDefinition) *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        definition.Name,
+		Description: slimDescription(definition.Description),
+		Parameters:  slimSchema(definition.Parameters),
+	}
+}
+
+func slimSchema(schema *gollm.Schema) *gollm.Schema {
+	if schema == nil {
+		return nil
+	}
+	slim := &gollm.Schema{
+		Type:        schema.Type,
+		Items:       slimSchema(schema.Items),
+		Description: slimDescription(schema.Description),
+		Required:    schema.Required,
+	}
+	if len(schema.Properties) > 0 {
+		slim.Properties = make(map[string]*gollm.Schema, len(schema.Properties))
+		for name, property := range schema.Properties {
+			slim.Properties[name] = slimSchema(property)
+		}
+	}
+	return slim
+}
+
+// slimDescription returns the first sentence of the first paragraph of the description, with lists of quoted
+// values collapsed, and cut to maxSlimDescription bytes.
+func slimDescription(description string) string {
+	paragraph, _, _ := strings.Cut(strings.TrimSpace(description), "\n\n")
+	description = strings.Join(strings.Fields(paragraph), " ")
+	description = collapseEnums(firstSentence(description))
+	if len(description) <= maxSlimDescription {
+		return description
+	}
+	cut := description[:maxSlimDescription]
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRightFunc(cut, func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSpace(r) }) + "…"
+}
+
+// abbreviations end with a period that does not end a sentence.
+var abbreviations = []string{"e.g.", "i.e.", "etc.", "vs."}
+
+// firstSentence returns the first sentence of the text.
+func firstSentence(text string) string {
+	for i := 0; i < len(text); i++ {
+		if !strings.ContainsRune(".!?", rune(text[i])) || (i+1 < len(text) && text[i+1] != ' ') {
+			continue
+		}
+		abbreviation := false
+		for _, a := range abbreviations {
+			if strings.HasSuffix(text[:i+1], a) {
+				abbreviation = true
+			}
+		}
+		if !abbreviation {
+			return text[:i+1]
+		}
+	}
+	return text
+}
+
+// enumList matches a list of two or more quoted values, each maybe followed by a remark in parentheses, e.g.
+// `"memory" (the default), "cpu" or "memory_limit"`.
+var enumList = regexp.MustCompile(`"[^"\s]{1,40}"(?: \([^()]*\))?(?:(?:,? or |, )"[^"\s]{1,40}"(?: \([^()]*\))?)+`)
+
+// enumValue matches a quoted value of an enumList.
+var enumValue = regexp.MustCompile(`"[^"\s]{1,40}"`)
+
+// collapseEnums collapses lists of quoted values to the values separated by |, e.g. `"memory"|"cpu"|"memory_limit"`.
+// JSON arrays, such as `["pods", "services"]`, are left as they are.
+func collapseEnums(text string) string {
+	var collapsed strings.Builder
+	end := 0
+	for _, match := range enumList.FindAllStringIndex(text, -1) {
+		if match[0] > 0 && text[match[0]-1] == '[' {
+			continue
+		}
+		collapsed.WriteString(text[end:match[0]])
+		collapsed.WriteString(strings.Join(enumValue.FindAllString(text[match[0]:match[1]], -1), "|"))
+		end = match[1]
+	}
+	collapsed.WriteString(text[end:])
+	return collapsed.String()
+}
+
+// FunctionDefinitionsSize returns the size in bytes of the function definitions, as JSON.
+// Providers serialize them differently, but their sizes are close.
+func FunctionDefinitionsSize(definitions ...*gollm.FunctionDefinition) int {
+	b, err := json.Marshal(definitions)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func TestSlimDescription(t *testing.T) {
+	testCases := []struct {
+		name        string
+		description string
+		expected    string
+	}{
+		{
+			name:        "first sentence",
+			description: "Reads the logs of a pod, e.g. the web pod. Give either pod or selector.",
+			expected:    "Reads the logs of a pod, e.g. the web pod.",
+		},
+		{
+			name:        "first paragraph",
+			description: "  Runs a command\nin a pod\n\nThe command runs with a timeout.",
+			expected:    "Runs a command in a pod",
+		},
+		{
+			name:        "enum with remarks",
+			description: `How to sort the rows, the highest first: "memory" (the default), "cpu", "memory_limit" or "cpu_limit" (usage as a percentage of the limit).`,
+			expected:    `How to sort the rows, the highest first: "memory"|"cpu"|"memory_limit"|"cpu_limit".`,
+		},
+		{
+			name:        "two values",
+			description: `Either "label" or "annotate".`,
+			expected:    `Either "label"|"annotate".`,
+		},
+		{
+			name:        "JSON array",
+			description: `Only look at these resource types, e.g. ["deployments", "configmaps"].`,
+			expected:    `Only look at these resource types, e.g. ["deployments", "configmaps"].`,
+		},
+		{
+			name:        "long",
+			description: strings.Repeat("word ", 40) + "end.",
+			expected:    strings.TrimSpace(strings.Repeat("word ", 32)) + "…",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := slimDescription(tc.description); got != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSlimFunctionDefinition(t *testing.T) {
+	definition := &gollm.FunctionDefinition{
+		Name:        "pod_logs",
+		Description: "Reads the logs of a pod. The logs are truncated.",
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"pod": {Type: gollm.TypeString, Description: "The name of the pod. Required."},
+				"containers": {
+					Type:  gollm.TypeArray,
+					Items: &gollm.Schema{Type: gollm.TypeString, Description: "A container. Any container."},
+				},
+			},
+			Required: []string{"pod"},
+		},
+	}
+	expected := &gollm.FunctionDefinition{
+		Name:        "pod_logs",
+		Description: "Reads the logs of a pod.",
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"pod": {Type: gollm.TypeString, Description: "The name of the pod."},
+				"containers": {
+					Type:  gollm.TypeArray,
+					Items: &gollm.Schema{Type: gollm.TypeString, Description: "A container."},
+				},
+			},
+			Required: []string{"pod"},
+		},
+	}
+	slim := SlimFunctionDefinition(definition)
+	if !reflect.DeepEqual(slim, expected) {
+		t.Errorf("expected %+v, got %+v", expected, slim)
+	}
+	if FunctionDefinitionsSize(slim) >= FunctionDefinitionsSize(definition) {
+		t.Errorf("expected the slimmed definition to be smaller")
+	}
+	if definition.Description != "Reads the logs of a pod. The logs are truncated." {
+		t.Errorf("expected the definition to be left unchanged, got description %q", definition.Description)
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&SessionResourcesTool{})
+}
+
+// sessionResourcesTemplate prints the kind, namespace, name and annotations of each object, separated by tabs.
+const sessionResourcesTemplate = `{range .items[*]}{.kind}{"\t"}{.metadata.namespace}{"\t"}{.metadata.name}{"\t"}{.metadata.annotations}{"\n"}{end}`
+
+// SessionResourcesTool lists the objects that a session created or modified, from their attribution annotations.
+type SessionResourcesTool struct{}
+
+func (t *SessionResourcesTool) Name() string {
+	return "list_session_resources"
+}
+
+func (t *SessionResourcesTool) Description() string {
+	return `Lists the objects in the cluster that a kubectl-ai session created or modified, with who changed them and when,
+from the ` + SessionIDAnnotation + ` annotations that the agent adds to the objects it changes.
+Use this tool to review or roll back what a session did. Deleted objects are not listed.`
+}
+
+func (t *SessionResourcesTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"session_id": {
+					Type:        gollm.TypeString,
+					Description: `The ID of the session. Defaults to the current session.`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `Only list the objects in this namespace. Defaults to all namespaces, including cluster-scoped objects.`,
+				},
+				"resources": {
+					Type:        gollm.TypeArray,
+					Items:       &gollm.Schema{Type: gollm.TypeString},
+					Description: `Only look at these resource types, e.g. ["deployments", "configmaps"]. Defaults to all the types that can be listed, which is slower.`,
+				},
+			},
+		},
+	}
+}
+
+// SessionResourcesResult is the result of the list_session_resources tool.
+type SessionResourcesResult struct {
+	SessionID string            `json:"session_id"`
+	Resources []SessionResource `json:"resources"`
+	// Warnings are the errors reported for some resource types, e.g. that they cannot be listed.
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// SessionResource is an object changed by a session, with its attribution annotations.
+type SessionResource struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+	User      string `json:"user,omitempty"`
+	// Timestamp is when the session last changed the object.
+	Timestamp string `json:"timestamp,omitempty"`
+	QueryHash string `json:"query_hash,omitempty"`
+}
+
+func (t *SessionResourcesTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*SessionResourcesResult]()
+}
+
+func (t *SessionResourcesTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &SessionResourcesResult{SessionID: stringArg(args, "session_id"), Resources: []SessionResource{}}
+	if result.SessionID == "" {
+		if attribution := attributionFromContext(ctx); attribution != nil {
+			result.SessionID = attribution.SessionID
+		}
+	}
+	if result.SessionID == "" {
+		result.Error = "session_id is required, as the changes of the current session are not attributed"
+		return result, nil
+	}
+	namespace := stringArg(args, "namespace")
+
+	resources := stringSliceArg(args, "resources")
+	if len(resources) == 0 {
+		listArgs := []string{"api-resources", "--verbs=list,patch", "-o", "name"}
+		if namespace != "" {
+			listArgs = append(listArgs, "--namespaced=true")
+		}
+		out, err := runKubectl(ctx, listArgs...)
+		if err != nil {
+			return nil, err
+		}
+		if out.Error != "" || out.ExitCode != 0 {
+			result.Error = fmt.Sprintf("listing the resource types: %s %s", out.Error, strings.TrimSpace(out.Stderr))
+			return result, nil
+		}
+		for _, resource := range outputLines(out.Stdout) {
+			// Events are not changed by the agent, and there are many of them.
+			if resource != "events" && !strings.HasPrefix(resource, "events.") {
+				resources = append(resources, resource)
+			}
+		}
+	}
+	if len(resources) == 0 {
+		return result, nil
+	}
+
+	getArgs := []string{"get", strings.Join(resources, ","), "-o", "jsonpath=" + sessionResourcesTemplate}
+	if namespace != "" {
+		getArgs = append(getArgs, "--namespace", namespace)
+	} else {
+		getArgs = append(getArgs, "--all-namespaces")
+	}
+	out, err := runKubectl(ctx, getArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if out.Error != "" || out.ExitCode != 0 {
+		// kubectl lists the types it can, and reports the others.
+		result.Warnings = outputLines(out.Stderr)
+		if out.Stdout == "" {
+			result.Error = strings.TrimSpace(out.Error + " " + out.Stderr)
+			return result, nil
+		}
+	}
+	result.Resources = parseSessionResources(out.Stdout, result.SessionID)
+	return result, nil
+}
+
+// parseSessionResources returns the objects changed by the session in the output of sessionResourcesTemplate,
+// oldest change first.
+func parseSessionResources(output, sessionID string) []SessionResource {
+	resources := []SessionResource{}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) != 4 || fields[3] == "" {
+			continue
+		}
+		var annotations map[string]string
+		if err := json.Unmarshal([]byte(fields[3]), &annotations); err != nil || annotations[SessionIDAnnotation] != sessionID {
+			continue
+		}
+		resources = append(resources, SessionResource{
+			Kind:      fields[0],
+			Namespace: fields[1],
+			Name:      fields[2],
+			User:      annotations[UserAnnotation],
+			Timestamp: annotations[TimestampAnnotation],
+			QueryHash: annotations[QueryHashAnnotation],
+		})
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].Timestamp < resources[j].Timestamp
+	})
+	return resources
+}
+
+func (t *SessionResourcesTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *SessionResourcesTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,36 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseSessionResources(t *testing.T) {
+	output := "Deployment\tweb\tnginx\t{\"kubectl-ai/query-hash\":\"abc\",\"kubectl-ai/session-id\":\"s1\",\"kubectl-ai/timestamp\":\"2026-10-17T10:05:00Z\",\"kubectl-ai/user\":\"ana\"}\n" +
+		"ConfigMap\tweb\tsettings\t{\"kubectl-ai/session-id\":\"s2\"}\n" +
+		"Node\t\tnode-1\t{\"kubectl-ai/session-id\":\"s1\",\"kubectl-ai/timestamp\":\"2026-10-17T10:00:00Z\"}\n" +
+		"Service\tweb\tnginx\t\n"
+
+	got := parseSessionResources(output, "s1")
+	want := []SessionResource{
+		{Kind: "Node", Name: "node-1", Timestamp: "2026-10-17T10:00:00Z"},
+		{Kind: "Deployment", Name: "nginx", Namespace: "web", User: "ana", Timestamp: "2026-10-17T10:05:00Z", QueryHash: "abc"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSessionResources() = %+v, want %+v", got, want)
+	}
+}
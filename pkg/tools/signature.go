@@ -0,0 +1,205 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// ArtifactPolicy configures the verification of the sigstore (cosign) signatures of artifacts loaded from files,
+// such as custom tools, recipes and health rules, before they are loaded.
+// An artifact is signed by a signature file next to it: <file>.bundle (a sigstore bundle, as written by
+// cosign sign-blob --bundle), or <file>.sig (with <file>.pem for the certificate of a keyless signature).
+type ArtifactPolicy struct {
+	// TrustedSigners are the signers whose signatures are accepted; verification is off if there are none.
+	TrustedSigners []TrustedSigner `json:"trustedSigners,omitempty"`
+	// RequireSignatures refuses to load artifacts without a signature; otherwise they are loaded with a warning.
+	// Artifacts with a signature that cannot be verified are never loaded.
+	RequireSignatures bool `json:"requireSignatures,omitempty"`
+}
+
+// TrustedSigner is a signer whose signatures are accepted: either a public key, or the identity of keyless signatures.
+type TrustedSigner struct {
+	// Key is the public key of the signer: a file, or a KMS URI, as for cosign verify-blob --key.
+	Key string `json:"key,omitempty"`
+	// Identity is the identity in the certificate of keyless signatures, e.g. an email address or a workflow URL.
+	Identity string `json:"identity,omitempty"`
+	// Issuer is the OIDC issuer of the identity, e.g. https://accounts.google.com.
+	Issuer string `json:"issuer,omitempty"`
+}
+
+// Validate checks that the signers are well-formed.
+func (p *ArtifactPolicy) Validate() error {
+	for i, signer := range p.TrustedSigners {
+		keyless := signer.Identity != "" || signer.Issuer != ""
+		switch {
+		case signer.Key != "" && keyless:
+			return fmt.Errorf("trusted signer %d: set either key, or identity and issuer", i+1)
+		case signer.Key == "" && (signer.Identity == "" || signer.Issuer == ""):
+			return fmt.Errorf("trusted signer %d: keyless signers need both identity and issuer", i+1)
+		}
+	}
+	if p.RequireSignatures && len(p.TrustedSigners) == 0 {
+		return fmt.Errorf("requireSignatures is set, but there are no trusted signers")
+	}
+	return nil
+}
+
+// artifactPolicy is the policy that artifacts loaded from files are verified against.
+var artifactPolicy ArtifactPolicy
+
+// SetArtifactPolicy sets the policy that artifacts loaded from files are verified against.
+func SetArtifactPolicy(policy ArtifactPolicy) {
+	artifactPolicy = policy
+}
+
+// verifyTimeout bounds how long we wait for cosign, which may need to reach the transparency log.
+const verifyTimeout = 30 * time.Second
+
+// ReadArtifact reads an artifact from a file, such as a custom tool or a prompt template, after verifying its signature
+// according to the artifact policy.
+// The file is read once, and the signature of the bytes we read is verified, so that the file cannot be replaced
+// between the verification and the read.
+func ReadArtifact(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(artifactPolicy.TrustedSigners) != 0 {
+		if err := verifyArtifact(path, b); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// verifyArtifact checks that the contents of the file at path are signed by one of the trusted signers.
+// As cosign reads the blob from a file, the contents are written to a private temporary file for it.
+func verifyArtifact(path string, contents []byte) error {
+	signature := findSignature(path)
+	if signature == (artifactSignature{}) {
+		if artifactPolicy.RequireSignatures {
+			return fmt.Errorf("%s is not signed (no %s.bundle or %s.sig), and signatures are required", path, path, path)
+		}
+		klog.Warningf("Loading %s, which is not signed", path)
+		return nil
+	}
+
+	dir, err := os.MkdirTemp("", "kubectl-ai-artifact-")
+	if err != nil {
+		return fmt.Errorf("verifying the signature of %s: %w", path, err)
+	}
+	defer os.RemoveAll(dir)
+	blob := filepath.Join(dir, filepath.Base(path))
+	if err := os.WriteFile(blob, contents, 0o600); err != nil {
+		return fmt.Errorf("verifying the signature of %s: %w", path, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), verifyTimeout)
+	defer cancel()
+
+	var errs []error
+	for _, signer := range artifactPolicy.TrustedSigners {
+		cmd := exec.CommandContext(ctx, "cosign", signer.cosignArgs(blob, signature)...)
+		var output bytes.Buffer
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		err := cmd.Run()
+		if err == nil {
+			klog.Infof("Verified the signature of %s", path)
+			return nil
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("verifying the signature of %s: cosign is not installed", path)
+		}
+		errs = append(errs, fmt.Errorf("%s: %w: %s", signer, err, strings.TrimSpace(output.String())))
+	}
+	return fmt.Errorf("the signature of %s is not from a trusted signer: %w", path, errors.Join(errs...))
+}
+
+// artifactSignature are the files that hold the signature of an artifact; empty if there are none.
+type artifactSignature struct {
+	bundle      string
+	signature   string
+	certificate string
+}
+
+// findSignature returns the signature files next to the artifact.
+func findSignature(path string) artifactSignature {
+	exists := func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}
+	if exists(path + ".bundle") {
+		return artifactSignature{bundle: path + ".bundle"}
+	}
+	if exists(path + ".sig") {
+		signature := artifactSignature{signature: path + ".sig"}
+		if exists(path + ".pem") {
+			signature.certificate = path + ".pem"
+		}
+		return signature
+	}
+	return artifactSignature{}
+}
+
+// isSignatureFile returns true for the files that hold signatures of artifacts, which are not artifacts themselves.
+func isSignatureFile(name string) bool {
+	return strings.HasSuffix(name, ".bundle") || strings.HasSuffix(name, ".sig") || strings.HasSuffix(name, ".pem")
+}
+
+// cosignArgs returns the arguments of cosign to verify the signature of the artifact in the blob file by the signer.
+func (s TrustedSigner) cosignArgs(blob string, signature artifactSignature) []string {
+	args := append([]string{"verify-blob"}, s.signerArgs()...)
+	if signature.bundle != "" {
+		args = append(args, "--bundle", signature.bundle)
+	} else {
+		args = append(args, "--signature", signature.signature)
+		if signature.certificate != "" {
+			args = append(args, "--certificate", signature.certificate)
+		}
+	}
+	return append(args, blob)
+}
+
+// cosignImageArgs returns the arguments of cosign to verify the signature of an OCI artifact by the signer.
+func (s TrustedSigner) cosignImageArgs(ref string) []string {
+	return append(append([]string{"verify"}, s.signerArgs()...), ref)
+}
+
+// signerArgs are the arguments of cosign that identify the signer.
+func (s TrustedSigner) signerArgs() []string {
+	if s.Key != "" {
+		return []string{"--key", s.Key}
+	}
+	return []string{"--certificate-identity", s.Identity, "--certificate-oidc-issuer", s.Issuer}
+}
+
+func (s TrustedSigner) String() string {
+	if s.Key != "" {
+		return "key " + s.Key
+	}
+	return fmt.Sprintf("%s (issuer %s)", s.Identity, s.Issuer)
+}
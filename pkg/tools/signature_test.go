@@ -0,0 +1,85 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestArtifactPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ArtifactPolicy
+		wantErr bool
+	}{
+		{name: "empty", policy: ArtifactPolicy{}},
+		{name: "key", policy: ArtifactPolicy{TrustedSigners: []TrustedSigner{{Key: "cosign.pub"}}, RequireSignatures: true}},
+		{name: "keyless", policy: ArtifactPolicy{TrustedSigners: []TrustedSigner{{Identity: "ops@example.com", Issuer: "https://accounts.google.com"}}}},
+		{name: "key and identity", policy: ArtifactPolicy{TrustedSigners: []TrustedSigner{{Key: "cosign.pub", Identity: "ops@example.com"}}}, wantErr: true},
+		{name: "identity without issuer", policy: ArtifactPolicy{TrustedSigners: []TrustedSigner{{Identity: "ops@example.com"}}}, wantErr: true},
+		{name: "required without signers", policy: ArtifactPolicy{RequireSignatures: true}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.policy.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCosignArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		signer    TrustedSigner
+		signature artifactSignature
+		want      []string
+	}{
+		{
+			name:      "key with signature",
+			signer:    TrustedSigner{Key: "cosign.pub"},
+			signature: artifactSignature{signature: "recipes.yaml.sig"},
+			want:      []string{"verify-blob", "--key", "cosign.pub", "--signature", "recipes.yaml.sig", "recipes.yaml"},
+		},
+		{
+			name:      "keyless with bundle",
+			signer:    TrustedSigner{Identity: "ops@example.com", Issuer: "https://accounts.google.com"},
+			signature: artifactSignature{bundle: "recipes.yaml.bundle"},
+			want: []string{
+				"verify-blob", "--certificate-identity", "ops@example.com", "--certificate-oidc-issuer", "https://accounts.google.com",
+				"--bundle", "recipes.yaml.bundle", "recipes.yaml",
+			},
+		},
+		{
+			name:      "keyless with signature and certificate",
+			signer:    TrustedSigner{Identity: "ops@example.com", Issuer: "https://accounts.google.com"},
+			signature: artifactSignature{signature: "recipes.yaml.sig", certificate: "recipes.yaml.pem"},
+			want: []string{
+				"verify-blob", "--certificate-identity", "ops@example.com", "--certificate-oidc-issuer", "https://accounts.google.com",
+				"--signature", "recipes.yaml.sig", "--certificate", "recipes.yaml.pem", "recipes.yaml",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.signer.cosignArgs("recipes.yaml", tt.signature); !slices.Equal(got, tt.want) {
+				t.Errorf("cosignArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Simulator is implemented by tools that can show what a call would change, without changing anything.
+// In simulate mode, the agent calls Simulate instead of Run for calls that modify resources.
+type Simulator interface {
+	Simulate(ctx context.Context, args map[string]any) (any, error)
+}
+
+// SimulatedResult is the result of a call that was not run because of simulate mode,
+// for tools that cannot show what the call would change.
+type SimulatedResult struct {
+	Simulated bool   `json:"simulated"`
+	Message   string `json:"message"`
+}
+
+// NotSimulated returns the result of a call of a tool that cannot be simulated.
+func NotSimulated(tool Tool) *SimulatedResult {
+	return &SimulatedResult{
+		Simulated: true,
+		Message:   fmt.Sprintf("Simulate mode is on, so the %s tool was not run; it cannot show what it would change without changing it. Assume that the call would succeed.", tool.Name()),
+	}
+}
+
+// kubectlVerbsWithoutDryRun are the write verbs of kubectl that don't support --dry-run.
+var kubectlVerbsWithoutDryRun = map[string]bool{
+	"edit": true, "debug": true, "attach": true, "cp": true, "reconcile": true,
+	"approve": true, "deny": true, "certificate": true,
+}
+
+// simulateCommand runs a command with --dry-run=server added to its kubectl calls that modify resources,
+// and adds the output of kubectl diff for the calls that apply manifests.
+// Commands that run other programs, or kubectl verbs without a dry-run mode, are not run.
+func simulateCommand(ctx context.Context, command, workDir, kubeconfig string) (*ExecResult, error) {
+	dryRun, diff, err := dryRunCommand(command)
+	if err != nil {
+		return &ExecResult{
+			Command:   command,
+			Simulated: true,
+			Stdout:    fmt.Sprintf("Simulate mode is on, so this command was not run: %v. Assume that it would succeed.", err),
+		}, nil
+	}
+
+	result, err := runKubectlCommand(ctx, dryRun, workDir, kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	result.Simulated = true
+	if diff != "" && result.ExitCode == 0 && result.Error == "" {
+		diffResult, err := runKubectlCommand(ctx, diff, workDir, kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		// kubectl diff exits with 1 when there are differences.
+		if diffResult.Stdout != "" {
+			result.Stdout += "\nChanges (" + diff + "):\n" + diffResult.Stdout
+		}
+	}
+	return result, nil
+}
+
+// dryRunCommand returns the command with --dry-run=server added to the kubectl calls that modify resources,
+// and a kubectl diff command for the calls that apply manifests (or "" if there are none).
+func dryRunCommand(command string) (dryRun string, diff string, err error) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(command), "")
+	if err != nil {
+		return "", "", fmt.Errorf("it could not be parsed")
+	}
+
+	var diffs []string
+	syntax.Walk(file, func(node syntax.Node) bool {
+		if err != nil {
+			return false
+		}
+		call, ok := node.(*syntax.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		args := callArgs(call)
+		if len(args) == 0 {
+			err = fmt.Errorf("it could not be parsed")
+			return false
+		}
+		if !strings.HasSuffix(args[0], "kubectl") {
+			err = fmt.Errorf("it runs %q, which cannot be simulated", args[0])
+			return false
+		}
+		if analyzeCall(call) == "no" {
+			return true
+		}
+		kubectlCall := parseKubectlArgs(args[1:])[0]
+		if kubectlVerbsWithoutDryRun[kubectlCall.Verb] || !writeOps[kubectlCall.Verb] {
+			err = fmt.Errorf("kubectl %s cannot be simulated", kubectlCall.Verb)
+			return false
+		}
+		if kubectlCall.Verb == "apply" {
+			diffs = append(diffs, "kubectl diff "+strings.Join(withoutVerb(args[1:], "apply"), " "))
+		}
+		call.Args = append(call.Args, &syntax.Word{Parts: []syntax.WordPart{&syntax.Lit{Value: "--dry-run=server"}}})
+		return true
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	var sb strings.Builder
+	if err := syntax.NewPrinter().Print(&sb, file); err != nil {
+		return "", "", fmt.Errorf("it could not be rewritten: %w", err)
+	}
+	return strings.TrimSpace(sb.String()), strings.Join(diffs, "; "), nil
+}
+
+// withoutVerb returns the arguments of kubectl without the (first occurrence of the) verb.
+func withoutVerb(args []string, verb string) []string {
+	var out []string
+	removed := false
+	for _, arg := range args {
+		if !removed && arg == verb {
+			removed = true
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+func (t *Kubectl) Simulate(ctx context.Context, args map[string]any) (any, error) {
+	command, ok := args["command"].(string)
+	if !ok {
+		return &ExecResult{Error: "kubectl command must be a string"}, nil
+	}
+	return simulateCommand(ctx, command, ctx.Value(WorkDirKey).(string), ctx.Value(KubeconfigKey).(string))
+}
+
+func (t *BashTool) Simulate(ctx context.Context, args map[string]any) (any, error) {
+	command, ok := args["command"].(string)
+	if !ok || command == "" {
+		return &ExecResult{Error: "bash command not provided or is not a string"}, nil
+	}
+	return simulateCommand(ctx, command, ctx.Value(WorkDirKey).(string), ctx.Value(KubeconfigKey).(string))
+}
+
+// Simulate previews the change instead of applying it.
+func (t *BulkMetadataTool) Simulate(ctx context.Context, args map[string]any) (any, error) {
+	args = maps.Clone(args)
+	delete(args, "preview_id")
+	result, err := t.Run(ctx, args)
+	if preview, ok := result.(*BulkMetadataResult); ok && err == nil {
+		preview.Message = strings.TrimSpace("Simulate mode is on, so the change was only previewed. " + preview.Message)
+	}
+	return result, err
+}
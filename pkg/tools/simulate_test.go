@@ -0,0 +1,67 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import "testing"
+
+func TestDryRunCommand(t *testing.T) {
+	tests := []struct {
+		command    string
+		wantDryRun string
+		wantDiff   string
+		wantErr    bool
+	}{
+		{
+			command:    "kubectl scale deployment web --replicas=3",
+			wantDryRun: "kubectl scale deployment web --replicas=3 --dry-run=server",
+		},
+		{
+			command:    "kubectl apply -f app.yaml -n prod",
+			wantDryRun: "kubectl apply -f app.yaml -n prod --dry-run=server",
+			wantDiff:   "kubectl diff -f app.yaml -n prod",
+		},
+		{
+			command:    "kubectl get pods && kubectl delete pod web-1",
+			wantDryRun: "kubectl get pods && kubectl delete pod web-1 --dry-run=server",
+		},
+		{
+			command: "kubectl edit deployment web",
+			wantErr: true,
+		},
+		{
+			command: "helm upgrade web ./chart",
+			wantErr: true,
+		},
+		{
+			command: "kubectl get pods | grep web",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			dryRun, diff, err := dryRunCommand(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dryRunCommand(%q) error = %v, wantErr %v", tt.command, err, tt.wantErr)
+			}
+			if dryRun != tt.wantDryRun {
+				t.Errorf("dryRunCommand(%q) = %q, want %q", tt.command, dryRun, tt.wantDryRun)
+			}
+			if diff != tt.wantDiff {
+				t.Errorf("dryRunCommand(%q) diff = %q, want %q", tt.command, diff, tt.wantDiff)
+			}
+		})
+	}
+}
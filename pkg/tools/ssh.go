@@ -0,0 +1,117 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// SSHTarget is a host that kubectl and bash commands are run on over SSH, e.g. a bastion that can reach the API server.
+// Authentication is left to ssh: keys given by IdentityFile or found by ssh, or the SSH agent.
+type SSHTarget struct {
+	// Host is the host name or address of the target, or a Host alias from ~/.ssh/config.
+	Host string `json:"host"`
+	User string `json:"user,omitempty"`
+	Port int    `json:"port,omitempty"`
+	// IdentityFile is the private key to authenticate with; by default, ssh uses its usual keys and the SSH agent.
+	IdentityFile string `json:"identityFile,omitempty"`
+	// JumpHost is a host to connect through, as for ssh -J.
+	JumpHost string `json:"jumpHost,omitempty"`
+	// Kubeconfig is the path of the kubeconfig on the target; by default, the target's default kubeconfig is used.
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// WorkDir is the directory on the target that commands are run in; by default, the home directory of the user.
+	WorkDir string `json:"workDir,omitempty"`
+	// Options are additional ssh options, as for ssh -o, e.g. StrictHostKeyChecking=accept-new.
+	Options []string `json:"options,omitempty"`
+}
+
+// ParseSSHTarget parses a target given as [user@]host[:port].
+func ParseSSHTarget(s string) (*SSHTarget, error) {
+	target := &SSHTarget{Host: s}
+	if user, host, ok := strings.Cut(target.Host, "@"); ok {
+		target.User, target.Host = user, host
+	}
+	if host, port, ok := strings.Cut(target.Host, ":"); ok {
+		n, err := strconv.Atoi(port)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in SSH target %q", s)
+		}
+		target.Host, target.Port = host, n
+	}
+	if err := target.Validate(); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+// Validate checks that the target is well-formed.
+func (t *SSHTarget) Validate() error {
+	if t.Host == "" {
+		return fmt.Errorf("SSH target has no host")
+	}
+	if t.Port < 0 || t.Port > 65535 {
+		return fmt.Errorf("SSH target %q: invalid port %d", t.Host, t.Port)
+	}
+	return nil
+}
+
+// String returns the target as [user@]host[:port].
+func (t *SSHTarget) String() string {
+	s := t.Host
+	if t.User != "" {
+		s = t.User + "@" + s
+	}
+	if t.Port != 0 {
+		s += ":" + strconv.Itoa(t.Port)
+	}
+	return s
+}
+
+// Command returns the ssh command that runs the bash command on the target.
+// The kubeconfig and working directory of the target are used, rather than the local ones.
+func (t *SSHTarget) Command(ctx context.Context, command, workDir, kubeconfig string) (*exec.Cmd, error) {
+	if err := checkEgress(ctx, t); err != nil {
+		return nil, err
+	}
+	return exec.CommandContext(ctx, "ssh", t.sshArgs(command)...), nil
+}
+
+// sshArgs returns the arguments of ssh to run the bash command on the target.
+func (t *SSHTarget) sshArgs(command string) []string {
+	// BatchMode fails instead of prompting for passwords or host keys, which nobody would see.
+	args := []string{"-o", "BatchMode=yes"}
+	if t.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(t.Port))
+	}
+	if t.IdentityFile != "" {
+		args = append(args, "-i", t.IdentityFile)
+	}
+	if t.JumpHost != "" {
+		args = append(args, "-J", t.JumpHost)
+	}
+	for _, option := range t.Options {
+		args = append(args, "-o", option)
+	}
+	destination := t.Host
+	if t.User != "" {
+		destination = t.User + "@" + destination
+	}
+	// ssh passes the command to the login shell of the user, which might not be bash.
+	return append(args, destination, "--", remoteScript(command, t.WorkDir, t.Kubeconfig))
+}
@@ -0,0 +1,88 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSSHTarget(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    SSHTarget
+		wantErr bool
+	}{
+		{in: "bastion", want: SSHTarget{Host: "bastion"}},
+		{in: "ops@bastion.example.com", want: SSHTarget{Host: "bastion.example.com", User: "ops"}},
+		{in: "ops@10.0.0.5:2222", want: SSHTarget{Host: "10.0.0.5", User: "ops", Port: 2222}},
+		{in: "bastion:ssh", wantErr: true},
+		{in: "ops@", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseSSHTarget(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSSHTarget(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (got.Host != tt.want.Host || got.User != tt.want.User || got.Port != tt.want.Port) {
+			t.Errorf("ParseSSHTarget(%q) = %+v, want %+v", tt.in, *got, tt.want)
+		}
+	}
+}
+
+func TestSSHArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  SSHTarget
+		command string
+		want    []string
+	}{
+		{
+			name:    "host only",
+			target:  SSHTarget{Host: "bastion"},
+			command: "kubectl get pods",
+			want:    []string{"-o", "BatchMode=yes", "bastion", "--", "bash -c 'kubectl get pods'"},
+		},
+		{
+			name: "all fields",
+			target: SSHTarget{
+				Host:         "10.0.0.5",
+				User:         "ops",
+				Port:         2222,
+				IdentityFile: "~/.ssh/bastion",
+				JumpHost:     "jump.example.com",
+				Kubeconfig:   "/etc/kube/config",
+				WorkDir:      "/tmp/kubectl-ai",
+				Options:      []string{"StrictHostKeyChecking=accept-new"},
+			},
+			command: "kubectl get pods -l 'app=web'",
+			want: []string{
+				"-o", "BatchMode=yes", "-p", "2222", "-i", "~/.ssh/bastion", "-J", "jump.example.com",
+				"-o", "StrictHostKeyChecking=accept-new", "ops@10.0.0.5", "--",
+				`cd /tmp/kubectl-ai && KUBECONFIG=/etc/kube/config bash -c 'kubectl get pods -l '\''app=web'\'''`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.target.sshArgs(tt.command); !slices.Equal(got, tt.want) {
+				t.Errorf("sshArgs(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
@@ -18,16 +18,16 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"maps"
+	"io"
 	"os"
 	"path/filepath"
-	"slices"
 	"sort"
 	"strings"
 	"time"
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/google/uuid"
+	"k8s.io/klog/v2"
 	"sigs.k8s.io/yaml"
 )
 
@@ -63,8 +63,13 @@ func (t *Tools) Lookup(name string) Tool {
 	return t.tools[name]
 }
 
+// AllTools returns the tools ordered by name, so that the prompts and function definitions built from them are stable.
 func (t *Tools) AllTools() []Tool {
-	return slices.Collect(maps.Values(t.tools))
+	all := make([]Tool, 0, len(t.tools))
+	for _, name := range t.Names() {
+		all = append(all, t.tools[name])
+	}
+	return all
 }
 
 func (t *Tools) Names() []string {
@@ -108,6 +113,13 @@ func (t *ToolCall) Description() string {
 		return fmt.Sprintf("[MCP: %s] %s(%s)", mcpTool.serverName, t.name, strings.Join(args, ", "))
 	}
 
+	// Custom tools with parameters are shown as the command they run, for the user to confirm.
+	if customTool, ok := t.tool.(*CustomTool); ok && customTool.command != nil {
+		if command, err := customTool.renderCommand(t.arguments); err == nil {
+			return command
+		}
+	}
+
 	// Default formatting for non-MCP tools
 	if command, ok := t.arguments["command"]; ok {
 		return command.(string)
@@ -139,6 +151,25 @@ type InvokeToolOptions struct {
 
 	// Kubeconfig is the path to the kubeconfig file.
 	Kubeconfig string
+
+	// Progress receives the progress reported by the tool, if set.
+	Progress ProgressFunc
+
+	// Output receives the output of commands run by the tool as it is produced, if set.
+	Output io.Writer
+
+	// Simulate shows what the call would change instead of running it, for tools that implement Simulator.
+	// Other tools are not run.
+	Simulate bool
+
+	// Attribution, if set, is recorded in annotations on the objects the tool creates or modifies.
+	Attribution *Attribution
+
+	// Ledger, if set, records the objects the tool creates.
+	Ledger *Ledger
+
+	// PortForwards, if set, holds the port-forwards started by the tool, which outlive the call.
+	PortForwards *PortForwards
 }
 
 type ToolRequestEvent struct {
@@ -151,6 +182,8 @@ type ToolResponseEvent struct {
 	CallID   string `json:"id,omitempty"`
 	Response any    `json:"response,omitempty"`
 	Error    string `json:"error,omitempty"`
+	// SchemaError is set if the response does not match the result schema of the tool.
+	SchemaError string `json:"schemaError,omitempty"`
 }
 
 // InvokeTool handles the execution of a single action
@@ -170,8 +203,33 @@ func (t *ToolCall) InvokeTool(ctx context.Context, opt InvokeToolOptions) (any,
 
 	ctx = context.WithValue(ctx, KubeconfigKey, opt.Kubeconfig)
 	ctx = context.WithValue(ctx, WorkDirKey, opt.WorkDir)
+	if opt.Progress != nil {
+		ctx = WithProgress(ctx, opt.Progress)
+	}
+	if opt.Output != nil {
+		ctx = WithOutput(ctx, opt.Output)
+	}
+	if opt.Attribution != nil {
+		ctx = WithAttribution(ctx, opt.Attribution)
+	}
+	if opt.Ledger != nil {
+		ctx = WithLedger(ctx, opt.Ledger)
+	}
+	if opt.PortForwards != nil {
+		ctx = WithPortForwards(ctx, opt.PortForwards)
+	}
 
-	response, err := t.tool.Run(ctx, t.arguments)
+	var response any
+	var err error
+	if opt.Simulate {
+		if simulator, ok := t.tool.(Simulator); ok {
+			response, err = simulator.Simulate(ctx, t.arguments)
+		} else {
+			response = NotSimulated(t.tool)
+		}
+	} else {
+		response, err = t.tool.Run(ctx, t.arguments)
+	}
 
 	{
 		ev := ToolResponseEvent{
@@ -180,6 +238,14 @@ func (t *ToolCall) InvokeTool(ctx context.Context, opt InvokeToolOptions) (any,
 		}
 		if err != nil {
 			ev.Error = err.Error()
+		} else if _, notRun := response.(*SimulatedResult); notRun {
+			// The tool was not run, so there is no result to check.
+		} else if schema := ResultSchema(t.tool); schema != nil {
+			// The LLM relies on the schema, so a mismatch is a bug in the tool; we report it but still return the result.
+			if schemaErr := ValidateResult(schema, response); schemaErr != nil {
+				klog.Warningf("result of tool %q does not match its schema: %v", t.name, schemaErr)
+				ev.SchemaError = schemaErr.Error()
+			}
 		}
 		recorder.Write(ctx, &journal.Event{
 			Timestamp: time.Now(),
@@ -232,6 +298,9 @@ func LoadAndRegisterCustomTools(configPath string) error {
 		}
 
 		for _, entry := range configPaths {
+			if isSignatureFile(entry.Name()) {
+				continue
+			}
 			if err := LoadAndRegisterCustomTools(filepath.Join(configPath, entry.Name())); err != nil {
 				return err
 			}
@@ -240,7 +309,7 @@ func LoadAndRegisterCustomTools(configPath string) error {
 		return nil
 	}
 
-	yamlFile, err := os.ReadFile(configPath)
+	yamlFile, err := ReadArtifact(configPath)
 	if os.IsNotExist(err) {
 		return nil
 	} else if err != nil {
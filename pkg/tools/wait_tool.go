@@ -0,0 +1,328 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&WaitTool{})
+}
+
+// The conditions the wait_for tool can wait for.
+const (
+	waitRolloutComplete = "rollout_complete"
+	waitPodReady        = "pod_ready"
+	waitJobSucceeded    = "job_succeeded"
+	waitJSONPath        = "jsonpath"
+)
+
+const (
+	// defaultWaitTimeout is how long wait_for waits if no timeout is given, and maxWaitTimeout the longest it waits.
+	defaultWaitTimeout = 2 * time.Minute
+	maxWaitTimeout     = 30 * time.Minute
+
+	// jobPollInterval is how often the status of a job is checked while waiting for it.
+	jobPollInterval = 2 * time.Second
+)
+
+// WaitTool waits until a condition holds for objects in the cluster, e.g. until a rollout completes.
+type WaitTool struct{}
+
+func (t *WaitTool) Name() string {
+	return "wait_for"
+}
+
+func (t *WaitTool) Description() string {
+	return `Waits until a condition holds for objects in the cluster, or a timeout expires, and reports whether it was met.
+Use this tool to check that a change took effect (e.g. after a rollout, or to wait for a job to finish) instead of
+sleeping or polling in bash. The conditions are:
+- rollout_complete: the rollout of a deployment, statefulset or daemonset is complete.
+- pod_ready: the pod, or all the pods matching the selector, are ready.
+- job_succeeded: the job completed; waiting stops early if the job fails.
+- jsonpath: the JSONPath expression evaluates to the value for the object(s), e.g. jsonpath "{.status.phase}" and value "Running".`
+}
+
+func (t *WaitTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"condition": {
+					Type:        gollm.TypeString,
+					Description: `What to wait for: rollout_complete, pod_ready, job_succeeded or jsonpath.`,
+				},
+				"resource": {
+					Type:        gollm.TypeString,
+					Description: `The object to wait for, as kind/name, e.g. "deployment/web", "pod/web-0" or "job/migrate". For pod_ready and jsonpath, give the kind alone (e.g. "pods") with a selector instead to wait for several objects.`,
+				},
+				"selector": {
+					Type:        gollm.TypeString,
+					Description: `A label selector, e.g. "app=web", to wait for all the matching objects of the kind given in resource.`,
+				},
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the objects. Defaults to the namespace of the current context.`,
+				},
+				"jsonpath": {
+					Type:        gollm.TypeString,
+					Description: `For the jsonpath condition: the JSONPath expression, e.g. "{.status.phase}" or "{.status.readyReplicas}".`,
+				},
+				"value": {
+					Type:        gollm.TypeString,
+					Description: `For the jsonpath condition: the value the expression must have, e.g. "Running".`,
+				},
+				"timeout": {
+					Type:        gollm.TypeString,
+					Description: `How long to wait at most, as a duration such as "90s" or "5m". Defaults to 2m; at most 30m.`,
+				},
+			},
+			Required: []string{"condition", "resource"},
+		},
+	}
+}
+
+// WaitResult is the result of the wait_for tool.
+type WaitResult struct {
+	Condition string `json:"condition"`
+	Resource  string `json:"resource"`
+	// Met is true if the condition holds.
+	Met bool `json:"met"`
+	// TimedOut is set if the condition was not met before the timeout.
+	TimedOut bool `json:"timed_out,omitempty"`
+	// Failed is set if the condition can no longer be met, e.g. the job failed.
+	Failed bool `json:"failed,omitempty"`
+	// Waited is how long we waited, e.g. "12s".
+	Waited string `json:"waited"`
+	// Output is the output of the command that waited.
+	Output string `json:"output,omitempty"`
+	// Status is the state of the objects when the condition was not met, to tell why.
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (t *WaitTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*WaitResult]()
+}
+
+// waitRequest is a parsed wait_for call.
+type waitRequest struct {
+	condition string
+	resource  string
+	selector  string
+	namespace string
+	jsonpath  string
+	value     string
+	timeout   time.Duration
+}
+
+func parseWaitArgs(args map[string]any) (*waitRequest, error) {
+	r := &waitRequest{
+		condition: stringArg(args, "condition"),
+		resource:  stringArg(args, "resource"),
+		selector:  stringArg(args, "selector"),
+		namespace: stringArg(args, "namespace"),
+		jsonpath:  stringArg(args, "jsonpath"),
+		value:     stringArg(args, "value"),
+		timeout:   defaultWaitTimeout,
+	}
+	if timeout := stringArg(args, "timeout"); timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid timeout %q: give a duration such as 90s or 5m", timeout)
+		}
+		r.timeout = min(d, maxWaitTimeout)
+	}
+	if r.resource == "" {
+		return nil, fmt.Errorf("resource is required")
+	}
+	named := strings.Contains(r.resource, "/")
+	if named == (r.selector != "") {
+		return nil, fmt.Errorf("give either a resource as kind/name, or a kind with a selector")
+	}
+	switch r.condition {
+	case waitRolloutComplete, waitJobSucceeded:
+		if !named {
+			return nil, fmt.Errorf("%s needs a resource as kind/name, e.g. deployment/web", r.condition)
+		}
+	case waitPodReady:
+	case waitJSONPath:
+		if r.jsonpath == "" {
+			return nil, fmt.Errorf("the jsonpath condition needs a jsonpath expression")
+		}
+	default:
+		return nil, fmt.Errorf("unknown condition %q: use rollout_complete, pod_ready, job_succeeded or jsonpath", r.condition)
+	}
+	return r, nil
+}
+
+// targetArgs returns the kubectl arguments that select the objects.
+func (r *waitRequest) targetArgs() []string {
+	args := []string{r.resource}
+	if r.selector != "" {
+		args = append(args, "--selector", r.selector)
+	}
+	if r.namespace != "" {
+		args = append(args, "--namespace", r.namespace)
+	}
+	return args
+}
+
+// waitArgs returns the kubectl arguments that wait for the condition, for the conditions kubectl can wait for.
+func (r *waitRequest) waitArgs() []string {
+	timeout := "--timeout=" + r.timeout.String()
+	switch r.condition {
+	case waitRolloutComplete:
+		return append(append([]string{"rollout", "status"}, r.targetArgs()...), timeout)
+	case waitPodReady:
+		return append(append([]string{"wait", "--for=condition=Ready"}, r.targetArgs()...), timeout)
+	case waitJSONPath:
+		condition := "--for=jsonpath=" + r.jsonpath
+		if r.value != "" {
+			condition += "=" + r.value
+		}
+		return append(append([]string{"wait", condition}, r.targetArgs()...), timeout)
+	}
+	return nil
+}
+
+func (t *WaitTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	result := &WaitResult{Condition: stringArg(args, "condition"), Resource: stringArg(args, "resource")}
+	request, err := parseWaitArgs(args)
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	started := time.Now()
+	if request.condition == waitJobSucceeded {
+		err = waitForJob(ctx, request, result)
+	} else {
+		err = waitWithKubectl(ctx, request, result)
+	}
+	result.Waited = time.Since(started).Round(time.Second).String()
+	if err != nil {
+		return nil, err
+	}
+
+	if !result.Met && result.Error == "" {
+		if out, err := runKubectl(ctx, append([]string{"get"}, append(request.targetArgs(), "-o", "wide")...)...); err == nil {
+			result.Status = strings.TrimSpace(out.Stdout + "\n" + out.Stderr)
+		}
+	}
+	return result, nil
+}
+
+// waitWithKubectl waits with kubectl wait or kubectl rollout status.
+func waitWithKubectl(ctx context.Context, request *waitRequest, result *WaitResult) error {
+	out, err := runKubectl(ctx, request.waitArgs()...)
+	if err != nil {
+		return err
+	}
+	result.Output = strings.TrimSpace(out.Stdout + "\n" + out.Stderr)
+	switch {
+	case out.Error == "" && out.ExitCode == 0:
+		result.Met = true
+	case strings.Contains(out.Stderr, "exceeded its progress deadline"):
+		result.Failed = true
+	case strings.Contains(out.Stderr, "timed out"):
+		result.TimedOut = true
+	default:
+		result.Error = strings.TrimSpace(out.Error + " " + out.Stderr)
+	}
+	return nil
+}
+
+// jobStatus is the part of a job that tells whether it finished.
+type jobStatus struct {
+	Status struct {
+		Succeeded  int            `json:"succeeded"`
+		Failed     int            `json:"failed"`
+		Conditions []jobCondition `json:"conditions"`
+	} `json:"status"`
+}
+
+type jobCondition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// finished returns whether the job completed or failed, with the message of the condition that says so.
+func (j *jobStatus) finished() (succeeded, failed bool, message string) {
+	for _, condition := range j.Status.Conditions {
+		if condition.Status != "True" {
+			continue
+		}
+		switch condition.Type {
+		case "Complete", "SuccessCriteriaMet":
+			return true, false, strings.TrimSpace(condition.Reason + " " + condition.Message)
+		case "Failed", "FailureTarget":
+			return false, true, strings.TrimSpace(condition.Reason + " " + condition.Message)
+		}
+	}
+	return false, false, ""
+}
+
+// waitForJob polls the job until it completes or fails, as kubectl wait cannot stop at whichever comes first.
+func waitForJob(ctx context.Context, request *waitRequest, result *WaitResult) error {
+	deadline := time.Now().Add(request.timeout)
+	for {
+		var job jobStatus
+		if err := kubectlGetJSON(ctx, &job, request.targetArgs()...); err != nil {
+			result.Error = err.Error()
+			return nil
+		}
+		succeeded, failed, message := job.finished()
+		result.Output = strings.TrimSpace(fmt.Sprintf("%d succeeded, %d failed %s", job.Status.Succeeded, job.Status.Failed, message))
+		if succeeded || failed {
+			result.Met, result.Failed = succeeded, failed
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			result.TimedOut = true
+			return nil
+		}
+		ReportProgress(ctx, Progress{
+			Message: fmt.Sprintf("Waiting for %s: %s", request.resource, result.Output),
+			Current: (request.timeout - remaining).Seconds(),
+			Total:   request.timeout.Seconds(),
+		})
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(min(jobPollInterval, remaining)):
+		}
+	}
+}
+
+func (t *WaitTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *WaitTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
@@ -0,0 +1,123 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWaitArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     map[string]any
+		expected []string
+		err      bool
+	}{
+		{
+			name:     "rollout",
+			args:     map[string]any{"condition": "rollout_complete", "resource": "deployment/web", "namespace": "shop"},
+			expected: []string{"rollout", "status", "deployment/web", "--namespace", "shop", "--timeout=2m0s"},
+		},
+		{
+			name:     "pods by selector",
+			args:     map[string]any{"condition": "pod_ready", "resource": "pods", "selector": "app=web", "timeout": "90s"},
+			expected: []string{"wait", "--for=condition=Ready", "pods", "--selector", "app=web", "--timeout=1m30s"},
+		},
+		{
+			name:     "jsonpath",
+			args:     map[string]any{"condition": "jsonpath", "resource": "pod/web-0", "jsonpath": "{.status.phase}", "value": "Running"},
+			expected: []string{"wait", "--for=jsonpath={.status.phase}=Running", "pod/web-0", "--timeout=2m0s"},
+		},
+		{
+			name:     "timeout is capped",
+			args:     map[string]any{"condition": "pod_ready", "resource": "pod/web-0", "timeout": "2h"},
+			expected: []string{"wait", "--for=condition=Ready", "pod/web-0", "--timeout=30m0s"},
+		},
+		{
+			name: "rollout of a kind",
+			args: map[string]any{"condition": "rollout_complete", "resource": "deployments", "selector": "app=web"},
+			err:  true,
+		},
+		{
+			name: "name and selector",
+			args: map[string]any{"condition": "pod_ready", "resource": "pod/web-0", "selector": "app=web"},
+			err:  true,
+		},
+		{
+			name: "jsonpath without expression",
+			args: map[string]any{"condition": "jsonpath", "resource": "pod/web-0"},
+			err:  true,
+		},
+		{
+			name: "invalid timeout",
+			args: map[string]any{"condition": "pod_ready", "resource": "pod/web-0", "timeout": "soon"},
+			err:  true,
+		},
+		{
+			name: "unknown condition",
+			args: map[string]any{"condition": "sleep", "resource": "pod/web-0"},
+			err:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := parseWaitArgs(tc.args)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("parseWaitArgs(%v) succeeded, expected an error", tc.args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseWaitArgs(%v) failed: %v", tc.args, err)
+			}
+			if got := request.waitArgs(); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("waitArgs() = %q, expected %q", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestJobStatusFinished(t *testing.T) {
+	newJob := func(conditions ...string) *jobStatus {
+		job := &jobStatus{}
+		for i := 0; i+1 < len(conditions); i += 2 {
+			job.Status.Conditions = append(job.Status.Conditions, jobCondition{Type: conditions[i], Status: conditions[i+1]})
+		}
+		return job
+	}
+	testCases := []struct {
+		name      string
+		job       *jobStatus
+		succeeded bool
+		failed    bool
+	}{
+		{name: "running", job: newJob()},
+		{name: "complete", job: newJob("SuccessCriteriaMet", "True", "Complete", "True"), succeeded: true},
+		{name: "failed", job: newJob("FailureTarget", "True", "Failed", "True"), failed: true},
+		{name: "suspended", job: newJob("Suspended", "True", "Complete", "False")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			succeeded, failed, _ := tc.job.finished()
+			if succeeded != tc.succeeded || failed != tc.failed {
+				t.Errorf("finished() = %v, %v, expected %v, %v", succeeded, failed, tc.succeeded, tc.failed)
+			}
+		})
+	}
+}
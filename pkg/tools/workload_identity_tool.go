@@ -0,0 +1,289 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/gollm"
+)
+
+func init() {
+	RegisterTool(&WorkloadIdentityTool{})
+}
+
+// Annotations and labels used by the cloud workload identity integrations.
+const (
+	gkeServiceAccountAnnotation = "iam.gke.io/gcp-service-account"
+
+	eksRoleARNAnnotation  = "eks.amazonaws.com/role-arn"
+	eksAudienceAnnotation = "eks.amazonaws.com/audience"
+	eksDefaultAudience    = "sts.amazonaws.com"
+
+	azureClientIDAnnotation = "azure.workload.identity/client-id"
+	azureUseLabel           = "azure.workload.identity/use"
+	azureAudience           = "api://AzureADTokenExchange"
+)
+
+// minTokenExpirationSeconds is the minimum expiration the API server accepts for projected tokens.
+const minTokenExpirationSeconds = 600
+
+// gcpServiceAccountEmails match the emails of Google service accounts: those created in a project,
+// and the default service accounts of Compute Engine and App Engine.
+var gcpServiceAccountEmails = []*regexp.Regexp{
+	regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]@[a-z0-9-]+\.iam\.gserviceaccount\.com$`),
+	regexp.MustCompile(`^[0-9]+-compute@developer\.gserviceaccount\.com$`),
+	regexp.MustCompile(`^[a-z][a-z0-9.:-]*[a-z0-9]@appspot\.gserviceaccount\.com$`),
+}
+
+// isGCPServiceAccountEmail returns false if email is not the email of a Google service account.
+// Other emails under gserviceaccount.com, whose formats we don't know, are not checked.
+func isGCPServiceAccountEmail(email string) bool {
+	for _, re := range gcpServiceAccountEmails {
+		if re.MatchString(email) {
+			return true
+		}
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	return domain == "gserviceaccount.com" || strings.HasSuffix(domain, ".gserviceaccount.com")
+}
+
+// WorkloadIdentityTool reports which cloud identity a workload maps to, and common misconfigurations.
+type WorkloadIdentityTool struct{}
+
+func (t *WorkloadIdentityTool) Name() string {
+	return "inspect_workload_identity"
+}
+
+func (t *WorkloadIdentityTool) Description() string {
+	return `Resolves which cloud identity a pod or service account maps to (GKE Workload Identity, EKS IRSA or Azure Workload Identity),
+inspects the projected service account tokens (audience and expiry), and reports misconfigurations.
+Use this tool when a workload gets permission errors (e.g. 401/403) calling cloud APIs.`
+}
+
+func (t *WorkloadIdentityTool) FunctionDefinition() *gollm.FunctionDefinition {
+	return &gollm.FunctionDefinition{
+		Name:        t.Name(),
+		Description: t.Description(),
+		Parameters: &gollm.Schema{
+			Type: gollm.TypeObject,
+			Properties: map[string]*gollm.Schema{
+				"namespace": {
+					Type:        gollm.TypeString,
+					Description: `The namespace of the workload. Defaults to the current namespace.`,
+				},
+				"pod": {
+					Type:        gollm.TypeString,
+					Description: `The name of the pod to inspect. Preferred over service_account, as it also allows inspecting the tokens mounted into the pod.`,
+				},
+				"service_account": {
+					Type:        gollm.TypeString,
+					Description: `The name of the service account to inspect, if no pod is specified.`,
+				},
+			},
+		},
+	}
+}
+
+// WorkloadIdentityReport is the result of the inspect_workload_identity tool.
+type WorkloadIdentityReport struct {
+	Namespace      string               `json:"namespace,omitempty"`
+	Pod            string               `json:"pod,omitempty"`
+	ServiceAccount string               `json:"service_account,omitempty"`
+	Provider       string               `json:"provider,omitempty"`
+	Identity       string               `json:"identity,omitempty"`
+	Tokens         []ProjectedTokenInfo `json:"tokens,omitempty"`
+	Problems       []string             `json:"problems,omitempty"`
+	Error          string               `json:"error,omitempty"`
+}
+
+// ProjectedTokenInfo describes a service account token projected into a pod.
+type ProjectedTokenInfo struct {
+	Volume            string `json:"volume"`
+	Path              string `json:"path"`
+	Audience          string `json:"audience,omitempty"`
+	ExpirationSeconds int64  `json:"expiration_seconds,omitempty"`
+}
+
+func (t *WorkloadIdentityTool) ResultSchema() *gollm.Schema {
+	return resultSchemaFor[*WorkloadIdentityReport]()
+}
+
+func (t *WorkloadIdentityTool) Run(ctx context.Context, args map[string]any) (any, error) {
+	namespace := stringArg(args, "namespace")
+	podName := stringArg(args, "pod")
+	serviceAccountName := stringArg(args, "service_account")
+
+	var namespaceArgs []string
+	if namespace != "" {
+		namespaceArgs = []string{"--namespace", namespace}
+	}
+
+	report := &WorkloadIdentityReport{Namespace: namespace, Pod: podName}
+
+	var pod *podObject
+	if podName != "" {
+		pod = &podObject{}
+		if err := kubectlGetJSON(ctx, pod, append([]string{"pod", podName}, namespaceArgs...)...); err != nil {
+			report.Error = err.Error()
+			return report, nil
+		}
+		serviceAccountName = pod.Spec.ServiceAccountName
+		if serviceAccountName == "" {
+			serviceAccountName = "default"
+		}
+	}
+	if serviceAccountName == "" {
+		report.Error = "one of pod or service_account must be specified"
+		return report, nil
+	}
+
+	sa := &serviceAccountObject{}
+	if err := kubectlGetJSON(ctx, sa, append([]string{"serviceaccount", serviceAccountName}, namespaceArgs...)...); err != nil {
+		report.ServiceAccount = serviceAccountName
+		report.Error = err.Error()
+		return report, nil
+	}
+
+	analyzed := analyzeWorkloadIdentity(sa, pod)
+	analyzed.Namespace = report.Namespace
+	if analyzed.Namespace == "" {
+		analyzed.Namespace = sa.Metadata.Namespace
+	}
+	return analyzed, nil
+}
+
+// analyzeWorkloadIdentity inspects the service account (and optionally the pod using it)
+// and reports the cloud identity along with any problems found.
+func analyzeWorkloadIdentity(sa *serviceAccountObject, pod *podObject) *WorkloadIdentityReport {
+	report := &WorkloadIdentityReport{
+		ServiceAccount: sa.Metadata.Name,
+	}
+	if pod != nil {
+		report.Pod = pod.Metadata.Name
+		report.Tokens = projectedTokens(pod)
+	}
+
+	annotations := sa.Metadata.Annotations
+	expectedAudience := ""
+	switch {
+	case annotations[gkeServiceAccountAnnotation] != "":
+		report.Provider = "gke-workload-identity"
+		report.Identity = annotations[gkeServiceAccountAnnotation]
+		if !isGCPServiceAccountEmail(report.Identity) {
+			report.Problems = append(report.Problems, fmt.Sprintf("annotation %s=%q is not a valid Google service account email", gkeServiceAccountAnnotation, report.Identity))
+		}
+
+	case annotations[eksRoleARNAnnotation] != "":
+		report.Provider = "eks-irsa"
+		report.Identity = annotations[eksRoleARNAnnotation]
+		if !strings.HasPrefix(report.Identity, "arn:aws") || !strings.Contains(report.Identity, ":role/") {
+			report.Problems = append(report.Problems, fmt.Sprintf("annotation %s=%q is not a valid IAM role ARN", eksRoleARNAnnotation, report.Identity))
+		}
+		expectedAudience = eksDefaultAudience
+		if audience := annotations[eksAudienceAnnotation]; audience != "" {
+			expectedAudience = audience
+		}
+		if pod != nil {
+			if roleARN, ok := podEnvValue(pod, "AWS_ROLE_ARN"); !ok {
+				report.Problems = append(report.Problems, "AWS_ROLE_ARN is not set in the pod; the EKS pod identity webhook did not mutate it (was the pod created before the service account was annotated? restart it)")
+			} else if roleARN != report.Identity {
+				report.Problems = append(report.Problems, fmt.Sprintf("AWS_ROLE_ARN in the pod (%q) does not match the service account annotation (%q); restart the pod to pick up the change", roleARN, report.Identity))
+			}
+		}
+
+	case annotations[azureClientIDAnnotation] != "":
+		report.Provider = "azure-workload-identity"
+		report.Identity = annotations[azureClientIDAnnotation]
+		expectedAudience = azureAudience
+		if pod != nil && pod.Metadata.Labels[azureUseLabel] != "true" {
+			report.Problems = append(report.Problems, fmt.Sprintf("pod is missing the label %s=true, so the Azure workload identity webhook will not inject a token", azureUseLabel))
+		}
+
+	default:
+		report.Provider = "none"
+		report.Problems = append(report.Problems, fmt.Sprintf("service account %q has no workload identity annotation (%s, %s or %s); the workload will use the node's identity, if any",
+			sa.Metadata.Name, gkeServiceAccountAnnotation, eksRoleARNAnnotation, azureClientIDAnnotation))
+	}
+
+	if pod != nil && expectedAudience != "" {
+		found := false
+		for _, token := range report.Tokens {
+			if token.Audience == expectedAudience {
+				found = true
+			}
+		}
+		if !found {
+			report.Problems = append(report.Problems, fmt.Sprintf("no projected service account token with audience %q is mounted into the pod", expectedAudience))
+		}
+	}
+
+	for _, token := range report.Tokens {
+		if token.ExpirationSeconds != 0 && token.ExpirationSeconds < minTokenExpirationSeconds {
+			report.Problems = append(report.Problems, fmt.Sprintf("projected token in volume %q expires after %ds, below the minimum of %ds", token.Volume, token.ExpirationSeconds, minTokenExpirationSeconds))
+		}
+	}
+
+	return report
+}
+
+// projectedTokens returns the service account tokens projected into the pod.
+func projectedTokens(pod *podObject) []ProjectedTokenInfo {
+	var tokens []ProjectedTokenInfo
+	for _, v := range pod.Spec.Volumes {
+		if v.Projected == nil {
+			continue
+		}
+		for _, source := range v.Projected.Sources {
+			token := source.ServiceAccountToken
+			if token == nil {
+				continue
+			}
+			info := ProjectedTokenInfo{
+				Volume:   v.Name,
+				Path:     token.Path,
+				Audience: token.Audience,
+			}
+			if token.ExpirationSeconds != nil {
+				info.ExpirationSeconds = *token.ExpirationSeconds
+			}
+			tokens = append(tokens, info)
+		}
+	}
+	return tokens
+}
+
+// podEnvValue returns the value of the named environment variable in the first container setting it.
+func podEnvValue(pod *podObject, name string) (string, bool) {
+	for _, c := range pod.Spec.Containers {
+		for _, env := range c.Env {
+			if env.Name == name {
+				return env.Value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (t *WorkloadIdentityTool) IsInteractive(args map[string]any) (bool, error) {
+	return false, nil
+}
+
+func (t *WorkloadIdentityTool) CheckModifiesResource(args map[string]any) string {
+	return "no"
+}
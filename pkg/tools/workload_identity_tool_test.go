@@ -0,0 +1,162 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tools
+
+import (
+	"testing"
+)
+
+func TestAnalyzeWorkloadIdentity(t *testing.T) {
+	int64Ptr := func(v int64) *int64 { return &v }
+
+	tokenVolume := func(name, audience string, expirationSeconds int64) volume {
+		return volume{
+			Name: name,
+			Projected: &projectedVolume{
+				Sources: []volumeProjection{
+					{ServiceAccountToken: &serviceAccountTokenProjection{
+						Audience:          audience,
+						ExpirationSeconds: int64Ptr(expirationSeconds),
+						Path:              "token",
+					}},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		sa               *serviceAccountObject
+		pod              *podObject
+		expectedProvider string
+		expectedIdentity string
+		expectedProblems int
+	}{
+		{
+			name:             "no annotations",
+			sa:               &serviceAccountObject{Metadata: objectMeta{Name: "default"}},
+			expectedProvider: "none",
+			expectedProblems: 1,
+		},
+		{
+			name: "valid gke workload identity",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				gkeServiceAccountAnnotation: "app-sa@my-project.iam.gserviceaccount.com",
+			}}},
+			expectedProvider: "gke-workload-identity",
+			expectedIdentity: "app-sa@my-project.iam.gserviceaccount.com",
+			expectedProblems: 0,
+		},
+		{
+			name: "compute engine default service account",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				gkeServiceAccountAnnotation: "123456789012-compute@developer.gserviceaccount.com",
+			}}},
+			expectedProvider: "gke-workload-identity",
+			expectedIdentity: "123456789012-compute@developer.gserviceaccount.com",
+			expectedProblems: 0,
+		},
+		{
+			name: "app engine default service account",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				gkeServiceAccountAnnotation: "my-project@appspot.gserviceaccount.com",
+			}}},
+			expectedProvider: "gke-workload-identity",
+			expectedIdentity: "my-project@appspot.gserviceaccount.com",
+			expectedProblems: 0,
+		},
+		{
+			name: "other google service account",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				gkeServiceAccountAnnotation: "service-123456789012@gcp-sa-example.gserviceaccount.com",
+			}}},
+			expectedProvider: "gke-workload-identity",
+			expectedIdentity: "service-123456789012@gcp-sa-example.gserviceaccount.com",
+			expectedProblems: 0,
+		},
+		{
+			name: "gke service account in another domain",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				gkeServiceAccountAnnotation: "app-sa@my-project.iam.example.com",
+			}}},
+			expectedProvider: "gke-workload-identity",
+			expectedIdentity: "app-sa@my-project.iam.example.com",
+			expectedProblems: 1,
+		},
+		{
+			name: "invalid gke service account",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				gkeServiceAccountAnnotation: "app-sa",
+			}}},
+			expectedProvider: "gke-workload-identity",
+			expectedIdentity: "app-sa",
+			expectedProblems: 1,
+		},
+		{
+			name: "irsa with injected token",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				eksRoleARNAnnotation: "arn:aws:iam::123456789012:role/app",
+			}}},
+			pod: &podObject{
+				Metadata: objectMeta{Name: "app-1"},
+				Spec: podSpec{
+					Containers: []container{{Name: "app", Env: []envVar{{Name: "AWS_ROLE_ARN", Value: "arn:aws:iam::123456789012:role/app"}}}},
+					Volumes:    []volume{tokenVolume("aws-iam-token", eksDefaultAudience, 86400)},
+				},
+			},
+			expectedProvider: "eks-irsa",
+			expectedIdentity: "arn:aws:iam::123456789012:role/app",
+			expectedProblems: 0,
+		},
+		{
+			name: "irsa pod not mutated",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				eksRoleARNAnnotation: "arn:aws:iam::123456789012:role/app",
+			}}},
+			pod:              &podObject{Metadata: objectMeta{Name: "app-1"}},
+			expectedProvider: "eks-irsa",
+			expectedIdentity: "arn:aws:iam::123456789012:role/app",
+			expectedProblems: 2,
+		},
+		{
+			name: "azure pod missing label and short-lived token",
+			sa: &serviceAccountObject{Metadata: objectMeta{Name: "app", Annotations: map[string]string{
+				azureClientIDAnnotation: "00000000-0000-0000-0000-000000000000",
+			}}},
+			pod: &podObject{
+				Metadata: objectMeta{Name: "app-1"},
+				Spec:     podSpec{Volumes: []volume{tokenVolume("azure-identity-token", azureAudience, 300)}},
+			},
+			expectedProvider: "azure-workload-identity",
+			expectedIdentity: "00000000-0000-0000-0000-000000000000",
+			expectedProblems: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := analyzeWorkloadIdentity(tt.sa, tt.pod)
+			if report.Provider != tt.expectedProvider {
+				t.Errorf("expected provider %q, got %q", tt.expectedProvider, report.Provider)
+			}
+			if report.Identity != tt.expectedIdentity {
+				t.Errorf("expected identity %q, got %q", tt.expectedIdentity, report.Identity)
+			}
+			if len(report.Problems) != tt.expectedProblems {
+				t.Errorf("expected %d problems, got %d: %v", tt.expectedProblems, len(report.Problems), report.Problems)
+			}
+		})
+	}
+}
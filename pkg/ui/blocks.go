@@ -15,7 +15,10 @@
 package ui
 
 import (
+	"fmt"
 	"html/template"
+	"slices"
+	"strings"
 )
 
 // AgentTextBlock is used to render agent textual responses
@@ -86,8 +89,32 @@ type FunctionCallRequestBlock struct {
 
 	// result is populated after the function call has been executed
 	result any
+
+	// expanded is set when the user asks to see a large result in full
+	expanded bool
+
+	// progressMessage and progressFraction are the last progress reported while the call is running;
+	// progressFraction is between 0 and 1, or negative if unknown.
+	hasProgress      bool
+	progressMessage  string
+	progressFraction float64
+
+	// liveOutput holds the last lines of output of the running call, for tools that stream their output;
+	// partialOutput is the last line if it is not complete yet, and hiddenOutputLines counts the dropped lines.
+	liveOutput        []string
+	partialOutput     string
+	hiddenOutputLines int
 }
 
+// liveOutputLines is the number of lines of the output of a running call that we keep, and show.
+const liveOutputLines = 10
+
+const (
+	// Results with more lines or bytes than this are folded (collapsed to a summary line) until expanded.
+	foldResultLines = 20
+	foldResultBytes = 2000
+)
+
 func NewFunctionCallRequestBlock() *FunctionCallRequestBlock {
 	return &FunctionCallRequestBlock{}
 }
@@ -117,6 +144,113 @@ func (b *FunctionCallRequestBlock) ResultHTML() template.HTML {
 	return safeHTML
 }
 
+// ResultText returns the result formatted as plain text.
+func (b *FunctionCallRequestBlock) ResultText() string {
+	return strings.TrimRight(formatResultAsText(b.result), "\n")
+}
+
+// ResultSummary summarizes the size of the result, e.g. "120 lines, 8.2 KB".
+func (b *FunctionCallRequestBlock) ResultSummary() string {
+	text := b.ResultText()
+	lines := strings.Count(text, "\n") + 1
+	if text == "" {
+		lines = 0
+	}
+	size := fmt.Sprintf("%d bytes", len(text))
+	if len(text) >= 1024 {
+		size = fmt.Sprintf("%.1f KB", float64(len(text))/1024)
+	}
+	return fmt.Sprintf("%d lines, %s", lines, size)
+}
+
+// Folded returns true if the result is large and should be collapsed to a summary, unless the user expanded it.
+func (b *FunctionCallRequestBlock) Folded() bool {
+	if b.expanded || b.result == nil {
+		return false
+	}
+	text := b.ResultText()
+	return len(text) > foldResultBytes || strings.Count(text, "\n") >= foldResultLines
+}
+
+// Expanded returns true if the user asked to see the result in full.
+func (b *FunctionCallRequestBlock) Expanded() bool {
+	return b.expanded
+}
+
+// SetExpanded expands (or folds) a large result.
+func (b *FunctionCallRequestBlock) SetExpanded(expanded bool) *FunctionCallRequestBlock {
+	b.expanded = expanded
+	b.doc.blockChanged(b)
+	return b
+}
+
+// Progress returns the last progress reported while the call is running: a description of the current step,
+// and the part of the work done (between 0 and 1, or negative if unknown). ok is false if no progress was reported.
+func (b *FunctionCallRequestBlock) Progress() (message string, fraction float64, ok bool) {
+	return b.progressMessage, b.progressFraction, b.hasProgress
+}
+
+// ProgressPercent returns the part of the work done as a percentage, or -1 if unknown; for templates.
+func (b *FunctionCallRequestBlock) ProgressPercent() int {
+	if !b.hasProgress || b.progressFraction < 0 {
+		return -1
+	}
+	return int(b.progressFraction * 100)
+}
+
+// ProgressMessage returns the description of the current step of a running call; for templates.
+func (b *FunctionCallRequestBlock) ProgressMessage() string {
+	return b.progressMessage
+}
+
+// SetProgress reports the progress of the running call.
+func (b *FunctionCallRequestBlock) SetProgress(message string, fraction float64) *FunctionCallRequestBlock {
+	b.hasProgress = true
+	b.progressMessage = message
+	b.progressFraction = fraction
+	b.doc.blockChanged(b)
+	return b
+}
+
+// AppendOutput adds output of the running call, as it is produced. Only the last lines are kept;
+// the result of the call holds the complete output.
+func (b *FunctionCallRequestBlock) AppendOutput(text string) {
+	lines := strings.Split(b.partialOutput+text, "\n")
+	b.partialOutput = lines[len(lines)-1]
+	b.liveOutput = append(b.liveOutput, lines[:len(lines)-1]...)
+	if n := len(b.liveOutput) - liveOutputLines; n > 0 {
+		b.hiddenOutputLines += n
+		b.liveOutput = slices.Delete(b.liveOutput, 0, n)
+	}
+	b.doc.blockChanged(b)
+}
+
+// Write appends to the output of the running call, so the block can receive the output of a command.
+func (b *FunctionCallRequestBlock) Write(p []byte) (int, error) {
+	b.AppendOutput(string(p))
+	return len(p), nil
+}
+
+// LiveOutput returns the last lines of output of the running call, or "" if it has not streamed any.
+func (b *FunctionCallRequestBlock) LiveOutput() string {
+	lines := b.liveOutput
+	if b.partialOutput != "" {
+		lines = append(slices.Clip(lines), b.partialOutput)
+	}
+	if len(lines) > liveOutputLines {
+		lines = lines[len(lines)-liveOutputLines:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// HiddenOutputLines returns the number of lines of output of the running call that are no longer shown.
+func (b *FunctionCallRequestBlock) HiddenOutputLines() int {
+	if b.partialOutput != "" && len(b.liveOutput) == liveOutputLines {
+		return b.hiddenOutputLines + 1
+	}
+	return b.hiddenOutputLines
+}
+
 func (b *FunctionCallRequestBlock) SetDescription(description string) *FunctionCallRequestBlock {
 	b.description = description
 	b.doc.blockChanged(b)
@@ -168,6 +302,9 @@ type InputTextBlock struct {
 
 	// editable is true if the input text block is editable
 	editable bool
+
+	// initialText is the text the input starts with, which the user can edit
+	initialText string
 }
 
 func NewInputTextBlock() *InputTextBlock {
@@ -196,6 +333,17 @@ func (b *InputTextBlock) Editable() bool {
 	return b.editable
 }
 
+// SetInitialText sets the text the input starts with, e.g. a command for the user to edit.
+func (b *InputTextBlock) SetInitialText(text string) *InputTextBlock {
+	b.initialText = text
+	b.doc.blockChanged(b)
+	return b
+}
+
+func (b *InputTextBlock) InitialText() string {
+	return b.initialText
+}
+
 func (b *InputTextBlock) Text() (string, error) {
 	return b.text.Get()
 }
@@ -0,0 +1,109 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// minFrameBudget and maxFrameBudget bound how long we coalesce streaming updates before rendering them.
+	minFrameBudget = 30 * time.Millisecond
+	maxFrameBudget = 60 * time.Millisecond
+)
+
+// coalescingSubscriber batches the updates to a streaming AgentTextBlock, so that renderers
+// don't re-render on every streamed token, which causes flicker and high CPU usage.
+// Updates are delivered at most once per frame; the frame budget adapts to how long rendering takes.
+// Any other change, including the end of streaming, flushes the pending update immediately, preserving order.
+type coalescingSubscriber struct {
+	subscriber Subscriber
+
+	// deliverMutex serializes calls to the subscriber, as we also deliver from a timer.
+	deliverMutex sync.Mutex
+
+	// mutex guards the fields below.
+	mutex       sync.Mutex
+	pendingDoc  *Document
+	pending     Block
+	timer       *time.Timer
+	frameBudget time.Duration
+}
+
+// NewCoalescingSubscriber wraps subscriber so that updates to streaming text are coalesced.
+func NewCoalescingSubscriber(subscriber Subscriber) Subscriber {
+	return &coalescingSubscriber{
+		subscriber:  subscriber,
+		frameBudget: minFrameBudget,
+	}
+}
+
+func (s *coalescingSubscriber) DocumentChanged(doc *Document, block Block) {
+	if textBlock, ok := block.(*AgentTextBlock); ok && textBlock.Streaming() {
+		s.mutex.Lock()
+		if s.pending != nil && s.pending != block {
+			s.mutex.Unlock()
+			s.flush()
+			s.mutex.Lock()
+		}
+		s.pendingDoc = doc
+		s.pending = block
+		if s.timer == nil {
+			s.timer = time.AfterFunc(s.frameBudget, s.flush)
+		}
+		s.mutex.Unlock()
+		return
+	}
+
+	s.flush()
+	s.deliver(doc, block)
+}
+
+// flush delivers the pending update, if there is one.
+func (s *coalescingSubscriber) flush() {
+	s.mutex.Lock()
+	doc, block := s.pendingDoc, s.pending
+	s.pendingDoc, s.pending = nil, nil
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mutex.Unlock()
+
+	if block != nil {
+		s.deliver(doc, block)
+	}
+}
+
+func (s *coalescingSubscriber) deliver(doc *Document, block Block) {
+	s.deliverMutex.Lock()
+	defer s.deliverMutex.Unlock()
+
+	start := time.Now()
+	s.subscriber.DocumentChanged(doc, block)
+	elapsed := time.Since(start)
+
+	if _, ok := block.(*AgentTextBlock); !ok {
+		// Other blocks may wait for user input, so they don't tell us how long rendering takes.
+		return
+	}
+
+	// Give slow renderers more time between frames, so they spend most of it idle.
+	budget := min(max(4*elapsed, minFrameBudget), maxFrameBudget)
+	s.mutex.Lock()
+	s.frameBudget = budget
+	s.mutex.Unlock()
+}
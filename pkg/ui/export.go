@@ -0,0 +1,303 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ExportFormat is the format used when exporting a Document.
+type ExportFormat string
+
+const (
+	ExportFormatMarkdown ExportFormat = "markdown"
+	ExportFormatHTML     ExportFormat = "html"
+	ExportFormatJSON     ExportFormat = "json"
+)
+
+// ExportFormatFromPath picks the export format based on the file extension, defaulting to markdown.
+func ExportFormatFromPath(p string) ExportFormat {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".html", ".htm":
+		return ExportFormatHTML
+	case ".json":
+		return ExportFormatJSON
+	default:
+		return ExportFormatMarkdown
+	}
+}
+
+// ExportDetail is how much of the conversation an export includes.
+type ExportDetail string
+
+const (
+	// ExportDetailFull includes everything, with the output of tool calls.
+	ExportDetailFull ExportDetail = "full"
+	// ExportDetailCommands leaves out the output of tool calls.
+	ExportDetailCommands ExportDetail = "commands"
+	// ExportDetailAnswers only includes the queries and the answers.
+	ExportDetailAnswers ExportDetail = "answers"
+)
+
+// exportEntry is a format-independent view of a block, used for exporting.
+type exportEntry struct {
+	Kind   string
+	Title  string
+	Text   string
+	Output string
+	// Time is when the block was added, formatted with the document's TimeFormat.
+	// With relative times, it is the time since the first entry.
+	Time string
+}
+
+// ExportDocument renders the blocks of the document into a shareable report, with the given detail.
+// JSON exports are for programs, so they always include everything.
+func ExportDocument(doc *Document, w io.Writer, format ExportFormat, detail ExportDetail) error {
+	if format == ExportFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(struct {
+			ExportedAt time.Time           `json:"exported_at"`
+			Messages   []TranscriptMessage `json:"messages"`
+		}{ExportedAt: time.Now(), Messages: Transcript(doc)})
+	}
+
+	timeFormat := doc.TimeFormat()
+	var entries []exportEntry
+	var start time.Time
+	for _, block := range doc.Blocks() {
+		if entry, ok := exportBlock(block); ok {
+			switch {
+			case detail == ExportDetailAnswers && entry.Kind != "user" && entry.Kind != "agent":
+				continue
+			case detail == ExportDetailCommands:
+				entry.Output = ""
+			}
+			addedAt := doc.AddedAt(block)
+			if start.IsZero() {
+				start = addedAt
+			}
+			entry.Time = timeFormat.Elapsed(start, addedAt)
+			entries = append(entries, entry)
+		}
+	}
+	exportedAt := timeFormat.Absolute(time.Now())
+
+	switch format {
+	case ExportFormatMarkdown:
+		return exportMarkdown(w, exportedAt, entries)
+	case ExportFormatHTML:
+		return exportHTML(w, exportedAt, entries)
+	default:
+		return fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+func exportBlock(block Block) (exportEntry, bool) {
+	switch block := block.(type) {
+	case *AgentTextBlock:
+		if block.Text() == "" {
+			return exportEntry{}, false
+		}
+		return exportEntry{Kind: "agent", Title: "Assistant", Text: block.Text()}, true
+	case *ErrorBlock:
+		return exportEntry{Kind: "error", Title: "Error", Text: block.Text()}, true
+	case *FunctionCallRequestBlock:
+		return exportEntry{Kind: "function-call", Title: "Running", Text: block.Description(), Output: formatResultAsText(block.Result())}, true
+	case *InputTextBlock:
+		text, err := block.Text()
+		if err != nil || strings.TrimSpace(text) == "" {
+			return exportEntry{}, false
+		}
+		return exportEntry{Kind: "user", Title: "User", Text: strings.TrimSpace(text)}, true
+	case *InputOptionBlock:
+		selection, err := block.Selection().Get()
+		if err != nil || selection == "" {
+			selection = "(no answer)"
+		}
+		for _, option := range block.Options {
+			if option.Key == selection {
+				selection = option.Message
+			}
+		}
+		return exportEntry{Kind: "confirmation", Title: "Confirmation", Text: strings.TrimSpace(block.Prompt), Output: selection}, true
+	default:
+		return exportEntry{}, false
+	}
+}
+
+// TranscriptMessage is a block of a document, in a form for programs to consume.
+type TranscriptMessage struct {
+	// Role is one of "user", "assistant", "tool_call", "confirmation" and "error".
+	Role string    `json:"role"`
+	Time time.Time `json:"time"`
+	// Text is the text of the message; for tool calls, the description of the call,
+	// and for confirmations, the prompt.
+	Text string `json:"text"`
+	// Result is the result of a tool call, as returned by the tool.
+	Result any `json:"result,omitempty"`
+	// Choice is the option chosen for a confirmation.
+	Choice string `json:"choice,omitempty"`
+}
+
+// Transcript returns the messages of the document, in order.
+func Transcript(doc *Document) []TranscriptMessage {
+	messages := []TranscriptMessage{}
+	for _, block := range doc.Blocks() {
+		entry, ok := exportBlock(block)
+		if !ok {
+			continue
+		}
+		message := TranscriptMessage{Time: doc.AddedAt(block), Text: entry.Text}
+		switch block := block.(type) {
+		case *FunctionCallRequestBlock:
+			message.Role = "tool_call"
+			message.Result = block.Result()
+		case *InputOptionBlock:
+			message.Role = "confirmation"
+			message.Choice = entry.Output
+		case *AgentTextBlock:
+			message.Role = "assistant"
+		default:
+			message.Role = entry.Kind
+		}
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// formatResultAsText renders a function call result for inclusion in an export.
+func formatResultAsText(result any) string {
+	switch result := result.(type) {
+	case nil:
+		return ""
+	case CanFormatAsText:
+		return result.FormatAsText()
+	case string:
+		return result
+	default:
+		b, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Sprintf("%v", result)
+		}
+		return string(b)
+	}
+}
+
+func exportMarkdown(w io.Writer, exportedAt string, entries []exportEntry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# kubectl-ai session\n\n_Exported at %s_\n\n", exportedAt)
+	for _, entry := range entries {
+		switch entry.Kind {
+		case "user":
+			if entry.Time != "" {
+				fmt.Fprintf(&b, "## %s (%s)\n\n", entry.Title, entry.Time)
+			} else {
+				fmt.Fprintf(&b, "## %s\n\n", entry.Title)
+			}
+			for _, line := range strings.Split(entry.Text, "\n") {
+				fmt.Fprintf(&b, "> %s\n", line)
+			}
+			b.WriteString("\n")
+		case "function-call":
+			fmt.Fprintf(&b, "**%s:** %s\n\n", entry.Title, codeSpan(entry.Text))
+			if entry.Output != "" {
+				// The fence must be longer than any run of backticks in the output, which would end it otherwise.
+				fence := strings.Repeat("`", max(3, longestBacktickRun(entry.Output)+1))
+				fmt.Fprintf(&b, "%stext\n%s\n%s\n\n", fence, strings.TrimRight(entry.Output, "\n"), fence)
+			}
+		case "confirmation":
+			fmt.Fprintf(&b, "**%s:** %s → %s\n\n", entry.Title, entry.Text, entry.Output)
+		default:
+			fmt.Fprintf(&b, "**%s:**\n\n%s\n\n", entry.Title, strings.TrimSpace(entry.Text))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// codeSpan returns s as inline markdown code, delimited by more backticks than any run of backticks in s.
+func codeSpan(s string) string {
+	delimiter := strings.Repeat("`", longestBacktickRun(s)+1)
+	if strings.HasPrefix(s, "`") || strings.HasSuffix(s, "`") {
+		// A space on each side is stripped, and keeps the backticks of s apart from the delimiter.
+		s = " " + s + " "
+	}
+	return delimiter + s + delimiter
+}
+
+// longestBacktickRun returns the length of the longest run of backticks in s.
+func longestBacktickRun(s string) int {
+	longest, run := 0, 0
+	for _, r := range s {
+		if r != '`' {
+			run = 0
+			continue
+		}
+		run++
+		longest = max(longest, run)
+	}
+	return longest
+}
+
+var exportHTMLTemplate = template.Must(template.New("export").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>kubectl-ai session</title>
+<style>
+body { font-family: sans-serif; max-width: 960px; margin: 2em auto; color: #1a202c; }
+.entry { margin: 12px 0; padding: 8px 12px; border-radius: 4px; }
+.title { font-weight: bold; margin-bottom: 4px; }
+.time { font-weight: normal; font-size: 0.85em; color: #718096; }
+.text, pre { white-space: pre-wrap; margin: 0; }
+.user { background-color: #ebf8ff; }
+.agent { background-color: #f7fafc; }
+.function-call { background-color: #f5f5f5; font-family: monospace; }
+.function-call pre { margin-top: 8px; padding: 8px; background-color: #e2e8f0; }
+.confirmation { background-color: #fffff0; }
+.error { background-color: #fff5f5; color: #c53030; }
+</style>
+</head>
+<body>
+<h1>kubectl-ai session</h1>
+<p><em>Exported at {{.ExportedAt}}</em></p>
+{{range .Entries}}
+<div class="entry {{.Kind}}">
+  <div class="title">{{.Title}}{{if .Time}} <span class="time">{{.Time}}</span>{{end}}</div>
+  <div class="text">{{.Text}}</div>
+  {{if .Output}}<pre>{{.Output}}</pre>{{end}}
+</div>
+{{end}}
+</body>
+</html>
+`))
+
+func exportHTML(w io.Writer, exportedAt string, entries []exportEntry) error {
+	data := struct {
+		ExportedAt string
+		Entries    []exportEntry
+	}{
+		ExportedAt: exportedAt,
+		Entries:    entries,
+	}
+	return exportHTMLTemplate.Execute(w, data)
+}
@@ -0,0 +1,72 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportMarkdownFences(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		output     string
+		wantCall   string
+		wantOutput string
+	}{
+		{
+			name:       "plain output",
+			command:    "kubectl get pods",
+			output:     "NAME    READY\nweb-0   1/1\n",
+			wantCall:   "**Running:** `kubectl get pods`\n\n",
+			wantOutput: "```text\nNAME    READY\nweb-0   1/1\n```\n\n",
+		},
+		{
+			name:       "output with a fence",
+			command:    "cat README.md",
+			output:     "Install with:\n```\nmake install\n```\n",
+			wantCall:   "**Running:** `cat README.md`\n\n",
+			wantOutput: "````text\nInstall with:\n```\nmake install\n```\n````\n\n",
+		},
+		{
+			name:       "output with a longer fence",
+			command:    "cat notes.md",
+			output:     "`````\nnested\n`````",
+			wantCall:   "**Running:** `cat notes.md`\n\n",
+			wantOutput: "``````text\n`````\nnested\n`````\n``````\n\n",
+		},
+		{
+			name:       "command with backticks",
+			command:    "echo `date`",
+			output:     "",
+			wantCall:   "**Running:** `` echo `date` ``\n\n",
+			wantOutput: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := []exportEntry{{Kind: "function-call", Title: "Running", Text: tt.command, Output: tt.output}}
+			var b strings.Builder
+			if err := exportMarkdown(&b, "now", entries); err != nil {
+				t.Fatal(err)
+			}
+			want := "# kubectl-ai session\n\n_Exported at now_\n\n" + tt.wantCall + tt.wantOutput
+			if got := b.String(); got != want {
+				t.Errorf("exportMarkdown() =\n%s\nexpected\n%s", got, want)
+			}
+		})
+	}
+}
@@ -131,15 +131,15 @@ func (u *HTMLUserInterface) handlePOSTSendMessage(w http.ResponseWriter, req *ht
 		}
 	}
 
-	if inputBlock == nil {
-		log.Info("no input block found")
-		http.Error(w, "no input block found", http.StatusInternalServerError)
-		return
+	if inputBlock == nil || !inputBlock.Editable() {
+		// The agent is working; it will pick up the message as guidance before its next step.
+		log.Info("no input block waiting, interjecting")
+		u.doc.Interject(q)
+	} else {
+		inputBlock.Observable().Set(q, nil)
+		inputBlock.SetEditable(false)
 	}
 
-	inputBlock.Observable().Set(q, nil)
-	inputBlock.SetEditable(false)
-
 	var bb bytes.Buffer
 	bb.WriteString("ok")
 	w.Write(bb.Bytes())
@@ -197,6 +197,11 @@ func (u *HTMLUserInterface) handlePOSTChooseOption(w http.ResponseWriter, req *h
 }
 
 func (u *HTMLUserInterface) serveDocStream(w http.ResponseWriter, req *http.Request) {
+	streamDocument(w, req, u.doc, renderBlock)
+}
+
+// streamDocument sends the blocks of doc, rendered with render, as server-sent events whenever the document changes.
+func streamDocument(w http.ResponseWriter, req *http.Request, doc *ui.Document, render func(ctx context.Context, w io.Writer, block ui.Block) error) {
 	ctx := req.Context()
 	log := klog.FromContext(ctx)
 
@@ -212,10 +217,10 @@ func (u *HTMLUserInterface) serveDocStream(w http.ResponseWriter, req *http.Requ
 		var sse bytes.Buffer
 		sse.WriteString("event: ReplaceAll\ndata: ")
 
-		blocks := u.doc.Blocks()
+		blocks := doc.Blocks()
 		var html bytes.Buffer
 		for _, block := range blocks {
-			if err := u.renderBlock(ctx, &html, block); err != nil {
+			if err := render(ctx, &html, block); err != nil {
 				log.Error(err, "rendering block")
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
@@ -239,7 +244,7 @@ func (u *HTMLUserInterface) serveDocStream(w http.ResponseWriter, req *http.Requ
 		sendAllBlocks()
 	}
 
-	subscription := u.doc.AddSubscription(ui.SubscriberFromFunc(onDocChange))
+	subscription := doc.AddSubscription(ui.NewCoalescingSubscriber(ui.SubscriberFromFunc(onDocChange)))
 	defer subscription.Close()
 
 	// Send initial message
@@ -294,7 +299,7 @@ func (u *HTMLUserInterface) Close() error {
 	return errors.Join(errs...)
 }
 
-func (u *HTMLUserInterface) renderBlock(ctx context.Context, w io.Writer, block ui.Block) error {
+func renderBlock(ctx context.Context, w io.Writer, block ui.Block) error {
 	switch block := block.(type) {
 	case *ui.ErrorBlock:
 		return renderTemplate(ctx, w, "error_block.html", block)
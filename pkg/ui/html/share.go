@@ -0,0 +1,300 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package html
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/ui"
+	"k8s.io/klog/v2"
+)
+
+// ShareRole is what a user attached to a shared session can do.
+type ShareRole string
+
+const (
+	// ShareRoleViewer can follow the conversation.
+	ShareRoleViewer ShareRole = "viewer"
+	// ShareRoleApprover can also approve or reject the tool calls waiting for approval.
+	ShareRoleApprover ShareRole = "approver"
+)
+
+// shareCookie remembers the token of a user once they opened the link with the token.
+const shareCookie = "kubectl-ai-share"
+
+// SharedSession serves a session driven from another user interface, such as the terminal, to other users.
+// They attach by opening a link with a token that gives them a role.
+type SharedSession struct {
+	httpServer         *http.Server
+	httpServerListener net.Listener
+
+	doc    *ui.Document
+	tokens map[ShareRole]string
+
+	mutex sync.Mutex
+	// pending is the tool call waiting for approval, if any.
+	pending *approvalRequest
+	lastID  int
+}
+
+// approvalRequest is a tool call waiting for an approver.
+type approvalRequest struct {
+	ID          int
+	Description string
+	decision    chan bool
+}
+
+func NewSharedSession(doc *ui.Document, listenAddress string) (*SharedSession, error) {
+	s := &SharedSession{
+		doc:    doc,
+		tokens: make(map[ShareRole]string),
+	}
+	for _, role := range []ShareRole{ShareRoleViewer, ShareRoleApprover} {
+		token, err := newShareToken()
+		if err != nil {
+			return nil, fmt.Errorf("generating token for %s: %w", role, err)
+		}
+		s.tokens[role] = token
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /", s.serveIndex)
+	mux.HandleFunc("GET /doc-stream", s.serveDocStream)
+	mux.HandleFunc("GET /approval", s.serveApproval)
+	mux.HandleFunc("POST /approval", s.handlePOSTApproval)
+	s.httpServer = &http.Server{
+		Addr:    listenAddress,
+		Handler: mux,
+	}
+
+	httpServerListener, err := net.Listen("tcp", listenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("starting http server network listener: %w", err)
+	}
+	s.httpServerListener = httpServerListener
+	return s, nil
+}
+
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// URL returns the link that users open to attach to the session with role.
+func (s *SharedSession) URL(role ShareRole) string {
+	return fmt.Sprintf("http://%s/?token=%s", s.httpServerListener.Addr(), s.tokens[role])
+}
+
+func (s *SharedSession) RunServer(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		s.httpServerListener.Close()
+	}()
+	return s.httpServer.Serve(s.httpServerListener)
+}
+
+func (s *SharedSession) Close() error {
+	return s.httpServerListener.Close()
+}
+
+// RequestApproval shows the tool call to approvers, and waits until one of them approves or rejects it.
+func (s *SharedSession) RequestApproval(ctx context.Context, description string) (bool, error) {
+	s.mutex.Lock()
+	s.lastID++
+	request := &approvalRequest{
+		ID:          s.lastID,
+		Description: description,
+		decision:    make(chan bool, 1),
+	}
+	s.pending = request
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		if s.pending == request {
+			s.pending = nil
+		}
+		s.mutex.Unlock()
+	}()
+
+	select {
+	case approved := <-request.decision:
+		return approved, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// role returns the role of the user making the request, from the token in the URL or the cookie.
+func (s *SharedSession) role(req *http.Request) (ShareRole, bool) {
+	token := req.URL.Query().Get("token")
+	if token == "" {
+		if cookie, err := req.Cookie(shareCookie); err == nil {
+			token = cookie.Value
+		}
+	}
+	if token == "" {
+		return "", false
+	}
+	for role, roleToken := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(roleToken)) == 1 {
+			return role, true
+		}
+	}
+	return "", false
+}
+
+func (s *SharedSession) serveIndex(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := klog.FromContext(ctx)
+
+	role, ok := s.role(req)
+	if !ok {
+		http.Error(w, "open the link with the token you were given to attach to this session", http.StatusUnauthorized)
+		return
+	}
+	if token := req.URL.Query().Get("token"); token != "" {
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareCookie,
+			Value:    token,
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteStrictMode,
+		})
+	}
+
+	var bb bytes.Buffer
+	if err := renderTemplate(ctx, &bb, "share.html", map[string]any{"Role": role}); err != nil {
+		log.Error(err, "rendering share.html")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(bb.Bytes())
+}
+
+func (s *SharedSession) serveDocStream(w http.ResponseWriter, req *http.Request) {
+	if _, ok := s.role(req); !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	streamDocument(w, req, s.doc, renderSharedBlock)
+}
+
+// renderSharedBlock renders the blocks of a shared session. Only the driver answers prompts, so attached users
+// do not see the input blocks waiting for an answer.
+func renderSharedBlock(ctx context.Context, w io.Writer, block ui.Block) error {
+	switch block := block.(type) {
+	case *ui.InputTextBlock:
+		if block.Editable() {
+			return nil
+		}
+	case *ui.InputOptionBlock:
+		return nil
+	}
+	return renderBlock(ctx, w, block)
+}
+
+func (s *SharedSession) serveApproval(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := klog.FromContext(ctx)
+
+	role, ok := s.role(req)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	s.mutex.Lock()
+	pending := s.pending
+	s.mutex.Unlock()
+
+	data := map[string]any{
+		"Pending":    pending,
+		"CanApprove": role == ShareRoleApprover,
+	}
+	var bb bytes.Buffer
+	if err := renderTemplate(ctx, &bb, "share_approval.html", data); err != nil {
+		log.Error(err, "rendering share_approval.html")
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(bb.Bytes())
+}
+
+func (s *SharedSession) handlePOSTApproval(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	log := klog.FromContext(ctx)
+
+	role, ok := s.role(req)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if role != ShareRoleApprover {
+		http.Error(w, "only approvers can approve tool calls", http.StatusForbidden)
+		return
+	}
+
+	if err := req.ParseForm(); err != nil {
+		log.Error(err, "parsing form")
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.Atoi(req.FormValue("id"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+	var approved bool
+	switch decision := req.FormValue("decision"); decision {
+	case "approve":
+		approved = true
+	case "reject":
+		approved = false
+	default:
+		http.Error(w, fmt.Sprintf("invalid decision %q", decision), http.StatusBadRequest)
+		return
+	}
+
+	s.mutex.Lock()
+	pending := s.pending
+	if pending != nil && pending.ID == id {
+		s.pending = nil
+	}
+	s.mutex.Unlock()
+
+	if pending == nil || pending.ID != id {
+		// Another approver decided first, or the request was cancelled.
+		http.Error(w, "the tool call is no longer waiting for approval", http.StatusConflict)
+		return
+	}
+	log.Info("tool call decided by approver", "call", pending.Description, "approved", approved)
+	pending.decision <- approved
+
+	w.Write([]byte("ok"))
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Keymap is the set of line-editing keybindings used for the input prompt.
+type Keymap string
+
+const (
+	KeymapEmacs Keymap = "emacs"
+	KeymapVi    Keymap = "vi"
+)
+
+const (
+	inputPrompt        = ">>> "
+	continuationPrompt = "... "
+
+	// Escape sequences for bracketed paste mode: the terminal wraps pasted text in pasteStart and pasteEnd,
+	// so we can tell pasted newlines apart from the user pressing enter.
+	enableBracketedPaste  = "\x1b[?2004h"
+	disableBracketedPaste = "\x1b[?2004l"
+	pasteStart            = "\x1b[200~"
+	pasteEnd              = "\x1b[201~"
+
+	// pastedNewline stands in for newlines in pasted text while the line is being edited,
+	// as line editors submit the line on a newline.
+	pastedNewline = "␤"
+)
+
+// readQuery reads a query, using readLine to read each line.
+// A query continues on the next line if a line ends with a backslash, or while a ``` code fence is open,
+// so that multi-line input such as YAML can be typed in.
+func readQuery(readLine func(prompt string) (string, error)) (string, error) {
+	var lines []string
+	prompt := inputPrompt
+	for {
+		line, err := readLine(prompt)
+		if err != nil {
+			return "", err
+		}
+		line = strings.ReplaceAll(line, pastedNewline, "\n")
+		prompt = continuationPrompt
+
+		if continued, ok := strings.CutSuffix(line, "\\"); ok {
+			lines = append(lines, continued)
+			continue
+		}
+		lines = append(lines, line)
+		query := strings.Join(lines, "\n")
+		if !hasOpenCodeFence(query) {
+			return query, nil
+		}
+	}
+}
+
+// hasOpenCodeFence returns true if the text has a ``` code fence that is not closed.
+func hasOpenCodeFence(text string) bool {
+	open := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			open = !open
+		}
+	}
+	return open
+}
+
+// bracketedPasteReader reads from a terminal in bracketed paste mode.
+// It removes the paste markers, and replaces newlines in pasted text with pastedNewline,
+// so that pasting multi-line text doesn't submit it line by line.
+type bracketedPasteReader struct {
+	r io.ReadCloser
+
+	// pending is input that we have read but not returned yet.
+	pending []byte
+	// out is filtered input that we have not returned yet.
+	out []byte
+	// inPaste is true between pasteStart and pasteEnd.
+	inPaste bool
+	// skipLF is true if the last pasted character was a carriage return, so a following line feed is part of the same newline.
+	skipLF bool
+}
+
+func newBracketedPasteReader(r io.ReadCloser) *bracketedPasteReader {
+	return &bracketedPasteReader{r: r}
+}
+
+func (b *bracketedPasteReader) Read(p []byte) (int, error) {
+	for len(b.out) == 0 {
+		buf := make([]byte, len(p))
+		n, err := b.r.Read(buf)
+		b.pending = append(b.pending, buf[:n]...)
+		b.filter(err != nil)
+		if err != nil && len(b.out) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, b.out)
+	b.out = b.out[n:]
+	return n, nil
+}
+
+// filter moves the input from pending to out.
+// During a paste, input that could be the start of the end marker is kept pending, unless flush is set.
+// Outside a paste we don't wait, so that a lone escape key (e.g. in vi mode) is not delayed;
+// terminals send the start marker together with the pasted text.
+func (b *bracketedPasteReader) filter(flush bool) {
+	for len(b.pending) > 0 {
+		if bytes.HasPrefix(b.pending, []byte(pasteStart)) {
+			b.inPaste = true
+			b.pending = b.pending[len(pasteStart):]
+			continue
+		}
+		if bytes.HasPrefix(b.pending, []byte(pasteEnd)) {
+			b.inPaste = false
+			b.skipLF = false
+			b.pending = b.pending[len(pasteEnd):]
+			continue
+		}
+		if !flush && b.inPaste && len(b.pending) < len(pasteEnd) && strings.HasPrefix(pasteEnd, string(b.pending)) {
+			// Wait for the rest of the marker.
+			return
+		}
+
+		c := b.pending[0]
+		b.pending = b.pending[1:]
+		if !b.inPaste {
+			b.out = append(b.out, c)
+			continue
+		}
+		switch {
+		case c == '\n' && b.skipLF:
+			b.skipLF = false
+		case c == '\r' || c == '\n':
+			b.out = append(b.out, pastedNewline...)
+			b.skipLF = c == '\r'
+		default:
+			b.out = append(b.out, c)
+			b.skipLF = false
+		}
+	}
+}
+
+func (b *bracketedPasteReader) Close() error {
+	return b.r.Close()
+}
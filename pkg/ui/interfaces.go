@@ -29,9 +29,10 @@ type ComputedStyle struct {
 type ColorValue string
 
 const (
-	ColorGreen ColorValue = "green"
-	ColorWhite            = "white"
-	ColorRed              = "red"
+	ColorGreen  ColorValue = "green"
+	ColorWhite             = "white"
+	ColorRed               = "red"
+	ColorYellow            = "yellow"
 )
 
 type StyleOption func(s *ComputedStyle)
@@ -51,3 +52,9 @@ func RenderMarkdown() StyleOption {
 type CanFormatAsHTML interface {
 	FormatAsHTML() template.HTML
 }
+
+// CanFormatAsText is implemented by results that have a human-readable plain text form,
+// used for example when exporting a conversation.
+type CanFormatAsText interface {
+	FormatAsText() string
+}
@@ -18,6 +18,7 @@ import (
 	"io"
 	"slices"
 	"sync"
+	"time"
 )
 
 type Document struct {
@@ -26,6 +27,14 @@ type Document struct {
 	nextID        uint64
 
 	blocks []Block
+	// addedAt is when each block was added to the document.
+	addedAt map[Block]time.Time
+
+	// timeFormat is how times and durations are shown to the user.
+	timeFormat TimeFormat
+
+	// interjections is guidance typed by the user while the agent is working, not yet picked up by the agent.
+	interjections []string
 }
 
 func (d *Document) Blocks() []Block {
@@ -50,9 +59,53 @@ func (d *Document) IndexOf(find Block) int {
 	return -1
 }
 
+// AddedAt returns when the block was added to the document, or the zero time if it was not.
+func (d *Document) AddedAt(block Block) time.Time {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.addedAt[block]
+}
+
+// TimeFormat returns how times and durations are shown to the user.
+func (d *Document) TimeFormat() TimeFormat {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	return d.timeFormat
+}
+
+// SetTimeFormat sets how times and durations are shown to the user.
+func (d *Document) SetTimeFormat(f TimeFormat) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.timeFormat = f
+}
+
+// Interject queues guidance typed by the user while the agent is working.
+// The agent picks it up between iterations, with TakeInterjections.
+func (d *Document) Interject(text string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.interjections = append(d.interjections, text)
+}
+
+// TakeInterjections returns the queued guidance from the user, and clears the queue.
+func (d *Document) TakeInterjections() []string {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	interjections := d.interjections
+	d.interjections = nil
+	return interjections
+}
+
 func NewDocument() *Document {
 	return &Document{
-		nextID: 1,
+		nextID:  1,
+		addedAt: make(map[Block]time.Time),
 	}
 }
 
@@ -140,6 +193,7 @@ func (d *Document) AddBlock(block Block) {
 	newBlocks := slices.Clone(d.blocks)
 	newBlocks = append(newBlocks, block)
 	d.blocks = newBlocks
+	d.addedAt[block] = time.Now()
 
 	block.attached(d)
 	d.mutex.Unlock()
@@ -0,0 +1,153 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog/v2"
+)
+
+// NotificationMode is how the user is notified when the agent needs their attention.
+type NotificationMode string
+
+const (
+	// NotificationsOff disables notifications.
+	NotificationsOff NotificationMode = "off"
+	// NotificationsDesktop shows a desktop notification, using notify-send on Linux and osascript on macOS.
+	NotificationsDesktop NotificationMode = "desktop"
+	// NotificationsTerminal asks the terminal to show a notification (OSC 9), which many terminals support.
+	NotificationsTerminal NotificationMode = "terminal"
+)
+
+const notificationTitle = "kubectl-ai"
+
+// notifier is a Subscriber that notifies the user when the agent is waiting for input (the answer to a query,
+// or an approval), after working for longer than a threshold, so users who switched windows don't miss it.
+type notifier struct {
+	mode  NotificationMode
+	after time.Duration
+
+	mutex sync.Mutex
+	// workStart is when the agent started working after the last input; zero while waiting for input.
+	workStart time.Time
+	// lastInput is the last input block we have seen.
+	lastInput Block
+	// warned is set once we failed to send a notification, so we only warn once.
+	warned bool
+}
+
+// NewNotifier returns a Subscriber that notifies the user when the agent needs their input after working
+// for at least after. It must be subscribed before any UI that blocks while reading input.
+func NewNotifier(mode NotificationMode, after time.Duration) Subscriber {
+	return &notifier{mode: mode, after: after}
+}
+
+func (n *notifier) DocumentChanged(doc *Document, block Block) {
+	if n.mode == NotificationsOff {
+		return
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	switch block := block.(type) {
+	case *InputTextBlock, *InputOptionBlock:
+		if block == n.lastInput {
+			return
+		}
+		n.lastInput = block
+		workStart := n.workStart
+		n.workStart = time.Time{}
+		if workStart.IsZero() {
+			return
+		}
+		elapsed := time.Since(workStart)
+		if elapsed < n.after {
+			return
+		}
+		message := fmt.Sprintf("Finished after %s", doc.TimeFormat().Duration(elapsed))
+		if _, ok := block.(*InputOptionBlock); ok {
+			message = "Approval needed"
+			if description := lastFunctionCallDescription(doc); description != "" {
+				message += ": " + description
+			}
+		}
+		go n.notify(message)
+	default:
+		if n.workStart.IsZero() {
+			n.workStart = time.Now()
+		}
+	}
+}
+
+// lastFunctionCallDescription returns the description of the last function call in the document.
+func lastFunctionCallDescription(doc *Document) string {
+	blocks := doc.Blocks()
+	for i := len(blocks) - 1; i >= 0; i-- {
+		if block, ok := blocks[i].(*FunctionCallRequestBlock); ok {
+			return block.Description()
+		}
+	}
+	return ""
+}
+
+func (n *notifier) notify(message string) {
+	var err error
+	switch n.mode {
+	case NotificationsDesktop:
+		err = notifyDesktop(message)
+	case NotificationsTerminal:
+		// OSC 9 is a notification in iTerm2, Windows Terminal, kitty and others; the bell is a fallback.
+		_, err = fmt.Fprintf(os.Stdout, "\x1b]9;%s\x07\a", strings.ReplaceAll(message, "\x07", ""))
+	}
+	if err != nil {
+		n.mutex.Lock()
+		warned := n.warned
+		n.warned = true
+		n.mutex.Unlock()
+		if !warned {
+			klog.Warningf("Failed to send %s notification: %v", n.mode, err)
+		}
+	}
+}
+
+func notifyDesktop(message string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(notificationTitle))
+		cmd = exec.Command("osascript", "-e", script)
+	case "linux", "freebsd", "openbsd", "netbsd":
+		cmd = exec.Command("notify-send", "--app-name", notificationTitle, notificationTitle, message)
+	default:
+		return fmt.Errorf("desktop notifications are not supported on %s, use terminal notifications instead", runtime.GOOS)
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("running %s: %w: %s", cmd.Path, err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// appleScriptString quotes s as an AppleScript string literal.
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
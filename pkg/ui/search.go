@@ -0,0 +1,71 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"regexp"
+	"strings"
+)
+
+// SearchMatch is a line of a block that matches a search.
+type SearchMatch struct {
+	// BlockIndex is the index of the block in the document.
+	BlockIndex int
+	// Line is the matching line.
+	Line string
+	// Ranges are the [start, end) byte offsets of the matches in Line.
+	Ranges [][]int
+}
+
+// SearchDocument finds the lines of the document's blocks, including tool output, that match re.
+func SearchDocument(doc *Document, re *regexp.Regexp) []SearchMatch {
+	var matches []SearchMatch
+	for i, block := range doc.Blocks() {
+		entry, ok := exportBlock(block)
+		if !ok {
+			continue
+		}
+		for _, text := range []string{entry.Text, entry.Output} {
+			for _, line := range strings.Split(text, "\n") {
+				var ranges [][]int
+				for _, r := range re.FindAllStringIndex(line, -1) {
+					// Skip empty matches, e.g. of "a*", which would match every line.
+					if r[0] != r[1] {
+						ranges = append(ranges, r)
+					}
+				}
+				if len(ranges) > 0 {
+					matches = append(matches, SearchMatch{BlockIndex: i, Line: line, Ranges: ranges})
+				}
+			}
+		}
+	}
+	return matches
+}
+
+// Highlight returns the line with each match wrapped in before and after, e.g. "**" for markdown.
+func (m *SearchMatch) Highlight(before, after string) string {
+	var b strings.Builder
+	last := 0
+	for _, r := range m.Ranges {
+		b.WriteString(m.Line[last:r[0]])
+		b.WriteString(before)
+		b.WriteString(m.Line[r[0]:r[1]])
+		b.WriteString(after)
+		last = r[1]
+	}
+	b.WriteString(m.Line[last:])
+	return b.String()
+}
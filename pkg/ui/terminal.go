@@ -26,6 +26,7 @@ import (
 
 	"github.com/GoogleCloudPlatform/kubectl-ai/pkg/journal"
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
 	"github.com/chzyer/readline"
 	"k8s.io/klog/v2"
 )
@@ -38,6 +39,7 @@ type TerminalUI struct {
 
 	// Input handling fields (initialized once)
 	rlInstance        *readline.Instance // For readline input
+	input             *terminalInput     // Reads from rlInstance in the background
 	ttyFile           *os.File           // For TTY input
 	ttyReaderInstance *bufio.Reader      // For TTY input
 
@@ -46,6 +48,30 @@ type TerminalUI struct {
 	// currentBlockText is text of the currentBlock that we have already rendered to the screen
 	currentBlockText string
 
+	// renderedResults tracks how we rendered the result of each function call: folded or in full.
+	renderedResults map[*FunctionCallRequestBlock]resultRendering
+
+	// progressShown is true while the progress of a running function call is shown on the current line.
+	progressShown bool
+	// liveLines is the number of lines of live output of a running function call shown above the current line.
+	liveLines int
+
+	// keymap is the line-editing keymap for the input prompt.
+	keymap Keymap
+
+	// bracketedPaste is true if we enabled bracketed paste mode in the terminal.
+	bracketedPaste bool
+
+	// plain disables ANSI escape sequences (colors, styled markdown, clearing the screen),
+	// for output that is not an interactive terminal, such as CI logs.
+	plain bool
+
+	// streamAnswers writes the text of the LLM's answers to stdout as it arrives, as plain text without rendering
+	// the markdown, and everything else to stderr, so that programs reading stdout get the answer right away.
+	streamAnswers bool
+	// answerOpen is true if the text of an answer written to stdout does not end with a newline.
+	answerOpen bool
+
 	// This is useful in cases where stdin is already been used for providing the input to the agent (caller in this case)
 	// in such cases, stdin is already consumed and closed and reading input results in IO error.
 	// In such cases, we open /dev/tty and use it for taking input.
@@ -54,9 +80,18 @@ type TerminalUI struct {
 
 var _ UI = &TerminalUI{}
 
-func NewTerminalUI(doc *Document, journal journal.Recorder, useTTYForInput bool) (*TerminalUI, error) {
+func NewTerminalUI(doc *Document, journal journal.Recorder, useTTYForInput bool, keymap Keymap) (*TerminalUI, error) {
+	plain := usePlainOutput(os.Stdout)
+	if plain {
+		klog.Info("Using plain terminal output without colors")
+	}
+
+	style := glamour.WithAutoStyle()
+	if plain {
+		style = glamour.WithStandardStyle(styles.NoTTYStyle)
+	}
 	mdRenderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
+		style,
 		glamour.WithPreservedNewLines(),
 		glamour.WithEmoji(),
 	)
@@ -67,15 +102,35 @@ func NewTerminalUI(doc *Document, journal journal.Recorder, useTTYForInput bool)
 	u := &TerminalUI{
 		markdownRenderer: mdRenderer,
 		journal:          journal,
+		plain:            plain,
+		keymap:           keymap,
 		useTTYForInput:   useTTYForInput, // Store this flag
 	}
 
-	subscription := doc.AddSubscription(u)
+	subscription := doc.AddSubscription(NewCoalescingSubscriber(u))
 	u.subscription = subscription
 
 	return u, nil
 }
 
+// StreamAnswers writes the text of the LLM's answers to stdout as it arrives, without rendering markdown,
+// and the other blocks (tool calls, their results, prompts and errors) to stderr. This is for stdout
+// piped to another program, which then sees the answer as it is generated rather than after the round.
+func (u *TerminalUI) StreamAnswers() {
+	u.streamAnswers = true
+}
+
+// output returns where the block is written to.
+func (u *TerminalUI) output(block Block) io.Writer {
+	if !u.streamAnswers {
+		return os.Stdout
+	}
+	if textBlock, ok := block.(*AgentTextBlock); ok && textBlock.Color == "" {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
 func (u *TerminalUI) ttyReader() (*bufio.Reader, error) {
 	if u.ttyReaderInstance != nil {
 		return u.ttyReaderInstance, nil
@@ -96,12 +151,20 @@ func (u *TerminalUI) readlineInstance() (*readline.Instance, error) {
 	}
 	// Initialize readline input
 	historyPath := filepath.Join(os.TempDir(), "kubectl-ai-history")
+	var stdin io.ReadCloser = os.Stdin
+	if !u.plain {
+		// With bracketed paste, pasted multi-line text is edited as a single query instead of being submitted line by line.
+		stdin = newBracketedPasteReader(os.Stdin)
+		fmt.Print(enableBracketedPaste)
+		u.bracketedPaste = true
+	}
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt:      ">>> ", // Default prompt for main input
-		Stdin:       os.Stdin,
-		Stdout:      os.Stdout,
+		Prompt:      inputPrompt, // Default prompt for main input
+		Stdin:       stdin,
+		Stdout:      u.output(nil),
 		Stderr:      os.Stderr,
 		HistoryFile: historyPath,
+		VimMode:     u.keymap == KeymapVi,
 		// History enabled by default
 	})
 	if err != nil {
@@ -115,8 +178,26 @@ func (u *TerminalUI) readlineInstance() (*readline.Instance, error) {
 	return u.rlInstance, nil
 }
 
+// terminalInput returns the reader for input typed at the terminal, starting it if needed.
+func (u *TerminalUI) terminalInput(doc *Document) (*terminalInput, error) {
+	if u.input != nil {
+		return u.input, nil
+	}
+	rl, err := u.readlineInstance()
+	if err != nil {
+		return nil, err
+	}
+	u.input = newTerminalInput(rl, doc)
+	return u.input, nil
+}
+
 func (u *TerminalUI) Close() error {
 	var errs []error
+	if u.answerOpen {
+		// Programs reading stdout expect the answer to end with a newline.
+		fmt.Println()
+		u.answerOpen = false
+	}
 	if u.subscription != nil {
 		if err := u.subscription.Close(); err != nil {
 			errs = append(errs, err)
@@ -135,21 +216,55 @@ func (u *TerminalUI) Close() error {
 			errs = append(errs, fmt.Errorf("closing tty file: %w", err))
 		}
 	}
+	if u.bracketedPaste {
+		fmt.Print(disableBracketedPaste)
+		u.bracketedPaste = false
+	}
 	return errors.Join(errs...)
 }
 
+type resultRendering int
+
+const (
+	resultRenderedFolded resultRendering = iota + 1
+	resultRenderedFull
+)
+
 func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 	blockIndex := doc.IndexOf(block)
 
+	// Results of function calls arrive (or are expanded) after other blocks may have been added,
+	// so we render them at the end of the output, even if the block is not the last one.
+	if callBlock, ok := block.(*FunctionCallRequestBlock); ok && callBlock.Result() != nil {
+		u.clearProgress()
+		u.renderFunctionCallResult(blockIndex, callBlock)
+		return
+	}
+	if callBlock, ok := block.(*FunctionCallRequestBlock); ok && u.currentBlock == block {
+		if _, _, ok := callBlock.Progress(); ok || callBlock.LiveOutput() != "" {
+			u.renderProgress(callBlock)
+			return
+		}
+	}
+
 	if blockIndex != doc.NumBlocks()-1 {
 		klog.Warningf("update to blocks other than the last block is not supported in terminal mode")
 		return
 	}
 
+	out := u.output(block)
 	if u.currentBlock != block {
+		u.clearProgress()
 		u.currentBlock = block
-		if u.currentBlockText != "" {
-			fmt.Printf("\n")
+		switch {
+		case u.streamAnswers && out == os.Stdout:
+			// Answers are separated from each other, not from the blocks written to stderr in between.
+			if u.answerOpen {
+				fmt.Fprintf(out, "\n")
+				u.answerOpen = false
+			}
+		case u.currentBlockText != "":
+			fmt.Fprintf(out, "\n")
 		}
 		u.currentBlockText = ""
 	}
@@ -166,7 +281,9 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 		styleOptions = append(styleOptions, Foreground(ColorGreen))
 		text = fmt.Sprintf("  Running: %s\n", block.Description())
 	case *AgentTextBlock:
-		styleOptions = append(styleOptions, RenderMarkdown())
+		if !u.streamAnswers || block.Color != "" {
+			styleOptions = append(styleOptions, RenderMarkdown())
+		}
 		if block.Color != "" {
 			styleOptions = append(styleOptions, Foreground(block.Color))
 		}
@@ -180,21 +297,28 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 				block.Observable().Set("", fmt.Errorf("TTY reader not initialized"))
 				return
 			}
-			fmt.Print("\n>>> ") // Print prompt manually
-			query, err = tReader.ReadString('\n')
+			fmt.Print("\n")
+			if initialText := block.InitialText(); initialText != "" {
+				// We cannot fill in the input when reading from the TTY, so we show the text instead.
+				fmt.Printf("%s\n  (press Enter to keep it)\n", initialText)
+			}
+			query, err = readQuery(func(prompt string) (string, error) {
+				fmt.Print(prompt) // Print prompt manually
+				line, err := tReader.ReadString('\n')
+				return strings.TrimSuffix(line, "\n"), err
+			})
 			if err != nil {
 				block.Observable().Set("", err) // Set error (includes io.EOF)
 			} else {
 				block.Observable().Set(query, nil)
 			}
 		} else {
-			rlInstance, err := u.readlineInstance()
+			input, err := u.terminalInput(doc)
 			if err != nil {
 				block.Observable().Set("", fmt.Errorf("error creating readline instance: %w", err))
 				return
 			}
-			rlInstance.SetPrompt(">>> ") // Ensure correct prompt
-			query, err = rlInstance.Readline()
+			query, err = input.read(inputPrompt, block.InitialText())
 			if err != nil {
 				if err == readline.ErrInterrupt { // Handle Ctrl+C
 					block.Observable().Set("", io.EOF)
@@ -210,9 +334,9 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 		return
 
 	case *InputOptionBlock:
-		fmt.Printf("%s\n", block.Prompt) // Print initial prompt text
+		fmt.Fprintf(out, "%s\n", block.Prompt) // Print initial prompt text
 		for i, option := range block.Options {
-			fmt.Printf("  %d) %s\n", i+1, option.Message)
+			fmt.Fprintf(out, "  %d) %s\n", i+1, option.Message)
 		}
 
 		var choiceNumbers []string
@@ -230,7 +354,7 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 				inputMap[alias] = option.Key
 			}
 		}
-		fmt.Printf("  Enter your choice (%s): ", strings.Join(choiceNumbers, ","))
+		fmt.Fprintf(out, "  Enter your choice (%s): ", strings.Join(choiceNumbers, ","))
 
 		if u.useTTYForInput {
 			tReader, err := u.ttyReader()
@@ -239,7 +363,7 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 				return
 			}
 			for {
-				fmt.Printf("  Enter your choice (%s): ", strings.Join(choiceNumbers, ",")) // Print loop prompt manually
+				fmt.Fprintf(out, "  Enter your choice (%s): ", strings.Join(choiceNumbers, ",")) // Print loop prompt manually
 				response, err := tReader.ReadString('\n')
 				if err != nil {
 					block.Selection().Set("", err)
@@ -251,24 +375,19 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 					block.Selection().Set(optionKey, nil)
 					break // Exit loop on valid choice
 				} else {
-					fmt.Printf("  Invalid choice. Please enter one of: %s\n", strings.Join(allOptions, ", "))
+					fmt.Fprintf(out, "  Invalid choice. Please enter one of: %s\n", strings.Join(allOptions, ", "))
 				}
 			}
 		} else {
-			rlInstance, err := u.readlineInstance()
+			input, err := u.terminalInput(doc)
 			if err != nil {
 				block.Selection().Set("", fmt.Errorf("readline instance not initialized: %w", err))
 				return
 			}
-			// Temporarily change prompt for option selection
-			originalPrompt := rlInstance.Config.Prompt
-			choicePrompt := "  Enter your choice (1,2,3): "
-			rlInstance.SetPrompt(choicePrompt)
-			// Ensure original prompt is restored even if errors occur
-			defer rlInstance.SetPrompt(originalPrompt)
+			choicePrompt := fmt.Sprintf("  Enter your choice (%s): ", strings.Join(choiceNumbers, ","))
 
 			for {
-				response, err := rlInstance.Readline()
+				response, err := input.read(choicePrompt, "")
 				if err != nil {
 					if err == readline.ErrInterrupt { // Handle Ctrl+C
 						block.Selection().Set("", io.EOF)
@@ -288,7 +407,7 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 					block.Selection().Set(optionKey, nil)
 					break // Exit loop on valid choice
 				} else {
-					fmt.Printf("\n  Invalid choice. Please enter one of: %s\n", strings.Join(allOptions, ", "))
+					fmt.Fprintf(out, "\n  Invalid choice. Please enter one of: %s\n", strings.Join(allOptions, ", "))
 				}
 			}
 		}
@@ -326,16 +445,23 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 	}
 	u.currentBlockText = text
 
+	if u.plain {
+		computedStyle.Foreground = ""
+	}
+
 	reset := ""
 	switch computedStyle.Foreground {
 	case ColorRed:
-		fmt.Printf("\033[31m")
+		fmt.Fprintf(out, "\033[31m")
 		reset += "\033[0m"
 	case ColorGreen:
-		fmt.Printf("\033[32m")
+		fmt.Fprintf(out, "\033[32m")
 		reset += "\033[0m"
 	case ColorWhite:
-		fmt.Printf("\033[37m")
+		fmt.Fprintf(out, "\033[37m")
+		reset += "\033[0m"
+	case ColorYellow:
+		fmt.Fprintf(out, "\033[33m")
 		reset += "\033[0m"
 
 	case "":
@@ -343,9 +469,142 @@ func (u *TerminalUI) DocumentChanged(doc *Document, block Block) {
 		klog.Info("foreground color not supported by TerminalUI", "color", computedStyle.Foreground)
 	}
 
-	fmt.Printf("%s%s", printText, reset)
+	fmt.Fprintf(out, "%s%s", printText, reset)
+	if u.streamAnswers && out == os.Stdout && printText != "" {
+		u.answerOpen = !strings.HasSuffix(printText, "\n")
+	}
+}
+
+// progressBarWidth is the number of characters of the progress bar.
+const progressBarWidth = 20
+
+// renderProgress shows the last lines of output of a running function call, and its progress on a single line,
+// which are updated in place. We don't show them in plain output, as it cannot be updated in place.
+func (u *TerminalUI) renderProgress(block *FunctionCallRequestBlock) {
+	if u.plain {
+		return
+	}
+	u.clearProgress()
+
+	// Lines that are wider than the terminal would wrap, and we would not clear all of them, so we cut them.
+	width := readline.GetScreenWidth() - 4
+	if width <= 0 {
+		width = 76
+	}
+	var live strings.Builder
+	if hidden := block.HiddenOutputLines(); hidden > 0 {
+		fmt.Fprintf(&live, "  … %d earlier lines\n", hidden)
+		u.liveLines++
+	}
+	if output := block.LiveOutput(); output != "" {
+		for _, line := range strings.Split(output, "\n") {
+			line = strings.ReplaceAll(strings.ReplaceAll(line, "\r", ""), "\t", "    ")
+			if runes := []rune(line); len(runes) > width {
+				line = string(runes[:width-1]) + "…"
+			}
+			live.WriteString("  │ " + line + "\n")
+			u.liveLines++
+		}
+	}
+	if live.Len() != 0 {
+		fmt.Printf("\033[37m%s\033[0m", live.String())
+	}
+
+	message, fraction, ok := block.Progress()
+	if !ok {
+		return
+	}
+	line := "  "
+	if fraction >= 0 {
+		done := int(fraction * progressBarWidth)
+		line += fmt.Sprintf("[%s%s] %3d%% ", strings.Repeat("█", done), strings.Repeat("░", progressBarWidth-done), int(fraction*100))
+	}
+	line += message
+	fmt.Printf("\r\033[K\033[37m%s\033[0m", line)
+	u.progressShown = true
+}
+
+// clearProgress removes the progress line and live output, if they are shown.
+func (u *TerminalUI) clearProgress() {
+	if u.progressShown {
+		fmt.Print("\r\033[K")
+		u.progressShown = false
+	}
+	if u.liveLines > 0 {
+		fmt.Printf("\033[%dA\033[J", u.liveLines)
+		u.liveLines = 0
+	}
+}
+
+// renderFunctionCallResult prints the result of a function call, or a summary line if the result is large.
+func (u *TerminalUI) renderFunctionCallResult(blockIndex int, block *FunctionCallRequestBlock) {
+	rendering := resultRenderedFull
+	if block.Folded() {
+		rendering = resultRenderedFolded
+	}
+	if u.renderedResults == nil {
+		u.renderedResults = make(map[*FunctionCallRequestBlock]resultRendering)
+	}
+	if u.renderedResults[block] >= rendering {
+		// Already rendered (we never fold output that was already shown in full)
+		return
+	}
+	u.renderedResults[block] = rendering
+
+	out := u.output(block)
+	if u.currentBlock != block && u.currentBlockText != "" {
+		fmt.Fprintf(out, "\n")
+	}
+	u.currentBlock = block
+	u.currentBlockText = ""
+
+	if rendering == resultRenderedFolded {
+		text := fmt.Sprintf("  ▸ [%d] Output folded (%s), type /expand %d to show it\n", blockIndex, block.ResultSummary(), blockIndex)
+		if !u.plain {
+			text = "\033[37m" + text + "\033[0m"
+		}
+		fmt.Fprint(out, text)
+		return
+	}
+
+	text := block.ResultText()
+	if text == "" {
+		return
+	}
+	if block.Expanded() {
+		fmt.Fprintf(out, "  ▾ [%d] %s\n", blockIndex, block.Description())
+	}
+	fmt.Fprintf(out, "%s\n", text)
 }
 
 func (u *TerminalUI) ClearScreen() {
+	if u.plain {
+		return
+	}
 	fmt.Print("\033[H\033[2J")
 }
+
+// ciEnvVars are environment variables set by common CI systems.
+var ciEnvVars = []string{"CI", "BUILD_NUMBER", "GITHUB_ACTIONS", "GITLAB_CI", "BUILDKITE", "TF_BUILD", "JENKINS_URL"}
+
+// usePlainOutput returns true if we should not use ANSI escape sequences when writing to out:
+// if NO_COLOR is set (see https://no-color.org), the terminal is dumb, we are running in CI,
+// or out is not a terminal (e.g. redirected to a file).
+func usePlainOutput(out *os.File) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return true
+	}
+	if os.Getenv("TERM") == "dumb" {
+		return true
+	}
+	for _, envVar := range ciEnvVars {
+		if os.Getenv(envVar) != "" {
+			return true
+		}
+	}
+	stat, err := out.Stat()
+	if err != nil {
+		return true
+	}
+	return stat.Mode()&os.ModeCharDevice == 0
+}
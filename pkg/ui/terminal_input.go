@@ -0,0 +1,132 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/chzyer/readline"
+	"k8s.io/klog/v2"
+)
+
+// terminalInput reads from readline in the background, so that the user can type while the agent is working.
+// Input is delivered to the prompt that is waiting for it, if any; input typed while no prompt is waiting
+// is queued in the document as guidance, which the agent picks up before its next step.
+type terminalInput struct {
+	rl  *readline.Instance
+	doc *Document
+
+	mutex sync.Mutex
+	// waiting receives the next input, if a prompt is waiting for it.
+	waiting chan inputResult
+	// waitingPrompt is the prompt of the reader that is waiting.
+	waitingPrompt string
+	// err is set once reading has failed (e.g. with io.EOF); all later reads fail with it.
+	err error
+}
+
+type inputResult struct {
+	text string
+	err  error
+}
+
+func newTerminalInput(rl *readline.Instance, doc *Document) *terminalInput {
+	t := &terminalInput{rl: rl, doc: doc}
+	go t.run()
+	return t
+}
+
+func (t *terminalInput) run() {
+	for {
+		query, err := readQuery(func(prompt string) (string, error) {
+			if prompt != continuationPrompt {
+				prompt = t.prompt()
+			}
+			t.rl.SetPrompt(prompt)
+			return t.rl.Readline()
+		})
+
+		failed := err != nil && err != readline.ErrInterrupt
+
+		t.mutex.Lock()
+		waiting := t.waiting
+		t.waiting = nil
+		if failed {
+			t.err = err
+		}
+		t.mutex.Unlock()
+
+		switch {
+		case waiting != nil:
+			waiting <- inputResult{text: query, err: err}
+		case err == readline.ErrInterrupt:
+			// The terminal is in raw mode while we read, so Ctrl+C doesn't send a signal; send it ourselves,
+			// so that Ctrl+C still stops the agent while it is working.
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				if err := p.Signal(os.Interrupt); err != nil {
+					klog.Warningf("Failed to interrupt: %v", err)
+				}
+			}
+		case err == nil && strings.TrimSpace(query) != "":
+			t.doc.Interject(strings.TrimSpace(query))
+			fmt.Printf("  (noted, the agent will take this into account before its next step)\n")
+		}
+
+		if failed {
+			return
+		}
+	}
+}
+
+// prompt is the prompt to show for new input: the prompt of the waiting reader, or none while the agent is working.
+func (t *terminalInput) prompt() string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.waiting == nil {
+		return ""
+	}
+	return t.waitingPrompt
+}
+
+// read waits for the user to enter input at prompt; the input starts with text, which the user can edit.
+func (t *terminalInput) read(prompt, text string) (string, error) {
+	t.mutex.Lock()
+	if t.err != nil {
+		err := t.err
+		t.mutex.Unlock()
+		return "", err
+	}
+	waiting := make(chan inputResult, 1)
+	t.waiting = waiting
+	t.waitingPrompt = prompt
+	t.mutex.Unlock()
+
+	// readline may already be reading with the "working" prompt.
+	t.rl.SetPrompt(prompt)
+	t.rl.Refresh()
+	if text != "" {
+		// Newlines would submit the input, so we show them as in pasted text.
+		if _, err := t.rl.WriteStdin([]byte(strings.ReplaceAll(text, "\n", pastedNewline))); err != nil {
+			klog.Warningf("Failed to fill in the input: %v", err)
+		}
+	}
+
+	result := <-waiting
+	return result.text, result.err
+}
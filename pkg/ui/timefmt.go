@@ -0,0 +1,112 @@
+// Copyright 2025 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeStyle is how timestamps are shown to the user.
+type TimeStyle string
+
+const (
+	// TimeStyleAbsolute shows the date and time, e.g. "2025-06-01 14:03:05 CEST".
+	TimeStyleAbsolute TimeStyle = "absolute"
+	// TimeStyleRelative shows the time relative to now, e.g. "5m ago"; times more than a day ago are shown as absolute.
+	TimeStyleRelative TimeStyle = "relative"
+)
+
+const absoluteTimeLayout = "2006-01-02 15:04:05 MST"
+
+// TimeFormat formats the timestamps and durations shown in the UI, the journal replay and exports,
+// so they are consistent everywhere. The zero value shows absolute times in the local time zone.
+type TimeFormat struct {
+	// Style is whether times are shown as absolute or relative times.
+	Style TimeStyle
+	// Location is the time zone times are shown in; nil means the local time zone.
+	Location *time.Location
+}
+
+// NewTimeFormat returns the TimeFormat for a style and a time zone, which is "local", "UTC" or an IANA
+// time zone name such as "Europe/Paris".
+func NewTimeFormat(style TimeStyle, timezone string) (TimeFormat, error) {
+	switch style {
+	case TimeStyleAbsolute, TimeStyleRelative:
+	default:
+		return TimeFormat{}, fmt.Errorf("invalid time format %q, supported values: %s, %s", style, TimeStyleAbsolute, TimeStyleRelative)
+	}
+
+	f := TimeFormat{Style: style}
+	if timezone != "" && !strings.EqualFold(timezone, "local") {
+		location, err := time.LoadLocation(timezone)
+		if err != nil {
+			return TimeFormat{}, fmt.Errorf("invalid time zone %q: %w", timezone, err)
+		}
+		f.Location = location
+	}
+	return f, nil
+}
+
+// Time formats t, according to the style.
+func (f TimeFormat) Time(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if f.Style == TimeStyleRelative {
+		if ago := time.Since(t); ago >= 0 && ago < 24*time.Hour {
+			if ago < 10*time.Second {
+				return "just now"
+			}
+			return f.Duration(ago.Truncate(time.Second)) + " ago"
+		}
+	}
+	return f.Absolute(t)
+}
+
+// Elapsed formats t for a record that is read later, such as an export or a replayed journal, where a time relative
+// to now would go stale: with the relative style, it is shown as the time since start, e.g. "+1m5s".
+func (f TimeFormat) Elapsed(start, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	if f.Style == TimeStyleRelative && !start.IsZero() && !t.Before(start) {
+		return "+" + f.Duration(t.Sub(start))
+	}
+	return f.Absolute(t)
+}
+
+// Absolute formats t as an absolute time, regardless of the style.
+func (f TimeFormat) Absolute(t time.Time) string {
+	location := f.Location
+	if location == nil {
+		location = time.Local
+	}
+	return t.In(location).Format(absoluteTimeLayout)
+}
+
+// Duration formats d with a precision that suits its length: milliseconds below a second,
+// tenths of a second below a minute, and whole seconds above, e.g. "350ms", "4.2s" or "1h2m5s".
+func (f TimeFormat) Duration(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	default:
+		return d.Round(time.Second).String()
+	}
+}